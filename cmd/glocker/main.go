@@ -2,10 +2,10 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
-	"net"
 	"os"
 	"os/exec"
 	"os/signal"
@@ -14,85 +14,249 @@ import (
 	"syscall"
 	"time"
 
+	"glocker/internal/audit"
+	"glocker/internal/buildinfo"
 	"glocker/internal/cli"
 	"glocker/internal/config"
 	"glocker/internal/enforcement"
 	"glocker/internal/install"
 	"glocker/internal/ipc"
 	"glocker/internal/monitoring"
+	"glocker/internal/notify"
+	"glocker/internal/state"
+	"glocker/internal/utils"
 	"glocker/internal/web"
 )
 
 func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+// runningAsRoot reports whether the real user is root; tests replace it.
+var runningAsRoot = func() bool { return install.RunningAsRoot(true) }
+
+// dropToRealUser gives up the root privileges of the setuid binary for the rest
+// of the process, so files are read with the invoking user's permissions.
+func dropToRealUser() error {
+	uid, gid := os.Getuid(), os.Getgid()
+	if err := syscall.Setresgid(gid, gid, gid); err != nil {
+		return fmt.Errorf("failed to drop group privileges: %w", err)
+	}
+	if err := syscall.Setresuid(uid, uid, uid); err != nil {
+		return fmt.Errorf("failed to drop user privileges: %w", err)
+	}
+	return nil
+}
+
+// emailShutdownTimeout bounds how long a stopping daemon waits for queued emails.
+const emailShutdownTimeout = 30 * time.Second
+
+// run runs the glocker command line with args and returns its exit code
+// (see cli.ExitCode).
+func run(args []string) int {
 	// Parse command-line flags
-	installFlag := flag.Bool("install", false, "Install glocker as a system service")
-	uninstallReason := flag.String("uninstall", "", "Uninstall Glocker and revert all changes (provide reason)")
-	daemonFlag := flag.Bool("daemon", false, "Run as daemon (for systemd service)")
-	statusFlag := flag.Bool("status", false, "Show runtime status (violations, temp unblocks, panic mode)")
-	infoFlag := flag.Bool("info", false, "Show configuration info (domains, programs, keywords)")
-	reloadFlag := flag.Bool("reload", false, "Reload configuration from config file")
-	blockHosts := flag.String("block", "", "Comma-separated list of hosts to add to always block list")
-	unblockHosts := flag.String("unblock", "", "Comma-separated list of hosts to temporarily unblock (format: 'domain1,domain2:reason')")
-	addKeyword := flag.String("add-keyword", "", "Comma-separated list of keywords to add to both URL and content keyword lists")
-	panicMinutes := flag.Int("panic", 0, "Enter panic mode for N minutes (suspends system and re-suspends on early wake)")
-	lockFlag := flag.Bool("lock", false, "Immediately lock sudoers access (ignores time windows)")
-	versionFlag := flag.Bool("version", false, "Show version information")
-
-	flag.Parse()
+	flags := flag.NewFlagSet("glocker", flag.ContinueOnError)
+	flags.Usage = func() { printUsage(flags) }
+	installFlag := flags.Bool("install", false, "Install glocker as a system service")
+	forceConfig := flags.Bool("force-config", false, "With -install, replace an installed config with conf/conf.yaml (it is kept by default)")
+	uninstallReason := flags.String("uninstall", "", "Uninstall Glocker and revert all changes (provide reason)")
+	daemonFlag := flags.Bool("daemon", false, "Run as daemon (for systemd service)")
+	statusFlag := flags.Bool("status", false, "Show runtime status (violations, temp unblocks, panic mode)")
+	infoFlag := flags.Bool("info", false, "Show configuration info (domains, programs, keywords)")
+	reloadFlag := flags.Bool("reload", false, "Reload configuration from config file")
+	historyFlag := flags.Bool("history", false, "Show the recent enforcement actions and why they were taken")
+	reloadDryFlag := flags.Bool("reload-dry", false, "Show what reloading the config file would change, without applying it")
+	blockHosts := flags.String("block", "", "Comma-separated list of hosts to add to always block list")
+	unblockHosts := flags.String("unblock", "", "Comma-separated list of hosts to temporarily unblock (format: 'domain1,domain2:reason', or 'domain1,domain2:reason:note' with require_note)")
+	unblockUntil := flags.String("until", "", "With -unblock: keep the domains unblocked until HH:MM today instead of for the usual duration (needs unblocking.max_until_minutes)")
+	revokeHost := flags.String("revoke", "", "End the temporary unblock of a domain now, blocking it again")
+	addKeyword := flags.String("add-keyword", "", "Comma-separated list of keywords to add to both URL and content keyword lists")
+	enableCategory := flags.String("enable-category", "", "Switch on a keyword category (keyword_categories name) until it is disabled again")
+	disableCategory := flags.String("disable-category", "", "Switch off a keyword category until it is enabled again (format: 'name:reason')")
+	panicMinutes := flags.Int("panic", 0, "Enter panic mode for N minutes (suspends system and re-suspends on early wake)")
+	pauseMinutes := flags.Int("pause", 0, "Pause hosts, firewall and sudoers blocking for N minutes (after a typing challenge)")
+	focusFor := flags.String("focus", "", "Start a focus session: block focus.distraction_domains for a duration (e.g. 50m)")
+	lockFlag := flags.Bool("lock", false, "Immediately lock sudoers access (ignores time windows)")
+	lockScreenFlag := flags.Bool("lock-screen", false, "Lock the screen until a passage from mindful_text is typed")
+	testEmailFlag := flags.Bool("test-email", false, "Send a test accountability email to verify the email settings")
+	doctorFlag := flags.Bool("doctor", false, "Check the installation for common problems and suggest fixes")
+	versionFlag := flags.Bool("version", false, "Show version information")
+	simulateAt := flags.String("simulate", "", "Show which domains the config would block at a local time (format: \"YYYY-MM-DD HH:MM\")")
+	exportConfigFlag := flags.Bool("export-config", false, "Print the config file with API keys and passwords redacted, for sharing")
+	configPath := flags.String("config", config.GlockerConfigFile, "With -status, -info or -simulate: show this config file instead of the installed one (for testing a config before installing it)")
+	jsonFlag := flags.Bool("json", false, "Report errors on stderr as JSON ({\"error\":\"...\",\"code\":N}); with -status or -info, print them as JSON")
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return cli.ExitOK
+		}
+		return cli.ExitValidation
+	}
+
+	// With -config, -status and -info show that file instead of asking the daemon
+	configSet := false
+	var otherFlags []string
+	flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "config":
+			configSet = true
+		case "json", "status", "info", "simulate":
+		default:
+			otherFlags = append(otherFlags, "-"+f.Name)
+		}
+	})
+
+	// With -json, stderr carries nothing but the error object
+	if *jsonFlag {
+		log.SetOutput(os.Stdout)
+	}
+
+	// fail reports err and returns its exit code (see cli.ExitCode)
+	fail := func(err error) int {
+		if *jsonFlag {
+			fmt.Fprintln(os.Stderr, cli.FormatError(err, true))
+		} else {
+			log.SetOutput(os.Stderr)
+			log.Print(cli.FormatError(err, false))
+		}
+		return cli.ExitCode(err)
+	}
+
+	if configSet {
+		// The binary is setuid root, so a config of the user's choosing must never
+		// reach the daemon, the socket commands or anything else run as root
+		if len(otherFlags) > 0 {
+			return fail(cli.NewExitError(cli.ExitValidation, "-config only works with -status, -info or -simulate, not %s", strings.Join(otherFlags, ", ")))
+		}
+		if !runningAsRoot() {
+			if err := dropToRealUser(); err != nil {
+				return fail(cli.NewExitError(cli.ExitPermission, "Refusing to read %s: %v", *configPath, err))
+			}
+		}
+		config.SetConfigPath(*configPath)
+	}
 
 	// Handle version flag
 	if *versionFlag {
-		log.Println("Glocker v1.0.0")
-		return
+		fmt.Println(buildinfo.Get())
+		return cli.ExitOK
+	}
+
+	// Handle config export (reads the file directly, so the daemon needn't run)
+	if *exportConfigFlag {
+		data, err := config.ExportConfig()
+		if err != nil {
+			return fail(cli.NewExitError(cli.ExitValidation, "Failed to export config: %v", err))
+		}
+		os.Stdout.Write(data)
+		return cli.ExitOK
+	}
+
+	// Handle simulate (evaluates the config file, so the daemon needn't run)
+	if *simulateAt != "" {
+		at, err := cli.ParseSimulateTime(*simulateAt)
+		if err != nil {
+			return fail(cli.NewExitError(cli.ExitValidation, "%w", err))
+		}
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fail(cli.NewExitError(cli.ExitValidation, "Failed to load config: %v", err))
+		}
+		fmt.Print(cli.GetSimulateResponse(cfg, at))
+		return cli.ExitOK
+	}
+
+	// Handle doctor (checks the system directly, so it works when the daemon is down)
+	if *doctorFlag {
+		checks := cli.RunDoctor()
+		fmt.Print(cli.FormatDoctorReport(checks))
+		if failures := cli.DoctorFailures(checks); failures > 0 {
+			return fail(cli.NewExitError(cli.ExitFailure, "%d doctor checks failed", failures))
+		}
+		return cli.ExitOK
+	}
+
+	// Handle test email
+	if *testEmailFlag {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return fail(cli.NewExitError(cli.ExitValidation, "Failed to load config: %v", err))
+		}
+		config.SetupLogging(cfg)
+
+		if !cfg.Accountability.Enabled {
+			return fail(cli.NewExitError(cli.ExitValidation, "Accountability is disabled - set accountability.enabled: true to send emails"))
+		}
+
+		log.Printf("Sending test email from %s to %s...", cfg.Accountability.FromEmail, strings.Join(cfg.Accountability.Recipients(), ", "))
+		response, err := notify.SendTestEmail(cfg)
+		if err != nil {
+			return fail(fmt.Errorf("Test email failed: %w", err))
+		}
+		log.Printf("Response: %s", response)
+		return cli.ExitOK
 	}
 
 	// Handle installation
 	if *installFlag {
-		if !install.RunningAsRoot(true) {
-			log.Fatal("Installation must be run as root (use sudo)")
+		if !runningAsRoot() {
+			return fail(cli.NewExitError(cli.ExitPermission, "Installation must be run as root (use sudo)"))
 		}
-		if err := install.InstallGlocker(); err != nil {
-			log.Fatalf("Installation failed: %v", err)
+		if err := install.InstallGlocker(*forceConfig); err != nil {
+			return fail(fmt.Errorf("Installation failed: %w", err))
 		}
-		return
+		return cli.ExitOK
 	}
 
 	// Handle uninstallation
 	if *uninstallReason != "" {
-		if !install.RunningAsRoot(true) {
-			log.Fatal("Uninstall must be run as root (use sudo)")
+		if !runningAsRoot() {
+			return fail(cli.NewExitError(cli.ExitPermission, "Uninstall must be run as root (use sudo)"))
 		}
 
 		// Check if glocker is actually installed
 		if _, err := os.Stat("/usr/local/bin/glocker"); os.IsNotExist(err) {
-			log.Fatal("Glocker is not installed. Nothing to uninstall.")
+			return fail(cli.NewExitError(cli.ExitValidation, "Glocker is not installed. Nothing to uninstall."))
 		}
 
 		// Send uninstall request to daemon via socket
-		conn, err := net.Dial("unix", ipc.SocketPath)
+		conn, err := ipc.Connect()
 		if err != nil {
-			log.Fatalf("Failed to connect to glocker service: %v", err)
+			return fail(err)
 		}
 		defer conn.Close()
 
-		message := fmt.Sprintf("uninstall:%s\n", *uninstallReason)
-		conn.Write([]byte(message))
+		message := ipc.SignCommand(fmt.Sprintf("uninstall:%s", *uninstallReason))
+		conn.Write([]byte(message + "\n"))
 
 		// Read initial response
 		reader := bufio.NewReader(conn)
 		response, err := reader.ReadString('\n')
 		if err != nil {
-			log.Fatalf("Failed to read response: %v", err)
+			return fail(fmt.Errorf("Failed to read response: %w", err))
+		}
+		response, err = ipc.CheckResponse(response)
+		if err != nil {
+			return fail(err)
+		}
+		log.Printf("Response: %s", response)
+
+		// Under uninstall.cooldown_minutes the first request only schedules the uninstall
+		if strings.HasPrefix(response, ipc.UninstallScheduledPrefix) {
+			return cli.ExitOK
 		}
-		log.Printf("Response: %s", strings.TrimSpace(response))
 
 		// Wait for completion signal
 		log.Println("Waiting for uninstall process to complete...")
 		completionResponse, err := reader.ReadString('\n')
 		if err != nil {
-			log.Fatalf("Failed to read completion response: %v", err)
+			return fail(fmt.Errorf("Failed to read completion response: %w", err))
+		}
+		completionResponse, err = ipc.CheckResponse(completionResponse)
+		if err != nil {
+			return fail(err)
 		}
-		log.Printf("Completion: %s", strings.TrimSpace(completionResponse))
+		log.Printf("Completion: %s", completionResponse)
 
 		// Now stop and disable the systemd service (daemon has exited)
 		log.Println("Stopping and disabling glocker service...")
@@ -128,269 +292,341 @@ func main() {
 		log.Printf("   rm -f %s", config.GlockerConfigFile)
 		log.Printf("   rmdir %s", filepath.Dir(config.GlockerConfigFile))
 
-		return
+		return cli.ExitOK
 	}
 
 	// Handle socket-based commands (don't need config)
 	if *reloadFlag {
-		conn, err := net.Dial("unix", ipc.SocketPath)
+		response, err := ipc.SendCommand("reload")
 		if err != nil {
-			log.Fatalf("Failed to connect to glocker service: %v", err)
+			return fail(err)
 		}
-		defer conn.Close()
-
-		conn.Write([]byte("reload\n"))
+		log.Printf("Response: %s", response)
+		return cli.ExitOK
+	}
 
-		reader := bufio.NewReader(conn)
-		response, err := reader.ReadString('\n')
+	if *reloadDryFlag {
+		lines, err := ipc.SendMultilineCommand("reload-dry")
 		if err != nil {
-			log.Fatalf("Failed to read response: %v", err)
+			return fail(err)
 		}
-
-		log.Printf("Response: %s", strings.TrimSpace(response))
-		return
+		for _, line := range lines {
+			fmt.Println(line)
+		}
+		return cli.ExitOK
 	}
 
-	if *blockHosts != "" {
-		conn, err := net.Dial("unix", ipc.SocketPath)
+	if *historyFlag {
+		lines, err := ipc.SendMultilineCommand("history")
 		if err != nil {
-			log.Fatalf("Failed to connect to glocker service: %v", err)
+			return fail(err)
 		}
-		defer conn.Close()
-
-		message := fmt.Sprintf("block:%s\n", *blockHosts)
-		conn.Write([]byte(message))
+		for _, line := range lines {
+			fmt.Println(line)
+		}
+		return cli.ExitOK
+	}
 
-		reader := bufio.NewReader(conn)
-		response, err := reader.ReadString('\n')
+	if *blockHosts != "" {
+		response, err := ipc.SendCommand(fmt.Sprintf("block:%s", *blockHosts))
 		if err != nil {
-			log.Fatalf("Failed to read response: %v", err)
+			return fail(err)
 		}
-
-		log.Printf("Response: %s", strings.TrimSpace(response))
+		log.Printf("Response: %s", response)
 		log.Println("Domains will be permanently blocked.")
-		return
+		return cli.ExitOK
+	}
+
+	if *unblockUntil != "" && *unblockHosts == "" {
+		return fail(cli.NewExitError(cli.ExitValidation, "ERROR: -until only works with -unblock"))
 	}
 
 	if *unblockHosts != "" {
 		// Parse format: "domain1,domain2:reason"
 		parts := strings.SplitN(*unblockHosts, ":", 2)
 		if len(parts) != 2 {
-			log.Fatal("ERROR: Reason required. Use format: 'domain1,domain2:reason'")
+			return fail(cli.NewExitError(cli.ExitValidation, "ERROR: Reason required. Use format: 'domain1,domain2:reason'"))
 		}
 
 		domains := strings.TrimSpace(parts[0])
 		reason := strings.TrimSpace(parts[1])
 
 		if domains == "" {
-			log.Fatal("ERROR: No domains specified")
+			return fail(cli.NewExitError(cli.ExitValidation, "ERROR: No domains specified"))
 		}
 
 		if reason == "" {
-			log.Fatal("ERROR: Reason cannot be empty")
+			return fail(cli.NewExitError(cli.ExitValidation, "ERROR: Reason cannot be empty"))
 		}
 
-		conn, err := net.Dial("unix", ipc.SocketPath)
+		command := fmt.Sprintf("unblock:%s:%s", domains, reason)
+		if until := strings.TrimSpace(*unblockUntil); until != "" {
+			command += ":until=" + until
+		}
+		response, err := ipc.SendCommand(command)
 		if err != nil {
-			log.Fatalf("Failed to connect to glocker service: %v", err)
+			return fail(err)
 		}
-		defer conn.Close()
-
-		message := fmt.Sprintf("unblock:%s:%s\n", domains, reason)
-		conn.Write([]byte(message))
+		log.Printf("Response: %s", response)
+		return cli.ExitOK
+	}
 
-		reader := bufio.NewReader(conn)
-		response, err := reader.ReadString('\n')
+	if *revokeHost != "" {
+		response, err := ipc.SendCommand(fmt.Sprintf("revoke-unblock:%s", strings.TrimSpace(*revokeHost)))
 		if err != nil {
-			log.Fatalf("Failed to read response: %v", err)
+			return fail(err)
 		}
-
-		log.Printf("Response: %s", strings.TrimSpace(response))
-		return
+		log.Printf("Response: %s", response)
+		return cli.ExitOK
 	}
 
 	if *addKeyword != "" {
-		conn, err := net.Dial("unix", ipc.SocketPath)
+		response, err := ipc.SendCommand(fmt.Sprintf("add-keyword:%s", *addKeyword))
 		if err != nil {
-			log.Fatalf("Failed to connect to glocker service: %v", err)
+			return fail(err)
 		}
-		defer conn.Close()
-
-		message := fmt.Sprintf("add-keyword:%s\n", *addKeyword)
-		conn.Write([]byte(message))
+		log.Printf("Response: %s", response)
+		log.Println("Keywords will be added to both URL and content keyword lists.")
+		return cli.ExitOK
+	}
 
-		reader := bufio.NewReader(conn)
-		response, err := reader.ReadString('\n')
+	if *enableCategory != "" || *disableCategory != "" {
+		command := "enable-category:" + *enableCategory
+		if *disableCategory != "" {
+			// Parse format: "name:reason"
+			name, reason, _ := strings.Cut(*disableCategory, ":")
+			if strings.TrimSpace(reason) == "" {
+				return fail(cli.NewExitError(cli.ExitValidation, "ERROR: Reason required. Use format: 'name:reason'"))
+			}
+			command = fmt.Sprintf("disable-category:%s:%s", strings.TrimSpace(name), strings.TrimSpace(reason))
+		}
+		response, err := ipc.SendCommand(command)
 		if err != nil {
-			log.Fatalf("Failed to read response: %v", err)
+			return fail(err)
 		}
-
-		log.Printf("Response: %s", strings.TrimSpace(response))
-		log.Println("Keywords will be added to both URL and content keyword lists.")
-		return
+		log.Printf("Response: %s", response)
+		return cli.ExitOK
 	}
 
 	if *panicMinutes > 0 {
-		conn, err := net.Dial("unix", ipc.SocketPath)
+		response, err := ipc.SendCommand(fmt.Sprintf("panic:%d", *panicMinutes))
 		if err != nil {
-			log.Fatalf("Failed to connect to glocker service: %v", err)
+			return fail(err)
 		}
-		defer conn.Close()
-
-		message := fmt.Sprintf("panic:%d\n", *panicMinutes)
-		conn.Write([]byte(message))
+		log.Printf("%s", response)
+		return cli.ExitOK
+	}
 
-		reader := bufio.NewReader(conn)
-		response, err := reader.ReadString('\n')
+	if *pauseMinutes > 0 {
+		quote := mindfulQuote()
+		response, err := ipc.RequestPause(*pauseMinutes, func(delay time.Duration, challenge string) (string, error) {
+			return answerPauseChallenge(delay, challenge, quote)
+		})
 		if err != nil {
-			log.Fatalf("Failed to read response: %v", err)
+			return fail(err)
 		}
+		log.Printf("Response: %s", response)
+		return cli.ExitOK
+	}
 
-		log.Printf("%s", strings.TrimSpace(response))
-		return
+	if *focusFor != "" {
+		duration, err := cli.ParseFocusDuration(*focusFor)
+		if err != nil {
+			return fail(cli.NewExitError(cli.ExitValidation, "%w", err))
+		}
+		response, err := ipc.SendCommand(fmt.Sprintf("focus:%s", duration))
+		if err != nil {
+			return fail(err)
+		}
+		log.Printf("Response: %s", response)
+		return cli.ExitOK
 	}
 
 	if *lockFlag {
-		conn, err := net.Dial("unix", ipc.SocketPath)
+		response, err := ipc.SendCommand("lock")
 		if err != nil {
-			log.Fatalf("Failed to connect to glocker service: %v", err)
+			return fail(err)
 		}
-		defer conn.Close()
-
-		conn.Write([]byte("lock\n"))
+		log.Printf("Response: %s", response)
+		return cli.ExitOK
+	}
 
-		reader := bufio.NewReader(conn)
-		response, err := reader.ReadString('\n')
+	if *lockScreenFlag {
+		response, err := ipc.SendCommand("lock-screen")
 		if err != nil {
-			log.Fatalf("Failed to read response: %v", err)
+			return fail(err)
 		}
-
-		log.Printf("Response: %s", strings.TrimSpace(response))
-		return
+		log.Printf("Response: %s", response)
+		return cli.ExitOK
 	}
 
 	// Handle status command (try socket first, only load config if needed)
 	if *statusFlag {
+		command := "status"
+		if *jsonFlag {
+			command = "status-json"
+		}
+
 		// Try to get live status from socket first
-		if _, err := os.Stat(ipc.SocketPath); err == nil {
-			conn, err := net.Dial("unix", ipc.SocketPath)
-			if err == nil {
-				defer conn.Close()
-
-				conn.Write([]byte("status\n"))
-
-				scanner := bufio.NewScanner(conn)
-				for scanner.Scan() {
-					line := scanner.Text()
-					if line == "END" {
-						break
-					}
+		if _, err := os.Stat(ipc.SocketPath()); err == nil && !configSet {
+			if lines, err := ipc.SendMultilineCommand(command); err == nil {
+				for _, line := range lines {
 					fmt.Println(line)
 				}
-				return
+				return cli.ExitOK
 			}
 		}
 
 		// Socket not available, need to load config for static status
 		cfg, err := config.LoadConfig()
 		if err != nil {
-			log.Fatalf("Failed to load config: %v", err)
+			return fail(cli.NewExitError(cli.ExitValidation, "Failed to load config: %v", err))
 		}
 		config.SetupLogging(cfg)
 
-		log.Println("(Service not running - showing configuration only)")
+		if *jsonFlag {
+			fmt.Print(strings.TrimSuffix(cli.GetStatusJSONResponse(cfg), "END\n"))
+			return cli.ExitOK
+		}
+		if configSet {
+			log.Printf("(Showing configuration from %s)", config.ConfigPath())
+		} else {
+			log.Println("(Service not running - showing configuration only)")
+		}
 		response := cli.GetStatusResponse(cfg)
 		fmt.Print(response)
-		return
+		return cli.ExitOK
 	}
 
 	// Handle info command
 	if *infoFlag {
+		command := "info"
+		if *jsonFlag {
+			command = "info-json"
+		}
+
 		// Try to get info from socket first
-		if _, err := os.Stat(ipc.SocketPath); err == nil {
-			conn, err := net.Dial("unix", ipc.SocketPath)
-			if err == nil {
-				defer conn.Close()
-
-				conn.Write([]byte("info\n"))
-
-				scanner := bufio.NewScanner(conn)
-				for scanner.Scan() {
-					line := scanner.Text()
-					if line == "END" {
-						break
-					}
+		if _, err := os.Stat(ipc.SocketPath()); err == nil && !configSet {
+			if lines, err := ipc.SendMultilineCommand(command); err == nil {
+				for _, line := range lines {
 					fmt.Println(line)
 				}
-				return
+				return cli.ExitOK
 			}
 		}
 
 		// Socket not available, need to load config for static info
 		cfg, err := config.LoadConfig()
 		if err != nil {
-			log.Fatalf("Failed to load config: %v", err)
+			return fail(cli.NewExitError(cli.ExitValidation, "Failed to load config: %v", err))
 		}
 		config.SetupLogging(cfg)
 
-		log.Println("(Service not running - showing configuration only)")
+		if *jsonFlag {
+			fmt.Print(strings.TrimSuffix(cli.GetInfoJSONResponse(cfg), "END\n"))
+			return cli.ExitOK
+		}
+		if configSet {
+			log.Printf("(Showing configuration from %s)", config.ConfigPath())
+		} else {
+			log.Println("(Service not running - showing configuration only)")
+		}
 		response := cli.GetInfoResponse(cfg)
 		fmt.Print(response)
-		return
+		return cli.ExitOK
 	}
 
-	// Handle default behavior (no flags) - show status or help
-	if flag.NFlag() == 0 {
+	// Handle default behavior (no flags other than -json and -config) - show status or help
+	commandFlags := flags.NFlag()
+	if *jsonFlag {
+		commandFlags--
+	}
+	if configSet {
+		commandFlags--
+	}
+	if commandFlags == 0 {
 		// Check if socket exists and daemon is running
-		if _, err := os.Stat(ipc.SocketPath); err == nil {
-			conn, err := net.Dial("unix", ipc.SocketPath)
-			if err == nil {
-				defer conn.Close()
-
+		if _, err := os.Stat(ipc.SocketPath()); err == nil {
+			if lines, err := ipc.SendMultilineCommand("status"); err == nil {
 				log.Println("=== LIVE STATUS ===")
-				conn.Write([]byte("status\n"))
-
-				// Read response until END
-				scanner := bufio.NewScanner(conn)
-				for scanner.Scan() {
-					line := scanner.Text()
-					if line == "END" {
-						break
-					}
+				for _, line := range lines {
 					fmt.Println(line)
 				}
-				return
+				return cli.ExitOK
 			}
 		}
 
 		// Socket not available, show help
-		fmt.Println("Glocker - Domain and System Access Control")
-		fmt.Println()
-		fmt.Println("Usage:")
-		flag.PrintDefaults()
-		return
+		flags.SetOutput(os.Stdout)
+		flags.Usage()
+		return cli.ExitOK
 	}
 
 	// Daemon mode (started by systemd or manually with -daemon)
 	if !*daemonFlag {
-		log.Fatal("No matching command. Use -h for help, or -daemon to start the daemon.")
+		return fail(cli.NewExitError(cli.ExitValidation, "No matching command. Use -h for help, or -daemon to start the daemon."))
+	}
+	if !runningAsRoot() {
+		return fail(cli.NewExitError(cli.ExitPermission, "The daemon must be run as root (it is started by the glocker service)"))
 	}
 
 	// Load configuration
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+		return fail(cli.NewExitError(cli.ExitValidation, "Failed to load config: %v", err))
 	}
 
 	// Setup logging
 	config.SetupLogging(cfg)
 
+	// Validate configuration before enforcing anything
+	if err := config.ValidateConfig(cfg); err != nil {
+		return fail(cli.NewExitError(cli.ExitValidation, "Invalid config: %v", err))
+	}
+	for _, warning := range config.Warnings(cfg) {
+		log.Printf("Warning: %s", warning)
+	}
+	if cfg.MindfulQuotesFile != "" {
+		if quotes, err := config.LoadMindfulQuotes(cfg.MindfulQuotesFile); err != nil {
+			log.Printf("Warning: %v - no quotes will be shown during the mindful delay", err)
+		} else {
+			log.Printf("Loaded %d mindful quotes from %s", len(quotes), cfg.MindfulQuotesFile)
+		}
+	}
+
 	log.Println("Starting glocker daemon...")
 
+	// Record blocks, unblocks, violations and other events as JSON lines for glockpeek
+	audit.Enable(audit.DefaultLogPath, cfg.LogMaxBytes(), cfg.LogRotatedFiles())
+
+	// Find out which external tools are available before relying on them
+	enforcement.CheckCapabilities(cfg)
+
+	// Keep the runtime overlay immutable between glocker's own writes
+	config.SetOverlayImmutable(enforcement.GetCapabilities().Chattr)
+
+	// Restore today's unblock grants so restarting doesn't reset the daily limit
+	if cfg.Unblocking.MaxPerDay > 0 {
+		if err := state.LoadUnblockGrants(cfg.Unblocking.UnblockStateFile()); err != nil {
+			log.Printf("Failed to load unblock grants: %v", err)
+		}
+	}
+
+	// Restore when blocks were added so restarting doesn't end new_block_cooldown
+	if cfg.Unblocking.NewBlockCooldown > 0 {
+		if err := state.LoadBlockAddedTimes(config.BlockAddedStateFile); err != nil {
+			log.Printf("Failed to load block add times: %v", err)
+		}
+	}
+
 	// Setup IPC socket
 	if err := ipc.SetupCommunication(cfg); err != nil {
-		log.Fatalf("Failed to setup IPC: %v", err)
+		return fail(fmt.Errorf("Failed to setup IPC: %w", err))
+	}
+	if err := ipc.SetupObserverSocket(cfg); err != nil {
+		log.Printf("Warning: %v", err)
 	}
+	monitoring.SetScreenLocker(ipc.LockScreen)
 
 	// Start monitoring goroutines
 	if cfg.TamperDetection.Enabled {
@@ -399,6 +635,8 @@ func main() {
 		}()
 	}
 
+	go monitoring.MonitorResources()
+
 	if cfg.ForbiddenPrograms.Enabled {
 		go monitoring.MonitorForbiddenPrograms(cfg)
 	}
@@ -411,22 +649,81 @@ func main() {
 		go monitoring.MonitorPanicMode(cfg)
 	}
 
+	if len(cfg.PanicSchedule) > 0 {
+		go monitoring.MonitorPanicSchedule(cfg)
+	}
+
 	if cfg.Accountability.DailyReportEnabled {
 		go monitoring.MonitorDailyReport(cfg)
 	}
 
+	if cfg.Accountability.WeeklyReportEnabled {
+		go monitoring.MonitorWeeklyReport(cfg)
+	}
+
+	if cfg.Unblocking.ExpiryWarningSeconds > 0 {
+		go enforcement.MonitorUnblockExpiry(cfg)
+	}
+
+	if len(cfg.RemoteBlocklists) > 0 {
+		go enforcement.MonitorRemoteBlocklists(cfg)
+	}
+
 	// Start web tracking server
 	if cfg.WebTracking.Enabled || cfg.ContentMonitoring.Enabled {
 		go web.StartWebTrackingServer(cfg)
 	}
 
+	// Under systemd with WatchdogSec= set, ping the watchdog from the main loop. If
+	// an enforcement cycle hangs the pings stop and systemd restarts the daemon.
+	// The watchdog runs from process start, so it is armed before the initial
+	// enforcement, which can take longer than the timeout on a big config.
+	var watchdog <-chan time.Time
+	watchdogTimeout := utils.WatchdogTimeout()
+	if watchdogTimeout > 0 {
+		watchdogTicker := time.NewTicker(watchdogTimeout / 2)
+		defer watchdogTicker.Stop()
+		watchdog = watchdogTicker.C
+		log.Printf("Systemd watchdog enabled (timeout %v)", watchdogTimeout)
+		pingWatchdog()
+	}
+
 	// Initial enforcement - build hosts file and store state
 	log.Println("Performing initial enforcement...")
+	stopPings := pingWatchdogDuring(watchdogTimeout)
 	enforcement.InitialEnforcement(cfg)
+	stopPings()
 
-	// Main enforcement loop - only check for changes
-	ticker := time.NewTicker(time.Duration(cfg.EnforceInterval) * time.Second)
+	// Check that a sample of the blocks really works, now and periodically
+	if cfg.SelfTest.Enabled {
+		go enforcement.MonitorSelfTest(cfg)
+	}
+
+	// Main enforcement loop - only check for changes. A reload can change the
+	// interval, so the ticker is reset after each one.
+	interval := time.Duration(cfg.EnforceInterval) * time.Second
+	ticker := utils.NewTicker(interval)
 	defer ticker.Stop()
+	reloads := make(chan struct{}, 1)
+	state.AddReloadListener(reloads)
+
+	// Check right away when an enforced file changes, so an edit can't be used
+	// and reverted between two ticks. The ticker stays as a backstop. Bursts of
+	// events collapse into one pending check.
+	fileChanges := make(chan string, 1)
+	if files := enforcement.WatchedFiles(cfg); len(files) > 0 {
+		watcher, err := monitoring.WatchFiles(files, func(path string) {
+			select {
+			case fileChanges <- path:
+			default:
+			}
+		})
+		if err != nil {
+			log.Printf("Warning: couldn't watch enforced files, relying on periodic checks: %v", err)
+		} else {
+			defer watcher.Close()
+		}
+	}
 
 	// Setup signal handling
 	sigChan := make(chan os.Signal, 1)
@@ -436,11 +733,112 @@ func main() {
 
 	for {
 		select {
-		case <-ticker.C:
+		case <-ticker.Chan():
+			enforcement.EnforcementCheck(cfg)
+			web.CheckKeywordCategories(cfg, time.Now())
+			if watchdog != nil {
+				pingWatchdog()
+			}
+		case path := <-fileChanges:
+			log.Printf("Enforced file changed: %s - checking now", path)
 			enforcement.EnforcementCheck(cfg)
+		case <-watchdog:
+			pingWatchdog()
+		case <-reloads:
+			if next := utils.ResetTicker(ticker, interval, time.Duration(cfg.EnforceInterval)*time.Second); next != interval {
+				log.Printf("Enforcement interval changed from %v to %v", interval, next)
+				interval = next
+			}
 		case sig := <-sigChan:
 			log.Printf("Received signal %v, shutting down...", sig)
-			return
+			if !notify.WaitForEmails(emailShutdownTimeout) {
+				log.Printf("Gave up waiting for queued emails after %v", emailShutdownTimeout)
+			}
+			return cli.ExitOK
+		}
+	}
+}
+
+// pingWatchdog tells systemd the daemon is still healthy.
+func pingWatchdog() {
+	if _, err := utils.SdNotify("WATCHDOG=1"); err != nil {
+		log.Printf("Failed to ping systemd watchdog: %v", err)
+	}
+}
+
+// pingWatchdogDuring keeps pinging the systemd watchdog every half timeout until
+// the returned function is called, for startup work with no loop to ping from.
+// Does nothing when the watchdog isn't enabled.
+func pingWatchdogDuring(timeout time.Duration) (stop func()) {
+	if timeout <= 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(timeout / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				pingWatchdog()
+			case <-done:
+				return
+			}
 		}
+	}()
+	return func() { close(done) }
+}
+
+// mindfulQuote returns a random quote from mindful_quotes_file, or "" if none is
+// configured or it can't be read.
+func mindfulQuote() string {
+	cfg, err := config.LoadConfig()
+	if err != nil || cfg.MindfulQuotesFile == "" {
+		return ""
+	}
+	quotes, err := config.LoadMindfulQuotes(cfg.MindfulQuotesFile)
+	if err != nil {
+		return ""
 	}
+	return config.RandomQuote(quotes)
+}
+
+// answerPauseChallenge shows quote, counts down the mindful delay and then asks
+// for the challenge text.
+func answerPauseChallenge(delay time.Duration, challenge, quote string) (string, error) {
+	if quote != "" {
+		fmt.Printf("\n%s\n\n", quote)
+	}
+	for remaining := int(delay / time.Second); remaining > 0; remaining-- {
+		fmt.Printf("\rTake a moment before pausing... %3ds", remaining)
+		time.Sleep(time.Second)
+	}
+	if delay > 0 {
+		fmt.Println()
+	}
+
+	fmt.Println("Type the following text to pause blocking:")
+	fmt.Printf("  %s\n> ", challenge)
+	typed, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("failed to read challenge answer: %w", err)
+	}
+	return typed, nil
+}
+
+// printUsage prints the help text for -h: the flags and the exit codes.
+func printUsage(flags *flag.FlagSet) {
+	out := flags.Output()
+	fmt.Fprintln(out, "Glocker - Domain and System Access Control")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Usage:")
+	flags.PrintDefaults()
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Exit codes:")
+	fmt.Fprintf(out, "  %d  success\n", cli.ExitOK)
+	fmt.Fprintf(out, "  %d  any other failure\n", cli.ExitFailure)
+	fmt.Fprintf(out, "  %d  the daemon isn't running or its socket can't be reached\n", cli.ExitDaemonDown)
+	fmt.Fprintf(out, "  %d  the daemon rejected the request (invalid reason, permanently blocked, limit reached)\n", cli.ExitRejected)
+	fmt.Fprintf(out, "  %d  invalid arguments or configuration\n", cli.ExitValidation)
+	fmt.Fprintf(out, "  %d  the command must be run as root\n", cli.ExitPermission)
 }
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"glocker/internal/cli"
+	"glocker/internal/config"
+)
+
+func TestRun_ExitCodes(t *testing.T) {
+	dir := t.TempDir()
+	socketPath := filepath.Join(dir, "glocker.sock")
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte("ipc:\n  socket_path: "+socketPath+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	origRoot := runningAsRoot
+	t.Cleanup(func() { runningAsRoot = origRoot })
+	runningAsRoot = func() bool { return false }
+
+	// -config only goes with the read-only display commands, so the socket
+	// commands are pointed at the test config directly
+	t.Cleanup(func() { config.SetConfigPath(config.GlockerConfigFile) })
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"version", []string{"-version"}, cli.ExitOK},
+		{"help", []string{"-h"}, cli.ExitOK},
+		{"unknown flag", []string{"-no-such-flag"}, cli.ExitValidation},
+		{"until without unblock", []string{"-until", "17:00"}, cli.ExitValidation},
+		{"unblock without reason", []string{"-unblock", "example.com"}, cli.ExitValidation},
+		{"simulate", []string{"-simulate", "2024-06-11 15:00", "-config", configPath}, cli.ExitOK},
+		{"simulate bad time", []string{"-simulate", "next tuesday"}, cli.ExitValidation},
+		{"missing config", []string{"-simulate", "2024-06-11 15:00", "-config", filepath.Join(dir, "missing.yaml")}, cli.ExitValidation},
+		{"config with daemon", []string{"-daemon", "-config", configPath}, cli.ExitValidation},
+		{"config with socket command", []string{"-reload", "-config", configPath}, cli.ExitValidation},
+		{"config with export", []string{"-export-config", "-config", configPath}, cli.ExitValidation},
+		{"daemon as user", []string{"-daemon"}, cli.ExitPermission},
+		{"uninstall as user", []string{"-uninstall", "done"}, cli.ExitPermission},
+		{"daemon down", []string{"-reload"}, cli.ExitDaemonDown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.SetConfigPath(configPath)
+			if got := run(tt.args); got != tt.want {
+				t.Errorf("run(%v) = %d, want %d", tt.args, got, tt.want)
+			}
+		})
+	}
+
+	// A daemon answering "ERROR: ..." rejected the request
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if _, err := bufio.NewReader(conn).ReadString('\n'); err == nil {
+			conn.Write([]byte("ERROR: invalid reason\n"))
+		}
+	}()
+
+	config.SetConfigPath(configPath)
+	if got := run([]string{"-unblock", "example.com:boredom"}); got != cli.ExitRejected {
+		t.Errorf("rejected unblock = %d, want %d", got, cli.ExitRejected)
+	}
+}
+
+func TestPrintUsage_ListsExitCodes(t *testing.T) {
+	var b strings.Builder
+	flags := flag.NewFlagSet("glocker", flag.ContinueOnError)
+	flags.SetOutput(&b)
+	flags.Bool("daemon", false, "Run as daemon")
+	printUsage(flags)
+
+	for _, want := range []string{"Usage:", "-daemon", "Exit codes:", "2  the daemon isn't running", "5  the command must be run as root"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("usage should contain %q, got:\n%s", want, b.String())
+		}
+	}
+}
@@ -11,6 +11,9 @@
 //	# Text-based lock using mindful_text from config
 //	glocklock -mindful
 //
+//	# Math lock: solve 5 problems (or violation_tracking.math_problems from config)
+//	glocklock -math 5 -difficulty hard
+//
 //	# Text-based lock from file
 //	glocklock -text /path/to/file.txt
 //
@@ -39,6 +42,8 @@ func main() {
 	message := flag.String("message", "Screen locked", "Message to display")
 	textFile := flag.String("text", "", "Path to text file (enables text-based lock)")
 	mindful := flag.Bool("mindful", false, "Use mindful_text from config for text-based lock")
+	mathProblems := flag.Int("math", 0, "Number of arithmetic problems to solve to unlock (overrides config math_problems)")
+	difficulty := flag.String("difficulty", "", "Math problem difficulty: easy, medium or hard (overrides config math_difficulty)")
 	flag.Parse()
 
 	// Load config (errors are non-fatal, we just use defaults)
@@ -89,6 +94,44 @@ func main() {
 		return
 	}
 
+	// Math lock from the flag, or from config when no other lock mode was asked for
+	problems := *mathProblems
+	if problems == 0 && cfg != nil && !*mindful && *textFile == "" && *duration == 0 {
+		problems = cfg.ViolationTracking.MathProblems
+	}
+	if problems > 0 {
+		difficultyName := *difficulty
+		if difficultyName == "" && cfg != nil {
+			difficultyName = cfg.ViolationTracking.MathDifficulty
+		}
+		mathDifficulty, err := lock.ParseMathDifficulty(difficultyName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("Locking screen until %d math problems are solved...\n", problems)
+		fmt.Println("A wrong answer replaces all problems with new ones.")
+
+		locker, err := lock.NewMathLocker(lock.MathLockConfig{
+			Problems:        problems,
+			Difficulty:      mathDifficulty,
+			BackgroundImage: backgroundImage,
+		})
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating math locker: %v\n", err)
+			os.Exit(1)
+		}
+
+		if err := locker.Lock(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error locking screen: %v\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Println("Screen unlocked.")
+		return
+	}
+
 	// Text-based lock from file
 	if *textFile != "" {
 		fmt.Printf("Locking screen until text from %s is typed...\n", *textFile)
@@ -4,10 +4,15 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"slices"
 	"strings"
+	"syscall"
 	"time"
 
+	"glocker/internal/config"
 	"glocker/internal/reports"
 )
 
@@ -19,18 +24,81 @@ const (
 	colorYellow  = "\033[93m"
 	colorDim     = "\033[2m"
 	colorInverse = "\033[7m"
+	clearScreen  = "\033[H\033[2J"
 	barChar      = "⣿"
 )
 
+// weekdayFlag collects repeatable -weekday values.
+type weekdayFlag []time.Weekday
+
+func (w *weekdayFlag) String() string {
+	names := make([]string, len(*w))
+	for i, day := range *w {
+		names[i] = day.String()[:3]
+	}
+	return strings.Join(names, ",")
+}
+
+func (w *weekdayFlag) Set(value string) error {
+	for _, name := range strings.Split(value, ",") {
+		day, err := reports.ParseWeekday(name)
+		if err != nil {
+			return err
+		}
+		if !slices.Contains(*w, day) {
+			*w = append(*w, day)
+		}
+	}
+	return nil
+}
+
+// stringListFlag collects repeatable, comma-separated string values.
+type stringListFlag []string
+
+func (l *stringListFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringListFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" && !slices.Contains(*l, item) {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// exclusions are the -exclude-domain and -exclude-keyword values, applied to every view.
+type exclusions struct {
+	domains  []string
+	keywords []string
+}
+
+// active reports whether any exclusions were given.
+func (x exclusions) active() bool {
+	return len(x.domains) > 0 || len(x.keywords) > 0
+}
+
 func main() {
+	var weekdays weekdayFlag
+	var excludeDomains, excludeKeywords stringListFlag
 	summaryFlag := flag.Bool("summary", false, "Print summary statistics")
 	unblocksFlag := flag.Bool("unblocks", false, "Show unblocks summary")
 	violationsFlag := flag.Bool("violations", false, "Show violations summary")
+	cohortsFlag := flag.Bool("cohorts", false, "Compare weekday and weekend violations")
+	compareFlag := flag.String("compare", "", "Compare violations of two periods: -compare BEFORE AFTER (each YYYY, YYYY-MM or YYYY-MM-DD)")
 	topN := flag.Int("top", 5, "Number of top items to show")
 	fromDate := flag.String("from", "", "Start date (YYYY, YYYY-MM, or YYYY-MM-DD)")
 	toDate := flag.String("to", "", "End date (YYYY, YYYY-MM, or YYYY-MM-DD)")
-	periodDate := flag.String("period", "", "Show detailed logs for a period (YYYY-MM for month, YYYY-MM-DD for day)")
+	periodDate := flag.String("period", "", "Show detailed logs for a period (YYYY for year, YYYY-MM for month, YYYY-MM-DD for day, FROM:TO for a range)")
 	dailyDate := flag.String("daily", "", "Show daily email report format (YYYY-MM-DD, or 'yesterday')")
+	flag.Var(&weekdays, "weekday", "Only include entries on this weekday (Mon..Sun, repeatable)")
+	flag.Var(&excludeDomains, "exclude-domain", "Leave out entries for this domain and its subdomains (repeatable)")
+	flag.Var(&excludeKeywords, "exclude-keyword", "Leave out violations for this keyword (repeatable)")
+	watchFlag := flag.Bool("watch", false, "Redraw the summary every -interval seconds until Ctrl-C")
+	intervalSecs := flag.Int("interval", 5, "Seconds between -watch refreshes")
+	csvFlag := flag.Bool("csv", false, "Write raw entries as CSV to stdout (use with -violations or -unblocks)")
 
 	flag.Usage = func() {
 		fmt.Fprintf(os.Stderr, "glockpeek - peek at your glocker logs\n\n")
@@ -47,14 +115,27 @@ func main() {
 		fmt.Fprintf(os.Stderr, "  glockpeek -from 2024-06-15         Show from specific date\n")
 		fmt.Fprintf(os.Stderr, "  glockpeek -from 2024 -to 2024      Show only 2024\n")
 		fmt.Fprintf(os.Stderr, "  glockpeek -from 2024-01 -to 2024-06 Show Jan-Jun 2024\n")
+		fmt.Fprintf(os.Stderr, "  glockpeek -weekday Sat -weekday Sun Show weekends only\n")
+		fmt.Fprintf(os.Stderr, "  glockpeek -from 2024 -weekday Sat  Show Saturdays in 2024 onwards\n")
+		fmt.Fprintf(os.Stderr, "  glockpeek -cohorts -from 2024-06   Compare weekdays vs weekends\n")
+		fmt.Fprintf(os.Stderr, "  glockpeek -compare 2024-06 2024-07 Compare June with July\n")
+		fmt.Fprintf(os.Stderr, "  glockpeek -exclude-domain ads.example.com -exclude-keyword foo\n")
+		fmt.Fprintf(os.Stderr, "                                     Leave out noisy domains and keywords\n")
+		fmt.Fprintf(os.Stderr, "  glockpeek -violations -csv > v.csv Export violations as CSV\n")
+		fmt.Fprintf(os.Stderr, "  glockpeek -unblocks -csv -from 2024 Export 2024 unblocks as CSV\n")
+		fmt.Fprintf(os.Stderr, "  glockpeek -watch -interval 10      Redraw the summary every 10 seconds\n")
 		fmt.Fprintf(os.Stderr, "  glockpeek -period 2024-06-15       Show detailed logs for a day\n")
 		fmt.Fprintf(os.Stderr, "  glockpeek -period 2024-06          Show detailed logs for a month\n")
+		fmt.Fprintf(os.Stderr, "  glockpeek -period 2024             Show month by month for a year\n")
+		fmt.Fprintf(os.Stderr, "  glockpeek -period 2024-06:2024-08  Show month by month for a range\n")
 		fmt.Fprintf(os.Stderr, "  glockpeek -daily yesterday         Show daily report for yesterday\n")
 		fmt.Fprintf(os.Stderr, "  glockpeek -daily 2024-06-15        Show daily report for specific date\n")
 	}
 
 	flag.Parse()
 
+	excl := exclusions{domains: excludeDomains, keywords: excludeKeywords}
+
 	// Handle -daily flag (email report format preview)
 	if *dailyDate != "" {
 		var date time.Time
@@ -67,27 +148,61 @@ func main() {
 			fmt.Fprintf(os.Stderr, "Format must be YYYY-MM-DD or 'yesterday'\n")
 			os.Exit(1)
 		}
-		printDailyReport(date)
+		printDailyReport(date, excl)
 		return
 	}
 
-	// Handle -period flag (detailed view for day or month)
+	// Handle -period flag (detailed view for day, month, year or range)
 	if *periodDate != "" {
+		// Range of months (FROM:TO, each YYYY, YYYY-MM or YYYY-MM-DD)
+		if fromStr, toStr, ok := strings.Cut(*periodDate, ":"); ok {
+			start, errStart := parseDateStart(fromStr)
+			end, errEnd := parseDateEnd(toStr)
+			if errStart != nil || errEnd != nil || start.After(end) {
+				fmt.Fprintf(os.Stderr, "Error: invalid -period range %q\n", *periodDate)
+				fmt.Fprintf(os.Stderr, "Format must be FROM:TO with FROM before TO, e.g. 2024-06:2024-08\n")
+				os.Exit(1)
+			}
+			printPeriodDetails(start, end, fmt.Sprintf("PERIOD: %s to %s", fromStr, toStr), excl)
+			return
+		}
 		// Try day format first (YYYY-MM-DD)
 		if day, err := time.ParseInLocation("2006-01-02", *periodDate, time.Local); err == nil {
-			printDayDetails(day)
+			printDayDetails(day, excl)
 			return
 		}
 		// Try month format (YYYY-MM)
 		if month, err := time.ParseInLocation("2006-01", *periodDate, time.Local); err == nil {
-			printMonthDetails(month)
+			printMonthDetails(month, excl)
+			return
+		}
+		// Try year format (YYYY)
+		if year, err := time.ParseInLocation("2006", *periodDate, time.Local); err == nil {
+			printPeriodDetails(year, year.AddDate(1, 0, 0).Add(-time.Second), fmt.Sprintf("YEARLY LOG: %d", year.Year()), excl)
 			return
 		}
 		fmt.Fprintf(os.Stderr, "Error: invalid -period date %q\n", *periodDate)
-		fmt.Fprintf(os.Stderr, "Format must be YYYY-MM (month) or YYYY-MM-DD (day)\n")
+		fmt.Fprintf(os.Stderr, "Format must be YYYY (year), YYYY-MM (month), YYYY-MM-DD (day) or FROM:TO (range)\n")
 		os.Exit(1)
 	}
 
+	// Handle -compare flag (two periods side by side)
+	if *compareFlag != "" {
+		before, errBefore := parseComparePeriod(*compareFlag)
+		if flag.NArg() != 1 || errBefore != nil {
+			fmt.Fprintf(os.Stderr, "Error: -compare needs two periods, e.g. -compare 2024-06 2024-07\n")
+			os.Exit(1)
+		}
+		after, errAfter := parseComparePeriod(flag.Arg(0))
+		if errAfter != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid -compare period %q\n", flag.Arg(0))
+			fmt.Fprintf(os.Stderr, "Supported formats: YYYY, YYYY-MM, YYYY-MM-DD\n")
+			os.Exit(1)
+		}
+		printPeriodComparison(os.Stdout, before, after, *topN, excl)
+		return
+	}
+
 	// Parse and validate dates
 	var from, to *time.Time
 	if *fromDate != "" {
@@ -115,26 +230,136 @@ func main() {
 		os.Exit(1)
 	}
 
+	// Handle -csv flag (raw rows instead of summaries)
+	if *csvFlag {
+		if *unblocksFlag == *violationsFlag {
+			fmt.Fprintf(os.Stderr, "Error: -csv requires exactly one of -violations or -unblocks\n")
+			os.Exit(1)
+		}
+		var err error
+		if *unblocksFlag {
+			err = writeUnblocksCSV(from, to, weekdays, excl)
+		} else {
+			err = writeViolationsCSV(from, to, weekdays, excl)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Handle -cohorts flag (weekday vs weekend comparison)
+	if *cohortsFlag {
+		printCohortComparison(*topN, from, to, excl)
+		return
+	}
+
 	// Default to summary (violations only) if no specific flag
 	if !*summaryFlag && !*unblocksFlag && !*violationsFlag {
 		*summaryFlag = true
 	}
 
-	showUnblocks := *unblocksFlag
-	showViolations := *summaryFlag || *violationsFlag
+	sel := summarySelection{
+		unblocks:   *unblocksFlag,
+		violations: *summaryFlag || *violationsFlag,
+		topN:       *topN,
+		from:       from,
+		to:         to,
+		weekdays:   weekdays,
+		excl:       excl,
+		categories: loadDomainCategories(),
+	}
 
-	if showUnblocks {
-		printUnblocksSummary(*topN, from, to)
+	// Handle -watch flag (redraw the summaries until interrupted)
+	if *watchFlag {
+		if *intervalSecs < 1 {
+			fmt.Fprintf(os.Stderr, "Error: -interval must be at least 1 second\n")
+			os.Exit(1)
+		}
+		watchSummaries(sel, time.Duration(*intervalSecs)*time.Second)
+		return
 	}
 
-	if showViolations {
-		if showUnblocks {
+	renderSummaries(os.Stdout, sel)
+}
+
+// summarySelection is which summaries to show and the filters applied to them.
+type summarySelection struct {
+	unblocks   bool
+	violations bool
+	topN       int
+	from, to   *time.Time
+	weekdays   []time.Weekday
+	excl       exclusions
+	categories map[string]string // Domain categories from the config, empty if none are set
+}
+
+// renderSummaries writes the selected summaries to w.
+func renderSummaries(w io.Writer, sel summarySelection) {
+	if sel.unblocks {
+		printUnblocksSummary(w, sel.topN, sel.from, sel.to, sel.weekdays, sel.excl, sel.categories)
+	}
+
+	if sel.violations {
+		if sel.unblocks {
+			fmt.Fprintln(w)
+		}
+		printViolationsSummary(w, sel.topN, sel.from, sel.to, sel.weekdays, sel.excl, sel.categories)
+	}
+}
+
+// watchSummaries redraws the selected summaries every interval, re-reading the
+// logs each time, until interrupted.
+func watchSummaries(sel summarySelection, interval time.Duration) {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		var body strings.Builder
+		renderSummaries(&body, sel)
+		fmt.Print(renderWatchFrame(time.Now(), interval, body.String()))
+
+		select {
+		case <-sigs:
 			fmt.Println()
+			return
+		case <-ticker.C:
 		}
-		printViolationsSummary(*topN, from, to)
 	}
 }
 
+// renderWatchFrame returns one -watch frame: a clear-screen sequence, a header
+// with the refresh time and interval, then body.
+func renderWatchFrame(now time.Time, interval time.Duration, body string) string {
+	var b strings.Builder
+	b.WriteString(clearScreen)
+	fmt.Fprintf(&b, "%sglockpeek - updated %s, every %s (Ctrl-C to quit)%s\n\n",
+		colorDim, now.Format("2006-01-02 15:04:05"), interval, colorReset)
+	b.WriteString(body)
+	return b.String()
+}
+
+// skippedWarned is set once the user has been warned about unparseable lines in
+// the reports log, so views that load it several times only warn once.
+var skippedWarned bool
+
+// loadReports loads the keyword reports, warning on stderr when lines of the
+// reports log had to be skipped.
+func loadReports() ([]reports.ReportEntry, error) {
+	entries, skipped, err := reports.LoadReports()
+	if skipped > 0 && !skippedWarned {
+		skippedWarned = true
+		fmt.Fprintf(os.Stderr, "%sWarning: skipped %d malformed line(s) in %s%s\n",
+			colorYellow, skipped, reports.DefaultReportsLogPath, colorReset)
+	}
+	return entries, err
+}
+
 // parseDateStart parses a date string and returns the start of that period.
 // Supports: YYYY, YYYY-MM, YYYY-MM-DD
 func parseDateStart(s string) (time.Time, error) {
@@ -174,65 +399,89 @@ func parseDateEnd(s string) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("invalid date format")
 }
 
-func printUnblocksSummary(topN int, from, to *time.Time) {
-	fmt.Println("╔════════════════════════════════════════════════╗")
-	fmt.Println("║              UNBLOCKS SUMMARY                  ║")
-	fmt.Println("╚════════════════════════════════════════════════╝")
+// writeUnblocksCSV writes the filtered unblock entries to stdout as CSV.
+func writeUnblocksCSV(from, to *time.Time, weekdays []time.Weekday, excl exclusions) error {
+	entries, err := reports.LoadUnblocks()
+	if err != nil {
+		return fmt.Errorf("reading unblocks log: %w", err)
+	}
+	entries = reports.FilterUnblocks(entries, reports.UnblockFilter{
+		StartTime:      from,
+		EndTime:        to,
+		Weekdays:       weekdays,
+		ExcludeDomains: excl.domains,
+	})
+	return reports.WriteUnblocksCSV(os.Stdout, entries)
+}
 
-	entries, err := reports.ParseUnblocksLog("")
+// writeViolationsCSV writes the filtered violation entries to stdout as CSV.
+func writeViolationsCSV(from, to *time.Time, weekdays []time.Weekday, excl exclusions) error {
+	entries, err := loadReports()
 	if err != nil {
-		fmt.Printf("\nError reading unblocks log: %v\n", err)
+		return fmt.Errorf("reading reports log: %w", err)
+	}
+	entries = reports.FilterReports(entries, reports.ReportFilter{
+		StartTime:       from,
+		EndTime:         to,
+		Weekdays:        weekdays,
+		ExcludeDomains:  excl.domains,
+		ExcludeKeywords: excl.keywords,
+	})
+	return reports.WriteReportsCSV(os.Stdout, entries)
+}
+
+func printUnblocksSummary(w io.Writer, topN int, from, to *time.Time, weekdays []time.Weekday, excl exclusions, categories map[string]string) {
+	fmt.Fprintln(w, "╔════════════════════════════════════════════════╗")
+	fmt.Fprintln(w, "║              UNBLOCKS SUMMARY                  ║")
+	fmt.Fprintln(w, "╚════════════════════════════════════════════════╝")
+
+	entries, err := reports.LoadUnblocks()
+	if err != nil {
+		fmt.Fprintf(w, "\nError reading unblocks log: %v\n", err)
 		return
 	}
 
-	// Apply date filter
-	if from != nil || to != nil {
+	// Apply date, weekday and exclusion filters
+	if from != nil || to != nil || len(weekdays) > 0 || excl.active() {
 		entries = reports.FilterUnblocks(entries, reports.UnblockFilter{
-			StartTime: from,
-			EndTime:   to,
+			StartTime:      from,
+			EndTime:        to,
+			Weekdays:       weekdays,
+			ExcludeDomains: excl.domains,
 		})
 	}
 
 	if len(entries) == 0 {
-		fmt.Println("\nNo unblock entries found.")
+		fmt.Fprintln(w, "\nNo unblock entries found.")
 		return
 	}
 
 	summary := reports.SummarizeUnblocks(entries)
 
-	fmt.Printf("\nTotal unblocks: %d\n", summary.TotalCount)
+	fmt.Fprintf(w, "\nTotal unblocks: %d\n", summary.TotalCount)
+	printWeekdayFilter(w, weekdays)
 	if summary.FirstEntry != nil && summary.LastEntry != nil {
-		fmt.Printf("Date range: %s to %s\n",
+		fmt.Fprintf(w, "Date range: %s to %s\n",
 			summary.FirstEntry.Format("2006-01-02"),
 			summary.LastEntry.Format("2006-01-02"))
 	}
 
 	// Time of day analysis
-	fmt.Println("\n── Time of Day ──")
+	fmt.Fprintln(w, "\n── Time of Day ──")
 	hourCounts := make(map[int]int)
 	for _, e := range entries {
 		hourCounts[e.UnblockTime.Hour()]++
 	}
-	printHourDistribution(hourCounts)
+	printHourDistribution(w, hourCounts)
 
 	// Top domains
-	fmt.Printf("\n── Top %d Domains ──\n", topN)
-	topDomains := reports.TopN(summary.ByDomain, topN)
-	maxLen := maxNameLen(topDomains)
-	domainCounts := make([]int, len(topDomains))
-	for i, item := range topDomains {
-		domainCounts[i] = item.Count
-	}
-	avgDomains := calcAverage(domainCounts)
-	for _, item := range topDomains {
-		bar := coloredBar(item.Count, topDomains[0].Count, avgDomains, 20)
-		fmt.Printf("  %-*s %3d %s\n", maxLen, item.Name, item.Count, bar)
-	}
+	fmt.Fprintf(w, "\n── Top %d Domains ──\n", topN)
+	printTopDomains(w, reports.TopN(summary.ByDomain, topN), categories)
 
 	// Reasons
-	fmt.Printf("\n── Reasons ──\n")
+	fmt.Fprintf(w, "\n── Reasons ──\n")
 	topReasons := reports.TopN(summary.ByReason, 10)
-	maxLen = maxNameLen(topReasons)
+	maxLen := maxNameLen(topReasons)
 	reasonCounts := make([]int, len(topReasons))
 	for i, item := range topReasons {
 		reasonCounts[i] = item.Count
@@ -240,62 +489,70 @@ func printUnblocksSummary(topN int, from, to *time.Time) {
 	avgReasons := calcAverage(reasonCounts)
 	for _, item := range topReasons {
 		bar := coloredBar(item.Count, topReasons[0].Count, avgReasons, 20)
-		fmt.Printf("  %-*s %3d %s\n", maxLen, item.Name, item.Count, bar)
+		fmt.Fprintf(w, "  %-*s %3d %s\n", maxLen, item.Name, item.Count, bar)
 	}
 
 	// Day of week
-	fmt.Println("\n── Day of Week ──")
+	fmt.Fprintln(w, "\n── Day of Week ──")
 	dayCounts := make(map[string]int)
 	for _, e := range entries {
 		dayCounts[e.UnblockTime.Weekday().String()]++
 	}
-	printDayDistribution(dayCounts)
+	printDayDistribution(w, dayCounts)
 }
 
-func printViolationsSummary(topN int, from, to *time.Time) {
-	fmt.Println("╔════════════════════════════════════════════════╗")
-	fmt.Println("║             VIOLATIONS SUMMARY                 ║")
-	fmt.Println("╚════════════════════════════════════════════════╝")
+func printViolationsSummary(w io.Writer, topN int, from, to *time.Time, weekdays []time.Weekday, excl exclusions, categories map[string]string) {
+	fmt.Fprintln(w, "╔════════════════════════════════════════════════╗")
+	fmt.Fprintln(w, "║             VIOLATIONS SUMMARY                 ║")
+	fmt.Fprintln(w, "╚════════════════════════════════════════════════╝")
 
-	entries, err := reports.ParseReportsLog("")
+	entries, err := loadReports()
 	if err != nil {
-		fmt.Printf("\nError reading reports log: %v\n", err)
+		fmt.Fprintf(w, "\nError reading reports log: %v\n", err)
 		return
 	}
 
-	// Apply date filter
-	if from != nil || to != nil {
+	// Apply date, weekday and exclusion filters
+	if from != nil || to != nil || len(weekdays) > 0 || excl.active() {
 		entries = reports.FilterReports(entries, reports.ReportFilter{
-			StartTime: from,
-			EndTime:   to,
+			StartTime:       from,
+			EndTime:         to,
+			Weekdays:        weekdays,
+			ExcludeDomains:  excl.domains,
+			ExcludeKeywords: excl.keywords,
 		})
 	}
 
 	if len(entries) == 0 {
-		fmt.Println("\nNo violation entries found.")
+		fmt.Fprintln(w, "\nNo violation entries found.")
 		return
 	}
 
 	summary := reports.SummarizeReports(entries)
 
-	fmt.Printf("\nTotal violations: %d\n", summary.TotalCount)
+	fmt.Fprintf(w, "\nTotal violations: %d\n", summary.TotalCount)
+	printWeekdayFilter(w, weekdays)
 	if summary.FirstEntry != nil && summary.LastEntry != nil {
-		fmt.Printf("Date range: %s to %s\n",
+		fmt.Fprintf(w, "Date range: %s to %s\n",
 			summary.FirstEntry.Format("2006-01-02"),
 			summary.LastEntry.Format("2006-01-02"))
 	}
 
+	// Trend over the selected range
+	fmt.Fprintln(w, "\n── Trend ──")
+	printViolationsTrend(w, entries, from, to, summary)
+
 	// By type
-	fmt.Println("\n── By Type ──")
-	fmt.Printf("  URL keyword:     %d\n", summary.ByType[reports.ReportTypeURL])
-	fmt.Printf("  Content keyword: %d\n", summary.ByType[reports.ReportTypeContent])
+	fmt.Fprintln(w, "\n── By Type ──")
+	fmt.Fprintf(w, "  URL keyword:     %d\n", summary.ByType[reports.ReportTypeURL])
+	fmt.Fprintf(w, "  Content keyword: %d\n", summary.ByType[reports.ReportTypeContent])
 
 	// Time of day analysis with top keyword per period
-	fmt.Println("\n── Time of Day ──")
-	printViolationsHourDistribution(entries)
+	fmt.Fprintln(w, "\n── Time of Day ──")
+	printViolationsHourDistribution(w, entries)
 
 	// Top keywords with most common time period
-	fmt.Printf("\n── Top %d Keywords ──\n", topN)
+	fmt.Fprintf(w, "\n── Top %d Keywords ──\n", topN)
 	keywordPeriods := buildKeywordPeriodMap(entries)
 	topKeywords := reports.TopN(summary.ByKeyword, topN)
 	maxLen := maxNameLen(topKeywords)
@@ -307,33 +564,290 @@ func printViolationsSummary(topN int, from, to *time.Time) {
 	for _, item := range topKeywords {
 		bar := coloredBar(item.Count, topKeywords[0].Count, avgKeywords, 20)
 		period := getTopPeriodForKeyword(keywordPeriods, item.Name)
-		fmt.Printf("  %-*s %3d %s (%s)\n", maxLen, item.Name, item.Count, bar, period)
+		fmt.Fprintf(w, "  %-*s %3d %s (%s)\n", maxLen, item.Name, item.Count, bar, period)
 	}
 
 	// Top domains
-	fmt.Printf("\n── Top %d Domains ──\n", topN)
-	topDomains := reports.TopN(summary.ByDomain, topN)
-	maxLen = maxNameLen(topDomains)
-	domainCounts := make([]int, len(topDomains))
-	for i, item := range topDomains {
-		domainCounts[i] = item.Count
-	}
-	avgDomains := calcAverage(domainCounts)
-	for _, item := range topDomains {
-		bar := coloredBar(item.Count, topDomains[0].Count, avgDomains, 20)
-		fmt.Printf("  %-*s %3d %s\n", maxLen, item.Name, item.Count, bar)
+	fmt.Fprintf(w, "\n── Top %d Domains ──\n", topN)
+	printTopDomains(w, reports.TopN(summary.ByDomain, topN), categories)
+
+	// By category, when the config categorizes domains
+	if len(categories) > 0 {
+		fmt.Fprintln(w, "\n── By Category ──")
+		byCategory := reports.TopN(reports.CountByCategory(summary.ByDomain, categories), 0)
+		maxLen = maxNameLen(byCategory)
+		categoryCounts := make([]int, len(byCategory))
+		for i, item := range byCategory {
+			categoryCounts[i] = item.Count
+		}
+		avgCategories := calcAverage(categoryCounts)
+		for _, item := range byCategory {
+			bar := coloredBar(item.Count, byCategory[0].Count, avgCategories, 20)
+			fmt.Fprintf(w, "  %-*s %3d %s\n", maxLen, item.Name, item.Count, bar)
+		}
 	}
 
 	// Day of week
-	fmt.Println("\n── Day of Week ──")
+	fmt.Fprintln(w, "\n── Day of Week ──")
 	dayCounts := make(map[string]int)
 	for _, e := range entries {
 		dayCounts[e.Timestamp.Weekday().String()]++
 	}
-	printDayDistribution(dayCounts)
+	printDayDistribution(w, dayCounts)
+}
+
+// printTopDomains prints domain counts with bars. When the config categorizes
+// domains, they are grouped under a heading per category.
+func printTopDomains(w io.Writer, items []reports.CountItem, categories map[string]string) {
+	if len(items) == 0 {
+		return
+	}
+	maxLen := maxNameLen(items)
+	counts := make([]int, len(items))
+	for i, item := range items {
+		counts[i] = item.Count
+	}
+	avg := calcAverage(counts)
+	printItem := func(indent string, item reports.CountItem) {
+		bar := coloredBar(item.Count, items[0].Count, avg, 20)
+		fmt.Fprintf(w, "%s%-*s %3d %s\n", indent, maxLen, item.Name, item.Count, bar)
+	}
+
+	if len(categories) == 0 {
+		for _, item := range items {
+			printItem("  ", item)
+		}
+		return
+	}
+	for _, group := range reports.GroupByCategory(items, categories) {
+		fmt.Fprintf(w, "  %s (%d)\n", group.Category, group.Count)
+		for _, item := range group.Items {
+			printItem("    ", item)
+		}
+	}
+}
+
+// loadDomainCategories returns the domain categories from the glocker config,
+// or nil if it can't be read.
+func loadDomainCategories() map[string]string {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil
+	}
+	return cfg.DomainCategories()
+}
+
+// printViolationsTrend shows violations per day, or per month for long ranges, as a
+// sparkline over the -from/-to range (the range of the entries if unset).
+func printViolationsTrend(w io.Writer, entries []reports.ReportEntry, from, to *time.Time, summary reports.ReportSummary) {
+	start, end := *summary.FirstEntry, *summary.LastEntry
+	if from != nil {
+		start = *from
+	}
+	if to != nil {
+		end = *to
+	}
+
+	buckets, monthly := reports.BucketTrend(entries, start, end)
+	counts := make([]int, len(buckets))
+	maxCount := 0
+	for i, bucket := range buckets {
+		counts[i] = bucket.Count
+		maxCount = max(maxCount, bucket.Count)
+	}
+
+	layout, unit := "2006-01-02", "day"
+	if monthly {
+		layout, unit = "2006-01", "month"
+	}
+	fmt.Fprintf(w, "  %s %s %s\n", buckets[0].Start.Format(layout), reports.Sparkline(counts), buckets[len(buckets)-1].Start.Format(layout))
+	fmt.Fprintf(w, "  %sper %s, peak %d%s\n", colorDim, unit, maxCount, colorReset)
+}
+
+// printCohortComparison compares violations on weekdays against weekends.
+func printCohortComparison(topN int, from, to *time.Time, excl exclusions) {
+	fmt.Println("╔════════════════════════════════════════════════╗")
+	fmt.Println("║              WEEKDAYS vs WEEKENDS              ║")
+	fmt.Println("╚════════════════════════════════════════════════╝")
+
+	entries, err := loadReports()
+	if err != nil {
+		fmt.Printf("\nError reading reports log: %v\n", err)
+		return
+	}
+
+	if from != nil || to != nil || excl.active() {
+		entries = reports.FilterReports(entries, reports.ReportFilter{
+			StartTime:       from,
+			EndTime:         to,
+			ExcludeDomains:  excl.domains,
+			ExcludeKeywords: excl.keywords,
+		})
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("\nNo violation entries found.")
+		return
+	}
+
+	// Compare over the requested range, or the span of the log if open-ended
+	summary := reports.SummarizeReports(entries)
+	start, end := *summary.FirstEntry, *summary.LastEntry
+	if from != nil {
+		start = *from
+	}
+	if to != nil && to.Before(time.Now()) {
+		end = *to
+	}
+
+	comparison := reports.CompareWeekdayWeekend(entries, start, end)
+	cohorts := []reports.Cohort{comparison.Weekday, comparison.Weekend}
+
+	fmt.Printf("\nDate range: %s to %s\n", start.Format("2006-01-02"), end.Format("2006-01-02"))
+
+	fmt.Println("\n── Daily Average ──")
+	maxAvg := max(comparison.Weekday.DailyAverage(), comparison.Weekend.DailyAverage())
+	for _, cohort := range cohorts {
+		avg := cohort.DailyAverage()
+		bar := strings.Repeat(barChar, int(avg/maxAvg*20))
+		fmt.Printf("  %-9s %5.1f/day %s %s(%d violations over %d days)%s\n",
+			cohort.Name, avg, bar, colorDim, cohort.Summary.TotalCount, cohort.Days, colorReset)
+	}
+
+	fmt.Println("\n── Time of Day (share of each cohort) ──")
+	fmt.Printf("  %-10s %9s %9s\n", "", comparison.Weekday.Name, comparison.Weekend.Name)
+	for _, period := range reports.TimePeriods {
+		fmt.Printf("  %-10s", period)
+		for _, cohort := range cohorts {
+			share := 0.0
+			if cohort.Summary.TotalCount > 0 {
+				share = float64(cohort.ByPeriod[period]) / float64(cohort.Summary.TotalCount) * 100
+			}
+			fmt.Printf(" %8.0f%%", share)
+		}
+		fmt.Println()
+	}
+
+	for _, cohort := range cohorts {
+		fmt.Printf("\n── Top %d Keywords: %s ──\n", topN, cohort.Name)
+		topKeywords := reports.TopN(cohort.Summary.ByKeyword, topN)
+		if len(topKeywords) == 0 {
+			fmt.Println("  (none)")
+			continue
+		}
+		maxLen := maxNameLen(topKeywords)
+		for _, item := range topKeywords {
+			fmt.Printf("  %-*s %3d\n", maxLen, item.Name, item.Count)
+		}
+	}
+
+	fmt.Printf("\nVerdict: %s\n", comparison.Verdict())
+}
+
+// comparePeriod is one side of -compare: a day, month or year.
+type comparePeriod struct {
+	label      string
+	start, end time.Time
+}
+
+// parseComparePeriod parses a -compare period (YYYY, YYYY-MM or YYYY-MM-DD).
+func parseComparePeriod(s string) (comparePeriod, error) {
+	start, err := parseDateStart(s)
+	if err != nil {
+		return comparePeriod{}, err
+	}
+	end, err := parseDateEnd(s)
+	if err != nil {
+		return comparePeriod{}, err
+	}
+	return comparePeriod{label: s, start: start, end: end}, nil
+}
+
+// printPeriodComparison prints the violations of two periods side by side, with
+// the change in the totals and per keyword and domain.
+func printPeriodComparison(w io.Writer, before, after comparePeriod, topN int, excl exclusions) {
+	fmt.Fprintln(w, "╔════════════════════════════════════════════════╗")
+	fmt.Fprintln(w, "║              PERIOD COMPARISON                 ║")
+	fmt.Fprintln(w, "╚════════════════════════════════════════════════╝")
+
+	entries, err := loadReports()
+	if err != nil {
+		fmt.Fprintf(w, "\nError reading reports log: %v\n", err)
+		return
+	}
+
+	summarize := func(period comparePeriod) reports.ReportSummary {
+		return reports.SummarizeReports(reports.FilterReports(entries, reports.ReportFilter{
+			StartTime:       &period.start,
+			EndTime:         &period.end,
+			ExcludeDomains:  excl.domains,
+			ExcludeKeywords: excl.keywords,
+		}))
+	}
+	comparison := reports.ComparePeriods(summarize(before), summarize(after))
+
+	fmt.Fprintf(w, "\n%s vs %s\n", before.label, after.label)
+	fmt.Fprintf(w, "Total violations: %d → %d  %s\n", comparison.Total.Before, comparison.Total.After, formatDelta(comparison.Total))
+
+	sections := []struct {
+		title  string
+		deltas []reports.CountDelta
+	}{
+		{"Keywords", comparison.Keywords},
+		{"Domains", comparison.Domains},
+	}
+	for _, section := range sections {
+		fmt.Fprintf(w, "\n── Top %d %s by Change ──\n", topN, section.title)
+		deltas := section.deltas
+		if topN > 0 && len(deltas) > topN {
+			deltas = deltas[:topN]
+		}
+		if len(deltas) == 0 {
+			fmt.Fprintln(w, "  (none)")
+			continue
+		}
+		maxLen := 0
+		for _, d := range deltas {
+			maxLen = max(maxLen, len(d.Name))
+		}
+		fmt.Fprintf(w, "  %-*s %8s %8s\n", maxLen, "", before.label, after.label)
+		for _, d := range deltas {
+			fmt.Fprintf(w, "  %-*s %8d %8d  %s\n", maxLen, d.Name, d.Before, d.After, formatDelta(d))
+		}
+	}
+}
+
+// formatDelta renders a change as an arrow, the net change and the percentage,
+// e.g. "↑ 5 (+50%)". Rises are red and falls green, since fewer violations is
+// better.
+func formatDelta(d reports.CountDelta) string {
+	change := d.Change()
+	switch {
+	case change == 0:
+		return colorDim + "= 0" + colorReset
+	case d.Before == 0:
+		return fmt.Sprintf("%s↑ %d (new)%s", colorRed, change, colorReset)
+	}
+	percent, _ := d.Percent()
+	if change > 0 {
+		return fmt.Sprintf("%s↑ %d (%+.0f%%)%s", colorRed, change, percent, colorReset)
+	}
+	return fmt.Sprintf("%s↓ %d (%+.0f%%)%s", colorGreen, -change, percent, colorReset)
 }
 
-func printHourDistribution(hourCounts map[int]int) {
+// printWeekdayFilter notes which weekdays a summary is restricted to, if any.
+func printWeekdayFilter(w io.Writer, weekdays []time.Weekday) {
+	if len(weekdays) == 0 {
+		return
+	}
+	names := make([]string, len(weekdays))
+	for i, day := range weekdays {
+		names[i] = day.String()
+	}
+	fmt.Fprintf(w, "Weekdays: %s only\n", strings.Join(names, ", "))
+}
+
+func printHourDistribution(w io.Writer, hourCounts map[int]int) {
 	maxCount := 0
 	for _, c := range hourCounts {
 		if c > maxCount {
@@ -377,11 +891,11 @@ func printHourDistribution(hourCounts map[int]int) {
 	for _, name := range order {
 		count := periods[name]
 		bar := coloredBar(count, maxPeriod, avgPeriod, 20)
-		fmt.Printf("  %-18s %3d %s\n", name, count, bar)
+		fmt.Fprintf(w, "  %-18s %3d %s\n", name, count, bar)
 	}
 }
 
-func printViolationsHourDistribution(entries []reports.ReportEntry) {
+func printViolationsHourDistribution(w io.Writer, entries []reports.ReportEntry) {
 	// Track counts and keywords per period
 	type periodData struct {
 		count    int
@@ -442,14 +956,14 @@ func printViolationsHourDistribution(entries []reports.ReportEntry) {
 		bar := coloredBar(p.count, maxPeriod, avgPeriod, 20)
 		keyword := topKeyword(p.keywords)
 		if keyword != "" {
-			fmt.Printf("  %-18s %3d %s (%s)\n", name, p.count, bar, keyword)
+			fmt.Fprintf(w, "  %-18s %3d %s (%s)\n", name, p.count, bar, keyword)
 		} else {
-			fmt.Printf("  %-18s %3d %s\n", name, p.count, bar)
+			fmt.Fprintf(w, "  %-18s %3d %s\n", name, p.count, bar)
 		}
 	}
 }
 
-func printDayDistribution(dayCounts map[string]int) {
+func printDayDistribution(w io.Writer, dayCounts map[string]int) {
 	days := []string{"Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday", "Sunday"}
 
 	maxCount := 0
@@ -466,7 +980,7 @@ func printDayDistribution(dayCounts map[string]int) {
 	for _, day := range days {
 		count := dayCounts[day]
 		bar := coloredBar(count, maxCount, avgCount, 20)
-		fmt.Printf("  %-9s %3d %s\n", day, count, bar)
+		fmt.Fprintf(w, "  %-9s %3d %s\n", day, count, bar)
 	}
 }
 
@@ -689,7 +1203,7 @@ func getUnmanagedHoursInDay(day time.Time, periods []unmanagedPeriod) int {
 }
 
 // printDayDetails prints aggregated hourly logs for a specific day
-func printDayDetails(day time.Time) {
+func printDayDetails(day time.Time, excl exclusions) {
 	dayStart := day
 	dayEnd := day.Add(23*time.Hour + 59*time.Minute + 59*time.Second)
 
@@ -701,10 +1215,12 @@ func printDayDetails(day time.Time) {
 	unmanagedPeriods := getUnmanagedPeriods()
 
 	// Get violations for this day
-	violations, _ := reports.ParseReportsLog("")
+	violations, _ := loadReports()
 	violations = reports.FilterReports(violations, reports.ReportFilter{
-		StartTime: &dayStart,
-		EndTime:   &dayEnd,
+		StartTime:       &dayStart,
+		EndTime:         &dayEnd,
+		ExcludeDomains:  excl.domains,
+		ExcludeKeywords: excl.keywords,
 	})
 
 	// Initialize hourly stats for all 24 hours
@@ -811,7 +1327,7 @@ func getTopKey(m map[string]int) string {
 }
 
 // printMonthDetails prints aggregated daily logs for a specific month
-func printMonthDetails(month time.Time) {
+func printMonthDetails(month time.Time, excl exclusions) {
 	monthStart := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.Local)
 	monthEnd := monthStart.AddDate(0, 1, 0).Add(-time.Second)
 
@@ -823,10 +1339,12 @@ func printMonthDetails(month time.Time) {
 	unmanagedPeriods := getUnmanagedPeriods()
 
 	// Get violations for this month
-	violations, _ := reports.ParseReportsLog("")
+	violations, _ := loadReports()
 	violations = reports.FilterReports(violations, reports.ReportFilter{
-		StartTime: &monthStart,
-		EndTime:   &monthEnd,
+		StartTime:       &monthStart,
+		EndTime:         &monthEnd,
+		ExcludeDomains:  excl.domains,
+		ExcludeKeywords: excl.keywords,
 	})
 
 	// Aggregate by day
@@ -994,6 +1512,137 @@ func printMonthDetails(month time.Time) {
 	}
 }
 
+// monthStats is one month of the year and range views.
+type monthStats struct {
+	month          time.Time // First day of the month
+	days           int       // Days of the month inside the period
+	violations     int
+	violationDays  int
+	unmanagedDays  int // Days with at least one unmanaged hour
+	unmanagedHours int
+	keywords       map[string]int
+}
+
+// aggregateMonths counts violations and unmanaged time per month for the days from
+// start to end. Days after now are left out, so the current year stops at the
+// present month.
+func aggregateMonths(violations []reports.ReportEntry, periods []unmanagedPeriod, start, end, now time.Time) []*monthStats {
+	lastDay := end
+	if today := time.Date(now.Year(), now.Month(), now.Day(), 23, 59, 59, 0, now.Location()); lastDay.After(today) {
+		lastDay = today
+	}
+
+	var months []*monthStats
+	byMonth := make(map[string]*monthStats)
+	for d := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location()); !d.After(lastDay); d = d.AddDate(0, 0, 1) {
+		key := d.Format("2006-01")
+		m := byMonth[key]
+		if m == nil {
+			m = &monthStats{
+				month:    time.Date(d.Year(), d.Month(), 1, 0, 0, 0, 0, d.Location()),
+				keywords: make(map[string]int),
+			}
+			byMonth[key] = m
+			months = append(months, m)
+		}
+		m.days++
+		if hours := getUnmanagedHoursInDay(d, periods); hours > 0 {
+			m.unmanagedDays++
+			m.unmanagedHours += hours
+		}
+	}
+
+	violationDays := make(map[string]bool)
+	for _, v := range violations {
+		if v.Timestamp.Before(start) || v.Timestamp.After(lastDay) {
+			continue
+		}
+		m := byMonth[v.Timestamp.Format("2006-01")]
+		if m == nil {
+			continue
+		}
+		m.violations++
+		m.keywords[v.Keyword]++
+		if day := v.Timestamp.Format("2006-01-02"); !violationDays[day] {
+			violationDays[day] = true
+			m.violationDays++
+		}
+	}
+	return months
+}
+
+// printPeriodDetails prints a month-by-month grid of violations and unmanaged time
+// for a year or a range of months.
+func printPeriodDetails(start, end time.Time, title string, excl exclusions) {
+	fmt.Printf("╔════════════════════════════════════════════════╗\n")
+	fmt.Printf("║  %-46s║\n", title)
+	fmt.Printf("╚════════════════════════════════════════════════╝\n")
+
+	violations, _ := loadReports()
+	violations = reports.FilterReports(violations, reports.ReportFilter{
+		StartTime:       &start,
+		EndTime:         &end,
+		ExcludeDomains:  excl.domains,
+		ExcludeKeywords: excl.keywords,
+	})
+	months := aggregateMonths(violations, getUnmanagedPeriods(), start, end, time.Now())
+	if len(months) == 0 {
+		fmt.Println("\nNo data for this period yet.")
+		return
+	}
+
+	var counts []int
+	maxCount := 0
+	for _, m := range months {
+		counts = append(counts, m.violations)
+		maxCount = max(maxCount, m.violations)
+	}
+	avg := calcAverage(counts)
+
+	// Format: "Jun 2024 │ ⣿⣿⣿⣿ V:42  9/30 days (porn)  3d unmanaged (8%)"
+	fmt.Println()
+	totalV, totalDays, cleanDays, unmanagedDays := 0, 0, 0, 0
+	for _, m := range months {
+		totalV += m.violations
+		totalDays += m.days
+		unmanagedDays += m.unmanagedDays
+		cleanDays += max(0, m.days-m.violationDays-m.unmanagedDays)
+
+		monthPart := m.month.Format("Jan 2006")
+		if m.unmanagedHours == m.days*24 {
+			fmt.Printf("%s%s%s │ %s████ UNMANAGED%s\n", colorRed, monthPart, colorReset, colorRed, colorReset)
+			continue
+		}
+
+		line := monthPart + " │"
+		if m.violations > 0 {
+			line += fmt.Sprintf(" %-*s V:%-4d %2d/%d days", 20+len(colorRed)+len(colorReset), coloredBar(m.violations, maxCount, avg, 20), m.violations, m.violationDays, m.days)
+			if kw := getTopKey(m.keywords); kw != "" {
+				line += fmt.Sprintf(" %s(%s)%s", colorDim, kw, colorReset)
+			}
+		} else {
+			line += fmt.Sprintf(" %s·%s", colorDim, colorReset)
+		}
+		if m.unmanagedDays > 0 {
+			line += fmt.Sprintf("  %s%dd unmanaged (%d%%)%s", colorRed, m.unmanagedDays, m.unmanagedHours*100/(m.days*24), colorReset)
+		}
+		fmt.Println(line)
+	}
+
+	if unmanagedDays > 0 {
+		fmt.Printf("\n── Totals: %sV:%d%s │ %d months, %d days (%s%d clean%s, %s%d unmanaged%s) ──\n",
+			colorRed, totalV, colorReset,
+			len(months), totalDays,
+			colorGreen, cleanDays, colorReset,
+			colorRed, unmanagedDays, colorReset)
+	} else {
+		fmt.Printf("\n── Totals: %sV:%d%s │ %d months, %d days (%s%d clean%s) ──\n",
+			colorRed, totalV, colorReset,
+			len(months), totalDays,
+			colorGreen, cleanDays, colorReset)
+	}
+}
+
 // getTimePeriod returns the time period name for an hour
 func getTimePeriod(hour int) string {
 	switch {
@@ -1042,22 +1691,25 @@ func getTopFromMap(m map[string]int, n int) []string {
 }
 
 // printDailyReport prints the daily report in the same format as the email.
-func printDailyReport(date time.Time) {
+func printDailyReport(date time.Time, excl exclusions) {
 	dayStart := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.Local)
 	dayEnd := dayStart.Add(24*time.Hour - time.Second)
 
 	// Gather violations
-	violations, _ := reports.ParseReportsLog("")
+	violations, _ := loadReports()
 	violations = reports.FilterReports(violations, reports.ReportFilter{
-		StartTime: &dayStart,
-		EndTime:   &dayEnd,
+		StartTime:       &dayStart,
+		EndTime:         &dayEnd,
+		ExcludeDomains:  excl.domains,
+		ExcludeKeywords: excl.keywords,
 	})
 
 	// Gather unblocks
-	unblocks, _ := reports.ParseUnblocksLog("")
+	unblocks, _ := reports.LoadUnblocks()
 	unblocks = reports.FilterUnblocks(unblocks, reports.UnblockFilter{
-		StartTime: &dayStart,
-		EndTime:   &dayEnd,
+		StartTime:      &dayStart,
+		EndTime:        &dayEnd,
+		ExcludeDomains: excl.domains,
 	})
 
 	// Gather lifecycle events
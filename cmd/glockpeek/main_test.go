@@ -0,0 +1,109 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"glocker/internal/reports"
+)
+
+func TestRenderWatchFrame(t *testing.T) {
+	now := time.Date(2026, 1, 6, 14, 30, 5, 0, time.Local)
+	body := "╔═══╗\nTotal violations: 3\n"
+
+	frame := renderWatchFrame(now, 10*time.Second, body)
+
+	if !strings.HasPrefix(frame, clearScreen) {
+		t.Errorf("frame should start by clearing the screen, got %q", frame)
+	}
+	if !strings.Contains(frame, "2026-01-06 14:30:05") {
+		t.Errorf("frame should show the refresh time, got %q", frame)
+	}
+	if !strings.Contains(frame, "every 10s") {
+		t.Errorf("frame should show the interval, got %q", frame)
+	}
+	if !strings.Contains(frame, "Ctrl-C") {
+		t.Errorf("frame should say how to quit, got %q", frame)
+	}
+	if !strings.HasSuffix(frame, body) {
+		t.Errorf("frame should end with the body, got %q", frame)
+	}
+	if strings.Count(frame, clearScreen) != 1 {
+		t.Errorf("frame should clear the screen once, got %q", frame)
+	}
+}
+
+func TestRenderSummaries_NothingSelected(t *testing.T) {
+	var b strings.Builder
+	renderSummaries(&b, summarySelection{topN: 5})
+	if b.Len() != 0 {
+		t.Errorf("expected no output with no summaries selected, got %q", b.String())
+	}
+}
+
+func TestAggregateMonths(t *testing.T) {
+	at := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2025, month, day, hour, 0, 0, 0, time.Local)
+	}
+	violations := []reports.ReportEntry{
+		{Timestamp: at(time.January, 3, 10), Keyword: "casino"},
+		{Timestamp: at(time.January, 3, 22), Keyword: "casino"},
+		{Timestamp: at(time.January, 20, 9), Keyword: "poker"},
+		{Timestamp: at(time.March, 1, 12), Keyword: "poker"},
+		{Timestamp: at(time.April, 2, 12), Keyword: "poker"}, // after now
+		{Timestamp: time.Date(2024, time.December, 31, 23, 0, 0, 0, time.Local), Keyword: "casino"},
+	}
+	// Unmanaged from Feb 10 18:00 to Feb 12 06:00: 6 + 24 + 6 hours over 3 days
+	periods := []unmanagedPeriod{{start: at(time.February, 10, 18), end: at(time.February, 12, 6)}}
+
+	start := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.Local)
+	end := start.AddDate(1, 0, 0).Add(-time.Second)
+	now := at(time.March, 15, 8)
+
+	months := aggregateMonths(violations, periods, start, end, now)
+	if len(months) != 3 {
+		t.Fatalf("Expected January to March for the current year, got %d months", len(months))
+	}
+
+	want := []struct {
+		month                           time.Month
+		days, violations, violationDays int
+		unmanagedDays, unmanagedHours   int
+		topKeyword                      string
+	}{
+		{time.January, 31, 3, 2, 0, 0, "casino"},
+		{time.February, 28, 0, 0, 3, 36, ""},
+		{time.March, 15, 1, 1, 0, 0, "poker"},
+	}
+	for i, w := range want {
+		m := months[i]
+		if m.month.Month() != w.month || m.days != w.days || m.violations != w.violations || m.violationDays != w.violationDays ||
+			m.unmanagedDays != w.unmanagedDays || m.unmanagedHours != w.unmanagedHours || getTopKey(m.keywords) != w.topKeyword {
+			t.Errorf("Month %d = %+v, want %+v", i, *m, w)
+		}
+	}
+
+	// A past range covers every month in full
+	months = aggregateMonths(violations, nil, time.Date(2024, time.June, 1, 0, 0, 0, 0, time.Local), end, now)
+	if len(months) != 10 || months[0].month.Month() != time.June || months[6].violations != 1 {
+		t.Errorf("Expected Jun 2024 to Mar 2025 with the Dec 31 violation, got %d months", len(months))
+	}
+}
+
+func TestFormatDelta(t *testing.T) {
+	tests := []struct {
+		delta reports.CountDelta
+		want  string
+	}{
+		{reports.CountDelta{Before: 10, After: 15}, colorRed + "↑ 5 (+50%)" + colorReset},
+		{reports.CountDelta{Before: 8, After: 2}, colorGreen + "↓ 6 (-75%)" + colorReset},
+		{reports.CountDelta{Before: 0, After: 3}, colorRed + "↑ 3 (new)" + colorReset},
+		{reports.CountDelta{Before: 4, After: 4}, colorDim + "= 0" + colorReset},
+	}
+	for _, tt := range tests {
+		if got := formatDelta(tt.delta); got != tt.want {
+			t.Errorf("formatDelta(%+v) = %q, want %q", tt.delta, got, tt.want)
+		}
+	}
+}
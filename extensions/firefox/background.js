@@ -342,6 +342,44 @@ function setCachedResult(url, result) {
   urlCheckCache.set(url, result);
 }
 
+// Ask glocker whether a page is blocked by a path_patterns rule. The hosts file
+// can't block a path, so pages are checked here. Fails open when glocker isn't
+// reachable.
+async function checkPathBlocked(details) {
+  if (details.type !== 'main_frame') {
+    return {};
+  }
+  const target = new URL(details.url);
+  const query = new URLSearchParams({host: target.hostname, path: target.pathname});
+  try {
+    const response = await fetch(`http://127.0.0.1/is-blocked?${query}`);
+    const result = await response.json();
+    if (result.blocked) {
+      console.log("Path blocked:", details.url, "matched:", result.matched);
+      // Checking has no side effects; the block is reported like a keyword hit
+      fetch('http://127.0.0.1/report', {
+        method: 'POST',
+        headers: {'Content-Type': 'application/json'},
+        body: JSON.stringify({
+          url: details.url,
+          trigger: 'path-pattern',
+          timestamp: Date.now()
+        })
+      }).catch(() => {}); // Ignore failures
+      const blockedQuery = new URLSearchParams({
+        domain: target.hostname,
+        matched: result.matched,
+        reason: result.reason,
+        url: details.url
+      });
+      return {redirectUrl: `http://127.0.0.1/blocked?${blockedQuery}`};
+    }
+  } catch (error) {
+    console.log("Path check failed:", error);
+  }
+  return {};
+}
+
 browser.webRequest.onBeforeRequest.addListener(
   function(details) {
     const url = details.url.toLowerCase();
@@ -367,14 +405,14 @@ browser.webRequest.onBeforeRequest.addListener(
         const reason = encodeURIComponent(`URL contains blocked keyword: "${cachedResult.keyword}"`);
         return {redirectUrl: `http://127.0.0.1/blocked?reason=${reason}`};
       }
-      return; // URL was checked before and not blocked
+      return checkPathBlocked(details); // No blocked keyword, but the path may be blocked
     }
     
     // Check if URL is whitelisted - if so, skip all blocking logic
     if (isWhitelisted(urlToCheck)) {
       console.log("URL is whitelisted, skipping blocking logic:", urlToCheck);
       setCachedResult(urlToCheck, { blocked: false });
-      return;
+      return checkPathBlocked(details);
     }
     
     console.log("Checking URL:", urlToCheck, "against", urlKeywordRegexes.length, "patterns");
@@ -405,6 +443,7 @@ browser.webRequest.onBeforeRequest.addListener(
     
     // Cache the non-blocked result
     setCachedResult(urlToCheck, { blocked: false });
+    return checkPathBlocked(details);
   },
   {urls: ["<all_urls>"]},
   ["blocking"]
@@ -0,0 +1,108 @@
+// Package audit writes a structured log of glocker events as newline-delimited
+// JSON, so tools can read them without parsing the human-readable logs.
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"glocker/internal/schema"
+	"glocker/internal/utils"
+)
+
+// DefaultLogPath is where the daemon writes the audit log.
+const DefaultLogPath = "/var/log/glocker-audit.jsonl"
+
+// EventType identifies what happened.
+type EventType string
+
+const (
+	EventBlock     EventType = "block"     // Domain added with -block
+	EventUnblock   EventType = "unblock"   // Temporary unblock granted
+	EventRevoke    EventType = "revoke"    // Temporary unblock revoked before it expired
+	EventViolation EventType = "violation" // Blocked access, keyword report or forbidden program
+	EventReload    EventType = "reload"    // Config reloaded
+	EventPanic     EventType = "panic"     // Panic mode entered
+	EventTamper    EventType = "tamper"    // Tampering detected
+
+	EventCategoryEnable  EventType = "category_enable"  // Keyword category switched on with -enable-category
+	EventCategoryDisable EventType = "category_disable" // Keyword category switched off with -disable-category
+)
+
+// Event is a single line of the audit log. Empty fields are left out.
+type Event struct {
+	SchemaVersion int       `json:"schema_version"` // Set by Log
+	Timestamp     time.Time `json:"ts"`
+	Type          EventType `json:"type"`
+	Domain        string    `json:"domain,omitempty"`
+	Keyword       string    `json:"keyword,omitempty"`
+	Category      string    `json:"category,omitempty"` // Keyword category switched on or off
+	Reason        string    `json:"reason,omitempty"`
+	Note          string    `json:"note,omitempty"` // Free-text justification given with an unblock reason
+	URL           string    `json:"url,omitempty"`
+	Source        string    `json:"source,omitempty"` // What produced the event, e.g. "socket", "web_access", "url-keyword"
+	Until         time.Time `json:"until,omitzero"`   // End of a temporary unblock or panic
+}
+
+// Logger appends events to an audit log file, rotated by size.
+type Logger struct {
+	writer *utils.RotatingWriter
+	now    func() time.Time
+}
+
+// NewLogger returns a Logger that appends to the file at path, rotating it once
+// it would grow past maxBytes and keeping maxFiles rotated files.
+func NewLogger(path string, maxBytes int64, maxFiles int) *Logger {
+	return &Logger{writer: utils.NewRotatingWriter(path, maxBytes, maxFiles), now: time.Now}
+}
+
+// Log appends event as one JSON line, stamped with the current schema version.
+// A zero Timestamp is set to the current time.
+func (l *Logger) Log(event Event) error {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = l.now()
+	}
+	event.SchemaVersion = schema.Version
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit event: %w", err)
+	}
+
+	if _, err := l.writer.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log: %w", err)
+	}
+	return nil
+}
+
+var (
+	defaultLogger *Logger
+	defaultMutex  sync.RWMutex
+)
+
+// Enable starts writing events passed to Log to the file at path, rotated like
+// the daemon's own log (see NewLogger). Until it is called Log does nothing, so
+// only the daemon writes the audit log.
+func Enable(path string, maxBytes int64, maxFiles int) {
+	defaultMutex.Lock()
+	defer defaultMutex.Unlock()
+	defaultLogger = NewLogger(path, maxBytes, maxFiles)
+}
+
+// Log appends event to the audit log set up by Enable. Failures are only logged
+// at debug level: auditing never gets in the way of enforcement.
+func Log(event Event) {
+	defaultMutex.RLock()
+	logger := defaultLogger
+	defaultMutex.RUnlock()
+
+	if logger == nil {
+		return
+	}
+	if err := logger.Log(event); err != nil {
+		slog.Debug("Failed to write audit event", "type", event.Type, "error", err)
+	}
+}
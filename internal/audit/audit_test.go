@@ -0,0 +1,129 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"glocker/internal/schema"
+)
+
+func TestLogger_WritesJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	logger := NewLogger(path, 1<<20, 1)
+	fixed := time.Date(2026, 1, 6, 10, 30, 0, 0, time.UTC)
+	logger.now = func() time.Time { return fixed }
+
+	events := []Event{
+		{Type: EventBlock, Domain: "reddit.com", Source: "socket"},
+		{Type: EventUnblock, Domain: "youtube.com", Reason: "work", Source: "socket", Until: fixed.Add(30 * time.Minute)},
+		{Timestamp: fixed.Add(time.Minute), Type: EventViolation, Domain: "example.com", Keyword: "casino", URL: "https://example.com/", Source: "url-keyword"},
+	}
+	for _, e := range events {
+		if err := logger.Log(e); err != nil {
+			t.Fatalf("Log failed: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %d:\n%s", len(lines), data)
+	}
+
+	want := fmt.Sprintf(`{"schema_version":%d,"ts":"2026-01-06T10:30:00Z","type":"block","domain":"reddit.com","source":"socket"}`, schema.Version)
+	if lines[0] != want {
+		t.Errorf("First line = %s, want %s", lines[0], want)
+	}
+	if strings.Contains(lines[0], "until") || strings.Contains(lines[0], "keyword") {
+		t.Errorf("Empty fields should be left out: %s", lines[0])
+	}
+
+	var unblock Event
+	if err := json.Unmarshal([]byte(lines[1]), &unblock); err != nil {
+		t.Fatal(err)
+	}
+	if !unblock.Timestamp.Equal(fixed) || !unblock.Until.Equal(fixed.Add(30*time.Minute)) || unblock.Reason != "work" {
+		t.Errorf("Unblock event round-tripped as %+v", unblock)
+	}
+
+	var violation Event
+	if err := json.Unmarshal([]byte(lines[2]), &violation); err != nil {
+		t.Fatal(err)
+	}
+	if !violation.Timestamp.Equal(fixed.Add(time.Minute)) {
+		t.Errorf("An explicit timestamp should be kept, got %v", violation.Timestamp)
+	}
+}
+
+func TestLogger_ConcurrentWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	logger := NewLogger(path, 1<<20, 1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			logger.Log(Event{Type: EventViolation, URL: strings.Repeat("x", 2000)})
+		}()
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var e Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Errorf("Line %d is not valid JSON: %v", i, err)
+		}
+	}
+}
+
+func TestLog_DisabledUntilEnabled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	t.Cleanup(func() {
+		defaultMutex.Lock()
+		defaultLogger = nil
+		defaultMutex.Unlock()
+	})
+
+	Log(Event{Type: EventReload})
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("Log should not write before Enable")
+	}
+
+	Enable(path, 1<<20, 1)
+	Log(Event{Type: EventReload, Source: "socket"})
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(data), `"type":"reload"`) {
+		t.Errorf("Expected a reload event after Enable, got %q (%v)", data, err)
+	}
+}
+
+func TestLogger_Rotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	logger := NewLogger(path, 200, 1)
+	for range 3 {
+		if err := logger.Log(Event{Type: EventViolation, URL: strings.Repeat("x", 100)}); err != nil {
+			t.Fatalf("Log failed: %v", err)
+		}
+	}
+
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Errorf("Expected the audit log to be rotated to %s.1: %v", path, err)
+	}
+	if _, err := os.Stat(path + ".2"); !os.IsNotExist(err) {
+		t.Errorf("Expected only one rotated file to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,95 @@
+// Package buildinfo reports which build of glocker is running, so an installed
+// binary can be matched to the commit it was built from.
+package buildinfo
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Set at build time, e.g.
+//
+//	go build -ldflags "-X glocker/internal/buildinfo.version=v1.2.0 -X glocker/internal/buildinfo.commit=abc1234"
+//
+// The Makefile does this for every binary.
+var (
+	version   string
+	commit    string
+	buildDate string
+)
+
+// Fallbacks for fields neither -ldflags nor the Go build info provide.
+const (
+	DefaultVersion = "dev"
+	Unknown        = "unknown"
+)
+
+// Info describes a build.
+type Info struct {
+	Version   string
+	Commit    string
+	BuildDate string
+	GoVersion string
+}
+
+// Get returns the running binary's build info. Values injected with -ldflags take
+// precedence; missing ones are taken from the VCS stamp the go command embeds
+// (vcs.revision, vcs.time), when there is one.
+func Get() Info {
+	return get(version, commit, buildDate, debug.ReadBuildInfo)
+}
+
+func get(version, commit, buildDate string, readBuildInfo func() (*debug.BuildInfo, bool)) Info {
+	info := Info{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+	}
+
+	if bi, ok := readBuildInfo(); ok && bi != nil {
+		if bi.GoVersion != "" {
+			info.GoVersion = bi.GoVersion
+		}
+		if info.Version == "" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+
+		var revision, modified string
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				revision = setting.Value
+			case "vcs.modified":
+				modified = setting.Value
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			}
+		}
+		if info.Commit == "" && revision != "" {
+			info.Commit = revision
+			if modified == "true" {
+				info.Commit += "-dirty"
+			}
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = DefaultVersion
+	}
+	if info.Commit == "" {
+		info.Commit = Unknown
+	}
+	if info.BuildDate == "" {
+		info.BuildDate = Unknown
+	}
+	return info
+}
+
+// String formats the info for -version.
+func (i Info) String() string {
+	return fmt.Sprintf("Glocker %s\n  commit: %s\n  built:  %s\n  go:     %s", i.Version, i.Commit, i.BuildDate, i.GoVersion)
+}
@@ -0,0 +1,72 @@
+package buildinfo
+
+import (
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+func TestGet_FromBuildInfo(t *testing.T) {
+	read := func() (*debug.BuildInfo, bool) {
+		return &debug.BuildInfo{
+			GoVersion: "go1.24.1",
+			Main:      debug.Module{Path: "glocker", Version: "v1.3.0"},
+			Settings: []debug.BuildSetting{
+				{Key: "vcs.revision", Value: "0123456789abcdef"},
+				{Key: "vcs.time", Value: "2026-01-06T10:00:00Z"},
+				{Key: "vcs.modified", Value: "true"},
+			},
+		}, true
+	}
+
+	got := get("", "", "", read)
+	want := Info{Version: "v1.3.0", Commit: "0123456789abcdef-dirty", BuildDate: "2026-01-06T10:00:00Z", GoVersion: "go1.24.1"}
+	if got != want {
+		t.Errorf("get() = %+v, want %+v", got, want)
+	}
+}
+
+func TestGet_LdflagsTakePrecedence(t *testing.T) {
+	read := func() (*debug.BuildInfo, bool) {
+		return &debug.BuildInfo{
+			GoVersion: "go1.24.1",
+			Main:      debug.Module{Path: "glocker", Version: "(devel)"},
+			Settings:  []debug.BuildSetting{{Key: "vcs.revision", Value: "0123456789abcdef"}},
+		}, true
+	}
+
+	got := get("v2.0.0", "abc1234", "2026-01-06", read)
+	want := Info{Version: "v2.0.0", Commit: "abc1234", BuildDate: "2026-01-06", GoVersion: "go1.24.1"}
+	if got != want {
+		t.Errorf("get() = %+v, want %+v", got, want)
+	}
+}
+
+func TestGet_Fallback(t *testing.T) {
+	read := func() (*debug.BuildInfo, bool) { return nil, false }
+
+	got := get("", "", "", read)
+	if got.Version != DefaultVersion || got.Commit != Unknown || got.BuildDate != Unknown {
+		t.Errorf("get() = %+v, want fallback values", got)
+	}
+	if got.GoVersion == "" {
+		t.Error("Expected the Go version to come from the runtime")
+	}
+}
+
+func TestGet_Runtime(t *testing.T) {
+	// Test binaries carry build info too, so the Go version is always known
+	info := Get()
+	if !strings.HasPrefix(info.GoVersion, "go") {
+		t.Errorf("GoVersion = %q, want a Go version", info.GoVersion)
+	}
+	if info.Version == "" || info.Commit == "" || info.BuildDate == "" {
+		t.Errorf("Get() left fields empty: %+v", info)
+	}
+
+	for _, field := range []string{info.Version, info.Commit, info.BuildDate, info.GoVersion} {
+		if !strings.Contains(info.String(), field) {
+			t.Errorf("String() = %q, missing %q", info.String(), field)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"glocker/internal/audit"
+	"glocker/internal/config"
+	"glocker/internal/enforcement"
+	"glocker/internal/notify"
+	"glocker/internal/web"
+)
+
+// ProcessCategoryRequest switches the keyword category name on or off, as if its
+// enabled setting were changed in the config. Switching one off weakens
+// monitoring, so like an unblock it needs a reason and is reported to the
+// accountability partner. The change is persisted to the runtime overlay, so it
+// survives reloads and restarts, and connected extensions are sent the new
+// keyword set.
+func ProcessCategoryRequest(cfg *config.Config, name string, enabled bool, reason string) error {
+	name = strings.TrimSpace(name)
+	reason = strings.TrimSpace(reason)
+	slog.Debug("Processing category request", "category", name, "enabled", enabled, "reason", reason)
+
+	if !enabled && reason == "" {
+		return fmt.Errorf("reason required to disable category %s", name)
+	}
+	index := slices.IndexFunc(cfg.KeywordCategories, func(category config.KeywordCategory) bool {
+		return category.Name == name
+	})
+	if index < 0 {
+		return unknownCategoryError(cfg, name)
+	}
+
+	if err := config.SetOverlayCategory(name, enabled); err != nil {
+		return fmt.Errorf("failed to persist category %s to %s: %w", name, config.OverlayPath(), err)
+	}
+	cfg.KeywordCategories[index].Enabled = &enabled
+
+	now := enforcement.Now()
+	if enabled {
+		log.Printf("KEYWORD CATEGORY ENABLED: %s", name)
+		audit.Log(audit.Event{Timestamp: now, Type: audit.EventCategoryEnable, Category: name, Source: "socket"})
+	} else {
+		log.Printf("KEYWORD CATEGORY DISABLED: %s (reason: %s)", name, reason)
+		audit.Log(audit.Event{Timestamp: now, Type: audit.EventCategoryDisable, Category: name, Reason: reason, Source: "socket"})
+		sendCategoryDisabledEmail(cfg, name, reason, now)
+	}
+
+	// Only the extensions' keyword set changes; hosts and firewall are untouched
+	web.CheckKeywordCategories(cfg, now)
+	return nil
+}
+
+// sendCategoryDisabledEmail tells the accountability partner a keyword category
+// was switched off.
+func sendCategoryDisabledEmail(cfg *config.Config, name, reason string, now time.Time) {
+	if !cfg.NotifiesPartner() {
+		return
+	}
+
+	subject := "GLOCKER ALERT: Keyword Category Disabled"
+	body := fmt.Sprintf("The keyword category %q was switched off at %s.\n\n", name, now.Format("2006-01-02 15:04:05"))
+	body += fmt.Sprintf("Reason: %s\n", reason)
+	body += "Its keywords are no longer checked until it is enabled again with -enable-category.\n\n"
+	body += "This is an automated alert from Glocker."
+
+	if err := notify.SendEmail(cfg, notify.EventCategoryDisabled, subject, body); err != nil {
+		log.Printf("Failed to send category email: %v", err)
+	}
+}
+
+// unknownCategoryError explains why name can't be switched: domain categories
+// only group reports, and anything else isn't a category at all.
+func unknownCategoryError(cfg *config.Config, name string) error {
+	// The daemon clears cfg.Domains after enforcement, so fall back to the enforced domain cache
+	domains := cfg.Domains
+	if len(domains) == 0 {
+		domains = enforcement.GetEnforcedDomains()
+	}
+	if slices.ContainsFunc(domains, func(domain config.Domain) bool { return domain.Category == name }) {
+		return fmt.Errorf("%q is a domain category, which only groups reports; only keyword_categories can be switched on or off", name)
+	}
+
+	names := make([]string, 0, len(cfg.KeywordCategories))
+	for _, category := range cfg.KeywordCategories {
+		names = append(names, category.Name)
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("unknown category %q: no keyword_categories are configured", name)
+	}
+	return fmt.Errorf("unknown category %q (keyword_categories: %s)", name, strings.Join(names, ", "))
+}
@@ -4,11 +4,17 @@ import (
 	"fmt"
 	"log"
 	"log/slog"
+	"maps"
+	"slices"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"glocker/internal/audit"
 	"glocker/internal/config"
 	"glocker/internal/enforcement"
+	"glocker/internal/monitoring"
+	"glocker/internal/notify"
 	"glocker/internal/state"
 	"glocker/internal/web"
 )
@@ -24,7 +30,23 @@ func GetStatusResponse(cfg *config.Config) string {
 
 	// Current time and service status
 	response.WriteString(fmt.Sprintf("Current Time: %s\n", now.Format("2006-01-02 15:04:05")))
-	response.WriteString(fmt.Sprintf("Service Status: Running\n\n"))
+	if progress := formatEnforcementProgress(); progress != "" {
+		response.WriteString(fmt.Sprintf("Service Status: Running (%s)\n\n", progress))
+	} else {
+		response.WriteString(fmt.Sprintf("Service Status: Running\n\n"))
+	}
+
+	// Show an enforcement pause before anything that it makes misleading
+	if pausedUntil := state.GetPausedUntil(); now.Before(pausedUntil) {
+		response.WriteString(fmt.Sprintf("⏸️  PAUSED until %s - hosts, firewall and sudoers blocking suspended\n\n", pausedUntil.Format("15:04")))
+	}
+	if domains, until, ok := activeFocusSession(now); ok {
+		response.WriteString(fmt.Sprintf("🎯 FOCUS SESSION: %v remaining (until %s) - blocking %s\n\n",
+			until.Sub(now).Round(time.Minute), until.Format("15:04"), strings.Join(domains, ", ")))
+	}
+	if pending, ok := state.GetPendingUninstall(); ok {
+		response.WriteString(fmt.Sprintf("🗑️  UNINSTALL PENDING - can be confirmed with -uninstall after %s\n\n", pending.UnlockAt.Format("2006-01-02 15:04")))
+	}
 
 	// Get blocked domain count from enforcement state
 	_, blockedCount, _ := enforcement.GetEnforcementState()
@@ -45,13 +67,42 @@ func GetStatusResponse(cfg *config.Config) string {
 	}
 	response.WriteString(fmt.Sprintf("Currently Blocked Domains: %d\n", effectiveBlocked))
 	response.WriteString(fmt.Sprintf("Temporary Unblocks: %d active\n", activeUnblocks))
+	if maxPerDay := cfg.Unblocking.MaxPerDay; maxPerDay > 0 {
+		periodStart := cfg.Unblocking.UnblockPeriodStart(now)
+		response.WriteString(fmt.Sprintf("Unblocks Today: %d of %d (resets at %s)\n",
+			state.CountUnblockGrantsSince(periodStart), maxPerDay, periodStart.AddDate(0, 0, 1).Format("15:04")))
+	}
+	if result, ok := state.GetSelfTestResult(); ok {
+		response.WriteString(fmt.Sprintf("Block Self-Test: %d of %d sampled blocks verified unreachable (at %s)\n",
+			result.Verified, result.Sampled, result.Time.Format("15:04")))
+		if len(result.Reachable) > 0 {
+			response.WriteString(fmt.Sprintf("  Still reachable: %s\n", strings.Join(result.Reachable, ", ")))
+		}
+	}
 
 	if activeUnblocks > 0 {
 		response.WriteString("  Active temporary unblocks:\n")
 		for _, unblock := range unblocks {
 			if now.Before(unblock.ExpiresAt) {
 				remaining := unblock.ExpiresAt.Sub(now)
-				response.WriteString(fmt.Sprintf("    - %s (expires in %v)\n", unblock.Domain, remaining.Round(time.Minute)))
+				if unblock.Duration > 0 {
+					response.WriteString(fmt.Sprintf("    - %s (%v granted, expires in %v)\n", unblock.Domain, unblock.Duration, remaining.Round(time.Minute)))
+				} else {
+					response.WriteString(fmt.Sprintf("    - %s (expires in %v)\n", unblock.Domain, remaining.Round(time.Minute)))
+				}
+			}
+		}
+	}
+
+	// Show when each time-based domain next blocks or opens
+	if timeWindowDomains := enforcement.GetTimeWindowDomains(); len(timeWindowDomains) > 0 {
+		response.WriteString("\n")
+		response.WriteString("Time-Based Domains:\n")
+		for i, domain := range timeWindowDomains {
+			response.WriteString(fmt.Sprintf("  %s: %s\n", domain.Name, formatTimeWindowTransition(domain.TimeWindows, now)))
+			if i >= 9 && len(timeWindowDomains) > 10 {
+				response.WriteString(fmt.Sprintf("  ... and %d more\n", len(timeWindowDomains)-10))
+				break
 			}
 		}
 	}
@@ -59,13 +110,7 @@ func GetStatusResponse(cfg *config.Config) string {
 	// Show violation tracking status
 	if cfg.ViolationTracking.Enabled {
 		violations := state.GetViolations()
-		recentViolations := 0
-		cutoff := now.Add(-time.Duration(cfg.ViolationTracking.TimeWindowMinutes) * time.Minute)
-		for _, v := range violations {
-			if v.Timestamp.After(cutoff) {
-				recentViolations++
-			}
-		}
+		recentViolations := monitoring.CountRecentViolations(cfg, now)
 
 		response.WriteString("\n")
 		response.WriteString("Violation Tracking:\n")
@@ -74,6 +119,25 @@ func GetStatusResponse(cfg *config.Config) string {
 		response.WriteString(fmt.Sprintf("  Total Violations: %d\n", len(violations)))
 	}
 
+	// Show active profile
+	if activeProfile, since := state.GetActiveProfile(); activeProfile != "" {
+		response.WriteString("\n")
+		response.WriteString(fmt.Sprintf("Active Profile: %s (since %s)\n", activeProfile, since.Format("15:04")))
+	}
+
+	// Show daemon resource usage
+	if samples := state.GetResourceSamples(); len(samples) > 0 {
+		latest := samples[len(samples)-1]
+		response.WriteString("\n")
+		response.WriteString(fmt.Sprintf("Daemon Resources (sampled %s):\n", latest.Timestamp.Format("15:04")))
+		response.WriteString(fmt.Sprintf("  Memory: %s RSS, %s heap\n", monitoring.FormatBytes(latest.RSSBytes), monitoring.FormatBytes(latest.HeapBytes)))
+		response.WriteString(fmt.Sprintf("  Goroutines: %d\n", latest.Goroutines))
+		response.WriteString(fmt.Sprintf("  GC: %d cycles, %v total pause\n", latest.NumGC, latest.GCPause.Round(time.Microsecond)))
+		if warning := monitoring.ResourceGrowthWarning(samples); warning != "" {
+			response.WriteString(fmt.Sprintf("  ⚠️  %s\n", warning))
+		}
+	}
+
 	// Show panic mode status
 	panicUntil := state.GetPanicUntil()
 	if !panicUntil.IsZero() && now.Before(panicUntil) {
@@ -83,6 +147,16 @@ func GetStatusResponse(cfg *config.Config) string {
 		response.WriteString(fmt.Sprintf("Time Remaining: %v\n", remaining.Round(time.Second)))
 	}
 
+	// Show the next scheduled panic window
+	if start, end, ok := monitoring.NextPanicWindow(cfg.PanicSchedule, now); ok {
+		response.WriteString("\n")
+		if now.Before(start) {
+			response.WriteString(fmt.Sprintf("Next Scheduled Panic: %s - %s\n", start.Format("Mon 15:04"), end.Format("Mon 15:04")))
+		} else {
+			response.WriteString(fmt.Sprintf("Scheduled Panic: now, until %s\n", end.Format("Mon 15:04")))
+		}
+	}
+
 	response.WriteString("\nEND\n")
 	return response.String()
 }
@@ -95,6 +169,9 @@ func GetInfoResponse(cfg *config.Config) string {
 	response.WriteString("║            CONFIGURATION INFO                  ║\n")
 	response.WriteString("╚════════════════════════════════════════════════╝\n\n")
 
+	if progress := formatEnforcementProgress(); progress != "" {
+		response.WriteString(fmt.Sprintf("Service Status: %s (domain counts may be incomplete)\n", progress))
+	}
 	response.WriteString(fmt.Sprintf("Enforcement Interval: %d seconds\n", cfg.EnforceInterval))
 
 	// Get domain counts from enforcement state
@@ -113,7 +190,7 @@ func GetInfoResponse(cfg *config.Config) string {
 	if timeBasedCount > 0 {
 		response.WriteString(fmt.Sprintf("Time-Based Domains (%d):\n", timeBasedCount))
 		for i, domain := range timeWindowDomains {
-			response.WriteString(fmt.Sprintf("  %s: %s\n", domain.Name, formatTimeWindows(domain.TimeWindows)))
+			response.WriteString(fmt.Sprintf("  %s: %s\n", formatDomainName(domain), formatTimeWindows(domain.TimeWindows)))
 			if i >= 9 && len(timeWindowDomains) > 10 {
 				response.WriteString(fmt.Sprintf("  ... and %d more\n", timeBasedCount-10))
 				break
@@ -122,6 +199,14 @@ func GetInfoResponse(cfg *config.Config) string {
 		response.WriteString("\n")
 	}
 
+	// Show domain categories and labels. The daemon clears cfg.Domains after
+	// enforcement, so fall back to the enforced domain cache
+	domains := cfg.Domains
+	if len(domains) == 0 {
+		domains = enforcement.GetEnforcedDomains()
+	}
+	writeDomainCategories(&response, domains)
+
 	// Show forbidden programs with time windows
 	if cfg.EnableForbiddenPrograms && cfg.ForbiddenPrograms.Enabled && len(cfg.ForbiddenPrograms.Programs) > 0 {
 		response.WriteString(fmt.Sprintf("Forbidden Programs (%d):\n", len(cfg.ForbiddenPrograms.Programs)))
@@ -183,6 +268,70 @@ func GetInfoResponse(cfg *config.Config) string {
 	return response.String()
 }
 
+// writeDomainCategories lists how many domains each category has and, since
+// labels say why a domain is blocked, the labeled domains.
+func writeDomainCategories(response *strings.Builder, domains []config.Domain) {
+	categoryCounts := make(map[string]int)
+	var labeled []config.Domain
+	for _, domain := range domains {
+		if domain.Category != "" {
+			categoryCounts[domain.Category]++
+		}
+		if domain.Label != "" {
+			labeled = append(labeled, domain)
+		}
+	}
+
+	if len(categoryCounts) > 0 {
+		categories := slices.Sorted(maps.Keys(categoryCounts))
+		response.WriteString(fmt.Sprintf("Categories (%d):\n", len(categories)))
+		for _, category := range categories {
+			response.WriteString(fmt.Sprintf("  %s: %d domains\n", category, categoryCounts[category]))
+		}
+		response.WriteString("\n")
+	}
+
+	if len(labeled) > 0 {
+		response.WriteString(fmt.Sprintf("Labeled Domains (%d):\n", len(labeled)))
+		for i, domain := range labeled {
+			response.WriteString(fmt.Sprintf("  %s\n", formatDomainName(domain)))
+			if i >= 9 && len(labeled) > 10 {
+				response.WriteString(fmt.Sprintf("  ... and %d more\n", len(labeled)-10))
+				break
+			}
+		}
+		response.WriteString("\n")
+	}
+}
+
+// formatDomainName returns the domain name with its category and label, e.g.
+// `reddit.com [social] "doomscrolling"`.
+func formatDomainName(domain config.Domain) string {
+	name := domain.Name
+	if domain.Category != "" {
+		name += " [" + domain.Category + "]"
+	}
+	if domain.Label != "" {
+		name += fmt.Sprintf(" %q", domain.Label)
+	}
+	return name
+}
+
+// formatEnforcementProgress describes an ongoing hosts file write, e.g.
+// "enforcement in progress: 62% (496000/800000 domains written)".
+// Returns an empty string when no write is in progress.
+func formatEnforcementProgress() string {
+	written, total, active := state.GetHostsWriteProgress()
+	if !active {
+		return ""
+	}
+	percent := 100
+	if total > 0 {
+		percent = written * 100 / total
+	}
+	return fmt.Sprintf("enforcement in progress: %d%% (%d/%d domains written)", percent, written, total)
+}
+
 // formatTimeWindows converts time windows to a readable string.
 func formatTimeWindows(windows []config.TimeWindow) string {
 	if len(windows) == 0 {
@@ -197,6 +346,72 @@ func formatTimeWindows(windows []config.TimeWindow) string {
 	return strings.Join(parts, "; ")
 }
 
+// formatTimeWindowTransition describes when a domain with the given time
+// windows next changes state, e.g. "blocks at 20:00" or "blocked, opens in 1h23m".
+func formatTimeWindowTransition(windows []config.TimeWindow, now time.Time) string {
+	at, blocking, ok := enforcement.NextTimeWindowTransition(windows, now)
+	switch {
+	case !ok && blocking:
+		return "blocked all week"
+	case !ok:
+		return "no block this week"
+	case blocking:
+		remaining := at.Sub(now).Round(time.Minute)
+		hours, minutes := int(remaining.Hours()), int(remaining.Minutes())%60
+		if hours == 0 {
+			return fmt.Sprintf("blocked, opens in %dm", minutes)
+		}
+		return fmt.Sprintf("blocked, opens in %dh%02dm", hours, minutes)
+	case at.YearDay() != now.YearDay() || at.Year() != now.Year():
+		return "blocks at " + at.Format("Mon 15:04")
+	default:
+		return "blocks at " + at.Format("15:04")
+	}
+}
+
+// GetReloadDryRunResponse loads and validates the config on disk and describes how it
+// differs from what is currently enforced, without applying anything.
+func GetReloadDryRunResponse(cfg *config.Config) string {
+	var response strings.Builder
+
+	response.WriteString("╔════════════════════════════════════════════════╗\n")
+	response.WriteString("║              RELOAD DRY RUN                    ║\n")
+	response.WriteString("╚════════════════════════════════════════════════╝\n\n")
+
+	newCfg, err := config.LoadConfig()
+	if err != nil {
+		response.WriteString(fmt.Sprintf("ERROR: Failed to load config: %v\n", err))
+		response.WriteString("\nEND\n")
+		return response.String()
+	}
+	if err := config.ValidateConfig(newCfg); err != nil {
+		response.WriteString(fmt.Sprintf("ERROR: Invalid config: %v\n", err))
+		response.WriteString("Reloading would fail; nothing would change.\n")
+		response.WriteString("\nEND\n")
+		return response.String()
+	}
+	warnings := config.Warnings(newCfg)
+	enforcement.PrepareEnforcedConfig(newCfg)
+	keepRuntimeKeywords(newCfg) // A reload keeps them, so they aren't a change
+
+	// cfg.Domains is cleared after enforcement, so compare against the enforced domain cache
+	current := *cfg
+	current.Domains = enforcement.GetEnforcedDomains()
+
+	response.WriteString("Config is valid.\n")
+	for _, warning := range warnings {
+		response.WriteString(fmt.Sprintf("Warning: %s\n", warning))
+	}
+	response.WriteString("\nChanges on reload:\n\n")
+	for _, line := range config.DiffConfigs(&current, newCfg) {
+		response.WriteString("  " + line + "\n")
+	}
+	response.WriteString("\nNothing was applied. Run 'glocker -reload' to apply.\n")
+
+	response.WriteString("\nEND\n")
+	return response.String()
+}
+
 // ProcessReloadRequest reloads the configuration.
 func ProcessReloadRequest(cfg *config.Config) {
 	slog.Debug("Processing reload request")
@@ -212,6 +427,24 @@ func ProcessReloadRequest(cfg *config.Config) {
 		log.Printf("ERROR: Invalid config: %v", err)
 		return
 	}
+	for _, warning := range config.Warnings(newCfg) {
+		log.Printf("Warning: %s", warning)
+	}
+
+	// Remember when newly added domains were added, for the new-block cooldown
+	now := time.Now()
+	var added []string
+	for _, domain := range newCfg.Domains {
+		if _, inConfig := enforcement.IsUnblockable(domain.Name, now); !inConfig {
+			added = append(added, domain.Name)
+		}
+	}
+	recordBlocksAdded(newCfg, added, now)
+
+	// Keep keywords added with -add-keyword that the config file doesn't have
+	if kept := keepRuntimeKeywords(newCfg); len(kept) > 0 {
+		log.Printf("Keeping runtime-added keywords missing from the config file: %s", strings.Join(kept, ", "))
+	}
 
 	// Replace config pointer contents
 	*cfg = *newCfg
@@ -222,35 +455,71 @@ func ProcessReloadRequest(cfg *config.Config) {
 	// Force full enforcement with new config
 	enforcement.ForceEnforcement(cfg)
 
+	// Let the periodic loops pick up changed intervals
+	state.NotifyReload()
+
+	audit.Log(audit.Event{Timestamp: now, Type: audit.EventReload, Source: "socket"})
 	log.Println("✓ Configuration reloaded successfully")
 }
 
-// ProcessUnblockRequest processes a temporary unblock request.
-func ProcessUnblockRequest(cfg *config.Config, hostsStr, reason string) error {
-	slog.Debug("Processing unblock request", "hosts", hostsStr, "reason", reason)
-
-	// Validate reason against configured valid reasons
-	if len(cfg.Unblocking.Reasons) > 0 {
-		validReason := false
-		for _, validR := range cfg.Unblocking.Reasons {
-			if strings.EqualFold(reason, validR) {
-				validReason = true
-				break
-			}
+// keepRuntimeKeywords adds the keywords added with -add-keyword since the daemon
+// started to newCfg's URL and content keywords, so a reload never drops them.
+// Returns the keywords the config file was missing, which the reload would
+// otherwise have lost.
+func keepRuntimeKeywords(newCfg *config.Config) []string {
+	var kept []string
+	keywords := &newCfg.ExtensionKeywords
+	for _, keyword := range state.GetRuntimeKeywords() {
+		missing := false
+		if !slices.Contains(keywords.URLKeywords, keyword) {
+			keywords.URLKeywords = append(keywords.URLKeywords, keyword)
+			missing = true
 		}
-		if !validReason {
-			errMsg := fmt.Sprintf("REJECTED: Invalid reason '%s'. Valid reasons: %s",
-				reason, strings.Join(cfg.Unblocking.Reasons, ", "))
-			log.Println(errMsg)
-			return fmt.Errorf("invalid reason: %s (valid reasons: %s)", reason, strings.Join(cfg.Unblocking.Reasons, ", "))
+		if !slices.Contains(keywords.ContentKeywords, keyword) {
+			keywords.ContentKeywords = append(keywords.ContentKeywords, keyword)
+			missing = true
+		}
+		if missing {
+			kept = append(kept, keyword)
 		}
 	}
+	return kept
+}
 
+// ProcessUnblockRequest processes a temporary unblock request. A non-zero until
+// ends the unblocks then (see ParseUnblockUntil) instead of after their usual duration.
+func ProcessUnblockRequest(cfg *config.Config, hostsStr, reason string, until time.Time) error {
+	slog.Debug("Processing unblock request", "hosts", hostsStr, "reason", reason, "until", until)
+
+	// Validate reason against configured valid reasons
+	reason, note, err := parseUnblockReason(cfg, reason)
+	if err != nil {
+		log.Printf("REJECTED: %v", err)
+		return err
+	}
+
+	now := enforcement.Now()
+	if !until.IsZero() {
+		if err := checkUnblockUntil(cfg, until, now); err != nil {
+			log.Printf("REJECTED: %v", err)
+			return err
+		}
+	}
 	hosts := strings.Split(hostsStr, ",")
 	unblocked := 0
 	rejected := 0
+	limitReached := false
 	var rejectedDomains []string
+	var absoluteLines []string
 	var unblockedDomains []string
+	var grantedLines []string
+
+	// Grants from before the current daily period no longer count toward the limit
+	maxPerDay := cfg.Unblocking.MaxPerDay
+	periodStart := cfg.Unblocking.UnblockPeriodStart(now)
+	if maxPerDay > 0 {
+		state.PruneUnblockGrants(periodStart)
+	}
 
 	for _, host := range hosts {
 		host = strings.TrimSpace(host)
@@ -260,7 +529,16 @@ func ProcessUnblockRequest(cfg *config.Config, hostsStr, reason string) error {
 
 		// Check if domain can be unblocked using cached enforcement state
 		// This avoids reloading the entire config from disk
-		canUnblock, inConfig := enforcement.IsUnblockable(host)
+		canUnblock, inConfig := enforcement.IsUnblockable(host, now)
+
+		// Unblockable domains can still be locked down at certain times
+		if window, absolute := enforcement.ActiveAbsoluteWindow(host, now); absolute {
+			log.Printf("REJECTED UNBLOCK: %s - domain can't be unblocked during %s-%s", host, window.Start, window.End)
+			rejected++
+			rejectedDomains = append(rejectedDomains, host)
+			absoluteLines = append(absoluteLines, fmt.Sprintf("  - %s: can't be unblocked during %s-%s on %s", host, window.Start, window.End, strings.Join(window.Days, ",")))
+			continue
+		}
 
 		if !canUnblock {
 			// Domain is in config but not marked as unblockable - reject
@@ -276,18 +554,52 @@ func ProcessUnblockRequest(cfg *config.Config, hostsStr, reason string) error {
 
 		// Domain is unblockable or not in config (allow for backward compatibility)
 
-		// Add to temporary unblocks
-		duration := time.Duration(cfg.Unblocking.TempUnblockTime) * time.Minute
-		if duration == 0 {
-			duration = 30 * time.Minute
+		// Recently added blocks stay locked until their cooldown has passed
+		if until, cooling := newBlockCooldownUntil(cfg, host, now); cooling {
+			log.Printf("REJECTED UNBLOCK: %s - block was added recently, can be unblocked after %s",
+				host, until.Format("2006-01-02 15:04"))
+			rejected++
+			rejectedDomains = append(rejectedDomains, host)
+			continue
 		}
-		expiresAt := time.Now().Add(duration)
 
-		state.AddTempUnblock(host, expiresAt)
+		// Enforce the daily unblock limit, taking one of the grants left
+		if maxPerDay > 0 && !state.ClaimUnblockGrant(now, periodStart, maxPerDay) {
+			log.Printf("REJECTED UNBLOCK: %s - daily unblock limit of %d reached", host, maxPerDay)
+			limitReached = true
+			rejected++
+			rejectedDomains = append(rejectedDomains, host)
+			continue
+		}
+
+		// Add to temporary unblocks for this domain's duration, or until the requested time
+		duration := unblockDuration(cfg, host)
+		if !until.IsZero() {
+			duration = until.Sub(now).Round(time.Second)
+		}
+		expiresAt := now.Add(duration)
 
-		log.Printf("UNBLOCKED: %s (reason: %s) until %s", host, reason, expiresAt.Format("15:04:05"))
+		state.AddTempUnblockFor(host, expiresAt, duration)
+		if err := web.LogUnblockEntry(cfg, host, reason, now, expiresAt); err != nil {
+			log.Printf("Failed to log unblock entry: %v", err)
+		}
+		audit.Log(audit.Event{Timestamp: now, Type: audit.EventUnblock, Domain: host, Reason: reason, Note: note, Source: "socket", Until: expiresAt})
+
+		if note != "" {
+			log.Printf("UNBLOCKED: %s (reason: %s, note: %s) for %v until %s", host, reason, note, duration, expiresAt.Format("15:04:05"))
+		} else {
+			log.Printf("UNBLOCKED: %s (reason: %s) for %v until %s", host, reason, duration, expiresAt.Format("15:04:05"))
+		}
 		unblocked++
 		unblockedDomains = append(unblockedDomains, host)
+		grantedLines = append(grantedLines, fmt.Sprintf("  - %s: %v (until %s)", host, duration, expiresAt.Format("15:04")))
+	}
+
+	// Persist grants so the daily limit survives daemon restarts
+	if maxPerDay > 0 && unblocked > 0 {
+		if err := state.SaveUnblockGrants(cfg.Unblocking.UnblockStateFile()); err != nil {
+			log.Printf("Failed to save unblock grants: %v", err)
+		}
 	}
 
 	// Log summary
@@ -304,37 +616,331 @@ func ProcessUnblockRequest(cfg *config.Config, hostsStr, reason string) error {
 	// Force enforcement to apply changes immediately
 	if unblocked > 0 {
 		enforcement.ForceEnforcement(cfg)
+		sendUnblockEmail(cfg, reason, note, until, grantedLines, absoluteLines)
+	} else if len(absoluteLines) > 0 {
+		sendAbsoluteWindowEmail(cfg, reason, note, absoluteLines)
 	}
 
 	// Return error if all domains were rejected
+	if limitReached && unblocked == 0 {
+		return fmt.Errorf("daily unblock limit reached (%d per day), resets at %s",
+			maxPerDay, periodStart.AddDate(0, 0, 1).Format("2006-01-02 15:04"))
+	}
+	if len(absoluteLines) == rejected && unblocked == 0 && rejected > 0 {
+		return fmt.Errorf("all domains rejected: %s (inside an absolute window, can't be unblocked right now)", strings.Join(rejectedDomains, ", "))
+	}
 	if rejected > 0 && unblocked == 0 {
-		return fmt.Errorf("all domains rejected: %s (permanently blocked, not marked as unblockable)", strings.Join(rejectedDomains, ", "))
+		return fmt.Errorf("all domains rejected: %s (permanently blocked, not marked as unblockable, or recently added)", strings.Join(rejectedDomains, ", "))
 	}
 
 	return nil
 }
 
-// ProcessBlockRequest adds domains to the block list.
-func ProcessBlockRequest(cfg *config.Config, hostsStr string) {
-	slog.Debug("Processing block request", "hosts", hostsStr)
+// ParseUnblockUntil parses the HH:MM end time of an unblock requested with
+// -until as a time today. Times that have passed, or are more than
+// unblocking.max_until_minutes ahead, are rejected, as is every time when
+// max_until_minutes is unset.
+func ParseUnblockUntil(cfg *config.Config, until string, now time.Time) (time.Time, error) {
+	parsed, err := time.Parse("15:04", strings.TrimSpace(until))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid until time %q (use HH:MM)", until)
+	}
+	end := time.Date(now.Year(), now.Month(), now.Day(), parsed.Hour(), parsed.Minute(), 0, 0, now.Location())
+	if err := checkUnblockUntil(cfg, end, now); err != nil {
+		return time.Time{}, err
+	}
+	return end, nil
+}
 
-	hosts := strings.Split(hostsStr, ",")
-	for _, host := range hosts {
-		host = strings.TrimSpace(host)
-		if host == "" {
+// checkUnblockUntil rejects an unblock end time that isn't in the future or is
+// further ahead than unblocking.max_until_minutes. -until can outlast the
+// unblock durations the config sets, so it is disabled unless
+// max_until_minutes is set.
+func checkUnblockUntil(cfg *config.Config, until, now time.Time) error {
+	maxUntil := cfg.Unblocking.MaxUntil()
+	if maxUntil <= 0 {
+		return fmt.Errorf("-until is disabled (set unblocking.max_until_minutes to allow it)")
+	}
+	if !until.After(now) {
+		return fmt.Errorf("until time %s has already passed", until.Format("15:04"))
+	}
+	if until.Sub(now) > maxUntil {
+		return fmt.Errorf("until time %s is more than %v away (max_until_minutes), latest allowed is %s",
+			until.Format("15:04"), maxUntil, now.Add(maxUntil).Format("15:04"))
+	}
+	return nil
+}
+
+// parseUnblockReason checks an unblock reason against unblocking.reasons,
+// min_reason_length and require_note. With require_note the reason must be
+// "category:note": the category is checked against the configured reasons and the
+// note against min_reason_length. Otherwise the whole reason is. It returns the
+// reason (the category with require_note) and the note.
+func parseUnblockReason(cfg *config.Config, reason string) (string, string, error) {
+	reason = strings.TrimSpace(reason)
+	note := ""
+	if cfg.Unblocking.RequireNote {
+		category, rest, found := strings.Cut(reason, ":")
+		reason, note = strings.TrimSpace(category), strings.TrimSpace(rest)
+		if !found || note == "" {
+			return "", "", fmt.Errorf("a note is required: use 'domains:reason:note', e.g. 'example.com:work:reading the API docs'")
+		}
+	}
+
+	if !web.IsValidUnblockReason(cfg, reason) {
+		return "", "", fmt.Errorf("invalid reason: %s (valid reasons: %s)", reason, strings.Join(cfg.Unblocking.Reasons, ", "))
+	}
+
+	checked, name := reason, "reason"
+	if cfg.Unblocking.RequireNote {
+		checked, name = note, "note"
+	}
+	if length := utf8.RuneCountInString(checked); length < cfg.Unblocking.MinReasonLength {
+		return "", "", fmt.Errorf("%s too short: %d characters, at least %d required", name, length, cfg.Unblocking.MinReasonLength)
+	}
+
+	return reason, note, nil
+}
+
+// unblockDuration returns how long a temporary unblock of domain lasts: the domain's
+// unblock_minutes if set, otherwise unblocking.temp_unblock_time (default 30 minutes).
+// An active profile's temp_unblock_time replaces the default and caps per-domain values.
+func unblockDuration(cfg *config.Config, domain string) time.Duration {
+	minutes := cfg.Unblocking.TempUnblockTime
+	profileMinutes := 0
+	if activeProfile, _ := state.GetActiveProfile(); activeProfile != "" {
+		if profile, ok := cfg.Profiles[activeProfile]; ok {
+			profileMinutes = profile.TempUnblockTime
+		}
+	}
+	if profileMinutes > 0 {
+		minutes = profileMinutes
+	}
+	if domainMinutes := enforcement.GetUnblockMinutes(domain); domainMinutes > 0 && (profileMinutes <= 0 || domainMinutes < profileMinutes) {
+		minutes = domainMinutes
+	}
+	if minutes <= 0 {
+		minutes = 30
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// sendUnblockEmail notifies the accountability partner of granted unblocks,
+// one line per domain with the duration it was granted for, and of domains in the
+// same request refused because of their absolute_windows. A non-zero until is
+// the end time the unblock was requested with.
+func sendUnblockEmail(cfg *config.Config, reason, note string, until time.Time, grantedLines, absoluteLines []string) {
+	subject := "GLOCKER ALERT: Temporary Unblock"
+	body := fmt.Sprintf("Domains were temporarily unblocked at %s.\n\n", enforcement.Now().Format("2006-01-02 15:04:05"))
+	body += fmt.Sprintf("Reason: %s\n", reason)
+	if note != "" {
+		body += fmt.Sprintf("Note: %s\n", note)
+	}
+	if !until.IsZero() {
+		body += fmt.Sprintf("Requested until: %s\n", until.Format("15:04"))
+	}
+	body += strings.Join(grantedLines, "\n") + "\n\n"
+	if len(absoluteLines) > 0 {
+		body += "Refused (absolute window):\n"
+		body += strings.Join(absoluteLines, "\n") + "\n\n"
+	}
+	body += "This is an automated alert from Glocker."
+
+	if err := notify.SendEmail(cfg, notify.EventTemporaryUnblock, subject, body); err != nil {
+		log.Printf("Failed to send unblock email: %v", err)
+	}
+}
+
+// sendAbsoluteWindowEmail notifies the accountability partner of an unblock request
+// refused because every domain in it was inside an absolute window.
+func sendAbsoluteWindowEmail(cfg *config.Config, reason, note string, absoluteLines []string) {
+	subject := "GLOCKER ALERT: Unblock Refused"
+	body := fmt.Sprintf("An unblock was requested at %s during an absolute window and refused.\n\n", enforcement.Now().Format("2006-01-02 15:04:05"))
+	body += fmt.Sprintf("Reason given: %s\n", reason)
+	if note != "" {
+		body += fmt.Sprintf("Note: %s\n", note)
+	}
+	body += strings.Join(absoluteLines, "\n") + "\n\n"
+	body += "This is an automated alert from Glocker."
+
+	if err := notify.SendEmail(cfg, notify.EventUnblockRefused, subject, body); err != nil {
+		log.Printf("Failed to send unblock email: %v", err)
+	}
+}
+
+// blockAddedStateFile is where the add times new_block_cooldown checks are
+// persisted (replaced in tests).
+var blockAddedStateFile = config.BlockAddedStateFile
+
+// recordBlocksAdded remembers when domains were added to the block list, for the
+// new-block cooldown. The add times still inside the cooldown are persisted, so a
+// daemon restart doesn't end it early.
+func recordBlocksAdded(cfg *config.Config, domains []string, now time.Time) {
+	for _, domain := range domains {
+		state.RecordBlockAdded(domain, now)
+	}
+	cooldown := cfg.Unblocking.NewBlockCooldown
+	if cooldown <= 0 || len(domains) == 0 {
+		return
+	}
+	state.PruneBlockAddedTimes(now.Add(-time.Duration(cooldown) * time.Hour))
+	if err := state.SaveBlockAddedTimes(blockAddedStateFile); err != nil {
+		log.Printf("Failed to save block add times: %v", err)
+	}
+}
+
+// newBlockCooldownUntil reports whether domain was added to the block list within the
+// configured new_block_cooldown, and when that cooldown ends.
+func newBlockCooldownUntil(cfg *config.Config, domain string, now time.Time) (time.Time, bool) {
+	if cfg.Unblocking.NewBlockCooldown <= 0 {
+		return time.Time{}, false
+	}
+	added, ok := state.GetBlockAddedTime(domain)
+	if !ok {
+		return time.Time{}, false
+	}
+	until := added.Add(time.Duration(cfg.Unblocking.NewBlockCooldown) * time.Hour)
+	return until, now.Before(until)
+}
+
+// GetUnblocksResponse lists the active temporary unblocks with their remaining time.
+func GetUnblocksResponse() string {
+	var response strings.Builder
+
+	now := enforcement.Now()
+	active := 0
+	for _, unblock := range state.GetTempUnblocks() {
+		if !now.Before(unblock.ExpiresAt) {
 			continue
 		}
+		active++
+		remaining := unblock.ExpiresAt.Sub(now).Round(time.Second)
+		response.WriteString(fmt.Sprintf("%s (expires %s, %v left)\n", unblock.Domain, unblock.ExpiresAt.Format("15:04:05"), remaining))
+	}
+	if active == 0 {
+		response.WriteString("No active temporary unblocks\n")
+	}
+
+	response.WriteString("END\n")
+	return response.String()
+}
+
+// GetHistoryResponse lists the recent enforcement decisions, oldest first, with
+// why each was made and how many domains were blocked afterwards.
+func GetHistoryResponse() string {
+	var response strings.Builder
+
+	history := state.GetEnforcementHistory()
+	for _, event := range history {
+		response.WriteString(fmt.Sprintf("%s  %-16s  %6d blocked  %s\n",
+			event.Time.Format("2006-01-02 15:04:05"), event.Action, event.BlockedCount, event.Reason))
+	}
+	if len(history) == 0 {
+		response.WriteString("No enforcement actions recorded since the daemon started\n")
+	}
+
+	response.WriteString("END\n")
+	return response.String()
+}
+
+// ProcessRevokeRequest ends the temporary unblock of domain before it expires,
+// blocking it again right away, and reports the revocation to the accountability
+// partner.
+func ProcessRevokeRequest(cfg *config.Config, domain string) error {
+	slog.Debug("Processing revoke request", "domain", domain)
+
+	domain = strings.TrimSpace(domain)
+	if domain == "" {
+		return fmt.Errorf("no domain specified")
+	}
+
+	now := enforcement.Now()
+	var revoked []state.TempUnblock
+	for _, unblock := range state.RemoveTempUnblock(domain) {
+		if now.Before(unblock.ExpiresAt) {
+			revoked = append(revoked, unblock)
+		}
+	}
+	if len(revoked) == 0 {
+		return fmt.Errorf("%s is not temporarily unblocked", domain)
+	}
+
+	expiresAt := revoked[len(revoked)-1].ExpiresAt
+	audit.Log(audit.Event{Timestamp: now, Type: audit.EventRevoke, Domain: domain, Source: "socket", Until: expiresAt})
+	log.Printf("REVOKED UNBLOCK: %s (was unblocked until %s)", domain, expiresAt.Format("15:04:05"))
+
+	// Force enforcement to block the domain again immediately
+	enforcement.ForceEnforcement(cfg)
+
+	subject := "GLOCKER ALERT: Unblock Revoked"
+	body := fmt.Sprintf("The temporary unblock of %s was revoked at %s, %v before it would have expired.\n\n",
+		domain, now.Format("2006-01-02 15:04:05"), expiresAt.Sub(now).Round(time.Minute))
+	body += "The domain is blocked again.\n\n"
+	body += "This is an automated alert from Glocker."
+	if err := notify.SendEmail(cfg, notify.EventUnblockRevoked, subject, body); err != nil {
+		log.Printf("Failed to send revoke email: %v", err)
+	}
+
+	return nil
+}
+
+// ProcessBlockRequest adds domains to the block list. The request is rejected
+// if the config blocks one of them only during its time windows: the overlay
+// can't override a config entry, so the block wouldn't survive a restart.
+func ProcessBlockRequest(cfg *config.Config, hostsStr string) error {
+	slog.Debug("Processing block request", "hosts", hostsStr)
+
+	var hosts []string
+	for _, host := range strings.Split(hostsStr, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	if windowed := timeWindowedDomains(hosts); len(windowed) > 0 {
+		err := fmt.Errorf("can't block %s: the config only blocks it during its time_windows, change them there instead", strings.Join(windowed, ", "))
+		log.Printf("REJECTED BLOCK: %v", err)
+		return err
+	}
+
+	now := time.Now()
+	var added, blocked []string
+	for _, host := range hosts {
 
 		// Add to config domains (no time windows = always blocked by default)
 		cfg.Domains = append(cfg.Domains, config.Domain{
 			Name: host,
 		})
+		added = append(added, host)
+		audit.Log(audit.Event{Timestamp: now, Type: audit.EventBlock, Domain: host, Source: "socket"})
+
+		// Domains the config already lists are loaded with it; only persist new ones
+		if _, inConfig := enforcement.IsUnblockable(host, now); !inConfig {
+			blocked = append(blocked, host)
+		}
 
 		log.Printf("BLOCKED: %s", host)
 	}
+	recordBlocksAdded(cfg, added, now)
+
+	// Persist to the runtime overlay, which the enforcement below reloads from
+	if err := config.AppendToOverlay(nil, blocked); err != nil {
+		log.Printf("Warning: Failed to persist blocked domains to %s: %v", config.OverlayPath(), err)
+	}
 
 	// Force enforcement to apply changes immediately
 	enforcement.ForceEnforcement(cfg)
+	return nil
+}
+
+// timeWindowedDomains returns the hosts the config blocks only during time windows.
+func timeWindowedDomains(hosts []string) []string {
+	var windowed []string
+	for _, host := range hosts {
+		if enforcement.IsTimeWindowed(host) && !slices.Contains(windowed, host) {
+			windowed = append(windowed, host)
+		}
+	}
+	return windowed
 }
 
 // ProcessPanicRequest activates panic mode for the specified duration.
@@ -344,6 +950,7 @@ func ProcessPanicRequest(cfg *config.Config, minutes int) {
 	now := time.Now()
 	panicUntil := now.Add(time.Duration(minutes) * time.Minute)
 	state.SetPanicUntil(panicUntil)
+	audit.Log(audit.Event{Timestamp: now, Type: audit.EventPanic, Reason: fmt.Sprintf("%d minutes", minutes), Source: "socket", Until: panicUntil})
 
 	log.Printf("⚠️  PANIC MODE ACTIVATED for %d minutes (until %s)", minutes, panicUntil.Format("15:04:05"))
 
@@ -352,3 +959,130 @@ func ProcessPanicRequest(cfg *config.Config, minutes int) {
 		// The monitoring goroutine will handle suspension
 	}
 }
+
+// PauseChallenge returns the sentence that must be typed to pause enforcement for minutes.
+func PauseChallenge(minutes int) string {
+	return fmt.Sprintf("I choose to disable all blocking for %d minutes", minutes)
+}
+
+// CheckPauseRequest rejects a pause that isn't allowed: pausing is disabled
+// (max_pause_minutes unset), the pause is longer than max_pause_minutes, or
+// enforcement is already paused.
+func CheckPauseRequest(cfg *config.Config, minutes int, now time.Time) error {
+	if minutes <= 0 {
+		return fmt.Errorf("pause minutes must be a positive integer")
+	}
+	if cfg.MaxPauseMinutes <= 0 {
+		return fmt.Errorf("pausing is disabled (set max_pause_minutes to allow it)")
+	}
+	if minutes > cfg.MaxPauseMinutes {
+		return fmt.Errorf("cannot pause for %d minutes, the maximum is %d (max_pause_minutes)", minutes, cfg.MaxPauseMinutes)
+	}
+	if pausedUntil := state.GetPausedUntil(); now.Before(pausedUntil) {
+		return fmt.Errorf("already paused until %s", pausedUntil.Format("15:04"))
+	}
+	return nil
+}
+
+// CheckPauseResponse verifies the typed pause challenge. It is only accepted once
+// mindful_delay seconds have passed since the challenge was issued.
+func CheckPauseResponse(cfg *config.Config, minutes int, typed string, issuedAt, now time.Time) error {
+	if wait := time.Duration(cfg.MindfulDelay) * time.Second; now.Sub(issuedAt) < wait {
+		return fmt.Errorf("answered too quickly, wait %d seconds before typing the challenge", cfg.MindfulDelay)
+	}
+	if strings.TrimSpace(typed) != PauseChallenge(minutes) {
+		return fmt.Errorf("challenge text doesn't match, pause cancelled")
+	}
+	return nil
+}
+
+// ProcessPauseRequest suspends hosts, firewall and sudoers enforcement for the given
+// minutes and reports the pause to the accountability partner. Enforcement resumes
+// on its own once the pause runs out. Returns the time the pause ends.
+func ProcessPauseRequest(cfg *config.Config, minutes int) (time.Time, error) {
+	slog.Debug("Processing pause request", "minutes", minutes)
+
+	now := enforcement.Now()
+	if err := CheckPauseRequest(cfg, minutes, now); err != nil {
+		return time.Time{}, err
+	}
+
+	pausedUntil := now.Add(time.Duration(minutes) * time.Minute)
+	state.SetPausedUntil(pausedUntil)
+	log.Printf("⏸️  ENFORCEMENT PAUSED for %d minutes (until %s)", minutes, pausedUntil.Format("15:04:05"))
+
+	enforcement.SuspendEnforcement(cfg)
+
+	if cfg.NotifiesPartner() {
+		subject := "GLOCKER ALERT: Blocking Paused"
+		body := fmt.Sprintf("All blocking was paused at %s for %d minutes (until %s).\n\n",
+			now.Format("2006-01-02 15:04:05"), minutes, pausedUntil.Format("15:04"))
+		body += "Hosts, firewall and sudoers restrictions are lifted until then and resume automatically.\n\n"
+		body += "This is an automated alert from Glocker."
+
+		if err := notify.SendEmail(cfg, notify.EventBlockingPaused, subject, body); err != nil {
+			log.Printf("Failed to send pause email: %v", err)
+		}
+	}
+
+	return pausedUntil, nil
+}
+
+// UninstallConfirmWindow is how long after its cooldown a pending uninstall can be
+// confirmed; after that, the next -uninstall starts a new cooldown.
+const UninstallConfirmWindow = 24 * time.Hour
+
+// ProcessUninstallRequest runs the two phases of an uninstall under
+// uninstall.cooldown_minutes. Without a cooldown the uninstall proceeds at once.
+// Otherwise the first request is recorded as pending and reported to the
+// accountability partner, a request before the cooldown has passed is rejected,
+// and one after it confirms the uninstall. Returns whether to uninstall now and,
+// if not, when the pending uninstall can be confirmed.
+func ProcessUninstallRequest(cfg *config.Config, reason string) (bool, time.Time, error) {
+	cooldown := time.Duration(cfg.Uninstall.CooldownMinutes) * time.Minute
+	if cooldown <= 0 {
+		return true, time.Time{}, nil
+	}
+
+	now := enforcement.Now()
+	pending, ok := state.GetPendingUninstall()
+	if ok && !now.Before(pending.UnlockAt.Add(UninstallConfirmWindow)) {
+		log.Printf("Pending uninstall from %s was never confirmed, starting over", pending.RequestedAt.Format("2006-01-02 15:04"))
+		state.ClearPendingUninstall()
+		ok = false
+	}
+
+	if !ok {
+		pending = state.PendingUninstall{Reason: reason, RequestedAt: now, UnlockAt: now.Add(cooldown)}
+		state.SetPendingUninstall(pending)
+		log.Printf("UNINSTALL SCHEDULED: can be confirmed after %s (reason: %s)", pending.UnlockAt.Format("2006-01-02 15:04"), reason)
+		sendUninstallScheduledEmail(cfg, pending)
+		return false, pending.UnlockAt, nil
+	}
+
+	if now.Before(pending.UnlockAt) {
+		return false, pending.UnlockAt, fmt.Errorf("uninstall is pending, confirm it after %s", pending.UnlockAt.Format("2006-01-02 15:04"))
+	}
+
+	state.ClearPendingUninstall()
+	log.Printf("UNINSTALL CONFIRMED: requested %s (reason: %s)", pending.RequestedAt.Format("2006-01-02 15:04"), pending.Reason)
+	return true, time.Time{}, nil
+}
+
+// sendUninstallScheduledEmail tells the accountability partner an uninstall was requested.
+func sendUninstallScheduledEmail(cfg *config.Config, pending state.PendingUninstall) {
+	if !cfg.NotifiesPartner() {
+		return
+	}
+
+	subject := "GLOCKER ALERT: Uninstall Requested"
+	body := fmt.Sprintf("Uninstalling glocker was requested at %s.\n\n", pending.RequestedAt.Format("2006-01-02 15:04:05"))
+	body += fmt.Sprintf("Reason: %s\n", pending.Reason)
+	body += fmt.Sprintf("It can be confirmed with a second -uninstall after %s, until %s.\n\n",
+		pending.UnlockAt.Format("2006-01-02 15:04"), pending.UnlockAt.Add(UninstallConfirmWindow).Format("2006-01-02 15:04"))
+	body += "This is an automated alert from Glocker."
+
+	if err := notify.SendEmail(cfg, notify.EventUninstallRequested, subject, body); err != nil {
+		log.Printf("Failed to send uninstall email: %v", err)
+	}
+}
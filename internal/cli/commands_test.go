@@ -1,13 +1,22 @@
 package cli
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
 	"glocker/internal/config"
 	"glocker/internal/enforcement"
+	"glocker/internal/schema"
 	"glocker/internal/state"
+	"glocker/internal/utils"
+	"glocker/internal/web"
 )
 
 func TestGetStatusResponse(t *testing.T) {
@@ -28,6 +37,25 @@ func TestGetStatusResponse(t *testing.T) {
 	}
 }
 
+func TestGetStatusResponse_EnforcementInProgress(t *testing.T) {
+	cfg := &config.Config{}
+
+	state.SetHostsWriteProgress(496000, 800000)
+	defer state.ClearHostsWriteProgress()
+
+	if response := GetStatusResponse(cfg); !strings.Contains(response, "enforcement in progress: 62%") {
+		t.Errorf("Status should report write progress, got:\n%s", response)
+	}
+	if response := GetInfoResponse(cfg); !strings.Contains(response, "enforcement in progress: 62%") {
+		t.Errorf("Info should report write progress, got:\n%s", response)
+	}
+
+	state.ClearHostsWriteProgress()
+	if response := GetStatusResponse(cfg); strings.Contains(response, "enforcement in progress") {
+		t.Error("Status should not report progress when no write is ongoing")
+	}
+}
+
 func TestGetStatusResponse_WithExtensionKeywords(t *testing.T) {
 	cfg := &config.Config{
 		Domains: []config.Domain{
@@ -142,7 +170,7 @@ func TestProcessUnblockRequest_RejectsPermanentDomains(t *testing.T) {
 	state.SetTempUnblocks([]state.TempUnblock{})
 
 	// Try to unblock both domains - should succeed for unblockable.com, reject permanent.com
-	err := ProcessUnblockRequest(cfg, "permanent.com,unblockable.com", "work")
+	err := ProcessUnblockRequest(cfg, "permanent.com,unblockable.com", "work", time.Time{})
 	if err != nil {
 		t.Errorf("Should not error when at least one domain is unblockable, got: %v", err)
 	}
@@ -167,6 +195,67 @@ func TestProcessUnblockRequest_RejectsPermanentDomains(t *testing.T) {
 	}
 }
 
+func TestProcessUnblockRequest_NewBlockCooldown(t *testing.T) {
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{Name: "fresh.com", Unblockable: true},
+			{Name: "settled.com", Unblockable: true},
+		},
+		Unblocking: config.UnblockingConfig{
+			TempUnblockTime:  30,
+			NewBlockCooldown: 24,
+		},
+	}
+	enforcement.InitializeTestCache(cfg.Domains)
+	state.SetTempUnblocks([]state.TempUnblock{})
+
+	now := time.Now()
+	state.RecordBlockAdded("fresh.com", now.Add(-time.Hour))
+	state.RecordBlockAdded("settled.com", now.Add(-25*time.Hour))
+
+	if err := ProcessUnblockRequest(cfg, "fresh.com,settled.com", "work", time.Time{}); err != nil {
+		t.Fatalf("Should not error when one domain is past its cooldown, got: %v", err)
+	}
+
+	unblocks := state.GetTempUnblocks()
+	if len(unblocks) != 1 || unblocks[0].Domain != "settled.com" {
+		t.Errorf("Expected only settled.com to be unblocked, got %+v", unblocks)
+	}
+
+	// -block'd domains aren't in the config cache but still get the cooldown
+	state.RecordBlockAdded("blocked-now.com", now)
+	if err := ProcessUnblockRequest(cfg, "blocked-now.com", "work", time.Time{}); err == nil {
+		t.Error("Expected a just-blocked domain to be rejected during its cooldown")
+	}
+
+	// With the cooldown disabled, normal rules apply
+	cfg.Unblocking.NewBlockCooldown = 0
+	if err := ProcessUnblockRequest(cfg, "fresh.com", "work", time.Time{}); err != nil {
+		t.Errorf("Expected unblock to succeed without cooldown, got: %v", err)
+	}
+}
+
+func TestRecordBlocksAdded_SurvivesRestart(t *testing.T) {
+	orig := blockAddedStateFile
+	blockAddedStateFile = filepath.Join(t.TempDir(), "block-added.json")
+	defer func() { blockAddedStateFile = orig }()
+	state.SetBlockAddedTimes(nil)
+	defer state.SetBlockAddedTimes(nil)
+
+	now := time.Now()
+	cfg := &config.Config{Unblocking: config.UnblockingConfig{NewBlockCooldown: 24}}
+	recordBlocksAdded(cfg, []string{"fresh.com"}, now)
+
+	// A restarted daemon loads the add times, so the cooldown still applies
+	state.SetBlockAddedTimes(nil)
+	if err := state.LoadBlockAddedTimes(blockAddedStateFile); err != nil {
+		t.Fatalf("LoadBlockAddedTimes failed: %v", err)
+	}
+	if until, cooling := newBlockCooldownUntil(cfg, "fresh.com", now.Add(time.Hour)); !cooling || !until.Equal(now.Add(24*time.Hour)) {
+		t.Errorf("Expected fresh.com to stay in its cooldown after a restart, got until=%v cooling=%v", until, cooling)
+	}
+}
+
 func TestProcessUnblockRequest_AllPermanentDomainsError(t *testing.T) {
 	cfg := &config.Config{
 		Domains: []config.Domain{
@@ -186,7 +275,7 @@ func TestProcessUnblockRequest_AllPermanentDomainsError(t *testing.T) {
 	state.SetTempUnblocks([]state.TempUnblock{})
 
 	// Try to unblock only permanent domains - should return error
-	err := ProcessUnblockRequest(cfg, "permanent1.com,permanent2.com", "work")
+	err := ProcessUnblockRequest(cfg, "permanent1.com,permanent2.com", "work", time.Time{})
 	if err == nil {
 		t.Error("Expected error when all domains are permanently blocked")
 	}
@@ -236,7 +325,7 @@ func TestProcessUnblockRequest_ValidatesReasons(t *testing.T) {
 			// Clear temp unblocks before each test
 			state.SetTempUnblocks([]state.TempUnblock{})
 
-			err := ProcessUnblockRequest(cfg, "example.com", tt.reason)
+			err := ProcessUnblockRequest(cfg, "example.com", tt.reason, time.Time{})
 
 			if tt.shouldError {
 				if err == nil {
@@ -261,6 +350,80 @@ func TestProcessUnblockRequest_ValidatesReasons(t *testing.T) {
 	}
 }
 
+func TestParseUnblockReason(t *testing.T) {
+	reasons := []string{"work", "research"}
+	tests := []struct {
+		name       string
+		unblocking config.UnblockingConfig
+		reason     string
+		wantReason string
+		wantNote   string
+		wantErr    string
+	}{
+		{"plain reason", config.UnblockingConfig{Reasons: reasons}, "Work", "Work", "", ""},
+		{"invalid reason", config.UnblockingConfig{Reasons: reasons}, "gaming", "", "", "invalid reason"},
+		{"reason long enough", config.UnblockingConfig{MinReasonLength: 4}, "work", "work", "", ""},
+		{"reason too short", config.UnblockingConfig{MinReasonLength: 5}, "work", "", "", "reason too short: 4 characters, at least 5 required"},
+		{"note given", config.UnblockingConfig{Reasons: reasons, RequireNote: true}, "work: reading the API docs", "work", "reading the API docs", ""},
+		{"note missing", config.UnblockingConfig{Reasons: reasons, RequireNote: true}, "work", "", "", "a note is required"},
+		{"note empty", config.UnblockingConfig{Reasons: reasons, RequireNote: true}, "work:  ", "", "", "a note is required"},
+		{"invalid category with note", config.UnblockingConfig{Reasons: reasons, RequireNote: true}, "gaming:just one match", "", "", "invalid reason: gaming"},
+		{"note too short", config.UnblockingConfig{Reasons: reasons, RequireNote: true, MinReasonLength: 10}, "work:docs", "", "", "note too short: 4 characters, at least 10 required"},
+		{"note long enough", config.UnblockingConfig{Reasons: reasons, RequireNote: true, MinReasonLength: 10}, "research:papers on sleep", "research", "papers on sleep", ""},
+		{"note keeps colons", config.UnblockingConfig{RequireNote: true}, "work:call at 10:30", "work", "call at 10:30", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Unblocking: tt.unblocking}
+			reason, note, err := parseUnblockReason(cfg, tt.reason)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("parseUnblockReason(%q) error = %v, want %q", tt.reason, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseUnblockReason(%q) failed: %v", tt.reason, err)
+			}
+			if reason != tt.wantReason || note != tt.wantNote {
+				t.Errorf("parseUnblockReason(%q) = %q, %q; want %q, %q", tt.reason, reason, note, tt.wantReason, tt.wantNote)
+			}
+		})
+	}
+}
+
+func TestProcessUnblockRequest_RequireNote(t *testing.T) {
+	cfg := &config.Config{
+		Domains: []config.Domain{{Name: "news.com", Unblockable: true}},
+		Unblocking: config.UnblockingConfig{
+			TempUnblockTime: 30,
+			Reasons:         []string{"work"},
+			RequireNote:     true,
+			MinReasonLength: 10,
+		},
+	}
+	enforcement.InitializeTestCache(cfg.Domains)
+	defer enforcement.InitializeTestCache(nil)
+	state.SetTempUnblocks([]state.TempUnblock{})
+	defer state.SetTempUnblocks([]state.TempUnblock{})
+
+	for _, reason := range []string{"work", "work:docs"} {
+		if err := ProcessUnblockRequest(cfg, "news.com", reason, time.Time{}); err == nil {
+			t.Errorf("Expected reason %q to be rejected", reason)
+		}
+	}
+	if unblocks := state.GetTempUnblocks(); len(unblocks) != 0 {
+		t.Fatalf("Expected no unblocks after rejected reasons, got %v", unblocks)
+	}
+
+	if err := ProcessUnblockRequest(cfg, "news.com", "work:reading the API docs", time.Time{}); err != nil {
+		t.Fatalf("Expected the unblock with a note to succeed, got: %v", err)
+	}
+	if unblocks := state.GetTempUnblocks(); len(unblocks) != 1 || unblocks[0].Domain != "news.com" {
+		t.Errorf("Expected news.com to be unblocked, got %v", unblocks)
+	}
+}
+
 func TestProcessUnblockRequest_NoReasonValidationWhenListEmpty(t *testing.T) {
 	cfg := &config.Config{
 		Domains: []config.Domain{
@@ -279,7 +442,7 @@ func TestProcessUnblockRequest_NoReasonValidationWhenListEmpty(t *testing.T) {
 	state.SetTempUnblocks([]state.TempUnblock{})
 
 	// Try to unblock with any reason (should work when list is empty)
-	err := ProcessUnblockRequest(cfg, "example.com", "any reason at all")
+	err := ProcessUnblockRequest(cfg, "example.com", "any reason at all", time.Time{})
 	if err != nil {
 		t.Errorf("Expected no error when reasons list is empty, got: %v", err)
 	}
@@ -290,3 +453,1084 @@ func TestProcessUnblockRequest_NoReasonValidationWhenListEmpty(t *testing.T) {
 		t.Errorf("Expected 1 unblock, got %d", len(unblocks))
 	}
 }
+
+func TestProcessUnblockRequest_PerDomainDuration(t *testing.T) {
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{Name: "short.com", Unblockable: true, UnblockMinutes: 10},
+			{Name: "default.com", Unblockable: true},
+			{Name: "long.com", Unblockable: true, UnblockMinutes: 120},
+		},
+		Unblocking: config.UnblockingConfig{
+			TempUnblockTime: 30,
+		},
+	}
+	enforcement.InitializeTestCache(cfg.Domains)
+	state.SetTempUnblocks([]state.TempUnblock{})
+
+	before := time.Now()
+	if err := ProcessUnblockRequest(cfg, "short.com,default.com,long.com", "work", time.Time{}); err != nil {
+		t.Fatalf("Expected unblock to succeed, got: %v", err)
+	}
+
+	expected := map[string]time.Duration{
+		"short.com":   10 * time.Minute,
+		"default.com": 30 * time.Minute,
+		"long.com":    120 * time.Minute,
+	}
+	unblocks := state.GetTempUnblocks()
+	if len(unblocks) != len(expected) {
+		t.Fatalf("Expected %d unblocks, got %+v", len(expected), unblocks)
+	}
+	for _, unblock := range unblocks {
+		want := expected[unblock.Domain]
+		if unblock.Duration != want {
+			t.Errorf("%s: expected duration %v, got %v", unblock.Domain, want, unblock.Duration)
+		}
+		if got := unblock.ExpiresAt.Sub(before); got < want || got > want+time.Minute {
+			t.Errorf("%s: expected expiry about %v from now, got %v", unblock.Domain, want, got)
+		}
+	}
+
+	response := GetStatusResponse(cfg)
+	for _, line := range []string{"short.com (10m0s granted", "default.com (30m0s granted", "long.com (2h0m0s granted"} {
+		if !strings.Contains(response, line) {
+			t.Errorf("Status should contain %q, got:\n%s", line, response)
+		}
+	}
+}
+
+func TestProcessUnblockRequest_AbsoluteWindows(t *testing.T) {
+	allWeek := []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{Name: "night.com", Unblockable: true, AbsoluteWindows: []config.TimeWindow{
+				{Start: "22:00", End: "06:00", Days: allWeek},
+			}},
+			{Name: "anytime.com", Unblockable: true},
+		},
+		Unblocking: config.UnblockingConfig{TempUnblockTime: 30},
+	}
+	enforcement.InitializeTestCache(cfg.Domains)
+	defer enforcement.InitializeTestCache(nil)
+
+	fake := utils.NewFakeTimeProvider(time.Time{})
+	enforcement.SetClock(fake)
+	defer enforcement.SetClock(utils.DefaultTimeProvider{})
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		allowed bool
+	}{
+		{"before the window", time.Date(2026, 1, 6, 21, 59, 0, 0, time.Local), true},
+		{"in the window", time.Date(2026, 1, 6, 23, 0, 0, 0, time.Local), false},
+		{"after midnight in the window", time.Date(2026, 1, 7, 5, 30, 0, 0, time.Local), false},
+		{"after the window", time.Date(2026, 1, 7, 6, 1, 0, 0, time.Local), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake.Set(tt.now)
+			state.SetTempUnblocks([]state.TempUnblock{})
+			defer state.SetTempUnblocks([]state.TempUnblock{})
+
+			err := ProcessUnblockRequest(cfg, "night.com", "work", time.Time{})
+			if tt.allowed && err != nil {
+				t.Errorf("Expected unblock outside the absolute window to succeed, got: %v", err)
+			}
+			if !tt.allowed && (err == nil || !strings.Contains(err.Error(), "absolute window")) {
+				t.Errorf("Expected an absolute window rejection, got: %v", err)
+			}
+
+			if canUnblock, _ := enforcement.IsUnblockable("night.com", tt.now); canUnblock != tt.allowed {
+				t.Errorf("IsUnblockable() = %v, want %v", canUnblock, tt.allowed)
+			}
+		})
+	}
+
+	// Other domains in the same request are still unblocked
+	fake.Set(time.Date(2026, 1, 6, 23, 0, 0, 0, time.Local))
+	state.SetTempUnblocks([]state.TempUnblock{})
+	defer state.SetTempUnblocks([]state.TempUnblock{})
+	if err := ProcessUnblockRequest(cfg, "night.com,anytime.com", "work", time.Time{}); err != nil {
+		t.Fatalf("Partially allowed unblock should not error, got: %v", err)
+	}
+	if unblocks := state.GetTempUnblocks(); len(unblocks) != 1 || unblocks[0].Domain != "anytime.com" {
+		t.Errorf("Expected only anytime.com to be unblocked, got %+v", unblocks)
+	}
+}
+
+func TestProcessUnblockRequest_DailyLimit(t *testing.T) {
+	fake := utils.NewFakeTimeProvider(time.Date(2026, 1, 6, 10, 0, 0, 0, time.Local))
+	enforcement.SetClock(fake)
+	defer enforcement.SetClock(utils.DefaultTimeProvider{})
+
+	stateFile := filepath.Join(t.TempDir(), "unblock-grants.json")
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{Name: "a.com", Unblockable: true},
+			{Name: "b.com", Unblockable: true},
+			{Name: "c.com", Unblockable: true},
+		},
+		Unblocking: config.UnblockingConfig{
+			TempUnblockTime: 30,
+			MaxPerDay:       2,
+			ResetTime:       "04:00",
+			StateFile:       stateFile,
+		},
+	}
+	enforcement.InitializeTestCache(cfg.Domains)
+	state.SetTempUnblocks([]state.TempUnblock{})
+	state.SetUnblockGrants(nil)
+	defer state.SetUnblockGrants(nil)
+
+	// Two grants fit within the limit; the third domain in the request is rejected
+	if err := ProcessUnblockRequest(cfg, "a.com", "work", time.Time{}); err != nil {
+		t.Fatalf("First unblock should succeed, got: %v", err)
+	}
+	if err := ProcessUnblockRequest(cfg, "b.com,c.com", "work", time.Time{}); err != nil {
+		t.Fatalf("Partially allowed unblock should not error, got: %v", err)
+	}
+	if unblocks := state.GetTempUnblocks(); len(unblocks) != 2 {
+		t.Errorf("Expected 2 unblocks before hitting the limit, got %+v", unblocks)
+	}
+
+	err := ProcessUnblockRequest(cfg, "c.com", "work", time.Time{})
+	if err == nil || !strings.Contains(err.Error(), "daily unblock limit") {
+		t.Fatalf("Expected daily limit error, got: %v", err)
+	}
+
+	// The count persists across a daemon restart
+	state.SetUnblockGrants(nil)
+	if err := state.LoadUnblockGrants(stateFile); err != nil {
+		t.Fatalf("LoadUnblockGrants failed: %v", err)
+	}
+	if err := ProcessUnblockRequest(cfg, "c.com", "work", time.Time{}); err == nil {
+		t.Error("Expected the limit to still apply after reloading grants")
+	}
+
+	// Still the same period just before the reset time the next morning
+	fake.Set(time.Date(2026, 1, 7, 3, 59, 0, 0, time.Local))
+	if err := ProcessUnblockRequest(cfg, "c.com", "work", time.Time{}); err == nil {
+		t.Error("Expected the limit to apply until reset_time")
+	}
+
+	// Crossing reset_time starts a new period
+	fake.Set(time.Date(2026, 1, 7, 4, 0, 0, 0, time.Local))
+	if err := ProcessUnblockRequest(cfg, "c.com", "work", time.Time{}); err != nil {
+		t.Errorf("Expected unblock to succeed after reset, got: %v", err)
+	}
+}
+
+func TestExitCodeAndFormatError(t *testing.T) {
+	rejected := NewExitError(ExitRejected, "invalid reason: %s", "fun")
+	wrapped := fmt.Errorf("unblock failed: %w", rejected)
+
+	tests := []struct {
+		err      error
+		wantCode int
+		wantJSON string
+	}{
+		{rejected, ExitRejected, `"error":"invalid reason: fun","code":3}`},
+		{wrapped, ExitRejected, `"error":"unblock failed: invalid reason: fun","code":3}`},
+		{NewExitError(ExitDaemonDown, "daemon down"), ExitDaemonDown, `"error":"daemon down","code":2}`},
+		{errors.New("boom"), ExitFailure, `"error":"boom","code":1}`},
+	}
+	versioned := fmt.Sprintf(`{"schema_version":%d,`, schema.Version)
+
+	for _, tt := range tests {
+		if got := ExitCode(tt.err); got != tt.wantCode {
+			t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.wantCode)
+		}
+		if got := FormatError(tt.err, true); got != versioned+tt.wantJSON {
+			t.Errorf("FormatError(%v, true) = %s, want %s", tt.err, got, versioned+tt.wantJSON)
+		}
+		if got := FormatError(tt.err, false); got != tt.err.Error() {
+			t.Errorf("FormatError(%v, false) = %q, want the plain message", tt.err, got)
+		}
+	}
+
+	if got := ExitCode(nil); got != ExitOK {
+		t.Errorf("ExitCode(nil) = %d, want %d", got, ExitOK)
+	}
+}
+
+func TestStatusAndInfoJSON(t *testing.T) {
+	now := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC) // Tuesday
+	cfg := &config.Config{
+		EnableHosts:     true,
+		EnforceInterval: 60,
+		Domains: []config.Domain{
+			{Name: "always.com"},
+			{Name: "workhours.com", TimeWindows: []config.TimeWindow{{Start: "09:00", End: "17:00", Days: []string{"Tue"}}}},
+			{Name: "evening.com", TimeWindows: []config.TimeWindow{{Start: "20:00", End: "23:00", Days: []string{"Tue"}}}},
+		},
+		ViolationTracking: config.ViolationTrackingConfig{
+			Enabled:           true,
+			MaxViolations:     5,
+			TimeWindowMinutes: 60,
+		},
+		ExtensionKeywords: config.ExtensionKeywordsConfig{URLKeywords: []string{"casino"}},
+	}
+	enforcement.InitializeTestCache(cfg.Domains)
+	defer enforcement.InitializeTestCache(nil)
+
+	state.SetTempUnblocks([]state.TempUnblock{
+		{Domain: "active.com", ExpiresAt: now.Add(10 * time.Minute), Duration: 30 * time.Minute},
+		{Domain: "expired.com", ExpiresAt: now.Add(-time.Minute)},
+	})
+	defer state.SetTempUnblocks([]state.TempUnblock{})
+	state.ClearViolations()
+	state.AddViolation(state.Violation{Timestamp: now.Add(-10 * time.Minute), Host: "a.com"})
+	state.AddViolation(state.Violation{Timestamp: now.Add(-2 * time.Hour), Host: "b.com"})
+	defer state.ClearViolations()
+	state.SetPanicUntil(now.Add(time.Hour))
+	defer state.SetPanicUntil(time.Time{})
+
+	data, err := json.Marshal(BuildStatusJSON(cfg, now))
+	if err != nil {
+		t.Fatalf("Failed to marshal status: %v", err)
+	}
+	var status StatusJSON
+	if err := json.Unmarshal(data, &status); err != nil {
+		t.Fatalf("Failed to unmarshal status %s: %v", data, err)
+	}
+	if status.SchemaVersion != schema.Version {
+		t.Errorf("Expected schema_version %d, got %d", schema.Version, status.SchemaVersion)
+	}
+
+	if len(status.ActiveUnblocks) != 1 || status.ActiveUnblocks[0].Domain != "active.com" ||
+		status.ActiveUnblocks[0].RemainingSeconds != 600 || status.ActiveUnblocks[0].GrantedSeconds != 1800 {
+		t.Errorf("Unexpected active unblocks: %+v", status.ActiveUnblocks)
+	}
+	if status.Violations == nil || status.Violations.Recent != 1 || status.Violations.Total != 2 || status.Violations.Max != 5 {
+		t.Errorf("Unexpected violations: %+v", status.Violations)
+	}
+	if status.PanicUntil == nil || !status.PanicUntil.Equal(now.Add(time.Hour)) {
+		t.Errorf("Expected panic_until %v, got %v", now.Add(time.Hour), status.PanicUntil)
+	}
+	if !status.Features.Hosts || !status.Features.ViolationTracking || status.Features.Firewall {
+		t.Errorf("Unexpected feature flags: %+v", status.Features)
+	}
+	blocking := make(map[string]bool)
+	for _, d := range status.TimeWindowDomains {
+		blocking[d.Name] = d.Blocking
+	}
+	if len(blocking) != 2 || !blocking["workhours.com"] || blocking["evening.com"] {
+		t.Errorf("Unexpected time window domains: %+v", status.TimeWindowDomains)
+	}
+
+	// The socket response is one JSON line followed by END
+	response := GetInfoJSONResponse(cfg)
+	jsonLine, ok := strings.CutSuffix(response, "\nEND\n")
+	if !ok {
+		t.Fatalf("Info response should end with END, got %q", response)
+	}
+	var info InfoJSON
+	if err := json.Unmarshal([]byte(jsonLine), &info); err != nil {
+		t.Fatalf("Failed to unmarshal info %s: %v", jsonLine, err)
+	}
+	if info.SchemaVersion != schema.Version {
+		t.Errorf("Expected schema_version %d in info, got %d", schema.Version, info.SchemaVersion)
+	}
+	if info.EnforceIntervalSeconds != 60 || len(info.TimeWindowDomains) != 2 || len(info.URLKeywords) != 1 {
+		t.Errorf("Unexpected info: %+v", info)
+	}
+	if w := info.TimeWindowDomains[0].TimeWindows; len(w) != 1 || w[0].Start == "" || len(w[0].Days) != 1 {
+		t.Errorf("Expected time windows in info, got %+v", info.TimeWindowDomains)
+	}
+}
+
+func TestCheckPauseRequest_Cap(t *testing.T) {
+	now := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+	state.SetPausedUntil(time.Time{})
+	defer state.SetPausedUntil(time.Time{})
+
+	if err := CheckPauseRequest(&config.Config{}, 10, now); err == nil || !strings.Contains(err.Error(), "disabled") {
+		t.Errorf("Expected pausing to be disabled without max_pause_minutes, got %v", err)
+	}
+
+	cfg := &config.Config{MaxPauseMinutes: 30}
+	if err := CheckPauseRequest(cfg, 30, now); err != nil {
+		t.Errorf("Expected a pause of exactly max_pause_minutes to be allowed, got %v", err)
+	}
+	if err := CheckPauseRequest(cfg, 31, now); err == nil || !strings.Contains(err.Error(), "maximum is 30") {
+		t.Errorf("Expected a pause over the cap to be rejected, got %v", err)
+	}
+	if err := CheckPauseRequest(cfg, 0, now); err == nil {
+		t.Error("Expected a zero-minute pause to be rejected")
+	}
+
+	// No stacking pauses to get past the cap
+	state.SetPausedUntil(now.Add(5 * time.Minute))
+	if err := CheckPauseRequest(cfg, 10, now); err == nil || !strings.Contains(err.Error(), "already paused") {
+		t.Errorf("Expected a pause to be rejected while paused, got %v", err)
+	}
+}
+
+func TestCheckPauseResponse(t *testing.T) {
+	issued := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+	cfg := &config.Config{MindfulDelay: 60}
+	challenge := PauseChallenge(10)
+
+	if err := CheckPauseResponse(cfg, 10, challenge, issued, issued.Add(30*time.Second)); err == nil {
+		t.Error("Expected an answer within mindful_delay to be rejected")
+	}
+	if err := CheckPauseResponse(cfg, 10, "whatever", issued, issued.Add(time.Minute)); err == nil {
+		t.Error("Expected a wrong answer to be rejected")
+	}
+	if err := CheckPauseResponse(cfg, 10, PauseChallenge(20), issued, issued.Add(time.Minute)); err == nil {
+		t.Error("Expected the challenge for another duration to be rejected")
+	}
+	if err := CheckPauseResponse(cfg, 10, challenge+"\n", issued, issued.Add(time.Minute)); err != nil {
+		t.Errorf("Expected the typed challenge to be accepted, got %v", err)
+	}
+}
+
+func TestProcessPauseRequest(t *testing.T) {
+	fake := utils.NewFakeTimeProvider(time.Now())
+	enforcement.SetClock(fake)
+	defer enforcement.SetClock(utils.DefaultTimeProvider{})
+	state.SetPausedUntil(time.Time{})
+	defer state.SetPausedUntil(time.Time{})
+
+	cfg := &config.Config{MaxPauseMinutes: 15}
+	if _, err := ProcessPauseRequest(cfg, 20); err == nil {
+		t.Fatal("Expected a pause over the cap to be rejected")
+	}
+	if !state.GetPausedUntil().IsZero() {
+		t.Error("A rejected pause should not pause enforcement")
+	}
+
+	pausedUntil, err := ProcessPauseRequest(cfg, 15)
+	if err != nil {
+		t.Fatalf("ProcessPauseRequest failed: %v", err)
+	}
+	if want := fake.Now().Add(15 * time.Minute); !pausedUntil.Equal(want) || !state.GetPausedUntil().Equal(want) {
+		t.Errorf("Expected pause until %v, got %v (state %v)", want, pausedUntil, state.GetPausedUntil())
+	}
+
+	want := "PAUSED until " + pausedUntil.Format("15:04")
+	if response := GetStatusResponse(cfg); !strings.Contains(response, want) {
+		t.Errorf("Status should show %q, got:\n%s", want, response)
+	}
+	if status := BuildStatusJSON(cfg, fake.Now()); status.PausedUntil == nil || !status.PausedUntil.Equal(pausedUntil) {
+		t.Errorf("Expected paused_until in status JSON, got %v", status.PausedUntil)
+	}
+}
+
+func TestProcessRevokeRequest(t *testing.T) {
+	fake := utils.NewFakeTimeProvider(time.Date(2026, 1, 6, 10, 0, 0, 0, time.Local))
+	enforcement.SetClock(fake)
+	defer enforcement.SetClock(utils.DefaultTimeProvider{})
+
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{Name: "youtube.com", Unblockable: true},
+			{Name: "reddit.com", Unblockable: true},
+		},
+	}
+	enforcement.InitializeTestCache(cfg.Domains)
+	state.SetTempUnblocks([]state.TempUnblock{
+		{Domain: "youtube.com", ExpiresAt: fake.Now().Add(20 * time.Minute)},
+		{Domain: "reddit.com", ExpiresAt: fake.Now().Add(5 * time.Minute)},
+	})
+	defer state.SetTempUnblocks(nil)
+
+	response := GetUnblocksResponse()
+	for _, line := range []string{"youtube.com (expires 10:20:00, 20m0s left)", "reddit.com (expires 10:05:00, 5m0s left)"} {
+		if !strings.Contains(response, line) {
+			t.Errorf("Unblock list should contain %q, got:\n%s", line, response)
+		}
+	}
+
+	if err := ProcessRevokeRequest(cfg, "youtube.com"); err != nil {
+		t.Fatalf("Expected revoke to succeed, got: %v", err)
+	}
+	unblocks := state.GetTempUnblocks()
+	if len(unblocks) != 1 || unblocks[0].Domain != "reddit.com" {
+		t.Errorf("Expected only reddit.com to stay unblocked, got %+v", unblocks)
+	}
+
+	// Revoking again, or revoking a domain that was never unblocked, is an error
+	for _, domain := range []string{"youtube.com", "example.com", " "} {
+		if err := ProcessRevokeRequest(cfg, domain); err == nil {
+			t.Errorf("Expected an error revoking %q", domain)
+		}
+	}
+	if len(state.GetTempUnblocks()) != 1 {
+		t.Errorf("Failed revokes shouldn't change the unblocks, got %+v", state.GetTempUnblocks())
+	}
+
+	// An unblock that has already expired can't be revoked
+	fake.Set(fake.Now().Add(10 * time.Minute))
+	if err := ProcessRevokeRequest(cfg, "reddit.com"); err == nil {
+		t.Error("Expected an error revoking an expired unblock")
+	}
+	if !strings.Contains(GetUnblocksResponse(), "No active temporary unblocks") {
+		t.Errorf("Expected no active unblocks, got:\n%s", GetUnblocksResponse())
+	}
+}
+
+func TestRunDoctor(t *testing.T) {
+	dir := t.TempDir()
+	hostsPath := filepath.Join(dir, "hosts")
+	configPath := filepath.Join(dir, "config.yaml")
+	configYAML := fmt.Sprintf("enable_hosts: true\nenable_firewall: true\nhosts_path: %s\n", hostsPath)
+	if err := os.WriteFile(configPath, []byte(configYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config.SetConfigPath(configPath)
+	defer config.SetConfigPath(config.GlockerConfigFile)
+
+	origRunning, origRules, origRead, origImmutable, origDial := doctorServiceRunning, doctorFirewallRules, doctorReadFile, doctorIsImmutable, doctorDialSocket
+	defer func() {
+		doctorServiceRunning, doctorFirewallRules, doctorReadFile, doctorIsImmutable, doctorDialSocket = origRunning, origRules, origRead, origImmutable, origDial
+	}()
+	doctorServiceRunning = func() bool { return true }
+	doctorFirewallRules = func() int { return 0 }
+	doctorReadFile = func(string) ([]byte, error) { return []byte("127.0.0.1 localhost\n" + config.HostsMarkerStart + "\n"), nil }
+	doctorIsImmutable = func(string) (bool, error) { return true, nil }
+	doctorDialSocket = func(string) error { return errors.New("connection refused") }
+
+	checks := RunDoctor()
+	want := map[string]string{
+		"Config file":          CheckPass,
+		"Service":              CheckPass,
+		"Control socket":       CheckFail,
+		"Hosts file":           CheckPass,
+		"Firewall rules":       CheckFail,
+		"Accountability email": CheckSkip,
+	}
+	if len(checks) != len(want) {
+		t.Fatalf("Expected %d checks, got %+v", len(want), checks)
+	}
+	for _, check := range checks {
+		if check.Status != want[check.Name] {
+			t.Errorf("%s: expected %s, got %s (%s)", check.Name, want[check.Name], check.Status, check.Detail)
+		}
+	}
+	if failures := DoctorFailures(checks); failures != 2 {
+		t.Errorf("Expected 2 failures, got %d", failures)
+	}
+
+	// Without a loadable config, the checks that need it are skipped
+	config.SetConfigPath(filepath.Join(dir, "missing.yaml"))
+	checks = RunDoctor()
+	if checks[0].Status != CheckFail || DoctorFailures(checks) != 1 {
+		t.Errorf("Expected only the config check to fail, got %+v", checks)
+	}
+}
+
+func TestFormatDoctorReport(t *testing.T) {
+	checks := []DoctorCheck{
+		checkService(true),
+		checkFirewall(false, 0),
+		checkHosts(true, "/etc/hosts", config.HostsMarkerStart, nil, false),
+		checkEmail(config.AccountabilityConfig{Enabled: true, PartnerEmail: "partner@example.com"}),
+	}
+
+	report := FormatDoctorReport(checks)
+	for _, want := range []string{
+		"[PASS] Service",
+		"[SKIP] Firewall rules",
+		"[FAIL] Hosts file",
+		"→ check that chattr is installed",
+		"[FAIL] Accountability email  no from_email set",
+		"→ set accountability.from_email",
+		"2 of 4 checks failed.",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Expected report to contain %q, got:\n%s", want, report)
+		}
+	}
+
+	if report := FormatDoctorReport(checks[:2]); !strings.Contains(report, "All checks passed.") || strings.Contains(report, "→") {
+		t.Errorf("Expected a clean report without hints, got:\n%s", report)
+	}
+}
+
+func TestProcessReloadRequest_KeepsRuntimeKeywords(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	configYAML := "extension_keywords:\n  url_keywords: [casino]\n  content_keywords: [casino]\n"
+	if err := os.WriteFile(configPath, []byte(configYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config.SetConfigPath(configPath)
+	defer config.SetConfigPath(config.GlockerConfigFile)
+	config.SetOverlayPath(filepath.Join(dir, "keywords.yaml"))
+	defer config.SetOverlayPath(config.RuntimeOverlayFile)
+	defer state.SetRuntimeKeywords(nil)
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// What -add-keyword does
+	for _, keyword := range []string{"poker", "casino"} {
+		cfg.ExtensionKeywords.URLKeywords = append(cfg.ExtensionKeywords.URLKeywords, keyword)
+		cfg.ExtensionKeywords.ContentKeywords = append(cfg.ExtensionKeywords.ContentKeywords, keyword)
+		state.AddRuntimeKeyword(keyword)
+	}
+
+	ProcessReloadRequest(cfg)
+
+	for _, keywords := range [][]string{cfg.ExtensionKeywords.URLKeywords, cfg.ExtensionKeywords.ContentKeywords} {
+		if strings.Join(keywords, ",") != "casino,poker" {
+			t.Errorf("Expected keywords [casino poker] after reload, got %v", keywords)
+		}
+	}
+}
+
+func TestKeepRuntimeKeywords(t *testing.T) {
+	defer state.SetRuntimeKeywords(nil)
+	state.SetRuntimeKeywords([]string{"poker", "casino", "slots"})
+
+	newCfg := &config.Config{ExtensionKeywords: config.ExtensionKeywordsConfig{
+		URLKeywords:     []string{"casino", "slots"},
+		ContentKeywords: []string{"casino"},
+	}}
+	kept := keepRuntimeKeywords(newCfg)
+
+	// Only the keywords the config file is missing count as kept
+	if strings.Join(kept, ",") != "poker,slots" {
+		t.Errorf("Expected kept keywords [poker slots], got %v", kept)
+	}
+	if got := strings.Join(newCfg.ExtensionKeywords.URLKeywords, ","); got != "casino,slots,poker" {
+		t.Errorf("URL keywords = %s, want casino,slots,poker", got)
+	}
+	if got := strings.Join(newCfg.ExtensionKeywords.ContentKeywords, ","); got != "casino,poker,slots" {
+		t.Errorf("Content keywords = %s, want casino,poker,slots", got)
+	}
+}
+
+func TestProcessBlockRequest_SurvivesReload(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	configYAML := "domains:\n  - name: reddit.com\n    unblockable: true\n"
+	if err := os.WriteFile(configPath, []byte(configYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	overlayPath := filepath.Join(dir, "keywords.yaml")
+	config.SetConfigPath(configPath)
+	defer config.SetConfigPath(config.GlockerConfigFile)
+	config.SetOverlayPath(overlayPath)
+	defer config.SetOverlayPath(config.RuntimeOverlayFile)
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	enforcement.InitialEnforcement(cfg)
+
+	if err := ProcessBlockRequest(cfg, "x.com, reddit.com"); err != nil {
+		t.Fatalf("ProcessBlockRequest failed: %v", err)
+	}
+
+	// Only the domain the config doesn't list is persisted
+	overlay, err := config.LoadOverlay(overlayPath)
+	if err != nil {
+		t.Fatalf("LoadOverlay failed: %v", err)
+	}
+	if strings.Join(overlay.Domains, ",") != "x.com" {
+		t.Errorf("Expected overlay domains [x.com], got %v", overlay.Domains)
+	}
+
+	ProcessReloadRequest(cfg)
+
+	// A restart loads the config the same way
+	restarted, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocked := enforcement.GetDomainsToBlock(restarted, time.Now())
+	if !slices.Contains(blocked, "x.com") {
+		t.Errorf("Expected x.com to be blocked after a reload, got %v", blocked)
+	}
+	for _, domain := range restarted.Domains {
+		if domain.Name == "reddit.com" && !domain.Unblockable {
+			t.Error("Expected reddit.com to keep its config entry")
+		}
+	}
+	if canUnblock, inConfig := enforcement.IsUnblockable("x.com", time.Now()); canUnblock || !inConfig {
+		t.Errorf("Expected x.com to be a permanent block after reload, got canUnblock=%v inConfig=%v", canUnblock, inConfig)
+	}
+}
+
+func TestGetInfoResponse_CategoriesAfterEnforcement(t *testing.T) {
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{Name: "reddit.com", Category: "social", Label: "doomscrolling"},
+			{Name: "x.com", Category: "social"},
+			{Name: "example.com"},
+		},
+	}
+	enforcement.InitialEnforcement(cfg)
+	if len(cfg.Domains) != 0 {
+		t.Fatalf("Expected enforcement to clear cfg.Domains, got %d", len(cfg.Domains))
+	}
+
+	// The categories and labels come from the enforced domain cache
+	response := GetInfoResponse(cfg)
+	if !strings.Contains(response, "social: 2 domains") {
+		t.Errorf("Expected the social category count, got:\n%s", response)
+	}
+	if !strings.Contains(response, `reddit.com [social] "doomscrolling"`) {
+		t.Errorf("Expected the labeled domain, got:\n%s", response)
+	}
+}
+
+func TestProcessCategoryRequest(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	configYAML := "domains:\n  - {name: reddit.com, category: social}\nkeyword_categories:\n  - {name: gambling, url_keywords: [casino]}\n"
+	if err := os.WriteFile(configPath, []byte(configYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	overlayPath := filepath.Join(dir, "keywords.yaml")
+	config.SetConfigPath(configPath)
+	defer config.SetConfigPath(config.GlockerConfigFile)
+	config.SetOverlayPath(overlayPath)
+	defer config.SetOverlayPath(config.RuntimeOverlayFile)
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The daemon clears cfg.Domains after enforcement; domain categories come from its cache
+	enforcement.InitializeTestCache(cfg.Domains)
+	t.Cleanup(func() { enforcement.InitializeTestCache(nil) })
+	cfg.Domains = nil
+
+	if err := ProcessCategoryRequest(cfg, "gambling", false, " "); err == nil {
+		t.Error("Expected disabling without a reason to fail")
+	}
+	if err := ProcessCategoryRequest(cfg, "gambling", false, "false positives at work"); err != nil {
+		t.Fatalf("Disabling gambling failed: %v", err)
+	}
+	if urlKeywords, _ := web.EffectiveKeywords(cfg, time.Now()); slices.Contains(urlKeywords, "casino") {
+		t.Errorf("Expected gambling keywords to be dropped, got %v", urlKeywords)
+	}
+	restarted, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enabled := restarted.KeywordCategories[0].Enabled; enabled == nil || *enabled {
+		t.Errorf("Expected gambling to stay disabled after a restart, got %v", enabled)
+	}
+
+	if err := ProcessCategoryRequest(cfg, " gambling ", true, ""); err != nil {
+		t.Fatalf("Enabling gambling failed: %v", err)
+	}
+	if urlKeywords, _ := web.EffectiveKeywords(cfg, time.Now()); !slices.Contains(urlKeywords, "casino") {
+		t.Errorf("Expected gambling keywords back, got %v", urlKeywords)
+	}
+
+	// Unknown names and domain categories are rejected without touching the overlay
+	for name, want := range map[string]string{
+		"sports": `unknown category "sports" (keyword_categories: gambling)`,
+		"social": `"social" is a domain category`,
+	} {
+		if err := ProcessCategoryRequest(cfg, name, false, "testing"); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ProcessCategoryRequest(%s) error = %v, want it to contain %q", name, err, want)
+		}
+	}
+	overlay, err := config.LoadOverlay(overlayPath)
+	if err != nil {
+		t.Fatalf("LoadOverlay failed: %v", err)
+	}
+	if len(overlay.Categories) != 1 || !overlay.Categories["gambling"] {
+		t.Errorf("Expected only gambling in the overlay, got %v", overlay.Categories)
+	}
+}
+
+func TestParseUnblockUntil(t *testing.T) {
+	now := time.Date(2026, 1, 6, 14, 45, 30, 0, time.Local)
+	tests := []struct {
+		name     string
+		maxUntil int
+		until    string
+		want     time.Time
+		wantErr  string
+	}{
+		{"later today", 120, "16:00", time.Date(2026, 1, 6, 16, 0, 0, 0, time.Local), ""},
+		{"at the max", 120, "16:45", time.Date(2026, 1, 6, 16, 45, 0, 0, time.Local), ""},
+		{"past the max", 120, "16:46", time.Time{}, "more than 2h0m0s away"},
+		{"within a longer max", 240, "18:30", time.Date(2026, 1, 6, 18, 30, 0, 0, time.Local), ""},
+		{"past a shorter max", 30, "15:30", time.Time{}, "latest allowed is 15:15"},
+		{"disabled", 0, "15:00", time.Time{}, "-until is disabled"},
+		{"already passed", 120, "14:45", time.Time{}, "already passed"},
+		{"invalid", 120, "5pm", time.Time{}, "use HH:MM"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Unblocking: config.UnblockingConfig{MaxUntilMinutes: tt.maxUntil}}
+			got, err := ParseUnblockUntil(cfg, tt.until, now)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil || !got.Equal(tt.want) {
+				t.Errorf("ParseUnblockUntil(%q) = %v, %v; want %v", tt.until, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessUnblockRequest_Until(t *testing.T) {
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{Name: "short.com", Unblockable: true, UnblockMinutes: 10},
+			{Name: "default.com", Unblockable: true},
+		},
+		Unblocking: config.UnblockingConfig{TempUnblockTime: 30, MaxUntilMinutes: 180},
+	}
+	enforcement.InitializeTestCache(cfg.Domains)
+	defer enforcement.InitializeTestCache(nil)
+	state.SetTempUnblocks(nil)
+	defer state.SetTempUnblocks(nil)
+
+	fake := utils.NewFakeTimeProvider(time.Date(2026, 1, 6, 14, 45, 30, 0, time.Local))
+	enforcement.SetClock(fake)
+	defer enforcement.SetClock(utils.DefaultTimeProvider{})
+
+	// Past the max, nothing is unblocked
+	if err := ProcessUnblockRequest(cfg, "default.com", "work", time.Date(2026, 1, 6, 18, 0, 0, 0, time.Local)); err == nil {
+		t.Error("Expected an until time past max_until_minutes to be rejected")
+	}
+	if unblocks := state.GetTempUnblocks(); len(unblocks) != 0 {
+		t.Fatalf("Expected no unblocks, got %+v", unblocks)
+	}
+
+	// The end time replaces each domain's usual duration
+	until := time.Date(2026, 1, 6, 17, 0, 0, 0, time.Local)
+	if err := ProcessUnblockRequest(cfg, "short.com,default.com", "work", until); err != nil {
+		t.Fatalf("Expected unblock to succeed, got: %v", err)
+	}
+	unblocks := state.GetTempUnblocks()
+	if len(unblocks) != 2 {
+		t.Fatalf("Expected 2 unblocks, got %+v", unblocks)
+	}
+	for _, unblock := range unblocks {
+		if !unblock.ExpiresAt.Equal(until) || unblock.Duration != 2*time.Hour+14*time.Minute+30*time.Second {
+			t.Errorf("%s: expected to expire at 17:00 after 2h14m30s, got %v after %v", unblock.Domain, unblock.ExpiresAt, unblock.Duration)
+		}
+	}
+}
+
+func TestProcessUninstallRequest_Cooldown(t *testing.T) {
+	state.ClearPendingUninstall()
+	t.Cleanup(state.ClearPendingUninstall)
+
+	start := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+	fake := utils.NewFakeTimeProvider(start)
+	enforcement.SetClock(fake)
+	defer enforcement.SetClock(utils.DefaultTimeProvider{})
+
+	// Without a cooldown, uninstalls proceed at once
+	if proceed, _, err := ProcessUninstallRequest(&config.Config{}, "done"); !proceed || err != nil {
+		t.Fatalf("Expected an immediate uninstall without a cooldown, got %v, %v", proceed, err)
+	}
+
+	cfg := &config.Config{Uninstall: config.UninstallConfig{CooldownMinutes: 60}}
+
+	// The first request only schedules the uninstall
+	proceed, unlockAt, err := ProcessUninstallRequest(cfg, "done")
+	if proceed || err != nil || !unlockAt.Equal(start.Add(time.Hour)) {
+		t.Fatalf("Expected the uninstall to be scheduled until 11:00, got %v, %v, %v", proceed, unlockAt, err)
+	}
+	if pending, ok := state.GetPendingUninstall(); !ok || pending.Reason != "done" {
+		t.Fatalf("Expected a pending uninstall, got %+v, %v", pending, ok)
+	}
+
+	// Confirming during the cooldown is rejected and doesn't restart it
+	fake.Advance(30 * time.Minute)
+	if proceed, unlockAt, err := ProcessUninstallRequest(cfg, "done"); proceed || err == nil || !unlockAt.Equal(start.Add(time.Hour)) {
+		t.Errorf("Expected confirmation during the cooldown to be rejected, got %v, %v, %v", proceed, unlockAt, err)
+	}
+
+	// After the cooldown the confirmation proceeds and clears the pending uninstall
+	fake.Advance(31 * time.Minute)
+	if proceed, _, err := ProcessUninstallRequest(cfg, "still done"); !proceed || err != nil {
+		t.Errorf("Expected confirmation after the cooldown to proceed, got %v, %v", proceed, err)
+	}
+	if _, ok := state.GetPendingUninstall(); ok {
+		t.Error("Expected the pending uninstall to be cleared")
+	}
+}
+
+func TestProcessUninstallRequest_ConfirmWindowExpires(t *testing.T) {
+	state.ClearPendingUninstall()
+	t.Cleanup(state.ClearPendingUninstall)
+
+	start := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+	fake := utils.NewFakeTimeProvider(start)
+	enforcement.SetClock(fake)
+	defer enforcement.SetClock(utils.DefaultTimeProvider{})
+
+	cfg := &config.Config{Uninstall: config.UninstallConfig{CooldownMinutes: 60}}
+	ProcessUninstallRequest(cfg, "done")
+
+	// A pending uninstall left unconfirmed past the window starts over
+	fake.Advance(time.Hour + UninstallConfirmWindow)
+	proceed, unlockAt, err := ProcessUninstallRequest(cfg, "done")
+	if proceed || err != nil || !unlockAt.Equal(fake.Now().Add(time.Hour)) {
+		t.Errorf("Expected a new cooldown, got %v, %v, %v", proceed, unlockAt, err)
+	}
+}
+
+func TestParseSimulateTime(t *testing.T) {
+	at, err := ParseSimulateTime(" 2024-06-11 15:00 ")
+	if err != nil {
+		t.Fatalf("ParseSimulateTime: %v", err)
+	}
+	if want := time.Date(2024, 6, 11, 15, 0, 0, 0, time.Local); !at.Equal(want) {
+		t.Errorf("ParseSimulateTime = %v, want %v", at, want)
+	}
+
+	for _, value := range []string{"", "15:00", "2024-06-11", "2024-06-11 25:00", "next tuesday"} {
+		if _, err := ParseSimulateTime(value); err == nil {
+			t.Errorf("ParseSimulateTime(%q) should fail", value)
+		}
+	}
+}
+
+func TestGetSimulateResponse(t *testing.T) {
+	weekdays := []string{"Mon", "Tue", "Wed", "Thu", "Fri"}
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{Name: "always.com"},
+			{Name: "reddit.com", Unblockable: true, TimeWindows: []config.TimeWindow{{Days: weekdays, Start: "09:00", End: "17:00"}}},
+			{Name: "late.com", TimeWindows: []config.TimeWindow{{Days: []string{"Mon"}, Start: "22:00", End: "02:00"}}},
+		},
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want []string
+	}{
+		{
+			name: "Tuesday afternoon",
+			at:   time.Date(2024, 6, 11, 15, 0, 0, 0, time.Local),
+			want: []string{
+				"Time: 2024-06-11 15:00 (Tuesday)",
+				"BLOCKED always.com: always blocked (permanent)",
+				"BLOCKED reddit.com: time-based block (active 09:00-17:00 on Mon,Tue,Wed,Thu,Fri)",
+				"allowed late.com: outside its time windows (22:00-02:00 (Mon))",
+				"2 of 3 domains would be blocked",
+			},
+		},
+		{
+			name: "Saturday",
+			at:   time.Date(2024, 6, 15, 10, 0, 0, 0, time.Local),
+			want: []string{
+				"allowed reddit.com: outside its time windows",
+				"1 of 3 domains would be blocked",
+			},
+		},
+		{
+			name: "Monday night",
+			at:   time.Date(2024, 6, 10, 23, 30, 0, 0, time.Local),
+			want: []string{
+				"BLOCKED late.com: time-based block (active 22:00-02:00 on Mon)",
+				"2 of 3 domains would be blocked",
+			},
+		},
+		{
+			// The early morning part of Monday's window is on Tuesday
+			name: "after midnight",
+			at:   time.Date(2024, 6, 11, 1, 30, 0, 0, time.Local),
+			want: []string{
+				"BLOCKED late.com: time-based block (active 22:00-02:00 on Mon)",
+				"allowed reddit.com",
+			},
+		},
+		{
+			// Sunday night's early morning hours aren't covered by a Monday window
+			name: "Monday after midnight",
+			at:   time.Date(2024, 6, 10, 1, 30, 0, 0, time.Local),
+			want: []string{
+				"allowed late.com",
+				"1 of 3 domains would be blocked",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := strings.Join(strings.Fields(GetSimulateResponse(cfg, tt.at)), " ")
+			for _, want := range tt.want {
+				if !strings.Contains(response, want) {
+					t.Errorf("Expected %q in the simulation, got:\n%s", want, GetSimulateResponse(cfg, tt.at))
+				}
+			}
+		})
+	}
+}
+
+func TestGetSimulateResponse_AllowlistMode(t *testing.T) {
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{Name: "docs.com", TimeWindows: []config.TimeWindow{{Days: []string{"Sat"}, Start: "00:00", End: "23:59"}}},
+			{Name: "news.com", TimeWindows: []config.TimeWindow{{Days: []string{"Sat"}, Start: "00:00", End: "23:59"}}},
+		},
+		AllowlistMode: config.AllowlistModeConfig{
+			Enabled:        true,
+			AllowedDomains: []config.Domain{{Name: "docs.com"}},
+			TimeWindows:    []config.TimeWindow{{Days: []string{"Mon", "Tue", "Wed", "Thu", "Fri"}, Start: "09:00", End: "17:00"}},
+		},
+	}
+
+	response := strings.Join(strings.Fields(GetSimulateResponse(cfg, time.Date(2024, 6, 11, 10, 0, 0, 0, time.Local))), " ")
+	for _, want := range []string{"allowed docs.com", "BLOCKED news.com: not on the allowlist (allowlist mode)"} {
+		if !strings.Contains(response, want) {
+			t.Errorf("Expected %q in the simulation, got %s", want, response)
+		}
+	}
+}
+
+func TestParseFocusDuration(t *testing.T) {
+	for value, want := range map[string]time.Duration{"50m": 50 * time.Minute, "1h30m": 90 * time.Minute, " 25 ": 25 * time.Minute} {
+		if got, err := ParseFocusDuration(value); err != nil || got != want {
+			t.Errorf("ParseFocusDuration(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", "soon", "30s", "-5m", "25h"} {
+		if _, err := ParseFocusDuration(value); err == nil {
+			t.Errorf("ParseFocusDuration(%q) should fail", value)
+		}
+	}
+}
+
+func TestProcessFocusRequest(t *testing.T) {
+	fake := utils.NewFakeTimeProvider(time.Now())
+	enforcement.SetClock(fake)
+	defer enforcement.SetClock(utils.DefaultTimeProvider{})
+	state.SetTempBlocks(nil)
+	defer state.SetTempBlocks(nil)
+
+	if _, err := ProcessFocusRequest(&config.Config{}, 50*time.Minute); err == nil {
+		t.Error("Expected an error without focus.distraction_domains")
+	}
+
+	cfg := &config.Config{Focus: config.FocusConfig{DistractionDomains: []string{"reddit.com", "news.com"}}}
+	until, err := ProcessFocusRequest(cfg, 50*time.Minute)
+	if err != nil {
+		t.Fatalf("ProcessFocusRequest: %v", err)
+	}
+	if !until.Equal(fake.Now().Add(50 * time.Minute)) {
+		t.Errorf("Expected the session to end in 50m, got %v", until)
+	}
+	blocks := state.GetTempBlocks()
+	if len(blocks) != 2 || blocks[0].Domain != "reddit.com" || blocks[1].Domain != "news.com" || !blocks[0].ExpiresAt.Equal(until) {
+		t.Errorf("Expected both distraction domains blocked until %v, got %+v", until, blocks)
+	}
+
+	// The session shows in the status with its remaining time
+	fake.Advance(20 * time.Minute)
+	response := GetStatusResponse(cfg)
+	if !strings.Contains(response, "FOCUS SESSION:") || !strings.Contains(response, "until "+until.Format("15:04")+") - blocking reddit.com, news.com") {
+		t.Errorf("Expected the focus session in the status, got:\n%s", response)
+	}
+	status := BuildStatusJSON(cfg, until.Add(-30*time.Minute))
+	if status.Focus == nil || !status.Focus.Until.Equal(until) || status.Focus.RemainingSeconds != 1800 || len(status.Focus.Domains) != 2 {
+		t.Errorf("Expected the focus session in the JSON status, got %+v", status.Focus)
+	}
+
+	// Starting another session extends the running one
+	longer, err := ProcessFocusRequest(cfg, time.Hour)
+	if err != nil || !longer.After(until) {
+		t.Fatalf("Expected a longer session, got %v, %v", longer, err)
+	}
+	if blocks := state.GetTempBlocks(); len(blocks) != 2 || !blocks[0].ExpiresAt.Equal(longer) {
+		t.Errorf("Expected the blocks to be extended to %v, got %+v", longer, blocks)
+	}
+
+	// Once it has ended, it's gone from the status
+	if status := BuildStatusJSON(cfg, longer); status.Focus != nil {
+		t.Errorf("Expected no focus session after it ended, got %+v", status.Focus)
+	}
+}
+
+func TestFormatTimeWindowTransition(t *testing.T) {
+	windows := []config.TimeWindow{{Start: "20:00", End: "22:00", Days: []string{"Tue", "Fri"}}}
+
+	// 2026-01-06 is a Tuesday
+	for now, want := range map[time.Time]string{
+		time.Date(2026, 1, 6, 18, 0, 0, 0, time.Local):  "blocks at 20:00",
+		time.Date(2026, 1, 6, 20, 38, 0, 0, time.Local): "blocked, opens in 1h23m",
+		time.Date(2026, 1, 6, 21, 50, 0, 0, time.Local): "blocked, opens in 11m",
+		time.Date(2026, 1, 6, 23, 0, 0, 0, time.Local):  "blocks at Fri 20:00",
+	} {
+		if got := formatTimeWindowTransition(windows, now); got != want {
+			t.Errorf("formatTimeWindowTransition() at %s = %q, want %q", now.Format("Mon 15:04"), got, want)
+		}
+	}
+	if got := formatTimeWindowTransition(nil, time.Now()); got != "no block this week" {
+		t.Errorf("formatTimeWindowTransition() without windows = %q, want %q", got, "no block this week")
+	}
+}
+
+func TestProcessBlockRequest_RejectsTimeWindowedDomain(t *testing.T) {
+	overlayPath := filepath.Join(t.TempDir(), "keywords.yaml")
+	config.SetOverlayPath(overlayPath)
+	defer config.SetOverlayPath(config.RuntimeOverlayFile)
+
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{Name: "youtube.com", TimeWindows: []config.TimeWindow{{Days: []string{"Mon"}, Start: "09:00", End: "17:00"}}},
+		},
+	}
+	enforcement.InitializeTestCache(cfg.Domains)
+	defer enforcement.InitializeTestCache(nil)
+
+	err := ProcessBlockRequest(cfg, "x.com, youtube.com")
+	if err == nil || !strings.Contains(err.Error(), "youtube.com") {
+		t.Fatalf("Expected the time-windowed domain to be rejected, got %v", err)
+	}
+
+	// Nothing in the request is blocked
+	if len(cfg.Domains) != 1 {
+		t.Errorf("Expected no domains to be added, got %+v", cfg.Domains)
+	}
+	if _, err := os.Stat(overlayPath); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be written to the overlay, got %v", err)
+	}
+}
+
+func TestProcessBlockRequest_RejectsTimeWindowedDomainAfterEnforcement(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	configYAML := "domains:\n  - name: youtube.com\n    time_windows:\n      - days: [Mon]\n        start: \"09:00\"\n        end: \"17:00\"\n"
+	if err := os.WriteFile(configPath, []byte(configYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	overlayPath := filepath.Join(dir, "keywords.yaml")
+	config.SetConfigPath(configPath)
+	defer config.SetConfigPath(config.GlockerConfigFile)
+	config.SetOverlayPath(overlayPath)
+	defer config.SetOverlayPath(config.RuntimeOverlayFile)
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	enforcement.InitialEnforcement(cfg)
+	defer enforcement.InitializeTestCache(nil)
+	if cfg.Domains != nil {
+		t.Fatalf("Expected a full enforcement to clear cfg.Domains, got %+v", cfg.Domains)
+	}
+
+	err = ProcessBlockRequest(cfg, "youtube.com")
+	if err == nil || !strings.Contains(err.Error(), "youtube.com") {
+		t.Fatalf("Expected the time-windowed domain to be rejected, got %v", err)
+	}
+	if _, err := os.Stat(overlayPath); !os.IsNotExist(err) {
+		t.Errorf("Expected nothing to be written to the overlay, got %v", err)
+	}
+}
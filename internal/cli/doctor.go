@@ -0,0 +1,230 @@
+package cli
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"glocker/internal/config"
+	"glocker/internal/enforcement"
+	"glocker/internal/monitoring"
+)
+
+// doctorDialTimeout is how long the doctor waits for the control socket.
+const doctorDialTimeout = 2 * time.Second
+
+// Check outcomes.
+const (
+	CheckPass = "PASS"
+	CheckFail = "FAIL"
+	CheckSkip = "SKIP" // The checked feature is disabled
+)
+
+// DoctorCheck is the outcome of one doctor check, with a hint on how to fix a
+// failure.
+type DoctorCheck struct {
+	Name   string
+	Status string
+	Detail string
+	Hint   string
+}
+
+// System probes used by the doctor (replaced in tests).
+var (
+	doctorServiceRunning = monitoring.IsServiceRunning
+	doctorFirewallRules  = func() int {
+		// Unlike the daemon, the CLI hasn't probed for iptables and ip6tables yet
+		enforcement.SetCapabilities(enforcement.ProbeCapabilities(exec.LookPath))
+		return enforcement.CountFirewallRules()
+	}
+	doctorReadFile    = os.ReadFile
+	doctorIsImmutable = isImmutable
+	doctorDialSocket  = func(path string) error {
+		conn, err := net.DialTimeout("unix", path, doctorDialTimeout)
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	}
+)
+
+// passed, failed and skipped build a DoctorCheck with that outcome.
+func passed(name, detail string) DoctorCheck {
+	return DoctorCheck{Name: name, Status: CheckPass, Detail: detail}
+}
+
+func failed(name, detail, hint string) DoctorCheck {
+	return DoctorCheck{Name: name, Status: CheckFail, Detail: detail, Hint: hint}
+}
+
+func skipped(name, detail string) DoctorCheck {
+	return DoctorCheck{Name: name, Status: CheckSkip, Detail: detail}
+}
+
+// RunDoctor runs every check against the config file and the running system.
+// Checks that need the config are skipped when it can't be loaded.
+func RunDoctor() []DoctorCheck {
+	cfg, check := checkConfig()
+	checks := []DoctorCheck{check, checkService(doctorServiceRunning())}
+	if cfg == nil {
+		return append(checks, skipped("Control socket", "config not loaded"),
+			skipped("Hosts file", "config not loaded"),
+			skipped("Firewall rules", "config not loaded"),
+			skipped("Accountability email", "config not loaded"))
+	}
+
+	socketPath := cfg.IPC.SocketPath()
+	checks = append(checks, checkSocket(socketPath, doctorDialSocket(socketPath)))
+
+	hostsPath := cfg.HostsPath
+	if hostsPath == "" {
+		hostsPath = "/etc/hosts"
+	}
+	content, err := doctorReadFile(hostsPath)
+	immutable, _ := doctorIsImmutable(hostsPath)
+	checks = append(checks, checkHosts(cfg.EnableHosts, hostsPath, string(content), err, immutable))
+
+	firewallRules := 0
+	if cfg.EnableFirewall {
+		firewallRules = doctorFirewallRules()
+	}
+	return append(checks, checkFirewall(cfg.EnableFirewall, firewallRules), checkEmail(cfg.Accountability))
+}
+
+// checkConfig loads and validates the config file.
+func checkConfig() (*config.Config, DoctorCheck) {
+	const name = "Config file"
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, failed(name, firstLine(err.Error()), "fix the YAML in "+config.ConfigPath()+", or run: sudo glocker -install")
+	}
+	if err := config.ValidateConfig(cfg); err != nil {
+		return nil, failed(name, err.Error(), "fix the setting in "+config.ConfigPath()+", then run: glocker -reload")
+	}
+	return cfg, passed(name, config.ConfigPath()+" parses and validates")
+}
+
+// checkService checks that the systemd service is active.
+func checkService(running bool) DoctorCheck {
+	const name = "Service"
+	if !running {
+		return failed(name, "glocker.service is not active", "run: sudo systemctl start glocker.service (see journalctl -u glocker for errors)")
+	}
+	return passed(name, "glocker.service is active")
+}
+
+// checkSocket checks that the daemon accepts connections on its control socket.
+func checkSocket(path string, dialErr error) DoctorCheck {
+	const name = "Control socket"
+	if dialErr != nil {
+		return failed(name, fmt.Sprintf("%s: %v", path, dialErr), "restart the service: sudo systemctl restart glocker.service")
+	}
+	return passed(name, path+" accepts connections")
+}
+
+// checkHosts checks that the hosts file has the glocker section and is
+// immutable.
+func checkHosts(enabled bool, path, content string, readErr error, immutable bool) DoctorCheck {
+	const name = "Hosts file"
+	switch {
+	case !enabled:
+		return skipped(name, "enable_hosts is off")
+	case readErr != nil:
+		return failed(name, readErr.Error(), "check that "+path+" exists and is readable")
+	case !strings.Contains(content, config.HostsMarkerStart):
+		return failed(name, "no glocker block section in "+path, "wait for the next enforcement cycle or run: glocker -reload")
+	case !immutable:
+		return failed(name, path+" is not immutable", "check that chattr is installed and the filesystem supports it: sudo chattr +i "+path)
+	}
+	return passed(name, path+" has the block section and is immutable")
+}
+
+// checkFirewall checks that firewall rules are in place when the firewall is
+// enabled.
+func checkFirewall(enabled bool, rules int) DoctorCheck {
+	const name = "Firewall rules"
+	if !enabled {
+		return skipped(name, "enable_firewall is off")
+	}
+	if rules == 0 {
+		return failed(name, "no "+enforcement.FirewallRuleMarker+" rules in iptables or ip6tables", "check that iptables is installed and glocker runs as root")
+	}
+	return passed(name, fmt.Sprintf("%d rules active", rules))
+}
+
+// checkEmail checks that accountability email has a sender and recipients.
+func checkEmail(a config.AccountabilityConfig) DoctorCheck {
+	const name = "Accountability email"
+	switch {
+	case !a.Enabled:
+		return skipped(name, "accountability is disabled")
+	case len(a.Recipients()) == 0:
+		return failed(name, "no partner_email set", "set accountability.partner_email")
+	case a.FromEmail == "":
+		return failed(name, "no from_email set", "set accountability.from_email")
+	case strings.EqualFold(a.Provider, "smtp") && a.SMTPHost == "":
+		return failed(name, "no smtp_host set", "set accountability.smtp_host")
+	case !strings.EqualFold(a.Provider, "smtp") && (a.ApiKey == "" || a.MailgunDomain == ""):
+		return failed(name, "mailgun api_key or mailgun_domain missing", "set accountability.api_key and accountability.mailgun_domain")
+	}
+	return passed(name, "configured, verify delivery with: glocker -test-email")
+}
+
+// DoctorFailures counts the failed checks.
+func DoctorFailures(checks []DoctorCheck) int {
+	failures := 0
+	for _, check := range checks {
+		if check.Status == CheckFail {
+			failures++
+		}
+	}
+	return failures
+}
+
+// FormatDoctorReport renders the checks as a table, with the fix for each
+// failure below it, and a summary line.
+func FormatDoctorReport(checks []DoctorCheck) string {
+	var b strings.Builder
+	b.WriteString("╔════════════════════════════════════════════════╗\n")
+	b.WriteString("║                GLOCKER DOCTOR                  ║\n")
+	b.WriteString("╚════════════════════════════════════════════════╝\n\n")
+
+	nameWidth := 0
+	for _, check := range checks {
+		nameWidth = max(nameWidth, len(check.Name))
+	}
+	for _, check := range checks {
+		fmt.Fprintf(&b, "[%s] %-*s  %s\n", check.Status, nameWidth, check.Name, check.Detail)
+		if check.Status == CheckFail && check.Hint != "" {
+			fmt.Fprintf(&b, "       %*s  → %s\n", nameWidth, "", check.Hint)
+		}
+	}
+
+	failures := DoctorFailures(checks)
+	b.WriteString("\n")
+	if failures == 0 {
+		b.WriteString("All checks passed.\n")
+	} else {
+		fmt.Fprintf(&b, "%d of %d checks failed.\n", failures, len(checks))
+	}
+	return b.String()
+}
+
+// isImmutable reports whether lsattr shows the immutable flag on path.
+func isImmutable(path string) (bool, error) {
+	output, err := exec.Command("lsattr", "-d", path).Output()
+	if err != nil {
+		return false, err
+	}
+	attrs, _, _ := strings.Cut(strings.TrimSpace(string(output)), " ")
+	return strings.Contains(attrs, "i"), nil
+}
+
+// firstLine returns s up to its first newline.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
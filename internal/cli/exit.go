@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"glocker/internal/schema"
+)
+
+// Exit codes returned by the glocker command line.
+const (
+	ExitOK         = 0
+	ExitFailure    = 1 // Any failure not covered below
+	ExitDaemonDown = 2 // The daemon isn't running or its socket can't be reached
+	ExitRejected   = 3 // The daemon rejected the request (invalid reason, permanently blocked, limit reached)
+	ExitValidation = 4 // Invalid arguments or configuration
+	ExitPermission = 5 // The command must be run as root
+)
+
+// ExitError is a command line failure together with the exit code it produces.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// NewExitError creates an ExitError with a formatted message.
+func NewExitError(code int, format string, args ...any) *ExitError {
+	return &ExitError{Code: code, Err: fmt.Errorf(format, args...)}
+}
+
+// ExitCode returns the exit code for err: the code of a wrapped ExitError,
+// ExitOK for nil, and ExitFailure for anything else.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return ExitFailure
+}
+
+// FormatError renders err for stderr: the plain message, or
+// {"error":"...","code":N} when jsonOutput is set.
+func FormatError(err error, jsonOutput bool) string {
+	if !jsonOutput {
+		return err.Error()
+	}
+	data, _ := json.Marshal(struct {
+		SchemaVersion int    `json:"schema_version"`
+		Error         string `json:"error"`
+		Code          int    `json:"code"`
+	}{schema.Version, err.Error(), ExitCode(err)})
+	return string(data)
+}
@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"strings"
+	"time"
+
+	"glocker/internal/config"
+	"glocker/internal/enforcement"
+	"glocker/internal/state"
+)
+
+// MaxFocusDuration is the longest focus session -focus accepts.
+const MaxFocusDuration = 24 * time.Hour
+
+// ParseFocusDuration parses the length of a focus session, e.g. "50m" or "1h30m".
+// A plain number is taken as minutes.
+func ParseFocusDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		duration, err = time.ParseDuration(value + "m")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid focus duration %q (use e.g. 50m or 1h30m)", value)
+	}
+	if duration < time.Minute {
+		return 0, fmt.Errorf("focus duration must be at least a minute")
+	}
+	if duration > MaxFocusDuration {
+		return 0, fmt.Errorf("focus duration %v is longer than the maximum of %v", duration, MaxFocusDuration)
+	}
+	return duration, nil
+}
+
+// activeFocusSession returns the distraction domains blocked by a focus session
+// at now and when the last of them is unblocked. ok is false outside a session.
+func activeFocusSession(now time.Time) (domains []string, until time.Time, ok bool) {
+	for _, block := range state.GetTempBlocks() {
+		if now.Before(block.ExpiresAt) {
+			domains = append(domains, block.Domain)
+			if block.ExpiresAt.After(until) {
+				until = block.ExpiresAt
+			}
+		}
+	}
+	return domains, until, len(domains) > 0
+}
+
+// ProcessFocusRequest starts a focus session: focus.distraction_domains are
+// blocked on top of the other blocks until duration has passed. A session started
+// during another one extends it. Focus blocks only last for this daemon run.
+// Returns the time the session ends.
+func ProcessFocusRequest(cfg *config.Config, duration time.Duration) (time.Time, error) {
+	slog.Debug("Processing focus request", "duration", duration)
+
+	if len(cfg.Focus.DistractionDomains) == 0 {
+		return time.Time{}, fmt.Errorf("no distraction domains configured (set focus.distraction_domains)")
+	}
+	if duration < time.Minute || duration > MaxFocusDuration {
+		return time.Time{}, fmt.Errorf("focus duration must be between 1m and %v", MaxFocusDuration)
+	}
+
+	until := enforcement.Now().Add(duration)
+	for _, domain := range cfg.Focus.DistractionDomains {
+		state.AddTempBlock(strings.TrimSpace(domain), until)
+	}
+	log.Printf("🎯 FOCUS SESSION STARTED: %d distraction domains blocked for %v (until %s)",
+		len(cfg.Focus.DistractionDomains), duration, until.Format("15:04:05"))
+
+	// Block the distraction domains right away
+	enforcement.ForceEnforcement(cfg)
+
+	return until, nil
+}
@@ -0,0 +1,327 @@
+package cli
+
+import (
+	"encoding/json"
+	"time"
+
+	"glocker/internal/config"
+	"glocker/internal/enforcement"
+	"glocker/internal/monitoring"
+	"glocker/internal/schema"
+	"glocker/internal/state"
+)
+
+// StatusJSON is the machine-readable runtime status returned by the status-json command.
+type StatusJSON struct {
+	SchemaVersion       int                      `json:"schema_version"`
+	Time                time.Time                `json:"time"`
+	EnforcementProgress *EnforcementProgressJSON `json:"enforcement_progress,omitempty"` // Set while the hosts file is being written
+	BlockedDomains      int                      `json:"blocked_domains"`                // Excludes active temporary unblocks
+	ActiveUnblocks      []UnblockJSON            `json:"active_unblocks"`
+	UnblocksToday       *UnblockLimitJSON        `json:"unblocks_today,omitempty"` // Set when unblocking.max_per_day is configured
+	Violations          *ViolationsJSON          `json:"violations,omitempty"`     // Set when violation tracking is enabled
+	SelfTest            *SelfTestJSON            `json:"self_test,omitempty"`      // Set once a block self-test has run
+	ActiveProfile       string                   `json:"active_profile,omitempty"`
+	PanicUntil          *time.Time               `json:"panic_until,omitempty"`         // Set while panic mode is active
+	NextPanic           *PanicWindowJSON         `json:"next_panic,omitempty"`          // Current or next panic_schedule window
+	PausedUntil         *time.Time               `json:"paused_until,omitempty"`        // Set while enforcement is paused
+	UninstallUnlockAt   *time.Time               `json:"uninstall_unlock_at,omitempty"` // Set while an uninstall waits out its cooldown
+	Focus               *FocusJSON               `json:"focus,omitempty"`               // Set during a focus session
+	TimeWindowDomains   []TimeWindowStatusJSON   `json:"time_window_domains"`
+	Features            FeaturesJSON             `json:"features"`
+}
+
+// FocusJSON is an ongoing focus session.
+type FocusJSON struct {
+	Until            time.Time `json:"until"`
+	RemainingSeconds int64     `json:"remaining_seconds"`
+	Domains          []string  `json:"domains"`
+}
+
+// PanicWindowJSON is a scheduled panic window.
+type PanicWindowJSON struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// EnforcementProgressJSON reports an ongoing hosts file write.
+type EnforcementProgressJSON struct {
+	Written int `json:"written"`
+	Total   int `json:"total"`
+}
+
+// UnblockJSON is an active temporary unblock.
+type UnblockJSON struct {
+	Domain           string    `json:"domain"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RemainingSeconds int64     `json:"remaining_seconds"`
+	GrantedSeconds   int64     `json:"granted_seconds,omitempty"`
+}
+
+// UnblockLimitJSON reports usage of the daily unblock limit.
+type UnblockLimitJSON struct {
+	Used    int       `json:"used"`
+	Max     int       `json:"max"`
+	ResetAt time.Time `json:"reset_at"`
+}
+
+// ViolationsJSON reports violation tracking counters.
+type ViolationsJSON struct {
+	Recent        int `json:"recent"` // Weighted by violation_tracking.weights
+	Total         int `json:"total"`
+	Max           int `json:"max"`
+	WindowMinutes int `json:"window_minutes"`
+}
+
+// SelfTestJSON reports the last block self-test.
+type SelfTestJSON struct {
+	Time      time.Time `json:"time"`
+	Sampled   int       `json:"sampled"`
+	Verified  int       `json:"verified"`
+	Reachable []string  `json:"reachable"`
+}
+
+// TimeWindowStatusJSON reports whether a time-windowed domain is currently blocked.
+type TimeWindowStatusJSON struct {
+	Name           string     `json:"name"`
+	Blocking       bool       `json:"blocking"`
+	NextTransition *time.Time `json:"next_transition,omitempty"` // When blocking next starts or ends, if within a week
+}
+
+// FeaturesJSON reports which enforcement features are enabled.
+type FeaturesJSON struct {
+	Hosts             bool `json:"hosts"`
+	Firewall          bool `json:"firewall"`
+	ForbiddenPrograms bool `json:"forbidden_programs"`
+	SelfHealing       bool `json:"self_healing"`
+	Sudoers           bool `json:"sudoers"`
+	TamperDetection   bool `json:"tamper_detection"`
+	Accountability    bool `json:"accountability"`
+	WebTracking       bool `json:"web_tracking"`
+	ContentMonitoring bool `json:"content_monitoring"`
+	ViolationTracking bool `json:"violation_tracking"`
+}
+
+// InfoJSON is the machine-readable configuration summary returned by the info-json command.
+type InfoJSON struct {
+	SchemaVersion          int                    `json:"schema_version"`
+	EnforceIntervalSeconds int                    `json:"enforce_interval_seconds"`
+	TotalDomains           int                    `json:"total_domains"`
+	AlwaysBlockedDomains   int                    `json:"always_blocked_domains"`
+	TimeWindowDomains      []TimeWindowDomainJSON `json:"time_window_domains"`
+	ForbiddenPrograms      []TimeWindowDomainJSON `json:"forbidden_programs,omitempty"` // Set when forbidden programs are enabled
+	Sudoers                *SudoersJSON           `json:"sudoers,omitempty"`            // Set when sudoers restrictions are enabled
+	URLKeywords            []string               `json:"url_keywords"`
+	ContentKeywords        []string               `json:"content_keywords"`
+	WhitelistedDomains     int                    `json:"whitelisted_domains"`
+	Features               FeaturesJSON           `json:"features"`
+}
+
+// TimeWindowDomainJSON is a domain or program with its blocking windows
+// (no windows means always blocked).
+type TimeWindowDomainJSON struct {
+	Name        string           `json:"name"`
+	TimeWindows []TimeWindowJSON `json:"time_windows"`
+}
+
+// TimeWindowJSON is a time window in HH:MM with its days.
+type TimeWindowJSON struct {
+	Start string   `json:"start"`
+	End   string   `json:"end"`
+	Days  []string `json:"days"`
+}
+
+// SudoersJSON reports the sudoers restriction.
+type SudoersJSON struct {
+	User        string           `json:"user"`
+	TimeAllowed []TimeWindowJSON `json:"time_allowed"`
+}
+
+// BuildStatusJSON collects the runtime status at the given time.
+func BuildStatusJSON(cfg *config.Config, now time.Time) StatusJSON {
+	status := StatusJSON{
+		SchemaVersion:     schema.Version,
+		Time:              now,
+		ActiveUnblocks:    []UnblockJSON{},
+		TimeWindowDomains: []TimeWindowStatusJSON{},
+		Features:          buildFeaturesJSON(cfg),
+	}
+
+	if written, total, active := state.GetHostsWriteProgress(); active {
+		status.EnforcementProgress = &EnforcementProgressJSON{Written: written, Total: total}
+	}
+
+	for _, unblock := range state.GetTempUnblocks() {
+		if !now.Before(unblock.ExpiresAt) {
+			continue
+		}
+		status.ActiveUnblocks = append(status.ActiveUnblocks, UnblockJSON{
+			Domain:           unblock.Domain,
+			ExpiresAt:        unblock.ExpiresAt,
+			RemainingSeconds: int64(unblock.ExpiresAt.Sub(now) / time.Second),
+			GrantedSeconds:   int64(unblock.Duration / time.Second),
+		})
+	}
+
+	_, blockedCount, _ := enforcement.GetEnforcementState()
+	status.BlockedDomains = max(blockedCount-len(status.ActiveUnblocks), 0)
+
+	if maxPerDay := cfg.Unblocking.MaxPerDay; maxPerDay > 0 {
+		periodStart := cfg.Unblocking.UnblockPeriodStart(now)
+		status.UnblocksToday = &UnblockLimitJSON{
+			Used:    state.CountUnblockGrantsSince(periodStart),
+			Max:     maxPerDay,
+			ResetAt: periodStart.AddDate(0, 0, 1),
+		}
+	}
+
+	if cfg.ViolationTracking.Enabled {
+		violations := state.GetViolations()
+		status.Violations = &ViolationsJSON{
+			Recent:        monitoring.CountRecentViolations(cfg, now),
+			Total:         len(violations),
+			Max:           cfg.ViolationTracking.MaxViolations,
+			WindowMinutes: cfg.ViolationTracking.TimeWindowMinutes,
+		}
+	}
+
+	if result, ok := state.GetSelfTestResult(); ok {
+		status.SelfTest = &SelfTestJSON{
+			Time:      result.Time,
+			Sampled:   result.Sampled,
+			Verified:  result.Verified,
+			Reachable: nonNil(result.Reachable),
+		}
+	}
+
+	status.ActiveProfile, _ = state.GetActiveProfile()
+
+	if panicUntil := state.GetPanicUntil(); !panicUntil.IsZero() && now.Before(panicUntil) {
+		status.PanicUntil = &panicUntil
+	}
+
+	if start, end, ok := monitoring.NextPanicWindow(cfg.PanicSchedule, now); ok {
+		status.NextPanic = &PanicWindowJSON{Start: start, End: end}
+	}
+
+	if pausedUntil := state.GetPausedUntil(); now.Before(pausedUntil) {
+		status.PausedUntil = &pausedUntil
+	}
+
+	if pending, ok := state.GetPendingUninstall(); ok {
+		status.UninstallUnlockAt = &pending.UnlockAt
+	}
+
+	if domains, until, ok := activeFocusSession(now); ok {
+		status.Focus = &FocusJSON{Until: until, RemainingSeconds: int64(until.Sub(now).Seconds()), Domains: domains}
+	}
+
+	windowState := enforcement.GetTimeWindowState(now)
+	for _, domain := range enforcement.GetTimeWindowDomains() {
+		domainStatus := TimeWindowStatusJSON{
+			Name:     domain.Name,
+			Blocking: windowState[domain.Name],
+		}
+		if at, _, ok := enforcement.NextTimeWindowTransition(domain.TimeWindows, now); ok {
+			domainStatus.NextTransition = &at
+		}
+		status.TimeWindowDomains = append(status.TimeWindowDomains, domainStatus)
+	}
+
+	return status
+}
+
+// BuildInfoJSON collects the configuration summary.
+func BuildInfoJSON(cfg *config.Config) InfoJSON {
+	_, blockedCount, _ := enforcement.GetEnforcementState()
+	timeWindowDomains := enforcement.GetTimeWindowDomains()
+
+	info := InfoJSON{
+		SchemaVersion:          schema.Version,
+		EnforceIntervalSeconds: cfg.EnforceInterval,
+		TotalDomains:           blockedCount,
+		AlwaysBlockedDomains:   max(blockedCount-len(timeWindowDomains), 0),
+		TimeWindowDomains:      make([]TimeWindowDomainJSON, 0, len(timeWindowDomains)),
+		URLKeywords:            nonNil(cfg.ExtensionKeywords.URLKeywords),
+		ContentKeywords:        nonNil(cfg.ExtensionKeywords.ContentKeywords),
+		WhitelistedDomains:     len(cfg.ExtensionKeywords.Whitelist),
+		Features:               buildFeaturesJSON(cfg),
+	}
+
+	for _, domain := range timeWindowDomains {
+		info.TimeWindowDomains = append(info.TimeWindowDomains, TimeWindowDomainJSON{
+			Name:        domain.Name,
+			TimeWindows: toTimeWindowsJSON(domain.TimeWindows),
+		})
+	}
+
+	if cfg.EnableForbiddenPrograms && cfg.ForbiddenPrograms.Enabled {
+		for _, program := range cfg.ForbiddenPrograms.Programs {
+			info.ForbiddenPrograms = append(info.ForbiddenPrograms, TimeWindowDomainJSON{
+				Name:        program.Name,
+				TimeWindows: toTimeWindowsJSON(program.TimeWindows),
+			})
+		}
+	}
+
+	if cfg.Sudoers.Enabled && len(cfg.Sudoers.TimeAllowed) > 0 {
+		info.Sudoers = &SudoersJSON{
+			User:        cfg.Sudoers.User,
+			TimeAllowed: toTimeWindowsJSON(cfg.Sudoers.TimeAllowed),
+		}
+	}
+
+	return info
+}
+
+// GetStatusJSONResponse returns the runtime status as JSON followed by the END marker.
+func GetStatusJSONResponse(cfg *config.Config) string {
+	return jsonResponse(BuildStatusJSON(cfg, time.Now()))
+}
+
+// GetInfoJSONResponse returns the configuration summary as JSON followed by the END marker.
+func GetInfoJSONResponse(cfg *config.Config) string {
+	return jsonResponse(BuildInfoJSON(cfg))
+}
+
+// jsonResponse encodes v on a single line, terminated like the other multi-line responses.
+func jsonResponse(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(map[string]string{"error": err.Error()})
+	}
+	return string(data) + "\nEND\n"
+}
+
+// buildFeaturesJSON reports the enabled features of cfg.
+func buildFeaturesJSON(cfg *config.Config) FeaturesJSON {
+	return FeaturesJSON{
+		Hosts:             cfg.EnableHosts,
+		Firewall:          cfg.EnableFirewall,
+		ForbiddenPrograms: cfg.EnableForbiddenPrograms && cfg.ForbiddenPrograms.Enabled,
+		SelfHealing:       cfg.SelfHeal,
+		Sudoers:           cfg.Sudoers.Enabled,
+		TamperDetection:   cfg.TamperDetection.Enabled,
+		Accountability:    cfg.Accountability.Enabled,
+		WebTracking:       cfg.WebTracking.Enabled,
+		ContentMonitoring: cfg.ContentMonitoring.Enabled,
+		ViolationTracking: cfg.ViolationTracking.Enabled,
+	}
+}
+
+// toTimeWindowsJSON converts config time windows for JSON output.
+func toTimeWindowsJSON(windows []config.TimeWindow) []TimeWindowJSON {
+	result := make([]TimeWindowJSON, 0, len(windows))
+	for _, window := range windows {
+		result = append(result, TimeWindowJSON{Start: window.Start, End: window.End, Days: nonNil(window.Days)})
+	}
+	return result
+}
+
+// nonNil returns list, or an empty list if it is nil, so it encodes as [] rather than null.
+func nonNil(list []string) []string {
+	if list == nil {
+		return []string{}
+	}
+	return list
+}
@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"glocker/internal/config"
+	"glocker/internal/enforcement"
+)
+
+// SimulateTimeLayout is the format of the time given to -simulate.
+const SimulateTimeLayout = "2006-01-02 15:04"
+
+// ParseSimulateTime parses the local time given to -simulate, e.g.
+// "2024-06-11 15:00".
+func ParseSimulateTime(value string) (time.Time, error) {
+	at, err := time.ParseInLocation(SimulateTimeLayout, strings.TrimSpace(value), time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid simulate time %q (use \"YYYY-MM-DD HH:MM\")", value)
+	}
+	return at, nil
+}
+
+// simulateDomain returns whether domain would be blocked at at and why, from
+// the config alone: temporary unblocks, pauses and the like aren't considered.
+func simulateDomain(cfg *config.Config, domain config.Domain, at time.Time) (bool, string) {
+	if enforcement.IsAllowlistActive(cfg, at) && !cfg.AllowlistMode.Allows(domain.Name) {
+		return true, "not on the allowlist (allowlist mode)"
+	}
+	if !enforcement.IsScheduledBlock(domain, at) {
+		return false, fmt.Sprintf("outside its time windows (%s)", formatTimeWindows(domain.TimeWindows))
+	}
+	return true, enforcement.GetBlockingReason(cfg, domain.Name, at)
+}
+
+// GetSimulateResponse lists every configured domain with whether it would be
+// blocked at at and why. It works from the config alone, so the daemon needn't
+// run.
+func GetSimulateResponse(cfg *config.Config, at time.Time) string {
+	var response strings.Builder
+
+	response.WriteString("╔════════════════════════════════════════════════╗\n")
+	response.WriteString("║              BLOCKING SIMULATION               ║\n")
+	response.WriteString("╚════════════════════════════════════════════════╝\n\n")
+
+	response.WriteString(fmt.Sprintf("Time: %s (%s)\n", at.Format(SimulateTimeLayout), at.Weekday()))
+	response.WriteString("Temporary unblocks and pauses are not taken into account.\n\n")
+
+	blockedCount := 0
+	for _, domain := range cfg.Domains {
+		blocked, reason := simulateDomain(cfg, domain, at)
+		status := "allowed"
+		if blocked {
+			status = "BLOCKED"
+			blockedCount++
+		}
+		response.WriteString(fmt.Sprintf("  %-7s %s: %s\n", status, formatDomainName(domain), reason))
+	}
+
+	response.WriteString(fmt.Sprintf("\n%d of %d domains would be blocked\n", blockedCount, len(cfg.Domains)))
+	return response.String()
+}
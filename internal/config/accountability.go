@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Recipients returns every address accountability emails are sent to: the
+// comma-separated partner_email followed by partner_emails, without duplicates.
+func (a AccountabilityConfig) Recipients() []string {
+	var recipients []string
+	seen := make(map[string]bool)
+	for _, address := range append(strings.Split(a.PartnerEmail, ","), a.PartnerEmails...) {
+		address = strings.TrimSpace(address)
+		if address == "" || seen[strings.ToLower(address)] {
+			continue
+		}
+		seen[strings.ToLower(address)] = true
+		recipients = append(recipients, address)
+	}
+	return recipients
+}
+
+// defaultEmailMaxRetries is how many times a failed email is retried when
+// accountability.max_retries is not set.
+const defaultEmailMaxRetries = 3
+
+// EmailMaxRetries returns how many times a failed email send is retried,
+// defaulting to 3. Zero disables retries.
+func (a AccountabilityConfig) EmailMaxRetries() int {
+	if a.MaxRetries == nil {
+		return defaultEmailMaxRetries
+	}
+	return *a.MaxRetries
+}
+
+// Weekly report defaults: Sunday evening, summarizing the week that just ended.
+const (
+	defaultWeeklyReportDay  = time.Sunday
+	defaultWeeklyReportTime = "20:00"
+)
+
+// WeeklyReportSchedule returns the weekday and HH:MM time the weekly report is sent,
+// defaulting to Sunday at 20:00. The day may be a full or three-letter name in any case.
+func (a AccountabilityConfig) WeeklyReportSchedule() (time.Weekday, string, error) {
+	day := defaultWeeklyReportDay
+	if name := strings.ToLower(strings.TrimSpace(a.WeeklyReportDay)); name != "" {
+		found := false
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			if full := strings.ToLower(d.String()); name == full || name == full[:3] {
+				day, found = d, true
+				break
+			}
+		}
+		if !found {
+			return 0, "", fmt.Errorf("invalid weekly_report_day %q", a.WeeklyReportDay)
+		}
+	}
+
+	reportTime := a.WeeklyReportTime
+	if reportTime == "" {
+		reportTime = defaultWeeklyReportTime
+	}
+	if !isValidTime(reportTime) {
+		return 0, "", fmt.Errorf("invalid weekly_report_time %q (use HH:MM)", reportTime)
+	}
+	return day, reportTime, nil
+}
@@ -0,0 +1,14 @@
+package config
+
+// Allows reports whether host is on the allowlist: covered by one of the
+// allowed domains (honoring exact_only and except_subdomains) or matched by an
+// allowed pattern.
+func (a *AllowlistModeConfig) Allows(host string) bool {
+	for i := range a.AllowedDomains {
+		domain := &a.AllowedDomains[i]
+		if domain.CoversHost(host) || domain.MatchesHost(host) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// BlocklistFormat identifies the syntax of a blocklist file.
+type BlocklistFormat int
+
+const (
+	// BlocklistPlain lists one domain per line.
+	BlocklistPlain BlocklistFormat = iota
+	// BlocklistHosts uses hosts file lines ("0.0.0.0 domain").
+	BlocklistHosts
+	// BlocklistABP uses AdBlock Plus filter syntax ("||domain^").
+	BlocklistABP
+)
+
+// String returns the format name used in logs.
+func (f BlocklistFormat) String() string {
+	switch f {
+	case BlocklistHosts:
+		return "hosts"
+	case BlocklistABP:
+		return "abp"
+	default:
+		return "plain"
+	}
+}
+
+// blocklistDetectLines is how many rule lines are inspected to detect the format.
+const blocklistDetectLines = 100
+
+// blocklistSinkAddresses are the addresses hosts-style lists use to null-route a domain.
+var blocklistSinkAddresses = []string{"0.0.0.0", "127.0.0.1", "::", "::1"}
+
+// blocklistIgnoredNames are entries found in hosts-style lists that must never be blocked.
+var blocklistIgnoredNames = []string{
+	"localhost", "localhost.localdomain", "local", "broadcasthost",
+	"ip6-localhost", "ip6-loopback", "0.0.0.0",
+}
+
+// abpDomainOptions are ABP rule options that still block the whole domain.
+// Rules with any other option ($third-party, $script, $domain=...) are skipped.
+var abpDomainOptions = []string{"important", "all", "document", "doc"}
+
+// ParseBlocklist reads a blocklist in hosts, AdBlock Plus or plain domain-per-line
+// format (detected automatically) and returns the listed domains in order, lowercased
+// and without duplicates. Comments and rules that can't be expressed as a plain
+// domain block (cosmetic filters, exceptions, regexes, paths, wildcards) are ignored.
+// Returns an error, and no domains, if the list can't be read in full (for example
+// a line longer than 1 MiB), so a partial list is never taken for the whole one.
+func ParseBlocklist(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading blocklist line %d: %w", len(lines)+1, err)
+	}
+
+	format := DetectBlocklistFormat(lines)
+
+	var domains []string
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		var names []string
+		switch format {
+		case BlocklistHosts:
+			names = parseHostsLine(line)
+		case BlocklistABP:
+			names = parseABPLine(line)
+		default:
+			names = parsePlainLine(line)
+		}
+
+		for _, name := range names {
+			name = strings.ToLower(strings.TrimSuffix(name, "."))
+			if seen[name] || slices.Contains(blocklistIgnoredNames, name) || !isBlocklistDomain(name) {
+				continue
+			}
+			seen[name] = true
+			domains = append(domains, name)
+		}
+	}
+
+	return domains, nil
+}
+
+// DetectBlocklistFormat guesses a blocklist's format from its first rule lines.
+// An "[Adblock ...]" header or a "||" rule means ABP; a line starting with a sink
+// address means hosts; anything else is treated as plain domains.
+func DetectBlocklistFormat(lines []string) BlocklistFormat {
+	checked := 0
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[Adblock") || strings.HasPrefix(line, "||") {
+			return BlocklistABP
+		}
+		if strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+			continue
+		}
+		if fields := strings.Fields(line); len(fields) >= 2 && slices.Contains(blocklistSinkAddresses, fields[0]) {
+			return BlocklistHosts
+		}
+
+		checked++
+		if checked >= blocklistDetectLines {
+			break
+		}
+	}
+	return BlocklistPlain
+}
+
+// parseHostsLine returns the names on a hosts line pointing at a sink address.
+func parseHostsLine(line string) []string {
+	if idx := strings.Index(line, "#"); idx != -1 {
+		line = line[:idx]
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 2 || !slices.Contains(blocklistSinkAddresses, fields[0]) {
+		return nil
+	}
+	return fields[1:]
+}
+
+// parseABPLine returns the domain of a "||domain^" rule, or nil for any rule that
+// isn't a whole-domain block.
+func parseABPLine(line string) []string {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "||") {
+		// Comments, headers, exceptions (@@), cosmetic filters (##), regexes and
+		// unanchored URL rules can't be expressed as a domain block.
+		return nil
+	}
+	rule := strings.TrimPrefix(line, "||")
+
+	if idx := strings.Index(rule, "$"); idx != -1 {
+		for _, option := range strings.Split(rule[idx+1:], ",") {
+			if !slices.Contains(abpDomainOptions, strings.TrimSpace(option)) {
+				return nil
+			}
+		}
+		rule = rule[:idx]
+	}
+
+	rule = strings.TrimSuffix(rule, "|")
+	rule = strings.TrimSuffix(rule, "^")
+	return []string{rule}
+}
+
+// parsePlainLine returns the domain on a plain domain-per-line entry.
+func parsePlainLine(line string) []string {
+	if idx := strings.Index(line, "#"); idx != -1 {
+		line = line[:idx]
+	}
+	fields := strings.Fields(line)
+	if len(fields) != 1 {
+		return nil
+	}
+	return fields
+}
+
+// isBlocklistDomain reports whether name is a plain domain name (no wildcards, paths,
+// ports or IP addresses) with at least two labels.
+func isBlocklistDomain(name string) bool {
+	if len(name) == 0 || len(name) > 253 || !strings.Contains(name, ".") {
+		return false
+	}
+	labels := strings.Split(name, ".")
+	if strings.Trim(labels[len(labels)-1], "0123456789") == "" {
+		return false
+	}
+	for _, label := range labels {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
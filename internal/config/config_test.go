@@ -1,7 +1,15 @@
 package config
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 func TestValidateConfig_EmptyDomainName(t *testing.T) {
@@ -125,6 +133,759 @@ func TestValidateConfig_ForbiddenPrograms(t *testing.T) {
 	}
 }
 
+func TestValidateConfig_ForbiddenProgramMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		program ForbiddenProgram
+		wantErr bool
+	}{
+		{"default", ForbiddenProgram{Name: "steam"}, false},
+		{"exact", ForbiddenProgram{Name: "steam", Match: "exact"}, false},
+		{"regex", ForbiddenProgram{Name: "^steam$", Match: "regex"}, false},
+		{"invalid regex", ForbiddenProgram{Name: "steam(", Match: "regex"}, true},
+		{"unknown match", ForbiddenProgram{Name: "steam", Match: "glob"}, true},
+		{"cgroup match", ForbiddenProgram{Name: "steam", CgroupMatch: `app-flatpak-com\.valvesoftware\.Steam`}, false},
+		{"invalid cgroup match", ForbiddenProgram{Name: "steam", CgroupMatch: "app-("}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{
+				EnableForbiddenPrograms: true,
+				ForbiddenPrograms: ForbiddenProgramsConfig{
+					Enabled:  true,
+					Programs: []ForbiddenProgram{tt.program},
+				},
+			}
+
+			err := ValidateConfig(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateConfig_AbsoluteWindows(t *testing.T) {
+	night := []TimeWindow{{Start: "22:00", End: "06:00", Days: []string{"Mon"}}}
+
+	cfg := &Config{Domains: []Domain{{Name: "example.com", Unblockable: true, AbsoluteWindows: night}}}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Expected valid absolute windows, got: %v", err)
+	}
+
+	cfg.Domains[0].Unblockable = false
+	if err := ValidateConfig(cfg); err == nil {
+		t.Error("Expected an error for absolute windows on a permanent domain")
+	}
+
+	cfg.Domains[0].Unblockable = true
+	cfg.Domains[0].AbsoluteWindows = []TimeWindow{{Start: "25:00", End: "06:00", Days: []string{"Mon"}}}
+	if err := ValidateConfig(cfg); !errors.Is(err, ErrInvalidTimeWindow) {
+		t.Errorf("Expected ErrInvalidTimeWindow, got: %v", err)
+	}
+}
+
+func TestValidateConfig_DoHExtraDomains(t *testing.T) {
+	cfg := &Config{BlockDoH: true, DoHExtraDomains: []string{"doh.example.net"}}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Expected valid doh_extra_domains, got: %v", err)
+	}
+
+	for _, domain := range []string{"", "https://doh.example.net/dns-query"} {
+		cfg.DoHExtraDomains = []string{domain}
+		if err := ValidateConfig(cfg); err == nil {
+			t.Errorf("Expected an error for doh_extra_domains entry %q", domain)
+		}
+	}
+}
+
+func TestValidateConfig_PanicScheduleNeedsCommand(t *testing.T) {
+	cfg := &Config{PanicSchedule: []TimeWindow{{Start: "00:00", End: "06:00", Days: []string{"Mon"}}}}
+	if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "panic_command") {
+		t.Errorf("Expected panic_schedule without panic_command to be rejected, got: %v", err)
+	}
+
+	cfg.PanicCommand = "systemctl suspend"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Expected panic_schedule with panic_command to be valid, got: %v", err)
+	}
+}
+
+func TestValidateConfig_WarnRatio(t *testing.T) {
+	for ratio, valid := range map[float64]bool{0: true, 0.8: true, 1: true, -0.1: false, 1.5: false} {
+		cfg := &Config{ViolationTracking: ViolationTrackingConfig{WarnRatio: ratio}}
+		if err := ValidateConfig(cfg); (err == nil) != valid {
+			t.Errorf("warn_ratio %v: valid = %v, got error %v", ratio, valid, err)
+		}
+	}
+}
+
+func TestValidateConfig_ViolationActions(t *testing.T) {
+	disabled := false
+	tests := []struct {
+		name   string
+		action ViolationAction
+		valid  bool
+	}{
+		{"command", ViolationAction{Type: "command", Command: "logger", Args: []string{"too many"}}, true},
+		{"command without command", ViolationAction{Type: "command"}, false},
+		{"lock", ViolationAction{Type: "lock"}, true},
+		{"suspend with panic_command", ViolationAction{Type: "suspend"}, true},
+		{"notify", ViolationAction{Type: "notify"}, true},
+		{"unknown type", ViolationAction{Type: "reboot"}, false},
+		{"disabled unknown type", ViolationAction{Type: "reboot", Enabled: &disabled}, false},
+	}
+	for _, tt := range tests {
+		cfg := &Config{
+			PanicCommand:   "systemctl suspend",
+			Accountability: AccountabilityConfig{Enabled: true},
+			ViolationTracking: ViolationTrackingConfig{
+				MindfulText: "I will focus on my work.",
+				Actions:     []ViolationAction{tt.action},
+			},
+		}
+		if err := ValidateConfig(cfg); (err == nil) != tt.valid {
+			t.Errorf("%s: valid = %v, got error %v", tt.name, tt.valid, err)
+		}
+	}
+
+	// Actions need the settings they rely on
+	for _, action := range []ViolationAction{{Type: "lock"}, {Type: "suspend"}, {Type: "notify"}} {
+		cfg := &Config{ViolationTracking: ViolationTrackingConfig{Actions: []ViolationAction{action}}}
+		if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "violation_tracking.actions[0]") {
+			t.Errorf("%s action without its settings: expected an actions[0] error, got %v", action.Type, err)
+		}
+	}
+}
+
+func TestValidateConfig_Notifications(t *testing.T) {
+	for webhookURL, valid := range map[string]bool{"": true, "https://hooks.slack.com/services/T0/B0/X": true, "http://localhost:8080/hook": true, "hooks.slack.com/services": false, "ftp://example.com/hook": false} {
+		cfg := &Config{Notifications: NotificationsConfig{WebhookURL: webhookURL}}
+		if err := ValidateConfig(cfg); (err == nil) != valid {
+			t.Errorf("notifications.webhook_url %q: valid = %v, got error %v", webhookURL, valid, err)
+		}
+	}
+
+	// A webhook alone is enough for notify actions
+	cfg := &Config{
+		Notifications:     NotificationsConfig{WebhookURL: "https://example.com/hook"},
+		ViolationTracking: ViolationTrackingConfig{Actions: []ViolationAction{{Type: "notify"}}},
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("notify action with a webhook: %v", err)
+	}
+	if timeout := (NotificationsConfig{}).WebhookTimeout(); timeout != 10*time.Second {
+		t.Errorf("Default webhook timeout = %v, want 10s", timeout)
+	}
+}
+
+func TestValidateConfig_ViolationWeights(t *testing.T) {
+	for _, tc := range []struct {
+		weights map[string]int
+		valid   bool
+	}{
+		{nil, true},
+		{map[string]int{"web_access": 1, "content_report": 0, "forbidden_program": 3}, true},
+		{map[string]int{"web_acess": 2}, false},
+		{map[string]int{"forbidden_program": -1}, false},
+	} {
+		cfg := &Config{ViolationTracking: ViolationTrackingConfig{Weights: tc.weights}}
+		if err := ValidateConfig(cfg); (err == nil) != tc.valid {
+			t.Errorf("violation_tracking.weights %v: valid = %v, got error %v", tc.weights, tc.valid, err)
+		}
+	}
+
+	tracking := ViolationTrackingConfig{Weights: map[string]int{"forbidden_program": 3, "content_report": 0}}
+	for violationType, want := range map[string]int{"forbidden_program": 3, "content_report": 0, "web_access": 1} {
+		if got := tracking.Weight(violationType); got != want {
+			t.Errorf("Weight(%q) = %d, want %d", violationType, got, want)
+		}
+	}
+}
+
+func TestValidateConfig_ObserverSocket(t *testing.T) {
+	for path, valid := range map[string]bool{"": true, "/run/glocker-observer.sock": true, GlockerSock: false, "/run/glocker/../glocker/glocker.sock": false} {
+		cfg := &Config{ObserverSocket: ObserverSocketConfig{Enabled: true, Path: path}}
+		if err := ValidateConfig(cfg); (err == nil) != valid {
+			t.Errorf("observer_socket.path %q: valid = %v, got error %v", path, valid, err)
+		}
+	}
+	if path := (ObserverSocketConfig{}).SocketPath(); path != DefaultObserverSock {
+		t.Errorf("Default observer socket path = %s, want %s", path, DefaultObserverSock)
+	}
+}
+
+func TestParseQuotes(t *testing.T) {
+	// Blank lines separate multi-line quotes
+	quotes := ParseQuotes("To be, or not to be:\r\nthat is the question.\n\n  \n\nOnce more unto the breach.\n   \nWhat's done is done.\n")
+	want := []string{"To be, or not to be:\nthat is the question.", "Once more unto the breach.", "What's done is done."}
+	if !slices.Equal(quotes, want) {
+		t.Errorf("ParseQuotes() with blank lines = %q, want %q", quotes, want)
+	}
+
+	// Without blank lines every line is a quote
+	quotes = ParseQuotes("\nFirst quote.\n  Second quote.  \nThird quote.\n\n")
+	want = []string{"First quote.", "Second quote.", "Third quote."}
+	if !slices.Equal(quotes, want) {
+		t.Errorf("ParseQuotes() one per line = %q, want %q", quotes, want)
+	}
+
+	if quotes := ParseQuotes(" \n\n "); len(quotes) != 0 {
+		t.Errorf("Expected no quotes for blank text, got %q", quotes)
+	}
+}
+
+func TestLoadMindfulQuotes(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "quotes.txt")
+	if err := os.WriteFile(path, []byte("Be still.\nBreathe.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	quotes, err := LoadMindfulQuotes(path)
+	if err != nil || !slices.Equal(quotes, []string{"Be still.", "Breathe."}) {
+		t.Errorf("LoadMindfulQuotes() = %q, %v", quotes, err)
+	}
+
+	empty := filepath.Join(dir, "empty.txt")
+	if err := os.WriteFile(empty, []byte("\n\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadMindfulQuotes(empty); err == nil {
+		t.Error("Expected an error for a file without quotes")
+	}
+	if _, err := LoadMindfulQuotes(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestRandomQuote_Uniform(t *testing.T) {
+	if quote := RandomQuote(nil); quote != "" {
+		t.Errorf("RandomQuote(nil) = %q, want empty", quote)
+	}
+
+	quotes := []string{"a", "b", "c", "d"}
+	const draws = 8000
+	counts := make(map[string]int)
+	for range draws {
+		counts[RandomQuote(quotes)]++
+	}
+	// Each quote is expected 2000 times; 1700-2300 is more than 7 standard deviations
+	for _, quote := range quotes {
+		if c := counts[quote]; c < 1700 || c > 2300 {
+			t.Errorf("Quote %q picked %d times out of %d, expected about %d", quote, c, draws, draws/len(quotes))
+		}
+	}
+}
+
+func TestRedactConfig(t *testing.T) {
+	input := `# Accountability settings
+accountability:
+  enabled: true
+  partner_email: "partner@example.com"
+  api_key: "key-0123456789abcdef"
+  smtp_username: "me@example.com"
+  smtp_password: "hunter2"
+notifications:
+  webhook_url: "https://hooks.slack.com/services/T000/B000/XXXX"
+domains:
+  - name: "reddit.com"
+    time_windows:
+      - start: "09:00"
+        end: "17:00"
+        days: ["Weekdays"]
+`
+	exported, err := RedactConfig([]byte(input))
+	if err != nil {
+		t.Fatalf("RedactConfig() error: %v", err)
+	}
+	out := string(exported)
+
+	for _, secret := range []string{"key-0123456789abcdef", "hunter2", "hooks.slack.com"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("Exported config still contains %q:\n%s", secret, out)
+		}
+	}
+	if !strings.Contains(out, "# Accountability settings") {
+		t.Errorf("Expected comments to be kept:\n%s", out)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(exported, &cfg); err != nil {
+		t.Fatalf("Exported config doesn't parse: %v", err)
+	}
+	a := cfg.Accountability
+	if a.ApiKey != "REDACTED" || a.SMTPPassword != "REDACTED" {
+		t.Errorf("Expected redacted secrets, got api_key %q and smtp_password %q", a.ApiKey, a.SMTPPassword)
+	}
+	if !a.Enabled || a.PartnerEmail != "partner@example.com" || a.SMTPUsername != "me@example.com" {
+		t.Errorf("Other accountability settings changed: %+v", a)
+	}
+	if len(cfg.Domains) != 1 || cfg.Domains[0].Name != "reddit.com" || len(cfg.Domains[0].TimeWindows) != 1 ||
+		!slices.Equal(cfg.Domains[0].TimeWindows[0].Days, []string{"Weekdays"}) {
+		t.Errorf("Domains changed: %+v", cfg.Domains)
+	}
+
+	if _, err := RedactConfig([]byte("domains: [")); err == nil {
+		t.Error("Expected an error for invalid YAML")
+	}
+}
+
+func TestValidateConfig_KeywordCategories(t *testing.T) {
+	window := TimeWindow{Start: "09:00", End: "17:00", Days: []string{"Mon"}}
+	cfg := &Config{KeywordCategories: []KeywordCategory{{Name: "gaming", TimeWindows: []TimeWindow{window}}}}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Expected valid keyword_categories, got: %v", err)
+	}
+
+	invalid := [][]KeywordCategory{
+		{{Name: ""}},
+		{{Name: "gaming"}, {Name: "gaming"}},
+		{{Name: "gaming", TimeWindows: []TimeWindow{{Start: "9am", End: "17:00", Days: []string{"Mon"}}}}},
+		{{Name: "gaming", TimeWindows: []TimeWindow{{Start: "09:00", End: "17:00"}}}},
+	}
+	for _, categories := range invalid {
+		cfg.KeywordCategories = categories
+		if err := ValidateConfig(cfg); err == nil {
+			t.Errorf("Expected an error for keyword_categories %+v", categories)
+		}
+	}
+}
+
+func TestWebTrackingConfig_Addresses(t *testing.T) {
+	tests := []struct {
+		name      string
+		web       WebTrackingConfig
+		httpAddr  string
+		httpsAddr string
+		baseURL   string
+	}{
+		{"defaults", WebTrackingConfig{}, ":80", ":443", "http://127.0.0.1"},
+		{"loopback", WebTrackingConfig{BindAddr: "127.0.0.1"}, "127.0.0.1:80", "127.0.0.1:443", "http://127.0.0.1"},
+		{"alternate ports", WebTrackingConfig{HTTPPort: 8080, HTTPSPort: 8443, BindAddr: "127.0.0.1"}, "127.0.0.1:8080", "127.0.0.1:8443", "http://127.0.0.1:8080"},
+		{"all interfaces", WebTrackingConfig{HTTPPort: 8080, BindAddr: "0.0.0.0"}, "0.0.0.0:8080", "0.0.0.0:443", "http://127.0.0.1:8080"},
+		{"other address", WebTrackingConfig{BindAddr: "127.0.0.2"}, "127.0.0.2:80", "127.0.0.2:443", "http://127.0.0.2"},
+		{"IPv6", WebTrackingConfig{HTTPPort: 8080, BindAddr: "::1"}, "[::1]:8080", "[::1]:443", "http://[::1]:8080"},
+		{"IPv6 default port", WebTrackingConfig{BindAddr: "::1"}, "[::1]:80", "[::1]:443", "http://[::1]"},
+	}
+	for _, tt := range tests {
+		if got := tt.web.HTTPAddr(); got != tt.httpAddr {
+			t.Errorf("%s: HTTPAddr() = %q, want %q", tt.name, got, tt.httpAddr)
+		}
+		if got := tt.web.HTTPSAddr(); got != tt.httpsAddr {
+			t.Errorf("%s: HTTPSAddr() = %q, want %q", tt.name, got, tt.httpsAddr)
+		}
+		if got := tt.web.BaseURL(); got != tt.baseURL {
+			t.Errorf("%s: BaseURL() = %q, want %q", tt.name, got, tt.baseURL)
+		}
+	}
+}
+
+func TestValidateConfig_WebTracking(t *testing.T) {
+	valid := []WebTrackingConfig{
+		{},
+		{HTTPPort: 8080, HTTPSPort: 8443, BindAddr: "127.0.0.1"},
+		{BindAddr: "::1"},
+	}
+	for _, web := range valid {
+		if err := ValidateConfig(&Config{WebTracking: web}); err != nil {
+			t.Errorf("Expected %+v to be valid, got: %v", web, err)
+		}
+	}
+
+	invalid := []WebTrackingConfig{
+		{HTTPPort: -1},
+		{HTTPSPort: 70000},
+		{HTTPPort: 443},
+		{HTTPPort: 8080, HTTPSPort: 8080},
+		{BindAddr: "localhost"},
+	}
+	for _, web := range invalid {
+		if err := ValidateConfig(&Config{WebTracking: web}); err == nil {
+			t.Errorf("Expected an error for %+v", web)
+		}
+	}
+}
+
+func TestValidateConfig_InvalidPattern(t *testing.T) {
+	cfg := &Config{
+		Domains: []Domain{
+			{Name: `proxy[0-9+\.example\.net`, Pattern: true}, // Unterminated character class
+		},
+	}
+
+	err := ValidateConfig(cfg)
+	if err == nil {
+		t.Fatal("Expected validation error for invalid pattern")
+	}
+	if !errors.Is(err, ErrInvalidPattern) {
+		t.Errorf("Expected ErrInvalidPattern, got: %v", err)
+	}
+}
+
+func TestValidateConfig_InvalidPathPattern(t *testing.T) {
+	for _, pattern := range []string{"/", "/r/[abc"} {
+		cfg := &Config{
+			Domains: []Domain{{Name: "reddit.com", PathPatterns: []string{pattern}}},
+		}
+		if err := ValidateConfig(cfg); !errors.Is(err, ErrInvalidPathPattern) {
+			t.Errorf("Expected ErrInvalidPathPattern for %q, got: %v", pattern, err)
+		}
+	}
+
+	cfg := &Config{
+		Domains: []Domain{{Name: "reddit.com", PathPatterns: []string{"/r/*/comments", "r/somesub"}}},
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Expected valid path patterns, got: %v", err)
+	}
+}
+
+func TestValidateConfig_BlockStyle(t *testing.T) {
+	for style, wantErr := range map[string]bool{"": false, "page": false, "refused": false, "silent": true} {
+		cfg := &Config{Domains: []Domain{{Name: "example.com", BlockStyle: style}}}
+		if err := ValidateConfig(cfg); (err != nil) != wantErr {
+			t.Errorf("ValidateConfig with block_style %q: err = %v, want error %v", style, err, wantErr)
+		}
+	}
+}
+
+func TestValidateConfig_MathLock(t *testing.T) {
+	for difficulty, wantErr := range map[string]bool{"": false, "easy": false, "Hard": false, "extreme": true} {
+		cfg := &Config{ViolationTracking: ViolationTrackingConfig{MathProblems: 5, MathDifficulty: difficulty}}
+		if err := ValidateConfig(cfg); (err != nil) != wantErr {
+			t.Errorf("ValidateConfig with math_difficulty %q: err = %v, want error %v", difficulty, err, wantErr)
+		}
+	}
+	if err := ValidateConfig(&Config{ViolationTracking: ViolationTrackingConfig{MathProblems: -1}}); err == nil {
+		t.Error("Expected negative math_problems to be rejected")
+	}
+}
+
+func TestCompilePatterns(t *testing.T) {
+	cfg := &Config{
+		Domains: []Domain{
+			{Name: `proxy\d+\.example\.net`, Pattern: true},
+			{Name: `bad[`, Pattern: true},
+			{Name: "example.com"},
+		},
+	}
+
+	CompilePatterns(cfg)
+
+	if !cfg.Domains[0].MatchesHost("proxy12.example.net") {
+		t.Error("Pattern should match proxy12.example.net")
+	}
+	if cfg.Domains[0].MatchesHost("www.proxy12.example.net") {
+		t.Error("Pattern should be anchored to the full host")
+	}
+	if cfg.Domains[1].MatchesHost("bad[") {
+		t.Error("Invalid pattern should never match")
+	}
+	if cfg.Domains[2].MatchesHost("example.com") {
+		t.Error("Non-pattern domain should not match via MatchesHost")
+	}
+}
+
+func TestValidateConfig_EscalationProfile(t *testing.T) {
+	cfg := &Config{
+		ViolationTracking: ViolationTrackingConfig{EscalationProfile: "strict"},
+	}
+	if err := ValidateConfig(cfg); err == nil {
+		t.Error("Expected error for undefined escalation profile")
+	}
+
+	cfg.Profiles = map[string]Profile{"strict": {Domains: []Domain{{Name: ""}}}}
+	if err := ValidateConfig(cfg); !errors.Is(err, ErrEmptyDomainName) {
+		t.Errorf("Expected ErrEmptyDomainName for profile domain, got: %v", err)
+	}
+
+	cfg.Profiles = map[string]Profile{"strict": {Domains: []Domain{{Name: "news.com"}}}}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Expected valid config, got: %v", err)
+	}
+}
+
+func TestApplyProfile(t *testing.T) {
+	cfg := &Config{
+		Domains: []Domain{
+			{Name: "youtube.com", Unblockable: true},
+			{Name: "reddit.com"},
+		},
+		Unblocking: UnblockingConfig{TempUnblockTime: 30},
+		Profiles: map[string]Profile{
+			"strict": {
+				Domains:         []Domain{{Name: "youtube.com"}, {Name: "news.com"}},
+				TempUnblockTime: 5,
+			},
+		},
+	}
+
+	if err := ApplyProfile(cfg, "missing"); err == nil {
+		t.Error("Expected error for undefined profile")
+	}
+
+	if err := ApplyProfile(cfg, "strict"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(cfg.Domains) != 3 {
+		t.Fatalf("Expected 3 domains after applying profile, got %d", len(cfg.Domains))
+	}
+	if cfg.Domains[0].Name != "youtube.com" || cfg.Domains[0].Unblockable {
+		t.Error("Profile domain should replace the config domain of the same name")
+	}
+	if cfg.Unblocking.TempUnblockTime != 5 {
+		t.Errorf("Expected temp unblock time 5, got %d", cfg.Unblocking.TempUnblockTime)
+	}
+}
+
+func TestDomainCoversHost(t *testing.T) {
+	tests := []struct {
+		domain Domain
+		host   string
+		want   bool
+	}{
+		{Domain{Name: "example.com"}, "example.com", true},
+		{Domain{Name: "example.com"}, "www.example.com", true},
+		{Domain{Name: "example.com"}, "api.example.com", true},
+		{Domain{Name: "example.com"}, "notexample.com", false},
+		{Domain{Name: "example.com", ExactOnly: true}, "www.example.com", true},
+		{Domain{Name: "example.com", ExactOnly: true}, "api.example.com", false},
+		{Domain{Name: "example.com", ExceptSubdomains: []string{"docs"}}, "docs.example.com", false},
+		{Domain{Name: "example.com", ExceptSubdomains: []string{"docs"}}, "v2.docs.example.com", false},
+		{Domain{Name: "example.com", ExceptSubdomains: []string{"docs.example.com"}}, "docs.example.com", false},
+		{Domain{Name: "example.com", ExceptSubdomains: []string{"docs"}}, "mydocs.example.com", true},
+		{Domain{Name: "example.com", ExceptSubdomains: []string{"docs"}}, "example.com", true},
+		{Domain{Name: "example\\.com", Pattern: true}, "example.com", false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.domain.CoversHost(tt.host); got != tt.want {
+			t.Errorf("Domain %+v CoversHost(%q) = %v, want %v", tt.domain, tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestParseBlocklist(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		format   BlocklistFormat
+		expected []string
+	}{
+		{
+			name: "hosts",
+			input: `# Test blocklist
+127.0.0.1 localhost
+0.0.0.0 0.0.0.0
+0.0.0.0 ads.example.com
+0.0.0.0 Tracker.Example.net # inline comment
+::1 ip6-localhost
+0.0.0.0 ads.example.com
+not-a-hosts-line
+`,
+			format:   BlocklistHosts,
+			expected: []string{"ads.example.com", "tracker.example.net"},
+		},
+		{
+			name: "abp",
+			input: `[Adblock Plus 2.0]
+! Title: Test list
+||ads.example.com^
+||tracker.example.net^$important
+||cdn.example.org^$third-party
+||example.org/banner/*
+||*.wildcard.example^
+@@||allowed.example.com^
+example.com##.ad-banner
+/banner[0-9]+/
+-ad-box.
+`,
+			format:   BlocklistABP,
+			expected: []string{"ads.example.com", "tracker.example.net"},
+		},
+		{
+			name: "plain",
+			input: `# one domain per line
+ads.example.com
+tracker.example.net.   # trailing dot
+192.168.1.1
+bad domain line
+ads.example.com
+`,
+			format:   BlocklistPlain,
+			expected: []string{"ads.example.com", "tracker.example.net"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if format := DetectBlocklistFormat(strings.Split(tt.input, "\n")); format != tt.format {
+				t.Errorf("Expected format %s, got %s", tt.format, format)
+			}
+
+			domains, err := ParseBlocklist(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("ParseBlocklist failed: %v", err)
+			}
+			if strings.Join(domains, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected %v, got %v", tt.expected, domains)
+			}
+		})
+	}
+}
+
+func TestParseBlocklist_LineTooLong(t *testing.T) {
+	input := "0.0.0.0 ads.example.com\n0.0.0.0 " + strings.Repeat("a", 2*1024*1024) + "\n0.0.0.0 tracker.example.net\n"
+	domains, err := ParseBlocklist(strings.NewReader(input))
+	if err == nil {
+		t.Fatalf("Expected an error for an oversized line, got %v", domains)
+	}
+	if domains != nil {
+		t.Errorf("Expected no domains from a partly read list, got %v", domains)
+	}
+}
+
+func TestSubdomainsToBlock(t *testing.T) {
+	cfg := &Config{}
+	if got := cfg.SubdomainsToBlock(Domain{Name: "example.com"}); got != nil {
+		t.Errorf("Expected no subdomains without include_subdomains, got %v", got)
+	}
+
+	got := cfg.SubdomainsToBlock(Domain{Name: "example.com", IncludeSubdomains: true})
+	if len(got) != len(DefaultHostsSubdomains) || got[0] != "m.example.com" {
+		t.Errorf("Expected the default subdomains under example.com, got %v", got)
+	}
+
+	cfg.HostsSubdomains = []string{"api", "www", "docs", "api", ""}
+	got = cfg.SubdomainsToBlock(Domain{Name: "example.com", IncludeSubdomains: true, ExceptSubdomains: []string{"docs"}})
+	if !slices.Equal(got, []string{"api.example.com"}) {
+		t.Errorf("Expected only api.example.com, got %v", got)
+	}
+}
+
+func TestValidateConfig_IncludeSubdomains(t *testing.T) {
+	for _, domain := range []Domain{
+		{Name: "example.com", IncludeSubdomains: true, ExactOnly: true},
+		{Name: `.*\.example\.com`, IncludeSubdomains: true, Pattern: true},
+	} {
+		cfg := &Config{Domains: []Domain{domain}}
+		if err := ValidateConfig(cfg); err == nil || !strings.Contains(err.Error(), "include_subdomains") {
+			t.Errorf("Expected include_subdomains error for %+v, got %v", domain, err)
+		}
+	}
+}
+
+func TestAllowlistModeAllows(t *testing.T) {
+	cfg := &Config{AllowlistMode: AllowlistModeConfig{AllowedDomains: []Domain{
+		{Name: "github.com", ExceptSubdomains: []string{"gist"}},
+		{Name: `docs\.[a-z]+\.org`, Pattern: true},
+	}}}
+	CompilePatterns(cfg)
+
+	for host, want := range map[string]bool{
+		"github.com":           true,
+		"api.github.com":       true,
+		"gist.github.com":      false,
+		"docs.python.org":      true,
+		"python.org":           false,
+		"news.ycombinator.com": false,
+	} {
+		if got := cfg.AllowlistMode.Allows(host); got != want {
+			t.Errorf("Allows(%q) = %v, want %v", host, got, want)
+		}
+	}
+}
+
+func TestValidateConfig_AllowlistMode(t *testing.T) {
+	cfg := &Config{AllowlistMode: AllowlistModeConfig{Enabled: true}}
+	if err := ValidateConfig(cfg); err == nil {
+		t.Error("Expected an error for allowlist mode without allowed domains")
+	}
+
+	cfg.AllowlistMode.AllowedDomains = []Domain{{Name: "github.com"}}
+	cfg.AllowlistMode.TimeWindows = []TimeWindow{{Start: "9am", End: "12:00", Days: []string{"Mon"}}}
+	if err := ValidateConfig(cfg); !errors.Is(err, ErrInvalidTimeWindow) {
+		t.Errorf("Expected ErrInvalidTimeWindow, got %v", err)
+	}
+
+	cfg.AllowlistMode.TimeWindows[0].Start = "09:00"
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Expected valid allowlist mode, got %v", err)
+	}
+}
+
+func TestValidateConfig_HostsSinks(t *testing.T) {
+	tests := []struct {
+		ipv4, ipv6 string
+		valid      bool
+	}{
+		{"", "", true},
+		{"0.0.0.0", "::", true},
+		{"127.0.0.1", "::1", true},
+		{"::1", "", false},
+		{"", "0.0.0.0", false},
+		{"localhost", "", false},
+	}
+
+	for _, tt := range tests {
+		cfg := &Config{HostsSinkIPv4: tt.ipv4, HostsSinkIPv6: tt.ipv6}
+		if err := ValidateConfig(cfg); (err == nil) != tt.valid {
+			t.Errorf("sinks %q/%q: expected valid=%v, got error %v", tt.ipv4, tt.ipv6, tt.valid, err)
+		}
+	}
+}
+
+func TestDiffConfigs(t *testing.T) {
+	workHours := []TimeWindow{{Start: "09:00", End: "17:00", Days: []string{"Mon", "Tue"}}}
+	evenings := []TimeWindow{{Start: "18:00", End: "22:00", Days: []string{"Sat"}}}
+
+	oldCfg := &Config{
+		EnableHosts: true,
+		Domains: []Domain{
+			{Name: "reddit.com"},
+			{Name: "twitter.com", TimeWindows: workHours},
+			{Name: "news.com", TimeWindows: workHours},
+		},
+		ExtensionKeywords: ExtensionKeywordsConfig{URLKeywords: []string{"casino", "poker"}},
+	}
+	newCfg := &Config{
+		EnableHosts:    true,
+		EnableFirewall: true,
+		Domains: []Domain{
+			{Name: "reddit.com"},
+			{Name: "twitter.com", TimeWindows: evenings},
+			{Name: "youtube.com", TimeWindows: workHours},
+			{Name: "example.org"},
+		},
+		ExtensionKeywords: ExtensionKeywordsConfig{URLKeywords: []string{"casino", "betting"}},
+	}
+
+	expected := []string{
+		"Domains: 3 -> 4 (+1)",
+		"~ time windows: twitter.com: 09:00-17:00 Mon,Tue -> 18:00-22:00 Sat",
+		"+ domain: example.org",
+		"+ time-windowed domain: youtube.com (09:00-17:00 Mon,Tue)",
+		"- time-windowed domain: news.com (09:00-17:00 Mon,Tue)",
+		"+ url keyword: betting",
+		"- url keyword: poker",
+		"~ enable_firewall: false -> true",
+	}
+	if got := DiffConfigs(oldCfg, newCfg); strings.Join(got, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected diff:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+
+	if got := DiffConfigs(oldCfg, oldCfg); len(got) != 1 || got[0] != "No changes" {
+		t.Errorf("Expected no changes for identical configs, got %v", got)
+	}
+
+	// Losing every domain (e.g. a YAML indentation typo) must be called out
+	got := DiffConfigs(oldCfg, &Config{EnableHosts: true, ExtensionKeywords: oldCfg.ExtensionKeywords})
+	if len(got) < 2 || got[0] != "Domains: 3 -> 0 (-3)" || got[1] != "WARNING: the new config blocks no domains" {
+		t.Errorf("Expected domain loss warning, got %v", got)
+	}
+}
+
 func TestIsValidTime(t *testing.T) {
 	tests := []struct {
 		time  string
@@ -160,6 +921,200 @@ func TestLoadConfig_FileNotFound(t *testing.T) {
 	}
 }
 
+func TestWindowsOverlap(t *testing.T) {
+	weekdays := []string{"Mon", "Tue", "Wed", "Thu", "Fri"}
+	tests := []struct {
+		name    string
+		a, b    TimeWindow
+		overlap bool
+	}{
+		{"same day overlap", TimeWindow{Start: "09:00", End: "17:00", Days: weekdays}, TimeWindow{Start: "12:00", End: "18:00", Days: []string{"Wed"}}, true},
+		{"contained", TimeWindow{Start: "09:00", End: "17:00", Days: []string{"Mon"}}, TimeWindow{Start: "10:00", End: "11:00", Days: []string{"Mon"}}, true},
+		{"different days", TimeWindow{Start: "09:00", End: "17:00", Days: []string{"Mon"}}, TimeWindow{Start: "09:00", End: "17:00", Days: []string{"Tue"}}, false},
+		{"touching", TimeWindow{Start: "09:00", End: "12:00", Days: []string{"Mon"}}, TimeWindow{Start: "12:00", End: "17:00", Days: []string{"Mon"}}, false},
+		{"disjoint", TimeWindow{Start: "09:00", End: "10:00", Days: []string{"Mon"}}, TimeWindow{Start: "11:00", End: "12:00", Days: []string{"Mon"}}, false},
+		{"wraparound into next day", TimeWindow{Start: "22:00", End: "02:00", Days: []string{"Mon"}}, TimeWindow{Start: "01:00", End: "03:00", Days: []string{"Tue"}}, true},
+		{"wraparound not into same day morning", TimeWindow{Start: "22:00", End: "02:00", Days: []string{"Mon"}}, TimeWindow{Start: "01:00", End: "03:00", Days: []string{"Mon"}}, false},
+		{"two wraparounds", TimeWindow{Start: "23:00", End: "01:00", Days: []string{"Fri"}}, TimeWindow{Start: "22:00", End: "00:30", Days: []string{"Fri"}}, true},
+		{"saturday night into sunday", TimeWindow{Start: "23:00", End: "02:00", Days: []string{"Sat"}}, TimeWindow{Start: "00:00", End: "01:00", Days: []string{"Sun"}}, true},
+		{"saturday night ends before sunday window", TimeWindow{Start: "23:00", End: "02:00", Days: []string{"Sat"}}, TimeWindow{Start: "02:00", End: "04:00", Days: []string{"Sun"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := windowsOverlap(tt.a, tt.b); got != tt.overlap {
+				t.Errorf("windowsOverlap(a, b) = %v, want %v", got, tt.overlap)
+			}
+			if got := windowsOverlap(tt.b, tt.a); got != tt.overlap {
+				t.Errorf("windowsOverlap(b, a) = %v, want %v", got, tt.overlap)
+			}
+		})
+	}
+}
+
+func TestTimeWindowOverlaps(t *testing.T) {
+	cfg := &Config{
+		Domains: []Domain{
+			{Name: "youtube.com", TimeWindows: []TimeWindow{
+				{Start: "09:00", End: "17:00", Days: []string{"Mon", "Tue"}},
+				{Start: "16:00", End: "18:00", Days: []string{"Tue"}},
+			}},
+			{Name: "reddit.com", TimeWindows: []TimeWindow{
+				{Start: "09:00", End: "12:00", Days: []string{"Mon"}},
+				{Start: "12:00", End: "17:00", Days: []string{"Mon"}},
+			}},
+		},
+		Sudoers: SudoersConfig{TimeAllowed: []TimeWindow{
+			{Start: "20:00", End: "01:00", Days: []string{"Sun"}},
+			{Start: "00:30", End: "02:00", Days: []string{"Mon"}},
+		}},
+	}
+
+	want := []string{
+		"domain youtube.com: time windows 09:00-17:00 (Mon,Tue) and 16:00-18:00 (Tue) overlap",
+		"sudoers time_allowed: time windows 20:00-01:00 (Sun) and 00:30-02:00 (Mon) overlap",
+	}
+	if got := TimeWindowOverlaps(cfg); !slices.Equal(got, want) {
+		t.Errorf("TimeWindowOverlaps() = %q, want %q", got, want)
+	}
+
+	// Overlaps are warnings, not validation errors
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Expected overlapping windows to pass validation, got: %v", err)
+	}
+}
+
+func TestWarnings_WebTrackingOffDefaultPort(t *testing.T) {
+	tests := []struct {
+		name        string
+		webTracking WebTrackingConfig
+		warn        bool
+	}{
+		{"defaults", WebTrackingConfig{Enabled: true}, false},
+		{"loopback", WebTrackingConfig{Enabled: true, BindAddr: "127.0.0.1"}, false},
+		{"all interfaces", WebTrackingConfig{Enabled: true, BindAddr: "0.0.0.0"}, false},
+		{"other port", WebTrackingConfig{Enabled: true, HTTPPort: 8080}, true},
+		{"other address", WebTrackingConfig{Enabled: true, BindAddr: "192.168.1.5"}, true},
+		{"disabled", WebTrackingConfig{HTTPPort: 8080}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Warnings(&Config{WebTracking: tt.webTracking})
+			if (len(got) > 0) != tt.warn {
+				t.Errorf("Warnings() = %q, want warning: %v", got, tt.warn)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_ConfigPath(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	SetConfigPath(path)
+	defer SetConfigPath(GlockerConfigFile)
+
+	if _, err := LoadConfig(); err == nil {
+		t.Fatal("Expected an error loading a missing config file")
+	} else if !strings.Contains(err.Error(), path) {
+		t.Errorf("Expected the error to name %s, got: %v", path, err)
+	}
+
+	valid := "enable_hosts: true\ndomains:\n  - name: \"example.com\"\n"
+	if err := os.WriteFile(path, []byte(valid), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Expected config to load from %s, got: %v", path, err)
+	}
+	if len(cfg.Domains) != 1 || cfg.Domains[0].Name != "example.com" {
+		t.Errorf("Expected the domain from %s, got %+v", path, cfg.Domains)
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Errorf("Expected valid config, got: %v", err)
+	}
+
+	// A config loaded from another path is still validated
+	invalid := "domains:\n  - name: \"example.com\"\n    time_windows:\n      - start: \"9am\"\n        end: \"17:00\"\n        days: [\"Mon\"]\n"
+	if err := os.WriteFile(path, []byte(invalid), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("Expected config to load from %s, got: %v", path, err)
+	}
+	if err := ValidateConfig(cfg); err == nil {
+		t.Error("Expected validation to reject the invalid time window")
+	}
+}
+
+func TestLoadConfig_DayGroups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	SetConfigPath(path)
+	defer SetConfigPath(GlockerConfigFile)
+
+	data := `domains:
+  - name: "youtube.com"
+    time_windows:
+      - start: "09:00"
+        end: "17:00"
+        days: ["Weekdays"]
+      - start: "10:00"
+        end: "12:00"
+        days: ["Weekends", "Sat"]
+sudoers:
+  time_allowed:
+    - start: "18:00"
+      end: "20:00"
+      days: ["Daily"]
+forbidden_programs:
+  programs:
+    - name: "steam"
+      time_windows:
+        - start: "09:00"
+          end: "17:00"
+          days: ["Mon", "Wed"]
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Expected config to load, got: %v", err)
+	}
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("Expected expanded config to be valid, got: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  []string
+		want []string
+	}{
+		{"Weekdays", cfg.Domains[0].TimeWindows[0].Days, []string{"Mon", "Tue", "Wed", "Thu", "Fri"}},
+		{"Weekends with a duplicate day", cfg.Domains[0].TimeWindows[1].Days, []string{"Sat", "Sun"}},
+		{"Daily", cfg.Sudoers.TimeAllowed[0].Days, []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}},
+		{"literal days", cfg.ForbiddenPrograms.Programs[0].TimeWindows[0].Days, []string{"Mon", "Wed"}},
+	}
+	for _, tt := range tests {
+		if !slices.Equal(tt.got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestValidateConfig_UnknownDay(t *testing.T) {
+	for _, day := range []string{"Weekday", "Monday", "mon"} {
+		cfg := &Config{Domains: []Domain{{
+			Name:        "youtube.com",
+			TimeWindows: []TimeWindow{{Start: "09:00", End: "17:00", Days: []string{day}}},
+		}}}
+		ExpandDayGroups(cfg)
+		err := ValidateConfig(cfg)
+		if !errors.Is(err, ErrInvalidTimeWindowDay) {
+			t.Errorf("Expected ErrInvalidTimeWindowDay for %q, got: %v", day, err)
+		}
+	}
+}
+
 func TestSetupLogging(t *testing.T) {
 	tests := []struct {
 		logLevel string
@@ -183,3 +1138,397 @@ func TestSetupLogging(t *testing.T) {
 		})
 	}
 }
+
+func TestUnblockPeriodStart(t *testing.T) {
+	now := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		resetTime string
+		want      time.Time
+	}{
+		{"", time.Date(2026, 1, 6, 0, 0, 0, 0, time.UTC)},
+		{"04:00", time.Date(2026, 1, 6, 4, 0, 0, 0, time.UTC)},
+		{"10:00", time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)},
+		{"22:30", time.Date(2026, 1, 5, 22, 30, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		u := UnblockingConfig{ResetTime: tt.resetTime}
+		if got := u.UnblockPeriodStart(now); !got.Equal(tt.want) {
+			t.Errorf("reset_time %q: UnblockPeriodStart() = %v, want %v", tt.resetTime, got, tt.want)
+		}
+	}
+}
+
+func TestWeeklyReportSchedule(t *testing.T) {
+	day, reportTime, err := AccountabilityConfig{}.WeeklyReportSchedule()
+	if err != nil || day != time.Sunday || reportTime != "20:00" {
+		t.Errorf("Default schedule = %v %s, %v; want Sunday 20:00", day, reportTime, err)
+	}
+
+	day, reportTime, err = AccountabilityConfig{WeeklyReportDay: "friday", WeeklyReportTime: "17:30"}.WeeklyReportSchedule()
+	if err != nil || day != time.Friday || reportTime != "17:30" {
+		t.Errorf("Schedule = %v %s, %v; want Friday 17:30", day, reportTime, err)
+	}
+
+	for _, bad := range []AccountabilityConfig{{WeeklyReportDay: "Funday"}, {WeeklyReportTime: "25:00"}} {
+		bad.WeeklyReportEnabled = true
+		if err := ValidateConfig(&Config{Accountability: bad}); err == nil {
+			t.Errorf("Expected %+v to be rejected", bad)
+		}
+	}
+}
+
+func TestEmailMaxRetries(t *testing.T) {
+	if got := (AccountabilityConfig{}).EmailMaxRetries(); got != 3 {
+		t.Errorf("Default retries = %d, want 3", got)
+	}
+	zero, negative := 0, -1
+	if got := (AccountabilityConfig{MaxRetries: &zero}).EmailMaxRetries(); got != 0 {
+		t.Errorf("max_retries: 0 should disable retries, got %d", got)
+	}
+	if err := ValidateConfig(&Config{Accountability: AccountabilityConfig{Enabled: true, MaxRetries: &negative}}); err == nil {
+		t.Error("Expected a negative max_retries to be rejected")
+	}
+}
+
+func TestAccountabilityRecipients(t *testing.T) {
+	acct := AccountabilityConfig{
+		PartnerEmail:  "spouse@example.com, sponsor@example.com",
+		PartnerEmails: []string{"coach@example.com", " SPOUSE@example.com ", ""},
+	}
+	want := []string{"spouse@example.com", "sponsor@example.com", "coach@example.com"}
+	if got := acct.Recipients(); !slices.Equal(got, want) {
+		t.Errorf("Recipients() = %q, want %q", got, want)
+	}
+
+	acct.Enabled = true
+	if err := ValidateConfig(&Config{Accountability: acct}); err != nil {
+		t.Errorf("Expected valid recipients, got %v", err)
+	}
+	acct.PartnerEmails = append(acct.PartnerEmails, "not an address")
+	if err := ValidateConfig(&Config{Accountability: acct}); err == nil || !strings.Contains(err.Error(), "not an address") {
+		t.Errorf("Expected the invalid address to be rejected, got %v", err)
+	}
+}
+
+func TestValidateConfig_FirewallRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    FirewallRule
+		wantErr bool
+	}{
+		{"tcp port", FirewallRule{Port: 22, Protocol: "tcp"}, false},
+		{"default protocol with window", FirewallRule{Port: 6881, TimeWindows: []TimeWindow{{Start: "09:00", End: "17:00", Days: []string{"Mon"}}}}, false},
+		{"port zero", FirewallRule{Port: 0}, true},
+		{"port too large", FirewallRule{Port: 70000}, true},
+		{"unknown protocol", FirewallRule{Port: 22, Protocol: "icmp"}, true},
+		{"bad window", FirewallRule{Port: 22, TimeWindows: []TimeWindow{{Start: "9am", End: "17:00", Days: []string{"Mon"}}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateConfig(&Config{FirewallRules: []FirewallRule{tt.rule}})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	if got := (FirewallRule{Protocol: "UDP"}).Protocols(); !slices.Equal(got, []string{"udp"}) {
+		t.Errorf("Protocols() = %v, want [udp]", got)
+	}
+	if got := (FirewallRule{}).Protocols(); !slices.Equal(got, []string{"tcp", "udp"}) {
+		t.Errorf("Protocols() = %v, want [tcp udp]", got)
+	}
+}
+
+func TestTimeWindow_Active(t *testing.T) {
+	night := TimeWindow{Start: "22:00", End: "02:00", Days: []string{"Fri"}}
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{"Friday night", time.Date(2024, 6, 14, 23, 0, 0, 0, time.UTC), true},
+		{"Saturday early morning", time.Date(2024, 6, 15, 1, 0, 0, 0, time.UTC), true},
+		{"through the end minute", time.Date(2024, 6, 15, 2, 0, 59, 0, time.UTC), true},
+		{"after the end", time.Date(2024, 6, 15, 2, 1, 0, 0, time.UTC), false},
+		{"Friday early morning", time.Date(2024, 6, 14, 1, 0, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := night.Active(tt.now); got != tt.want {
+				t.Errorf("Active(%s) = %v, want %v", tt.now.Format("Mon 15:04"), got, tt.want)
+			}
+		})
+	}
+
+	if AnyWindowActive(nil, time.Now()) {
+		t.Error("Expected no windows to never be active")
+	}
+}
+
+func TestTamperConfig_InMaintenanceWindow(t *testing.T) {
+	cfg := &Config{TamperDetection: TamperConfig{
+		DebounceSeconds: 10,
+		AllowedMaintenanceWindows: []TimeWindow{
+			{Start: "03:00", End: "04:00", Days: []string{"Daily"}},
+			{Start: "23:00", End: "01:00", Days: []string{"Sat"}},
+		},
+	}}
+	ExpandDayGroups(cfg)
+	if err := ValidateConfig(cfg); err != nil {
+		t.Fatalf("ValidateConfig() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want bool
+	}{
+		{"daily window", time.Date(2024, 6, 12, 3, 30, 0, 0, time.UTC), true},
+		{"outside windows", time.Date(2024, 6, 12, 12, 0, 0, 0, time.UTC), false},
+		{"Saturday night", time.Date(2024, 6, 15, 23, 30, 0, 0, time.UTC), true},
+		{"Saturday window after midnight", time.Date(2024, 6, 16, 0, 30, 0, 0, time.UTC), true},
+		{"Friday night", time.Date(2024, 6, 14, 23, 30, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cfg.TamperDetection.InMaintenanceWindow(tt.now); got != tt.want {
+				t.Errorf("InMaintenanceWindow(%s) = %v, want %v", tt.now.Format("Mon 15:04"), got, tt.want)
+			}
+		})
+	}
+
+	if got := cfg.TamperDetection.Debounce(); got != 10*time.Second {
+		t.Errorf("Debounce() = %v, want 10s", got)
+	}
+	if err := ValidateConfig(&Config{TamperDetection: TamperConfig{DebounceSeconds: -1}}); err == nil {
+		t.Error("Expected an error for a negative debounce_seconds")
+	}
+}
+
+func TestMergeOverlay(t *testing.T) {
+	cfg := &Config{
+		Domains: []Domain{
+			{Name: "reddit.com", Unblockable: true, TimeWindows: []TimeWindow{{Start: "09:00", End: "17:00"}}},
+		},
+		ExtensionKeywords: ExtensionKeywordsConfig{
+			URLKeywords:     []string{"casino"},
+			ContentKeywords: []string{"casino", "slots"},
+		},
+	}
+	overlay := &Overlay{
+		Keywords: []string{"casino", "slots", "poker"},
+		Domains:  []string{"reddit.com", "x.com"},
+	}
+
+	MergeOverlay(cfg, overlay)
+
+	if got := strings.Join(cfg.ExtensionKeywords.URLKeywords, ","); got != "casino,slots,poker" {
+		t.Errorf("URL keywords = %s, want casino,slots,poker", got)
+	}
+	if got := strings.Join(cfg.ExtensionKeywords.ContentKeywords, ","); got != "casino,slots,poker" {
+		t.Errorf("Content keywords = %s, want casino,slots,poker", got)
+	}
+
+	// The config entry for a domain wins over the overlay
+	if len(cfg.Domains) != 2 {
+		t.Fatalf("Expected 2 domains, got %+v", cfg.Domains)
+	}
+	if !cfg.Domains[0].Unblockable || len(cfg.Domains[0].TimeWindows) != 1 {
+		t.Errorf("Expected reddit.com to keep its config entry, got %+v", cfg.Domains[0])
+	}
+	if cfg.Domains[1].Name != "x.com" || cfg.Domains[1].Unblockable || len(cfg.Domains[1].TimeWindows) != 0 {
+		t.Errorf("Expected x.com to be added as a permanent block, got %+v", cfg.Domains[1])
+	}
+}
+
+func TestAppendToOverlay_PersistsAcrossReload(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte("domains:\n  - name: reddit.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	SetConfigPath(configPath)
+	defer SetConfigPath(GlockerConfigFile)
+	overlayFile := filepath.Join(dir, "state", "keywords.yaml")
+	SetOverlayPath(overlayFile)
+	defer SetOverlayPath(RuntimeOverlayFile)
+
+	// No overlay file yet
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if len(cfg.Domains) != 1 || len(cfg.ExtensionKeywords.URLKeywords) != 0 {
+		t.Fatalf("Expected only the config file's contents, got %+v", cfg)
+	}
+
+	if err := AppendToOverlay([]string{"poker"}, nil); err != nil {
+		t.Fatalf("AppendToOverlay failed: %v", err)
+	}
+	if err := AppendToOverlay([]string{"poker", "casino"}, []string{"x.com"}); err != nil {
+		t.Fatalf("AppendToOverlay failed: %v", err)
+	}
+
+	overlay, err := LoadOverlay(overlayFile)
+	if err != nil {
+		t.Fatalf("LoadOverlay failed: %v", err)
+	}
+	if strings.Join(overlay.Keywords, ",") != "poker,casino" || strings.Join(overlay.Domains, ",") != "x.com" {
+		t.Errorf("Expected overlay without duplicates, got %+v", overlay)
+	}
+	if _, err := os.Stat(overlayFile + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("Expected temp file to be renamed away, got: %v", err)
+	}
+
+	// A reload (or restart) picks the additions up
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if got := strings.Join(cfg.ExtensionKeywords.URLKeywords, ","); got != "poker,casino" {
+		t.Errorf("URL keywords after reload = %s, want poker,casino", got)
+	}
+	if got := strings.Join(cfg.ExtensionKeywords.ContentKeywords, ","); got != "poker,casino" {
+		t.Errorf("Content keywords after reload = %s, want poker,casino", got)
+	}
+	if len(cfg.Domains) != 2 || cfg.Domains[1].Name != "x.com" {
+		t.Errorf("Expected x.com to be blocked after reload, got %+v", cfg.Domains)
+	}
+
+	// A broken overlay doesn't stop the config from loading
+	if err := os.WriteFile(overlayFile, []byte("keywords: [unclosed\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed with a broken overlay: %v", err)
+	}
+	if len(cfg.Domains) != 1 {
+		t.Errorf("Expected only the config file's domains, got %+v", cfg.Domains)
+	}
+}
+
+func TestRestoreOverlay(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte("domains:\n  - name: reddit.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	SetConfigPath(configPath)
+	defer SetConfigPath(GlockerConfigFile)
+	overlayFile := filepath.Join(dir, "keywords.yaml")
+	SetOverlayPath(overlayFile)
+	defer SetOverlayPath(RuntimeOverlayFile)
+
+	// Nothing to compare with before the overlay was loaded
+	if restored, err := RestoreOverlay(); restored || err != nil {
+		t.Fatalf("RestoreOverlay() before a load = %v, %v", restored, err)
+	}
+
+	if _, err := LoadConfig(); err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if err := AppendToOverlay(nil, []string{"x.com", "instagram.com"}); err != nil {
+		t.Fatalf("AppendToOverlay failed: %v", err)
+	}
+	if restored, err := RestoreOverlay(); restored || err != nil {
+		t.Fatalf("RestoreOverlay() after glocker's own write = %v, %v", restored, err)
+	}
+
+	// Dropping a block by hand is undone
+	if err := os.WriteFile(overlayFile, []byte("domains:\n  - instagram.com\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if restored, err := RestoreOverlay(); !restored || err != nil {
+		t.Fatalf("RestoreOverlay() after an edit = %v, %v", restored, err)
+	}
+	overlay, err := LoadOverlay(overlayFile)
+	if err != nil {
+		t.Fatalf("LoadOverlay failed: %v", err)
+	}
+	if got := strings.Join(overlay.Domains, ","); got != "x.com,instagram.com" {
+		t.Errorf("Restored overlay domains = %s, want x.com,instagram.com", got)
+	}
+
+	// So is deleting the file
+	if err := os.Remove(overlayFile); err != nil {
+		t.Fatal(err)
+	}
+	if restored, err := RestoreOverlay(); !restored || err != nil {
+		t.Fatalf("RestoreOverlay() after deletion = %v, %v", restored, err)
+	}
+	if _, err := os.Stat(overlayFile); err != nil {
+		t.Errorf("Expected the overlay file to be restored: %v", err)
+	}
+}
+
+func TestSetOverlayCategory_PersistsAcrossReload(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config.yaml")
+	configYAML := "keyword_categories:\n  - {name: gambling, url_keywords: [casino]}\n  - {name: news, enabled: false, url_keywords: [election]}\n"
+	if err := os.WriteFile(configPath, []byte(configYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	SetConfigPath(configPath)
+	defer SetConfigPath(GlockerConfigFile)
+	overlayFile := filepath.Join(dir, "keywords.yaml")
+	SetOverlayPath(overlayFile)
+	defer SetOverlayPath(RuntimeOverlayFile)
+
+	if err := AppendToOverlay([]string{"poker"}, nil); err != nil {
+		t.Fatalf("AppendToOverlay failed: %v", err)
+	}
+	for name, enabled := range map[string]bool{"gambling": false, "news": true, "removed": false} {
+		if err := SetOverlayCategory(name, enabled); err != nil {
+			t.Fatalf("SetOverlayCategory(%s) failed: %v", name, err)
+		}
+	}
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig failed: %v", err)
+	}
+	if len(cfg.KeywordCategories) != 2 {
+		t.Fatalf("Expected the config's 2 categories, got %+v", cfg.KeywordCategories)
+	}
+	for _, category := range cfg.KeywordCategories {
+		want := category.Name == "news"
+		if category.Enabled == nil || *category.Enabled != want {
+			t.Errorf("Category %s enabled = %v, want %v", category.Name, category.Enabled, want)
+		}
+	}
+
+	// Earlier additions are kept
+	if got := strings.Join(cfg.ExtensionKeywords.URLKeywords, ","); got != "poker" {
+		t.Errorf("URL keywords after reload = %s, want poker", got)
+	}
+}
+
+func TestLogRotationSettings(t *testing.T) {
+	cfg := &Config{}
+	if got := cfg.LogMaxBytes(); got != DefaultLogMaxSizeMB*1024*1024 {
+		t.Errorf("LogMaxBytes() = %d, want the default", got)
+	}
+	if got := cfg.LogRotatedFiles(); got != DefaultLogMaxFiles {
+		t.Errorf("LogRotatedFiles() = %d, want %d", got, DefaultLogMaxFiles)
+	}
+
+	cfg = &Config{LogMaxSizeMB: 2, LogMaxFiles: 3}
+	if got := cfg.LogMaxBytes(); got != 2*1024*1024 {
+		t.Errorf("LogMaxBytes() = %d, want 2 MB", got)
+	}
+	if got := cfg.LogRotatedFiles(); got != 3 {
+		t.Errorf("LogRotatedFiles() = %d, want 3", got)
+	}
+
+	if err := ValidateConfig(&Config{LogMaxSizeMB: -1}); err == nil {
+		t.Error("Expected an error for a negative log_max_size_mb")
+	}
+	if err := ValidateConfig(&Config{LogMaxFiles: -1}); err == nil {
+		t.Error("Expected an error for a negative log_max_files")
+	}
+}
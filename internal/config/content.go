@@ -0,0 +1,16 @@
+package config
+
+import "time"
+
+// defaultReportDedupSeconds is the dedup window when
+// content_monitoring.dedup_seconds is not set.
+const defaultReportDedupSeconds = 30
+
+// DedupWindow returns how long identical content reports are collapsed into
+// one, defaulting to 30 seconds. Zero disables deduplication.
+func (c ContentMonitoringConfig) DedupWindow() time.Duration {
+	if c.DedupSeconds == nil {
+		return defaultReportDedupSeconds * time.Second
+	}
+	return time.Duration(*c.DedupSeconds) * time.Second
+}
@@ -0,0 +1,79 @@
+package config
+
+import "fmt"
+
+// dayGroups are the shorthands time window days can use for several days.
+var dayGroups = map[string][]string{
+	"Weekdays": {"Mon", "Tue", "Wed", "Thu", "Fri"},
+	"Weekends": {"Sat", "Sun"},
+	"Daily":    {"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"},
+}
+
+// ExpandDayGroups replaces the Weekdays, Weekends and Daily shorthands in every
+// time window of cfg with the day names they stand for. Unknown names are left
+// for validation to report.
+func ExpandDayGroups(cfg *Config) {
+	expandWindows(cfg.PanicSchedule)
+	expandWindows(cfg.Sudoers.TimeAllowed)
+	expandWindows(cfg.TamperDetection.AllowedMaintenanceWindows)
+	expandDomainWindows(cfg.Domains)
+	for _, profile := range cfg.Profiles {
+		expandDomainWindows(profile.Domains)
+	}
+	for i := range cfg.ForbiddenPrograms.Programs {
+		expandWindows(cfg.ForbiddenPrograms.Programs[i].TimeWindows)
+	}
+	for i := range cfg.KeywordCategories {
+		expandWindows(cfg.KeywordCategories[i].TimeWindows)
+	}
+	for i := range cfg.FirewallRules {
+		expandWindows(cfg.FirewallRules[i].TimeWindows)
+	}
+	expandWindows(cfg.AllowlistMode.TimeWindows)
+}
+
+func expandDomainWindows(domains []Domain) {
+	for i := range domains {
+		expandWindows(domains[i].TimeWindows)
+		expandWindows(domains[i].AbsoluteWindows)
+	}
+}
+
+func expandWindows(windows []TimeWindow) {
+	for i := range windows {
+		windows[i].Days = expandDays(windows[i].Days)
+	}
+}
+
+// expandDays returns days with the shorthands expanded, without duplicates.
+func expandDays(days []string) []string {
+	var expanded []string
+	seen := make(map[string]bool, len(days))
+	for _, day := range days {
+		group, ok := dayGroups[day]
+		if !ok {
+			group = []string{day}
+		}
+		for _, d := range group {
+			if !seen[d] {
+				seen[d] = true
+				expanded = append(expanded, d)
+			}
+		}
+	}
+	return expanded
+}
+
+// validateDays checks that a time window has days and that each is a day name
+// (Mon-Sun). Shorthands must have been expanded by ExpandDayGroups.
+func validateDays(days []string) error {
+	if len(days) == 0 {
+		return ErrEmptyTimeWindowDay
+	}
+	for _, day := range days {
+		if _, ok := weekdayIndex[day]; !ok {
+			return fmt.Errorf("%q (use Mon, Tue, Wed, Thu, Fri, Sat, Sun, Weekdays, Weekends or Daily): %w", day, ErrInvalidTimeWindowDay)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// maxDiffDomains is how many added/removed domain names DiffConfigs lists before summarizing.
+const maxDiffDomains = 20
+
+// DiffConfigs describes what would change if newCfg replaced oldCfg: the number of
+// blocked domains, domains added or removed (time-windowed ones with their windows),
+// extension keyword changes and toggled feature flags. Returns one line per change,
+// or a single "No changes" line.
+func DiffConfigs(oldCfg, newCfg *Config) []string {
+	var lines []string
+
+	lines = append(lines, diffDomains(oldCfg.Domains, newCfg.Domains)...)
+
+	keywordLists := []struct {
+		name     string
+		old, new []string
+	}{
+		{"url keyword", oldCfg.ExtensionKeywords.URLKeywords, newCfg.ExtensionKeywords.URLKeywords},
+		{"content keyword", oldCfg.ExtensionKeywords.ContentKeywords, newCfg.ExtensionKeywords.ContentKeywords},
+		{"whitelist entry", oldCfg.ExtensionKeywords.Whitelist, newCfg.ExtensionKeywords.Whitelist},
+	}
+	for _, list := range keywordLists {
+		added, removed := diffStrings(list.old, list.new)
+		for _, keyword := range added {
+			lines = append(lines, fmt.Sprintf("+ %s: %s", list.name, keyword))
+		}
+		for _, keyword := range removed {
+			lines = append(lines, fmt.Sprintf("- %s: %s", list.name, keyword))
+		}
+	}
+
+	for _, flag := range featureFlags(oldCfg, newCfg) {
+		if flag.old != flag.new {
+			lines = append(lines, fmt.Sprintf("~ %s: %v -> %v", flag.name, flag.old, flag.new))
+		}
+	}
+
+	if len(lines) == 1 && len(oldCfg.Domains) == len(newCfg.Domains) {
+		return []string{"No changes"}
+	}
+	return lines
+}
+
+// diffDomains summarizes domain list changes. The first line is always the domain count.
+func diffDomains(oldDomains, newDomains []Domain) []string {
+	lines := []string{fmt.Sprintf("Domains: %d -> %d (%+d)", len(oldDomains), len(newDomains), len(newDomains)-len(oldDomains))}
+	if len(oldDomains) > 0 && len(newDomains) == 0 {
+		lines = append(lines, "WARNING: the new config blocks no domains")
+	}
+
+	oldByName := make(map[string]Domain, len(oldDomains))
+	for _, d := range oldDomains {
+		oldByName[d.Name] = d
+	}
+	newByName := make(map[string]Domain, len(newDomains))
+	for _, d := range newDomains {
+		newByName[d.Name] = d
+	}
+
+	var added, removed []string
+	for name, d := range newByName {
+		old, ok := oldByName[name]
+		switch {
+		case !ok:
+			added = append(added, name)
+		case len(d.TimeWindows) > 0 || len(old.TimeWindows) > 0:
+			before, after := describeTimeWindows(old.TimeWindows), describeTimeWindows(d.TimeWindows)
+			if before != after {
+				lines = append(lines, fmt.Sprintf("~ time windows: %s: %s -> %s", name, before, after))
+			}
+		}
+	}
+	for name := range oldByName {
+		if _, ok := newByName[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(lines[1:])
+
+	lines = append(lines, describeDomainChanges("+", added, newByName)...)
+	lines = append(lines, describeDomainChanges("-", removed, oldByName)...)
+	return lines
+}
+
+// describeDomainChanges lists added or removed domains. Time-windowed domains are always
+// listed; other domains are listed up to maxDiffDomains and then counted.
+func describeDomainChanges(sign string, names []string, byName map[string]Domain) []string {
+	var lines []string
+	listed := 0
+	for _, name := range names {
+		if windows := byName[name].TimeWindows; len(windows) > 0 {
+			lines = append(lines, fmt.Sprintf("%s time-windowed domain: %s (%s)", sign, name, describeTimeWindows(windows)))
+			continue
+		}
+		if listed < maxDiffDomains {
+			lines = append(lines, fmt.Sprintf("%s domain: %s", sign, name))
+		}
+		listed++
+	}
+	if listed > maxDiffDomains {
+		lines = append(lines, fmt.Sprintf("%s ... and %d more domains", sign, listed-maxDiffDomains))
+	}
+	return lines
+}
+
+// describeTimeWindows formats time windows as "09:00-17:00 Mon,Tue; ...", or "always".
+func describeTimeWindows(windows []TimeWindow) string {
+	if len(windows) == 0 {
+		return "always"
+	}
+	parts := make([]string, len(windows))
+	for i, window := range windows {
+		parts[i] = fmt.Sprintf("%s-%s %s", window.Start, window.End, strings.Join(window.Days, ","))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// diffStrings returns the entries only in newList (added) and only in oldList (removed).
+func diffStrings(oldList, newList []string) (added, removed []string) {
+	for _, s := range newList {
+		if !slices.Contains(oldList, s) {
+			added = append(added, s)
+		}
+	}
+	for _, s := range oldList {
+		if !slices.Contains(newList, s) {
+			removed = append(removed, s)
+		}
+	}
+	return added, removed
+}
+
+// featureFlag is an on/off setting compared by DiffConfigs.
+type featureFlag struct {
+	name     string
+	old, new bool
+}
+
+// featureFlags returns the feature toggles of both configs, by yaml name.
+func featureFlags(oldCfg, newCfg *Config) []featureFlag {
+	return []featureFlag{
+		{"enable_hosts", oldCfg.EnableHosts, newCfg.EnableHosts},
+		{"enable_firewall", oldCfg.EnableFirewall, newCfg.EnableFirewall},
+		{"enable_forbidden_programs", oldCfg.EnableForbiddenPrograms, newCfg.EnableForbiddenPrograms},
+		{"enable_self_healing", oldCfg.SelfHeal, newCfg.SelfHeal},
+		{"sudoers.enabled", oldCfg.Sudoers.Enabled, newCfg.Sudoers.Enabled},
+		{"tamper_detection.enabled", oldCfg.TamperDetection.Enabled, newCfg.TamperDetection.Enabled},
+		{"accountability.enabled", oldCfg.Accountability.Enabled, newCfg.Accountability.Enabled},
+		{"web_tracking.enabled", oldCfg.WebTracking.Enabled, newCfg.WebTracking.Enabled},
+		{"content_monitoring.enabled", oldCfg.ContentMonitoring.Enabled, newCfg.ContentMonitoring.Enabled},
+		{"forbidden_programs.enabled", oldCfg.ForbiddenPrograms.Enabled, newCfg.ForbiddenPrograms.Enabled},
+		{"violation_tracking.enabled", oldCfg.ViolationTracking.Enabled, newCfg.ViolationTracking.Enabled},
+		{"dev", oldCfg.Dev, newCfg.Dev},
+	}
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"errors"
+	"path"
+	"strings"
+)
+
+// CoversHost reports whether a (non-pattern) domain rule applies to host.
+// The domain itself and its www. form always match. Other subdomains match unless
+// the rule is ExactOnly or the subdomain falls under one of ExceptSubdomains.
+func (d *Domain) CoversHost(host string) bool {
+	if d.Pattern {
+		return false
+	}
+
+	host = strings.TrimPrefix(host, "www.")
+	if host == d.Name {
+		return true
+	}
+	if d.ExactOnly || !strings.HasSuffix(host, "."+d.Name) {
+		return false
+	}
+
+	for _, except := range d.ExceptSubdomains {
+		// Entries may be full names ("mail.example.com") or relative labels ("mail")
+		if except != d.Name && !strings.HasSuffix(except, "."+d.Name) {
+			except = except + "." + d.Name
+		}
+		if host == except || strings.HasSuffix(host, "."+except) {
+			return false
+		}
+	}
+	return true
+}
+
+// DomainCategories maps each domain with a category to that category, for
+// grouping reports by category. Pattern domains are left out, since reports
+// only know the host.
+func (c *Config) DomainCategories() map[string]string {
+	categories := make(map[string]string)
+	for _, domain := range c.Domains {
+		if domain.Category != "" && !domain.Pattern {
+			categories[domain.Name] = domain.Category
+		}
+	}
+	return categories
+}
+
+// MatchesPath reports whether a URL path falls under one of the domain's PathPatterns.
+// Patterns match whole path segments from the start of the path, case-insensitively, so
+// "/r/somesub" covers "/r/somesub" and "/r/somesub/comments/..." but not "/r/somesubreddit".
+// A segment may use path.Match wildcards, e.g. "/r/*/comments".
+func (d *Domain) MatchesPath(urlPath string) bool {
+	pathSegments := splitPath(urlPath)
+	for _, pattern := range d.PathPatterns {
+		patternSegments := splitPath(pattern)
+		if len(patternSegments) == 0 || len(patternSegments) > len(pathSegments) {
+			continue
+		}
+		matched := true
+		for i, segment := range patternSegments {
+			if ok, err := path.Match(segment, pathSegments[i]); err != nil || !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
+
+// validatePathPattern checks that a path pattern names at least one segment and that
+// its wildcards are well formed.
+func validatePathPattern(pattern string) error {
+	segments := splitPath(pattern)
+	if len(segments) == 0 {
+		return errors.New("pattern must name a path such as /r/somesub")
+	}
+	for _, segment := range segments {
+		if _, err := path.Match(segment, ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitPath lowercases a URL path and splits it into its non-empty segments.
+func splitPath(urlPath string) []string {
+	var segments []string
+	for _, segment := range strings.Split(strings.ToLower(urlPath), "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redactedValue replaces secrets in exported configs.
+const redactedValue = "REDACTED"
+
+// ExportConfig returns the config file as YAML with secrets redacted, so it can
+// be shared for support. Comments and layout are kept.
+func ExportConfig() ([]byte, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+	return RedactConfig(data)
+}
+
+// RedactConfig replaces the value of every api_key, webhook_url and every key
+// containing "password", "secret" or "token" in config YAML with REDACTED.
+func RedactConfig(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing config file: %w", err)
+	}
+	redactNode(&doc)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("encoding config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("encoding config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// redactNode redacts the secret values under node.
+func redactNode(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if isSecretKey(key.Value) && value.Kind == yaml.ScalarNode && value.Value != "" {
+				value.Value = redactedValue
+				value.Tag = "!!str"
+				value.Style = yaml.DoubleQuotedStyle
+			}
+		}
+	}
+	for _, child := range node.Content {
+		redactNode(child)
+	}
+}
+
+// isSecretKey reports whether a config key holds a secret.
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	return key == "api_key" || key == "webhook_url" || strings.Contains(key, "password") || strings.Contains(key, "secret") || strings.Contains(key, "token")
+}
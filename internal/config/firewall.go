@@ -0,0 +1,13 @@
+package config
+
+import "strings"
+
+// Protocols returns the protocols the rule blocks its port on.
+func (r FirewallRule) Protocols() []string {
+	switch protocol := strings.ToLower(r.Protocol); protocol {
+	case "tcp", "udp":
+		return []string{protocol}
+	default:
+		return []string{"tcp", "udp"}
+	}
+}
@@ -0,0 +1,80 @@
+package config
+
+import (
+	"net"
+	"slices"
+
+	"glocker/internal/utils"
+)
+
+// DefaultHostsSubdomains are the subdomains written to the hosts file for
+// domains with include_subdomains when hosts_subdomains isn't set.
+var DefaultHostsSubdomains = []string{"m", "mobile", "api", "app", "cdn", "static", "old", "new"}
+
+// HostsSinks returns the IPv4 and IPv6 addresses blocked domains are pointed at
+// in the hosts file, falling back to the loopback defaults.
+func (c *Config) HostsSinks() (ipv4, ipv6 string) {
+	ipv4, ipv6 = c.HostsSinkIPv4, c.HostsSinkIPv6
+	if ipv4 == "" {
+		ipv4 = DefaultHostsSinkIPv4
+	}
+	if ipv6 == "" {
+		ipv6 = DefaultHostsSinkIPv6
+	}
+	return ipv4, ipv6
+}
+
+// IsSinkAddress reports whether ip is where the hosts file points blocked
+// domains, or a loopback or unspecified address. Such an address is never a
+// blocked site's real one, so no firewall rule is made for it.
+func (c *Config) IsSinkAddress(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	if parsed.IsLoopback() || parsed.IsUnspecified() {
+		return true
+	}
+	sink4, sink6 := c.HostsSinks()
+	return parsed.Equal(net.ParseIP(sink4)) || parsed.Equal(net.ParseIP(sink6))
+}
+
+// Nameservers returns the DNS servers firewall rules are resolved through:
+// dns_servers, or the system's upstream nameservers. They are asked directly,
+// so the blocks glocker writes to the hosts file don't affect the answers.
+func (c *Config) Nameservers() []string {
+	if len(c.DNSServers) > 0 {
+		return c.DNSServers
+	}
+	return utils.SystemNameservers()
+}
+
+// HostsIncludesWWW reports whether www.<domain> entries are written alongside each
+// blocked domain (true unless hosts_include_www is explicitly false).
+func (c *Config) HostsIncludesWWW() bool {
+	return c.HostsIncludeWWW == nil || *c.HostsIncludeWWW
+}
+
+// SubdomainsToBlock returns the subdomains of d written to the hosts file and
+// resolved for the firewall besides d itself: hosts_subdomains (or
+// DefaultHostsSubdomains) under d.Name for a domain with include_subdomains,
+// leaving out its except_subdomains. The hosts file can't wildcard, so this is
+// a fixed list; the web tracking interceptor covers every subdomain anyway.
+func (c *Config) SubdomainsToBlock(d Domain) []string {
+	if !d.IncludeSubdomains || d.Pattern || d.ExactOnly {
+		return nil
+	}
+	labels := c.HostsSubdomains
+	if len(labels) == 0 {
+		labels = DefaultHostsSubdomains
+	}
+
+	var subdomains []string
+	for _, label := range labels {
+		subdomain := label + "." + d.Name
+		if label != "" && label != "www" && d.CoversHost(subdomain) && !slices.Contains(subdomains, subdomain) {
+			subdomains = append(subdomains, subdomain)
+		}
+	}
+	return subdomains
+}
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
+// SocketPath returns the path of the control socket.
+func (i IPCConfig) SocketPath() string {
+	if i.Socket == "" {
+		return GlockerSock
+	}
+	return i.Socket
+}
+
+// ReadAuthKey reads the HMAC key from the ipc.auth_key file. Surrounding
+// whitespace is ignored, so the key can be written with a trailing newline.
+func (i IPCConfig) ReadAuthKey() ([]byte, error) {
+	data, err := os.ReadFile(i.AuthKey)
+	if err != nil {
+		return nil, fmt.Errorf("reading ipc.auth_key: %w", err)
+	}
+	key := bytes.TrimSpace(data)
+	if len(key) == 0 {
+		return nil, fmt.Errorf("ipc.auth_key %s is empty", i.AuthKey)
+	}
+	return key, nil
+}
@@ -2,28 +2,46 @@ package config
 
 import (
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"strings"
 
+	"glocker/internal/utils"
+
 	"gopkg.in/yaml.v3"
 )
 
-// LoadConfig reads and parses the glocker configuration from the config file.
-// Returns an error if the file doesn't exist or cannot be parsed.
+// configPath is the config file LoadConfig reads. It is the installed, immutable
+// GlockerConfigFile unless overridden with SetConfigPath (glocker -config).
+var configPath = GlockerConfigFile
+
+// SetConfigPath makes LoadConfig read path instead of GlockerConfigFile.
+func SetConfigPath(path string) {
+	configPath = path
+}
+
+// ConfigPath returns the config file LoadConfig reads.
+func ConfigPath() string {
+	return configPath
+}
+
+// LoadConfig reads and parses the glocker configuration from the config file,
+// with the runtime overlay (see Overlay) merged on top. Returns an error if the
+// file doesn't exist or cannot be parsed; an unreadable overlay is only logged.
 func LoadConfig() (*Config, error) {
 	var config Config
 
 	// Read from external config file
-	if _, err := os.Stat(GlockerConfigFile); err != nil {
-		if os.IsNotExist(err) {
+	if _, err := os.Stat(configPath); err != nil {
+		if os.IsNotExist(err) && configPath == GlockerConfigFile {
 			return nil, fmt.Errorf("config file not found at %s\n\nThis usually means glocker is not properly installed.\nPlease check:\n  1. Is glocker installed? Run: ls -la %s\n  2. Is the glocker service running? Run: systemctl status glocker.service\n  3. If not installed, run: sudo glocker -install\n\nOriginal error: %w", GlockerConfigFile, InstallPath, err)
 		}
-		return nil, fmt.Errorf("config file access error at %s: %w", GlockerConfigFile, err)
+		return nil, fmt.Errorf("config file access error at %s: %w", configPath, err)
 	}
 
-	slog.Debug("Loading config from external file", "path", GlockerConfigFile)
-	configData, err := os.ReadFile(GlockerConfigFile)
+	slog.Debug("Loading config from external file", "path", configPath)
+	configData, err := os.ReadFile(configPath)
 	if err != nil {
 		return nil, fmt.Errorf("reading config file: %w", err)
 	}
@@ -31,11 +49,45 @@ func LoadConfig() (*Config, error) {
 		return nil, fmt.Errorf("parsing config file: %w", err)
 	}
 
+	// Merge what -add-keyword and -block added at runtime
+	overlay, err := loadOverlayForConfig()
+	if err != nil {
+		slog.Warn("Ignoring runtime overlay", "path", overlayPath, "error", err)
+	} else {
+		MergeOverlay(&config, overlay)
+	}
+
+	ExpandDayGroups(&config)
+	CompilePatterns(&config)
+
 	return &config, nil
 }
 
+// logWriter is the rotating log_file SetupLogging last set up, if any.
+var logWriter *utils.RotatingWriter
+
+// LogMaxBytes returns the size at which glocker's own logs are rotated,
+// falling back to DefaultLogMaxSizeMB.
+func (c *Config) LogMaxBytes() int64 {
+	sizeMB := c.LogMaxSizeMB
+	if sizeMB <= 0 {
+		sizeMB = DefaultLogMaxSizeMB
+	}
+	return int64(sizeMB) * 1024 * 1024
+}
+
+// LogRotatedFiles returns how many rotated log files to keep, falling back to
+// DefaultLogMaxFiles.
+func (c *Config) LogRotatedFiles() int {
+	if c.LogMaxFiles <= 0 {
+		return DefaultLogMaxFiles
+	}
+	return c.LogMaxFiles
+}
+
 // SetupLogging initializes the structured logging system based on the config.
-// Sets the log level from config and configures the default slog logger.
+// Sets the log level from config and configures the default slog logger, which
+// writes to stdout or, with log_file set, to that file rotated by size.
 func SetupLogging(cfg *Config) {
 	var level slog.Level
 
@@ -56,7 +108,18 @@ func SetupLogging(cfg *Config) {
 		Level: level,
 	}
 
-	handler := slog.NewTextHandler(os.Stdout, opts)
+	// Replace the rotating writer from an earlier setup
+	if logWriter != nil {
+		logWriter.Close()
+		logWriter = nil
+	}
+	var output io.Writer = os.Stdout
+	if cfg.LogFile != "" {
+		logWriter = utils.NewRotatingWriter(cfg.LogFile, cfg.LogMaxBytes(), cfg.LogRotatedFiles())
+		output = logWriter
+	}
+
+	handler := slog.NewTextHandler(output, opts)
 	logger := slog.New(handler)
 	slog.SetDefault(logger)
 
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// defaultWebhookTimeout bounds a webhook POST when
+// notifications.webhook_timeout_seconds is not set.
+const defaultWebhookTimeout = 10 * time.Second
+
+// WebhookTimeout returns how long a webhook POST may take, defaulting to 10
+// seconds.
+func (n NotificationsConfig) WebhookTimeout() time.Duration {
+	if n.WebhookTimeoutSeconds <= 0 {
+		return defaultWebhookTimeout
+	}
+	return time.Duration(n.WebhookTimeoutSeconds) * time.Second
+}
+
+// NotifiesPartner reports whether alerts reach the accountability partner, by
+// email or by webhook.
+func (c *Config) NotifiesPartner() bool {
+	return c.Accountability.Enabled || c.Notifications.WebhookURL != ""
+}
+
+// validateNotifications checks the webhook settings.
+func validateNotifications(n NotificationsConfig) error {
+	if n.WebhookTimeoutSeconds < 0 {
+		return fmt.Errorf("notifications.webhook_timeout_seconds cannot be negative")
+	}
+	if n.WebhookURL == "" {
+		return nil
+	}
+	u, err := url.Parse(n.WebhookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("notifications.webhook_url must be an http or https URL")
+	}
+	return nil
+}
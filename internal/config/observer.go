@@ -0,0 +1,9 @@
+package config
+
+// SocketPath returns the path of the read-only observer socket.
+func (o ObserverSocketConfig) SocketPath() string {
+	if o.Path == "" {
+		return DefaultObserverSock
+	}
+	return o.Path
+}
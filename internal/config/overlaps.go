@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	minutesPerDay  = 24 * 60
+	minutesPerWeek = 7 * minutesPerDay
+)
+
+// weekdayIndex maps the day names used in time windows to their weekday.
+var weekdayIndex = map[string]int{"Sun": 0, "Mon": 1, "Tue": 2, "Wed": 3, "Thu": 4, "Fri": 5, "Sat": 6}
+
+// weekInterval is a span of minutes from the start of the week (Sunday 00:00).
+// end may run past the end of the week when a Saturday window crosses midnight.
+type weekInterval struct {
+	start, end int
+}
+
+// TimeWindowOverlaps returns a warning for each pair of overlapping time windows
+// within a domain's time_windows and within sudoers.time_allowed. Overlaps aren't
+// errors, but they usually mean the windows don't say what was intended.
+func TimeWindowOverlaps(cfg *Config) []string {
+	var warnings []string
+	for _, domain := range cfg.Domains {
+		for _, overlap := range findWindowOverlaps(domain.TimeWindows) {
+			warnings = append(warnings, fmt.Sprintf("domain %s: %s", domain.Name, overlap))
+		}
+	}
+	for _, overlap := range findWindowOverlaps(cfg.Sudoers.TimeAllowed) {
+		warnings = append(warnings, "sudoers time_allowed: "+overlap)
+	}
+	return warnings
+}
+
+// findWindowOverlaps describes each pair of windows that share at least a minute.
+// Windows that only touch, like 09:00-12:00 and 12:00-17:00, don't overlap.
+func findWindowOverlaps(windows []TimeWindow) []string {
+	var overlaps []string
+	for i := range windows {
+		for j := i + 1; j < len(windows); j++ {
+			if windowsOverlap(windows[i], windows[j]) {
+				overlaps = append(overlaps, fmt.Sprintf("time windows %s and %s overlap",
+					describeWindow(windows[i]), describeWindow(windows[j])))
+			}
+		}
+	}
+	return overlaps
+}
+
+// windowsOverlap reports whether two windows cover a common minute of the week.
+func windowsOverlap(a, b TimeWindow) bool {
+	for _, x := range weekIntervals(a) {
+		for _, y := range weekIntervals(b) {
+			// Shift by a week so a window running past Saturday midnight meets Sunday
+			for _, shift := range []int{-minutesPerWeek, 0, minutesPerWeek} {
+				if x.start < y.end+shift && y.start+shift < x.end {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// weekIntervals returns the spans of the week a window covers, one per day it
+// starts on. A window whose end is before its start runs into the next day.
+func weekIntervals(window TimeWindow) []weekInterval {
+	start, errStart := time.Parse("15:04", window.Start)
+	end, errEnd := time.Parse("15:04", window.End)
+	if errStart != nil || errEnd != nil {
+		return nil
+	}
+	startMinute := start.Hour()*60 + start.Minute()
+	length := end.Hour()*60 + end.Minute() - startMinute
+	if length < 0 {
+		length += minutesPerDay
+	}
+
+	var intervals []weekInterval
+	for _, day := range window.Days {
+		index, ok := weekdayIndex[day]
+		if !ok || length == 0 {
+			continue
+		}
+		dayStart := index*minutesPerDay + startMinute
+		intervals = append(intervals, weekInterval{start: dayStart, end: dayStart + length})
+	}
+	return intervals
+}
+
+// describeWindow formats a window as "22:00-02:00 (Fri,Sat)".
+func describeWindow(window TimeWindow) string {
+	return fmt.Sprintf("%s-%s (%s)", window.Start, window.End, strings.Join(window.Days, ","))
+}
@@ -0,0 +1,258 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+)
+
+// overlayPath is the runtime overlay LoadConfig merges. It is RuntimeOverlayFile
+// unless overridden with SetOverlayPath.
+var overlayPath = RuntimeOverlayFile
+
+// overlayMutex serializes read-modify-write cycles of the overlay file.
+var overlayMutex sync.Mutex
+
+// overlayBaseline is the overlay file as glocker last wrote it, or as the first
+// LoadConfig found it, so edits made behind glocker's back can be undone by
+// RestoreOverlay. It is nil until then; a missing file is an empty baseline.
+var overlayBaseline []byte
+
+// overlayImmutable makes writeOverlay keep the overlay file chattr +i between
+// its writes. The daemon sets it once chattr is known to be available.
+var overlayImmutable bool
+
+// SetOverlayPath makes LoadConfig and AppendToOverlay use path instead of
+// RuntimeOverlayFile.
+func SetOverlayPath(path string) {
+	overlayMutex.Lock()
+	defer overlayMutex.Unlock()
+	overlayPath = path
+	overlayBaseline = nil
+}
+
+// SetOverlayImmutable sets whether the overlay file is kept immutable (chattr
+// +i) between glocker's own writes, and protects an existing file right away.
+func SetOverlayImmutable(immutable bool) {
+	overlayMutex.Lock()
+	defer overlayMutex.Unlock()
+	overlayImmutable = immutable
+	if _, err := os.Stat(overlayPath); err == nil {
+		setImmutable(overlayPath, true)
+	}
+}
+
+// OverlayPath returns the runtime overlay file LoadConfig merges.
+func OverlayPath() string {
+	return overlayPath
+}
+
+// Overlay holds what was changed at runtime with -add-keyword, -block and
+// -enable-category/-disable-category. The config file is immutable, so these
+// live in a separate writable file that LoadConfig merges on top of it.
+type Overlay struct {
+	Keywords   []string        `yaml:"keywords,omitempty"`   // Added to both url_keywords and content_keywords
+	Domains    []string        `yaml:"domains,omitempty"`    // Blocked permanently, like a domain entry with only a name
+	Categories map[string]bool `yaml:"categories,omitempty"` // keyword_categories name -> enabled, overriding the config
+}
+
+// LoadOverlay reads the overlay at path. A missing file is an empty overlay.
+func LoadOverlay(path string) (*Overlay, error) {
+	var overlay Overlay
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &overlay, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading overlay file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, &overlay); err != nil {
+		return nil, fmt.Errorf("parsing overlay file: %w", err)
+	}
+	return &overlay, nil
+}
+
+// AppendToOverlay adds keywords and domains to the overlay file, skipping ones
+// it already has.
+func AppendToOverlay(keywords, domains []string) error {
+	overlayMutex.Lock()
+	defer overlayMutex.Unlock()
+
+	overlay, err := LoadOverlay(overlayPath)
+	if err != nil {
+		return err
+	}
+	changed := false
+	for _, keyword := range keywords {
+		if keyword != "" && !slices.Contains(overlay.Keywords, keyword) {
+			overlay.Keywords = append(overlay.Keywords, keyword)
+			changed = true
+		}
+	}
+	for _, domain := range domains {
+		if domain != "" && !slices.Contains(overlay.Domains, domain) {
+			overlay.Domains = append(overlay.Domains, domain)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return writeOverlay(overlay)
+}
+
+// SetOverlayCategory records in the overlay file whether the keyword category
+// name is enabled, overriding its enabled setting in the config.
+func SetOverlayCategory(name string, enabled bool) error {
+	overlayMutex.Lock()
+	defer overlayMutex.Unlock()
+
+	overlay, err := LoadOverlay(overlayPath)
+	if err != nil {
+		return err
+	}
+	if current, ok := overlay.Categories[name]; ok && current == enabled {
+		return nil
+	}
+	if overlay.Categories == nil {
+		overlay.Categories = make(map[string]bool)
+	}
+	overlay.Categories[name] = enabled
+	return writeOverlay(overlay)
+}
+
+// loadOverlayForConfig loads the overlay LoadConfig merges, recording it as the
+// baseline the first time.
+func loadOverlayForConfig() (*Overlay, error) {
+	overlayMutex.Lock()
+	defer overlayMutex.Unlock()
+
+	if overlayBaseline == nil {
+		data, err := os.ReadFile(overlayPath)
+		switch {
+		case err == nil:
+			overlayBaseline = data
+		case os.IsNotExist(err):
+			overlayBaseline = []byte{}
+		}
+	}
+	return LoadOverlay(overlayPath)
+}
+
+// RestoreOverlay undoes changes made to the overlay file behind glocker's back,
+// such as removing a -block domain from it, by writing back the baseline.
+// Returns whether the file had changed. Nothing is checked before the overlay
+// was first loaded or written.
+func RestoreOverlay() (bool, error) {
+	overlayMutex.Lock()
+	defer overlayMutex.Unlock()
+
+	if overlayBaseline == nil {
+		return false, nil
+	}
+	current, err := os.ReadFile(overlayPath)
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("reading overlay file: %w", err)
+	}
+	if bytes.Equal(current, overlayBaseline) {
+		return false, nil
+	}
+
+	if len(overlayBaseline) == 0 {
+		setImmutable(overlayPath, false)
+		if err := os.Remove(overlayPath); err != nil {
+			return true, fmt.Errorf("removing overlay file: %w", err)
+		}
+		return true, nil
+	}
+	return true, writeOverlayData(overlayBaseline)
+}
+
+// setImmutable sets or clears the immutable flag of the overlay file at path
+// when overlayImmutable is set. Failures are only logged: the file may not
+// exist yet, or its filesystem may not support the flag.
+func setImmutable(path string, immutable bool) {
+	if !overlayImmutable {
+		return
+	}
+	flag := "-i"
+	if immutable {
+		flag = "+i"
+	}
+	if err := exec.Command("chattr", flag, path).Run(); err != nil {
+		slog.Debug("Failed to change the overlay file's immutable flag", "path", path, "flag", flag, "error", err)
+	}
+}
+
+// writeOverlay replaces the overlay file with overlay.
+func writeOverlay(overlay *Overlay) error {
+	data, err := yaml.Marshal(overlay)
+	if err != nil {
+		return fmt.Errorf("marshaling overlay: %w", err)
+	}
+	return writeOverlayData(data)
+}
+
+// writeOverlayData replaces the overlay file with data and makes it the
+// baseline. The file is replaced atomically, so a crash never leaves it
+// truncated, and is immutable again afterwards.
+func writeOverlayData(data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(overlayPath), 0755); err != nil {
+		return fmt.Errorf("creating overlay directory: %w", err)
+	}
+	tmpPath := overlayPath + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("writing overlay file: %w", err)
+	}
+	// An immutable file can't be replaced
+	setImmutable(overlayPath, false)
+	if err := os.Rename(tmpPath, overlayPath); err != nil {
+		os.Remove(tmpPath)
+		setImmutable(overlayPath, true)
+		return fmt.Errorf("replacing overlay file: %w", err)
+	}
+	setImmutable(overlayPath, true)
+	overlayBaseline = data
+	return nil
+}
+
+// MergeOverlay adds the overlay's keywords and domains to cfg and switches its
+// keyword categories on or off. Keywords already in the config aren't
+// repeated, a domain the config already lists keeps its config entry (time
+// windows, unblockable, ...), and categories the config no longer has are
+// ignored.
+func MergeOverlay(cfg *Config, overlay *Overlay) {
+	keywords := &cfg.ExtensionKeywords
+	for _, keyword := range overlay.Keywords {
+		if !slices.Contains(keywords.URLKeywords, keyword) {
+			keywords.URLKeywords = append(keywords.URLKeywords, keyword)
+		}
+		if !slices.Contains(keywords.ContentKeywords, keyword) {
+			keywords.ContentKeywords = append(keywords.ContentKeywords, keyword)
+		}
+	}
+
+	listed := make(map[string]bool, len(cfg.Domains))
+	for _, domain := range cfg.Domains {
+		listed[domain.Name] = true
+	}
+	for _, name := range overlay.Domains {
+		if !listed[name] {
+			cfg.Domains = append(cfg.Domains, Domain{Name: name})
+			listed[name] = true
+		}
+	}
+
+	for i := range cfg.KeywordCategories {
+		if enabled, ok := overlay.Categories[cfg.KeywordCategories[i].Name]; ok {
+			cfg.KeywordCategories[i].Enabled = &enabled
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package config
+
+import (
+	"log/slog"
+	"regexp"
+)
+
+// compilePattern compiles a pattern domain name into a regexp anchored to the full host.
+func compilePattern(name string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + name + ")$")
+}
+
+// CompilePatterns compiles the names of all pattern domains into regular expressions.
+// Patterns that fail to compile are left uncompiled (and never match); ValidateConfig
+// reports them as errors.
+func CompilePatterns(cfg *Config) {
+	compileDomainPatterns(cfg.Domains)
+	compileDomainPatterns(cfg.AllowlistMode.AllowedDomains)
+}
+
+func compileDomainPatterns(domains []Domain) {
+	for i := range domains {
+		domain := &domains[i]
+		if !domain.Pattern {
+			continue
+		}
+		re, err := compilePattern(domain.Name)
+		if err != nil {
+			slog.Debug("Skipping invalid domain pattern", "pattern", domain.Name, "error", err)
+			domain.compiled = nil
+			continue
+		}
+		domain.compiled = re
+	}
+}
+
+// MatchesHost reports whether a pattern domain matches the given host.
+// Always returns false for non-pattern domains or patterns that failed to compile.
+func (d *Domain) MatchesHost(host string) bool {
+	if !d.Pattern || d.compiled == nil {
+		return false
+	}
+	return d.compiled.MatchString(host)
+}
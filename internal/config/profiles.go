@@ -0,0 +1,34 @@
+package config
+
+import "fmt"
+
+// ApplyProfile merges the named profile into cfg.
+// Profile domains replace config domains with the same name and are appended otherwise;
+// a positive profile temp_unblock_time overrides the unblocking setting.
+func ApplyProfile(cfg *Config, name string) error {
+	profile, ok := cfg.Profiles[name]
+	if !ok {
+		return fmt.Errorf("profile %q is not defined", name)
+	}
+
+	index := make(map[string]int, len(cfg.Domains))
+	for i, domain := range cfg.Domains {
+		index[domain.Name] = i
+	}
+
+	for _, domain := range profile.Domains {
+		if i, exists := index[domain.Name]; exists {
+			cfg.Domains[i] = domain
+			continue
+		}
+		index[domain.Name] = len(cfg.Domains)
+		cfg.Domains = append(cfg.Domains, domain)
+	}
+	CompilePatterns(cfg)
+
+	if profile.TempUnblockTime > 0 {
+		cfg.Unblocking.TempUnblockTime = profile.TempUnblockTime
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Forbidden program match modes (forbidden_programs.programs[].match).
+const (
+	ProgramMatchSubstring = "substring" // Name appears anywhere in the ps line, ignoring case (default)
+	ProgramMatchExact     = "exact"     // Name equals the executable's base name, ignoring case
+	ProgramMatchRegex     = "regex"     // Name is a regular expression matched against the command line
+)
+
+// MatchMode returns how the program name is matched against running processes,
+// defaulting to substring.
+func (p ForbiddenProgram) MatchMode() string {
+	if p.Match == "" {
+		return ProgramMatchSubstring
+	}
+	return strings.ToLower(p.Match)
+}
+
+// validateForbiddenProgramMatch checks the match mode and that any regular
+// expressions (name with regex matching, cgroup_match) compile.
+func validateForbiddenProgramMatch(program ForbiddenProgram) error {
+	if program.CgroupMatch != "" {
+		if _, err := regexp.Compile(program.CgroupMatch); err != nil {
+			return fmt.Errorf("forbidden program %q has an invalid cgroup_match: %w", program.Name, err)
+		}
+	}
+
+	switch program.MatchMode() {
+	case ProgramMatchSubstring, ProgramMatchExact:
+		return nil
+	case ProgramMatchRegex:
+		if _, err := regexp.Compile(program.Name); err != nil {
+			return fmt.Errorf("forbidden program %q is not a valid regular expression: %w", program.Name, err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("forbidden program %q: invalid match %q (use exact, substring or regex)", program.Name, program.Match)
+	}
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+)
+
+// ParseQuotes splits the contents of a mindful_quotes_file into quotes. If the
+// text has blank lines, they separate the quotes, which may then span several
+// lines; otherwise every line is a quote.
+func ParseQuotes(text string) []string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	text = strings.TrimSpace(strings.Join(lines, "\n"))
+
+	separator := "\n"
+	if strings.Contains(text, "\n\n") {
+		separator = "\n\n"
+	}
+	var quotes []string
+	for _, quote := range strings.Split(text, separator) {
+		if quote = strings.TrimSpace(quote); quote != "" {
+			quotes = append(quotes, quote)
+		}
+	}
+	return quotes
+}
+
+// LoadMindfulQuotes reads the quotes in a mindful_quotes_file.
+func LoadMindfulQuotes(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading mindful_quotes_file: %w", err)
+	}
+	quotes := ParseQuotes(string(data))
+	if len(quotes) == 0 {
+		return nil, fmt.Errorf("mindful_quotes_file %s has no quotes", path)
+	}
+	return quotes, nil
+}
+
+// RandomQuote returns a quote picked with crypto/rand, so the choice can't be
+// predicted from the time. It returns "" when there are no quotes.
+func RandomQuote(quotes []string) string {
+	if len(quotes) == 0 {
+		return ""
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(quotes))))
+	if err != nil {
+		return quotes[0]
+	}
+	return quotes[n.Int64()]
+}
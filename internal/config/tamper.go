@@ -0,0 +1,15 @@
+package config
+
+import "time"
+
+// Debounce returns how long to wait before re-checking a detected change.
+func (t TamperConfig) Debounce() time.Duration {
+	return time.Duration(t.DebounceSeconds) * time.Second
+}
+
+// InMaintenanceWindow reports whether now falls within one of the allowed
+// maintenance windows. The early morning part of a midnight-crossing window
+// belongs to the day it started on.
+func (t TamperConfig) InMaintenanceWindow(now time.Time) bool {
+	return AnyWindowActive(t.AllowedMaintenanceWindows, now)
+}
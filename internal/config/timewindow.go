@@ -0,0 +1,28 @@
+package config
+
+import (
+	"slices"
+	"time"
+
+	"glocker/internal/utils"
+)
+
+// Active reports whether now falls within the window. The early morning part of
+// a midnight-crossing window belongs to the day it started on, and the window
+// includes its whole end minute.
+func (w TimeWindow) Active(now time.Time) bool {
+	currentTime := now.Format("15:04")
+	day := now.Weekday().String()[:3]
+	if w.Start > w.End && currentTime <= w.End {
+		day = now.AddDate(0, 0, -1).Weekday().String()[:3]
+	}
+	return slices.Contains(w.Days, day) && utils.IsInTimeWindow(currentTime, w.Start, w.End)
+}
+
+// AnyWindowActive reports whether now falls within one of windows. It is false
+// for no windows; callers decide what having none means.
+func AnyWindowActive(windows []TimeWindow, now time.Time) bool {
+	return slices.ContainsFunc(windows, func(window TimeWindow) bool {
+		return window.Active(now)
+	})
+}
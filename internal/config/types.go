@@ -1,18 +1,39 @@
 package config
 
+import "regexp"
+
 // Constants used throughout the glocker application
 const (
-	InstallPath          = "/usr/local/bin/glocker"
-	GlocklockInstallPath = "/usr/local/bin/glocklock"
-	GlockpeekInstallPath = "/usr/local/bin/glockpeek"
-	GlockerConfigFile    = "/etc/glocker/config.yaml"
-	HostsMarkerStart     = "### GLOCKER START ###"
-	SudoersPath          = "/etc/sudoers"
-	SudoersBackup        = "/etc/sudoers.glocker.backup"
-	SudoersMarker        = "# GLOCKER-MANAGED"
-	SystemdFile          = "./extras/glocker.service"
-	GlockerSock          = "/tmp/glocker.sock"
-	EmailCooldownMinutes = 15 // Minimum time between emails for the same event type
+	InstallPath             = "/usr/local/bin/glocker"
+	GlocklockInstallPath    = "/usr/local/bin/glocklock"
+	GlockpeekInstallPath    = "/usr/local/bin/glockpeek"
+	GlockerConfigFile       = "/etc/glocker/config.yaml"
+	HostsMarkerStart        = "### GLOCKER START ###"
+	HostsBackupSuffix       = ".glocker.backup" // Original (non-glocker) hosts entries, restored if the hosts file is wiped
+	SudoersPath             = "/etc/sudoers"
+	SudoersBackup           = "/etc/sudoers.glocker.backup"
+	SudoersMarker           = "# GLOCKER-MANAGED"
+	SudoersDisabledTag      = "# GLOCKER-DISABLED: " // Prefix for user grants neutralized in sudoers drop-ins
+	SudoersBackupSuffix     = ".glocker.backup"      // Drop-in backups; sudo skips include files containing a dot
+	SudoTimestampDir        = "/run/sudo/ts"         // sudo's cached credentials, one file per user
+	SystemdFile             = "./extras/glocker.service"
+	GlockerSock             = "/run/glocker/glocker.sock"  // Default control socket; its directory is root-only
+	DefaultObserverSock     = "/run/glocker/observer.sock" // Read-only status socket, see ObserverSocketConfig
+	BlocklistCacheDir       = "/var/lib/glocker/blocklists"
+	DefaultUnblockStateFile = "/var/lib/glocker/unblock-grants.json"
+	BlockAddedStateFile     = "/var/lib/glocker/block-added.json" // When -block and -reload added domains, for new_block_cooldown
+	DailyReportStateFile    = "/var/lib/glocker/daily-report-sent"
+	WeeklyReportStateFile   = "/var/lib/glocker/weekly-report-sent"
+	PendingEmailsFile       = "/var/lib/glocker/pending_emails.jsonl" // Emails that failed to send, retried after the next successful send
+	RuntimeOverlayFile      = "/var/lib/glocker/keywords.yaml"        // Keywords and domains added with -add-keyword and -block, merged by LoadConfig
+	DNSCacheFile            = "/var/lib/glocker/dns-cache.json"       // Addresses resolved for firewall rules, kept until their DNS TTL runs out
+	DefaultHostsSinkIPv4    = "127.0.0.1"
+	DefaultHostsSinkIPv6    = "::1"
+	EmailCooldownMinutes    = 15 // Minimum time between emails for the same event type
+	DefaultWebHTTPPort      = 80
+	DefaultWebHTTPSPort     = 443
+	DefaultLogMaxSizeMB     = 10 // Size at which glocker's own logs are rotated
+	DefaultLogMaxFiles      = 5  // Rotated log files kept
 )
 
 // TimeWindow represents a time-based blocking window with specific days.
@@ -22,50 +43,108 @@ type TimeWindow struct {
 	Days  []string `yaml:"days"`  // Mon, Tue, Wed, Thu, Fri, Sat, Sun
 }
 
+// Block styles for Domain.BlockStyle.
+const (
+	BlockStylePage    = "page"    // Redirect to the friendly block page
+	BlockStyleRefused = "refused" // Close the connection without a response
+)
+
 // Domain represents a domain to be blocked with its blocking rules.
 type Domain struct {
-	Name        string       `yaml:"name"`
-	TimeWindows []TimeWindow `yaml:"time_windows,omitempty"`
-	LogBlocking bool         `yaml:"log_blocking,omitempty"`
-	Unblockable bool         `yaml:"unblockable,omitempty"` // Set to true to allow temporary unblocking (default: false = permanent)
+	Name              string       `yaml:"name"`
+	TimeWindows       []TimeWindow `yaml:"time_windows,omitempty"`
+	LogBlocking       bool         `yaml:"log_blocking,omitempty"`
+	Unblockable       bool         `yaml:"unblockable,omitempty"`        // Set to true to allow temporary unblocking (default: false = permanent)
+	Pattern           bool         `yaml:"pattern,omitempty"`            // Treat Name as a regular expression matched against the full host
+	ExactOnly         bool         `yaml:"exact_only,omitempty"`         // Only block the domain itself (and www.), not its subdomains
+	ExceptSubdomains  []string     `yaml:"except_subdomains,omitempty"`  // Subdomains that stay reachable (e.g. "mail" or "mail.example.com")
+	UnblockMinutes    int          `yaml:"unblock_minutes,omitempty"`    // Minutes a temporary unblock lasts; overrides unblocking.temp_unblock_time when > 0
+	PathPatterns      []string     `yaml:"path_patterns,omitempty"`      // Only block these URL paths (e.g. "/r/somesub", "/r/*/comments"); the host itself stays reachable
+	BlockStyle        string       `yaml:"block_style,omitempty"`        // How the web tracking server answers blocked requests: "page" (default) or "refused"
+	AbsoluteWindows   []TimeWindow `yaml:"absolute_windows,omitempty"`   // Times when an unblockable domain can't be unblocked after all
+	Label             string       `yaml:"label,omitempty"`              // Why the domain is blocked, shown by -info (e.g. "doomscrolling")
+	Category          string       `yaml:"category,omitempty"`           // Groups the domain in glockpeek reports (e.g. "social")
+	IncludeSubdomains bool         `yaml:"include_subdomains,omitempty"` // Also block hosts_subdomains (m., api., ...) in the hosts file and firewall
+
+	compiled *regexp.Regexp // Compiled form of Name when Pattern is set (populated by CompilePatterns)
 }
 
 // SudoersConfig controls sudo access restrictions.
 type SudoersConfig struct {
-	Enabled            bool         `yaml:"enabled"`
-	User               string       `yaml:"user"`
-	AllowedSudoersLine string       `yaml:"allowed_sudoers_line"`
-	BlockedSudoersLine string       `yaml:"blocked_sudoers_line"`
-	TimeAllowed        []TimeWindow `yaml:"time_allowed"`
+	Enabled             bool         `yaml:"enabled"`
+	User                string       `yaml:"user"`
+	AllowedSudoersLine  string       `yaml:"allowed_sudoers_line"`
+	BlockedSudoersLine  string       `yaml:"blocked_sudoers_line"`
+	TimeAllowed         []TimeWindow `yaml:"time_allowed"`
+	KillTimestampOnLock bool         `yaml:"kill_timestamp_on_lock"` // Drop the user's cached sudo credentials when sudo gets blocked
 }
 
-// AccountabilityConfig configures email notifications via Mailgun.
+// AccountabilityConfig configures email notifications via Mailgun or SMTP.
 type AccountabilityConfig struct {
 	Enabled            bool   `yaml:"enabled"`
-	PartnerEmail       string `yaml:"partner_email"`
+	PartnerEmail       string   `yaml:"partner_email"`  // One address, or several separated by commas
+	PartnerEmails      []string `yaml:"partner_emails"` // Additional recipients
 	FromEmail          string `yaml:"from_email"`
-	ApiKey             string `yaml:"api_key"`
+	Provider           string `yaml:"provider"`       // "mailgun" (default) or "smtp"
+	ApiKey             string `yaml:"api_key"`        // Mailgun API key
+	MailgunDomain      string `yaml:"mailgun_domain"` // Mailgun sending domain
+	SMTPHost           string `yaml:"smtp_host"`
+	SMTPPort           int    `yaml:"smtp_port"` // Default: 587
+	SMTPUsername       string `yaml:"smtp_username"`
+	SMTPPassword       string `yaml:"smtp_password"`
+	MaxRetries         *int   `yaml:"max_retries"` // Retries after a failed send (default: 3)
 	DailyReportTime    string `yaml:"daily_report_time"`
 	DailyReportEnabled bool   `yaml:"daily_report_enabled"`
+
+	WeeklyReportEnabled bool   `yaml:"weekly_report_enabled"`
+	WeeklyReportDay     string `yaml:"weekly_report_day"`  // Weekday name, default "Sun"
+	WeeklyReportTime    string `yaml:"weekly_report_time"` // HH:MM, default "20:00"
+}
+
+// NotificationsConfig configures alerts sent besides accountability emails.
+type NotificationsConfig struct {
+	WebhookURL            string `yaml:"webhook_url"`             // Alerts are POSTed here as JSON (e.g. a Slack incoming webhook)
+	WebhookTimeoutSeconds int    `yaml:"webhook_timeout_seconds"` // Default: 10
 }
 
 // TamperConfig controls file integrity monitoring and tamper detection.
 type TamperConfig struct {
-	Enabled       bool   `yaml:"enabled"`
-	CheckInterval int    `yaml:"check_interval_seconds"`
-	AlarmCommand  string `yaml:"alarm_command"`
+	Enabled                   bool         `yaml:"enabled"`
+	CheckInterval             int          `yaml:"check_interval_seconds"`
+	AlarmCommand              string       `yaml:"alarm_command"`
+	DebounceSeconds           int          `yaml:"debounce_seconds"`            // Re-check a detected change after this long and only alarm if it persists
+	AllowedMaintenanceWindows []TimeWindow `yaml:"allowed_maintenance_windows"` // Changes detected in these windows are only logged
 }
 
 // WebTrackingConfig controls the web tracking server for browser integration.
 type WebTrackingConfig struct {
+	Enabled             bool   `yaml:"enabled"`
+	Command             string `yaml:"command"`
+	BlockedPageTemplate string `yaml:"blocked_page_template"` // Optional html/template file for the blocked page
+	HTTPPort            int    `yaml:"http_port"`             // Default: DefaultWebHTTPPort
+	HTTPSPort           int    `yaml:"https_port"`            // Default: DefaultWebHTTPSPort
+	BindAddr            string `yaml:"bind_addr"`             // IP address to listen on (default: all interfaces)
+}
+
+// IPCConfig controls the control socket and authentication of its commands.
+type IPCConfig struct {
+	Socket  string `yaml:"socket_path"` // Default: GlockerSock
+	AuthKey string `yaml:"auth_key"`    // File holding the shared HMAC key; when set, commands that change state must be signed
+}
+
+// ObserverSocketConfig controls the read-only socket that serves status queries
+// to users who can't use the root-only control socket.
+type ObserverSocketConfig struct {
 	Enabled bool   `yaml:"enabled"`
-	Command string `yaml:"command"`
+	Path    string `yaml:"path"`  // Default: DefaultObserverSock
+	Group   string `yaml:"group"` // Group allowed to connect (default: root only)
 }
 
 // ContentMonitoringConfig controls content/keyword monitoring via browser extension.
 type ContentMonitoringConfig struct {
-	Enabled bool   `yaml:"enabled"`
-	LogFile string `yaml:"log_file"`
+	Enabled      bool   `yaml:"enabled"`
+	LogFile      string `yaml:"log_file"`
+	DedupSeconds *int   `yaml:"dedup_seconds"` // Identical reports within this window count once (default: 30, 0 disables)
 }
 
 // ExtensionKeywordsConfig defines keywords for browser extension monitoring.
@@ -75,24 +154,62 @@ type ExtensionKeywordsConfig struct {
 	Whitelist       []string `yaml:"whitelist"`
 }
 
+// KeywordCategory is a named group of extension keywords that can be switched off,
+// or limited to time windows, without deleting the keywords.
+type KeywordCategory struct {
+	Name            string       `yaml:"name"`
+	Enabled         *bool        `yaml:"enabled"` // Default: true
+	URLKeywords     []string     `yaml:"url_keywords"`
+	ContentKeywords []string     `yaml:"content_keywords"`
+	TimeWindows     []TimeWindow `yaml:"time_windows"` // When the keywords apply (default: always)
+}
+
 // ViolationTrackingConfig controls violation threshold tracking and enforcement.
 type ViolationTrackingConfig struct {
-	Enabled           bool   `yaml:"enabled"`
-	MaxViolations     int    `yaml:"max_violations"`
-	TimeWindowMinutes int    `yaml:"time_window_minutes"`
-	Command           string `yaml:"command"`
-	ResetDaily        bool   `yaml:"reset_daily"`
-	ResetTime         string `yaml:"reset_time"`
-	LockDuration      string `yaml:"lock_duration"`  // Duration for screen lock (e.g., "1m", "5m")
-	MindfulText       string `yaml:"mindful_text"`   // Text that must be typed to unlock
-	Background        string `yaml:"background"`     // Path to PNG/JPG background image
+	Enabled           bool    `yaml:"enabled"`
+	MaxViolations     int     `yaml:"max_violations"`
+	WarnRatio         float64 `yaml:"warn_ratio"` // Fraction of max_violations that triggers a warning notification (0 disables)
+	TimeWindowMinutes int     `yaml:"time_window_minutes"`
+	Command           string  `yaml:"command"`
+	ResetDaily        bool    `yaml:"reset_daily"`
+	ResetTime         string  `yaml:"reset_time"`
+	LockDuration      string  `yaml:"lock_duration"`      // Duration for screen lock (e.g., "1m", "5m")
+	MindfulText       string  `yaml:"mindful_text"`       // Text that must be typed to unlock
+	MathProblems      int     `yaml:"math_problems"`      // Problems to solve with glocklock -math (0 disables the math lock by default)
+	MathDifficulty    string  `yaml:"math_difficulty"`    // "easy", "medium" (default) or "hard"
+	Background        string  `yaml:"background"`         // Path to PNG/JPG background image
+	EscalationProfile string  `yaml:"escalation_profile"` // Profile switched on when the threshold is exceeded (until daily reset)
+
+	Weights map[string]int    `yaml:"weights"` // How much each violation type counts toward max_violations (default: 1)
+	Actions []ViolationAction `yaml:"actions"` // Run in order when the threshold is exceeded, after command
+}
+
+// ViolationAction is one step of the chain run when the violation threshold is
+// exceeded.
+type ViolationAction struct {
+	Type    string   `yaml:"type"`    // "command", "lock", "suspend" or "notify"
+	Command string   `yaml:"command"` // Program to run (command), or suspend command (suspend, default: panic_command)
+	Args    []string `yaml:"args"`    // Arguments of command
+	Enabled *bool    `yaml:"enabled"` // Default: true
 }
 
 // UnblockingConfig controls temporary unblocking behavior.
 type UnblockingConfig struct {
-	Reasons         []string `yaml:"reasons"`
-	LogFile         string   `yaml:"log_file"`
-	TempUnblockTime int      `yaml:"temp_unblock_time"` // Minutes
+	Reasons          []string `yaml:"reasons"`
+	LogFile          string   `yaml:"log_file"`
+	TempUnblockTime  int      `yaml:"temp_unblock_time"`  // Minutes
+	NewBlockCooldown int      `yaml:"new_block_cooldown"` // Hours a newly added block can't be unblocked (0 = off)
+	MaxPerDay        int      `yaml:"max_per_day"`        // Unblocks granted per day (0 = unlimited)
+	ResetTime        string   `yaml:"reset_time"`         // HH:MM when the daily unblock count resets (default: 00:00)
+	StateFile        string   `yaml:"state_file"`         // Where granted unblocks are persisted (default: DefaultUnblockStateFile)
+
+	ExpiryWarningSeconds int `yaml:"expiry_warning_seconds"` // Notify this long before an unblock expires (0 = off)
+	GraceSeconds         int `yaml:"grace_seconds"`          // Keep a domain reachable this long after its unblock expires
+
+	MinReasonLength int  `yaml:"min_reason_length"` // Minimum characters in the reason, or in the note with require_note (0 = off)
+	RequireNote     bool `yaml:"require_note"`      // Reasons must be "category:note" with a free-text note
+
+	MaxUntilMinutes int `yaml:"max_until_minutes"` // Furthest ahead -until may end an unblock (0 = -until disabled)
 }
 
 // LifecycleConfig controls install/uninstall logging behavior.
@@ -100,9 +217,22 @@ type LifecycleConfig struct {
 	LogFile string `yaml:"log_file"`
 }
 
+// UninstallConfig controls how -uninstall requests are handled.
+type UninstallConfig struct {
+	CooldownMinutes int `yaml:"cooldown_minutes"` // Wait between requesting and confirming an uninstall (0 = uninstall at once)
+}
+
+// FocusConfig controls focus sessions started with -focus.
+type FocusConfig struct {
+	DistractionDomains []string `yaml:"distraction_domains"` // Blocked for the length of a focus session
+}
+
 // ForbiddenProgram represents a program to be killed during blocking periods.
 type ForbiddenProgram struct {
 	Name        string       `yaml:"name"`
+	Match       string       `yaml:"match"`        // "substring" (default), "exact" or "regex"
+	Exclude     []string     `yaml:"exclude"`      // Processes whose command line contains any of these are spared
+	CgroupMatch string       `yaml:"cgroup_match"` // Regex on /proc/<pid>/cgroup; matching systemd scopes are stopped instead of killing by name
 	TimeWindows []TimeWindow `yaml:"time_windows"`
 }
 
@@ -113,28 +243,88 @@ type ForbiddenProgramsConfig struct {
 	Programs      []ForbiddenProgram `yaml:"programs"`
 }
 
+// RemoteBlocklist is a hosts-style blocklist fetched from a URL and merged into
+// the always-block set.
+type RemoteBlocklist struct {
+	URL          string `yaml:"url"`
+	RefreshHours int    `yaml:"refresh_hours"` // Hours between fetches (default: 24)
+}
+
+// SelfTestConfig controls the periodic check that a sample of blocked domains is really unreachable.
+type SelfTestConfig struct {
+	Enabled         bool `yaml:"enabled"`
+	SampleSize      int  `yaml:"sample_size"`      // Blocked domains checked per run (default: 5)
+	IntervalMinutes int  `yaml:"interval_minutes"` // Minutes between runs (default: 60)
+}
+
+// FirewallRule blocks outbound traffic to a port, like SSH or a torrent client's
+// port, during its time windows (always, if it has none).
+type FirewallRule struct {
+	Port        int          `yaml:"port"`
+	Protocol    string       `yaml:"protocol"` // "tcp", "udp" or "both" (default)
+	TimeWindows []TimeWindow `yaml:"time_windows"`
+}
+
+// AllowlistModeConfig turns the blocklist around during its time windows
+// (always, if it has none): everything except AllowedDomains is blocked.
+type AllowlistModeConfig struct {
+	Enabled        bool         `yaml:"enabled"`
+	AllowedDomains []Domain     `yaml:"allowed_domains"` // Domains (and their subdomains) that stay reachable
+	TimeWindows    []TimeWindow `yaml:"time_windows"`
+}
+
+// Profile is a named set of stricter rules that can be switched on at runtime.
+type Profile struct {
+	Domains         []Domain `yaml:"domains"`           // Extra domains to block; replace config domains of the same name
+	TempUnblockTime int      `yaml:"temp_unblock_time"` // Minutes; overrides unblocking.temp_unblock_time when > 0
+}
+
 // Config is the main configuration structure for glocker.
 type Config struct {
 	EnableHosts             bool                    `yaml:"enable_hosts"`
 	EnableFirewall          bool                    `yaml:"enable_firewall"`
 	EnableForbiddenPrograms bool                    `yaml:"enable_forbidden_programs"`
 	Domains                 []Domain                `yaml:"domains"`
+	RemoteBlocklists        []RemoteBlocklist       `yaml:"remote_blocklists"`
+	BlockDoH                bool                    `yaml:"block_doh"`         // Block known DNS-over-HTTPS resolvers, and DNS-over-TLS with enable_firewall
+	DoHExtraDomains         []string                `yaml:"doh_extra_domains"` // Resolvers blocked by block_doh in addition to the built-in list
+	FirewallRules           []FirewallRule          `yaml:"firewall_rules"`    // Outbound ports blocked with enable_firewall
+	AllowlistMode           AllowlistModeConfig     `yaml:"allowlist_mode"`    // Block everything except allowed_domains during its windows
+	DNSServers              []string                `yaml:"dns_servers"`       // Nameservers firewall rules are resolved through (default: the system's upstream servers)
 	HostsPath               string                  `yaml:"hosts_path"`
+	HostsSinkIPv4           string                  `yaml:"hosts_sink_ipv4"`   // Address blocked domains resolve to over IPv4 (default: 127.0.0.1)
+	HostsSinkIPv6           string                  `yaml:"hosts_sink_ipv6"`   // Address blocked domains resolve to over IPv6 (default: ::1)
+	HostsIncludeWWW         *bool                   `yaml:"hosts_include_www"` // Also write www.<domain> entries (default: true)
+	HostsSubdomains         []string                `yaml:"hosts_subdomains"`  // Subdomains written for include_subdomains domains (default: DefaultHostsSubdomains)
 	SelfHeal                bool                    `yaml:"enable_self_healing"`
 	EnforceInterval         int                     `yaml:"enforce_interval_seconds"`
 	Sudoers                 SudoersConfig           `yaml:"sudoers"`
 	TamperDetection         TamperConfig            `yaml:"tamper_detection"`
 	Accountability          AccountabilityConfig    `yaml:"accountability"`
+	Notifications           NotificationsConfig     `yaml:"notifications"`
 	WebTracking             WebTrackingConfig       `yaml:"web_tracking"`
+	IPC                     IPCConfig               `yaml:"ipc"`
+	ObserverSocket          ObserverSocketConfig    `yaml:"observer_socket"`
 	ContentMonitoring       ContentMonitoringConfig `yaml:"content_monitoring"`
 	ForbiddenPrograms       ForbiddenProgramsConfig `yaml:"forbidden_programs"`
 	ExtensionKeywords       ExtensionKeywordsConfig `yaml:"extension_keywords"`
+	KeywordCategories       []KeywordCategory       `yaml:"keyword_categories"`
 	ViolationTracking       ViolationTrackingConfig `yaml:"violation_tracking"`
 	Unblocking              UnblockingConfig        `yaml:"unblocking"`
+	SelfTest                SelfTestConfig          `yaml:"self_test"`
 	Lifecycle               LifecycleConfig         `yaml:"lifecycle"`
-	MindfulDelay            int                     `yaml:"mindful_delay"` // Seconds
+	Uninstall               UninstallConfig         `yaml:"uninstall"`
+	Focus                   FocusConfig             `yaml:"focus"`
+	Profiles                map[string]Profile      `yaml:"profiles"`
+	MindfulDelay            int                     `yaml:"mindful_delay"`       // Seconds
+	MindfulQuotesFile       string                  `yaml:"mindful_quotes_file"` // Quotes shown during the mindful delay, separated by newlines or blank lines
+	MaxPauseMinutes         int                     `yaml:"max_pause_minutes"`   // Longest allowed pause (0 disables pausing)
 	NotificationCommand     string                  `yaml:"notification_command"`
 	PanicCommand            string                  `yaml:"panic_command"`
+	PanicSchedule           []TimeWindow            `yaml:"panic_schedule"` // Windows during which panic mode is entered automatically
 	Dev                     bool                    `yaml:"dev"`
 	LogLevel                string                  `yaml:"log_level"`
+	LogFile                 string                  `yaml:"log_file"`        // Write glocker's own log here instead of stdout (the journal)
+	LogMaxSizeMB            int                     `yaml:"log_max_size_mb"` // Rotate log_file and the content report log past this size (default: 10)
+	LogMaxFiles             int                     `yaml:"log_max_files"`   // Rotated log files to keep (default: 5)
 }
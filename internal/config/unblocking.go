@@ -0,0 +1,44 @@
+package config
+
+import "time"
+
+// UnblockStateFile returns where granted unblocks are persisted,
+// falling back to DefaultUnblockStateFile.
+func (u UnblockingConfig) UnblockStateFile() string {
+	if u.StateFile == "" {
+		return DefaultUnblockStateFile
+	}
+	return u.StateFile
+}
+
+// MaxUntil returns how far ahead an unblock requested with -until may end
+// (0 = -until is disabled).
+func (u UnblockingConfig) MaxUntil() time.Duration {
+	return time.Duration(u.MaxUntilMinutes) * time.Minute
+}
+
+// ExpiryWarning returns how long before an unblock expires to warn about it (0 = off).
+func (u UnblockingConfig) ExpiryWarning() time.Duration {
+	return time.Duration(u.ExpiryWarningSeconds) * time.Second
+}
+
+// ExpiryGrace returns how long a domain stays reachable after its unblock expires,
+// so pages loading at the moment of expiry can finish.
+func (u UnblockingConfig) ExpiryGrace() time.Duration {
+	return time.Duration(u.GraceSeconds) * time.Second
+}
+
+// UnblockPeriodStart returns when the current daily unblock period began: the most
+// recent reset_time at or before now (midnight if reset_time is unset or invalid).
+func (u UnblockingConfig) UnblockPeriodStart(now time.Time) time.Time {
+	hour, minute := 0, 0
+	if reset, err := time.Parse("15:04", u.ResetTime); err == nil {
+		hour, minute = reset.Hour(), reset.Minute()
+	}
+
+	start := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+	if now.Before(start) {
+		start = time.Date(now.Year(), now.Month(), now.Day()-1, hour, minute, 0, 0, now.Location())
+	}
+	return start
+}
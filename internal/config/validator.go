@@ -3,35 +3,260 @@ package config
 import (
 	"errors"
 	"fmt"
+	"net"
+	"net/mail"
+	"path/filepath"
+	"strings"
 	"time"
 )
 
 // Common validation errors
 var (
-	ErrInvalidTimeWindow  = errors.New("invalid time window")
-	ErrEmptyDomainName    = errors.New("domain name cannot be empty")
-	ErrEmptyProgramName   = errors.New("forbidden program name cannot be empty")
-	ErrEmptyTimeWindowDay = errors.New("time window must specify at least one day")
+	ErrInvalidTimeWindow    = errors.New("invalid time window")
+	ErrEmptyDomainName      = errors.New("domain name cannot be empty")
+	ErrEmptyProgramName     = errors.New("forbidden program name cannot be empty")
+	ErrEmptyTimeWindowDay   = errors.New("time window must specify at least one day")
+	ErrInvalidTimeWindowDay = errors.New("unknown day in time window")
+	ErrInvalidPattern       = errors.New("invalid domain pattern")
+	ErrInvalidPathPattern   = errors.New("invalid path pattern")
 )
 
+// Warnings returns what is likely wrong with a valid config: overlapping time
+// windows, and a web tracking server the browser can't reach.
+func Warnings(cfg *Config) []string {
+	warnings := TimeWindowOverlaps(cfg)
+	if cfg.WebTracking.Enabled || cfg.ContentMonitoring.Enabled {
+		if warning := cfg.WebTracking.InterceptionWarning(); warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+	return warnings
+}
+
 // ValidateConfig validates the entire configuration structure.
 // Returns an error if any configuration field is invalid or missing required values.
 func ValidateConfig(config *Config) error {
+	// Validate the panic schedule, which is useless without a command to enter panic mode with
+	if len(config.PanicSchedule) > 0 && config.PanicCommand == "" {
+		return fmt.Errorf("panic_schedule needs panic_command to suspend the machine with")
+	}
+	for _, window := range config.PanicSchedule {
+		if !isValidTime(window.Start) || !isValidTime(window.End) {
+			return fmt.Errorf("invalid time format in panic_schedule (use HH:MM): %w", ErrInvalidTimeWindow)
+		}
+		if err := validateDays(window.Days); err != nil {
+			return fmt.Errorf("panic_schedule window %s-%s: %w", window.Start, window.End, err)
+		}
+	}
+
 	// Validate domains
-	for _, domain := range config.Domains {
-		if domain.Name == "" {
-			return ErrEmptyDomainName
+	if err := validateDomains(config.Domains); err != nil {
+		return err
+	}
+
+	// Validate profiles
+	for name, profile := range config.Profiles {
+		if err := validateDomains(profile.Domains); err != nil {
+			return fmt.Errorf("profile %s: %w", name, err)
 		}
-		for _, window := range domain.TimeWindows {
+		if profile.TempUnblockTime < 0 {
+			return fmt.Errorf("profile %s: temp_unblock_time cannot be negative", name)
+		}
+	}
+	if escalation := config.ViolationTracking.EscalationProfile; escalation != "" {
+		if _, ok := config.Profiles[escalation]; !ok {
+			return fmt.Errorf("violation_tracking.escalation_profile %q is not defined under profiles", escalation)
+		}
+	}
+	if ratio := config.ViolationTracking.WarnRatio; ratio < 0 || ratio > 1 {
+		return fmt.Errorf("violation_tracking.warn_ratio %v must be between 0.0 and 1.0", ratio)
+	}
+	if config.ViolationTracking.MathProblems < 0 {
+		return fmt.Errorf("violation_tracking.math_problems cannot be negative")
+	}
+	switch strings.ToLower(config.ViolationTracking.MathDifficulty) {
+	case "", "easy", "medium", "hard":
+	default:
+		return fmt.Errorf("violation_tracking.math_difficulty %q must be easy, medium or hard", config.ViolationTracking.MathDifficulty)
+	}
+	if err := validateViolationWeights(config.ViolationTracking.Weights); err != nil {
+		return fmt.Errorf("violation_tracking.weights: %w", err)
+	}
+
+	for i, action := range config.ViolationTracking.Actions {
+		if err := validateViolationAction(config, action); err != nil {
+			return fmt.Errorf("violation_tracking.actions[%d]: %w", i, err)
+		}
+	}
+
+	if config.Unblocking.NewBlockCooldown < 0 {
+		return fmt.Errorf("unblocking.new_block_cooldown cannot be negative")
+	}
+	if config.Unblocking.MaxPerDay < 0 {
+		return fmt.Errorf("unblocking.max_per_day cannot be negative")
+	}
+	if config.Unblocking.ExpiryWarningSeconds < 0 {
+		return fmt.Errorf("unblocking.expiry_warning_seconds cannot be negative")
+	}
+	if config.Unblocking.GraceSeconds < 0 {
+		return fmt.Errorf("unblocking.grace_seconds cannot be negative")
+	}
+	if config.Unblocking.MinReasonLength < 0 {
+		return fmt.Errorf("unblocking.min_reason_length cannot be negative")
+	}
+	if config.Unblocking.MaxUntilMinutes < 0 {
+		return fmt.Errorf("unblocking.max_until_minutes cannot be negative")
+	}
+	if config.Unblocking.ResetTime != "" && !isValidTime(config.Unblocking.ResetTime) {
+		return fmt.Errorf("unblocking.reset_time %q is not a valid time (use HH:MM): %w", config.Unblocking.ResetTime, ErrInvalidTimeWindow)
+	}
+
+	// Validate hosts sink addresses
+	for _, server := range config.DNSServers {
+		if net.ParseIP(server) == nil {
+			return fmt.Errorf("dns_servers: %q is not an IP address", server)
+		}
+	}
+	if config.HostsSinkIPv4 != "" {
+		if ip := net.ParseIP(config.HostsSinkIPv4); ip == nil || ip.To4() == nil {
+			return fmt.Errorf("hosts_sink_ipv4 %q is not a valid IPv4 address", config.HostsSinkIPv4)
+		}
+	}
+	if config.HostsSinkIPv6 != "" {
+		if ip := net.ParseIP(config.HostsSinkIPv6); ip == nil || ip.To4() != nil {
+			return fmt.Errorf("hosts_sink_ipv6 %q is not a valid IPv6 address", config.HostsSinkIPv6)
+		}
+	}
+
+	// Validate web tracking listen addresses
+	if port := config.WebTracking.HTTPPort; port < 0 || port > 65535 {
+		return fmt.Errorf("web_tracking.http_port %d is not a valid port (1-65535)", port)
+	}
+	if port := config.WebTracking.HTTPSPort; port < 0 || port > 65535 {
+		return fmt.Errorf("web_tracking.https_port %d is not a valid port (1-65535)", port)
+	}
+	if config.WebTracking.HTTPListenPort() == config.WebTracking.HTTPSListenPort() {
+		return fmt.Errorf("web_tracking.http_port and https_port must differ (both %d)", config.WebTracking.HTTPListenPort())
+	}
+	if addr := config.WebTracking.BindAddr; addr != "" && net.ParseIP(addr) == nil {
+		return fmt.Errorf("web_tracking.bind_addr %q is not a valid IP address", addr)
+	}
+
+	if path := config.IPC.Socket; path != "" && !filepath.IsAbs(path) {
+		return fmt.Errorf("ipc.socket_path %q must be an absolute path", path)
+	}
+	if path := config.IPC.AuthKey; path != "" && !filepath.IsAbs(path) {
+		return fmt.Errorf("ipc.auth_key %q must be an absolute path", path)
+	}
+
+	// The observer socket must not replace the control socket
+	controlSocket := filepath.Clean(config.IPC.SocketPath())
+	if config.ObserverSocket.Enabled && filepath.Clean(config.ObserverSocket.SocketPath()) == controlSocket {
+		return fmt.Errorf("observer_socket.path must differ from the control socket %s", controlSocket)
+	}
+
+	// Validate keyword categories
+	categoryNames := make(map[string]bool)
+	for _, category := range config.KeywordCategories {
+		if category.Name == "" {
+			return fmt.Errorf("keyword_categories: name cannot be empty")
+		}
+		if categoryNames[category.Name] {
+			return fmt.Errorf("keyword_categories: duplicate category %q", category.Name)
+		}
+		categoryNames[category.Name] = true
+		for _, window := range category.TimeWindows {
 			if !isValidTime(window.Start) || !isValidTime(window.End) {
-				return fmt.Errorf("invalid time format for domain %s (use HH:MM): %w", domain.Name, ErrInvalidTimeWindow)
+				return fmt.Errorf("keyword category %s: invalid time format (use HH:MM): %w", category.Name, ErrInvalidTimeWindow)
+			}
+			if err := validateDays(window.Days); err != nil {
+				return fmt.Errorf("keyword category %s: %w", category.Name, err)
+			}
+		}
+	}
+
+	if config.ContentMonitoring.DedupWindow() < 0 {
+		return fmt.Errorf("content_monitoring.dedup_seconds cannot be negative")
+	}
+
+	// Validate tamper detection
+	if config.TamperDetection.DebounceSeconds < 0 {
+		return fmt.Errorf("tamper_detection.debounce_seconds cannot be negative")
+	}
+	for _, window := range config.TamperDetection.AllowedMaintenanceWindows {
+		if !isValidTime(window.Start) || !isValidTime(window.End) {
+			return fmt.Errorf("tamper_detection.allowed_maintenance_windows: invalid time format (use HH:MM): %w", ErrInvalidTimeWindow)
+		}
+		if err := validateDays(window.Days); err != nil {
+			return fmt.Errorf("tamper_detection.allowed_maintenance_windows: %w", err)
+		}
+	}
+
+	// Validate firewall port rules
+	for _, rule := range config.FirewallRules {
+		if rule.Port < 1 || rule.Port > 65535 {
+			return fmt.Errorf("firewall_rules: port %d is out of range", rule.Port)
+		}
+		switch strings.ToLower(rule.Protocol) {
+		case "", "both", "tcp", "udp":
+		default:
+			return fmt.Errorf("firewall_rules: protocol %q for port %d is not supported (use tcp, udp or both)", rule.Protocol, rule.Port)
+		}
+		for _, window := range rule.TimeWindows {
+			if !isValidTime(window.Start) || !isValidTime(window.End) {
+				return fmt.Errorf("firewall rule for port %d: invalid time format (use HH:MM): %w", rule.Port, ErrInvalidTimeWindow)
 			}
-			if len(window.Days) == 0 {
-				return fmt.Errorf("time window for %s: %w", domain.Name, ErrEmptyTimeWindowDay)
+			if err := validateDays(window.Days); err != nil {
+				return fmt.Errorf("firewall rule for port %d: %w", rule.Port, err)
 			}
 		}
 	}
 
+	// Validate allowlist mode
+	if err := validateDomains(config.AllowlistMode.AllowedDomains); err != nil {
+		return fmt.Errorf("allowlist_mode: %w", err)
+	}
+	if config.AllowlistMode.Enabled && len(config.AllowlistMode.AllowedDomains) == 0 {
+		return fmt.Errorf("allowlist_mode: allowed_domains cannot be empty when enabled")
+	}
+	for _, window := range config.AllowlistMode.TimeWindows {
+		if !isValidTime(window.Start) || !isValidTime(window.End) {
+			return fmt.Errorf("allowlist_mode: invalid time format (use HH:MM): %w", ErrInvalidTimeWindow)
+		}
+		if err := validateDays(window.Days); err != nil {
+			return fmt.Errorf("allowlist_mode: %w", err)
+		}
+	}
+
+	if config.Uninstall.CooldownMinutes < 0 {
+		return fmt.Errorf("uninstall.cooldown_minutes cannot be negative")
+	}
+	for _, domain := range config.Focus.DistractionDomains {
+		if strings.TrimSpace(domain) == "" {
+			return fmt.Errorf("focus.distraction_domains: %w", ErrEmptyDomainName)
+		}
+	}
+
+	// Validate remote blocklists
+	for _, blocklist := range config.RemoteBlocklists {
+		if blocklist.URL == "" {
+			return fmt.Errorf("remote_blocklists: url cannot be empty")
+		}
+		if !strings.HasPrefix(blocklist.URL, "http://") && !strings.HasPrefix(blocklist.URL, "https://") {
+			return fmt.Errorf("remote_blocklists: url %s must use http or https", blocklist.URL)
+		}
+		if blocklist.RefreshHours < 0 {
+			return fmt.Errorf("remote_blocklists: refresh_hours for %s cannot be negative", blocklist.URL)
+		}
+	}
+
+	// Validate extra DNS-over-HTTPS resolvers
+	for _, domain := range config.DoHExtraDomains {
+		if strings.TrimSpace(domain) == "" || strings.ContainsAny(domain, " /:") {
+			return fmt.Errorf("doh_extra_domains: %q is not a domain name", domain)
+		}
+	}
+
 	// Validate sudoers config
 	if config.Sudoers.Enabled {
 		if config.Sudoers.User == "" {
@@ -47,10 +272,62 @@ func ValidateConfig(config *Config) error {
 			if !isValidTime(window.Start) || !isValidTime(window.End) {
 				return fmt.Errorf("invalid time format in sudoers time_allowed (use HH:MM): %w", ErrInvalidTimeWindow)
 			}
-			if len(window.Days) == 0 {
-				return fmt.Errorf("sudoers time_allowed window: %w", ErrEmptyTimeWindowDay)
+			if err := validateDays(window.Days); err != nil {
+				return fmt.Errorf("sudoers time_allowed window: %w", err)
+			}
+		}
+	}
+
+	// Validate block self-test settings
+	if config.SelfTest.SampleSize < 0 {
+		return fmt.Errorf("self_test.sample_size cannot be negative")
+	}
+	if config.SelfTest.IntervalMinutes < 0 {
+		return fmt.Errorf("self_test.interval_minutes cannot be negative")
+	}
+
+	if config.MaxPauseMinutes < 0 {
+		return fmt.Errorf("max_pause_minutes cannot be negative")
+	}
+
+	// Validate log rotation
+	if config.LogMaxSizeMB < 0 {
+		return fmt.Errorf("log_max_size_mb cannot be negative")
+	}
+	if config.LogMaxFiles < 0 {
+		return fmt.Errorf("log_max_files cannot be negative")
+	}
+
+	// Validate accountability email provider
+	if config.Accountability.Enabled {
+		switch strings.ToLower(config.Accountability.Provider) {
+		case "", "mailgun":
+		case "smtp":
+			if config.Accountability.SMTPHost == "" {
+				return fmt.Errorf("accountability.smtp_host cannot be empty when provider is smtp")
+			}
+			if config.Accountability.SMTPPort < 0 || config.Accountability.SMTPPort > 65535 {
+				return fmt.Errorf("accountability.smtp_port %d is out of range", config.Accountability.SMTPPort)
+			}
+		default:
+			return fmt.Errorf("accountability.provider %q is not supported (use mailgun or smtp)", config.Accountability.Provider)
+		}
+		for _, address := range config.Accountability.Recipients() {
+			if _, err := mail.ParseAddress(address); err != nil {
+				return fmt.Errorf("accountability: invalid partner email %q: %w", address, err)
 			}
 		}
+		if config.Accountability.EmailMaxRetries() < 0 {
+			return fmt.Errorf("accountability.max_retries cannot be negative")
+		}
+	}
+	if err := validateNotifications(config.Notifications); err != nil {
+		return err
+	}
+	if config.Accountability.WeeklyReportEnabled {
+		if _, _, err := config.Accountability.WeeklyReportSchedule(); err != nil {
+			return fmt.Errorf("accountability: %w", err)
+		}
 	}
 
 	// Validate forbidden programs config
@@ -59,12 +336,15 @@ func ValidateConfig(config *Config) error {
 			if program.Name == "" {
 				return ErrEmptyProgramName
 			}
+			if err := validateForbiddenProgramMatch(program); err != nil {
+				return err
+			}
 			for _, window := range program.TimeWindows {
 				if !isValidTime(window.Start) || !isValidTime(window.End) {
 					return fmt.Errorf("invalid time format for forbidden program %s (use HH:MM): %w", program.Name, ErrInvalidTimeWindow)
 				}
-				if len(window.Days) == 0 {
-					return fmt.Errorf("time window for forbidden program %s: %w", program.Name, ErrEmptyTimeWindowDay)
+				if err := validateDays(window.Days); err != nil {
+					return fmt.Errorf("time window for forbidden program %s: %w", program.Name, err)
 				}
 			}
 		}
@@ -73,6 +353,56 @@ func ValidateConfig(config *Config) error {
 	return nil
 }
 
+// validateDomains checks domain names, patterns and time windows.
+func validateDomains(domains []Domain) error {
+	for _, domain := range domains {
+		if domain.Name == "" {
+			return ErrEmptyDomainName
+		}
+		if domain.Pattern {
+			if _, err := compilePattern(domain.Name); err != nil {
+				return fmt.Errorf("pattern %q: %v: %w", domain.Name, err, ErrInvalidPattern)
+			}
+		}
+		if domain.UnblockMinutes < 0 {
+			return fmt.Errorf("unblock_minutes for domain %s cannot be negative", domain.Name)
+		}
+		if domain.IncludeSubdomains && (domain.ExactOnly || domain.Pattern) {
+			return fmt.Errorf("include_subdomains for domain %s can't be combined with exact_only or pattern", domain.Name)
+		}
+		switch domain.BlockStyle {
+		case "", BlockStylePage, BlockStyleRefused:
+		default:
+			return fmt.Errorf("block_style %q for domain %s is not supported (use %s or %s)", domain.BlockStyle, domain.Name, BlockStylePage, BlockStyleRefused)
+		}
+		for _, pattern := range domain.PathPatterns {
+			if err := validatePathPattern(pattern); err != nil {
+				return fmt.Errorf("path pattern %q for domain %s: %v: %w", pattern, domain.Name, err, ErrInvalidPathPattern)
+			}
+		}
+		for _, window := range domain.TimeWindows {
+			if !isValidTime(window.Start) || !isValidTime(window.End) {
+				return fmt.Errorf("invalid time format for domain %s (use HH:MM): %w", domain.Name, ErrInvalidTimeWindow)
+			}
+			if err := validateDays(window.Days); err != nil {
+				return fmt.Errorf("time window for %s: %w", domain.Name, err)
+			}
+		}
+		if len(domain.AbsoluteWindows) > 0 && !domain.Unblockable {
+			return fmt.Errorf("absolute_windows for domain %s only apply to unblockable domains", domain.Name)
+		}
+		for _, window := range domain.AbsoluteWindows {
+			if !isValidTime(window.Start) || !isValidTime(window.End) {
+				return fmt.Errorf("invalid absolute window time format for domain %s (use HH:MM): %w", domain.Name, ErrInvalidTimeWindow)
+			}
+			if err := validateDays(window.Days); err != nil {
+				return fmt.Errorf("absolute window for %s: %w", domain.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
 // isValidTime checks if a time string is in valid HH:MM format.
 func isValidTime(timeStr string) bool {
 	_, err := time.Parse("15:04", timeStr)
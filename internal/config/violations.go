@@ -0,0 +1,77 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Types of violation_tracking.actions.
+const (
+	ViolationActionCommand = "command" // Run command with args
+	ViolationActionLock    = "lock"    // Lock the screen with a mindful_text passage
+	ViolationActionSuspend = "suspend" // Suspend the system
+	ViolationActionNotify  = "notify"  // Email the accountability partner
+)
+
+// Types of recorded violations, the keys of violation_tracking.weights.
+const (
+	ViolationWebAccess        = "web_access"        // Visit to a blocked domain
+	ViolationContentReport    = "content_report"    // Keyword match reported by the browser extension
+	ViolationForbiddenProgram = "forbidden_program" // Forbidden program started
+)
+
+// Weight returns how much a violation of the given type counts toward
+// max_violations: its entry in weights, or 1.
+func (v ViolationTrackingConfig) Weight(violationType string) int {
+	if weight, ok := v.Weights[violationType]; ok {
+		return weight
+	}
+	return 1
+}
+
+// validateViolationWeights checks that weights only name known violation types
+// and none is negative.
+func validateViolationWeights(weights map[string]int) error {
+	for violationType, weight := range weights {
+		switch violationType {
+		case ViolationWebAccess, ViolationContentReport, ViolationForbiddenProgram:
+		default:
+			return fmt.Errorf("unknown violation type %q (must be web_access, content_report or forbidden_program)", violationType)
+		}
+		if weight < 0 {
+			return fmt.Errorf("%s cannot be negative", violationType)
+		}
+	}
+	return nil
+}
+
+// IsEnabled reports whether the action runs (enabled is true unless set false).
+func (a ViolationAction) IsEnabled() bool {
+	return a.Enabled == nil || *a.Enabled
+}
+
+// validateViolationAction checks an action's type and that it has what it needs
+// to run.
+func validateViolationAction(cfg *Config, action ViolationAction) error {
+	switch action.Type {
+	case ViolationActionCommand:
+		if strings.TrimSpace(action.Command) == "" {
+			return fmt.Errorf("command action needs a command")
+		}
+	case ViolationActionLock:
+		if strings.TrimSpace(cfg.ViolationTracking.MindfulText) == "" {
+			return fmt.Errorf("lock action needs violation_tracking.mindful_text")
+		}
+	case ViolationActionSuspend:
+		if action.Command == "" && cfg.PanicCommand == "" {
+			return fmt.Errorf("suspend action needs a command or panic_command")
+		}
+	case ViolationActionNotify:
+		if !cfg.NotifiesPartner() {
+			return fmt.Errorf("notify action needs accountability.enabled or notifications.webhook_url")
+		}
+	default:
+		return fmt.Errorf("type %q must be command, lock, suspend or notify", action.Type)
+	}
+	return nil
+}
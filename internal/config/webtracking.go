@@ -0,0 +1,66 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// HTTPListenPort returns the port the web tracking HTTP server listens on.
+func (w WebTrackingConfig) HTTPListenPort() int {
+	if w.HTTPPort == 0 {
+		return DefaultWebHTTPPort
+	}
+	return w.HTTPPort
+}
+
+// HTTPSListenPort returns the port the web tracking HTTPS server listens on.
+func (w WebTrackingConfig) HTTPSListenPort() int {
+	if w.HTTPSPort == 0 {
+		return DefaultWebHTTPSPort
+	}
+	return w.HTTPSPort
+}
+
+// HTTPAddr returns the listen address of the web tracking HTTP server.
+func (w WebTrackingConfig) HTTPAddr() string {
+	return net.JoinHostPort(w.BindAddr, strconv.Itoa(w.HTTPListenPort()))
+}
+
+// HTTPSAddr returns the listen address of the web tracking HTTPS server.
+func (w WebTrackingConfig) HTTPSAddr() string {
+	return net.JoinHostPort(w.BindAddr, strconv.Itoa(w.HTTPSListenPort()))
+}
+
+// BaseURL returns the URL the browser reaches the web tracking HTTP server at, such
+// as "http://127.0.0.1" or "http://127.0.0.1:8080". Loopback is used unless the
+// server is bound to one specific address.
+func (w WebTrackingConfig) BaseURL() string {
+	host := "127.0.0.1"
+	if ip := net.ParseIP(w.BindAddr); ip != nil && !ip.IsUnspecified() {
+		host = w.BindAddr
+	}
+
+	if port := w.HTTPListenPort(); port != 80 {
+		return "http://" + net.JoinHostPort(host, strconv.Itoa(port))
+	}
+	if ip := net.ParseIP(host); ip.To4() == nil {
+		return "http://[" + host + "]" // IPv6
+	}
+	return "http://" + host
+}
+
+// InterceptionWarning explains what breaks when the HTTP server can't be reached
+// at http://127.0.0.1 on port 80: the browser extension can't read the config
+// and always talks to that address, and blocked domains only arrive there
+// through the hosts file. Empty when the server is reachable there.
+func (w WebTrackingConfig) InterceptionWarning() string {
+	port := w.HTTPListenPort()
+	ip := net.ParseIP(w.BindAddr)
+	loopbackReachable := w.BindAddr == "" || (ip != nil && (ip.IsUnspecified() || ip.Equal(net.IPv4(127, 0, 0, 1))))
+	if port == 80 && loopbackReachable {
+		return ""
+	}
+	return fmt.Sprintf("web_tracking listens on %s, but the browser extension and blocked domains only reach http://127.0.0.1 on port 80: "+
+		"the extension can't fetch keywords, check paths or send reports, and visits to blocked domains aren't recorded", w.HTTPAddr())
+}
@@ -0,0 +1,134 @@
+package enforcement
+
+import (
+	"log/slog"
+	"net"
+	"os/exec"
+	"slices"
+	"time"
+
+	"glocker/internal/config"
+	"glocker/internal/notify"
+	"glocker/internal/utils"
+)
+
+// IsAllowlistActive reports whether allowlist mode is blocking everything off
+// the allowlist at now: it is enabled and one of its time windows is active
+// (or it has none).
+func IsAllowlistActive(cfg *config.Config, now time.Time) bool {
+	mode := cfg.AllowlistMode
+	if !mode.Enabled {
+		return false
+	}
+	if len(mode.TimeWindows) == 0 {
+		return true
+	}
+	return config.AnyWindowActive(mode.TimeWindows, now)
+}
+
+// allowlistRuleArgs returns the arguments of the rules that turn OUTPUT into
+// default-deny: accept loopback, replies to established connections, DHCP
+// (and ICMPv6 for ipv6, which neighbour discovery needs), DNS to the resolvers
+// and the allowed addresses, then reject the rest. The rules are appended, so
+// glocker's REJECT rules inserted at the top of the chain still win over the
+// allowlist.
+func allowlistRuleArgs(ipv6 bool, resolvers, allowedIPs []string) [][]string {
+	comment := []string{"-m", "comment", "--comment", FirewallRuleMarker}
+	rule := func(args ...string) []string {
+		return append(append([]string{"-A", "OUTPUT"}, args...), comment...)
+	}
+
+	result := [][]string{
+		rule("-o", "lo", "-j", "ACCEPT"),
+		rule("-m", "conntrack", "--ctstate", "ESTABLISHED,RELATED", "-j", "ACCEPT"),
+	}
+	if ipv6 {
+		result = append(result,
+			rule("-p", "ipv6-icmp", "-j", "ACCEPT"),
+			rule("-p", "udp", "--dport", "547", "-j", "ACCEPT"))
+	} else {
+		result = append(result, rule("-p", "udp", "--dport", "67", "-j", "ACCEPT"))
+	}
+	for _, resolver := range resolvers {
+		result = append(result,
+			rule("-d", resolver, "-p", "udp", "--dport", "53", "-j", "ACCEPT"),
+			rule("-d", resolver, "-p", "tcp", "--dport", "53", "-j", "ACCEPT"))
+	}
+	for _, ip := range allowedIPs {
+		result = append(result, rule("-d", ip, "-j", "ACCEPT"))
+	}
+	return append(result, rule("-j", "REJECT"))
+}
+
+// allowedHosts returns the hosts resolved for the allowlist firewall rules: each
+// allowed domain and its www. form, and the hosts alerts are delivered to, so
+// accountability emails and webhooks still go out. Pattern domains can't be
+// resolved and are left to the web tracking interceptor.
+func allowedHosts(cfg *config.Config) []string {
+	var hosts []string
+	for _, domain := range cfg.AllowlistMode.AllowedDomains {
+		if domain.Pattern {
+			continue
+		}
+		hosts = append(hosts, domain.Name, "www."+domain.Name)
+	}
+	return append(hosts, notify.DeliveryHosts(cfg)...)
+}
+
+// familyAddresses returns the addresses in ips of the given family.
+func familyAddresses(ips []string, ipv6 bool) []string {
+	var result []string
+	for _, ip := range ips {
+		parsed := net.ParseIP(ip)
+		if parsed != nil && (parsed.To4() == nil) == ipv6 {
+			result = append(result, ip)
+		}
+	}
+	return result
+}
+
+// BlockAllButAllowlist adds the default-deny firewall rules of allowlist mode
+// when it is active at now. Like BlockPorts, the rules carry the glocker marker,
+// so UpdateFirewall clears them; call it after every firewall update. Returns
+// whether allowlist mode was applied.
+func BlockAllButAllowlist(cfg *config.Config, now time.Time, dryRun bool) bool {
+	if !IsAllowlistActive(cfg, now) {
+		return false
+	}
+	if dryRun {
+		slog.Debug("Dry run mode - would block everything off the allowlist", "allowed_domains", len(cfg.AllowlistMode.AllowedDomains))
+		return true
+	}
+
+	caps := GetCapabilities()
+	families := []struct {
+		tool, recordType string
+		ipv6, available  bool
+	}{
+		{"iptables", "A", false, caps.Iptables},
+		{"ip6tables", "AAAA", true, caps.Ip6tables},
+	}
+	hosts := allowedHosts(cfg)
+	literals := slices.DeleteFunc(slices.Clone(hosts), func(host string) bool { return !utils.IsIPAddress(host) })
+	hosts = slices.DeleteFunc(hosts, utils.IsIPAddress)
+	for _, family := range families {
+		if !family.available {
+			continue
+		}
+
+		setNameservers(cfg)
+		ips := familyAddresses(literals, family.ipv6)
+		for _, hostIPs := range dnsCache.ResolveAll(hosts, family.recordType, now) {
+			ips = append(ips, firewallAddresses(cfg, hostIPs)...)
+		}
+		slices.Sort(ips)
+		ips = slices.Compact(ips)
+
+		for _, ruleArgs := range allowlistRuleArgs(family.ipv6, familyAddresses(cfg.Nameservers(), family.ipv6), ips) {
+			if err := exec.Command(family.tool, ruleArgs...).Run(); err != nil {
+				slog.Debug("Failed to add allowlist firewall rule", "tool", family.tool, "args", ruleArgs, "error", err)
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,229 @@
+package enforcement
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"glocker/internal/config"
+)
+
+// defaultBlocklistRefreshHours is used when a remote blocklist doesn't set refresh_hours.
+const defaultBlocklistRefreshHours = 24
+
+// blocklistHTTPClient is used for fetching remote blocklists.
+var blocklistHTTPClient = &http.Client{Timeout: 2 * time.Minute}
+
+// RefreshRemoteBlocklists fetches every configured remote blocklist whose cached copy
+// is missing or older than its refresh interval (or all of them when force is set),
+// storing the parsed domains under cacheDir.
+// Returns true if any cached list changed. A failed fetch keeps the previous snapshot.
+func RefreshRemoteBlocklists(cfg *config.Config, cacheDir string, force bool) bool {
+	changed := false
+	now := clock.Now()
+
+	for _, blocklist := range cfg.RemoteBlocklists {
+		cachePath := blocklistCachePath(cacheDir, blocklist.URL)
+
+		if !force && !isBlocklistStale(cachePath, blocklist, now) {
+			slog.Debug("Remote blocklist cache is fresh", "url", blocklist.URL, "cache", cachePath)
+			continue
+		}
+
+		domains, err := fetchRemoteBlocklist(blocklist.URL)
+		if err != nil {
+			log.Printf("Failed to fetch remote blocklist %s (keeping cached copy): %v", blocklist.URL, err)
+			continue
+		}
+
+		updated, err := writeBlocklistCache(cachePath, domains)
+		if err != nil {
+			log.Printf("Failed to cache remote blocklist %s: %v", blocklist.URL, err)
+			continue
+		}
+		if updated {
+			changed = true
+			log.Printf("Remote blocklist updated: %s (%d domains)", blocklist.URL, len(domains))
+		} else {
+			slog.Debug("Remote blocklist unchanged", "url", blocklist.URL, "domains", len(domains))
+		}
+	}
+
+	return changed
+}
+
+// MergeRemoteBlocklists adds the cached domains of every configured remote blocklist
+// to cfg.Domains as always-blocked (permanent) domains.
+// Domains already present in the config or in an earlier list are skipped.
+// Returns the number of domains added.
+func MergeRemoteBlocklists(cfg *config.Config, cacheDir string) int {
+	if len(cfg.RemoteBlocklists) == 0 {
+		return 0
+	}
+
+	seen := make(map[string]bool, len(cfg.Domains))
+	for _, domain := range cfg.Domains {
+		if !domain.Pattern {
+			seen[domain.Name] = true
+		}
+	}
+
+	added := 0
+	for _, blocklist := range cfg.RemoteBlocklists {
+		cachePath := blocklistCachePath(cacheDir, blocklist.URL)
+		domains, err := readBlocklistCache(cachePath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				log.Printf("No cached copy of remote blocklist %s yet", blocklist.URL)
+			} else {
+				log.Printf("Failed to read cached remote blocklist %s: %v", blocklist.URL, err)
+			}
+			continue
+		}
+
+		for _, domain := range domains {
+			if seen[domain] {
+				continue
+			}
+			seen[domain] = true
+			cfg.Domains = append(cfg.Domains, config.Domain{Name: domain})
+			added++
+		}
+	}
+
+	slog.Debug("Merged remote blocklists", "lists", len(cfg.RemoteBlocklists), "added_domains", added)
+	return added
+}
+
+// MonitorRemoteBlocklists periodically refreshes remote blocklists and forces a
+// full enforcement cycle when any of them changed.
+func MonitorRemoteBlocklists(cfg *config.Config) {
+	ticker := time.NewTicker(15 * time.Minute)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if len(cfg.RemoteBlocklists) == 0 {
+			continue
+		}
+		if RefreshRemoteBlocklists(cfg, config.BlocklistCacheDir, false) {
+			log.Println("Remote blocklists changed - re-applying enforcement")
+			ForceEnforcement(cfg)
+		}
+	}
+}
+
+// fetchRemoteBlocklist downloads a blocklist (hosts, ABP or plain format) and returns
+// the domains it lists. Gzip-compressed bodies are decompressed transparently.
+func fetchRemoteBlocklist(url string) ([]string, error) {
+	slog.Debug("Fetching remote blocklist", "url", url)
+
+	resp, err := blocklistHTTPClient.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("fetching blocklist: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching blocklist: unexpected HTTP status %s", resp.Status)
+	}
+
+	// The transport already decodes Content-Encoding: gzip; this handles .gz files
+	// and servers that mislabel the encoding.
+	body := bufio.NewReader(resp.Body)
+	var reader io.Reader = body
+	if magic, err := body.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, fmt.Errorf("decompressing blocklist: %w", err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	// Read the whole body first so a truncated download isn't mistaken for a short list
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("reading blocklist: %w", err)
+	}
+
+	domains, err := config.ParseBlocklist(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("blocklist contained no domains")
+	}
+
+	return domains, nil
+}
+
+// blocklistCachePath returns the on-disk cache location for a blocklist URL.
+func blocklistCachePath(cacheDir, url string) string {
+	hash := sha256.Sum256([]byte(url))
+	return filepath.Join(cacheDir, hex.EncodeToString(hash[:8])+".list")
+}
+
+// isBlocklistStale reports whether a cached blocklist should be re-fetched.
+func isBlocklistStale(cachePath string, blocklist config.RemoteBlocklist, now time.Time) bool {
+	info, err := os.Stat(cachePath)
+	if err != nil {
+		return true
+	}
+
+	refreshHours := blocklist.RefreshHours
+	if refreshHours <= 0 {
+		refreshHours = defaultBlocklistRefreshHours
+	}
+	return now.Sub(info.ModTime()) >= time.Duration(refreshHours)*time.Hour
+}
+
+// writeBlocklistCache stores domains one per line at cachePath.
+// Returns true if the content differs from the previous snapshot.
+func writeBlocklistCache(cachePath string, domains []string) (bool, error) {
+	if err := os.MkdirAll(filepath.Dir(cachePath), 0755); err != nil {
+		return false, fmt.Errorf("creating cache directory: %w", err)
+	}
+
+	content := []byte(strings.Join(domains, "\n") + "\n")
+	previous, err := os.ReadFile(cachePath)
+	changed := err != nil || !bytes.Equal(previous, content)
+
+	// Write to a temp file and rename so readers never see a partial list
+	tmpPath := cachePath + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0644); err != nil {
+		return false, fmt.Errorf("writing cache file: %w", err)
+	}
+	if err := os.Rename(tmpPath, cachePath); err != nil {
+		os.Remove(tmpPath)
+		return false, fmt.Errorf("replacing cache file: %w", err)
+	}
+
+	return changed, nil
+}
+
+// readBlocklistCache reads a cached blocklist written by writeBlocklistCache.
+func readBlocklistCache(cachePath string) ([]string, error) {
+	data, err := os.ReadFile(cachePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var domains []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			domains = append(domains, line)
+		}
+	}
+	return domains, nil
+}
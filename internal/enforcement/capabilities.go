@@ -0,0 +1,138 @@
+package enforcement
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"glocker/internal/config"
+	"glocker/internal/notify"
+)
+
+// Capabilities records which external tools used by enforcement are available.
+// Enforcement steps that need a missing tool are skipped instead of failing every cycle.
+type Capabilities struct {
+	Chattr    bool // Immutable flags on the hosts file and glocker binary
+	Iptables  bool // IPv4 firewall rules
+	Ip6tables bool // IPv6 firewall rules
+	Visudo    bool // Validating sudoers changes before they are written
+}
+
+// allCapabilities assumes every tool is available, until ProbeCapabilities says otherwise.
+var allCapabilities = Capabilities{Chattr: true, Iptables: true, Ip6tables: true, Visudo: true}
+
+var (
+	capabilitiesMu sync.RWMutex
+	capabilities   = allCapabilities
+)
+
+// capabilityTool describes a tool checked by ProbeCapabilities.
+type capabilityTool struct {
+	name      string
+	available func(c Capabilities) bool
+	degrades  string                        // Protection lost without the tool
+	relevant  func(cfg *config.Config) bool // Whether the protection is enabled
+}
+
+var capabilityTools = []capabilityTool{
+	{
+		name:      "chattr",
+		available: func(c Capabilities) bool { return c.Chattr },
+		degrades:  "the hosts file and glocker binary can't be made immutable",
+		relevant:  func(cfg *config.Config) bool { return cfg.EnableHosts || cfg.SelfHeal },
+	},
+	{
+		name:      "iptables",
+		available: func(c Capabilities) bool { return c.Iptables },
+		degrades:  "IPv4 firewall blocking is disabled",
+		relevant:  func(cfg *config.Config) bool { return cfg.EnableFirewall },
+	},
+	{
+		name:      "ip6tables",
+		available: func(c Capabilities) bool { return c.Ip6tables },
+		degrades:  "IPv6 firewall blocking is disabled",
+		relevant:  func(cfg *config.Config) bool { return cfg.EnableFirewall },
+	},
+	{
+		name:      "visudo",
+		available: func(c Capabilities) bool { return c.Visudo },
+		degrades:  "sudoers restrictions are disabled (changes can't be validated)",
+		relevant:  func(cfg *config.Config) bool { return cfg.Sudoers.Enabled },
+	},
+}
+
+// ProbeCapabilities checks which tools can be found with lookPath (exec.LookPath at runtime).
+func ProbeCapabilities(lookPath func(file string) (string, error)) Capabilities {
+	found := func(name string) bool {
+		_, err := lookPath(name)
+		return err == nil
+	}
+	return Capabilities{
+		Chattr:    found("chattr"),
+		Iptables:  found("iptables"),
+		Ip6tables: found("ip6tables"),
+		Visudo:    found("visudo"),
+	}
+}
+
+// Degraded lists the missing tools needed by the protections enabled in cfg,
+// one "tool: consequence" line each.
+func (c Capabilities) Degraded(cfg *config.Config) []string {
+	var lines []string
+	for _, tool := range capabilityTools {
+		if !tool.available(c) && tool.relevant(cfg) {
+			lines = append(lines, fmt.Sprintf("%s: %s", tool.name, tool.degrades))
+		}
+	}
+	return lines
+}
+
+// GetCapabilities returns the tools found at startup (all of them if never probed).
+func GetCapabilities() Capabilities {
+	capabilitiesMu.RLock()
+	defer capabilitiesMu.RUnlock()
+	return capabilities
+}
+
+// SetCapabilities replaces the recorded capabilities.
+func SetCapabilities(c Capabilities) {
+	capabilitiesMu.Lock()
+	defer capabilitiesMu.Unlock()
+	capabilities = c
+}
+
+// CheckCapabilities probes for the external tools enforcement relies on and records
+// the result. Missing tools needed by enabled protections are logged as a warning and,
+// with accountability enabled, reported by email once.
+func CheckCapabilities(cfg *config.Config) {
+	caps := ProbeCapabilities(exec.LookPath)
+	SetCapabilities(caps)
+	slog.Debug("Probed enforcement capabilities", "capabilities", fmt.Sprintf("%+v", caps))
+
+	degraded := caps.Degraded(cfg)
+	if len(degraded) == 0 {
+		return
+	}
+
+	log.Println("WARNING: ========================================")
+	log.Println("WARNING: Missing tools, some protections are degraded:")
+	for _, line := range degraded {
+		log.Printf("WARNING:   %s", line)
+	}
+	log.Println("WARNING: ========================================")
+
+	if cfg.NotifiesPartner() {
+		subject := "GLOCKER ALERT: Protections Degraded"
+		body := fmt.Sprintf("Glocker started at %s without some of the tools it needs:\n\n", clock.Now().Format("2006-01-02 15:04:05"))
+		body += strings.Join(degraded, "\n")
+		body += "\n\nThese protections stay disabled until the tools are installed and glocker is restarted."
+		body += "\n\nThis is an automated alert from Glocker."
+
+		if err := notify.SendEmail(cfg, notify.EventProtectionsDegraded, subject, body); err != nil {
+			log.Printf("Failed to send degraded protections email: %v", err)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package enforcement
+
+import (
+	"log/slog"
+	"os/exec"
+	"strings"
+
+	"glocker/internal/config"
+)
+
+// DoTPort is the port of DNS-over-TLS (TCP) and DNS-over-QUIC (UDP).
+const DoTPort = "853"
+
+// dohDomains are well-known public DNS-over-HTTPS resolvers. Browsers that use one
+// of them resolve names themselves and never look at the hosts file. The hosts file
+// matches names exactly, so the subdomains browsers use are listed as well.
+var dohDomains = []string{
+	// Cloudflare
+	"cloudflare-dns.com",
+	"mozilla.cloudflare-dns.com",
+	"chrome.cloudflare-dns.com",
+	"security.cloudflare-dns.com",
+	"family.cloudflare-dns.com",
+	"1dot1dot1dot1.cloudflare-dns.com",
+	"one.one.one.one",
+	// Google
+	"dns.google",
+	"dns.google.com",
+	"dns64.dns.google",
+	"8888.google",
+	// Quad9
+	"dns.quad9.net",
+	"dns9.quad9.net",
+	"dns10.quad9.net",
+	"dns11.quad9.net",
+	// OpenDNS / Cisco
+	"doh.opendns.com",
+	"doh.familyshield.opendns.com",
+	"doh.umbrella.com",
+	// AdGuard
+	"dns.adguard.com",
+	"dns.adguard-dns.com",
+	"dns-family.adguard.com",
+	"family.adguard-dns.com",
+	"unfiltered.adguard-dns.com",
+	// NextDNS
+	"dns.nextdns.io",
+	"firefox.dns.nextdns.io",
+	// Control D
+	"freedns.controld.com",
+	"dns.controld.com",
+	// Mullvad
+	"dns.mullvad.net",
+	"doh.mullvad.net",
+	"adblock.dns.mullvad.net",
+	"base.dns.mullvad.net",
+	"family.dns.mullvad.net",
+	// CleanBrowsing
+	"doh.cleanbrowsing.org",
+	// Comcast
+	"doh.xfinity.com",
+	// Others
+	"dns0.eu",
+	"doh.dns.sb",
+	"doh.libredns.gr",
+	"doh.ffmuc.net",
+	"dns.switch.ch",
+	"dns.digitale-gesellschaft.ch",
+	"doh.applied-privacy.net",
+	"odvr.nic.cz",
+	"ordns.he.net",
+	"dns.alidns.com",
+	"doh.pub",
+	"doh.360.cn",
+}
+
+// DoHDomains returns the resolvers block_doh blocks: the built-in list followed by
+// doh_extra_domains, without duplicates.
+func DoHDomains(cfg *config.Config) []string {
+	seen := make(map[string]bool, len(dohDomains)+len(cfg.DoHExtraDomains))
+	var domains []string
+	for _, domain := range append(append([]string{}, dohDomains...), cfg.DoHExtraDomains...) {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain == "" || seen[domain] {
+			continue
+		}
+		seen[domain] = true
+		domains = append(domains, domain)
+	}
+	return domains
+}
+
+// MergeDoHDomains adds the DNS-over-HTTPS resolvers to cfg.Domains as always-blocked
+// (permanent) domains when block_doh is on. Domains already in the config are
+// skipped, so a resolver listed there keeps its own settings.
+// Returns the number of domains added.
+func MergeDoHDomains(cfg *config.Config) int {
+	if !cfg.BlockDoH {
+		return 0
+	}
+
+	seen := make(map[string]bool, len(cfg.Domains))
+	for _, domain := range cfg.Domains {
+		if !domain.Pattern {
+			seen[domain.Name] = true
+		}
+	}
+
+	added := 0
+	for _, domain := range DoHDomains(cfg) {
+		if seen[domain] {
+			continue
+		}
+		seen[domain] = true
+		cfg.Domains = append(cfg.Domains, config.Domain{Name: domain})
+		added++
+	}
+
+	slog.Debug("Merged DNS-over-HTTPS resolvers", "added_domains", added)
+	return added
+}
+
+// dotRuleArgs returns the arguments of the rule rejecting outbound traffic to the
+// DNS-over-TLS port over protocol ("tcp" or "udp").
+func dotRuleArgs(protocol string) []string {
+	return []string{"-I", "OUTPUT", "-p", protocol, "--dport", DoTPort,
+		"-j", "REJECT", "-m", "comment", "--comment", FirewallRuleMarker}
+}
+
+// BlockDoT adds firewall rules rejecting outbound DNS-over-TLS and DNS-over-QUIC,
+// which bypass the hosts file like DNS-over-HTTPS. The rules carry the glocker
+// marker, so UpdateFirewall clears them along with the domain rules; call BlockDoT
+// after every firewall update.
+func BlockDoT(dryRun bool) {
+	if dryRun {
+		slog.Debug("Dry run mode - would block DNS-over-TLS")
+		return
+	}
+
+	caps := GetCapabilities()
+	for tool, available := range map[string]bool{"iptables": caps.Iptables, "ip6tables": caps.Ip6tables} {
+		if !available {
+			continue
+		}
+		for _, protocol := range []string{"tcp", "udp"} {
+			if err := exec.Command(tool, dotRuleArgs(protocol)...).Run(); err != nil {
+				slog.Debug("Failed to add DNS-over-TLS firewall rule", "tool", tool, "protocol", protocol, "error", err)
+			}
+		}
+	}
+}
@@ -10,12 +10,13 @@ import (
 
 	"glocker/internal/config"
 	"glocker/internal/state"
-	"glocker/internal/utils"
 )
 
 // GetDomainsToBlock evaluates all configured domains against current time windows
-// and returns a list of domain names that should be blocked right now.
-func GetDomainsToBlock(cfg *config.Config, now time.Time) []string {
+// and returns a list of domain names that should be blocked right now. While
+// allowlist mode is active, every domain off the allowlist is blocked, and the
+// distraction domains of a focus session are blocked until it ends.
+func (e *Engine) GetDomainsToBlock(cfg *config.Config, now time.Time) []string {
 	var blocked []string
 	var loggedBlocked []string
 	currentDay := now.Weekday().String()[:3] // Mon, Tue, etc.
@@ -24,10 +25,43 @@ func GetDomainsToBlock(cfg *config.Config, now time.Time) []string {
 	alwaysBlockCount := 0
 	timeBasedBlockCount := 0
 	tempUnblockedCount := 0
+	allowlistBlockCount := 0
+	allowlistActive := IsAllowlistActive(cfg, now)
 
 	slog.Debug("Evaluating domains for blocking", "current_day", currentDay, "current_time", currentTime, "total_domains", len(cfg.Domains))
 
 	for _, domain := range cfg.Domains {
+		// Pattern domains can't be written to the hosts file or resolved for the
+		// firewall; they are enforced by the web tracking interceptor instead.
+		if domain.Pattern {
+			if domain.LogBlocking {
+				slog.Debug("Skipping pattern domain for hosts/firewall", "pattern", domain.Name)
+			}
+			continue
+		}
+
+		// Path-pattern domains only block some URLs, so the host itself must stay
+		// resolvable; the web tracking interceptor blocks the matching paths.
+		if len(domain.PathPatterns) > 0 {
+			if domain.LogBlocking {
+				slog.Debug("Skipping path-pattern domain for hosts/firewall", "domain", domain.Name, "path_patterns", domain.PathPatterns)
+			}
+			continue
+		}
+
+		// Allowlist mode overrides the domain's own time windows and temporary
+		// unblocks; the firewall rejects everything off the allowlist anyway
+		if allowlistActive && !cfg.AllowlistMode.Allows(domain.Name) {
+			allowlistBlockCount++
+			blocked = append(blocked, domain.Name)
+			blocked = append(blocked, cfg.SubdomainsToBlock(domain)...)
+			if domain.LogBlocking {
+				log.Printf("DOMAIN STATUS: %s -> blocked by allowlist mode", domain.Name)
+				loggedBlocked = append(loggedBlocked, domain.Name)
+			}
+			continue
+		}
+
 		if domain.LogBlocking {
 			slog.Debug("Evaluating domain", "domain", domain.Name, "unblockable", domain.Unblockable, "has_time_windows", len(domain.TimeWindows) > 0)
 		}
@@ -36,7 +70,7 @@ func GetDomainsToBlock(cfg *config.Config, now time.Time) []string {
 		// Only check temp unblock for domains explicitly marked as unblockable
 		if domain.Unblockable {
 			// Check if domain is temporarily unblocked (only for unblockable domains)
-			if IsTempUnblocked(domain.Name, now) {
+			if isTempUnblockedWithGrace(domain.Name, now, cfg.Unblocking.ExpiryGrace()) {
 				tempUnblockedCount++
 				if domain.LogBlocking {
 					slog.Debug("Domain is temporarily unblocked", "domain", domain.Name)
@@ -52,6 +86,7 @@ func GetDomainsToBlock(cfg *config.Config, now time.Time) []string {
 			// No time windows means always block
 			alwaysBlockCount++
 			blocked = append(blocked, domain.Name)
+			blocked = append(blocked, cfg.SubdomainsToBlock(domain)...)
 			if domain.LogBlocking {
 				blockType := "always blocked (permanent)"
 				if domain.Unblockable {
@@ -72,25 +107,10 @@ func GetDomainsToBlock(cfg *config.Config, now time.Time) []string {
 				slog.Debug("Checking time window", "domain", domain.Name, "window_days", window.Days, "window_start", window.Start, "window_end", window.End)
 			}
 
-			// For midnight-crossing windows, check previous day for early morning times
-			dayToCheck := currentDay
-			if window.Start > window.End && currentTime <= window.End {
-				dayToCheck = now.AddDate(0, 0, -1).Weekday().String()[:3]
-				if domain.LogBlocking {
-					slog.Debug("Checking previous day for wraparound window", "domain", domain.Name, "current_day", currentDay, "checking_day", dayToCheck)
-				}
-			}
-
-			if !slices.Contains(window.Days, dayToCheck) {
-				if domain.LogBlocking {
-					slog.Debug("Day not in window", "domain", domain.Name, "day_checked", dayToCheck)
-				}
-				continue
-			}
-
-			if utils.IsInTimeWindow(currentTime, window.Start, window.End) {
+			if window.Active(now) {
 				timeBasedBlockCount++
 				blocked = append(blocked, domain.Name)
+				blocked = append(blocked, cfg.SubdomainsToBlock(domain)...)
 				domainBlocked = true
 				activeWindow = fmt.Sprintf("%s-%s on %s", window.Start, window.End, strings.Join(window.Days, ","))
 				if domain.LogBlocking {
@@ -108,24 +128,71 @@ func GetDomainsToBlock(cfg *config.Config, now time.Time) []string {
 		}
 	}
 
+	// Focus sessions block their distraction domains on top of the rest
+	focusBlockCount := 0
+	if tempBlocks := activeTempBlocks(now); len(tempBlocks) > 0 {
+		alreadyBlocked := make(map[string]bool, len(blocked))
+		for _, domain := range blocked {
+			alreadyBlocked[domain] = true
+		}
+		for _, domain := range tempBlocks {
+			if !alreadyBlocked[domain] {
+				focusBlockCount++
+				blocked = append(blocked, domain)
+				alreadyBlocked[domain] = true
+			}
+		}
+	}
+
 	// Log summary with counts only
 	slog.Debug("Domain blocking evaluation complete",
 		"total_blocked", len(blocked),
 		"always_block_count", alwaysBlockCount,
 		"time_based_block_count", timeBasedBlockCount,
 		"temp_unblocked_count", tempUnblockedCount,
+		"allowlist_block_count", allowlistBlockCount,
+		"focus_block_count", focusBlockCount,
 		"logged_domains_count", len(loggedBlocked))
 
 	return blocked
 }
 
+// activeTempBlocks returns the domains blocked by a focus session at now.
+func activeTempBlocks(now time.Time) []string {
+	var domains []string
+	for _, block := range state.GetTempBlocks() {
+		if now.Before(block.ExpiresAt) {
+			domains = append(domains, block.Domain)
+		}
+	}
+	return domains
+}
+
+// IsTempBlocked reports whether domain is blocked by a focus session at now.
+func IsTempBlocked(domain string, now time.Time) bool {
+	return slices.Contains(activeTempBlocks(now), domain)
+}
+
+// CleanupExpiredTempBlocks removes focus session blocks that have expired at now.
+func CleanupExpiredTempBlocks(now time.Time) {
+	if expired := state.RemoveExpiredTempBlocks(now); len(expired) > 0 {
+		log.Printf("FOCUS SESSION ENDED: %d distraction domains no longer blocked", len(expired))
+	}
+}
+
 // IsTempUnblocked checks if a domain is currently temporarily unblocked.
 // Returns true if the domain has an active temporary unblock that hasn't expired.
 func IsTempUnblocked(domain string, now time.Time) bool {
+	return isTempUnblockedWithGrace(domain, now, 0)
+}
+
+// isTempUnblockedWithGrace is IsTempUnblocked with unblocks lasting grace past
+// their nominal expiry (see UnblockingConfig.ExpiryGrace).
+func isTempUnblockedWithGrace(domain string, now time.Time, grace time.Duration) bool {
 	unblocks := state.GetTempUnblocks()
 	for _, unblock := range unblocks {
 		if unblock.Domain == domain {
-			if now.Before(unblock.ExpiresAt) {
+			if now.Before(unblock.ExpiresAt.Add(grace)) {
 				// Always log temporary unblocks since they're manual actions
 				slog.Debug("Domain is temporarily unblocked", "domain", domain, "expires_at", unblock.ExpiresAt.Format("2006-01-02 15:04:05"))
 				return true
@@ -138,32 +205,31 @@ func IsTempUnblocked(domain string, now time.Time) bool {
 	return false
 }
 
-// CleanupExpiredUnblocks removes expired temporary unblocks from the state.
-func CleanupExpiredUnblocks(now time.Time) {
-	unblocks := state.GetTempUnblocks()
-	var activeUnblocks []state.TempUnblock
-	expiredCount := 0
+// CleanupExpiredUnblocks removes expired temporary unblocks from the state, once
+// the configured grace period after their expiry has passed.
+func CleanupExpiredUnblocks(cfg *config.Config, now time.Time) {
+	expired := state.RemoveExpiredTempUnblocks(now.Add(-cfg.Unblocking.ExpiryGrace()))
+	for _, unblock := range expired {
+		slog.Debug("Removed expired temporary unblock", "domain", unblock.Domain, "expired_at", unblock.ExpiresAt.Format("2006-01-02 15:04:05"))
+	}
 
-	for _, unblock := range unblocks {
-		if now.Before(unblock.ExpiresAt) {
-			activeUnblocks = append(activeUnblocks, unblock)
-		} else {
-			expiredCount++
-			slog.Debug("Removing expired temporary unblock", "domain", unblock.Domain, "expired_at", unblock.ExpiresAt.Format("2006-01-02 15:04:05"))
-		}
+	if len(expired) > 0 {
+		slog.Debug("Cleaned up expired temporary unblocks", "removed_count", len(expired), "remaining_count", len(state.GetTempUnblocks()))
 	}
+}
 
-	if expiredCount > 0 {
-		state.SetTempUnblocks(activeUnblocks)
-		slog.Debug("Cleaned up expired temporary unblocks", "removed_count", expiredCount, "remaining_count", len(activeUnblocks))
+// IsScheduledBlock reports whether domain's own rules block it at now: it has
+// no time windows, or one of them is active. Temporary unblocks and allowlist
+// mode aren't considered.
+func IsScheduledBlock(domain config.Domain, now time.Time) bool {
+	if len(domain.TimeWindows) == 0 {
+		return true
 	}
+	return config.AnyWindowActive(domain.TimeWindows, now)
 }
 
 // GetBlockingReason returns a human-readable string explaining why a domain is blocked.
 func GetBlockingReason(cfg *config.Config, domain string, now time.Time) string {
-	currentDay := now.Weekday().String()[:3]
-	currentTime := now.Format("15:04")
-
 	// Find the domain in the config
 	for _, configDomain := range cfg.Domains {
 		if configDomain.Name == domain {
@@ -178,7 +244,7 @@ func GetBlockingReason(cfg *config.Config, domain string, now time.Time) string
 
 			// Check which time window is active
 			for _, window := range configDomain.TimeWindows {
-				if slices.Contains(window.Days, currentDay) && utils.IsInTimeWindow(currentTime, window.Start, window.End) {
+				if window.Active(now) {
 					return fmt.Sprintf("time-based block (active %s-%s on %s)", window.Start, window.End, strings.Join(window.Days, ","))
 				}
 			}
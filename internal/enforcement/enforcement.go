@@ -6,7 +6,6 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"time"
 
 	"glocker/internal/config"
 )
@@ -14,11 +13,11 @@ import (
 // RunOnce performs a single enforcement cycle, applying all configured blocking mechanisms.
 // It updates hosts files, firewall rules, and sudoers restrictions based on current time windows.
 func RunOnce(cfg *config.Config, dryRun bool) {
-	now := time.Now()
+	now := clock.Now()
 	slog.Debug("Starting enforcement run", "time", now.Format("2006-01-02 15:04:05"), "dry_run", dryRun)
 
 	// Clean up expired temporary unblocks
-	CleanupExpiredUnblocks(now)
+	CleanupExpiredUnblocks(cfg, now)
 
 	blockedDomains := GetDomainsToBlock(cfg, now)
 	slog.Debug("Domains to block determined", "count", len(blockedDomains))
@@ -40,9 +39,14 @@ func RunOnce(cfg *config.Config, dryRun bool) {
 
 	if cfg.EnableFirewall {
 		slog.Debug("Updating firewall rules", "enabled", true)
-		if err := UpdateFirewall(blockedDomains, dryRun); err != nil {
+		if err := UpdateFirewall(cfg, blockedDomains, dryRun); err != nil {
 			log.Printf("ERROR updating firewall: %v", err)
 		}
+		if cfg.BlockDoH {
+			BlockDoT(dryRun)
+		}
+		BlockPorts(cfg, now, dryRun)
+		BlockAllButAllowlist(cfg, now, dryRun)
 	} else {
 		slog.Debug("Firewall management disabled")
 	}
@@ -76,7 +80,9 @@ func SelfHeal(cfg *config.Config) {
 	}
 
 	// Re-apply immutable flag on our binary
-	exec.Command("chattr", "+i", config.InstallPath).Run()
+	if GetCapabilities().Chattr {
+		exec.Command("chattr", "+i", config.InstallPath).Run()
+	}
 
 	// Verify we're still running as the expected process
 	exe, err := os.Executable()
@@ -1,12 +1,26 @@
 package enforcement
 
 import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"glocker/internal/config"
 	"glocker/internal/state"
+	"glocker/internal/utils"
 )
 
 func TestGetDomainsToBlock_AlwaysBlock(t *testing.T) {
@@ -31,6 +45,121 @@ func TestGetDomainsToBlock_AlwaysBlock(t *testing.T) {
 	}
 }
 
+func TestGetDomainsToBlock_IncludeSubdomains(t *testing.T) {
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{Name: "example.com", IncludeSubdomains: true},
+			{Name: "other.com"},
+		},
+		HostsSubdomains: []string{"api", "m"},
+	}
+
+	now := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+	blocked := GetDomainsToBlock(cfg, now)
+
+	want := []string{"example.com", "api.example.com", "m.example.com", "other.com"}
+	if !slices.Equal(blocked, want) {
+		t.Errorf("Expected %v, got %v", want, blocked)
+	}
+}
+
+func TestGetDomainsToBlock_AllowlistMode(t *testing.T) {
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{Name: "evening.com", TimeWindows: []config.TimeWindow{{Start: "18:00", End: "22:00", Days: []string{"Tue"}}}},
+			{Name: "github.com", TimeWindows: []config.TimeWindow{{Start: "18:00", End: "22:00", Days: []string{"Tue"}}}},
+			{Name: "always.com"},
+		},
+		AllowlistMode: config.AllowlistModeConfig{
+			Enabled:        true,
+			AllowedDomains: []config.Domain{{Name: "github.com"}, {Name: "always.com"}},
+			TimeWindows:    []config.TimeWindow{{Start: "09:00", End: "12:00", Days: []string{"Tue"}}},
+		},
+	}
+
+	// Off the allowlist, evening.com is blocked outside its own windows; the
+	// allowlist never unblocks always.com
+	focus := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC) // Tuesday
+	if blocked := GetDomainsToBlock(cfg, focus); !slices.Equal(blocked, []string{"evening.com", "always.com"}) {
+		t.Errorf("Expected evening.com and always.com in allowlist mode, got %v", blocked)
+	}
+
+	afternoon := time.Date(2026, 1, 6, 14, 0, 0, 0, time.UTC)
+	if blocked := GetDomainsToBlock(cfg, afternoon); !slices.Equal(blocked, []string{"always.com"}) {
+		t.Errorf("Expected only always.com outside allowlist mode, got %v", blocked)
+	}
+}
+
+func TestIsAllowlistActive(t *testing.T) {
+	cfg := &config.Config{}
+	now := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC) // Tuesday
+	if IsAllowlistActive(cfg, now) {
+		t.Error("Expected allowlist mode to be off unless enabled")
+	}
+
+	cfg.AllowlistMode.Enabled = true
+	if !IsAllowlistActive(cfg, now) {
+		t.Error("Expected allowlist mode without windows to always be active")
+	}
+
+	cfg.AllowlistMode.TimeWindows = []config.TimeWindow{{Start: "22:00", End: "11:00", Days: []string{"Mon"}}}
+	if !IsAllowlistActive(cfg, now) {
+		t.Error("Expected Monday's midnight-crossing window to cover Tuesday 10:00")
+	}
+	if IsAllowlistActive(cfg, now.Add(2*time.Hour)) {
+		t.Error("Expected allowlist mode to be off after its window")
+	}
+}
+
+func TestAllowlistRuleArgs(t *testing.T) {
+	args := allowlistRuleArgs(false, []string{"192.0.2.53"}, []string{"140.82.112.3"})
+	if len(args) != 7 {
+		t.Fatalf("Expected 7 rules, got %d: %v", len(args), args)
+	}
+	for _, rule := range args {
+		if rule[0] != "-A" || !slices.Contains(rule, FirewallRuleMarker) {
+			t.Errorf("Expected an appended glocker rule, got %v", rule)
+		}
+	}
+	if !slices.Contains(args[1], "ESTABLISHED,RELATED") {
+		t.Errorf("Expected replies to established connections to be accepted, got %v", args[1])
+	}
+	if !slices.Contains(args[2], "67") {
+		t.Errorf("Expected DHCP to be accepted, got %v", args[2])
+	}
+	for _, rule := range args[3:5] {
+		if !slices.Contains(rule, "192.0.2.53") || !slices.Contains(rule, "53") {
+			t.Errorf("Expected DNS to be accepted only to the resolver, got %v", rule)
+		}
+	}
+	if !slices.Contains(args[5], "140.82.112.3") || !slices.Contains(args[5], "ACCEPT") {
+		t.Errorf("Expected the allowed address to be accepted, got %v", args[5])
+	}
+	if last := args[len(args)-1]; !slices.Equal(last[:4], []string{"-A", "OUTPUT", "-j", "REJECT"}) {
+		t.Errorf("Expected a final reject-everything rule, got %v", last)
+	}
+
+	args = allowlistRuleArgs(true, nil, nil)
+	if !slices.ContainsFunc(args, func(rule []string) bool { return slices.Contains(rule, "ipv6-icmp") }) ||
+		!slices.ContainsFunc(args, func(rule []string) bool { return slices.Contains(rule, "547") }) {
+		t.Errorf("Expected ICMPv6 and DHCPv6 to be accepted, got %v", args)
+	}
+}
+
+func TestAllowedHosts_IncludesDeliveryHosts(t *testing.T) {
+	cfg := &config.Config{
+		AllowlistMode: config.AllowlistModeConfig{
+			AllowedDomains: []config.Domain{{Name: "github.com"}, {Name: "docs", Pattern: true}},
+		},
+		Accountability: config.AccountabilityConfig{Enabled: true, Provider: "smtp", SMTPHost: "smtp.example.com"},
+		Notifications:  config.NotificationsConfig{WebhookURL: "https://hooks.example.com/alert"},
+	}
+	want := []string{"github.com", "www.github.com", "smtp.example.com", "hooks.example.com"}
+	if hosts := allowedHosts(cfg); !slices.Equal(hosts, want) {
+		t.Errorf("allowedHosts() = %v, want %v", hosts, want)
+	}
+}
+
 func TestGetDomainsToBlock_TimeWindows(t *testing.T) {
 	// Test with a time window that is currently active
 	now := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC) // Monday 10:00
@@ -199,7 +328,7 @@ func TestCleanupExpiredUnblocks(t *testing.T) {
 	state.AddTempUnblock("active.com", now.Add(30*time.Minute))
 
 	// Cleanup
-	CleanupExpiredUnblocks(now)
+	CleanupExpiredUnblocks(&config.Config{}, now)
 
 	// Check remaining unblocks
 	unblocks := state.GetTempUnblocks()
@@ -214,6 +343,103 @@ func TestCleanupExpiredUnblocks(t *testing.T) {
 	state.SetTempUnblocks([]state.TempUnblock{})
 }
 
+func TestCleanupExpiredUnblocks_Grace(t *testing.T) {
+	state.SetTempUnblocks([]state.TempUnblock{})
+	defer state.SetTempUnblocks([]state.TempUnblock{})
+
+	cfg := &config.Config{Unblocking: config.UnblockingConfig{GraceSeconds: 30}}
+	now := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+
+	state.AddTempUnblock("in-grace.com", now.Add(-10*time.Second))
+	state.AddTempUnblock("past-grace.com", now.Add(-30*time.Second))
+
+	CleanupExpiredUnblocks(cfg, now)
+
+	unblocks := state.GetTempUnblocks()
+	if len(unblocks) != 1 || unblocks[0].Domain != "in-grace.com" {
+		t.Fatalf("Expected only in-grace.com to remain, got %+v", unblocks)
+	}
+
+	// Still reachable during the grace period, even though nominally expired
+	if IsTempUnblocked("in-grace.com", now) {
+		t.Error("IsTempUnblocked should report the nominal expiry")
+	}
+	if !isTempUnblockedWithGrace("in-grace.com", now, cfg.Unblocking.ExpiryGrace()) {
+		t.Error("Expected in-grace.com to stay unblocked during the grace period")
+	}
+	if isTempUnblockedWithGrace("in-grace.com", now.Add(20*time.Second), cfg.Unblocking.ExpiryGrace()) {
+		t.Error("Expected in-grace.com to be blocked once the grace period ends")
+	}
+}
+
+func TestExpiryWarningDue(t *testing.T) {
+	expires := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+	unblock := state.TempUnblock{Domain: "example.com", ExpiresAt: expires}
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		warning time.Duration
+		want    bool
+	}{
+		{"before the warning period", expires.Add(-61 * time.Second), time.Minute, false},
+		{"start of the warning period", expires.Add(-60 * time.Second), time.Minute, true},
+		{"within the warning period", expires.Add(-5 * time.Second), time.Minute, true},
+		{"at expiry", expires, time.Minute, false},
+		{"warnings disabled", expires.Add(-5 * time.Second), 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expiryWarningDue(unblock, tt.now, tt.warning); got != tt.want {
+				t.Errorf("expiryWarningDue() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpiryWarner(t *testing.T) {
+	expires := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+	unblocks := []state.TempUnblock{
+		{Domain: "soon.com", ExpiresAt: expires},
+		{Domain: "later.com", ExpiresAt: expires.Add(10 * time.Minute)},
+	}
+
+	clock := utils.NewFakeTimeProvider(expires.Add(-2 * time.Minute))
+	var warnings []string
+	warner := &expiryWarner{
+		clock:   clock,
+		warning: time.Minute,
+		notify: func(domain string, remaining time.Duration) {
+			warnings = append(warnings, fmt.Sprintf("%s:%v", domain, remaining))
+		},
+		warned: make(map[string]time.Time),
+	}
+
+	warner.check(unblocks)
+	if len(warnings) != 0 {
+		t.Fatalf("Expected no warnings before the warning period, got %v", warnings)
+	}
+
+	clock.Set(expires.Add(-45 * time.Second))
+	warner.check(unblocks)
+	clock.Set(expires.Add(-44 * time.Second))
+	warner.check(unblocks)
+	if len(warnings) != 1 || warnings[0] != "soon.com:45s" {
+		t.Fatalf("Expected one warning for soon.com, got %v", warnings)
+	}
+
+	// A new unblock of the same domain after expiry is warned about again
+	clock.Set(expires.Add(5 * time.Second))
+	warner.check(unblocks)
+	renewed := []state.TempUnblock{{Domain: "soon.com", ExpiresAt: expires.Add(time.Minute)}}
+	clock.Set(expires.Add(30 * time.Second))
+	warner.check(renewed)
+	if len(warnings) != 2 || warnings[1] != "soon.com:30s" {
+		t.Errorf("Expected a second warning for the renewed unblock, got %v", warnings)
+	}
+}
+
 func TestGetBlockingReason_AlwaysBlock(t *testing.T) {
 	cfg := &config.Config{
 		Domains: []config.Domain{
@@ -305,6 +531,43 @@ func TestGetBlockingReason_Unknown(t *testing.T) {
 	}
 }
 
+func TestBuildHostsChunk(t *testing.T) {
+	disabled := false
+	domains := []string{"example.com", "test.org"}
+
+	tests := []struct {
+		name     string
+		cfg      *config.Config
+		expected string
+	}{
+		{
+			name: "defaults",
+			cfg:  &config.Config{},
+			expected: "127.0.0.1 example.com\n127.0.0.1 www.example.com\n::1 example.com\n::1 www.example.com\n" +
+				"127.0.0.1 test.org\n127.0.0.1 www.test.org\n::1 test.org\n::1 www.test.org\n",
+		},
+		{
+			name: "zero sinks",
+			cfg:  &config.Config{HostsSinkIPv4: "0.0.0.0", HostsSinkIPv6: "::"},
+			expected: "0.0.0.0 example.com\n0.0.0.0 www.example.com\n:: example.com\n:: www.example.com\n" +
+				"0.0.0.0 test.org\n0.0.0.0 www.test.org\n:: test.org\n:: www.test.org\n",
+		},
+		{
+			name:     "without www",
+			cfg:      &config.Config{HostsSinkIPv4: "0.0.0.0", HostsIncludeWWW: &disabled},
+			expected: "0.0.0.0 example.com\n::1 example.com\n0.0.0.0 test.org\n::1 test.org\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildHostsChunk(tt.cfg, domains); got != tt.expected {
+				t.Errorf("Unexpected hosts lines:\n%s\nwant:\n%s", got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestIsSudoAllowed_Enabled(t *testing.T) {
 	now := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC) // Monday 10:00
 	currentDay := now.Weekday().String()[:3]
@@ -333,6 +596,81 @@ func TestIsSudoAllowed_Enabled(t *testing.T) {
 	}
 }
 
+func TestPlanSudoersDropinChanges(t *testing.T) {
+	dir := t.TempDir()
+	dropinDir := filepath.Join(dir, "sudoers.d")
+	if err := os.Mkdir(dropinDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"90-noufal":          "# user grant\nnoufal ALL=(ALL) NOPASSWD:ALL\nother ALL=(ALL) ALL\n",
+		"README":             "# nothing to see\n",
+		"90-noufal.dpkg-old": "noufal ALL=(ALL) ALL\n", // ignored by sudo (contains '.')
+		"backup~":            "noufal ALL=(ALL) ALL\n", // ignored by sudo (ends in '~')
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dropinDir, name), []byte(content), 0440); err != nil {
+			t.Fatal(err)
+		}
+	}
+	extra := filepath.Join(dir, "extra")
+	if err := os.WriteFile(extra, []byte("noufal\tALL=(ALL) ALL\n"), 0440); err != nil {
+		t.Fatal(err)
+	}
+
+	mainPath := filepath.Join(dir, "sudoers")
+	mainContent := "root ALL=(ALL) ALL\n@includedir " + dropinDir + "\n#include extra\n"
+
+	changes, err := planSudoersDropinChanges(mainPath, mainContent, "noufal", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("Expected changes for 2 files, got %d: %+v", len(changes), changes)
+	}
+
+	if changes[0].path != filepath.Join(dropinDir, "90-noufal") || changes[0].neutralized != 1 {
+		t.Errorf("Expected 1 grant neutralized in 90-noufal, got %+v", changes[0])
+	}
+	expected := "# user grant\n" + config.SudoersDisabledTag + "noufal ALL=(ALL) NOPASSWD:ALL\nother ALL=(ALL) ALL\n"
+	if string(changes[0].content) != expected {
+		t.Errorf("Unexpected neutralized content:\n%s", changes[0].content)
+	}
+	if changes[1].path != extra {
+		t.Errorf("Expected relative #include to resolve to %s, got %s", extra, changes[1].path)
+	}
+
+	// Already-neutralized files need no further changes
+	if err := os.WriteFile(changes[0].path, changes[0].content, 0440); err != nil {
+		t.Fatal(err)
+	}
+	changes, _ = planSudoersDropinChanges(mainPath, "@includedir "+dropinDir+"\n", "noufal", nil)
+	if len(changes) != 0 {
+		t.Errorf("Expected no changes for neutralized drop-ins, got %+v", changes)
+	}
+}
+
+func TestNeutralizeSudoersUserLines_SharedGrants(t *testing.T) {
+	content := "%wheel ALL=(ALL) ALL\n%#1001 ALL=(ALL) NOPASSWD:ALL\nother,noufal ALL=(ALL) ALL\n%staff ALL=(ALL) ALL\nnoufal ALL=(ALL) ALL\nDefaults env_reset\n"
+
+	// Only the user's own line is rewritten; the others would lose sudo too
+	got, count := neutralizeSudoersUserLines(content, "noufal")
+	if count != 1 {
+		t.Errorf("Expected 1 grant neutralized, got %d", count)
+	}
+	want := strings.Replace(content, "noufal ALL=(ALL) ALL\nDefaults", config.SudoersDisabledTag+"noufal ALL=(ALL) ALL\nDefaults", 1)
+	if got != want {
+		t.Errorf("Unexpected neutralized content:\n%s", got)
+	}
+
+	shared := sharedSudoersGrants(content, "noufal", []string{"wheel", "#1001"})
+	wantShared := []string{"%wheel ALL=(ALL) ALL", "%#1001 ALL=(ALL) NOPASSWD:ALL", "other,noufal ALL=(ALL) ALL"}
+	if !slices.Equal(shared, wantShared) {
+		t.Errorf("Expected shared grants %q, got %q", wantShared, shared)
+	}
+}
+
 func TestIsSudoAllowed_Disabled(t *testing.T) {
 	cfg := &config.Config{
 		Sudoers: config.SudoersConfig{
@@ -400,3 +738,991 @@ func TestGetDomainsToBlock_MultipleConditions(t *testing.T) {
 		t.Error("wrongday.com should not be blocked (wrong day)")
 	}
 }
+
+func TestGetDomainsToBlock_SkipsPatterns(t *testing.T) {
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{Name: "example.com"},
+			{Name: `proxy\d+\.example\.net`, Pattern: true},
+			{Name: "reddit.com", PathPatterns: []string{"/r/somesub"}},
+		},
+	}
+
+	now := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+	blocked := GetDomainsToBlock(cfg, now)
+
+	if len(blocked) != 1 || blocked[0] != "example.com" {
+		t.Errorf("Expected only example.com to be written to hosts, got %v", blocked)
+	}
+}
+
+const testHostsBlocklist = `# Test blocklist
+127.0.0.1 localhost
+0.0.0.0 ads.example.com
+0.0.0.0 tracker.example.net # inline comment
+127.0.0.1 existing.com
+0.0.0.0 ads.example.com
+::1 ip6-localhost
+not-a-hosts-line
+`
+
+func TestRemoteBlocklists_FetchAndMerge(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte(testHostsBlocklist))
+		gz.Close()
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	cfg := &config.Config{
+		Domains:          []config.Domain{{Name: "existing.com"}},
+		RemoteBlocklists: []config.RemoteBlocklist{{URL: server.URL, RefreshHours: 1}},
+	}
+
+	if !RefreshRemoteBlocklists(cfg, cacheDir, false) {
+		t.Fatal("Expected first refresh to report a change")
+	}
+
+	added := MergeRemoteBlocklists(cfg, cacheDir)
+	if added != 2 {
+		t.Errorf("Expected 2 domains merged (ads.example.com, tracker.example.net), got %d", added)
+	}
+	if len(cfg.Domains) != 3 {
+		t.Errorf("Expected 3 total domains after merge, got %d", len(cfg.Domains))
+	}
+
+	now := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+	blocked := GetDomainsToBlock(cfg, now)
+	if len(blocked) != 3 {
+		t.Errorf("Expected merged domains to be always blocked, got %v", blocked)
+	}
+
+	// A fresh cache is not re-fetched
+	if RefreshRemoteBlocklists(cfg, cacheDir, false) {
+		t.Error("Expected no change when cache is fresh")
+	}
+}
+
+func TestRemoteBlocklists_FallbackToCache(t *testing.T) {
+	var failing, oversized atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if oversized.Load() {
+			// A line the parser can't read would otherwise cut the list short
+			w.Write([]byte("0.0.0.0 first.example.com\n0.0.0.0 " + strings.Repeat("a", 2*1024*1024) + "\n"))
+		}
+		w.Write([]byte(testHostsBlocklist))
+	}))
+	defer server.Close()
+
+	cacheDir := t.TempDir()
+	cfg := &config.Config{
+		RemoteBlocklists: []config.RemoteBlocklist{{URL: server.URL}},
+	}
+
+	RefreshRemoteBlocklists(cfg, cacheDir, true)
+
+	failing.Store(true)
+	if RefreshRemoteBlocklists(cfg, cacheDir, true) {
+		t.Error("Failed fetch should not report a change")
+	}
+
+	if added := MergeRemoteBlocklists(cfg, cacheDir); added != 3 {
+		t.Errorf("Expected 3 domains from cached snapshot, got %d", added)
+	}
+
+	failing.Store(false)
+	oversized.Store(true)
+	if RefreshRemoteBlocklists(cfg, cacheDir, true) {
+		t.Error("A list that fails to parse should not report a change")
+	}
+	cfg.Domains = nil
+	if added := MergeRemoteBlocklists(cfg, cacheDir); added != 3 {
+		t.Errorf("Expected 3 domains from cached snapshot after a parse failure, got %d", added)
+	}
+}
+
+func TestMergeDoHDomains(t *testing.T) {
+	state.SetTempUnblocks(nil)
+	now := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+	newConfig := func(blockDoH bool) *config.Config {
+		return &config.Config{
+			BlockDoH:        blockDoH,
+			DoHExtraDomains: []string{"doh.example.net", "DNS.GOOGLE"},
+			Domains: []config.Domain{
+				{Name: "reddit.com"},
+				{Name: "dns.quad9.net", Unblockable: true, TimeWindows: []config.TimeWindow{{Days: []string{"Sat"}, Start: "00:00", End: "23:59"}}},
+			},
+		}
+	}
+
+	cfg := newConfig(true)
+	MergeDoHDomains(cfg)
+	blocked := GetDomainsToBlock(cfg, now)
+	for _, domain := range []string{"cloudflare-dns.com", "dns.google", "mozilla.cloudflare-dns.com", "doh.example.net", "reddit.com"} {
+		if !slices.Contains(blocked, domain) {
+			t.Errorf("Expected %s to be blocked with block_doh on", domain)
+		}
+	}
+	// A resolver configured under domains keeps its own settings
+	if slices.Contains(blocked, "dns.quad9.net") {
+		t.Error("Expected dns.quad9.net to follow its own time windows")
+	}
+	seen := make(map[string]bool)
+	for _, domain := range blocked {
+		if seen[domain] {
+			t.Errorf("%s is blocked twice", domain)
+		}
+		seen[domain] = true
+	}
+
+	cfg = newConfig(false)
+	if added := MergeDoHDomains(cfg); added != 0 {
+		t.Errorf("MergeDoHDomains() added %d domains with block_doh off", added)
+	}
+	blocked = GetDomainsToBlock(cfg, now)
+	for _, domain := range []string{"cloudflare-dns.com", "dns.google", "doh.example.net"} {
+		if slices.Contains(blocked, domain) {
+			t.Errorf("Expected %s not to be blocked with block_doh off", domain)
+		}
+	}
+}
+
+func TestDotRuleArgs(t *testing.T) {
+	args := strings.Join(dotRuleArgs("tcp"), " ")
+	want := "-I OUTPUT -p tcp --dport 853 -j REJECT -m comment --comment " + FirewallRuleMarker
+	if args != want {
+		t.Errorf("dotRuleArgs(tcp) = %q, want %q", args, want)
+	}
+}
+
+func TestPortRuleArgs(t *testing.T) {
+	rules := []config.FirewallRule{
+		{Port: 22, Protocol: "tcp", TimeWindows: []config.TimeWindow{
+			{Start: "09:00", End: "17:00", Days: []string{"Mon", "Tue", "Wed", "Thu", "Fri"}},
+		}},
+		{Port: 6881},
+		{Port: 51413, Protocol: "udp", TimeWindows: []config.TimeWindow{
+			{Start: "22:00", End: "02:00", Days: []string{"Fri"}},
+		}},
+	}
+	rule := func(protocol, port string) string {
+		return "-I OUTPUT -p " + protocol + " --dport " + port + " -j REJECT -m comment --comment " + FirewallRuleMarker
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want []string
+	}{
+		{
+			name: "weekday focus hours",
+			now:  time.Date(2024, 6, 12, 10, 0, 0, 0, time.Local), // Wednesday
+			want: []string{rule("tcp", "22"), rule("tcp", "6881"), rule("udp", "6881")},
+		},
+		{
+			name: "weekday evening",
+			now:  time.Date(2024, 6, 12, 19, 0, 0, 0, time.Local),
+			want: []string{rule("tcp", "6881"), rule("udp", "6881")},
+		},
+		{
+			name: "after midnight of a Friday window",
+			now:  time.Date(2024, 6, 15, 1, 0, 0, 0, time.Local), // Saturday
+			want: []string{rule("tcp", "6881"), rule("udp", "6881"), rule("udp", "51413")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, args := range portRuleArgs(rules, tt.now) {
+				got = append(got, strings.Join(args, " "))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("portRuleArgs() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+
+	evening := portRuleArgs(rules, time.Date(2024, 6, 12, 19, 0, 0, 0, time.Local))
+	focus := portRuleArgs(rules, time.Date(2024, 6, 12, 10, 0, 0, 0, time.Local))
+	if !portRulesChanged(evening, focus) || portRulesChanged(focus, focus) {
+		t.Error("portRulesChanged() should only report a different rule set")
+	}
+	if portRulesChanged(nil, portRuleArgs(nil, time.Now())) {
+		t.Error("portRulesChanged() should treat no rules as unchanged")
+	}
+}
+
+func TestMissingFirewallRules(t *testing.T) {
+	orig := liveFirewallRules
+	defer func() { liveFirewallRules = orig }()
+
+	tests := []struct {
+		name     string
+		expected int
+		live     int
+		want     int
+	}{
+		{"all rules present", 120, 120, 0},
+		{"rules flushed", 120, 0, 120},
+		{"some rules deleted", 120, 100, 20},
+		{"extra rules", 120, 130, 0},
+		{"firewall never rebuilt", 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEngine()
+			e.state.expectedFirewallRules = tt.expected
+			liveFirewallRules = func() int { return tt.live }
+			if got := e.missingFirewallRules(); got != tt.want {
+				t.Errorf("missingFirewallRules() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRebuildFirewall_RecordsExpectedRules(t *testing.T) {
+	orig := liveFirewallRules
+	defer func() { liveFirewallRules = orig }()
+	liveFirewallRules = func() int { return 42 }
+	// Without iptables the rebuild doesn't touch the system firewall
+	origCaps := GetCapabilities()
+	defer SetCapabilities(origCaps)
+	SetCapabilities(Capabilities{})
+
+	e := NewEngine()
+	e.rebuildFirewall(&config.Config{}, nil, time.Now())
+	if e.state.expectedFirewallRules != 42 {
+		t.Errorf("expectedFirewallRules = %d, want 42", e.state.expectedFirewallRules)
+	}
+
+	// Healing does nothing while every rule is still there
+	e.healFirewall(time.Now())
+	if e.missingFirewallRules() != 0 {
+		t.Error("Expected no missing rules")
+	}
+}
+
+func TestUpdateHosts_RestoresWipedHostsFile(t *testing.T) {
+	hostsPath := filepath.Join(t.TempDir(), "hosts")
+	// UpdateHosts marks the file immutable when run as root
+	t.Cleanup(func() { exec.Command("chattr", "-i", hostsPath).Run() })
+	original := "127.0.0.1 localhost\n192.168.1.10 nas.lan\n"
+	if err := os.WriteFile(hostsPath, []byte(original+"\n"+config.HostsMarkerStart+"\n127.0.0.1 old.com\n"), 0644); err != nil {
+		t.Fatalf("Failed to write hosts file: %v", err)
+	}
+
+	// The backup keeps only the user's own entries
+	if err := CreateHostsBackup(hostsPath); err != nil {
+		t.Fatalf("CreateHostsBackup failed: %v", err)
+	}
+	backup, err := os.ReadFile(hostsPath + config.HostsBackupSuffix)
+	if err != nil {
+		t.Fatalf("Failed to read hosts backup: %v", err)
+	}
+	if string(backup) != original {
+		t.Errorf("Backup = %q, want %q", backup, original)
+	}
+
+	// Deleting the hosts file outright must not lose those entries
+	os.Remove(hostsPath)
+	cfg := &config.Config{HostsPath: hostsPath}
+	if err := UpdateHosts(cfg, []string{"example.com"}, false); err != nil {
+		t.Fatalf("UpdateHosts failed: %v", err)
+	}
+
+	content, err := os.ReadFile(hostsPath)
+	if err != nil {
+		t.Fatalf("Failed to read hosts file: %v", err)
+	}
+	for _, want := range []string{"127.0.0.1 localhost", "192.168.1.10 nas.lan", config.HostsMarkerStart, "127.0.0.1 example.com"} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("Hosts file should contain %q, got:\n%s", want, content)
+		}
+	}
+	if strings.Contains(string(content), "old.com") {
+		t.Errorf("Hosts file should not contain the stale block section, got:\n%s", content)
+	}
+}
+
+func TestProbeCapabilities(t *testing.T) {
+	installed := map[string]bool{"chattr": true, "iptables": true, "visudo": true}
+	lookPath := func(file string) (string, error) {
+		if installed[file] {
+			return "/usr/sbin/" + file, nil
+		}
+		return "", exec.ErrNotFound
+	}
+
+	caps := ProbeCapabilities(lookPath)
+	want := Capabilities{Chattr: true, Iptables: true, Visudo: true}
+	if caps != want {
+		t.Errorf("ProbeCapabilities() = %+v, want %+v", caps, want)
+	}
+
+	// Only missing tools needed by enabled protections are reported
+	cfg := &config.Config{EnableHosts: true}
+	if degraded := caps.Degraded(cfg); len(degraded) != 0 {
+		t.Errorf("Expected nothing degraded with only hosts enabled, got %v", degraded)
+	}
+
+	cfg.EnableFirewall = true
+	degraded := caps.Degraded(cfg)
+	if len(degraded) != 1 || !strings.HasPrefix(degraded[0], "ip6tables: ") {
+		t.Errorf("Expected ip6tables to be reported, got %v", degraded)
+	}
+
+	if degraded := ProbeCapabilities(func(string) (string, error) { return "", exec.ErrNotFound }).Degraded(&config.Config{Sudoers: config.SudoersConfig{Enabled: true}}); len(degraded) != 1 || !strings.HasPrefix(degraded[0], "visudo: ") {
+		t.Errorf("Expected only visudo to be reported for sudoers, got %v", degraded)
+	}
+}
+
+func TestUpdateSudoers_SkippedWithoutVisudo(t *testing.T) {
+	SetCapabilities(Capabilities{Chattr: true, Iptables: true, Ip6tables: true})
+	t.Cleanup(func() { SetCapabilities(allCapabilities) })
+
+	cfg := &config.Config{Sudoers: config.SudoersConfig{Enabled: true, User: "nobody"}}
+	now := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+
+	if err := UpdateSudoers(cfg, now, false, false); err != nil {
+		t.Errorf("Expected the periodic update to be skipped, got: %v", err)
+	}
+	if err := UpdateSudoers(cfg, now, false, true); err == nil {
+		t.Error("Expected a forced lock to fail without visudo")
+	}
+}
+
+func TestIsSudoLockTransition(t *testing.T) {
+	allowed := "me ALL=(ALL) NOPASSWD:ALL"
+	blocked := "me ALL=(ALL) ALL"
+	managed := func(line string) string {
+		return "root ALL=(ALL) ALL\n" + line + " " + config.SudoersMarker + "\n@includedir /etc/sudoers.d\n"
+	}
+
+	tests := []struct {
+		name        string
+		content     string
+		sudoAllowed bool
+		want        bool
+	}{
+		{"allowed to blocked", managed(allowed), false, true},
+		{"still blocked", managed(blocked), false, false},
+		{"blocked to allowed", managed(blocked), true, false},
+		{"still allowed", managed(allowed), true, false},
+		{"first update while blocked", "root ALL=(ALL) ALL\nme ALL=(ALL) NOPASSWD:ALL\n", false, true},
+		{"first update while allowed", "root ALL=(ALL) ALL\n", true, false},
+	}
+	for _, tt := range tests {
+		if got := isSudoLockTransition(tt.content, blocked, tt.sudoAllowed); got != tt.want {
+			t.Errorf("%s: isSudoLockTransition() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRemoveSudoTimestamp(t *testing.T) {
+	orig := sudoTimestampDir
+	sudoTimestampDir = t.TempDir()
+	t.Cleanup(func() { sudoTimestampDir = orig })
+
+	for _, user := range []string{"me", "other"} {
+		if err := os.WriteFile(filepath.Join(sudoTimestampDir, user), []byte("ts"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := removeSudoTimestamp("me"); err != nil {
+		t.Fatalf("removeSudoTimestamp() error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(sudoTimestampDir, "me")); !os.IsNotExist(err) {
+		t.Errorf("Expected the user's timestamp to be removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(sudoTimestampDir, "other")); err != nil {
+		t.Errorf("Other users' timestamps should be kept: %v", err)
+	}
+
+	// Nothing cached is fine, a path is not
+	if err := removeSudoTimestamp("me"); err != nil {
+		t.Errorf("Expected no error without a timestamp, got %v", err)
+	}
+	for _, user := range []string{"", "..", "../etc/passwd"} {
+		if err := removeSudoTimestamp(user); err == nil {
+			t.Errorf("Expected an error for user %q", user)
+		}
+	}
+}
+
+const testIptablesOutput = `-P OUTPUT ACCEPT
+-A OUTPUT -d 93.184.216.34/32 -m comment --comment GLOCKER-BLOCK -j REJECT --reject-with icmp-host-unreachable
+-A OUTPUT -d 10.0.0.0/8 -m comment --comment "allow lan" -j ACCEPT
+-A OUTPUT -d 151.101.1.140/32 -m comment --comment "GLOCKER-BLOCK reddit.com \"old\"" -j REJECT --reject-with icmp-host-unreachable
+-A OUTPUT -o lo -j ACCEPT
+`
+
+func TestFirewallDeleteArgs(t *testing.T) {
+	got := firewallDeleteArgs(testIptablesOutput)
+	want := [][]string{
+		{"-D", "OUTPUT", "-d", "93.184.216.34/32", "-m", "comment", "--comment", "GLOCKER-BLOCK", "-j", "REJECT", "--reject-with", "icmp-host-unreachable"},
+		{"-D", "OUTPUT", "-d", "151.101.1.140/32", "-m", "comment", "--comment", `GLOCKER-BLOCK reddit.com "old"`, "-j", "REJECT", "--reject-with", "icmp-host-unreachable"},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d delete commands, got %d: %q", len(want), len(got), got)
+	}
+	for i := range want {
+		if strings.Join(got[i], "\x00") != strings.Join(want[i], "\x00") {
+			t.Errorf("Delete command %d:\n got %q\nwant %q", i, got[i], want[i])
+		}
+	}
+
+	const ip6tablesOutput = "-P OUTPUT ACCEPT\n-A OUTPUT -d 2606:2800:220:1:248:1893:25c8:1946/128 -m comment --comment GLOCKER-BLOCK -j REJECT --reject-with icmp6-adm-prohibited\n"
+	got = firewallDeleteArgs(ip6tablesOutput)
+	if len(got) != 1 || got[0][0] != "-D" || got[0][3] != "2606:2800:220:1:248:1893:25c8:1946/128" {
+		t.Errorf("Unexpected ip6tables delete commands: %q", got)
+	}
+
+	if got := firewallDeleteArgs("-P OUTPUT ACCEPT\n"); len(got) != 0 {
+		t.Errorf("Expected no delete commands without glocker rules, got %q", got)
+	}
+}
+
+func TestRunSelfTest(t *testing.T) {
+	addrs := map[string][]string{
+		"sinkholed.com":  {"127.0.0.1", "::1"},
+		"custom.com":     {"10.0.0.53"},
+		"firewalled.com": {"93.184.216.34"},
+		"leaky.com":      {"151.101.1.140"},
+	}
+	origLookup, origDial := selfTestLookupHost, selfTestDial
+	t.Cleanup(func() { selfTestLookupHost, selfTestDial = origLookup, origDial })
+	selfTestLookupHost = func(ctx context.Context, host string) ([]string, error) {
+		if a, ok := addrs[host]; ok {
+			return a, nil
+		}
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	selfTestDial = func(ctx context.Context, network, address string) (net.Conn, error) {
+		if address == "151.101.1.140:443" {
+			client, server := net.Pipe()
+			server.Close()
+			return client, nil
+		}
+		return nil, errors.New("connection refused")
+	}
+
+	defaultEngine.rememberSelfTestCandidates([]string{"sinkholed.com", "custom.com", "firewalled.com", "leaky.com", "gone.com", "203.0.113.7", "unblocked.com"})
+	state.SetTempUnblocks([]state.TempUnblock{{Domain: "unblocked.com", ExpiresAt: time.Now().Add(time.Hour)}})
+	t.Cleanup(func() {
+		state.SetTempUnblocks(nil)
+		defaultEngine.rememberSelfTestCandidates(nil)
+	})
+
+	cfg := &config.Config{HostsSinkIPv4: "10.0.0.53", SelfTest: config.SelfTestConfig{Enabled: true, SampleSize: 10}}
+	result := RunSelfTest(cfg, time.Now())
+
+	if result.Sampled != 5 || result.Verified != 4 {
+		t.Errorf("Expected 4 of 5 sampled blocks verified, got %d of %d", result.Verified, result.Sampled)
+	}
+	if len(result.Reachable) != 1 || result.Reachable[0] != "leaky.com" {
+		t.Errorf("Expected only leaky.com to be reachable, got %v", result.Reachable)
+	}
+	if recorded, ok := state.GetSelfTestResult(); !ok || recorded.Verified != result.Verified {
+		t.Errorf("Expected the result to be recorded, got %+v (ok=%v)", recorded, ok)
+	}
+
+	// The sample size caps how many domains are checked
+	cfg.SelfTest.SampleSize = 2
+	if result := RunSelfTest(cfg, time.Now()); result.Sampled != 2 {
+		t.Errorf("Expected 2 sampled domains, got %d", result.Sampled)
+	}
+}
+
+func TestEnforcementHistory_RecordsDecisions(t *testing.T) {
+	state.SetTempUnblocks(nil)
+	defer state.SetTempUnblocks(nil)
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configYAML := "domains:\n  - {name: news.com, unblockable: true}\n  - {name: games.com}\n"
+	if err := os.WriteFile(configPath, []byte(configYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config.SetConfigPath(configPath)
+	defer config.SetConfigPath(config.GlockerConfigFile)
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	e := NewEngine()
+	e.InitialEnforcement(cfg)
+
+	history := state.GetEnforcementHistory()
+	last := history[len(history)-1]
+	if last.Action != "full enforcement" || last.Reason != "daemon started" || last.BlockedCount != 2 {
+		t.Errorf("Expected the initial enforcement to be recorded, got %+v", last)
+	}
+
+	// A check without changes records nothing
+	e.EnforcementCheck(cfg)
+	if got := len(state.GetEnforcementHistory()); got != len(history) {
+		t.Errorf("Expected no event for an unchanged check, got %d events after %d", got, len(history))
+	}
+
+	// An unblock makes the next check rebuild the block list
+	state.AddTempUnblock("news.com", time.Now().Add(10*time.Minute))
+	e.EnforcementCheck(cfg)
+	history = state.GetEnforcementHistory()
+	last = history[len(history)-1]
+	if last.Action != "hosts update" || last.Reason != "temp unblocks changed" || last.BlockedCount != 1 {
+		t.Errorf("Expected the unblock update to be recorded, got %+v", last)
+	}
+}
+
+func TestEnforcementCheck_SerializedWithForcedRuns(t *testing.T) {
+	state.SetTempUnblocks(nil)
+	origCaps := GetCapabilities()
+	defer SetCapabilities(origCaps)
+	SetCapabilities(Capabilities{}) // Keep the temporary hosts file mutable
+	dir := t.TempDir()
+	hostsPath := filepath.Join(dir, "hosts")
+	configYAML := fmt.Sprintf("enable_hosts: true\nhosts_path: %s\ndomains:\n", hostsPath)
+	for i := range 2000 {
+		configYAML += fmt.Sprintf("  - name: site%d.com\n", i)
+	}
+	configPath := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configPath, []byte(configYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config.SetConfigPath(configPath)
+	defer config.SetConfigPath(config.GlockerConfigFile)
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	e := NewEngine()
+	e.InitialEnforcement(cfg)
+	tamperEvents := state.GetTamperEventCount()
+
+	// A check triggered while a forced run is rewriting the hosts file must not
+	// see the half-written file as tampering
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for range 10 {
+			e.ForceEnforcement(cfg)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for range 200 {
+			e.EnforcementCheck(cfg)
+		}
+	}()
+	wg.Wait()
+
+	if got := state.GetTamperEventCount(); got != tamperEvents {
+		t.Errorf("Expected no tamper events from glocker's own writes, got %d", got-tamperEvents)
+	}
+}
+
+func TestEngines_Independent(t *testing.T) {
+	state.SetTempUnblocks(nil)
+	work := NewEngine()
+	home := NewEngine()
+
+	work.InitialEnforcement(&config.Config{Domains: []config.Domain{
+		{Name: "news.com", Unblockable: true, UnblockMinutes: 5},
+		{Name: "video.com", TimeWindows: []config.TimeWindow{{Days: []string{"Mon", "Tue", "Wed", "Thu", "Fri"}, Start: "09:00", End: "17:00"}}},
+	}})
+	home.InitialEnforcement(&config.Config{Domains: []config.Domain{
+		{Name: "games.com"},
+	}})
+
+	now := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+	if canUnblock, inConfig := work.IsUnblockable("news.com", now); !canUnblock || !inConfig {
+		t.Errorf("work.IsUnblockable(news.com) = %v, %v; want true, true", canUnblock, inConfig)
+	}
+	if _, inConfig := home.IsUnblockable("news.com", now); inConfig {
+		t.Error("news.com leaked into the home engine's config")
+	}
+	if canUnblock, inConfig := home.IsUnblockable("games.com", now); canUnblock || !inConfig {
+		t.Errorf("home.IsUnblockable(games.com) = %v, %v; want false, true", canUnblock, inConfig)
+	}
+
+	if minutes := work.GetUnblockMinutes("news.com"); minutes != 5 {
+		t.Errorf("work.GetUnblockMinutes(news.com) = %d, want 5", minutes)
+	}
+	if minutes := home.GetUnblockMinutes("news.com"); minutes != 0 {
+		t.Errorf("home.GetUnblockMinutes(news.com) = %d, want 0", minutes)
+	}
+
+	if domains := work.GetTimeWindowDomains(); len(domains) != 1 || domains[0].Name != "video.com" {
+		t.Errorf("work.GetTimeWindowDomains() = %v, want [video.com]", domains)
+	}
+	if domains := home.GetTimeWindowDomains(); len(domains) != 0 {
+		t.Errorf("home.GetTimeWindowDomains() = %v, want none", domains)
+	}
+	if windowState := work.GetTimeWindowState(now); !windowState["video.com"] {
+		t.Errorf("work.GetTimeWindowState() = %v, want video.com blocked", windowState)
+	}
+	if windowState := home.GetTimeWindowState(now); len(windowState) != 0 {
+		t.Errorf("home.GetTimeWindowState() = %v, want empty", windowState)
+	}
+
+	if got := len(home.GetEnforcedDomains()); got != 1 {
+		t.Errorf("home.GetEnforcedDomains() has %d domains, want 1", got)
+	}
+	if got := len(work.GetEnforcedDomains()); got != 2 {
+		t.Errorf("work.GetEnforcedDomains() has %d domains, want 2", got)
+	}
+
+	// Neither touches the default engine
+	for _, domain := range GetEnforcedDomains() {
+		if domain.Name == "news.com" || domain.Name == "games.com" {
+			t.Errorf("Default engine picked up %s", domain.Name)
+		}
+	}
+}
+
+func TestEnforcementCheck_TimeWindowTransitions(t *testing.T) {
+	state.SetTempUnblocks(nil)
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	configYAML := "domains:\n  - {name: games.com}\n  - name: work.com\n    time_windows: [{start: \"09:00\", end: \"17:00\", days: [Mon, Tue, Wed, Thu, Fri]}]\n"
+	if err := os.WriteFile(configPath, []byte(configYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config.SetConfigPath(configPath)
+	defer config.SetConfigPath(config.GlockerConfigFile)
+
+	// 2026-01-06 is a Tuesday
+	fake := utils.NewFakeTimeProvider(time.Date(2026, 1, 6, 8, 58, 0, 0, time.Local))
+	SetClock(fake)
+	defer SetClock(utils.DefaultTimeProvider{})
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	e := NewEngine()
+	e.InitialEnforcement(cfg)
+
+	lastEvent := func() state.EnforcementEvent {
+		history := state.GetEnforcementHistory()
+		return history[len(history)-1]
+	}
+	if event := lastEvent(); event.BlockedCount != 1 || !event.Time.Equal(fake.Now()) {
+		t.Errorf("Expected only games.com blocked at 08:58, got %+v", event)
+	}
+
+	// Still before the window: nothing changes
+	fake.Advance(time.Minute)
+	before := len(state.GetEnforcementHistory())
+	e.EnforcementCheck(cfg)
+	if got := len(state.GetEnforcementHistory()); got != before {
+		t.Errorf("Expected no event at 08:59, got %+v", lastEvent())
+	}
+
+	// The window opens
+	fake.Advance(time.Minute)
+	e.EnforcementCheck(cfg)
+	event := lastEvent()
+	if event.Reason != "time window state changed for work.com" || event.BlockedCount != 2 || !event.Time.Equal(fake.Now()) {
+		t.Errorf("Expected work.com to be blocked at 09:00, got %+v", event)
+	}
+
+	// The window closes
+	fake.Set(time.Date(2026, 1, 6, 17, 1, 0, 0, time.Local))
+	e.EnforcementCheck(cfg)
+	event = lastEvent()
+	if event.Reason != "time window state changed for work.com" || event.BlockedCount != 1 {
+		t.Errorf("Expected work.com to be unblocked at 17:01, got %+v", event)
+	}
+}
+
+func TestNextTimeWindowTransition(t *testing.T) {
+	weekdays := []string{"Mon", "Tue", "Wed", "Thu", "Fri"}
+	allDays := []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+	work := []config.TimeWindow{{Start: "09:00", End: "17:00", Days: weekdays}}
+	night := []config.TimeWindow{{Start: "22:00", End: "05:00", Days: []string{"Tue"}}}
+	split := []config.TimeWindow{{Start: "09:00", End: "11:59", Days: allDays}, {Start: "12:00", End: "14:00", Days: allDays}}
+	always := []config.TimeWindow{{Start: "00:00", End: "23:59", Days: allDays}}
+
+	// 2026-01-06 is a Tuesday
+	at := func(day, hour, minute int) time.Time { return time.Date(2026, 1, day, hour, minute, 0, 0, time.Local) }
+	tests := []struct {
+		name         string
+		windows      []config.TimeWindow
+		now          time.Time
+		want         time.Time
+		wantBlocking bool
+		wantOK       bool
+	}{
+		{"before a window", work, at(6, 8, 0), at(6, 9, 0), false, true},
+		{"inside a window", work, at(6, 10, 30), at(6, 17, 1), true, true},
+		{"through the end minute", work, at(6, 17, 0), at(6, 17, 1), true, true},
+		{"after the last window of the day", work, at(6, 18, 0), at(7, 9, 0), false, true},
+		{"over the weekend", work, at(9, 18, 0), at(12, 9, 0), false, true},
+		{"before a midnight-crossing window", night, at(6, 21, 0), at(6, 22, 0), false, true},
+		{"midnight-crossing window before midnight", night, at(6, 23, 0), at(7, 5, 1), true, true},
+		{"midnight-crossing window after midnight", night, at(7, 1, 0), at(7, 5, 1), true, true},
+		{"after a midnight-crossing window", night, at(7, 6, 0), at(13, 22, 0), false, true},
+		{"back-to-back windows merge", split, at(6, 10, 0), at(6, 14, 1), true, true},
+		{"blocked all week", always, at(6, 10, 0), time.Time{}, true, false},
+		{"no windows", nil, at(6, 10, 0), time.Time{}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, blocking, ok := NextTimeWindowTransition(tt.windows, tt.now)
+			if !got.Equal(tt.want) || blocking != tt.wantBlocking || ok != tt.wantOK {
+				t.Errorf("NextTimeWindowTransition() = (%v, %v, %v), want (%v, %v, %v)", got, blocking, ok, tt.want, tt.wantBlocking, tt.wantOK)
+			}
+			// The reported state agrees with enforcement's
+			if window := config.AnyWindowActive(tt.windows, tt.now); window != blocking {
+				t.Errorf("blocking = %v, but AnyWindowActive = %v", blocking, window)
+			}
+		})
+	}
+}
+
+func TestFirewallAddresses_DropsSinks(t *testing.T) {
+	cfg := &config.Config{HostsSinkIPv4: "192.0.2.99"}
+	ips := []string{"93.184.215.14", "127.0.0.1", "::1", "0.0.0.0", "::", "192.0.2.99", "2001:db8::1"}
+	if got, want := firewallAddresses(cfg, ips), []string{"93.184.215.14", "2001:db8::1"}; !slices.Equal(got, want) {
+		t.Errorf("firewallAddresses() = %v, want %v", got, want)
+	}
+	if len(ips) != 7 {
+		t.Errorf("firewallAddresses() modified its input: %v", ips)
+	}
+}
+
+func TestResolveCache_TTLExpiry(t *testing.T) {
+	var calls atomic.Int32
+	resolve := func(host, recordType string) ([]string, time.Duration) {
+		calls.Add(1)
+		switch host {
+		case "fast.com":
+			return []string{"192.0.2.1"}, 10 * time.Second
+		case "slow.com":
+			return []string{"192.0.2.2"}, 48 * time.Hour
+		}
+		return []string{}, 0
+	}
+	cache := newResolveCache(resolve, 4, "")
+	start := time.Date(2026, 1, 5, 9, 0, 0, 0, time.Local)
+	hosts := []string{"fast.com", "slow.com", "missing.com"}
+
+	got := cache.ResolveAll(hosts, "A", start)
+	if !slices.Equal(got["fast.com"], []string{"192.0.2.1"}) || !slices.Equal(got["slow.com"], []string{"192.0.2.2"}) || len(got["missing.com"]) != 0 {
+		t.Fatalf("Unexpected resolutions: %v", got)
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("Expected 3 lookups, got %d", calls.Load())
+	}
+
+	// A 10s TTL is still cached for the minimum of a minute
+	cache.ResolveAll(hosts, "A", start.Add(30*time.Second))
+	if calls.Load() != 3 {
+		t.Errorf("Expected cached answers at +30s, got %d lookups", calls.Load())
+	}
+
+	// Only fast.com has expired
+	cache.ResolveAll(hosts, "A", start.Add(61*time.Second))
+	if calls.Load() != 4 {
+		t.Errorf("Expected fast.com to be looked up again at +61s, got %d lookups", calls.Load())
+	}
+
+	// Failed lookups are retried after failedResolveTTL
+	cache.ResolveAll(hosts, "A", start.Add(failedResolveTTL+time.Second))
+	if calls.Load() != 6 {
+		t.Errorf("Expected fast.com and missing.com to be looked up again, got %d lookups", calls.Load())
+	}
+
+	// A 48h TTL is capped at maxResolveTTL
+	calls.Store(0)
+	cache.ResolveAll([]string{"slow.com"}, "A", start.Add(maxResolveTTL+time.Second))
+	if calls.Load() != 1 {
+		t.Errorf("Expected slow.com to be looked up again after %v, got %d lookups", maxResolveTTL, calls.Load())
+	}
+
+	// IPv6 answers are cached separately
+	calls.Store(0)
+	cache.ResolveAll([]string{"slow.com"}, "AAAA", start.Add(maxResolveTTL+time.Second))
+	if calls.Load() != 1 {
+		t.Errorf("Expected an AAAA lookup for slow.com, got %d lookups", calls.Load())
+	}
+}
+
+func TestResolveCache_BoundedConcurrency(t *testing.T) {
+	const workers = 3
+	var inFlight, maxInFlight, calls atomic.Int32
+	resolve := func(host, recordType string) ([]string, time.Duration) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			m := maxInFlight.Load()
+			if n <= m || maxInFlight.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		calls.Add(1)
+		time.Sleep(5 * time.Millisecond)
+		return []string{"192.0.2.1"}, time.Hour
+	}
+	cache := newResolveCache(resolve, workers, "")
+
+	var hosts []string
+	for i := range 30 {
+		hosts = append(hosts, fmt.Sprintf("host%d.com", i))
+	}
+	// Duplicates are only looked up once
+	hosts = append(hosts, "host0.com", "host1.com")
+
+	got := cache.ResolveAll(hosts, "A", time.Now())
+	if len(got) != 30 {
+		t.Errorf("Expected 30 resolved hosts, got %d", len(got))
+	}
+	if calls.Load() != 30 {
+		t.Errorf("Expected 30 lookups, got %d", calls.Load())
+	}
+	if m := maxInFlight.Load(); m > workers {
+		t.Errorf("Expected at most %d lookups at once, got %d", workers, m)
+	} else if m < 2 {
+		t.Errorf("Expected lookups to run concurrently, got at most %d at once", m)
+	}
+}
+
+func TestResolveCache_Persistence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dns-cache.json")
+	now := time.Date(2026, 1, 5, 9, 0, 0, 0, time.Local)
+	cache := newResolveCache(func(host, recordType string) ([]string, time.Duration) {
+		if host == "short.com" {
+			return []string{"192.0.2.2"}, time.Minute
+		}
+		return []string{"192.0.2.1"}, time.Hour
+	}, 2, path)
+	cache.ResolveAll([]string{"example.com", "short.com"}, "A", now)
+
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("Expected the cache to be saved: %v", err)
+	}
+
+	// A new cache (a restarted daemon) loads the still-valid answers
+	var calls atomic.Int32
+	restarted := newResolveCache(func(host, recordType string) ([]string, time.Duration) {
+		calls.Add(1)
+		return []string{"198.51.100.1"}, time.Hour
+	}, 2, path)
+	got := restarted.ResolveAll([]string{"example.com", "short.com"}, "A", now.Add(10*time.Minute))
+	if !slices.Equal(got["example.com"], []string{"192.0.2.1"}) {
+		t.Errorf("Expected example.com from the saved cache, got %v", got["example.com"])
+	}
+	if !slices.Equal(got["short.com"], []string{"198.51.100.1"}) || calls.Load() != 1 {
+		t.Errorf("Expected only the expired short.com to be looked up, got %v after %d lookups", got["short.com"], calls.Load())
+	}
+}
+
+func TestResolveCache_CorruptFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dns-cache.json")
+	if err := os.WriteFile(path, []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cache := newResolveCache(func(host, recordType string) ([]string, time.Duration) {
+		return []string{"192.0.2.1"}, time.Hour
+	}, 1, path)
+	if got := cache.ResolveAll([]string{"example.com"}, "A", time.Now()); !slices.Equal(got["example.com"], []string{"192.0.2.1"}) {
+		t.Errorf("Expected a corrupt cache to be ignored, got %v", got)
+	}
+}
+
+func TestGetDomainsToBlock_FocusSession(t *testing.T) {
+	state.SetTempUnblocks(nil)
+	state.SetTempBlocks(nil)
+	t.Cleanup(func() {
+		state.SetTempUnblocks(nil)
+		state.SetTempBlocks(nil)
+	})
+
+	now := time.Date(2026, 1, 6, 10, 0, 0, 0, time.Local)
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{Name: "games.com"},
+			{Name: "reddit.com", TimeWindows: []config.TimeWindow{{Days: []string{"Sat"}, Start: "00:00", End: "23:59"}}},
+			{Name: "news.com", Unblockable: true},
+		},
+	}
+	state.AddTempUnblock("news.com", now.Add(time.Hour))
+
+	e := NewEngine()
+	if blocked := e.GetDomainsToBlock(cfg, now); !slices.Equal(blocked, []string{"games.com"}) {
+		t.Fatalf("Expected only games.com blocked before the session, got %v", blocked)
+	}
+
+	// The session stacks on the other blocks, overrides the temporary unblock
+	// and doesn't list an already blocked domain twice
+	for _, domain := range []string{"games.com", "reddit.com", "news.com", "youtube.com"} {
+		state.AddTempBlock(domain, now.Add(50*time.Minute))
+	}
+	blocked := e.GetDomainsToBlock(cfg, now.Add(10*time.Minute))
+	slices.Sort(blocked)
+	if want := []string{"games.com", "news.com", "reddit.com", "youtube.com"}; !slices.Equal(blocked, want) {
+		t.Errorf("Expected %v during the session, got %v", want, blocked)
+	}
+
+	// Once it ends, only the configured blocks remain
+	CleanupExpiredTempBlocks(now.Add(50 * time.Minute))
+	if blocks := state.GetTempBlocks(); len(blocks) != 0 {
+		t.Errorf("Expected the focus blocks to be removed, got %+v", blocks)
+	}
+	if blocked := e.GetDomainsToBlock(cfg, now.Add(50*time.Minute)); !slices.Equal(blocked, []string{"games.com"}) {
+		t.Errorf("Expected only games.com blocked after the session, got %v", blocked)
+	}
+}
+
+func TestEnforcementCheck_FocusSession(t *testing.T) {
+	state.SetTempUnblocks(nil)
+	state.SetTempBlocks(nil)
+	t.Cleanup(func() { state.SetTempBlocks(nil) })
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(configPath, []byte("domains:\n  - {name: games.com}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config.SetConfigPath(configPath)
+	defer config.SetConfigPath(config.GlockerConfigFile)
+
+	fake := utils.NewFakeTimeProvider(time.Date(2026, 1, 6, 10, 0, 0, 0, time.Local))
+	SetClock(fake)
+	defer SetClock(utils.DefaultTimeProvider{})
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	e := NewEngine()
+	e.InitialEnforcement(cfg)
+
+	lastEvent := func() state.EnforcementEvent {
+		history := state.GetEnforcementHistory()
+		return history[len(history)-1]
+	}
+
+	// A session starting is picked up by the next check
+	state.AddTempBlock("reddit.com", fake.Now().Add(50*time.Minute))
+	fake.Advance(time.Minute)
+	e.EnforcementCheck(cfg)
+	if event := lastEvent(); event.Reason != "focus session changed" || event.BlockedCount != 2 {
+		t.Errorf("Expected reddit.com to be blocked for the session, got %+v", event)
+	}
+
+	// And so is it ending
+	fake.Advance(50 * time.Minute)
+	e.EnforcementCheck(cfg)
+	if event := lastEvent(); event.Reason != "focus session changed" || event.BlockedCount != 1 || !event.Time.Equal(fake.Now()) {
+		t.Errorf("Expected reddit.com to be unblocked after the session, got %+v", event)
+	}
+}
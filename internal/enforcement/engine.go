@@ -0,0 +1,125 @@
+package enforcement
+
+import (
+	"sync"
+	"time"
+
+	"glocker/internal/config"
+	"glocker/internal/state"
+	"glocker/internal/utils"
+)
+
+// clock supplies the current time to enforcement; tests replace it with SetClock.
+var clock utils.TimeProvider = utils.DefaultTimeProvider{}
+
+// SetClock makes enforcement read the current time from c.
+func SetClock(c utils.TimeProvider) {
+	clock = c
+}
+
+// Now returns the current time from the enforcement clock, for callers whose
+// decisions have to agree with enforcement's, such as the control commands.
+func Now() time.Time {
+	return clock.Now()
+}
+
+// Engine enforces a config and keeps the state needed to check it cheaply between
+// full enforcements: the cached domain settings, the expected hosts file checksum
+// and the time window, temp unblock and sudoers state of the last check.
+// Engines are independent of each other; the daemon uses the default engine
+// through the package-level functions.
+type Engine struct {
+	state *EnforcementState
+
+	// run serializes enforcement runs. A check triggered by a file change must
+	// not compare the hosts file with the expected checksum while a forced run
+	// is between writing the file and recording its new checksum.
+	run sync.Mutex
+}
+
+// NewEngine returns an engine that hasn't enforced anything yet.
+func NewEngine() *Engine {
+	return &Engine{state: newEnforcementState()}
+}
+
+var defaultEngine = NewEngine()
+
+// InitialEnforcement performs the initial full enforcement with the default engine.
+func InitialEnforcement(cfg *config.Config) {
+	defaultEngine.InitialEnforcement(cfg)
+}
+
+// EnforcementCheck performs a lightweight check with the default engine.
+func EnforcementCheck(cfg *config.Config) {
+	defaultEngine.EnforcementCheck(cfg)
+}
+
+// ForceEnforcement forces a full enforcement cycle with the default engine.
+func ForceEnforcement(cfg *config.Config) {
+	defaultEngine.ForceEnforcement(cfg)
+}
+
+// GetDomainsToBlock returns the domains that should be blocked at now.
+func GetDomainsToBlock(cfg *config.Config, now time.Time) []string {
+	return defaultEngine.GetDomainsToBlock(cfg, now)
+}
+
+// SuspendEnforcement lifts blocking for a pause with the default engine.
+func SuspendEnforcement(cfg *config.Config) {
+	defaultEngine.SuspendEnforcement(cfg)
+}
+
+// RunSelfTest runs the block self-test on the default engine's blocked domains.
+func RunSelfTest(cfg *config.Config, now time.Time) state.SelfTestResult {
+	return defaultEngine.RunSelfTest(cfg, now)
+}
+
+// GetTimeWindowState returns the default engine's time window state at now.
+func GetTimeWindowState(now time.Time) map[string]bool {
+	return defaultEngine.GetTimeWindowState(now)
+}
+
+// GetEnforcementState returns the default engine's state for status reporting.
+func GetEnforcementState() (lastEnforcement time.Time, blockedCount int, hostsHash string) {
+	return defaultEngine.GetEnforcementState()
+}
+
+// Generation returns the number of full enforcements the default engine has run.
+func Generation() uint64 {
+	return defaultEngine.Generation()
+}
+
+// GetTimeWindowDomains returns the default engine's cached time-windowed domains.
+func GetTimeWindowDomains() []config.Domain {
+	return defaultEngine.GetTimeWindowDomains()
+}
+
+// IsTimeWindowed reports whether the default engine's config only blocks domain during time windows.
+func IsTimeWindowed(domain string) bool {
+	return defaultEngine.IsTimeWindowed(domain)
+}
+
+// GetEnforcedDomains returns the domains of the default engine's last full enforcement.
+func GetEnforcedDomains() []config.Domain {
+	return defaultEngine.GetEnforcedDomains()
+}
+
+// IsUnblockable checks if the default engine allows domain to be unblocked at now.
+func IsUnblockable(domain string, now time.Time) (canUnblock bool, inConfig bool) {
+	return defaultEngine.IsUnblockable(domain, now)
+}
+
+// ActiveAbsoluteWindow returns the default engine's absolute window for domain at now.
+func ActiveAbsoluteWindow(domain string, now time.Time) (config.TimeWindow, bool) {
+	return defaultEngine.ActiveAbsoluteWindow(domain, now)
+}
+
+// GetUnblockMinutes returns domain's own unblock duration in the default engine.
+func GetUnblockMinutes(domain string) int {
+	return defaultEngine.GetUnblockMinutes(domain)
+}
+
+// InitializeTestCache initializes the default engine's cache for testing.
+func InitializeTestCache(domains []config.Domain) {
+	defaultEngine.InitializeTestCache(domains)
+}
@@ -0,0 +1,74 @@
+package enforcement
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"glocker/internal/config"
+	"glocker/internal/notify"
+	"glocker/internal/state"
+	"glocker/internal/utils"
+)
+
+// expiryWarner sends a desktop notification shortly before each temporary unblock
+// expires, so the site closing doesn't come as a surprise.
+type expiryWarner struct {
+	clock   utils.TimeProvider
+	warning time.Duration
+	notify  func(domain string, remaining time.Duration)
+
+	warned map[string]time.Time // Domain to the expiry it was warned about
+}
+
+// MonitorUnblockExpiry runs a background goroutine that warns about temporary
+// unblocks expiry_warning_seconds before they expire.
+func MonitorUnblockExpiry(cfg *config.Config) {
+	warning := cfg.Unblocking.ExpiryWarning()
+	if warning <= 0 {
+		return
+	}
+
+	warner := &expiryWarner{
+		clock:   clock,
+		warning: warning,
+		notify: func(domain string, remaining time.Duration) {
+			notify.SendNotification(cfg, "Glocker",
+				fmt.Sprintf("Unblock of %s ends in %d seconds", domain, int(remaining.Round(time.Second)/time.Second)),
+				"normal", "dialog-information")
+		},
+		warned: make(map[string]time.Time),
+	}
+
+	ticker := time.NewTicker(1 * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		warner.check(state.GetTempUnblocks())
+	}
+}
+
+// check warns once about each unblock that is within the warning period.
+func (w *expiryWarner) check(unblocks []state.TempUnblock) {
+	now := w.clock.Now()
+
+	for _, unblock := range unblocks {
+		if !expiryWarningDue(unblock, now, w.warning) || w.warned[unblock.Domain].Equal(unblock.ExpiresAt) {
+			continue
+		}
+		w.warned[unblock.Domain] = unblock.ExpiresAt
+		slog.Debug("Warning about unblock expiry", "domain", unblock.Domain, "expires_at", unblock.ExpiresAt.Format("15:04:05"))
+		w.notify(unblock.Domain, unblock.ExpiresAt.Sub(now))
+	}
+
+	// Forget unblocks that have expired, so a new unblock of the domain is warned about
+	for domain, expiresAt := range w.warned {
+		if !now.Before(expiresAt) {
+			delete(w.warned, domain)
+		}
+	}
+}
+
+// expiryWarningDue reports whether now is within warning of the unblock's expiry.
+func expiryWarningDue(unblock state.TempUnblock, now time.Time, warning time.Duration) bool {
+	return warning > 0 && now.Before(unblock.ExpiresAt) && !now.Before(unblock.ExpiresAt.Add(-warning))
+}
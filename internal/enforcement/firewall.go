@@ -1,16 +1,25 @@
 package enforcement
 
 import (
+	"fmt"
 	"log/slog"
 	"net"
 	"os/exec"
+	"strings"
 
+	"glocker/internal/config"
 	"glocker/internal/utils"
 )
 
+// FirewallRuleMarker is the comment that identifies glocker's firewall rules.
+const FirewallRuleMarker = "GLOCKER-BLOCK"
+
 // UpdateFirewall updates iptables and ip6tables rules to block specified domains and IPs.
 // It resolves domain names to IP addresses and creates firewall rules for both IPv4 and IPv6.
-func UpdateFirewall(domains []string, dryRun bool) error {
+// Names are resolved concurrently through dnsCache, so hosts are only looked up
+// again once their DNS TTL has run out. Sink and loopback addresses are never
+// blocked.
+func UpdateFirewall(cfg *config.Config, domains []string, dryRun bool) error {
 	slog.Debug("Starting firewall update", "domains_count", len(domains), "dry_run", dryRun)
 
 	if dryRun {
@@ -18,15 +27,46 @@ func UpdateFirewall(domains []string, dryRun bool) error {
 		return nil
 	}
 
+	// Missing tools were reported at startup; skip what they would do
+	caps := GetCapabilities()
+	if !caps.Iptables && !caps.Ip6tables {
+		slog.Debug("Skipping firewall update, neither iptables nor ip6tables is available")
+		return nil
+	}
+
 	// Clear old rules with our marker
-	slog.Debug("Clearing old IPv4 firewall rules")
-	clearCmd := `iptables -S OUTPUT | grep 'GLOCKER-BLOCK' | sed 's/-A/-D/' | while read rule; do iptables $rule 2>/dev/null; done`
-	exec.Command("bash", "-c", clearCmd).Run()
+	if caps.Iptables {
+		slog.Debug("Clearing old IPv4 firewall rules")
+		if _, err := ClearFirewallRules("iptables"); err != nil {
+			slog.Debug("Failed to clear IPv4 firewall rules", "error", err)
+		}
+	}
 
 	// Also clear ip6tables rules
-	slog.Debug("Clearing old IPv6 firewall rules")
-	clearCmd6 := `ip6tables -S OUTPUT | grep 'GLOCKER-BLOCK' | sed 's/-A/-D/' | while read rule; do ip6tables $rule 2>/dev/null; done`
-	exec.Command("bash", "-c", clearCmd6).Run()
+	if caps.Ip6tables {
+		slog.Debug("Clearing old IPv6 firewall rules")
+		if _, err := ClearFirewallRules("ip6tables"); err != nil {
+			slog.Debug("Failed to clear IPv6 firewall rules", "error", err)
+		}
+	}
+
+	// Resolve every hostname up front
+	var hosts []string
+	for _, domain := range domains {
+		if !utils.IsIPAddress(domain) {
+			hosts = append(hosts, domain)
+		}
+	}
+	var resolved4, resolved6 map[string][]string
+	now := clock.Now()
+	setNameservers(cfg)
+	if caps.Iptables {
+		resolved4 = dnsCache.ResolveAll(hosts, "A", now)
+	}
+	if caps.Ip6tables {
+		resolved6 = dnsCache.ResolveAll(hosts, "AAAA", now)
+	}
+	slog.Debug("Resolved hostnames for firewall rules", "hosts", len(hosts))
 
 	totalIPs := 0
 	for _, domain := range domains {
@@ -44,10 +84,13 @@ func UpdateFirewall(domains []string, dryRun bool) error {
 			}
 
 			if ip.To4() != nil {
+				if !caps.Iptables {
+					continue
+				}
 				// IPv4 address
 				cmd := exec.Command("iptables", "-I", "OUTPUT", "-d", domain,
 					"-j", "REJECT", "--reject-with", "icmp-host-unreachable",
-					"-m", "comment", "--comment", "GLOCKER-BLOCK")
+					"-m", "comment", "--comment", FirewallRuleMarker)
 
 				if err := cmd.Run(); err == nil {
 					totalIPs++
@@ -56,10 +99,13 @@ func UpdateFirewall(domains []string, dryRun bool) error {
 					slog.Debug("Failed to add IPv4 firewall rule for IP", "ip", domain, "error", err)
 				}
 			} else {
+				if !caps.Ip6tables {
+					continue
+				}
 				// IPv6 address
 				cmd := exec.Command("ip6tables", "-I", "OUTPUT", "-d", domain,
 					"-j", "REJECT", "--reject-with", "icmp6-adm-prohibited",
-					"-m", "comment", "--comment", "GLOCKER-BLOCK")
+					"-m", "comment", "--comment", FirewallRuleMarker)
 
 				if err := cmd.Run(); err == nil {
 					totalIPs++
@@ -69,17 +115,15 @@ func UpdateFirewall(domains []string, dryRun bool) error {
 				}
 			}
 		} else {
-			// It's a hostname, resolve and block
-			slog.Debug("Entry is a hostname, resolving", "hostname", domain)
-
-			// Resolve and block IPv4 addresses
-			ips := utils.ResolveIPs(domain, "A")
+			// It's a hostname, block the addresses it resolved to
+			// Block IPv4 addresses
+			ips := firewallAddresses(cfg, resolved4[domain])
 			slog.Debug("Resolved IPv4 addresses", "domain", domain, "ips", ips)
 
 			for _, ip := range ips {
 				cmd := exec.Command("iptables", "-I", "OUTPUT", "-d", ip,
 					"-j", "REJECT", "--reject-with", "icmp-host-unreachable",
-					"-m", "comment", "--comment", "GLOCKER-BLOCK")
+					"-m", "comment", "--comment", FirewallRuleMarker)
 
 				if err := cmd.Run(); err == nil {
 					totalIPs++
@@ -89,14 +133,14 @@ func UpdateFirewall(domains []string, dryRun bool) error {
 				}
 			}
 
-			// Resolve and block IPv6 addresses
-			ips6 := utils.ResolveIPs(domain, "AAAA")
+			// Block IPv6 addresses
+			ips6 := firewallAddresses(cfg, resolved6[domain])
 			slog.Debug("Resolved IPv6 addresses", "domain", domain, "ips", ips6)
 
 			for _, ip := range ips6 {
 				cmd := exec.Command("ip6tables", "-I", "OUTPUT", "-d", ip,
 					"-j", "REJECT", "--reject-with", "icmp6-adm-prohibited",
-					"-m", "comment", "--comment", "GLOCKER-BLOCK")
+					"-m", "comment", "--comment", FirewallRuleMarker)
 
 				if err := cmd.Run(); err == nil {
 					totalIPs++
@@ -111,3 +155,81 @@ func UpdateFirewall(domains []string, dryRun bool) error {
 	slog.Debug("Firewall update completed", "total_ips_blocked", totalIPs)
 	return nil
 }
+
+// ClearFirewallRules deletes every glocker rule from the OUTPUT chain, using tool
+// ("iptables" or "ip6tables"). Returns the number of rules deleted.
+func ClearFirewallRules(tool string) (int, error) {
+	output, err := exec.Command(tool, "-S", "OUTPUT").Output()
+	if err != nil {
+		return 0, fmt.Errorf("listing %s OUTPUT rules: %w", tool, err)
+	}
+
+	deleteArgs := firewallDeleteArgs(string(output))
+	deleted := 0
+	var lastErr error
+	for _, args := range deleteArgs {
+		if err := exec.Command(tool, args...).Run(); err != nil {
+			slog.Debug("Failed to delete firewall rule", "tool", tool, "args", args, "error", err)
+			lastErr = err
+			continue
+		}
+		deleted++
+	}
+
+	if lastErr != nil {
+		return deleted, fmt.Errorf("deleting %d of %d %s rules: %w", len(deleteArgs)-deleted, len(deleteArgs), tool, lastErr)
+	}
+	return deleted, nil
+}
+
+// firewallDeleteArgs parses "iptables -S OUTPUT" output and returns, for each glocker
+// rule, the arguments that delete it (the rule with -A replaced by -D).
+func firewallDeleteArgs(rules string) [][]string {
+	var result [][]string
+	for _, line := range strings.Split(rules, "\n") {
+		if !strings.Contains(line, FirewallRuleMarker) {
+			continue
+		}
+		args := splitRuleArgs(line)
+		if len(args) < 2 || args[0] != "-A" {
+			continue
+		}
+		args[0] = "-D"
+		result = append(result, args)
+	}
+	return result
+}
+
+// splitRuleArgs splits a rule printed by iptables -S into arguments. iptables quotes
+// arguments containing spaces (e.g. comments) with double quotes and escapes quotes
+// inside them with a backslash.
+func splitRuleArgs(line string) []string {
+	var args []string
+	var current strings.Builder
+	inQuotes, inArg := false, false
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case inQuotes && c == '\\' && i+1 < len(line):
+			i++
+			current.WriteByte(line[i])
+		case c == '"':
+			inQuotes = !inQuotes
+			inArg = true
+		case !inQuotes && (c == ' ' || c == '\t' || c == '\r'):
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteByte(c)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args
+}
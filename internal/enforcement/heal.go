@@ -0,0 +1,86 @@
+package enforcement
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"os/exec"
+	"strings"
+	"time"
+
+	"glocker/internal/config"
+)
+
+// liveFirewallRules counts the glocker rules in the OUTPUT chains (replaced in tests).
+var liveFirewallRules = CountFirewallRules
+
+// CountFirewallRules counts the glocker rules in the iptables and ip6tables
+// OUTPUT chains, including the DNS-over-TLS and firewall_rules port rules.
+// Tools that aren't available count as having no rules.
+func CountFirewallRules() int {
+	caps := GetCapabilities()
+	count := 0
+	for tool, available := range map[string]bool{"iptables": caps.Iptables, "ip6tables": caps.Ip6tables} {
+		if !available {
+			continue
+		}
+		output, err := exec.Command(tool, "-S", "OUTPUT").Output()
+		if err != nil {
+			slog.Debug("Failed to list firewall rules", "tool", tool, "error", err)
+			continue
+		}
+		count += strings.Count(string(output), FirewallRuleMarker)
+	}
+	return count
+}
+
+// rebuildFirewall replaces the firewall rules with rules for blockedDomains, the
+// DNS-over-TLS port, the active firewall_rules ports and allowlist mode, and
+// remembers how many rules that left in place for the self-heal check.
+func (e *Engine) rebuildFirewall(cfg *config.Config, blockedDomains []string, now time.Time) {
+	if err := UpdateFirewall(cfg, blockedDomains, false); err != nil {
+		log.Printf("ERROR updating firewall: %v", err)
+	}
+	if cfg.BlockDoH {
+		BlockDoT(false)
+	}
+	portRules := BlockPorts(cfg, now, false)
+	BlockAllButAllowlist(cfg, now, false)
+	ruleCount := liveFirewallRules()
+
+	e.state.mu.Lock()
+	e.state.lastPortRules = portRules
+	e.state.expectedFirewallRules = ruleCount
+	e.state.mu.Unlock()
+}
+
+// missingFirewallRules returns how many of the rules the last firewall rebuild
+// left in place are gone.
+func (e *Engine) missingFirewallRules() int {
+	e.state.mu.RLock()
+	expected := e.state.expectedFirewallRules
+	e.state.mu.RUnlock()
+	if expected == 0 {
+		return 0
+	}
+	return max(expected-liveFirewallRules(), 0)
+}
+
+// healFirewall rebuilds the firewall when rules have disappeared since the last
+// rebuild, e.g. after "iptables -F". Nothing is reloaded unless rules are missing.
+func (e *Engine) healFirewall(now time.Time) {
+	missing := e.missingFirewallRules()
+	if missing == 0 {
+		return
+	}
+	log.Printf("SELF-HEAL: %d firewall rules are missing - rebuilding the firewall", missing)
+
+	// The full domain list isn't kept in memory
+	freshCfg, err := LoadEnforcedConfig()
+	if err != nil {
+		log.Printf("ERROR: Failed to reload config for firewall self-heal: %v", err)
+		return
+	}
+	e.rebuildFirewall(freshCfg, e.GetDomainsToBlock(freshCfg, now), now)
+	e.recordEnforcement(now, "firewall heal", fmt.Sprintf("%d firewall rules were missing", missing))
+}
@@ -7,10 +7,15 @@ import (
 	"os"
 	"os/exec"
 	"strings"
+	"sync"
 
 	"glocker/internal/config"
+	"glocker/internal/state"
 )
 
+// immutableWarning makes sure a failing chattr +i on the hosts file is reported only once.
+var immutableWarning sync.Once
+
 // UpdateHosts updates the /etc/hosts file with blocked domains.
 // It removes old glocker entries and adds new ones based on the provided domains list.
 // Uses chunked writing for performance with large domain lists.
@@ -43,6 +48,20 @@ func UpdateHosts(cfg *config.Config, domains []string, dryRun bool) error {
 	}
 	slog.Debug("Read hosts file", "size_bytes", len(content), "exists", err == nil)
 
+	// A deleted or wiped hosts file lost the user's own entries along with ours
+	if strings.TrimSpace(stripHostsBlockSection(string(content))) == "" {
+		if backup, err := readHostsBackup(hostsPath); err == nil && len(backup) > 0 {
+			log.Printf("Hosts file %s was wiped - restoring original entries from backup", hostsPath)
+			content = backup
+		} else {
+			slog.Debug("Hosts file is empty and no backup is available", "error", err)
+		}
+	} else if !dryRun {
+		if err := CreateHostsBackup(hostsPath); err != nil {
+			slog.Debug("Failed to create hosts backup", "error", err)
+		}
+	}
+
 	lines := strings.Split(string(content), "\n")
 	var originalLines []string
 	inBlockSection := false
@@ -73,11 +92,14 @@ func UpdateHosts(cfg *config.Config, domains []string, dryRun bool) error {
 	}
 
 	// Remove immutable flag temporarily
-	slog.Debug("Removing immutable flag from hosts file", "command", "chattr -i "+hostsPath)
-	if err := exec.Command("chattr", "-i", hostsPath).Run(); err != nil {
-		slog.Debug("Failed to remove immutable flag (may not be set)", "error", err)
-	} else {
-		slog.Debug("Successfully removed immutable flag")
+	canChattr := GetCapabilities().Chattr
+	if canChattr {
+		slog.Debug("Removing immutable flag from hosts file", "command", "chattr -i "+hostsPath)
+		if err := exec.Command("chattr", "-i", hostsPath).Run(); err != nil {
+			slog.Debug("Failed to remove immutable flag (may not be set)", "error", err)
+		} else {
+			slog.Debug("Successfully removed immutable flag")
+		}
 	}
 
 	// Open file for writing
@@ -121,29 +143,24 @@ func UpdateHosts(cfg *config.Config, domains []string, dryRun bool) error {
 	totalDomains := len(domains)
 	chunksWritten := 0
 
+	// Publish progress so status queries during long writes are informative
+	state.SetHostsWriteProgress(0, totalDomains)
+	defer state.ClearHostsWriteProgress()
+
 	for i := 0; i < totalDomains; i += chunkSize {
 		end := i + chunkSize
 		if end > totalDomains {
 			end = totalDomains
 		}
 
-		// Build chunk content
-		var chunkBuilder strings.Builder
-		for j := i; j < end; j++ {
-			domain := domains[j]
-			chunkBuilder.WriteString(fmt.Sprintf("127.0.0.1 %s\n", domain))
-			chunkBuilder.WriteString(fmt.Sprintf("127.0.0.1 www.%s\n", domain))
-			chunkBuilder.WriteString(fmt.Sprintf("::1 %s\n", domain))
-			chunkBuilder.WriteString(fmt.Sprintf("::1 www.%s\n", domain))
-		}
-
 		// Write chunk to file
-		if _, err := file.WriteString(chunkBuilder.String()); err != nil {
+		if _, err := file.WriteString(buildHostsChunk(cfg, domains[i:end])); err != nil {
 			slog.Debug("Failed to write domain chunk", "error", err, "chunk", chunksWritten+1)
 			return fmt.Errorf("writing domain chunk %d: %w", chunksWritten+1, err)
 		}
 
 		chunksWritten++
+		state.SetHostsWriteProgress(end, totalDomains)
 
 		// Flush and log progress every 1000 chunks or at the end
 		if chunksWritten%1000 == 0 || end == totalDomains {
@@ -173,11 +190,17 @@ func UpdateHosts(cfg *config.Config, domains []string, dryRun bool) error {
 	log.Printf("Hosts file update completed: %d domains written in %d chunks", totalDomains, chunksWritten)
 
 	// Set immutable flag
-	slog.Debug("Setting immutable flag on hosts file", "command", "chattr +i "+hostsPath)
-	if err := exec.Command("chattr", "+i", hostsPath).Run(); err != nil {
-		slog.Debug("Failed to set immutable flag", "error", err)
-	} else {
-		slog.Debug("Successfully set immutable flag")
+	if canChattr {
+		slog.Debug("Setting immutable flag on hosts file", "command", "chattr +i "+hostsPath)
+		if err := exec.Command("chattr", "+i", hostsPath).Run(); err != nil {
+			slog.Debug("Failed to set immutable flag", "error", err)
+			// chattr exists but the filesystem may not support immutable files; say so once
+			immutableWarning.Do(func() {
+				log.Printf("WARNING: could not make %s immutable (the filesystem may not support it): %v", hostsPath, err)
+			})
+		} else {
+			slog.Debug("Successfully set immutable flag")
+		}
 	}
 
 	// Update checksum after legitimate change
@@ -189,6 +212,61 @@ func UpdateHosts(cfg *config.Config, domains []string, dryRun bool) error {
 	return nil
 }
 
+// buildHostsChunk returns the hosts file lines blocking domains, pointing each one
+// (and its www. form, unless disabled) at the configured IPv4 and IPv6 sinks.
+func buildHostsChunk(cfg *config.Config, domains []string) string {
+	ipv4, ipv6 := cfg.HostsSinks()
+	includeWWW := cfg.HostsIncludesWWW()
+
+	var chunkBuilder strings.Builder
+	for _, domain := range domains {
+		chunkBuilder.WriteString(fmt.Sprintf("%s %s\n", ipv4, domain))
+		if includeWWW {
+			chunkBuilder.WriteString(fmt.Sprintf("%s www.%s\n", ipv4, domain))
+		}
+		chunkBuilder.WriteString(fmt.Sprintf("%s %s\n", ipv6, domain))
+		if includeWWW {
+			chunkBuilder.WriteString(fmt.Sprintf("%s www.%s\n", ipv6, domain))
+		}
+	}
+	return chunkBuilder.String()
+}
+
+// CreateHostsBackup saves the hosts file's own entries (everything before the glocker
+// section) next to it, so they can be restored if the file is deleted.
+// It only creates the backup if one doesn't already exist.
+func CreateHostsBackup(hostsPath string) error {
+	backupPath := hostsPath + config.HostsBackupSuffix
+	if _, err := os.Stat(backupPath); err == nil {
+		// Backup already exists, don't overwrite
+		return nil
+	}
+
+	content, err := os.ReadFile(hostsPath)
+	if err != nil {
+		return err
+	}
+
+	original := stripHostsBlockSection(string(content))
+	if strings.TrimSpace(original) == "" {
+		return fmt.Errorf("hosts file %s has no entries to back up", hostsPath)
+	}
+	return os.WriteFile(backupPath, []byte(original), 0644)
+}
+
+// readHostsBackup returns the original hosts entries saved by CreateHostsBackup.
+func readHostsBackup(hostsPath string) ([]byte, error) {
+	return os.ReadFile(hostsPath + config.HostsBackupSuffix)
+}
+
+// stripHostsBlockSection returns hosts file content up to the glocker start marker.
+func stripHostsBlockSection(content string) string {
+	if idx := strings.Index(content, config.HostsMarkerStart); idx != -1 {
+		content = content[:idx]
+	}
+	return strings.TrimRight(content, "\n \t") + "\n"
+}
+
 // CleanupHostsFile removes all glocker entries from the hosts file.
 // This is used during uninstallation to restore the original hosts file.
 func CleanupHostsFile(cfg *config.Config) error {
@@ -212,7 +290,9 @@ func CleanupHostsFile(cfg *config.Config) error {
 	}
 
 	// Remove immutable flag
-	exec.Command("chattr", "-i", hostsPath).Run()
+	if GetCapabilities().Chattr {
+		exec.Command("chattr", "-i", hostsPath).Run()
+	}
 
 	// Write back the cleaned content
 	newContent := strings.Join(originalLines, "\n")
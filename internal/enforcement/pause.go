@@ -0,0 +1,64 @@
+package enforcement
+
+import (
+	"log"
+	"log/slog"
+	"time"
+
+	"glocker/internal/config"
+	"glocker/internal/state"
+)
+
+// SuspendEnforcement lifts hosts, firewall and sudoers blocking for a pause (see
+// state.SetPausedUntil). EnforcementCheck leaves everything alone until the pause
+// runs out and then rebuilds the full enforcement.
+func (e *Engine) SuspendEnforcement(cfg *config.Config) {
+	e.run.Lock()
+	defer e.run.Unlock()
+	now := clock.Now()
+	log.Printf("Suspending enforcement until %s", state.GetPausedUntil().Format("15:04:05"))
+
+	if cfg.EnableHosts {
+		if err := UpdateHosts(cfg, nil, false); err != nil {
+			log.Printf("ERROR clearing hosts blocks: %v", err)
+		} else if hash, err := computeFileChecksum(cfg.HostsPath); err == nil {
+			// Keep tamper detection consistent with the unblocked hosts file
+			e.state.mu.Lock()
+			e.state.expectedHostsHash = hash
+			e.state.lastBlockedCount = 0
+			e.state.mu.Unlock()
+		}
+	}
+
+	if cfg.EnableFirewall {
+		if err := UpdateFirewall(cfg, nil, false); err != nil {
+			log.Printf("ERROR clearing firewall rules: %v", err)
+		}
+	}
+
+	// IsSudoAllowed allows sudo while paused
+	if cfg.Sudoers.Enabled {
+		if err := UpdateSudoers(cfg, now, false, false); err != nil {
+			log.Printf("ERROR updating sudoers: %v", err)
+		}
+		e.state.mu.Lock()
+		e.state.lastSudoersLocked = false
+		e.state.mu.Unlock()
+	}
+}
+
+// checkPause handles a pause at the start of an enforcement check. It returns true
+// when the check should be skipped: while paused, or after resuming, which already
+// ran a full enforcement.
+func (e *Engine) checkPause(cfg *config.Config, now time.Time) bool {
+	if state.EndExpiredPause(now) {
+		log.Println("Pause ended - resuming enforcement")
+		e.forceEnforcement(cfg, "pause ended")
+		return true
+	}
+	if state.IsPaused(now) {
+		slog.Debug("Enforcement paused, skipping check", "until", state.GetPausedUntil().Format("15:04:05"))
+		return true
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+package enforcement
+
+import (
+	"log/slog"
+	"os/exec"
+	"slices"
+	"strconv"
+	"time"
+
+	"glocker/internal/config"
+)
+
+// portRuleArgs returns the arguments of the rules rejecting outbound traffic to
+// each firewall_rules port whose time windows are active at now (rules without
+// windows always are). The same arguments work for iptables and ip6tables.
+func portRuleArgs(rules []config.FirewallRule, now time.Time) [][]string {
+	var result [][]string
+	for _, rule := range rules {
+		if !isPortRuleActive(rule, now) {
+			continue
+		}
+		for _, protocol := range rule.Protocols() {
+			result = append(result, []string{"-I", "OUTPUT", "-p", protocol, "--dport", strconv.Itoa(rule.Port),
+				"-j", "REJECT", "-m", "comment", "--comment", FirewallRuleMarker})
+		}
+	}
+	return result
+}
+
+// isPortRuleActive reports whether rule blocks its port at now.
+func isPortRuleActive(rule config.FirewallRule, now time.Time) bool {
+	if len(rule.TimeWindows) == 0 {
+		return true
+	}
+	return config.AnyWindowActive(rule.TimeWindows, now)
+}
+
+// portRulesChanged reports whether two sets of port rule arguments differ.
+func portRulesChanged(last, current [][]string) bool {
+	return !slices.EqualFunc(last, current, slices.Equal[[]string])
+}
+
+// BlockPorts adds the firewall rules for the firewall_rules ports active at now.
+// Like BlockDoT, the rules carry the glocker marker, so UpdateFirewall clears
+// them; call BlockPorts after every firewall update. Returns the arguments of
+// the rules it applied.
+func BlockPorts(cfg *config.Config, now time.Time, dryRun bool) [][]string {
+	args := portRuleArgs(cfg.FirewallRules, now)
+	if dryRun {
+		slog.Debug("Dry run mode - would block ports", "rules", len(args))
+		return args
+	}
+
+	caps := GetCapabilities()
+	for tool, available := range map[string]bool{"iptables": caps.Iptables, "ip6tables": caps.Ip6tables} {
+		if !available {
+			continue
+		}
+		for _, ruleArgs := range args {
+			if err := exec.Command(tool, ruleArgs...).Run(); err != nil {
+				slog.Debug("Failed to add port firewall rule", "tool", tool, "args", ruleArgs, "error", err)
+			}
+		}
+	}
+	return args
+}
@@ -0,0 +1,40 @@
+package enforcement
+
+import (
+	"log"
+
+	"glocker/internal/config"
+	"glocker/internal/state"
+)
+
+// applyActiveProfile merges the currently active runtime profile (if any) into cfg.
+func applyActiveProfile(cfg *config.Config) {
+	name, _ := state.GetActiveProfile()
+	if name == "" {
+		return
+	}
+	if err := config.ApplyProfile(cfg, name); err != nil {
+		log.Printf("ERROR applying profile %s: %v", name, err)
+	}
+}
+
+// LoadEnforcedConfig reloads the config from disk with everything enforcement adds on
+// top of it: the active profile, the cached remote blocklists and the DoH resolvers.
+// Use this instead of config.LoadConfig when the full list of blocked domains is needed.
+func LoadEnforcedConfig() (*config.Config, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	PrepareEnforcedConfig(cfg)
+	return cfg, nil
+}
+
+// PrepareEnforcedConfig applies the active profile and merges the cached remote
+// blocklists and, with block_doh, the DNS-over-HTTPS resolvers into a freshly
+// loaded config.
+func PrepareEnforcedConfig(cfg *config.Config) {
+	applyActiveProfile(cfg)
+	MergeRemoteBlocklists(cfg, config.BlocklistCacheDir)
+	MergeDoHDomains(cfg)
+}
@@ -0,0 +1,216 @@
+package enforcement
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"glocker/internal/config"
+	"glocker/internal/utils"
+)
+
+// Limits on how long a resolution is cached, whatever the record TTL says, and
+// on the number of lookups run at once.
+const (
+	minResolveTTL    = time.Minute     // Hosts with tiny TTLs aren't looked up on every rebuild
+	maxResolveTTL    = 24 * time.Hour  // Addresses are refreshed at least daily
+	failedResolveTTL = 5 * time.Minute // Hosts that didn't resolve are retried after this
+	resolveWorkers   = 16
+)
+
+// resolveFunc looks up the recordType ("A" or "AAAA") addresses of host and how
+// long the answer may be cached.
+type resolveFunc func(host, recordType string) ([]string, time.Duration)
+
+// resolvedHost is a cached answer. It is also the on-disk format.
+type resolvedHost struct {
+	IPs     []string  `json:"ips"`
+	Expires time.Time `json:"expires"`
+}
+
+// resolveCache caches the addresses firewall rules are built from, so a rebuild
+// only looks up hosts whose DNS TTL has run out. IPv4 and IPv6 answers are
+// cached separately.
+type resolveCache struct {
+	mu      sync.Mutex
+	entries map[string]resolvedHost // "<record type> <host>" -> answer
+	resolve resolveFunc
+	workers int
+	path    string // File the cache is persisted to ("" = memory only)
+	loaded  bool
+}
+
+// dnsCache is the cache UpdateFirewall and allowlist mode resolve through.
+var dnsCache = newResolveCache(upstreamResolve, resolveWorkers, config.DNSCacheFile)
+
+// nameservers are the DNS servers upstreamResolve asks, set from the config
+// before each firewall update.
+var nameservers struct {
+	mu      sync.Mutex
+	servers []string
+}
+
+// setNameservers makes upstreamResolve ask cfg's nameservers.
+func setNameservers(cfg *config.Config) {
+	servers := cfg.Nameservers()
+	nameservers.mu.Lock()
+	nameservers.servers = servers
+	nameservers.mu.Unlock()
+}
+
+// upstreamResolve asks the nameservers directly, so the hosts file glocker
+// writes blocked domains to doesn't turn every answer into the sinkhole, and
+// keeps the answer for its record TTL.
+func upstreamResolve(host, recordType string) ([]string, time.Duration) {
+	nameservers.mu.Lock()
+	servers := nameservers.servers
+	nameservers.mu.Unlock()
+	return utils.ResolveIPsVia(servers, host, recordType)
+}
+
+// firewallAddresses returns ips without the sink, loopback and unspecified
+// addresses, which must never get a firewall rule. Answers cached before DNS
+// was asked directly may still hold the sinkhole.
+func firewallAddresses(cfg *config.Config, ips []string) []string {
+	return slices.DeleteFunc(slices.Clone(ips), cfg.IsSinkAddress)
+}
+
+func newResolveCache(resolve resolveFunc, workers int, path string) *resolveCache {
+	return &resolveCache{
+		entries: make(map[string]resolvedHost),
+		resolve: resolve,
+		workers: max(workers, 1),
+		path:    path,
+	}
+}
+
+// resolveCacheKey returns the key of host's recordType answer.
+func resolveCacheKey(recordType, host string) string {
+	return recordType + " " + host
+}
+
+// cacheTTL returns how long to keep an answer with the given record TTL.
+func cacheTTL(ips []string, ttl time.Duration) time.Duration {
+	if len(ips) == 0 {
+		return failedResolveTTL
+	}
+	return min(max(ttl, minResolveTTL), maxResolveTTL)
+}
+
+// ResolveAll returns the recordType addresses of each host. Hosts without an
+// unexpired cached answer at now are looked up, at most c.workers at a time,
+// and the cache is saved if any were.
+func (c *resolveCache) ResolveAll(hosts []string, recordType string, now time.Time) map[string][]string {
+	c.mu.Lock()
+	if !c.loaded {
+		c.load(now)
+		c.loaded = true
+	}
+
+	result := make(map[string][]string, len(hosts))
+	var missing []string
+	for _, host := range hosts {
+		if entry, ok := c.entries[resolveCacheKey(recordType, host)]; ok && now.Before(entry.Expires) {
+			result[host] = entry.IPs
+		} else {
+			missing = append(missing, host)
+		}
+	}
+	c.mu.Unlock()
+
+	slices.Sort(missing)
+	missing = slices.Compact(missing)
+	if len(missing) == 0 {
+		return result
+	}
+
+	jobs := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < min(c.workers, len(missing)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for host := range jobs {
+				ips, ttl := c.resolve(host, recordType)
+				c.mu.Lock()
+				c.entries[resolveCacheKey(recordType, host)] = resolvedHost{IPs: ips, Expires: now.Add(cacheTTL(ips, ttl))}
+				result[host] = ips
+				c.mu.Unlock()
+			}
+		}()
+	}
+	for _, host := range missing {
+		jobs <- host
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := c.save(now); err != nil {
+		log.Printf("Warning: Failed to save DNS cache: %v", err)
+	}
+	return result
+}
+
+// load reads the persisted cache, keeping the answers still valid at now.
+// A missing file is not an error. The caller holds c.mu.
+func (c *resolveCache) load(now time.Time) {
+	if c.path == "" {
+		return
+	}
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Warning: Failed to read DNS cache: %v", err)
+		}
+		return
+	}
+
+	var entries map[string]resolvedHost
+	if err := json.Unmarshal(data, &entries); err != nil {
+		log.Printf("Warning: Ignoring unreadable DNS cache %s: %v", c.path, err)
+		return
+	}
+	for key, entry := range entries {
+		if now.Before(entry.Expires) {
+			c.entries[key] = entry
+		}
+	}
+}
+
+// save writes the answers still valid at now to c.path.
+func (c *resolveCache) save(now time.Time) error {
+	if c.path == "" {
+		return nil
+	}
+
+	c.mu.Lock()
+	maps.DeleteFunc(c.entries, func(_ string, entry resolvedHost) bool {
+		return !now.Before(entry.Expires)
+	})
+	data, err := json.Marshal(c.entries)
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal DNS cache: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	// Write to a temp file and rename so a crash never leaves a truncated file
+	tmpPath := c.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write DNS cache: %w", err)
+	}
+	if err := os.Rename(tmpPath, c.path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace DNS cache: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package enforcement
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"time"
+
+	"glocker/internal/config"
+	"glocker/internal/state"
+	"glocker/internal/utils"
+)
+
+const (
+	defaultSelfTestSampleSize      = 5
+	defaultSelfTestIntervalMinutes = 60
+
+	// selfTestCandidatePool is how many blocked domains are remembered for self-tests,
+	// so the full block list doesn't have to be kept in memory.
+	selfTestCandidatePool = 100
+
+	selfTestLookupTimeout = 5 * time.Second
+	selfTestDialTimeout   = 3 * time.Second
+)
+
+// Resolution and connection used by the self-test (replaced in tests).
+var (
+	selfTestLookupHost = net.DefaultResolver.LookupHost
+	selfTestDial       = (&net.Dialer{}).DialContext
+)
+
+// rememberSelfTestCandidates keeps a random sample of the blocked domains for later self-tests.
+func (e *Engine) rememberSelfTestCandidates(blockedDomains []string) {
+	candidates := make([]string, 0, min(len(blockedDomains), selfTestCandidatePool))
+	for _, i := range rand.Perm(len(blockedDomains)) {
+		if len(candidates) == selfTestCandidatePool {
+			break
+		}
+		if domain := blockedDomains[i]; !utils.IsIPAddress(domain) {
+			candidates = append(candidates, domain)
+		}
+	}
+
+	e.state.mu.Lock()
+	e.state.selfTestCandidates = candidates
+	e.state.mu.Unlock()
+}
+
+// RunSelfTest checks that a sample of the blocked domains is really unreachable: each must
+// resolve to a sinkhole address (or not at all), or refuse connections. Domains that are
+// still reachable point to DNS-over-HTTPS, cached DNS or a broken rule, and are logged.
+// The result is recorded for the status output.
+func (e *Engine) RunSelfTest(cfg *config.Config, now time.Time) state.SelfTestResult {
+	sampleSize := cfg.SelfTest.SampleSize
+	if sampleSize <= 0 {
+		sampleSize = defaultSelfTestSampleSize
+	}
+
+	e.state.mu.RLock()
+	candidates := e.state.selfTestCandidates
+	e.state.mu.RUnlock()
+
+	result := state.SelfTestResult{Time: now}
+	sinks := selfTestSinks(cfg)
+	for _, i := range rand.Perm(len(candidates)) {
+		if result.Sampled == sampleSize {
+			break
+		}
+		domain := candidates[i]
+		// Temporarily unblocked domains are expected to be reachable
+		if isTempUnblockedWithGrace(domain, now, cfg.Unblocking.ExpiryGrace()) {
+			continue
+		}
+
+		result.Sampled++
+		if reachable, detail := checkDomainReachable(domain, sinks); reachable {
+			result.Reachable = append(result.Reachable, domain)
+			log.Printf("SELF-TEST: %s is still reachable (%s) - check for DNS-over-HTTPS, cached DNS or a broken rule", domain, detail)
+		} else {
+			result.Verified++
+			slog.Debug("Self-test verified block", "domain", domain, "detail", detail)
+		}
+	}
+
+	state.SetSelfTestResult(result)
+	log.Printf("Self-test: %d of %d sampled blocks verified unreachable", result.Verified, result.Sampled)
+	return result
+}
+
+// MonitorSelfTest runs the block self-test right away and then periodically.
+func MonitorSelfTest(cfg *config.Config) {
+	intervalMinutes := cfg.SelfTest.IntervalMinutes
+	if intervalMinutes <= 0 {
+		intervalMinutes = defaultSelfTestIntervalMinutes
+	}
+
+	RunSelfTest(cfg, clock.Now())
+
+	ticker := time.NewTicker(time.Duration(intervalMinutes) * time.Minute)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		// Blocks are lifted on purpose during a pause
+		if state.IsPaused(now) {
+			continue
+		}
+		RunSelfTest(cfg, now)
+	}
+}
+
+// checkDomainReachable resolves a domain and, unless every address is a sinkhole,
+// tries to connect to it. Returns whether it was reachable and a short explanation.
+func checkDomainReachable(domain string, sinks map[string]bool) (bool, string) {
+	ctx, cancel := context.WithTimeout(context.Background(), selfTestLookupTimeout)
+	defer cancel()
+
+	addrs, err := selfTestLookupHost(ctx, domain)
+	if err != nil || len(addrs) == 0 {
+		return false, "does not resolve"
+	}
+
+	var routable []string
+	for _, addr := range addrs {
+		if !isSinkAddress(addr, sinks) {
+			routable = append(routable, addr)
+		}
+	}
+	if len(routable) == 0 {
+		return false, "resolves to sinkhole " + addrs[0]
+	}
+
+	// DNS isn't sinkholed (hosts disabled or bypassed); the firewall must refuse the connection
+	for _, addr := range routable {
+		dialCtx, dialCancel := context.WithTimeout(context.Background(), selfTestDialTimeout)
+		conn, err := selfTestDial(dialCtx, "tcp", net.JoinHostPort(addr, "443"))
+		dialCancel()
+		if err == nil {
+			conn.Close()
+			return true, fmt.Sprintf("resolves to %s and accepts connections", addr)
+		}
+	}
+	return false, fmt.Sprintf("resolves to %s but connections are refused", routable[0])
+}
+
+// selfTestSinks returns the addresses blocked domains are pointed at.
+func selfTestSinks(cfg *config.Config) map[string]bool {
+	ipv4, ipv6 := cfg.HostsSinks()
+	return map[string]bool{ipv4: true, ipv6: true}
+}
+
+// isSinkAddress reports whether addr is a configured sink, a loopback or an unspecified address.
+func isSinkAddress(addr string, sinks map[string]bool) bool {
+	if sinks[addr] {
+		return true
+	}
+	ip := net.ParseIP(addr)
+	return ip != nil && (ip.IsLoopback() || ip.IsUnspecified())
+}
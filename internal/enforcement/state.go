@@ -3,12 +3,15 @@ package enforcement
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"fmt"
 	"log"
 	"os"
 	"slices"
+	"strings"
 	"sync"
 	"time"
 
+	"glocker/internal/audit"
 	"glocker/internal/config"
 	"glocker/internal/state"
 )
@@ -30,6 +33,21 @@ type EnforcementState struct {
 	// This is a small set (typically <50) vs 800K permanent domains
 	unblockableDomains map[string]bool // domain name -> true if unblockable
 
+	// Per-domain unblock durations - only domains that override unblocking.temp_unblock_time
+	unblockMinutes map[string]int // domain name -> minutes
+
+	// Windows during which an unblockable domain can't be unblocked
+	absoluteWindows map[string][]config.TimeWindow // domain name -> windows
+
+	// Report categories - only domains with a category set
+	domainCategories map[string]string // domain name -> category
+
+	// Labels shown by -info - only domains with a label set
+	domainLabels map[string]string // domain name -> label
+
+	// Random sample of the blocked domains, checked by the block self-test
+	selfTestCandidates []string
+
 	// Config domain names - cached set of ALL domain names from config
 	// Used to distinguish between "in config but permanent" vs "not in config at all"
 	configDomainNames map[string]bool // domain name -> true if in config
@@ -40,34 +58,89 @@ type EnforcementState struct {
 	// Temp unblock state
 	lastTempUnblockCount int
 
+	// Focus session blocks at the last check
+	lastTempBlockCount int
+
 	// Sudoers state
 	lastSudoersLocked bool
 
+	// Port rules applied by the last firewall update
+	lastPortRules [][]string
+
+	// Whether allowlist mode was active at the last hosts and firewall update
+	lastAllowlistActive bool
+
+	// Glocker rules in place after the last firewall update, for self-healing
+	expectedFirewallRules int
+
+	// Profile that was active during the last full enforcement
+	lastActiveProfile string
+
 	// Last enforcement time
 	lastEnforcement time.Time
 
 	// Config checksum for detecting config changes
 	configChecksum string
+
+	// Number of full enforcements so far, so caches of the enforced rules know when to rebuild
+	generation uint64
 }
 
-var (
-	enforcementState = &EnforcementState{
+// newEnforcementState returns an empty enforcement state.
+func newEnforcementState() *EnforcementState {
+	return &EnforcementState{
 		lastTimeWindowState: make(map[string]bool),
 		unblockableDomains:  make(map[string]bool),
+		unblockMinutes:      make(map[string]int),
+		absoluteWindows:     make(map[string][]config.TimeWindow),
+		domainCategories:    make(map[string]string),
+		domainLabels:        make(map[string]string),
 		configDomainNames:   make(map[string]bool),
 	}
-)
+}
 
 // InitialEnforcement performs the initial full enforcement on daemon startup.
 // This builds the hosts file, applies all protections, and stores the initial state.
-func InitialEnforcement(cfg *config.Config) {
-	now := time.Now()
+func (e *Engine) InitialEnforcement(cfg *config.Config) {
+	e.run.Lock()
+	defer e.run.Unlock()
+	e.fullEnforcement(cfg, "daemon started")
+}
+
+// fullEnforcement performs a full enforcement and records it in the enforcement
+// history with reason.
+func (e *Engine) fullEnforcement(cfg *config.Config, reason string) {
+	now := clock.Now()
 	log.Printf("Performing initial enforcement at %s", now.Format("2006-01-02 15:04:05"))
 
+	// Apply the active runtime profile before caching anything
+	applyActiveProfile(cfg)
+	activeProfile, _ := state.GetActiveProfile()
+	if activeProfile != "" {
+		log.Printf("Enforcing with profile: %s", activeProfile)
+	}
+
+	// Merge remote blocklists into the always-block set (fetching any that are stale)
+	if len(cfg.RemoteBlocklists) > 0 {
+		RefreshRemoteBlocklists(cfg, config.BlocklistCacheDir, false)
+		added := MergeRemoteBlocklists(cfg, config.BlocklistCacheDir)
+		log.Printf("Merged %d domains from %d remote blocklists", added, len(cfg.RemoteBlocklists))
+	}
+
+	// Block known DNS-over-HTTPS resolvers, which bypass the hosts file
+	if cfg.BlockDoH {
+		added := MergeDoHDomains(cfg)
+		log.Printf("Merged %d DNS-over-HTTPS resolvers", added)
+	}
+
 	// Cache the list of domains with time windows (small list, typically <10)
 	// Domains without time windows are always blocked by default, so we only cache time-windowed domains
 	var timeWindowDomains []config.Domain
 	unblockableDomains := make(map[string]bool)
+	unblockMinutes := make(map[string]int)
+	absoluteWindows := make(map[string][]config.TimeWindow)
+	domainCategories := make(map[string]string)
+	domainLabels := make(map[string]string)
 	configDomainNames := make(map[string]bool)
 	for _, domain := range cfg.Domains {
 		configDomainNames[domain.Name] = true
@@ -77,22 +150,41 @@ func InitialEnforcement(cfg *config.Config) {
 		if domain.Unblockable {
 			unblockableDomains[domain.Name] = true
 		}
+		if domain.UnblockMinutes > 0 {
+			unblockMinutes[domain.Name] = domain.UnblockMinutes
+		}
+		if len(domain.AbsoluteWindows) > 0 {
+			absoluteWindows[domain.Name] = domain.AbsoluteWindows
+		}
+		if domain.Category != "" {
+			domainCategories[domain.Name] = domain.Category
+		}
+		if domain.Label != "" {
+			domainLabels[domain.Name] = domain.Label
+		}
 	}
-	enforcementState.mu.Lock()
-	enforcementState.timeWindowDomains = timeWindowDomains
-	enforcementState.unblockableDomains = unblockableDomains
-	enforcementState.configDomainNames = configDomainNames
-	enforcementState.mu.Unlock()
+	e.state.mu.Lock()
+	e.state.timeWindowDomains = timeWindowDomains
+	e.state.unblockableDomains = unblockableDomains
+	e.state.unblockMinutes = unblockMinutes
+	e.state.absoluteWindows = absoluteWindows
+	e.state.domainCategories = domainCategories
+	e.state.domainLabels = domainLabels
+	e.state.configDomainNames = configDomainNames
+	e.state.generation++
+	e.state.mu.Unlock()
 	log.Printf("Cached %d domains with time windows for state tracking", len(timeWindowDomains))
 	log.Printf("Cached %d unblockable domains", len(unblockableDomains))
 	log.Printf("Cached %d total domain names from config", len(configDomainNames))
 
-	// Clean up expired temporary unblocks
-	CleanupExpiredUnblocks(now)
+	// Clean up expired temporary unblocks and ended focus sessions
+	CleanupExpiredUnblocks(cfg, now)
+	CleanupExpiredTempBlocks(now)
 
 	// Get domains to block
-	blockedDomains := GetDomainsToBlock(cfg, now)
+	blockedDomains := e.GetDomainsToBlock(cfg, now)
 	log.Printf("Initial enforcement: %d domains to block", len(blockedDomains))
+	e.rememberSelfTestCandidates(blockedDomains)
 
 	// Build and write hosts file
 	if cfg.EnableHosts {
@@ -101,10 +193,10 @@ func InitialEnforcement(cfg *config.Config) {
 		} else {
 			// Store the expected hash of the hosts file
 			if hash, err := computeFileChecksum(cfg.HostsPath); err == nil {
-				enforcementState.mu.Lock()
-				enforcementState.expectedHostsHash = hash
-				enforcementState.lastBlockedCount = len(blockedDomains)
-				enforcementState.mu.Unlock()
+				e.state.mu.Lock()
+				e.state.expectedHostsHash = hash
+				e.state.lastBlockedCount = len(blockedDomains)
+				e.state.mu.Unlock()
 				log.Printf("Hosts file checksum stored: %s", hash[:16])
 			}
 		}
@@ -112,9 +204,7 @@ func InitialEnforcement(cfg *config.Config) {
 
 	// Update firewall
 	if cfg.EnableFirewall {
-		if err := UpdateFirewall(blockedDomains, false); err != nil {
-			log.Printf("ERROR updating firewall: %v", err)
-		}
+		e.rebuildFirewall(cfg, blockedDomains, now)
 	}
 
 	// Update sudoers
@@ -123,9 +213,9 @@ func InitialEnforcement(cfg *config.Config) {
 			log.Printf("ERROR updating sudoers: %v", err)
 		}
 		// Store sudoers lock state
-		enforcementState.mu.Lock()
-		enforcementState.lastSudoersLocked = !isSudoersAllowed(cfg, now)
-		enforcementState.mu.Unlock()
+		e.state.mu.Lock()
+		e.state.lastSudoersLocked = !isSudoersAllowed(cfg, now)
+		e.state.mu.Unlock()
 	}
 
 	// Self-heal check
@@ -135,15 +225,19 @@ func InitialEnforcement(cfg *config.Config) {
 
 	// Build time window state BEFORE acquiring lock to avoid deadlock
 	// (buildTimeWindowState also acquires RLock on the same mutex)
-	timeWindowState := buildTimeWindowState(now)
+	timeWindowState := e.buildTimeWindowState(now)
 	tempUnblockCount := len(state.GetTempUnblocks())
+	tempBlockCount := len(state.GetTempBlocks())
 
 	// Store time window state
-	enforcementState.mu.Lock()
-	enforcementState.lastTimeWindowState = timeWindowState
-	enforcementState.lastTempUnblockCount = tempUnblockCount
-	enforcementState.lastEnforcement = now
-	enforcementState.mu.Unlock()
+	e.state.mu.Lock()
+	e.state.lastTimeWindowState = timeWindowState
+	e.state.lastTempUnblockCount = tempUnblockCount
+	e.state.lastTempBlockCount = tempBlockCount
+	e.state.lastActiveProfile = activeProfile
+	e.state.lastAllowlistActive = IsAllowlistActive(cfg, now)
+	e.state.lastEnforcement = now
+	e.state.mu.Unlock()
 
 	// Clear the large domain list from config to free memory
 	// We've cached time-window domains and have the hosts file checksum
@@ -152,39 +246,64 @@ func InitialEnforcement(cfg *config.Config) {
 	cfg.Domains = nil
 	log.Printf("Cleared %d domains from memory (kept %d time-window domains cached)", domainCount, len(timeWindowDomains))
 
+	state.RecordEnforcement(state.EnforcementEvent{Time: now, Action: "full enforcement", Reason: reason, BlockedCount: len(blockedDomains)})
 	log.Println("Initial enforcement completed")
 }
 
 // EnforcementCheck performs a lightweight check and only applies changes if needed.
 // This is called periodically and avoids rewriting files unless something changed.
-func EnforcementCheck(cfg *config.Config) {
-	now := time.Now()
+func (e *Engine) EnforcementCheck(cfg *config.Config) {
+	e.run.Lock()
+	defer e.run.Unlock()
+	now := clock.Now()
 
-	// Clean up expired temporary unblocks
-	CleanupExpiredUnblocks(now)
+	// Nothing is enforced during a pause; a pause that ran out rebuilds everything
+	if e.checkPause(cfg, now) {
+		return
+	}
+
+	// Clean up expired temporary unblocks and ended focus sessions
+	CleanupExpiredUnblocks(cfg, now)
+	CleanupExpiredTempBlocks(now)
 
 	// Check what changed
 	hostsNeedsUpdate := false
 	sudoersNeedsUpdate := false
 	reason := ""
 
-	enforcementState.mu.RLock()
-	lastTimeWindowState := enforcementState.lastTimeWindowState
-	lastTempUnblockCount := enforcementState.lastTempUnblockCount
-	lastSudoersLocked := enforcementState.lastSudoersLocked
-	expectedHostsHash := enforcementState.expectedHostsHash
-	enforcementState.mu.RUnlock()
+	e.state.mu.RLock()
+	lastTimeWindowState := e.state.lastTimeWindowState
+	lastTempUnblockCount := e.state.lastTempUnblockCount
+	lastTempBlockCount := e.state.lastTempBlockCount
+	lastSudoersLocked := e.state.lastSudoersLocked
+	expectedHostsHash := e.state.expectedHostsHash
+	lastActiveProfile := e.state.lastActiveProfile
+	lastPortRules := e.state.lastPortRules
+	lastAllowlistActive := e.state.lastAllowlistActive
+	e.state.mu.RUnlock()
+
+	// A profile switch changes the domain set and the cached rules, so rebuild everything
+	if activeProfile, _ := state.GetActiveProfile(); activeProfile != lastActiveProfile {
+		reason := fmt.Sprintf("active profile changed (%q -> %q)", lastActiveProfile, activeProfile)
+		log.Printf("Active profile changed (%q -> %q) - forcing full enforcement", lastActiveProfile, activeProfile)
+		e.forceEnforcement(cfg, reason)
+		return
+	}
 
-	// 1. Check if temp unblocks changed
+	// 1. Check if temp unblocks or focus session blocks changed
 	currentTempUnblocks := len(state.GetTempUnblocks())
+	currentTempBlocks := len(state.GetTempBlocks())
 	if currentTempUnblocks != lastTempUnblockCount {
 		hostsNeedsUpdate = true
 		reason = "temp unblocks changed"
+	} else if currentTempBlocks != lastTempBlockCount {
+		hostsNeedsUpdate = true
+		reason = "focus session changed"
 	}
 
 	// 2. Check if time window state changed for any domain
 	if !hostsNeedsUpdate {
-		currentTimeWindowState := buildTimeWindowState(now)
+		currentTimeWindowState := e.buildTimeWindowState(now)
 		for domain, wasBlocked := range lastTimeWindowState {
 			if currentTimeWindowState[domain] != wasBlocked {
 				hostsNeedsUpdate = true
@@ -212,11 +331,44 @@ func EnforcementCheck(cfg *config.Config) {
 		} else if currentHash != expectedHostsHash {
 			hostsNeedsUpdate = true
 			reason = "hosts file tampered"
-			log.Printf("TAMPER DETECTED: hosts file checksum mismatch")
+			if cfg.TamperDetection.InMaintenanceWindow(now) {
+				log.Printf("Hosts file changed during a maintenance window - restoring it without a tamper alarm")
+			} else {
+				log.Printf("TAMPER DETECTED: hosts file checksum mismatch")
+				audit.Log(audit.Event{Timestamp: now, Type: audit.EventTamper, Reason: "hosts file checksum mismatch", Source: "enforcement"})
+				state.RecordTamperEvent()
+			}
+		}
+	}
+
+	// 3b. Check if the runtime overlay was edited behind glocker's back, e.g. to
+	// drop a -block domain. It is restored, and the hosts file rebuilt from it.
+	if restored, err := config.RestoreOverlay(); err != nil {
+		log.Printf("Warning: couldn't restore the runtime overlay: %v", err)
+	} else if restored {
+		log.Printf("TAMPER DETECTED: runtime overlay %s was modified, restored it", config.OverlayPath())
+		audit.Log(audit.Event{Timestamp: now, Type: audit.EventTamper, Reason: "runtime overlay modified", Source: "enforcement"})
+		state.RecordTamperEvent()
+		if !hostsNeedsUpdate {
+			hostsNeedsUpdate = true
+			reason = "runtime overlay tampered"
 		}
 	}
 
-	// 4. Check if sudoers lock state changed
+	// 4. Check if a firewall_rules window opened or closed; the firewall is only
+	// rebuilt together with the hosts file
+	if !hostsNeedsUpdate && cfg.EnableFirewall && portRulesChanged(lastPortRules, portRuleArgs(cfg.FirewallRules, now)) {
+		hostsNeedsUpdate = true
+		reason = "firewall port rules changed"
+	}
+
+	// 5. Check if allowlist mode switched on or off
+	if !hostsNeedsUpdate && IsAllowlistActive(cfg, now) != lastAllowlistActive {
+		hostsNeedsUpdate = true
+		reason = "allowlist mode switched"
+	}
+
+	// 6. Check if sudoers lock state changed
 	if cfg.Sudoers.Enabled {
 		currentSudoersLocked := !isSudoersAllowed(cfg, now)
 		if currentSudoersLocked != lastSudoersLocked {
@@ -229,11 +381,12 @@ func EnforcementCheck(cfg *config.Config) {
 		log.Printf("Hosts update needed: %s", reason)
 
 		// Reload config from disk to get full domain list
-		freshCfg, err := config.LoadConfig()
+		freshCfg, err := LoadEnforcedConfig()
 		if err != nil {
 			log.Printf("ERROR: Failed to reload config for hosts update: %v", err)
 		} else {
-			blockedDomains := GetDomainsToBlock(freshCfg, now)
+			blockedDomains := e.GetDomainsToBlock(freshCfg, now)
+			e.rememberSelfTestCandidates(blockedDomains)
 
 			if freshCfg.EnableHosts {
 				if err := UpdateHosts(freshCfg, blockedDomains, false); err != nil {
@@ -241,19 +394,18 @@ func EnforcementCheck(cfg *config.Config) {
 				} else {
 					// Update stored hash
 					if hash, err := computeFileChecksum(freshCfg.HostsPath); err == nil {
-						enforcementState.mu.Lock()
-						enforcementState.expectedHostsHash = hash
-						enforcementState.lastBlockedCount = len(blockedDomains)
-						enforcementState.mu.Unlock()
+						e.state.mu.Lock()
+						e.state.expectedHostsHash = hash
+						e.state.lastBlockedCount = len(blockedDomains)
+						e.state.mu.Unlock()
 					}
 				}
 			}
 
 			if freshCfg.EnableFirewall {
-				if err := UpdateFirewall(blockedDomains, false); err != nil {
-					log.Printf("ERROR updating firewall: %v", err)
-				}
+				e.rebuildFirewall(freshCfg, blockedDomains, now)
 			}
+			state.RecordEnforcement(state.EnforcementEvent{Time: now, Action: "hosts update", Reason: reason, BlockedCount: len(blockedDomains)})
 			// freshCfg goes out of scope here, freeing the domain list
 		}
 	}
@@ -263,31 +415,60 @@ func EnforcementCheck(cfg *config.Config) {
 		if err := UpdateSudoers(cfg, now, false, false); err != nil {
 			log.Printf("ERROR updating sudoers: %v", err)
 		}
+		sudoersReason := "sudo allowed by time_allowed"
+		if !isSudoersAllowed(cfg, now) {
+			sudoersReason = "sudo locked outside time_allowed"
+		}
+		e.recordEnforcement(now, "sudoers update", sudoersReason)
 	}
 
-	// Self-heal check (lightweight - just re-applies immutable flags)
+	// Self-heal check (lightweight - re-applies immutable flags, and only
+	// rebuilds the firewall when rules have gone missing)
 	if cfg.SelfHeal {
 		SelfHeal(cfg)
+		if cfg.EnableFirewall {
+			e.healFirewall(now)
+		}
 	}
 
 	// Build time window state BEFORE acquiring lock to avoid deadlock
-	timeWindowState := buildTimeWindowState(now)
+	timeWindowState := e.buildTimeWindowState(now)
 	sudoersLocked := cfg.Sudoers.Enabled && !isSudoersAllowed(cfg, now)
 
 	// Update state
-	enforcementState.mu.Lock()
-	enforcementState.lastTimeWindowState = timeWindowState
-	enforcementState.lastTempUnblockCount = currentTempUnblocks
+	e.state.mu.Lock()
+	e.state.lastTimeWindowState = timeWindowState
+	e.state.lastTempUnblockCount = currentTempUnblocks
+	e.state.lastTempBlockCount = currentTempBlocks
+	e.state.lastAllowlistActive = IsAllowlistActive(cfg, now)
 	if cfg.Sudoers.Enabled {
-		enforcementState.lastSudoersLocked = sudoersLocked
+		e.state.lastSudoersLocked = sudoersLocked
 	}
-	enforcementState.lastEnforcement = now
-	enforcementState.mu.Unlock()
+	e.state.lastEnforcement = now
+	e.state.mu.Unlock()
+}
+
+// WatchedFiles returns the files whose tampering EnforcementCheck detects and
+// repairs, so the daemon can run a check as soon as one of them changes.
+func WatchedFiles(cfg *config.Config) []string {
+	files := []string{config.OverlayPath()}
+	if cfg.EnableHosts {
+		files = append(files, cfg.HostsPath)
+	}
+	return files
 }
 
 // ForceEnforcement forces a full enforcement cycle, typically called after config reload or unblock.
 // It reloads the config from disk since cfg.Domains was cleared after initial enforcement.
-func ForceEnforcement(cfg *config.Config) {
+func (e *Engine) ForceEnforcement(cfg *config.Config) {
+	e.run.Lock()
+	defer e.run.Unlock()
+	e.forceEnforcement(cfg, "forced by a command or config reload")
+}
+
+// forceEnforcement forces a full enforcement cycle, recorded in the enforcement
+// history with reason.
+func (e *Engine) forceEnforcement(cfg *config.Config, reason string) {
 	log.Println("Forcing full enforcement cycle...")
 
 	// Reload config from disk to get full domain list (cfg.Domains was cleared)
@@ -300,70 +481,109 @@ func ForceEnforcement(cfg *config.Config) {
 	// Copy runtime-modified settings to fresh config
 	freshCfg.ExtensionKeywords = cfg.ExtensionKeywords // May have been modified via -add-keyword
 
-	InitialEnforcement(freshCfg)
+	e.fullEnforcement(freshCfg, reason)
+}
+
+// recordEnforcement adds an enforcement event that didn't change the blocked
+// domains to the enforcement history.
+func (e *Engine) recordEnforcement(now time.Time, action, reason string) {
+	e.state.mu.RLock()
+	blockedCount := e.state.lastBlockedCount
+	e.state.mu.RUnlock()
+	state.RecordEnforcement(state.EnforcementEvent{Time: now, Action: action, Reason: reason, BlockedCount: blockedCount})
 }
 
 // buildTimeWindowState creates a map of domain -> isBlockedByTimeWindow for current time.
 // Uses the cached timeWindowDomains list (typically <10 domains) instead of iterating 800K domains.
-func buildTimeWindowState(now time.Time) map[string]bool {
+func (e *Engine) buildTimeWindowState(now time.Time) map[string]bool {
 	result := make(map[string]bool)
-	currentDay := now.Weekday().String()[:3]
-	currentTime := now.Format("15:04")
 
-	enforcementState.mu.RLock()
-	domains := enforcementState.timeWindowDomains
-	enforcementState.mu.RUnlock()
+	e.state.mu.RLock()
+	domains := e.state.timeWindowDomains
+	e.state.mu.RUnlock()
 
 	for _, domain := range domains {
-		blocked := false
-		for _, window := range domain.TimeWindows {
-			// For midnight-crossing windows, check previous day for early morning times
-			dayToCheck := currentDay
-			if window.Start > window.End && currentTime <= window.End {
-				dayToCheck = now.AddDate(0, 0, -1).Weekday().String()[:3]
-			}
-
-			if !containsDay(window.Days, dayToCheck) {
-				continue
-			}
-
-			if isInTimeWindow(currentTime, window.Start, window.End) {
-				blocked = true
-				break
-			}
-		}
-		result[domain.Name] = blocked
+		result[domain.Name] = config.AnyWindowActive(domain.TimeWindows, now)
 	}
 
 	return result
 }
 
+// GetTimeWindowState reports, for each cached time-windowed domain, whether it is
+// inside one of its blocking windows at the given time.
+func (e *Engine) GetTimeWindowState(now time.Time) map[string]bool {
+	return e.buildTimeWindowState(now)
+}
+
 // isSudoersAllowed checks if sudoers should be in "allowed" state based on time windows.
 func isSudoersAllowed(cfg *config.Config, now time.Time) bool {
-	currentDay := now.Weekday().String()[:3]
-	currentTime := now.Format("15:04")
+	return config.AnyWindowActive(cfg.Sudoers.TimeAllowed, now)
+}
 
-	for _, window := range cfg.Sudoers.TimeAllowed {
-		if containsDay(window.Days, currentDay) && isInTimeWindow(currentTime, window.Start, window.End) {
-			return true
+// NextTimeWindowTransition returns when a domain with the given time windows
+// next changes state, and whether it is blocking until then: the end of the
+// blocking window covering now, or the start of the next one. Like
+// TimeWindow.Active, a window blocks through the whole of its end minute, and a
+// midnight-crossing window belongs to the day it starts on. ok is false if the
+// state doesn't change within the next week.
+func NextTimeWindowTransition(windows []config.TimeWindow, now time.Time) (at time.Time, blocking bool, ok bool) {
+	type interval struct{ start, end time.Time }
+
+	// Start from yesterday, whose midnight-crossing windows may still be running
+	var intervals []interval
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	for offset := -1; offset <= 7; offset++ {
+		day := today.AddDate(0, 0, offset)
+		for _, window := range windows {
+			if !slices.Contains(window.Days, day.Weekday().String()[:3]) {
+				continue
+			}
+			start, err := time.Parse("15:04", window.Start)
+			if err != nil {
+				continue
+			}
+			end, err := time.Parse("15:04", window.End)
+			if err != nil {
+				continue
+			}
+			iv := interval{
+				start: time.Date(day.Year(), day.Month(), day.Day(), start.Hour(), start.Minute(), 0, 0, now.Location()),
+				end:   time.Date(day.Year(), day.Month(), day.Day(), end.Hour(), end.Minute()+1, 0, 0, now.Location()),
+			}
+			if window.Start > window.End {
+				iv.end = iv.end.AddDate(0, 0, 1)
+			}
+			intervals = append(intervals, iv)
 		}
 	}
-	return false
-}
-
-// containsDay checks if a day is in the list of days.
-func containsDay(days []string, day string) bool {
-	return slices.Contains(days, day)
-}
+	slices.SortFunc(intervals, func(a, b interval) int { return a.start.Compare(b.start) })
+
+	// Merge overlapping and back-to-back windows, which block without a break
+	var merged []interval
+	for _, iv := range intervals {
+		if last := len(merged) - 1; last >= 0 && !iv.start.After(merged[last].end) {
+			if iv.end.After(merged[last].end) {
+				merged[last].end = iv.end
+			}
+			continue
+		}
+		merged = append(merged, iv)
+	}
 
-// isInTimeWindow checks if current time is within a time window.
-func isInTimeWindow(currentTime, start, end string) bool {
-	if start <= end {
-		// Normal window (e.g., 09:00 to 17:00)
-		return currentTime >= start && currentTime <= end
+	for _, iv := range merged {
+		if !now.Before(iv.end) {
+			continue
+		}
+		if now.Before(iv.start) {
+			return iv.start, false, true
+		}
+		// A block lasting the rest of the week never ends
+		if iv.end.Sub(now) > 7*24*time.Hour {
+			return time.Time{}, true, false
+		}
+		return iv.end, true, true
 	}
-	// Midnight-crossing window (e.g., 22:00 to 05:00)
-	return currentTime >= start || currentTime <= end
+	return time.Time{}, false, false
 }
 
 // computeFileChecksum computes SHA256 checksum of a file.
@@ -377,51 +597,149 @@ func computeFileChecksum(path string) (string, error) {
 }
 
 // GetEnforcementState returns a copy of the current enforcement state for status reporting.
-func GetEnforcementState() (lastEnforcement time.Time, blockedCount int, hostsHash string) {
-	enforcementState.mu.RLock()
-	defer enforcementState.mu.RUnlock()
-	return enforcementState.lastEnforcement, enforcementState.lastBlockedCount, enforcementState.expectedHostsHash
+func (e *Engine) GetEnforcementState() (lastEnforcement time.Time, blockedCount int, hostsHash string) {
+	e.state.mu.RLock()
+	defer e.state.mu.RUnlock()
+	return e.state.lastEnforcement, e.state.lastBlockedCount, e.state.expectedHostsHash
+}
+
+// Generation returns the number of full enforcements so far. It changes whenever
+// the enforced rules may have, so caches built from them can tell they are stale.
+func (e *Engine) Generation() uint64 {
+	e.state.mu.RLock()
+	defer e.state.mu.RUnlock()
+	return e.state.generation
 }
 
 // GetTimeWindowDomains returns the cached list of domains with time windows.
-func GetTimeWindowDomains() []config.Domain {
-	enforcementState.mu.RLock()
-	defer enforcementState.mu.RUnlock()
-	return enforcementState.timeWindowDomains
+func (e *Engine) GetTimeWindowDomains() []config.Domain {
+	e.state.mu.RLock()
+	defer e.state.mu.RUnlock()
+	return e.state.timeWindowDomains
+}
+
+// IsTimeWindowed reports whether the config only blocks domain during time windows.
+// It uses the cache, since cfg.Domains is cleared after a full enforcement.
+func (e *Engine) IsTimeWindowed(domain string) bool {
+	e.state.mu.RLock()
+	defer e.state.mu.RUnlock()
+	return slices.ContainsFunc(e.state.timeWindowDomains, func(d config.Domain) bool { return d.Name == domain })
+}
+
+// GetEnforcedDomains rebuilds the domain list of the last full enforcement from the
+// cached domain names, restoring time windows, categories, labels, the unblockable
+// flag and unblock durations. Pattern and subdomain settings aren't cached, so only
+// names, windows, categories, labels and unblock settings are reliable. The
+// domains are sorted by name.
+func (e *Engine) GetEnforcedDomains() []config.Domain {
+	e.state.mu.RLock()
+	defer e.state.mu.RUnlock()
+
+	windows := make(map[string][]config.TimeWindow, len(e.state.timeWindowDomains))
+	for _, domain := range e.state.timeWindowDomains {
+		windows[domain.Name] = domain.TimeWindows
+	}
+
+	domains := make([]config.Domain, 0, len(e.state.configDomainNames))
+	for name := range e.state.configDomainNames {
+		domains = append(domains, config.Domain{
+			Name:            name,
+			TimeWindows:     windows[name],
+			Unblockable:     e.state.unblockableDomains[name],
+			UnblockMinutes:  e.state.unblockMinutes[name],
+			AbsoluteWindows: e.state.absoluteWindows[name],
+			Category:        e.state.domainCategories[name],
+			Label:           e.state.domainLabels[name],
+		})
+	}
+	slices.SortFunc(domains, func(a, b config.Domain) int { return strings.Compare(a.Name, b.Name) })
+	return domains
 }
 
-// IsUnblockable checks if a domain can be temporarily unblocked.
+// IsUnblockable checks if a domain can be temporarily unblocked at now.
 // Returns: (canUnblock bool, inConfig bool)
-// - canUnblock: true if domain can be unblocked
+// - canUnblock: true if domain can be unblocked (unblockable, and outside its absolute_windows)
 // - inConfig: true if domain was in the original config
-func IsUnblockable(domain string) (canUnblock bool, inConfig bool) {
-	enforcementState.mu.RLock()
-	defer enforcementState.mu.RUnlock()
-
-	inConfig = enforcementState.configDomainNames[domain]
-	canUnblock = enforcementState.unblockableDomains[domain]
+func (e *Engine) IsUnblockable(domain string, now time.Time) (canUnblock bool, inConfig bool) {
+	e.state.mu.RLock()
+	inConfig = e.state.configDomainNames[domain]
+	canUnblock = e.state.unblockableDomains[domain]
+	e.state.mu.RUnlock()
 
 	// If not in config at all, allow unblock (backward compat for automated lists)
 	if !inConfig {
 		canUnblock = true
 	}
 
+	if _, absolute := e.ActiveAbsoluteWindow(domain, now); absolute {
+		canUnblock = false
+	}
+
 	return canUnblock, inConfig
 }
 
+// ActiveAbsoluteWindow returns the domain's absolute_windows entry covering now,
+// during which it can't be unblocked even if it is unblockable.
+func (e *Engine) ActiveAbsoluteWindow(domain string, now time.Time) (config.TimeWindow, bool) {
+	e.state.mu.RLock()
+	windows := e.state.absoluteWindows[domain]
+	e.state.mu.RUnlock()
+
+	for _, window := range windows {
+		if window.Active(now) {
+			return window, true
+		}
+	}
+	return config.TimeWindow{}, false
+}
+
+// GetUnblockMinutes returns the domain's own unblock duration in minutes,
+// or 0 if it uses the configured default.
+func (e *Engine) GetUnblockMinutes(domain string) int {
+	e.state.mu.RLock()
+	defer e.state.mu.RUnlock()
+	return e.state.unblockMinutes[domain]
+}
+
 // InitializeTestCache initializes the enforcement state cache for testing.
 // This is used by tests to set up the cache without running full enforcement.
-func InitializeTestCache(domains []config.Domain) {
+func (e *Engine) InitializeTestCache(domains []config.Domain) {
+	var timeWindowDomains []config.Domain
 	unblockableDomains := make(map[string]bool)
+	unblockMinutes := make(map[string]int)
+	absoluteWindows := make(map[string][]config.TimeWindow)
+	domainCategories := make(map[string]string)
+	domainLabels := make(map[string]string)
 	configDomainNames := make(map[string]bool)
 	for _, domain := range domains {
 		configDomainNames[domain.Name] = true
+		if len(domain.TimeWindows) > 0 {
+			timeWindowDomains = append(timeWindowDomains, domain)
+		}
 		if domain.Unblockable {
 			unblockableDomains[domain.Name] = true
 		}
+		if domain.UnblockMinutes > 0 {
+			unblockMinutes[domain.Name] = domain.UnblockMinutes
+		}
+		if len(domain.AbsoluteWindows) > 0 {
+			absoluteWindows[domain.Name] = domain.AbsoluteWindows
+		}
+		if domain.Category != "" {
+			domainCategories[domain.Name] = domain.Category
+		}
+		if domain.Label != "" {
+			domainLabels[domain.Name] = domain.Label
+		}
 	}
-	enforcementState.mu.Lock()
-	enforcementState.unblockableDomains = unblockableDomains
-	enforcementState.configDomainNames = configDomainNames
-	enforcementState.mu.Unlock()
+	e.state.mu.Lock()
+	e.state.timeWindowDomains = timeWindowDomains
+	e.state.unblockableDomains = unblockableDomains
+	e.state.unblockMinutes = unblockMinutes
+	e.state.absoluteWindows = absoluteWindows
+	e.state.domainCategories = domainCategories
+	e.state.domainLabels = domainLabels
+	e.state.configDomainNames = configDomainNames
+	e.state.generation++
+	e.state.mu.Unlock()
 }
@@ -2,14 +2,18 @@ package enforcement
 
 import (
 	"fmt"
+	"log"
+	"log/slog"
 	"os"
 	"os/exec"
+	"os/user"
+	"path/filepath"
 	"slices"
 	"strings"
 	"time"
 
 	"glocker/internal/config"
-	"glocker/internal/utils"
+	"glocker/internal/state"
 )
 
 // UpdateSudoers updates the /etc/sudoers file to restrict or allow sudo access
@@ -36,6 +40,15 @@ func UpdateSudoers(cfg *config.Config, now time.Time, dryRun bool, forceBlock bo
 		return nil
 	}
 
+	// Changes can't be validated without visudo, and a broken sudoers file locks out sudo
+	if !GetCapabilities().Visudo {
+		if forceBlock {
+			return fmt.Errorf("visudo is not available, sudoers can't be updated safely")
+		}
+		slog.Debug("Skipping sudoers update, visudo is not available")
+		return nil
+	}
+
 	// Read current sudoers file
 	content, err := os.ReadFile(config.SudoersPath)
 	if err != nil {
@@ -77,9 +90,97 @@ func UpdateSudoers(cfg *config.Config, now time.Time, dryRun bool, forceBlock bo
 
 	newContent := strings.Join(newLines, "\n")
 
+	// A grant for the user in an included drop-in (e.g. /etc/sudoers.d/90-user)
+	// would bypass the managed line, so neutralize those too. Grants shared with
+	// others (a %group the user is in, or a list of users) are only warned about,
+	// since commenting them out would take sudo from everyone else as well.
+	groups := sudoersUserGroups(cfg.Sudoers.User)
+	warnSharedSudoersGrants(config.SudoersPath, newContent, cfg.Sudoers.User, groups)
+	dropins, err := planSudoersDropinChanges(config.SudoersPath, newContent, cfg.Sudoers.User, groups)
+	if err != nil {
+		log.Printf("WARNING: couldn't check sudoers drop-ins: %v", err)
+	}
+
+	originals := make(map[string][]byte)
+	for _, change := range dropins {
+		if err := backupSudoersDropin(change.path, change.original); err != nil {
+			return fmt.Errorf("backing up %s: %w", change.path, err)
+		}
+		if err := writeSudoersFile(change.path, change.content); err != nil {
+			restoreSudoersFiles(originals)
+			return fmt.Errorf("neutralizing %s: %w", change.path, err)
+		}
+		originals[change.path] = change.original
+		log.Printf("Neutralized %d sudo grant(s) for %s in %s", change.neutralized, cfg.Sudoers.User, change.path)
+	}
+
+	locking := isSudoLockTransition(string(content), targetLine, sudoAllowed)
+	if err := writeSudoersFile(config.SudoersPath, []byte(newContent)); err != nil {
+		restoreSudoersFiles(originals)
+		return err
+	}
+	originals[config.SudoersPath] = content
+
+	// Each file was validated on its own; make sure the combined set still parses
+	if len(dropins) > 0 {
+		if err := exec.Command("visudo", "-c").Run(); err != nil {
+			restoreSudoersFiles(originals)
+			return fmt.Errorf("sudoers validation failed after updating drop-ins (changes reverted): %w", err)
+		}
+	}
+
+	// Shells that already authenticated would otherwise keep sudo until their timestamp expires
+	if locking && cfg.Sudoers.KillTimestampOnLock {
+		if err := removeSudoTimestamp(cfg.Sudoers.User); err != nil {
+			log.Printf("Warning: Failed to remove cached sudo credentials for %s: %v", cfg.Sudoers.User, err)
+		} else {
+			log.Printf("Removed cached sudo credentials for %s", cfg.Sudoers.User)
+		}
+	}
+
+	// Update checksum after legitimate change
+	// TODO: Call monitoring.UpdateChecksum(config.SudoersPath) once monitoring package is implemented
+
+	return nil
+}
+
+// isSudoLockTransition reports whether writing blockedLine over the sudoers
+// content takes sudo away: sudo isn't allowed and the managed line in content,
+// if any, is something else (the allowed line, or none before glocker's first
+// update).
+func isSudoLockTransition(content, blockedLine string, sudoAllowed bool) bool {
+	if sudoAllowed {
+		return false
+	}
+	for _, line := range strings.Split(content, "\n") {
+		if strings.Contains(line, config.SudoersMarker) {
+			return strings.TrimSpace(line) != blockedLine+" "+config.SudoersMarker
+		}
+	}
+	return true
+}
+
+// sudoTimestampDir is where sudo keeps cached credentials. Replaced in tests.
+var sudoTimestampDir = config.SudoTimestampDir
+
+// removeSudoTimestamp deletes user's sudo timestamp file, like 'sudo -K' run by
+// that user, so their open sessions must authenticate again. A missing file is
+// not an error.
+func removeSudoTimestamp(user string) error {
+	if user == "" || strings.ContainsRune(user, '/') || user == "." || user == ".." {
+		return fmt.Errorf("invalid user name %q", user)
+	}
+	if err := os.Remove(filepath.Join(sudoTimestampDir, user)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// writeSudoersFile validates content with visudo and atomically replaces path with it.
+func writeSudoersFile(path string, content []byte) error {
 	// Write to a temporary file
-	tmpFile := config.SudoersPath + ".tmp"
-	if err := os.WriteFile(tmpFile, []byte(newContent), 0440); err != nil {
+	tmpFile := path + ".tmp"
+	if err := os.WriteFile(tmpFile, content, 0440); err != nil {
 		return fmt.Errorf("writing temporary sudoers file: %w", err)
 	}
 	defer os.Remove(tmpFile)
@@ -91,47 +192,226 @@ func UpdateSudoers(cfg *config.Config, now time.Time, dryRun bool, forceBlock bo
 	}
 
 	// Validation passed, now replace the real file
-	if err := os.Rename(tmpFile, config.SudoersPath); err != nil {
+	if err := os.Rename(tmpFile, path); err != nil {
 		return fmt.Errorf("replacing sudoers file: %w", err)
 	}
 
 	// Ensure correct permissions
-	os.Chmod(config.SudoersPath, 0440)
+	os.Chmod(path, 0440)
+	return nil
+}
 
-	// Update checksum after legitimate change
-	// TODO: Call monitoring.UpdateChecksum(config.SudoersPath) once monitoring package is implemented
+// restoreSudoersFiles writes back the previous contents of sudoers files after a failed update.
+func restoreSudoersFiles(originals map[string][]byte) {
+	for path, content := range originals {
+		if err := os.WriteFile(path, content, 0440); err != nil {
+			log.Printf("ERROR: couldn't restore %s: %v", path, err)
+		}
+	}
+}
 
-	return nil
+// sudoersDropinChange is a pending rewrite of an included sudoers file.
+type sudoersDropinChange struct {
+	path        string
+	original    []byte
+	content     []byte
+	neutralized int
 }
 
-// IsSudoAllowed determines if sudo access should be allowed at the given time
-// based on the configured time windows.
-func IsSudoAllowed(cfg *config.Config, now time.Time) bool {
-	if !cfg.Sudoers.Enabled {
-		return true // If not enabled, don't restrict
+// sudoersUserGroups returns the groups username belongs to, both by name and as
+// "#gid", the two ways a sudoers %group entry can name them. Returns nil if the
+// user can't be looked up.
+func sudoersUserGroups(username string) []string {
+	u, err := user.Lookup(username)
+	if err != nil {
+		slog.Debug("Couldn't look up sudoers user", "user", username, "error", err)
+		return nil
+	}
+	gids, err := u.GroupIds()
+	if err != nil {
+		slog.Debug("Couldn't list the sudoers user's groups", "user", username, "error", err)
+		return nil
 	}
 
-	currentDay := now.Weekday().String()[:3]
-	currentTime := now.Format("15:04")
+	var groups []string
+	for _, gid := range gids {
+		groups = append(groups, "#"+gid)
+		if group, err := user.LookupGroupId(gid); err == nil {
+			groups = append(groups, group.Name)
+		}
+	}
+	return groups
+}
 
-	// Check if current time falls within any allowed window
-	for _, window := range cfg.Sudoers.TimeAllowed {
-		// For midnight-crossing windows, check previous day for early morning times
-		dayToCheck := currentDay
-		if window.Start > window.End && currentTime <= window.End {
-			dayToCheck = now.AddDate(0, 0, -1).Weekday().String()[:3]
+// planSudoersDropinChanges finds the files included by the main sudoers content and
+// returns the rewrites needed to comment out grants for user in them. Grants the
+// user shares with others through one of groups are logged, not rewritten.
+func planSudoersDropinChanges(mainPath, mainContent, user string, groups []string) ([]sudoersDropinChange, error) {
+	files, err := sudoersIncludedFiles(mainPath, mainContent)
+
+	var changes []sudoersDropinChange
+	for _, path := range files {
+		original, readErr := os.ReadFile(path)
+		if readErr != nil {
+			log.Printf("WARNING: couldn't read sudoers include %s: %v", path, readErr)
+			continue
+		}
+		warnSharedSudoersGrants(path, string(original), user, groups)
+		content, count := neutralizeSudoersUserLines(string(original), user)
+		if count == 0 {
+			continue
 		}
+		changes = append(changes, sudoersDropinChange{
+			path:        path,
+			original:    original,
+			content:     []byte(content),
+			neutralized: count,
+		})
+	}
 
-		if !slices.Contains(window.Days, dayToCheck) {
+	return changes, err
+}
+
+// sudoersIncludedFiles returns the files pulled in by @include/@includedir (and the
+// legacy #include/#includedir) directives, in the order sudo reads them.
+// Relative paths are resolved against the including file's directory. Files in an
+// include directory are skipped when sudo would skip them (names containing "." or ending in "~").
+func sudoersIncludedFiles(mainPath, content string) ([]string, error) {
+	var files []string
+	var errs []string
+
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
 			continue
 		}
+		target := fields[1]
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(mainPath), target)
+		}
+
+		switch fields[0] {
+		case "@include", "#include":
+			files = append(files, target)
+		case "@includedir", "#includedir":
+			entries, err := os.ReadDir(target)
+			if err != nil {
+				if !os.IsNotExist(err) {
+					errs = append(errs, fmt.Sprintf("reading %s: %v", target, err))
+				}
+				continue
+			}
+			for _, entry := range entries {
+				name := entry.Name()
+				if entry.IsDir() || strings.Contains(name, ".") || strings.HasSuffix(name, "~") {
+					continue
+				}
+				files = append(files, filepath.Join(target, name))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return files, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return files, nil
+}
+
+// neutralizeSudoersUserLines comments out every rule line granting sudo to user.
+// Returns the new content and how many lines were neutralized.
+func neutralizeSudoersUserLines(content, user string) (string, int) {
+	lines := strings.Split(content, "\n")
+	count := 0
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, user+" ") || strings.HasPrefix(trimmed, user+"\t") {
+			lines[i] = config.SudoersDisabledTag + line
+			count++
+		}
+	}
+	return strings.Join(lines, "\n"), count
+}
+
+// sharedSudoersGrants returns the rule lines that grant sudo to user along with
+// others: a user list such as "alice,user", or a %group or %#gid entry naming
+// one of groups. These can't be neutralized without locking the others out too.
+func sharedSudoersGrants(content, user string, groups []string) []string {
+	var shared []string
+	for _, line := range strings.Split(content, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] == user {
+			continue
+		}
+		for _, entry := range strings.Split(fields[0], ",") {
+			group, isGroup := strings.CutPrefix(entry, "%")
+			if entry == user || (isGroup && slices.Contains(groups, group)) {
+				shared = append(shared, strings.TrimSpace(line))
+				break
+			}
+		}
+	}
+	return shared
+}
 
-		if utils.IsInTimeWindow(currentTime, window.Start, window.End) {
-			return true
+// warnSharedSudoersGrants logs the grants in a sudoers file that let user
+// bypass the managed line but are left alone because others share them.
+func warnSharedSudoersGrants(path, content, user string, groups []string) {
+	for _, line := range sharedSudoersGrants(content, user, groups) {
+		log.Printf("WARNING: %s grants sudo to %s through a shared rule glocker won't edit (remove %s from it to enforce the sudo lock): %s", path, user, user, line)
+	}
+}
+
+// backupSudoersDropin saves a drop-in's original content next to it before its first
+// modification. It only creates the backup if one doesn't already exist.
+func backupSudoersDropin(path string, content []byte) error {
+	backupPath := path + config.SudoersBackupSuffix
+	if _, err := os.Stat(backupPath); err == nil {
+		return nil
+	}
+	return os.WriteFile(backupPath, content, 0440)
+}
+
+// RestoreSudoersDropins re-enables the grants neutralized in sudoers drop-ins and
+// removes their backups. Used on uninstall.
+func RestoreSudoersDropins() error {
+	content, err := os.ReadFile(config.SudoersPath)
+	if err != nil {
+		return fmt.Errorf("reading sudoers file: %w", err)
+	}
+
+	files, err := sudoersIncludedFiles(config.SudoersPath, string(content))
+	if err != nil {
+		log.Printf("WARNING: couldn't list all sudoers drop-ins: %v", err)
+	}
+
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil || !strings.Contains(string(data), config.SudoersDisabledTag) {
+			continue
+		}
+		restored := strings.ReplaceAll(string(data), config.SudoersDisabledTag, "")
+		if err := writeSudoersFile(path, []byte(restored)); err != nil {
+			return fmt.Errorf("restoring %s: %w", path, err)
 		}
+		os.Remove(path + config.SudoersBackupSuffix)
+		log.Printf("Restored sudo grants in %s", path)
+	}
+
+	return nil
+}
+
+// IsSudoAllowed determines if sudo access should be allowed at the given time
+// based on the configured time windows.
+func IsSudoAllowed(cfg *config.Config, now time.Time) bool {
+	if !cfg.Sudoers.Enabled {
+		return true // If not enabled, don't restrict
+	}
+	if state.IsPaused(now) {
+		return true // Enforcement is paused
 	}
 
-	return false
+	// Check if current time falls within any allowed window
+	return config.AnyWindowActive(cfg.Sudoers.TimeAllowed, now)
 }
 
 // CreateSudoersBackup creates a backup of the sudoers file before modification.
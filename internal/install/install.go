@@ -6,10 +6,12 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v3"
 
 	"glocker/internal/config"
+	"glocker/internal/enforcement"
 	"glocker/internal/utils"
 	"glocker/internal/web"
 )
@@ -20,30 +22,42 @@ const (
 
 // InstallGlocker performs the complete installation of Glocker on the system.
 // This includes copying the binary, config file, setting up systemd service,
-// and installing the Firefox extension.
-func InstallGlocker() error {
+// and installing the Firefox extension. Reinstalling over an existing
+// installation clears the immutable flags first and keeps the installed config
+// unless forceConfig is set.
+func InstallGlocker(forceConfig bool) error {
 	log.Println("╔════════════════════════════════════════════════╗")
 	log.Println("║              GLOCKER FULL INSTALL              ║")
 	log.Println("╚════════════════════════════════════════════════╝")
 	log.Println()
 
+	fs := utils.DefaultFileSystem{}
+	plan := planInstall(fs, forceConfig)
+
 	// Step 1: Validate config file before installation
-	log.Println("Validating configuration file...")
-	configData, err := os.ReadFile("conf/conf.yaml")
+	configSource := plan.configSource()
+	log.Printf("Validating configuration file %s...", configSource)
+	configData, err := os.ReadFile(configSource)
 	if err != nil {
-		return fmt.Errorf("failed to read config file conf/conf.yaml: %w", err)
+		return fmt.Errorf("failed to read config file %s: %w", configSource, err)
 	}
 
 	var cfg config.Config
 	if err := yaml.Unmarshal(configData, &cfg); err != nil {
-		return fmt.Errorf("invalid YAML in config file conf/conf.yaml: %w", err)
+		return fmt.Errorf("invalid YAML in config file %s: %w", configSource, err)
 	}
+	config.ExpandDayGroups(&cfg)
 
 	if err := config.ValidateConfig(&cfg); err != nil {
 		return fmt.Errorf("configuration validation failed: %w", err)
 	}
 	log.Println("✓ Configuration file is valid")
 
+	// Step 1b: Stop the daemon and make an existing installation writable again
+	if err := prepareUpgrade(fs, plan, time.Now()); err != nil {
+		return err
+	}
+
 	// Step 2: Get current executable path
 	exe, err := os.Executable()
 	if err != nil {
@@ -56,19 +70,21 @@ func InstallGlocker() error {
 	}
 
 	// Step 3: Copy config file from conf/conf.yaml to target location
-	log.Printf("Copying config file from conf/conf.yaml to %s", config.GlockerConfigFile)
+	if plan.CopyConfig {
+		log.Printf("Copying config file from conf/conf.yaml to %s", config.GlockerConfigFile)
 
-	// Create config directory if it doesn't exist
-	configDir := filepath.Dir(config.GlockerConfigFile)
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return fmt.Errorf("failed to create config directory: %w", err)
-	}
+		// Create config directory if it doesn't exist
+		configDir := filepath.Dir(config.GlockerConfigFile)
+		if err := os.MkdirAll(configDir, 0755); err != nil {
+			return fmt.Errorf("failed to create config directory: %w", err)
+		}
 
-	// Copy the config file
-	if err := utils.CopyFile("conf/conf.yaml", config.GlockerConfigFile); err != nil {
-		return fmt.Errorf("failed to copy config file: %w", err)
+		// Copy the config file
+		if err := utils.CopyFile("conf/conf.yaml", config.GlockerConfigFile); err != nil {
+			return fmt.Errorf("failed to copy config file: %w", err)
+		}
+		log.Printf("✓ Config file copied to %s", config.GlockerConfigFile)
 	}
-	log.Printf("✓ Config file copied to %s", config.GlockerConfigFile)
 
 	// Set ownership and make config file immutable
 	if err := os.Chown(config.GlockerConfigFile, 0, 0); err != nil {
@@ -80,7 +96,7 @@ func InstallGlocker() error {
 
 	// Step 4: Copy binary to install location
 	log.Printf("Installing binary to %s", config.InstallPath)
-	if err := utils.CopyFile(exePath, config.InstallPath); err != nil {
+	if err := utils.ReplaceFile(exePath, config.InstallPath); err != nil {
 		return fmt.Errorf("failed to copy binary: %w", err)
 	}
 
@@ -104,7 +120,7 @@ func InstallGlocker() error {
 	glocklockSource := filepath.Join(filepath.Dir(exePath), "glocklock")
 	if _, err := os.Stat(glocklockSource); err == nil {
 		log.Printf("Installing glocklock to %s", config.GlocklockInstallPath)
-		if err := utils.CopyFile(glocklockSource, config.GlocklockInstallPath); err != nil {
+		if err := utils.ReplaceFile(glocklockSource, config.GlocklockInstallPath); err != nil {
 			log.Printf("Warning: failed to copy glocklock binary: %v", err)
 		} else {
 			// Set ownership to root:root
@@ -131,7 +147,7 @@ func InstallGlocker() error {
 	glockpeekSource := filepath.Join(filepath.Dir(exePath), "glockpeek")
 	if _, err := os.Stat(glockpeekSource); err == nil {
 		log.Printf("Installing glockpeek to %s", config.GlockpeekInstallPath)
-		if err := utils.CopyFile(glockpeekSource, config.GlockpeekInstallPath); err != nil {
+		if err := utils.ReplaceFile(glockpeekSource, config.GlockpeekInstallPath); err != nil {
 			log.Printf("Warning: failed to copy glockpeek binary: %v", err)
 		} else {
 			// Set ownership to root:root
@@ -148,6 +164,17 @@ func InstallGlocker() error {
 		log.Printf("Note: glockpeek binary not found at %s, skipping", glockpeekSource)
 	}
 
+	// Step 4d: Back up the original hosts entries before glocker rewrites the file
+	hostsPath := cfg.HostsPath
+	if hostsPath == "" {
+		hostsPath = "/etc/hosts"
+	}
+	if err := enforcement.CreateHostsBackup(hostsPath); err != nil {
+		log.Printf("Warning: couldn't back up hosts file: %v", err)
+	} else {
+		log.Printf("✓ Original hosts entries backed up to %s", hostsPath+config.HostsBackupSuffix)
+	}
+
 	// Step 5: Create and install Firefox extension
 	if err := CreateFirefoxExtension(); err != nil {
 		log.Printf("Warning: Failed to create Firefox extension: %v", err)
@@ -156,7 +183,7 @@ func InstallGlocker() error {
 	}
 
 	// Step 6: Install systemd service
-	servicePath := "/etc/systemd/system/glocker.service"
+	servicePath := SystemdServicePath
 	log.Println("Installing systemd service...")
 	if err := utils.CopyFile(SystemdServiceFile, servicePath); err != nil {
 		return fmt.Errorf("failed to create service file: %w", err)
@@ -172,8 +199,9 @@ func InstallGlocker() error {
 		return fmt.Errorf("failed to enable service: %w", err)
 	}
 
-	// Start service
-	if err := exec.Command("systemctl", "start", "glocker.service").Run(); err != nil {
+	// Restart the service, so an upgrade runs the new binary and config even if
+	// stopping the old daemon failed
+	if err := exec.Command("systemctl", "restart", "glocker.service").Run(); err != nil {
 		return fmt.Errorf("failed to start service: %w", err)
 	}
 
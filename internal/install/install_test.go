@@ -1,10 +1,61 @@
 package install
 
 import (
+	"io/fs"
 	"os"
+	"slices"
 	"testing"
+	"time"
+
+	"glocker/internal/config"
 )
 
+// fakeFileSystem is an in-memory utils.FileSystem.
+type fakeFileSystem struct {
+	files map[string][]byte
+}
+
+func newFakeFileSystem(paths ...string) *fakeFileSystem {
+	f := &fakeFileSystem{files: make(map[string][]byte)}
+	for _, path := range paths {
+		f.files[path] = []byte("contents of " + path)
+	}
+	return f
+}
+
+func (f *fakeFileSystem) ReadFile(path string) ([]byte, error) {
+	data, ok := f.files[path]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+	return data, nil
+}
+
+func (f *fakeFileSystem) WriteFile(path string, data []byte, perm os.FileMode) error {
+	f.files[path] = data
+	return nil
+}
+
+func (f *fakeFileSystem) Stat(path string) (os.FileInfo, error) {
+	if _, ok := f.files[path]; !ok {
+		return nil, fs.ErrNotExist
+	}
+	return nil, nil
+}
+
+func (f *fakeFileSystem) Remove(path string) error {
+	delete(f.files, path)
+	return nil
+}
+
+func (f *fakeFileSystem) Chmod(path string, mode os.FileMode) error    { return nil }
+func (f *fakeFileSystem) Chown(path string, uid, gid int) error        { return nil }
+func (f *fakeFileSystem) MkdirAll(path string, perm os.FileMode) error { return nil }
+
+func (f *fakeFileSystem) OpenFile(name string, flag int, perm os.FileMode) (*os.File, error) {
+	return nil, fs.ErrPermission
+}
+
 func TestRunningAsRoot(t *testing.T) {
 	// Test real user ID check
 	realUID := os.Getuid()
@@ -23,6 +74,88 @@ func TestRunningAsRoot(t *testing.T) {
 	}
 }
 
+func TestPlanInstall(t *testing.T) {
+	tests := []struct {
+		name        string
+		installed   []string
+		forceConfig bool
+		want        installPlan
+	}{
+		{
+			name: "fresh install",
+			want: installPlan{CopyConfig: true},
+		},
+		{
+			name:      "upgrade keeps config",
+			installed: []string{config.InstallPath, config.GlockerConfigFile, SystemdServicePath},
+			want: installPlan{
+				Upgrade:      true,
+				Existing:     []string{config.InstallPath, config.GlockerConfigFile, SystemdServicePath},
+				BackupConfig: true,
+			},
+		},
+		{
+			name:        "upgrade with force-config",
+			installed:   []string{config.InstallPath, config.GlockerConfigFile},
+			forceConfig: true,
+			want: installPlan{
+				Upgrade:      true,
+				Existing:     []string{config.InstallPath, config.GlockerConfigFile},
+				BackupConfig: true,
+				CopyConfig:   true,
+			},
+		},
+		{
+			name:      "binary without config",
+			installed: []string{config.InstallPath},
+			want: installPlan{
+				Upgrade:    true,
+				Existing:   []string{config.InstallPath},
+				CopyConfig: true,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := planInstall(newFakeFileSystem(tt.installed...), tt.forceConfig)
+			if got.Upgrade != tt.want.Upgrade || got.BackupConfig != tt.want.BackupConfig ||
+				got.CopyConfig != tt.want.CopyConfig || !slices.Equal(got.Existing, tt.want.Existing) {
+				t.Errorf("planInstall() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstallPlan_ConfigSource(t *testing.T) {
+	if got := (installPlan{CopyConfig: true}).configSource(); got != "conf/conf.yaml" {
+		t.Errorf("configSource() with CopyConfig = %q, want conf/conf.yaml", got)
+	}
+	if got := (installPlan{}).configSource(); got != config.GlockerConfigFile {
+		t.Errorf("configSource() without CopyConfig = %q, want %q", got, config.GlockerConfigFile)
+	}
+}
+
+func TestBackupConfig(t *testing.T) {
+	fake := newFakeFileSystem(config.GlockerConfigFile)
+	now := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	backup, err := backupConfig(fake, now)
+	if err != nil {
+		t.Fatalf("backupConfig() error = %v", err)
+	}
+	if want := config.GlockerConfigFile + ".20260102-150405.bak"; backup != want {
+		t.Errorf("backupConfig() = %q, want %q", backup, want)
+	}
+	if string(fake.files[backup]) != string(fake.files[config.GlockerConfigFile]) {
+		t.Errorf("backup contents = %q, want the installed config", fake.files[backup])
+	}
+
+	if _, err := backupConfig(newFakeFileSystem(), now); err == nil {
+		t.Error("backupConfig() without an installed config should fail")
+	}
+}
+
 // Note: Most install package functions require root privileges and make system modifications,
 // so comprehensive testing would require a test environment with elevated privileges.
 // The functions are designed to be tested manually during actual installation/uninstallation.
@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"glocker/internal/config"
+	"glocker/internal/enforcement"
 )
 
 // RestoreSystemChanges removes all glocker modifications and restores the system to its original state.
@@ -21,18 +22,16 @@ func RestoreSystemChanges(cfg *config.Config) error {
 
 	// Clean up firewall rules
 	log.Println("Clearing firewall rules...")
-	clearCmd := `iptables -S OUTPUT | grep 'GLOCKER-BLOCK' | sed 's/-A/-D/' | xargs -r -L1 iptables`
-	if err := exec.Command("bash", "-c", clearCmd).Run(); err != nil {
+	if removed, err := enforcement.ClearFirewallRules("iptables"); err != nil {
 		log.Printf("   Warning: couldn't clear IPv4 rules: %v", err)
 	} else {
-		log.Println("✓ IPv4 firewall rules cleared")
+		log.Printf("✓ IPv4 firewall rules cleared (%d removed)", removed)
 	}
 
-	clearCmd6 := `ip6tables -S OUTPUT | grep 'GLOCKER-BLOCK' | sed 's/-A/-D/' | xargs -r -L1 ip6tables`
-	if err := exec.Command("bash", "-c", clearCmd6).Run(); err != nil {
+	if removed, err := enforcement.ClearFirewallRules("ip6tables"); err != nil {
 		log.Printf("   Warning: couldn't clear IPv6 rules: %v", err)
 	} else {
-		log.Println("✓ IPv6 firewall rules cleared")
+		log.Printf("✓ IPv6 firewall rules cleared (%d removed)", removed)
 	}
 
 	// Clean up hosts file
@@ -51,6 +50,11 @@ func RestoreSystemChanges(cfg *config.Config) error {
 		} else {
 			log.Println("✓ Sudoers configuration restored")
 		}
+		if err := enforcement.RestoreSudoersDropins(); err != nil {
+			log.Printf("   Warning: couldn't restore sudoers drop-ins: %v", err)
+		} else {
+			log.Println("✓ Sudoers drop-ins restored")
+		}
 	}
 
 	// Remove sudoers backup
@@ -79,6 +83,13 @@ func RestoreSystemChanges(cfg *config.Config) error {
 		log.Println("✓ Config file removed")
 	}
 
+	// Make the runtime overlay mutable, so /var/lib/glocker can be cleaned up
+	if _, err := os.Stat(config.OverlayPath()); err == nil {
+		if err := exec.Command("chattr", "-i", config.OverlayPath()).Run(); err != nil {
+			log.Printf("   Warning: couldn't make runtime overlay mutable: %v", err)
+		}
+	}
+
 	// Remove config directory if empty
 	configDir := filepath.Dir(config.GlockerConfigFile)
 	if err := os.Remove(configDir); err != nil {
@@ -88,12 +99,19 @@ func RestoreSystemChanges(cfg *config.Config) error {
 	}
 
 	// Remove socket file
-	socketPath := "/tmp/glocker.sock"
+	socketPath := cfg.IPC.SocketPath()
 	if err := os.Remove(socketPath); err != nil {
 		log.Printf("   Warning: couldn't remove socket file: %v", err)
 	} else {
 		log.Println("✓ Socket file removed")
 	}
+	if cfg.ObserverSocket.Enabled {
+		if err := os.Remove(cfg.ObserverSocket.SocketPath()); err != nil {
+			log.Printf("   Warning: couldn't remove observer socket file: %v", err)
+		} else {
+			log.Println("✓ Observer socket file removed")
+		}
+	}
 
 	// Make service file mutable (daemon can't delete it while running)
 	log.Println("Making service file mutable...")
@@ -149,8 +167,11 @@ func cleanupHostsFile(cfg *config.Config) error {
 	// Remove immutable flag
 	exec.Command("chattr", "-i", hostsPath).Run()
 
-	// Read current hosts file
+	// Read current hosts file, falling back to the backed up entries if it was deleted
 	content, err := os.ReadFile(hostsPath)
+	if os.IsNotExist(err) {
+		content, err = os.ReadFile(hostsPath + config.HostsBackupSuffix)
+	}
 	if err != nil {
 		return fmt.Errorf("reading hosts file: %w", err)
 	}
@@ -169,7 +190,15 @@ func cleanupHostsFile(cfg *config.Config) error {
 
 	// Write cleaned content
 	newContent := strings.Join(newLines, "\n")
-	return os.WriteFile(hostsPath, []byte(newContent), 0644)
+	if err := os.WriteFile(hostsPath, []byte(newContent), 0644); err != nil {
+		return err
+	}
+
+	// The backup is only needed while glocker manages the file
+	if err := os.Remove(hostsPath + config.HostsBackupSuffix); err != nil && !os.IsNotExist(err) {
+		log.Printf("   Warning: couldn't remove hosts backup: %v", err)
+	}
+	return nil
 }
 
 // restoreSudoers restores the sudoers file from backup or replaces blocked line with allowed line.
@@ -0,0 +1,115 @@
+package install
+
+import (
+	"fmt"
+	"log"
+	"os/exec"
+	"time"
+
+	"glocker/internal/config"
+	"glocker/internal/utils"
+)
+
+// SystemdServicePath is where the systemd unit is installed.
+const SystemdServicePath = "/etc/systemd/system/glocker.service"
+
+// immutablePaths are the installed files protected with chattr +i. They have to
+// be made mutable again before a reinstall can overwrite them.
+var immutablePaths = []string{
+	config.InstallPath,
+	config.GlocklockInstallPath,
+	config.GlockerConfigFile,
+	SystemdServicePath,
+}
+
+// installPlan describes what an install does with an existing installation.
+type installPlan struct {
+	Upgrade      bool     // Glocker is already installed
+	Existing     []string // Installed immutable files, made mutable before copying
+	BackupConfig bool     // Back up the installed config before anything is copied
+	CopyConfig   bool     // Install conf/conf.yaml over the config file
+}
+
+// planInstall inspects fs for an existing installation. An installed config is
+// backed up and kept unless forceConfig is set; without one, conf/conf.yaml is
+// installed.
+func planInstall(fs utils.FileSystem, forceConfig bool) installPlan {
+	var plan installPlan
+	for _, path := range immutablePaths {
+		if _, err := fs.Stat(path); err == nil {
+			plan.Existing = append(plan.Existing, path)
+		}
+	}
+	plan.Upgrade = len(plan.Existing) > 0
+
+	_, err := fs.Stat(config.GlockerConfigFile)
+	hasConfig := err == nil
+	plan.BackupConfig = hasConfig
+	plan.CopyConfig = !hasConfig || forceConfig
+	return plan
+}
+
+// configSource returns the config file the install validates and runs with.
+func (p installPlan) configSource() string {
+	if p.CopyConfig {
+		return "conf/conf.yaml"
+	}
+	return config.GlockerConfigFile
+}
+
+// configBackupPath returns the path the installed config is backed up to,
+// like /etc/glocker/config.yaml.20260102-150405.bak.
+func configBackupPath(now time.Time) string {
+	return fmt.Sprintf("%s.%s.bak", config.GlockerConfigFile, now.Format("20060102-150405"))
+}
+
+// backupConfig copies the installed config to configBackupPath and returns the
+// backup's path.
+func backupConfig(fs utils.FileSystem, now time.Time) (string, error) {
+	data, err := fs.ReadFile(config.GlockerConfigFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read installed config: %w", err)
+	}
+	backup := configBackupPath(now)
+	if err := fs.WriteFile(backup, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write config backup: %w", err)
+	}
+	return backup, nil
+}
+
+// prepareUpgrade stops a running daemon, makes the files of an existing
+// installation mutable and backs up its config, so the install can overwrite
+// them. The daemon is stopped first, so it doesn't take the replaced files for
+// tampering; its file checksums are taken afresh when the install restarts it.
+// Failing to stop it or clear a flag is only a warning; the copy that follows
+// reports the real error.
+func prepareUpgrade(fs utils.FileSystem, plan installPlan, now time.Time) error {
+	if !plan.Upgrade {
+		return nil
+	}
+	log.Println("Existing installation found, preparing upgrade...")
+	if utils.IsServiceRunning("glocker.service") {
+		if err := exec.Command("systemctl", "stop", "glocker.service").Run(); err != nil {
+			log.Printf("Warning: couldn't stop the running glocker service: %v", err)
+		} else {
+			log.Println("✓ Stopped the running glocker service")
+		}
+	}
+	for _, path := range plan.Existing {
+		if err := exec.Command("chattr", "-i", path).Run(); err != nil {
+			log.Printf("Warning: couldn't clear immutable flag on %s: %v", path, err)
+		}
+	}
+
+	if plan.BackupConfig {
+		backup, err := backupConfig(fs, now)
+		if err != nil {
+			return err
+		}
+		log.Printf("✓ Installed config backed up to %s", backup)
+	}
+	if !plan.CopyConfig {
+		log.Printf("Keeping installed config %s (use -force-config to replace it with conf/conf.yaml)", config.GlockerConfigFile)
+	}
+	return nil
+}
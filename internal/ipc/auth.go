@@ -0,0 +1,194 @@
+package ipc
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"glocker/internal/config"
+)
+
+// authPrefix starts a signed command: "auth:<nonce>:<hmac>:<command>". The nonce
+// is the client's clock in Unix nanoseconds and the HMAC-SHA256 covers
+// "<nonce>:<command>".
+const authPrefix = "auth:"
+
+// nonceWindow is how far a nonce may be from the daemon's clock. Accepted nonces
+// are remembered until they fall outside it, so a replayed command is rejected
+// either as already used or as too old.
+const nonceWindow = 2 * time.Minute
+
+// Authentication errors.
+var (
+	ErrAuthRequired    = errors.New("authentication required")
+	ErrAuthInvalid     = errors.New("invalid signature")
+	ErrAuthReplayed    = errors.New("nonce already used")
+	ErrAuthNonceExpiry = errors.New("nonce outside the allowed time window")
+)
+
+// readOnlyActions don't change any state, so they are served without a
+// signature even when ipc.auth_key is set.
+var readOnlyActions = map[string]bool{
+	"status":        true,
+	"info":          true,
+	"status-json":   true,
+	"info-json":     true,
+	"reload-dry":    true,
+	"list-unblocks": true,
+	"history":       true,
+}
+
+// seenNonces remembers the nonces accepted within nonceWindow.
+var seenNonces = struct {
+	mu   sync.Mutex
+	seen map[int64]time.Time
+}{seen: make(map[int64]time.Time)}
+
+// lastNonce is the last nonce this process signed with, so nonces stay
+// increasing even when two commands are signed within the clock's resolution.
+var lastNonce = struct {
+	mu    sync.Mutex
+	value int64
+}{}
+
+// loadAuthKey returns the key the CLI signs commands with, or nil to send them
+// unsigned. Overridden in tests.
+var loadAuthKey = func() []byte {
+	cfg := clientConfig()
+	if cfg.IPC.AuthKey == "" {
+		return nil
+	}
+	key, err := cfg.IPC.ReadAuthKey()
+	if err != nil {
+		slog.Debug("Sending socket command unsigned", "error", err)
+		return nil
+	}
+	return key
+}
+
+// commandMAC returns the hex HMAC-SHA256 of a nonce and command.
+func commandMAC(key []byte, nonce int64, command string) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%d:%s", nonce, command)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// signCommand returns command signed with key and nonce.
+func signCommand(key []byte, nonce int64, command string) string {
+	return fmt.Sprintf("%s%d:%s:%s", authPrefix, nonce, commandMAC(key, nonce, command), command)
+}
+
+// SignCommand signs command with the ipc.auth_key key when it is readable, and
+// returns it unchanged otherwise. Read-only commands are never signed.
+func SignCommand(command string) string {
+	if isReadOnly(command) {
+		return command
+	}
+	key := loadAuthKey()
+	if key == nil {
+		return command
+	}
+	return signCommand(key, nextNonce(time.Now()), command)
+}
+
+// nextNonce returns now in Unix nanoseconds, or one more than the last nonce if
+// the clock hasn't moved on.
+func nextNonce(now time.Time) int64 {
+	lastNonce.mu.Lock()
+	defer lastNonce.mu.Unlock()
+	lastNonce.value = max(lastNonce.value+1, now.UnixNano())
+	return lastNonce.value
+}
+
+// authenticateCommand checks a command line received on the control socket and
+// returns the command to run. Without a key every command is accepted as is.
+// With a key, signed commands must carry a valid HMAC and a fresh nonce, and
+// unsigned commands are only accepted for readOnlyActions.
+func authenticateCommand(key []byte, line string, now time.Time) (string, error) {
+	signed, isSigned := strings.CutPrefix(line, authPrefix)
+	if key == nil {
+		if isSigned {
+			// The daemon has no key; run the command the client signed
+			if parts := strings.SplitN(signed, ":", 3); len(parts) == 3 {
+				return parts[2], nil
+			}
+		}
+		return line, nil
+	}
+
+	if !isSigned {
+		if isReadOnly(line) {
+			return line, nil
+		}
+		return "", ErrAuthRequired
+	}
+
+	parts := strings.SplitN(signed, ":", 3)
+	if len(parts) != 3 {
+		return "", ErrAuthInvalid
+	}
+	nonce, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return "", ErrAuthInvalid
+	}
+	command := parts[2]
+	if !hmac.Equal([]byte(parts[1]), []byte(commandMAC(key, nonce, command))) {
+		return "", ErrAuthInvalid
+	}
+	if err := useNonce(nonce, now); err != nil {
+		return "", err
+	}
+	return command, nil
+}
+
+// authenticateLine authenticates a control socket command line against the
+// ipc.auth_key in cfg. If the key can't be read, only unsigned read-only
+// commands are accepted.
+func authenticateLine(cfg *config.Config, line string, now time.Time) (string, error) {
+	if cfg.IPC.AuthKey == "" {
+		return authenticateCommand(nil, line, now)
+	}
+	key, err := cfg.IPC.ReadAuthKey()
+	if err != nil {
+		if isReadOnly(line) {
+			return line, nil
+		}
+		return "", fmt.Errorf("can't verify command: %w", err)
+	}
+	return authenticateCommand(key, line, now)
+}
+
+// isReadOnly reports whether an unsigned command line is one of readOnlyActions.
+func isReadOnly(line string) bool {
+	action, _, _ := strings.Cut(line, ":")
+	return readOnlyActions[strings.TrimSpace(action)]
+}
+
+// useNonce records nonce as used, rejecting it if it was used before or is too
+// far from now.
+func useNonce(nonce int64, now time.Time) error {
+	issued := time.Unix(0, nonce)
+	if issued.Before(now.Add(-nonceWindow)) || issued.After(now.Add(nonceWindow)) {
+		return ErrAuthNonceExpiry
+	}
+
+	seenNonces.mu.Lock()
+	defer seenNonces.mu.Unlock()
+	for n, at := range seenNonces.seen {
+		if now.Sub(at) > 2*nonceWindow {
+			delete(seenNonces.seen, n)
+		}
+	}
+	if _, ok := seenNonces.seen[nonce]; ok {
+		return ErrAuthReplayed
+	}
+	seenNonces.seen[nonce] = now
+	return nil
+}
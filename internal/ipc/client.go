@@ -0,0 +1,145 @@
+package ipc
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"glocker/internal/cli"
+)
+
+// Connect dials the daemon socket. Failures are ExitDaemonDown exit errors.
+func Connect() (net.Conn, error) {
+	return connect(SocketPath())
+}
+
+func connect(socketPath string) (net.Conn, error) {
+	conn, err := net.Dial("unix", socketPath)
+	if err != nil {
+		return nil, cli.NewExitError(cli.ExitDaemonDown, "failed to connect to glocker service: %w", err)
+	}
+	return conn, nil
+}
+
+// SendCommand sends a one-line command to the daemon and returns its one-line response.
+// An "ERROR: ..." response is returned as an ExitRejected exit error.
+func SendCommand(message string) (string, error) {
+	return sendCommand(SocketPath(), message)
+}
+
+func sendCommand(socketPath, message string) (string, error) {
+	conn, err := connect(socketPath)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(SignCommand(message) + "\n")); err != nil {
+		return "", fmt.Errorf("failed to send command: %w", err)
+	}
+
+	response, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	return CheckResponse(response)
+}
+
+// SendMultilineCommand sends a command whose response spans several lines ending
+// with an "END" line, and returns those lines.
+func SendMultilineCommand(message string) ([]string, error) {
+	return sendMultilineCommand(SocketPath(), message)
+}
+
+func sendMultilineCommand(socketPath, message string) ([]string, error) {
+	conn, err := connect(socketPath)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(SignCommand(message) + "\n")); err != nil {
+		return nil, fmt.Errorf("failed to send command: %w", err)
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "END" {
+			return lines, nil
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return nil, fmt.Errorf("daemon closed the connection before the response ended")
+}
+
+// CheckResponse trims a daemon response line and turns "ERROR: ..." into an
+// ExitRejected exit error.
+func CheckResponse(response string) (string, error) {
+	response = strings.TrimSpace(response)
+	if msg, ok := strings.CutPrefix(response, "ERROR:"); ok {
+		return "", cli.NewExitError(cli.ExitRejected, "%s", strings.TrimSpace(msg))
+	}
+	return response, nil
+}
+
+// PauseAnswer is shown the pause challenge and the mindful delay to wait out,
+// and returns the text typed by the user.
+type PauseAnswer func(delay time.Duration, challenge string) (string, error)
+
+// RequestPause asks the daemon to pause enforcement for minutes, answering its
+// typing challenge with answer. Returns the daemon's final response.
+func RequestPause(minutes int, answer PauseAnswer) (string, error) {
+	return requestPause(SocketPath(), minutes, answer)
+}
+
+func requestPause(socketPath string, minutes int, answer PauseAnswer) (string, error) {
+	conn, err := connect(socketPath)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	if _, err := fmt.Fprintf(conn, "%s\n", SignCommand(fmt.Sprintf("pause:%d", minutes))); err != nil {
+		return "", fmt.Errorf("failed to send command: %w", err)
+	}
+
+	reader := bufio.NewReader(conn)
+	response, err := reader.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	response, err = CheckResponse(response)
+	if err != nil {
+		return "", err
+	}
+
+	// "CHALLENGE: <delay seconds>:<text>"
+	payload, ok := strings.CutPrefix(response, "CHALLENGE:")
+	delayStr, challenge, found := strings.Cut(strings.TrimSpace(payload), ":")
+	delay, convErr := strconv.Atoi(delayStr)
+	if !ok || !found || convErr != nil {
+		return "", fmt.Errorf("unexpected response: %s", response)
+	}
+
+	typed, err := answer(time.Duration(delay)*time.Second, challenge)
+	if err != nil {
+		return "", err
+	}
+	if _, err := fmt.Fprintf(conn, "%s\n", strings.TrimSpace(typed)); err != nil {
+		return "", fmt.Errorf("failed to send challenge answer: %w", err)
+	}
+
+	response, err = reader.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	return CheckResponse(response)
+}
@@ -0,0 +1,103 @@
+package ipc
+
+import (
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"glocker/internal/config"
+	"glocker/internal/utils"
+)
+
+// Hooks for tests, so the lock-screen command can run without a display or root.
+var (
+	findSession  = utils.FindGraphicalSession
+	startProcess = func(cmd *exec.Cmd) error { return cmd.Start() }
+)
+
+// mindfulPassages splits mindful_text into passages separated by blank lines.
+func mindfulPassages(text string) []string {
+	var passages []string
+	for _, block := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n\n") {
+		if block = strings.TrimSpace(block); block != "" {
+			passages = append(passages, block)
+		}
+	}
+	return passages
+}
+
+// lockScreenCommand builds the glocklock command that shows passageFile on the
+// user's display. It runs as the session user in a new session, so it keeps
+// running independent of the daemon and can talk to their X server.
+func lockScreenCommand(session utils.GraphicalSession, passageFile string) *exec.Cmd {
+	cmd := exec.Command(config.GlocklockInstallPath, "-text", passageFile)
+	cmd.Env = []string{
+		"DISPLAY=" + session.Display,
+		"PATH=/usr/local/bin:/usr/bin:/bin",
+	}
+	if session.XAuthority != "" {
+		cmd.Env = append(cmd.Env, "XAUTHORITY="+session.XAuthority)
+	}
+	if session.Home != "" {
+		cmd.Env = append(cmd.Env, "HOME="+session.Home)
+		cmd.Dir = session.Home
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setsid:     true,
+		Credential: &syscall.Credential{Uid: session.UID, Gid: session.GID},
+	}
+	return cmd
+}
+
+// LockScreen starts glocklock on the logged-in user's display with a random
+// passage from mindful_text. It returns once the locker has been launched. It
+// serves -lock-screen and lock actions of violation_tracking.actions.
+func LockScreen(cfg *config.Config) error {
+	passages := mindfulPassages(cfg.ViolationTracking.MindfulText)
+	if len(passages) == 0 {
+		return fmt.Errorf("no mindful_text configured")
+	}
+	passage := passages[rand.IntN(len(passages))]
+
+	session, err := findSession()
+	if err != nil {
+		return err
+	}
+
+	// glocklock reads the passage from a file readable by the session user
+	file, err := os.CreateTemp("", "glocker-lock-*.txt")
+	if err != nil {
+		return fmt.Errorf("failed to create passage file: %w", err)
+	}
+	passageFile := file.Name()
+	_, err = file.WriteString(passage + "\n")
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(passageFile, 0644)
+	}
+	if err != nil {
+		os.Remove(passageFile)
+		return fmt.Errorf("failed to write passage file: %w", err)
+	}
+
+	cmd := lockScreenCommand(session, passageFile)
+	if err := startProcess(cmd); err != nil {
+		os.Remove(passageFile)
+		return fmt.Errorf("failed to start screen locker: %w", err)
+	}
+	log.Printf("Screen locker started on display %s for uid %d", session.Display, session.UID)
+
+	go func() {
+		if cmd.Process != nil {
+			cmd.Wait()
+		}
+		os.Remove(passageFile)
+	}()
+	return nil
+}
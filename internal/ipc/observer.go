@@ -0,0 +1,129 @@
+package ipc
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"log/slog"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"glocker/internal/cli"
+	"glocker/internal/config"
+)
+
+// observerActions are the read-only commands served on the observer socket.
+var observerActions = map[string]func(*config.Config) string{
+	"status":      cli.GetStatusResponse,
+	"info":        cli.GetInfoResponse,
+	"status-json": cli.GetStatusJSONResponse,
+	"info-json":   cli.GetInfoJSONResponse,
+	"history":     func(*config.Config) string { return cli.GetHistoryResponse() },
+}
+
+// SetupObserverSocket starts the read-only observer socket when enabled. It is
+// readable and writable by observer_socket.group, so members can query status
+// without root; everything that changes state stays on the control socket.
+func SetupObserverSocket(cfg *config.Config) error {
+	if !cfg.ObserverSocket.Enabled {
+		return nil
+	}
+	socketPath := cfg.ObserverSocket.SocketPath()
+
+	dir := filepath.Dir(socketPath)
+	if err := prepareSocketDir(dir); err != nil {
+		return err
+	}
+	if err := removeStaleSocket(socketPath); err != nil {
+		return err
+	}
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to create observer socket: %w", err)
+	}
+
+	mode := os.FileMode(0600)
+	if group := cfg.ObserverSocket.Group; group != "" {
+		if err := chownGroup(socketPath, group); err != nil {
+			log.Printf("Warning: observer socket is root-only: %v", err)
+		} else if err := allowGroupTraverse(dir, group); err != nil {
+			log.Printf("Warning: observer socket is root-only: %v", err)
+		} else {
+			mode = 0660
+		}
+	}
+	if err := os.Chmod(socketPath, mode); err != nil {
+		log.Printf("Warning: couldn't set observer socket permissions: %v", err)
+	}
+
+	log.Printf("Observer socket listening on %s", socketPath)
+	go handleConnections(cfg, listener, HandleObserverConnection)
+	return nil
+}
+
+// allowGroupTraverse lets the named group reach sockets in dir, which may be the
+// control socket's root-only directory: the group can look up names in it, but
+// not list it, and the control socket itself stays root-only. A directory the
+// group can already traverse is left alone.
+func allowGroupTraverse(dir, group string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("failed to check socket directory: %w", err)
+	}
+	if info.Mode().Perm()&0011 != 0 {
+		return nil
+	}
+	if err := chownGroup(dir, group); err != nil {
+		return err
+	}
+	if err := os.Chmod(dir, info.Mode().Perm()|0010); err != nil {
+		return fmt.Errorf("failed to let group %s into %s: %w", group, dir, err)
+	}
+	return nil
+}
+
+// chownGroup gives group ownership of path to the named group.
+func chownGroup(path, name string) error {
+	group, err := user.LookupGroup(name)
+	if err != nil {
+		return fmt.Errorf("failed to look up group %s: %w", name, err)
+	}
+	gid, err := strconv.Atoi(group.Gid)
+	if err != nil {
+		return fmt.Errorf("invalid gid %q for group %s: %w", group.Gid, name, err)
+	}
+	if err := os.Chown(path, -1, gid); err != nil {
+		return fmt.Errorf("failed to set group %s on %s: %w", name, path, err)
+	}
+	return nil
+}
+
+// HandleObserverConnection processes commands from an observer socket connection.
+// Only the read-only commands in observerActions are served; any other command is
+// rejected without being run.
+func HandleObserverConnection(cfg *config.Config, conn net.Conn) {
+	defer conn.Close()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		action, _, _ := strings.Cut(line, ":")
+		action = strings.TrimSpace(action)
+		slog.Debug("Observer socket command received", "action", action)
+
+		respond, ok := observerActions[action]
+		if !ok {
+			conn.Write([]byte(fmt.Sprintf("ERROR: %s is not allowed on the read-only observer socket\n", action)))
+			continue
+		}
+		conn.Write([]byte(respond(cfg)))
+	}
+}
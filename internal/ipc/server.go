@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"net"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
@@ -15,33 +16,42 @@ import (
 	"glocker/internal/config"
 	"glocker/internal/enforcement"
 	"glocker/internal/install"
+	"glocker/internal/notify"
+	"glocker/internal/state"
 	"glocker/internal/web"
 )
 
-const SocketPath = "/tmp/glocker.sock"
+// UninstallScheduledPrefix starts the answer to an -uninstall request that was only
+// scheduled, so the client doesn't wait for the uninstall to complete.
+const UninstallScheduledPrefix = "SCHEDULED:"
 
 // SetupCommunication creates and starts listening on the Unix domain socket.
 func SetupCommunication(cfg *config.Config) error {
-	// Remove existing socket
-	os.Remove(SocketPath)
+	socketPath := cfg.IPC.SocketPath()
+	if err := prepareSocketDir(filepath.Dir(socketPath)); err != nil {
+		return err
+	}
+	if err := removeStaleSocket(socketPath); err != nil {
+		return err
+	}
 
 	// Create Unix domain socket
-	listener, err := net.Listen("unix", SocketPath)
+	listener, err := net.Listen("unix", socketPath)
 	if err != nil {
 		return fmt.Errorf("failed to create socket: %w", err)
 	}
 
 	// Set permissions
-	if err := os.Chmod(SocketPath, 0600); err != nil {
+	if err := os.Chmod(socketPath, 0600); err != nil {
 		log.Printf("Warning: couldn't set socket permissions: %v", err)
 	}
 
-	go handleConnections(cfg, listener)
+	go handleConnections(cfg, listener, HandleConnection)
 	return nil
 }
 
-// handleConnections accepts incoming socket connections.
-func handleConnections(cfg *config.Config, listener net.Listener) {
+// handleConnections accepts incoming socket connections and serves each with handle.
+func handleConnections(cfg *config.Config, listener net.Listener, handle func(*config.Config, net.Conn)) {
 	defer listener.Close()
 
 	for {
@@ -51,7 +61,7 @@ func handleConnections(cfg *config.Config, listener net.Listener) {
 			continue
 		}
 
-		go HandleConnection(cfg, conn)
+		go handle(cfg, conn)
 	}
 }
 
@@ -66,6 +76,13 @@ func HandleConnection(cfg *config.Config, conn net.Conn) {
 			continue
 		}
 
+		line, err := authenticateLine(cfg, line, time.Now())
+		if err != nil {
+			log.Printf("Rejected socket command: %v", err)
+			conn.Write([]byte(fmt.Sprintf("ERROR: %v\n", err)))
+			continue
+		}
+
 		parts := strings.SplitN(line, ":", 2)
 		if len(parts) < 1 {
 			conn.Write([]byte("ERROR: Invalid format\n"))
@@ -82,15 +99,24 @@ func HandleConnection(cfg *config.Config, conn net.Conn) {
 		case "info":
 			response := cli.GetInfoResponse(cfg)
 			conn.Write([]byte(response))
+		case "status-json":
+			response := cli.GetStatusJSONResponse(cfg)
+			conn.Write([]byte(response))
+		case "info-json":
+			response := cli.GetInfoJSONResponse(cfg)
+			conn.Write([]byte(response))
 		case "reload":
 			conn.Write([]byte("OK: Reload request received\n"))
 			go cli.ProcessReloadRequest(cfg)
+		case "reload-dry":
+			response := cli.GetReloadDryRunResponse(cfg)
+			conn.Write([]byte(response))
 		case "unblock":
 			if len(parts) != 2 {
 				conn.Write([]byte("ERROR: Invalid format. Use 'unblock:domains:reason'\n"))
 				continue
 			}
-			payload := strings.TrimSpace(parts[1])
+			payload, untilStr := splitUnblockUntil(strings.TrimSpace(parts[1]))
 			payloadParts := strings.SplitN(payload, ":", 2)
 			if len(payloadParts) != 2 {
 				conn.Write([]byte("ERROR: Reason required. Use 'unblock:domains:reason'\n"))
@@ -102,20 +128,48 @@ func HandleConnection(cfg *config.Config, conn net.Conn) {
 				conn.Write([]byte("ERROR: Reason cannot be empty\n"))
 				continue
 			}
+			var until time.Time
+			if untilStr != "" {
+				var err error
+				if until, err = cli.ParseUnblockUntil(cfg, untilStr, time.Now()); err != nil {
+					conn.Write([]byte(fmt.Sprintf("ERROR: %v\n", err)))
+					continue
+				}
+			}
 			// Process unblock request and check for errors
-			if err := cli.ProcessUnblockRequest(cfg, domains, reason); err != nil {
+			if err := cli.ProcessUnblockRequest(cfg, domains, reason, until); err != nil {
 				conn.Write([]byte(fmt.Sprintf("ERROR: %v\n", err)))
 				continue
 			}
-			conn.Write([]byte("OK: Unblock request received\n"))
+			if !until.IsZero() {
+				conn.Write([]byte(fmt.Sprintf("OK: Unblock request received, unblocked until %s\n", until.Format("15:04"))))
+			} else {
+				conn.Write([]byte("OK: Unblock request received\n"))
+			}
+		case "list-unblocks":
+			conn.Write([]byte(cli.GetUnblocksResponse()))
+		case "history":
+			conn.Write([]byte(cli.GetHistoryResponse()))
+		case "revoke-unblock":
+			if len(parts) != 2 {
+				conn.Write([]byte("ERROR: Invalid format. Use 'revoke-unblock:domain'\n"))
+				continue
+			}
+			if err := cli.ProcessRevokeRequest(cfg, parts[1]); err != nil {
+				conn.Write([]byte(fmt.Sprintf("ERROR: %v\n", err)))
+				continue
+			}
+			conn.Write([]byte("OK: Unblock revoked\n"))
 		case "block":
 			if len(parts) != 2 {
 				conn.Write([]byte("ERROR: Invalid format. Use 'block:domains'\n"))
 				continue
 			}
-			domains := strings.TrimSpace(parts[1])
+			if err := cli.ProcessBlockRequest(cfg, strings.TrimSpace(parts[1])); err != nil {
+				conn.Write([]byte(fmt.Sprintf("ERROR: %v\n", err)))
+				continue
+			}
 			conn.Write([]byte("OK: Block request received\n"))
-			go cli.ProcessBlockRequest(cfg, domains)
 		case "panic":
 			if len(parts) != 2 {
 				conn.Write([]byte("ERROR: Invalid format. Use 'panic:minutes'\n"))
@@ -129,9 +183,65 @@ func HandleConnection(cfg *config.Config, conn net.Conn) {
 			}
 			conn.Write([]byte(fmt.Sprintf("OK: Entering panic mode for %d minutes\n", minutes)))
 			go cli.ProcessPanicRequest(cfg, minutes)
+		case "pause":
+			if len(parts) != 2 {
+				conn.Write([]byte("ERROR: Invalid format. Use 'pause:minutes'\n"))
+				continue
+			}
+			minutes, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+			if err != nil {
+				conn.Write([]byte("ERROR: Invalid minutes value. Must be a positive integer\n"))
+				continue
+			}
+			if err := cli.CheckPauseRequest(cfg, minutes, time.Now()); err != nil {
+				conn.Write([]byte(fmt.Sprintf("ERROR: %v\n", err)))
+				continue
+			}
+
+			// The client waits out mindful_delay and then types the challenge back
+			challenge := cli.PauseChallenge(minutes)
+			issuedAt := time.Now()
+			conn.Write([]byte(fmt.Sprintf("CHALLENGE: %d:%s\n", cfg.MindfulDelay, challenge)))
+			if !scanner.Scan() {
+				return
+			}
+			if err := cli.CheckPauseResponse(cfg, minutes, scanner.Text(), issuedAt, time.Now()); err != nil {
+				log.Printf("Pause request rejected: %v", err)
+				conn.Write([]byte(fmt.Sprintf("ERROR: %v\n", err)))
+				continue
+			}
+			pausedUntil, err := cli.ProcessPauseRequest(cfg, minutes)
+			if err != nil {
+				conn.Write([]byte(fmt.Sprintf("ERROR: %v\n", err)))
+				continue
+			}
+			conn.Write([]byte(fmt.Sprintf("OK: Blocking paused until %s\n", pausedUntil.Format("15:04"))))
+		case "focus":
+			if len(parts) != 2 {
+				conn.Write([]byte("ERROR: Invalid format. Use 'focus:duration'\n"))
+				continue
+			}
+			duration, err := cli.ParseFocusDuration(parts[1])
+			if err != nil {
+				conn.Write([]byte(fmt.Sprintf("ERROR: %v\n", err)))
+				continue
+			}
+			until, err := cli.ProcessFocusRequest(cfg, duration)
+			if err != nil {
+				conn.Write([]byte(fmt.Sprintf("ERROR: %v\n", err)))
+				continue
+			}
+			conn.Write([]byte(fmt.Sprintf("OK: Focus session started, distractions blocked until %s\n", until.Format("15:04"))))
 		case "lock":
 			conn.Write([]byte("OK: Lock request received\n"))
 			go processLockRequest(cfg)
+		case "lock-screen":
+			if err := LockScreen(cfg); err != nil {
+				log.Printf("Lock screen request failed: %v", err)
+				conn.Write([]byte(fmt.Sprintf("ERROR: %v\n", err)))
+				continue
+			}
+			conn.Write([]byte("OK: Screen locker started\n"))
 		case "add-keyword":
 			if len(parts) != 2 {
 				conn.Write([]byte("ERROR: Invalid format. Use 'add-keyword:keywords'\n"))
@@ -140,6 +250,32 @@ func HandleConnection(cfg *config.Config, conn net.Conn) {
 			keywords := strings.TrimSpace(parts[1])
 			conn.Write([]byte("OK: Add keyword request received\n"))
 			go processAddKeywordRequest(cfg, keywords)
+		case "enable-category":
+			if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+				conn.Write([]byte("ERROR: Invalid format. Use 'enable-category:name'\n"))
+				continue
+			}
+			if err := cli.ProcessCategoryRequest(cfg, parts[1], true, ""); err != nil {
+				conn.Write([]byte(fmt.Sprintf("ERROR: %v\n", err)))
+				continue
+			}
+			conn.Write([]byte(fmt.Sprintf("OK: Category %s enabled\n", strings.TrimSpace(parts[1]))))
+		case "disable-category":
+			if len(parts) != 2 {
+				conn.Write([]byte("ERROR: Invalid format. Use 'disable-category:name:reason'\n"))
+				continue
+			}
+			payloadParts := strings.SplitN(parts[1], ":", 2)
+			if len(payloadParts) != 2 || strings.TrimSpace(payloadParts[1]) == "" {
+				conn.Write([]byte("ERROR: Reason required. Use 'disable-category:name:reason'\n"))
+				continue
+			}
+			name := strings.TrimSpace(payloadParts[0])
+			if err := cli.ProcessCategoryRequest(cfg, name, false, payloadParts[1]); err != nil {
+				conn.Write([]byte(fmt.Sprintf("ERROR: %v\n", err)))
+				continue
+			}
+			conn.Write([]byte(fmt.Sprintf("OK: Category %s disabled\n", name)))
 		case "uninstall":
 			if len(parts) != 2 {
 				conn.Write([]byte("ERROR: Invalid format. Use 'uninstall:reason'\n"))
@@ -150,6 +286,15 @@ func HandleConnection(cfg *config.Config, conn net.Conn) {
 				conn.Write([]byte("ERROR: Reason cannot be empty\n"))
 				continue
 			}
+			proceed, unlockAt, err := cli.ProcessUninstallRequest(cfg, reason)
+			if err != nil {
+				conn.Write([]byte(fmt.Sprintf("ERROR: %v\n", err)))
+				continue
+			}
+			if !proceed {
+				conn.Write([]byte(fmt.Sprintf("%s Uninstall can be confirmed after %s, run -uninstall again then\n", UninstallScheduledPrefix, unlockAt.Format("2006-01-02 15:04"))))
+				continue
+			}
 			conn.Write([]byte("OK: Uninstall request received\n"))
 			go processUninstallRequest(cfg, reason, conn)
 		default:
@@ -160,7 +305,7 @@ func HandleConnection(cfg *config.Config, conn net.Conn) {
 
 // SendSocketMessage sends a message to the glocker socket and returns the response.
 func SendSocketMessage(action, payload string) (string, error) {
-	conn, err := net.Dial("unix", SocketPath)
+	conn, err := net.Dial("unix", SocketPath())
 	if err != nil {
 		return "", fmt.Errorf("failed to connect to socket: %w", err)
 	}
@@ -170,7 +315,7 @@ func SendSocketMessage(action, payload string) (string, error) {
 	if payload != "" {
 		message += ":" + payload
 	}
-	message += "\n"
+	message = SignCommand(message) + "\n"
 
 	if _, err := conn.Write([]byte(message)); err != nil {
 		return "", fmt.Errorf("failed to send message: %w", err)
@@ -207,10 +352,23 @@ func processLockRequest(cfg *config.Config) {
 	}
 }
 
-// processAddKeywordRequest adds keywords to both URL and content keyword lists.
+// splitUnblockUntil splits the optional ":until=HH:MM" suffix off an unblock
+// payload ("domains:reason[:until=HH:MM]"), returning the rest and the end time
+// ("" if there is none).
+func splitUnblockUntil(payload string) (string, string) {
+	i := strings.LastIndex(payload, ":until=")
+	if i < 0 {
+		return payload, ""
+	}
+	return payload[:i], strings.TrimSpace(payload[i+len(":until="):])
+}
+
+// processAddKeywordRequest adds keywords to both URL and content keyword lists,
+// and to the runtime overlay so they survive reloads and restarts.
 func processAddKeywordRequest(cfg *config.Config, keywordsStr string) {
 	slog.Debug("Processing add-keyword request", "keywords", keywordsStr)
 
+	var added []string
 	keywords := strings.Split(keywordsStr, ",")
 	for _, keyword := range keywords {
 		keyword = strings.TrimSpace(keyword)
@@ -222,10 +380,18 @@ func processAddKeywordRequest(cfg *config.Config, keywordsStr string) {
 		cfg.ExtensionKeywords.URLKeywords = append(cfg.ExtensionKeywords.URLKeywords, keyword)
 		cfg.ExtensionKeywords.ContentKeywords = append(cfg.ExtensionKeywords.ContentKeywords, keyword)
 
+		// Remember it so a config reload doesn't drop it
+		state.AddRuntimeKeyword(keyword)
+		added = append(added, keyword)
+
 		log.Printf("KEYWORD ADDED: %s", keyword)
 	}
 
-	// TODO: Persist to config file and broadcast to SSE clients
+	if err := config.AppendToOverlay(added, nil); err != nil {
+		log.Printf("Warning: Failed to persist keywords to %s: %v", config.OverlayPath(), err)
+	}
+
+	// TODO: Broadcast to SSE clients
 }
 
 // processUninstallRequest handles the uninstallation process.
@@ -250,6 +416,9 @@ func processUninstallRequest(cfg *config.Config, reason string, conn net.Conn) {
 	// Give time for the message to be sent
 	time.Sleep(100 * time.Millisecond)
 
+	// Let the uninstall alerts go out before the daemon is gone
+	notify.WaitForEmails(30 * time.Second)
+
 	log.Println("Uninstall complete. Exiting daemon.")
 
 	// Exit daemon
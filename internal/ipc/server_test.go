@@ -1,14 +1,479 @@
 package ipc
 
 import (
+	"bufio"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"glocker/internal/cli"
+	"glocker/internal/config"
+	"glocker/internal/state"
+	"glocker/internal/utils"
 )
 
 func TestSocketPath(t *testing.T) {
-	expected := "/tmp/glocker.sock"
-	if SocketPath != expected {
-		t.Errorf("SocketPath = %s, expected %s", SocketPath, expected)
+	expected := "/run/glocker/glocker.sock"
+	if path := (config.IPCConfig{}).SocketPath(); path != expected {
+		t.Errorf("SocketPath = %s, expected %s", path, expected)
+	}
+	if path := (config.IPCConfig{Socket: "/var/run/gl.sock"}).SocketPath(); path != "/var/run/gl.sock" {
+		t.Errorf("SocketPath with ipc.socket_path = %s", path)
+	}
+}
+
+func TestPrepareSocketDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "run", "glocker")
+	if err := prepareSocketDir(dir); err != nil {
+		t.Fatalf("prepareSocketDir() error: %v", err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatalf("Socket directory not created: %v", err)
+	}
+	if !info.IsDir() || info.Mode().Perm() != 0700 || !ownedByUs(info) {
+		t.Errorf("Socket directory mode = %v, want a 0700 directory owned by uid %d", info.Mode(), os.Geteuid())
+	}
+
+	// An existing directory is accepted as it is
+	if err := prepareSocketDir(dir); err != nil {
+		t.Errorf("prepareSocketDir() on an existing directory: %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "not-a-dir")
+	if err := os.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := prepareSocketDir(file); err == nil {
+		t.Error("Expected an error when the socket directory is a file")
+	}
+}
+
+func TestAllowGroupTraverse(t *testing.T) {
+	group, err := user.LookupGroupId(strconv.Itoa(os.Getgid()))
+	if err != nil {
+		t.Skipf("Can't look up our own group: %v", err)
+	}
+	dir := filepath.Join(t.TempDir(), "run", "glocker")
+	if err := prepareSocketDir(dir); err != nil {
+		t.Fatalf("prepareSocketDir() error: %v", err)
+	}
+
+	if err := allowGroupTraverse(dir, group.Name); err != nil {
+		t.Fatalf("allowGroupTraverse() error: %v", err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0710 {
+		t.Errorf("Socket directory mode = %v, want 0710 so the group can only traverse it", info.Mode().Perm())
+	}
+
+	if err := allowGroupTraverse(dir, "no-such-group-glocker"); err != nil {
+		t.Errorf("allowGroupTraverse() on a directory the group can already traverse: %v", err)
+	}
+}
+
+func TestRemoveStaleSocket(t *testing.T) {
+	dir := t.TempDir()
+	socketPath := filepath.Join(dir, "glocker.sock")
+	if err := removeStaleSocket(socketPath); err != nil {
+		t.Errorf("removeStaleSocket() with no socket: %v", err)
+	}
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to listen on test socket: %v", err)
+	}
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+	if err := removeStaleSocket(socketPath); err != nil {
+		t.Errorf("removeStaleSocket() on a stale socket: %v", err)
+	}
+	if _, err := os.Lstat(socketPath); !os.IsNotExist(err) {
+		t.Errorf("Stale socket not removed: %v", err)
+	}
+
+	// A regular file in the socket's place is not ours to remove
+	if err := os.WriteFile(socketPath, []byte("squatter"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := removeStaleSocket(socketPath); err == nil {
+		t.Error("Expected an error for a file that isn't a socket")
+	}
+	if _, err := os.Stat(socketPath); err != nil {
+		t.Errorf("Non-socket file was removed: %v", err)
 	}
 }
 
 // Note: Full socket testing requires running server, which is tested during integration testing.
+
+// serveResponses answers each command line on a test socket with the given response.
+func serveResponses(t *testing.T, responses map[string]string) string {
+	t.Helper()
+	socketPath := filepath.Join(t.TempDir(), "glocker.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("Failed to listen on test socket: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				scanner := bufio.NewScanner(conn)
+				for scanner.Scan() {
+					conn.Write([]byte(responses[scanner.Text()]))
+				}
+			}()
+		}
+	}()
+	return socketPath
+}
+
+func TestSendCommand_ExitCodes(t *testing.T) {
+	socketPath := serveResponses(t, map[string]string{
+		"lock":             "OK: Lock request received\n",
+		"unblock:x.com:no": "ERROR: invalid reason: no\n",
+		"status":           "line one\nline two\nEND\n",
+	})
+
+	response, err := sendCommand(socketPath, "lock")
+	if err != nil || response != "OK: Lock request received" {
+		t.Errorf("sendCommand(lock) = %q, %v", response, err)
+	}
+
+	_, err = sendCommand(socketPath, "unblock:x.com:no")
+	if cli.ExitCode(err) != cli.ExitRejected || !strings.Contains(err.Error(), "invalid reason") {
+		t.Errorf("Expected a rejected error for an ERROR response, got %v (code %d)", err, cli.ExitCode(err))
+	}
+
+	lines, err := sendMultilineCommand(socketPath, "status")
+	if err != nil || strings.Join(lines, "|") != "line one|line two" {
+		t.Errorf("sendMultilineCommand(status) = %q, %v", lines, err)
+	}
+
+	_, err = sendCommand(filepath.Join(t.TempDir(), "missing.sock"), "lock")
+	if cli.ExitCode(err) != cli.ExitDaemonDown {
+		t.Errorf("Expected daemon-down exit code when the socket is missing, got %v (code %d)", err, cli.ExitCode(err))
+	}
+}
+
+func TestRequestPause_AnswersChallenge(t *testing.T) {
+	socketPath := serveResponses(t, map[string]string{
+		"pause:10":  "CHALLENGE: 60:type this\n",
+		"type this": "OK: Blocking paused until 10:10\n",
+		"pause:99":  "ERROR: cannot pause for 99 minutes, the maximum is 30 (max_pause_minutes)\n",
+	})
+
+	var gotDelay time.Duration
+	var gotChallenge string
+	response, err := requestPause(socketPath, 10, func(delay time.Duration, challenge string) (string, error) {
+		gotDelay, gotChallenge = delay, challenge
+		return "type this\n", nil
+	})
+	if err != nil || response != "OK: Blocking paused until 10:10" {
+		t.Errorf("requestPause = %q, %v", response, err)
+	}
+	if gotDelay != time.Minute || gotChallenge != "type this" {
+		t.Errorf("Expected a 1m delay and the challenge text, got %v and %q", gotDelay, gotChallenge)
+	}
+
+	_, err = requestPause(socketPath, 99, func(time.Duration, string) (string, error) {
+		t.Error("A rejected pause should not show the challenge")
+		return "", nil
+	})
+	if cli.ExitCode(err) != cli.ExitRejected {
+		t.Errorf("Expected a rejected error for a pause over the cap, got %v (code %d)", err, cli.ExitCode(err))
+	}
+}
+
+func TestLockScreenCommand(t *testing.T) {
+	session := utils.GraphicalSession{UID: 1000, GID: 100, Display: ":1", XAuthority: "/home/alice/.Xauthority", Home: "/home/alice"}
+	cmd := lockScreenCommand(session, "/tmp/passage.txt")
+
+	wantArgs := []string{config.GlocklockInstallPath, "-text", "/tmp/passage.txt"}
+	if cmd.Path != config.GlocklockInstallPath || !slices.Equal(cmd.Args, wantArgs) {
+		t.Errorf("Command = %s %q, want %q", cmd.Path, cmd.Args, wantArgs)
+	}
+	for _, want := range []string{"DISPLAY=:1", "XAUTHORITY=/home/alice/.Xauthority", "HOME=/home/alice"} {
+		if !slices.Contains(cmd.Env, want) {
+			t.Errorf("Env %q is missing %s", cmd.Env, want)
+		}
+	}
+	attr := cmd.SysProcAttr
+	if attr == nil || !attr.Setsid || attr.Credential == nil || attr.Credential.Uid != 1000 || attr.Credential.Gid != 100 {
+		t.Errorf("Expected Setsid with uid 1000 and gid 100, got %+v", attr)
+	}
+
+	// Without XAUTHORITY the variable is left out rather than set empty
+	cmd = lockScreenCommand(utils.GraphicalSession{UID: 1000, Display: ":0"}, "/tmp/passage.txt")
+	for _, env := range cmd.Env {
+		if strings.HasPrefix(env, "XAUTHORITY=") || strings.HasPrefix(env, "HOME=") {
+			t.Errorf("Unexpected %s for a session without it", env)
+		}
+	}
+}
+
+func TestMindfulPassages(t *testing.T) {
+	passages := mindfulPassages("First passage,\nstill first.\n\n\n  Second passage.  \r\n\r\nThird.\n")
+	want := []string{"First passage,\nstill first.", "Second passage.", "Third."}
+	if !slices.Equal(passages, want) {
+		t.Errorf("mindfulPassages() = %q, want %q", passages, want)
+	}
+	if len(mindfulPassages("  \n\n ")) != 0 {
+		t.Error("Expected no passages for blank text")
+	}
+}
+
+func TestHandleConnection_LockScreen(t *testing.T) {
+	origFind, origStart := findSession, startProcess
+	t.Cleanup(func() { findSession, startProcess = origFind, origStart })
+
+	findSession = func() (utils.GraphicalSession, error) {
+		return utils.GraphicalSession{UID: 1000, GID: 1000, Display: ":0"}, nil
+	}
+	var started *exec.Cmd
+	var passage string
+	startProcess = func(cmd *exec.Cmd) error {
+		started = cmd
+		data, err := os.ReadFile(cmd.Args[len(cmd.Args)-1])
+		if err != nil {
+			t.Errorf("Passage file not readable: %v", err)
+		}
+		passage = string(data)
+		return nil
+	}
+
+	cfg := &config.Config{}
+	cfg.ViolationTracking.MindfulText = "Only passage."
+	if response := roundTrip(t, cfg, "lock-screen"); response != "OK: Screen locker started" {
+		t.Errorf("lock-screen response = %q", response)
+	}
+	if started == nil || passage != "Only passage.\n" {
+		t.Errorf("Expected the locker to start with the passage, got %v and %q", started, passage)
+	}
+
+	findSession = func() (utils.GraphicalSession, error) {
+		return utils.GraphicalSession{}, errors.New("no graphical session found")
+	}
+	if response := roundTrip(t, cfg, "lock-screen"); response != "ERROR: no graphical session found" {
+		t.Errorf("lock-screen without a session = %q", response)
+	}
+
+	cfg.ViolationTracking.MindfulText = ""
+	if response := roundTrip(t, cfg, "lock-screen"); !strings.HasPrefix(response, "ERROR: no mindful_text") {
+		t.Errorf("lock-screen without mindful_text = %q", response)
+	}
+}
+
+// roundTrip sends one command to HandleConnection and returns the first response line.
+func roundTrip(t *testing.T, cfg *config.Config, command string) string {
+	t.Helper()
+	client, server := net.Pipe()
+	go HandleConnection(cfg, server)
+	defer client.Close()
+
+	if _, err := client.Write([]byte(command + "\n")); err != nil {
+		t.Fatalf("Failed to send %s: %v", command, err)
+	}
+	scanner := bufio.NewScanner(client)
+	if !scanner.Scan() {
+		t.Fatalf("No response to %s", command)
+	}
+	return scanner.Text()
+}
+
+func TestHandleObserverConnection_RejectsMutatingCommands(t *testing.T) {
+	cfg := &config.Config{}
+	for _, command := range []string{
+		"unblock:example.com:work",
+		"block:example.com",
+		"uninstall:done",
+		"revoke-unblock:example.com",
+		"panic:10",
+		"reload",
+	} {
+		action, _, _ := strings.Cut(command, ":")
+		want := "ERROR: " + action + " is not allowed on the read-only observer socket"
+		if response := observerRoundTrip(t, cfg, command); response != want {
+			t.Errorf("%s on the observer socket = %q, want %q", command, response, want)
+		}
+	}
+}
+
+func TestHandleObserverConnection_ServesStatus(t *testing.T) {
+	cfg := &config.Config{}
+	for _, command := range []string{"status", "info", "status-json"} {
+		if response := observerRoundTrip(t, cfg, command); strings.HasPrefix(response, "ERROR:") {
+			t.Errorf("%s on the observer socket = %q, want a status response", command, response)
+		}
+	}
+}
+
+// observerRoundTrip sends one command to HandleObserverConnection and returns the
+// first response line.
+func observerRoundTrip(t *testing.T, cfg *config.Config, command string) string {
+	t.Helper()
+	client, server := net.Pipe()
+	go HandleObserverConnection(cfg, server)
+	defer client.Close()
+
+	if _, err := client.Write([]byte(command + "\n")); err != nil {
+		t.Fatalf("Failed to send %s: %v", command, err)
+	}
+	scanner := bufio.NewScanner(client)
+	if !scanner.Scan() {
+		t.Fatalf("No response to %s", command)
+	}
+	return scanner.Text()
+}
+
+func TestAuthenticateCommand(t *testing.T) {
+	key := []byte("secret")
+	now := time.Date(2026, 1, 6, 12, 0, 0, 0, time.UTC)
+	nonce := now.UnixNano()
+
+	signed := signCommand(key, nonce, "unblock:example.com:work")
+	command, err := authenticateCommand(key, signed, now)
+	if err != nil || command != "unblock:example.com:work" {
+		t.Errorf("Valid signed command = %q, %v", command, err)
+	}
+
+	// Replaying the same message is rejected
+	if _, err := authenticateCommand(key, signed, now.Add(time.Second)); !errors.Is(err, ErrAuthReplayed) {
+		t.Errorf("Replayed command: expected ErrAuthReplayed, got %v", err)
+	}
+	// So is an old message that has dropped out of the nonce window
+	old := signCommand(key, now.Add(-nonceWindow-time.Second).UnixNano(), "block:example.com")
+	if _, err := authenticateCommand(key, old, now); !errors.Is(err, ErrAuthNonceExpiry) {
+		t.Errorf("Expired nonce: expected ErrAuthNonceExpiry, got %v", err)
+	}
+
+	// Changing the command, nonce or signature breaks the HMAC
+	tampered := []string{
+		strings.Replace(signCommand(key, nonce+1, "unblock:example.com:work"), "example.com", "reddit.com", 1),
+		strings.Replace(signCommand(key, nonce+2, "uninstall:done"), strconv.FormatInt(nonce+2, 10), strconv.FormatInt(nonce+3, 10), 1),
+		signCommand([]byte("wrong key"), nonce+4, "uninstall:done"),
+		authPrefix + "not-a-nonce:abcd:uninstall:done",
+	}
+	for _, line := range tampered {
+		if _, err := authenticateCommand(key, line, now); !errors.Is(err, ErrAuthInvalid) {
+			t.Errorf("Tampered %q: expected ErrAuthInvalid, got %v", line, err)
+		}
+	}
+
+	// Unsigned commands are only accepted when they don't change anything
+	if _, err := authenticateCommand(key, "uninstall:done", now); !errors.Is(err, ErrAuthRequired) {
+		t.Errorf("Unsigned uninstall: expected ErrAuthRequired, got %v", err)
+	}
+	if command, err := authenticateCommand(key, "status", now); err != nil || command != "status" {
+		t.Errorf("Unsigned status = %q, %v", command, err)
+	}
+
+	// Without a key nothing is checked
+	if command, err := authenticateCommand(nil, "uninstall:done", now); err != nil || command != "uninstall:done" {
+		t.Errorf("Unsigned command without a key = %q, %v", command, err)
+	}
+}
+
+func TestHandleConnection_RequiresSignature(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "ipc.key")
+	if err := os.WriteFile(keyFile, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{IPC: config.IPCConfig{AuthKey: keyFile}}
+
+	if response := roundTrip(t, cfg, "block:example.com"); response != "ERROR: authentication required" {
+		t.Errorf("Unsigned block = %q", response)
+	}
+	signed := signCommand([]byte("secret"), nextNonce(time.Now()), "revoke-unblock:example.com")
+	if response := roundTrip(t, cfg, signed); response != "ERROR: example.com is not temporarily unblocked" {
+		t.Errorf("Signed revoke-unblock = %q", response)
+	}
+	if response := roundTrip(t, cfg, signed); response != "ERROR: nonce already used" {
+		t.Errorf("Replayed revoke-unblock = %q", response)
+	}
+}
+
+func TestHandleConnection_UninstallCooldown(t *testing.T) {
+	state.ClearPendingUninstall()
+	t.Cleanup(state.ClearPendingUninstall)
+
+	cfg := &config.Config{Uninstall: config.UninstallConfig{CooldownMinutes: 60}}
+	if response := roundTrip(t, cfg, "uninstall:moving on"); !strings.HasPrefix(response, UninstallScheduledPrefix) {
+		t.Errorf("First uninstall = %q, want it scheduled", response)
+	}
+	if response := roundTrip(t, cfg, "uninstall:moving on"); !strings.HasPrefix(response, "ERROR: uninstall is pending") {
+		t.Errorf("Uninstall during the cooldown = %q, want it rejected", response)
+	}
+}
+
+func TestHandleConnection_Focus(t *testing.T) {
+	state.SetTempBlocks(nil)
+	t.Cleanup(func() { state.SetTempBlocks(nil) })
+
+	cfg := &config.Config{Focus: config.FocusConfig{DistractionDomains: []string{"reddit.com"}}}
+	if response := roundTrip(t, cfg, "focus:50m0s"); !strings.HasPrefix(response, "OK: Focus session started") {
+		t.Errorf("focus:50m0s = %q, want the session started", response)
+	}
+	if blocks := state.GetTempBlocks(); len(blocks) != 1 || blocks[0].Domain != "reddit.com" {
+		t.Errorf("Expected reddit.com blocked for the session, got %+v", blocks)
+	}
+	for _, command := range []string{"focus", "focus:soon", "focus:48h"} {
+		if response := roundTrip(t, cfg, command); !strings.HasPrefix(response, "ERROR:") {
+			t.Errorf("%s = %q, want an error", command, response)
+		}
+	}
+}
+
+func TestHandleConnection_Category(t *testing.T) {
+	config.SetOverlayPath(filepath.Join(t.TempDir(), "keywords.yaml"))
+	t.Cleanup(func() { config.SetOverlayPath(config.RuntimeOverlayFile) })
+
+	cfg := &config.Config{KeywordCategories: []config.KeywordCategory{{Name: "news", URLKeywords: []string{"election"}}}}
+	if response := roundTrip(t, cfg, "disable-category:news:too distracting to see"); response != "OK: Category news disabled" {
+		t.Errorf("disable-category:news:reason = %q", response)
+	}
+	if enabled := cfg.KeywordCategories[0].Enabled; enabled == nil || *enabled {
+		t.Errorf("Expected news to be disabled, got %v", enabled)
+	}
+	if response := roundTrip(t, cfg, "enable-category:news"); response != "OK: Category news enabled" {
+		t.Errorf("enable-category:news = %q", response)
+	}
+	for _, command := range []string{"enable-category", "disable-category:", "disable-category:news", "disable-category:news: ", "enable-category:sports"} {
+		if response := roundTrip(t, cfg, command); !strings.HasPrefix(response, "ERROR:") {
+			t.Errorf("%s = %q, want an error", command, response)
+		}
+	}
+}
+
+func TestSplitUnblockUntil(t *testing.T) {
+	tests := []struct {
+		payload, wantRest, wantUntil string
+	}{
+		{"example.com:work", "example.com:work", ""},
+		{"example.com:work:until=17:00", "example.com:work", "17:00"},
+		{"a.com,b.com:work:reading the docs:until=09:30", "a.com,b.com:work:reading the docs", "09:30"},
+	}
+	for _, tt := range tests {
+		rest, until := splitUnblockUntil(tt.payload)
+		if rest != tt.wantRest || until != tt.wantUntil {
+			t.Errorf("splitUnblockUntil(%q) = %q, %q; want %q, %q", tt.payload, rest, until, tt.wantRest, tt.wantUntil)
+		}
+	}
+}
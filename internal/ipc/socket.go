@@ -0,0 +1,86 @@
+package ipc
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"sync"
+	"syscall"
+
+	"glocker/internal/config"
+)
+
+// clientConfig is the config the CLI reads the socket path and auth key from,
+// loaded on first use. A config that can't be loaded gives the defaults.
+var clientConfig = sync.OnceValue(func() *config.Config {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		slog.Debug("Using default socket settings", "error", err)
+		return &config.Config{}
+	}
+	return cfg
+})
+
+// SocketPath returns the control socket the CLI connects to (ipc.socket_path).
+func SocketPath() string {
+	return clientConfig().IPC.SocketPath()
+}
+
+// prepareSocketDir makes sure dir can safely hold the control socket. A missing
+// directory is created with mode 0700. An existing one must be a directory owned
+// by the daemon's user; if others can write to it, a warning is logged, since
+// they could put their own socket in place of a stale one.
+func prepareSocketDir(dir string) error {
+	info, err := os.Lstat(dir)
+	if os.IsNotExist(err) {
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return fmt.Errorf("failed to create socket directory: %w", err)
+		}
+		// MkdirAll applies the umask, so set the mode explicitly
+		return os.Chmod(dir, 0700)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check socket directory: %w", err)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("socket directory %s is not a directory", dir)
+	}
+	if !ownedByUs(info) {
+		return fmt.Errorf("socket directory %s is not owned by uid %d", dir, os.Geteuid())
+	}
+	if info.Mode().Perm()&0022 != 0 {
+		log.Printf("Warning: socket directory %s is writable by other users", dir)
+	}
+	return nil
+}
+
+// removeStaleSocket removes a socket left behind at path by an earlier daemon.
+// Anything that isn't a socket owned by the daemon's user is left alone and
+// reported, rather than being removed or listened over.
+func removeStaleSocket(path string) error {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check existing socket: %w", err)
+	}
+	if info.Mode().Type() != os.ModeSocket {
+		return fmt.Errorf("refusing to replace %s: not a socket", path)
+	}
+	if !ownedByUs(info) {
+		return fmt.Errorf("refusing to replace %s: not owned by uid %d", path, os.Geteuid())
+	}
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove stale socket: %w", err)
+	}
+	return nil
+}
+
+// ownedByUs reports whether a file is owned by the effective user.
+func ownedByUs(info os.FileInfo) bool {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	return ok && int(stat.Uid) == os.Geteuid()
+}
@@ -0,0 +1,107 @@
+package lock
+
+import (
+	"math/rand/v2"
+	"strconv"
+	"testing"
+)
+
+func TestGenerateMathProblems_Answers(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	for _, difficulty := range []MathDifficulty{MathEasy, MathMedium, MathHard} {
+		problems := GenerateMathProblems(200, difficulty, rng)
+		if len(problems) != 200 {
+			t.Fatalf("Expected 200 problems, got %d", len(problems))
+		}
+		for _, p := range problems {
+			var want int
+			switch p.Op {
+			case '+':
+				want = p.A + p.B
+			case '-':
+				want = p.A - p.B
+			case 'x':
+				want = p.A * p.B
+			default:
+				t.Fatalf("Unexpected operation %q in %v", p.Op, p)
+			}
+			if p.Answer != want {
+				t.Errorf("%s: answer %d, want %d", p, p.Answer, want)
+			}
+			if p.Answer < 0 {
+				t.Errorf("%s: negative answer %d", p, p.Answer)
+			}
+		}
+	}
+}
+
+func TestGenerateMathProblems_DifficultyRanges(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	for difficulty, level := range mathLevels {
+		seen := map[rune]bool{}
+		for _, p := range GenerateMathProblems(500, difficulty, rng) {
+			seen[p.Op] = true
+			lo, hi := level.operandMin, level.operandMax
+			if p.Op == 'x' {
+				lo, hi = level.factorMin, level.factorMax
+			}
+			if p.A < lo || p.A > hi || p.B < lo || p.B > hi {
+				t.Errorf("Difficulty %d: %s has operands outside %d-%d", difficulty, p, lo, hi)
+			}
+		}
+		// Exactly the level's operations are used (no multiplication on easy)
+		if len(seen) != len(level.ops) {
+			t.Errorf("Difficulty %d: expected all of %q to be used, got %v", difficulty, string(level.ops), seen)
+		}
+	}
+}
+
+func TestParseMathDifficulty(t *testing.T) {
+	tests := map[string]MathDifficulty{"easy": MathEasy, "": MathMedium, "Medium": MathMedium, "HARD": MathHard}
+	for input, want := range tests {
+		if got, err := ParseMathDifficulty(input); err != nil || got != want {
+			t.Errorf("ParseMathDifficulty(%q) = %v, %v; want %v", input, got, err, want)
+		}
+	}
+	if _, err := ParseMathDifficulty("impossible"); err == nil {
+		t.Error("Expected an error for an unknown difficulty")
+	}
+}
+
+func TestMathSession_WrongAnswerRegenerates(t *testing.T) {
+	s := newMathSession(3, MathMedium, rand.New(rand.NewPCG(5, 6)))
+
+	// Correct answers advance
+	s.typed = strconv.Itoa(s.problems[0].Answer)
+	if s.submit() || s.current != 1 {
+		t.Fatalf("Expected to move to the second problem, at %d", s.current)
+	}
+
+	// A wrong answer starts over with new problems
+	before := append([]MathProblem(nil), s.problems...)
+	s.typed = strconv.Itoa(s.problems[1].Answer + 1)
+	if s.submit() {
+		t.Fatal("A wrong answer should not unlock")
+	}
+	if s.current != 0 || s.failure == "" {
+		t.Errorf("Expected to start over with a failure message, at %d with %q", s.current, s.failure)
+	}
+	same := true
+	for i := range before {
+		same = same && before[i] == s.problems[i]
+	}
+	if same {
+		t.Error("Expected new problems after a wrong answer")
+	}
+
+	// Solving every problem unlocks
+	for i := range s.problems {
+		s.typed = strconv.Itoa(s.problems[i].Answer)
+		if unlocked := s.submit(); unlocked != (i == len(s.problems)-1) {
+			t.Errorf("submit() after problem %d = %v", i+1, unlocked)
+		}
+	}
+	if s.failure != "" {
+		t.Errorf("Expected the failure message to clear, got %q", s.failure)
+	}
+}
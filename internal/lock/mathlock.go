@@ -0,0 +1,326 @@
+package lock
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+
+	"github.com/jezek/xgb/xproto"
+)
+
+// MathDifficulty sets the size of the numbers and the operations used in math problems.
+type MathDifficulty int
+
+const (
+	MathEasy   MathDifficulty = iota + 1 // Addition and subtraction with numbers up to 20
+	MathMedium                           // Addition and subtraction up to 100, multiplication up to 12 x 12
+	MathHard                             // Addition and subtraction up to 999, multiplication up to 99 x 99
+)
+
+// maxAnswerLength limits how many characters can be typed as an answer.
+const maxAnswerLength = 7
+
+// mathLevel describes the problems generated for a difficulty.
+type mathLevel struct {
+	operandMin, operandMax int    // Range for addition and subtraction operands
+	factorMin, factorMax   int    // Range for multiplication factors
+	ops                    []rune // Operations to choose from
+}
+
+var mathLevels = map[MathDifficulty]mathLevel{
+	MathEasy:   {operandMin: 1, operandMax: 20, ops: []rune{'+', '-'}},
+	MathMedium: {operandMin: 10, operandMax: 100, factorMin: 2, factorMax: 12, ops: []rune{'+', '-', 'x'}},
+	MathHard:   {operandMin: 100, operandMax: 999, factorMin: 11, factorMax: 99, ops: []rune{'+', '-', 'x'}},
+}
+
+// ParseMathDifficulty converts "easy", "medium" or "hard" (any case) to a MathDifficulty.
+// An empty string is medium.
+func ParseMathDifficulty(s string) (MathDifficulty, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "easy":
+		return MathEasy, nil
+	case "", "medium":
+		return MathMedium, nil
+	case "hard":
+		return MathHard, nil
+	}
+	return 0, fmt.Errorf("invalid math difficulty %q (use easy, medium or hard)", s)
+}
+
+// MathProblem is a single arithmetic problem. Op is '+', '-' or 'x'.
+type MathProblem struct {
+	A, B   int
+	Op     rune
+	Answer int
+}
+
+// String formats the problem without its answer, e.g. "12 + 7".
+func (p MathProblem) String() string {
+	return fmt.Sprintf("%d %c %d", p.A, p.Op, p.B)
+}
+
+// GenerateMathProblems returns n random problems of the given difficulty.
+// Subtractions never have a negative answer.
+func GenerateMathProblems(n int, difficulty MathDifficulty, rng *rand.Rand) []MathProblem {
+	level, ok := mathLevels[difficulty]
+	if !ok {
+		level = mathLevels[MathMedium]
+	}
+	between := func(lo, hi int) int { return lo + rng.IntN(hi-lo+1) }
+
+	problems := make([]MathProblem, n)
+	for i := range problems {
+		p := MathProblem{Op: level.ops[rng.IntN(len(level.ops))]}
+		switch p.Op {
+		case 'x':
+			p.A, p.B = between(level.factorMin, level.factorMax), between(level.factorMin, level.factorMax)
+			p.Answer = p.A * p.B
+		case '-':
+			p.A, p.B = between(level.operandMin, level.operandMax), between(level.operandMin, level.operandMax)
+			if p.A < p.B {
+				p.A, p.B = p.B, p.A
+			}
+			p.Answer = p.A - p.B
+		default:
+			p.A, p.B = between(level.operandMin, level.operandMax), between(level.operandMin, level.operandMax)
+			p.Answer = p.A + p.B
+		}
+		problems[i] = p
+	}
+	return problems
+}
+
+// mathSession tracks progress through a set of problems, independent of X11.
+type mathSession struct {
+	count      int
+	difficulty MathDifficulty
+	rng        *rand.Rand
+
+	problems []MathProblem
+	current  int    // Index of the problem being answered; earlier ones were answered correctly
+	typed    string // Answer being typed for the current problem
+	failure  string // Explanation of the last wrong answer, shown until the next submission
+}
+
+func newMathSession(count int, difficulty MathDifficulty, rng *rand.Rand) *mathSession {
+	return &mathSession{
+		count:      count,
+		difficulty: difficulty,
+		rng:        rng,
+		problems:   GenerateMathProblems(count, difficulty, rng),
+	}
+}
+
+// submit checks the typed answer to the current problem. A wrong answer replaces
+// every problem with new ones and starts over. Returns true once all problems
+// have been answered correctly.
+func (s *mathSession) submit() bool {
+	if s.typed == "" {
+		return false
+	}
+	problem := s.problems[s.current]
+	answer, err := strconv.Atoi(s.typed)
+	s.failure = ""
+	if err != nil || answer != problem.Answer {
+		s.failure = fmt.Sprintf("Wrong: %s = %d, not %s. New problems generated.", problem, problem.Answer, s.typed)
+		s.problems = GenerateMathProblems(s.count, s.difficulty, s.rng)
+		s.current = 0
+		s.typed = ""
+		return false
+	}
+
+	s.typed = ""
+	s.current++
+	return s.current == len(s.problems)
+}
+
+// MathLocker is a screen locker that unlocks once a set of arithmetic problems is
+// solved without a mistake. It uses the TextLocker window, input and drawing code.
+type MathLocker struct {
+	TextLocker
+
+	problems   int
+	difficulty MathDifficulty
+	session    *mathSession
+}
+
+// MathLockConfig holds configuration for the math locker.
+type MathLockConfig struct {
+	// Problems is how many problems must be solved to unlock.
+	Problems int
+	// Difficulty sets the size of the numbers (defaults to MathMedium).
+	Difficulty MathDifficulty
+	// Message is displayed above the problems.
+	Message string
+	// BackgroundImage is the path to a PNG/JPG background image.
+	BackgroundImage string
+	// BackgroundColor is the fallback background color (RGB format).
+	BackgroundColor uint32
+}
+
+// NewMathLocker creates a new math locker.
+func NewMathLocker(cfg MathLockConfig) (*MathLocker, error) {
+	if cfg.Problems <= 0 {
+		return nil, fmt.Errorf("number of problems must be positive")
+	}
+	if cfg.Difficulty == 0 {
+		cfg.Difficulty = MathMedium
+	}
+	if _, ok := mathLevels[cfg.Difficulty]; !ok {
+		return nil, fmt.Errorf("invalid math difficulty %d", cfg.Difficulty)
+	}
+	if cfg.Message == "" {
+		cfg.Message = "Solve every problem to unlock:"
+	}
+	if cfg.BackgroundColor == 0 {
+		cfg.BackgroundColor = DefaultBackgroundColor
+	}
+
+	return &MathLocker{
+		TextLocker: TextLocker{
+			message:         cfg.Message,
+			backgroundImage: cfg.BackgroundImage,
+			backgroundColor: cfg.BackgroundColor,
+			stopChan:        make(chan struct{}),
+		},
+		problems:   cfg.Problems,
+		difficulty: cfg.Difficulty,
+	}, nil
+}
+
+// Lock activates the screen lock.
+// It blocks until every problem has been answered correctly.
+func (ml *MathLocker) Lock() error {
+	ml.session = newMathSession(ml.problems, ml.difficulty, rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())))
+	return ml.lockWith(ml.runLoop)
+}
+
+func (ml *MathLocker) runLoop() error {
+	defer ml.ungrabInputs()
+	defer ml.destroyWindow()
+
+	// Initial draw
+	ml.drawScreen()
+
+	for {
+		select {
+		case <-ml.stopChan:
+			return nil
+		default:
+			ev, err := ml.conn.WaitForEvent()
+			if err != nil || ev == nil {
+				continue
+			}
+
+			switch e := ev.(type) {
+			case xproto.KeyPressEvent:
+				if ml.handleKeyPress(e) {
+					return nil // All problems solved
+				}
+				ml.drawScreen()
+			case xproto.ExposeEvent:
+				ml.drawScreen()
+			}
+		}
+	}
+}
+
+func (ml *MathLocker) handleKeyPress(e xproto.KeyPressEvent) bool {
+	keysym := ml.keycodeToKeysym(e.Detail, e.State)
+	s := ml.session
+
+	switch keysym {
+	case 0xff0d, 0xff8d: // Return, KP_Enter
+		return s.submit()
+	case 0xff08, 0xffff: // BackSpace, Delete
+		if len(s.typed) > 0 {
+			s.typed = s.typed[:len(s.typed)-1]
+		}
+		return false
+	case 0xff1b: // Escape - clear the answer
+		s.typed = ""
+		return false
+	}
+
+	// Keypad digits (KP_0 - KP_9) and minus
+	if keysym >= 0xffb0 && keysym <= 0xffb9 {
+		keysym = keysym - 0xffb0 + '0'
+	} else if keysym == 0xffad {
+		keysym = '-'
+	}
+
+	if char := ml.keysymToChar(keysym); (char >= '0' && char <= '9') || (char == '-' && s.typed == "") {
+		if len(s.typed) < maxAnswerLength {
+			s.typed += string(char)
+		}
+	}
+	return false
+}
+
+func (ml *MathLocker) drawScreen() {
+	gc, err := xproto.NewGcontextId(ml.conn)
+	if err != nil {
+		return
+	}
+	defer xproto.FreeGC(ml.conn, gc)
+
+	// Clear screen
+	xproto.ClearArea(ml.conn, false, ml.window, 0, 0, 0, 0)
+
+	// White text with large font
+	err = xproto.CreateGCChecked(
+		ml.conn,
+		gc,
+		xproto.Drawable(ml.window),
+		xproto.GcForeground|xproto.GcFont,
+		[]uint32{0xffffff, uint32(ml.font)},
+	).Check()
+	if err != nil {
+		return
+	}
+
+	screenWidth := int(ml.screen.WidthInPixels)
+	screenHeight := int(ml.screen.HeightInPixels)
+	charWidth := CharWidth
+	lineHeight := LineHeight
+	s := ml.session
+
+	// Center the block vertically: message + problems + feedback + status
+	totalLines := 2 + len(s.problems) + 1 + 2 + 2
+	y := (screenHeight - totalLines*lineHeight) / 2
+	if y < lineHeight {
+		y = lineHeight
+	}
+
+	ml.drawCenteredText(gc, ml.message, y, screenWidth, charWidth)
+	y += lineHeight * 2
+
+	// Solved problems in green, the current one in white, the rest in gray
+	for i, problem := range s.problems {
+		var line string
+		var color uint32
+		switch {
+		case i < s.current:
+			line, color = fmt.Sprintf("%s = %d", problem, problem.Answer), 0x00ff00
+		case i == s.current:
+			line, color = fmt.Sprintf("%s = %s_", problem, s.typed), 0xffffff
+		default:
+			line, color = fmt.Sprintf("%s = ?", problem), 0x888888
+		}
+		xproto.ChangeGC(ml.conn, gc, xproto.GcForeground, []uint32{color})
+		ml.drawCenteredText(gc, line, y, screenWidth, charWidth)
+		y += lineHeight
+	}
+	y += lineHeight
+
+	if s.failure != "" {
+		xproto.ChangeGC(ml.conn, gc, xproto.GcForeground, []uint32{0xff0000})
+		ml.drawCenteredText(gc, s.failure, y, screenWidth, charWidth)
+	}
+	y += lineHeight * 2
+
+	xproto.ChangeGC(ml.conn, gc, xproto.GcForeground, []uint32{0x666666})
+	status := fmt.Sprintf("Problem %d of %d. Press Enter to submit, Escape to clear.", s.current+1, len(s.problems))
+	ml.drawCenteredText(gc, status, y, screenWidth, charWidth)
+}
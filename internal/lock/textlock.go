@@ -87,6 +87,12 @@ func NewTextLockerFromFile(filePath string) (*TextLocker, error) {
 // Lock activates the screen lock.
 // It blocks until the user types the correct text and presses Enter.
 func (tl *TextLocker) Lock() error {
+	return tl.lockWith(tl.runLoop)
+}
+
+// lockWith sets up the lock window, grabs keyboard and pointer, and runs loop
+// until it returns. Lockers built on TextLocker pass their own event loop.
+func (tl *TextLocker) lockWith(loop func() error) error {
 	tl.mu.Lock()
 	if tl.running {
 		tl.mu.Unlock()
@@ -155,7 +161,7 @@ func (tl *TextLocker) Lock() error {
 	}
 
 	// Run the lock loop
-	return tl.runLoop()
+	return loop()
 }
 
 // Stop terminates the lock screen.
@@ -0,0 +1,164 @@
+package monitoring
+
+import (
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"glocker/internal/config"
+)
+
+// cgroupScope is a systemd scope holding processes whose cgroup matched a
+// forbidden program's cgroup_match.
+type cgroupScope struct {
+	Unit string // e.g. "app-flatpak-com.valvesoftware.Steam-1234.scope"
+	UID  string // UID of the user manager running the scope, empty for system scopes
+	PIDs []string
+}
+
+// parseCgroupPaths returns the cgroup paths in a /proc/<pid>/cgroup file. Each
+// line is "hierarchy-ID:controllers:path"; with the unified (v2) hierarchy there
+// is a single "0::/..." line.
+func parseCgroupPaths(data string) []string {
+	var paths []string
+	for _, line := range strings.Split(data, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), ":", 3)
+		if len(parts) != 3 || parts[2] == "" {
+			continue
+		}
+		paths = append(paths, parts[2])
+	}
+	return paths
+}
+
+// scopeFromCgroupPath returns the systemd scope unit a cgroup path belongs to and,
+// when it lives under user@UID.service, the UID of that user manager. Login
+// sessions and init.scope are never returned: stopping them would log the user
+// out or take the system down rather than close one application.
+func scopeFromCgroupPath(path string) (unit, uid string, ok bool) {
+	for _, element := range strings.Split(path, "/") {
+		if strings.HasPrefix(element, "user@") && strings.HasSuffix(element, ".service") {
+			uid = strings.TrimSuffix(strings.TrimPrefix(element, "user@"), ".service")
+		}
+		if strings.HasSuffix(element, ".scope") {
+			unit = element
+		}
+	}
+
+	if unit == "" || unit == "init.scope" || strings.HasPrefix(unit, "session-") {
+		return "", "", false
+	}
+	return unit, uid, true
+}
+
+// findCgroupScopes scans procRoot for processes whose cgroup path matches pattern
+// and groups them by scope. Processes outside a stoppable scope, PID 1 and
+// anything in a glocker cgroup are skipped.
+func findCgroupScopes(procRoot string, pattern *regexp.Regexp) ([]cgroupScope, error) {
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", procRoot, err)
+	}
+
+	scopes := make(map[string]*cgroupScope)
+	for _, entry := range entries {
+		pid := entry.Name()
+		if _, err := strconv.Atoi(pid); err != nil || !entry.IsDir() || pid == "1" {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(procRoot, pid, "cgroup"))
+		if err != nil {
+			continue // Processes can exit while we scan
+		}
+
+		for _, path := range parseCgroupPaths(string(data)) {
+			if !pattern.MatchString(path) {
+				continue
+			}
+			if strings.Contains(strings.ToLower(path), "glocker") {
+				slog.Debug("Skipping protected cgroup", "pid", pid, "cgroup", path)
+				break
+			}
+			unit, uid, ok := scopeFromCgroupPath(path)
+			if !ok {
+				slog.Debug("Matched cgroup is not a stoppable scope", "pid", pid, "cgroup", path)
+				break
+			}
+
+			scope, exists := scopes[unit]
+			if !exists {
+				scope = &cgroupScope{Unit: unit, UID: uid}
+				scopes[unit] = scope
+			}
+			scope.PIDs = append(scope.PIDs, pid)
+			break
+		}
+	}
+
+	var result []cgroupScope
+	for _, scope := range scopes {
+		result = append(result, *scope)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Unit < result[j].Unit })
+	return result, nil
+}
+
+// systemctlStopArgs returns the systemctl arguments that stop scope. Scopes under a
+// user manager have to be stopped through that manager.
+func systemctlStopArgs(scope cgroupScope) []string {
+	if scope.UID == "" {
+		return []string{"stop", scope.Unit}
+	}
+
+	name := scope.UID
+	if u, err := user.LookupId(scope.UID); err == nil {
+		name = u.Username
+	}
+	return []string{"--user", "--machine=" + name + "@", "stop", scope.Unit}
+}
+
+// killMatchingScopes stops the systemd scopes of processes whose cgroup matches
+// the program's cgroup_match, for apps (such as flatpaks) whose processes don't
+// carry the program's name.
+func killMatchingScopes(cfg *config.Config, program config.ForbiddenProgram) {
+	pattern, err := regexp.Compile(program.CgroupMatch)
+	if err != nil {
+		log.Printf("Warning: invalid cgroup_match for forbidden program %q: %v", program.Name, err)
+		return
+	}
+
+	scopes, err := findCgroupScopes("/proc", pattern)
+	if err != nil {
+		slog.Debug("Failed to scan process cgroups", "error", err)
+		return
+	}
+	stopScopes(cfg, program, scopes)
+}
+
+// stopScopes stops scopes with systemctl. The violation is only recorded, and
+// the termination reported, once at least one scope was actually stopped.
+func stopScopes(cfg *config.Config, program config.ForbiddenProgram, scopes []cgroupScope) {
+	stoppedScopes := []string{}
+	for _, scope := range scopes {
+		if err := runCommand("systemctl", systemctlStopArgs(scope)...); err != nil {
+			log.Printf("Failed to stop forbidden scope %s: %v", scope.Unit, err)
+			continue
+		}
+		stoppedScopes = append(stoppedScopes, fmt.Sprintf("%s (PIDs: %s)", scope.Unit, strings.Join(scope.PIDs, ", ")))
+		log.Printf("STOPPED FORBIDDEN SCOPE: %s (PIDs: %s) - matched cgroup: %s", scope.Unit, strings.Join(scope.PIDs, ", "), program.CgroupMatch)
+	}
+	if len(stoppedScopes) == 0 {
+		return
+	}
+
+	RecordViolation(cfg, "forbidden_program", program.Name, fmt.Sprintf("Stopped %d scope(s)", len(stoppedScopes)))
+	reportTerminatedPrograms(cfg, program.Name, stoppedScopes)
+}
@@ -2,18 +2,22 @@ package monitoring
 
 import (
 	"fmt"
-	"log"
 	"strings"
 	"time"
 
 	"glocker/internal/config"
 	"glocker/internal/notify"
 	"glocker/internal/reports"
-	"glocker/internal/state"
+	"glocker/internal/utils"
 )
 
+// dailyReportRetry is how long to wait before retrying a daily report that failed to send.
+const dailyReportRetry = time.Hour
+
 // MonitorDailyReport runs a background goroutine that sends daily reports at the configured time.
 // Reports contain the previous day's data (violations, unblocks, unmanaged periods).
+// If the machine was asleep or the daemon down at that time, the report is sent at
+// the first check after it, once per day.
 func MonitorDailyReport(cfg *config.Config) {
 	if !cfg.Accountability.DailyReportEnabled {
 		return
@@ -24,31 +28,43 @@ func MonitorDailyReport(cfg *config.Config) {
 		reportTime = "08:00" // Default to 8am
 	}
 
-	ticker := time.NewTicker(1 * time.Minute)
-	defer ticker.Stop()
+	newDailyReporter(clock, reportTime, config.DailyReportStateFile, func(date time.Time) error {
+		return sendDailyReport(cfg, date)
+	}).run()
+}
 
-	for range ticker.C {
-		now := time.Now()
-		currentTime := now.Format("15:04")
+// newDailyReporter returns a reporter that sends the previous day's report with
+// send once the day's reportTime (HH:MM) has passed.
+func newDailyReporter(clock utils.TimeProvider, reportTime, stateFile string, send func(date time.Time) error) *periodReporter {
+	return &periodReporter{
+		name:      "daily",
+		clock:     clock,
+		retry:     dailyReportRetry,
+		stateFile: stateFile,
+		due: func(now, lastSent time.Time) (time.Time, bool) {
+			return dailyReportDue(now, reportTime, lastSent)
+		},
+		send: func(scheduled time.Time) error {
+			return send(scheduled.AddDate(0, 0, -1))
+		},
+	}
+}
 
-		// Check if it's time to send the report
-		if currentTime == reportTime {
-			// Check if we've already sent today's report
-			lastSent, exists := state.GetLastEmailTime("daily_report")
-			if exists && lastSent.Day() == now.Day() && lastSent.Month() == now.Month() && lastSent.Year() == now.Year() {
-				continue // Already sent today
-			}
+// dailyReportDue returns today's scheduled report time and whether the report should
+// be sent now: the time has passed and the last report sent was scheduled before it.
+// The schedule is built from the local calendar date, so DST changes don't skip or
+// repeat a report.
+func dailyReportDue(now time.Time, reportTime string, lastSent time.Time) (time.Time, bool) {
+	hour, minute := 8, 0
+	if t, err := time.Parse("15:04", reportTime); err == nil {
+		hour, minute = t.Hour(), t.Minute()
+	}
 
-			// Send report for yesterday
-			yesterday := now.AddDate(0, 0, -1)
-			if err := sendDailyReport(cfg, yesterday); err != nil {
-				log.Printf("Failed to send daily report: %v", err)
-			} else {
-				state.SetLastEmailTime("daily_report", now)
-				log.Printf("Daily report sent for %s", yesterday.Format("2006-01-02"))
-			}
-		}
+	scheduled := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+	if now.Before(scheduled) {
+		return scheduled, false
 	}
+	return scheduled, lastSent.Before(scheduled)
 }
 
 // sendDailyReport generates and sends the daily report email for a specific date.
@@ -57,14 +73,14 @@ func sendDailyReport(cfg *config.Config, date time.Time) error {
 	dayEnd := dayStart.Add(24*time.Hour - time.Second)
 
 	// Gather violations
-	violations, _ := reports.ParseReportsLog("")
+	violations, _, _ := reports.LoadReports()
 	violations = reports.FilterReports(violations, reports.ReportFilter{
 		StartTime: &dayStart,
 		EndTime:   &dayEnd,
 	})
 
 	// Gather unblocks
-	unblocks, _ := reports.ParseUnblocksLog("")
+	unblocks, _ := reports.LoadUnblocks()
 	unblocks = reports.FilterUnblocks(unblocks, reports.UnblockFilter{
 		StartTime: &dayStart,
 		EndTime:   &dayEnd,
@@ -151,7 +167,7 @@ func sendDailyReport(cfg *config.Config, date time.Time) error {
 		subject = fmt.Sprintf("Glocker Daily Report [ATTENTION]: %s", date.Format("Jan 2"))
 	}
 
-	return notify.SendEmail(cfg, subject, body.String())
+	return notify.SendEmail(cfg, notify.EventDailyReport, subject, body.String())
 }
 
 // calculateUnmanagedMinutes calculates the total unmanaged minutes for a specific day.
@@ -201,7 +217,7 @@ func calculateUnmanagedMinutes(date time.Time) int {
 	// Handle ongoing unmanaged period
 	if currentUninstall != nil {
 		start := *currentUninstall
-		end := time.Now()
+		end := clock.Now()
 		if end.After(dayEnd) {
 			end = dayEnd
 		}
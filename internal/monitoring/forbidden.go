@@ -5,6 +5,8 @@ import (
 	"log"
 	"log/slog"
 	"os/exec"
+	"path/filepath"
+	"regexp"
 	"slices"
 	"strings"
 	"time"
@@ -17,19 +19,24 @@ import (
 
 // MonitorForbiddenPrograms continuously monitors and kills forbidden programs based on time windows.
 func MonitorForbiddenPrograms(cfg *config.Config) {
-	// Set default check interval if not specified
-	checkInterval := cfg.ForbiddenPrograms.CheckInterval
-	if checkInterval == 0 {
-		checkInterval = 5 // Default: check every 5 seconds
-	}
-
-	slog.Debug("Starting forbidden programs monitoring", "check_interval_seconds", checkInterval, "programs_count", len(cfg.ForbiddenPrograms.Programs))
+	interval := forbiddenCheckInterval(cfg)
+	slog.Debug("Starting forbidden programs monitoring", "check_interval", interval, "programs_count", len(cfg.ForbiddenPrograms.Programs))
 
-	ticker := time.NewTicker(time.Duration(checkInterval) * time.Second)
+	// A reload can change check_interval_seconds
+	ticker := utils.NewTicker(interval)
 	defer ticker.Stop()
+	reloads := make(chan struct{}, 1)
+	state.AddReloadListener(reloads)
+
+	for {
+		select {
+		case <-reloads:
+			interval = utils.ResetTicker(ticker, interval, forbiddenCheckInterval(cfg))
+			continue
+		case <-ticker.Chan():
+		}
 
-	for range ticker.C {
-		now := time.Now()
+		now := clock.Now()
 		currentDay := now.Weekday().String()[:3]
 		currentTime := now.Format("15:04")
 
@@ -46,19 +53,7 @@ func MonitorForbiddenPrograms(cfg *config.Config) {
 			} else {
 				// Check time windows
 				for _, window := range program.TimeWindows {
-					dayToCheck := currentDay
-					if window.Start > window.End && currentTime <= window.End {
-						// We're in the early morning portion of a window that started yesterday
-						yesterday := now.AddDate(0, 0, -1).Weekday().String()[:3]
-						dayToCheck = yesterday
-						slog.Debug("Checking previous day for wraparound window", "current_day", currentDay, "checking_day", dayToCheck)
-					}
-
-					if !slices.Contains(window.Days, dayToCheck) {
-						continue
-					}
-
-					if utils.IsInTimeWindow(currentTime, window.Start, window.End) {
+					if window.Active(now) {
 						programForbidden = true
 						slog.Debug("Program is forbidden in current time window", "program", program.Name, "window", fmt.Sprintf("%s-%s", window.Start, window.End))
 						break
@@ -67,66 +62,119 @@ func MonitorForbiddenPrograms(cfg *config.Config) {
 			}
 
 			if programForbidden {
-				killMatchingProcesses(cfg, program.Name)
+				if program.CgroupMatch != "" {
+					killMatchingScopes(cfg, program)
+				} else {
+					killMatchingProcesses(cfg, program)
+				}
 			}
 		}
 	}
 }
 
-// killMatchingProcesses finds and kills processes matching the given program name.
-func killMatchingProcesses(cfg *config.Config, programName string) {
-	// Get list of running processes
-	cmd := exec.Command("ps", "aux")
-	output, err := cmd.Output()
+// processMatcher returns a function reporting whether a ps aux line belongs to
+// the program, according to its match mode.
+func processMatcher(program config.ForbiddenProgram) (func(psLine string) bool, error) {
+	name := strings.ToLower(program.Name)
+
+	switch program.MatchMode() {
+	case config.ProgramMatchExact:
+		return func(psLine string) bool {
+			return strings.ToLower(filepath.Base(extractProcessName(psLine))) == name
+		}, nil
+	case config.ProgramMatchRegex:
+		re, err := regexp.Compile(program.Name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex for forbidden program %q: %w", program.Name, err)
+		}
+		return func(psLine string) bool {
+			return re.MatchString(extractCommandLine(psLine))
+		}, nil
+	default:
+		// Substring of the whole line, as glocker has always matched
+		return func(psLine string) bool {
+			return strings.Contains(strings.ToLower(psLine), name)
+		}, nil
+	}
+}
+
+// selectForbiddenProcesses returns the processes in ps aux output that match the
+// program, grouped by PID. Processes matching one of the program's exclusions are
+// left out, as are glocker itself, systemd, kernel processes and PID 1.
+func selectForbiddenProcesses(psOutput string, program config.ForbiddenProgram) (map[string][]state.ProcessInfo, error) {
+	matches, err := processMatcher(program)
 	if err != nil {
-		slog.Debug("Failed to get process list", "error", err)
-		return
+		return nil, err
 	}
 
-	lines := strings.Split(string(output), "\n")
-	killedProcesses := []string{}
+	lines := strings.Split(psOutput, "\n")
 	processGroups := make(map[string][]state.ProcessInfo)
 
-	slog.Debug("Starting process matching", "program_filter", programName, "total_lines", len(lines))
+	slog.Debug("Starting process matching", "program_filter", program.Name, "match", program.MatchMode(), "total_lines", len(lines))
 
-	// Collect all matching processes
 	for _, line := range lines {
+		fields := strings.Fields(line)
 		// Skip header line and empty lines
-		if strings.Contains(line, "USER") || strings.TrimSpace(line) == "" {
+		if len(fields) < 2 || fields[0] == "USER" {
 			continue
 		}
 
-		// Check if the process name contains our forbidden program name (case-insensitive)
-		if strings.Contains(strings.ToLower(line), strings.ToLower(programName)) {
-			// Extract PID (second column in ps aux output)
-			fields := strings.Fields(line)
-			if len(fields) < 2 {
-				continue
-			}
+		if !matches(line) {
+			continue
+		}
 
-			pid := fields[1]
-			processName := extractProcessName(line)
+		pid := fields[1]
+		processName := extractProcessName(line)
+		commandLine := strings.ToLower(extractCommandLine(line))
 
-			slog.Debug("Extracted process info", "pid", pid, "extracted_name", processName)
+		slog.Debug("Extracted process info", "pid", pid, "extracted_name", processName)
 
-			// Don't kill our own process or system processes
-			if strings.Contains(strings.ToLower(processName), "glocker") ||
-				strings.Contains(strings.ToLower(processName), "systemd") ||
-				strings.Contains(strings.ToLower(processName), "kernel") ||
-				pid == "1" {
-				slog.Debug("Skipping protected process", "pid", pid, "name", processName)
-				continue
-			}
+		// Don't kill our own process or system processes
+		if strings.Contains(strings.ToLower(processName), "glocker") ||
+			strings.Contains(strings.ToLower(processName), "systemd") ||
+			strings.Contains(strings.ToLower(processName), "kernel") ||
+			pid == "1" {
+			slog.Debug("Skipping protected process", "pid", pid, "name", processName)
+			continue
+		}
 
-			processInfo := state.ProcessInfo{
-				PID:         pid,
-				Name:        processName,
-				CommandLine: line,
-			}
+		if slices.ContainsFunc(program.Exclude, func(exclude string) bool {
+			return exclude != "" && strings.Contains(commandLine, strings.ToLower(exclude))
+		}) {
+			slog.Debug("Skipping excluded process", "pid", pid, "name", processName)
+			continue
+		}
 
-			processGroups[pid] = append(processGroups[pid], processInfo)
+		processInfo := state.ProcessInfo{
+			PID:         pid,
+			Name:        processName,
+			CommandLine: line,
 		}
+
+		processGroups[pid] = append(processGroups[pid], processInfo)
+	}
+
+	return processGroups, nil
+}
+
+// killMatchingProcesses finds and kills processes matching the given program.
+func killMatchingProcesses(cfg *config.Config, program config.ForbiddenProgram) {
+	programName := program.Name
+
+	// Get list of running processes
+	cmd := exec.Command("ps", "aux")
+	output, err := cmd.Output()
+	if err != nil {
+		slog.Debug("Failed to get process list", "error", err)
+		return
+	}
+
+	processGroups, err := selectForbiddenProcesses(string(output), program)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return
 	}
+	killedProcesses := []string{}
 
 	// Kill matching processes
 	violationRecorded := false // Track if we've recorded a violation for this program
@@ -139,7 +187,7 @@ func killMatchingProcesses(cfg *config.Config, programName string) {
 		slog.Debug("Found forbidden process", "pid", proc.PID, "name", proc.Name)
 
 		// Record violation only once per program name (not per subprocess)
-		if !violationRecorded && cfg.ViolationTracking.Enabled {
+		if !violationRecorded {
 			RecordViolation(cfg, "forbidden_program", programName, fmt.Sprintf("Killed %d process(es)", len(processGroups)))
 			violationRecorded = true
 		}
@@ -155,6 +203,12 @@ func killMatchingProcesses(cfg *config.Config, programName string) {
 		}
 	}
 
+	reportTerminatedPrograms(cfg, programName, killedProcesses)
+}
+
+// reportTerminatedPrograms sends the desktop notification and accountability email
+// for processes (or scopes) terminated for a forbidden program.
+func reportTerminatedPrograms(cfg *config.Config, programName string, killedProcesses []string) {
 	// Send desktop notification once for all killed processes
 	if len(killedProcesses) > 0 {
 		message := fmt.Sprintf("Terminated forbidden program: %s (%d process(es))", programName, len(killedProcesses))
@@ -162,17 +216,26 @@ func killMatchingProcesses(cfg *config.Config, programName string) {
 	}
 
 	// Send accountability email if processes were killed
-	if len(killedProcesses) > 0 && cfg.Accountability.Enabled {
+	if len(killedProcesses) > 0 && cfg.NotifiesPartner() {
 		subject := "GLOCKER ALERT: Forbidden Programs Terminated"
-		body := fmt.Sprintf("Forbidden programs were detected and terminated at %s:\n\n", time.Now().Format("2006-01-02 15:04:05"))
+		body := fmt.Sprintf("Forbidden programs were detected and terminated at %s:\n\n", clock.Now().Format("2006-01-02 15:04:05"))
 		body += fmt.Sprintf("Filter: %s\n", programName)
 		body += "Terminated processes:\n"
 		for _, proc := range killedProcesses {
 			body += fmt.Sprintf("  - %s\n", proc)
 		}
 
-		notify.SendEmail(cfg, subject, body)
+		notify.SendEmail(cfg, notify.EventForbiddenPrograms, subject, body)
+	}
+}
+
+// forbiddenCheckInterval returns how often MonitorForbiddenPrograms checks, 5
+// seconds unless forbidden_programs.check_interval_seconds is set.
+func forbiddenCheckInterval(cfg *config.Config) time.Duration {
+	if cfg.ForbiddenPrograms.CheckInterval <= 0 {
+		return 5 * time.Second
 	}
+	return time.Duration(cfg.ForbiddenPrograms.CheckInterval) * time.Second
 }
 
 // extractProcessName extracts the process name from a ps aux output line.
@@ -184,3 +247,12 @@ func extractProcessName(psLine string) string {
 	}
 	return "unknown"
 }
+
+// extractCommandLine extracts the command and its arguments from a ps aux output line.
+func extractCommandLine(psLine string) string {
+	fields := strings.Fields(psLine)
+	if len(fields) >= 11 {
+		return strings.Join(fields[10:], " ")
+	}
+	return ""
+}
@@ -1,13 +1,22 @@
 package monitoring
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"glocker/internal/audit"
 	"glocker/internal/config"
+	"glocker/internal/enforcement"
+	"glocker/internal/reports"
 	"glocker/internal/state"
+	"glocker/internal/utils"
 )
 
 func TestCaptureChecksum(t *testing.T) {
@@ -139,7 +148,7 @@ func TestCountRecentViolations(t *testing.T) {
 		Type:      "web_access",
 	})
 
-	count := countRecentViolations(cfg, now)
+	count := CountRecentViolations(cfg, now)
 
 	if count != 2 {
 		t.Errorf("Expected 2 recent violations, got %d", count)
@@ -149,6 +158,53 @@ func TestCountRecentViolations(t *testing.T) {
 	state.ClearViolations()
 }
 
+func TestCountRecentViolations_Weighted(t *testing.T) {
+	state.ClearViolations()
+	t.Cleanup(state.ClearViolations)
+
+	var calls []string
+	stubViolationActions(t, &calls)
+	fake := utils.NewFakeTimeProvider(time.Date(2026, 1, 6, 10, 0, 0, 0, time.Local))
+	SetClock(fake)
+	t.Cleanup(func() { SetClock(utils.DefaultTimeProvider{}) })
+
+	cfg := &config.Config{
+		ViolationTracking: config.ViolationTrackingConfig{
+			Enabled:           true,
+			MaxViolations:     5,
+			TimeWindowMinutes: 60,
+			Command:           "echo threshold",
+			Weights:           map[string]int{"forbidden_program": 3, "content_report": 0},
+		},
+	}
+
+	// A weight of 0 ignores the type; types without a weight count once
+	state.AddViolation(state.Violation{Timestamp: fake.Now(), Host: "a.com", Type: "content_report"})
+	state.AddViolation(state.Violation{Timestamp: fake.Now(), Host: "b.com", Type: "web_access"})
+	checkViolationThreshold(cfg)
+	if count := CountRecentViolations(cfg, fake.Now()); count != 1 {
+		t.Errorf("Expected weighted count 1, got %d", count)
+	}
+	if len(calls) != 0 {
+		t.Fatalf("Expected no actions below the threshold, got %v", calls)
+	}
+
+	// 1 + 3 is still below 5, the next forbidden program crosses it
+	state.AddViolation(state.Violation{Timestamp: fake.Now(), Host: "steam", Type: "forbidden_program"})
+	checkViolationThreshold(cfg)
+	if len(calls) != 0 {
+		t.Fatalf("Expected no actions at weighted count 4, got %v", calls)
+	}
+	state.AddViolation(state.Violation{Timestamp: fake.Now(), Host: "steam", Type: "forbidden_program"})
+	checkViolationThreshold(cfg)
+	if count := CountRecentViolations(cfg, fake.Now()); count != 7 {
+		t.Errorf("Expected weighted count 7, got %d", count)
+	}
+	if want := []string{"command echo threshold"}; !slices.Equal(calls, want) {
+		t.Errorf("Expected %v once the weighted count crossed the threshold, got %v", want, calls)
+	}
+}
+
 func TestRecordViolation_Disabled(t *testing.T) {
 	// Clear violations
 	state.ClearViolations()
@@ -159,6 +215,9 @@ func TestRecordViolation_Disabled(t *testing.T) {
 		},
 	}
 
+	auditPath := filepath.Join(t.TempDir(), "audit.jsonl")
+	audit.Enable(auditPath, 1<<20, 1)
+
 	// Record a violation
 	RecordViolation(cfg, "web_access", "example.com", "https://example.com")
 
@@ -167,6 +226,151 @@ func TestRecordViolation_Disabled(t *testing.T) {
 	if len(violations) != 0 {
 		t.Errorf("Expected 0 violations when disabled, got %d", len(violations))
 	}
+
+	// It is still audited
+	data, err := os.ReadFile(auditPath)
+	if err != nil {
+		t.Fatalf("Expected the violation to be audited: %v", err)
+	}
+	if !strings.Contains(string(data), `"type":"violation","domain":"example.com"`) {
+		t.Errorf("Expected a violation event in the audit log, got %q", data)
+	}
+}
+
+func TestEscalationProfile(t *testing.T) {
+	state.SetActiveProfile("", time.Time{})
+	defer state.SetActiveProfile("", time.Time{})
+
+	cfg := &config.Config{
+		ViolationTracking: config.ViolationTrackingConfig{
+			Enabled:           true,
+			MaxViolations:     2,
+			TimeWindowMinutes: 60,
+			EscalationProfile: "strict",
+		},
+		Profiles: map[string]config.Profile{
+			"strict": {TempUnblockTime: 5},
+		},
+	}
+
+	state.ClearViolations()
+	state.AddViolation(state.Violation{Timestamp: time.Now(), Host: "a.com"})
+	checkViolationThreshold(cfg)
+	if active, _ := state.GetActiveProfile(); active != "" {
+		t.Fatalf("Profile should not escalate below threshold, got %q", active)
+	}
+
+	state.AddViolation(state.Violation{Timestamp: time.Now(), Host: "b.com"})
+	checkViolationThreshold(cfg)
+	if active, _ := state.GetActiveProfile(); active != "strict" {
+		t.Fatalf("Expected strict profile after threshold, got %q", active)
+	}
+
+	revertEscalation(cfg)
+	if active, _ := state.GetActiveProfile(); active != "" {
+		t.Errorf("Expected escalation to be reverted, got %q", active)
+	}
+	state.ClearViolations()
+}
+
+func TestCheckViolationWarning_RisingEdge(t *testing.T) {
+	violationWarning.reached = false
+	defer func() { violationWarning.reached = false }()
+	state.ClearViolations()
+	defer state.ClearViolations()
+
+	cfg := &config.Config{
+		ViolationTracking: config.ViolationTrackingConfig{
+			Enabled:           true,
+			MaxViolations:     5,
+			WarnRatio:         0.6,
+			TimeWindowMinutes: 60,
+		},
+	}
+
+	now := time.Now()
+	record := func(at time.Time) bool {
+		state.AddViolation(state.Violation{Timestamp: at, Host: "example.com"})
+		return checkViolationWarning(cfg, CountRecentViolations(cfg, now))
+	}
+
+	// Warned once at 3/5, not again at 4/5 or at the threshold
+	for i, want := range []bool{false, false, true, false, false, false} {
+		if got := record(now); got != want {
+			t.Errorf("Violation %d: warning sent = %v, want %v", i+1, got, want)
+		}
+	}
+
+	// After the daily reset the count starts over, and the warning can fire again
+	state.ClearViolations()
+	for i, want := range []bool{false, false, true} {
+		if got := record(now); got != want {
+			t.Errorf("After reset, violation %d: warning sent = %v, want %v", i+1, got, want)
+		}
+	}
+
+	// Violations older than the time window don't count
+	state.ClearViolations()
+	violationWarning.reached = false
+	old := now.Add(-2 * time.Hour)
+	for i := 0; i < 4; i++ {
+		state.AddViolation(state.Violation{Timestamp: old, Host: "example.com"})
+	}
+	if record(now) {
+		t.Error("Expected no warning when most violations are outside the time window")
+	}
+
+	cfg.ViolationTracking.WarnRatio = 0
+	state.ClearViolations()
+	violationWarning.reached = false
+	for i := 0; i < 4; i++ {
+		if record(now) {
+			t.Fatal("Expected no warning with warn_ratio unset")
+		}
+	}
+}
+
+func TestParseVmRSS(t *testing.T) {
+	status := "Name:\tglocker\nVmPeak:\t  300000 kB\nVmRSS:\t   51200 kB\nThreads:\t12\n"
+	rss, err := parseVmRSS(strings.NewReader(status))
+	if err != nil {
+		t.Fatalf("parseVmRSS failed: %v", err)
+	}
+	if rss != 51200*1024 {
+		t.Errorf("Expected %d bytes, got %d", 51200*1024, rss)
+	}
+
+	if _, err := parseVmRSS(strings.NewReader("Name:\tglocker\n")); err == nil {
+		t.Error("Expected error when VmRSS is missing")
+	}
+}
+
+func TestResourceGrowthWarning(t *testing.T) {
+	var samples []state.ResourceSample
+	for i := 0; i < resourceGrowthWindow; i++ {
+		samples = append(samples, state.ResourceSample{
+			RSSBytes:   uint64(100+i) * 1024 * 1024,
+			Goroutines: 20,
+		})
+	}
+
+	warning := ResourceGrowthWarning(samples)
+	if !strings.Contains(warning, "RSS grew from 100.0 MB to 105.0 MB") {
+		t.Errorf("Expected RSS growth warning, got %q", warning)
+	}
+	if strings.Contains(warning, "goroutines") {
+		t.Errorf("Did not expect goroutine warning for a flat count, got %q", warning)
+	}
+
+	// A single dip breaks the streak
+	samples[3].RSSBytes = samples[2].RSSBytes
+	if warning := ResourceGrowthWarning(samples); warning != "" {
+		t.Errorf("Expected no warning when growth isn't monotonic, got %q", warning)
+	}
+
+	if warning := ResourceGrowthWarning(samples[:2]); warning != "" {
+		t.Errorf("Expected no warning with too few samples, got %q", warning)
+	}
 }
 
 func TestExtractProcessName(t *testing.T) {
@@ -190,3 +394,851 @@ func TestExtractProcessName_Short(t *testing.T) {
 		t.Errorf("Expected 'unknown' for short line, got %s", name)
 	}
 }
+
+// samplePsOutput is synthetic ps aux output for the forbidden program tests.
+const samplePsOutput = `USER         PID %CPU %MEM    VSZ   RSS TTY      STAT START   TIME COMMAND
+root           1  0.0  0.1 168000 12000 ?        Ss   09:00   0:01 /sbin/init steam
+root         250  0.0  0.2 120000 20000 ?        Ss   09:00   0:00 /usr/bin/glocker -daemon
+alice       1001  5.0  4.0 900000 400000 ?       Sl   10:00   1:00 /usr/bin/steam -silent
+alice       1002  0.1  0.5 200000 50000 ?        S    10:00   0:01 /usr/lib/steam/steamwebhelper --type=renderer
+alice       1003  0.0  0.1  20000  5000 pts/0    S+   10:05   0:00 vim notes/steam-sale.txt
+alice       1004  1.0  2.0 500000 200000 ?       Sl   10:10   0:05 /usr/bin/STEAM
+alice       1005  0.0  0.1  20000  5000 pts/1    S+   10:06   0:00 less /home/alice/steam.log
+`
+
+func TestSelectForbiddenProcesses(t *testing.T) {
+	tests := []struct {
+		name    string
+		program config.ForbiddenProgram
+		want    []string
+	}{
+		{"default is substring", config.ForbiddenProgram{Name: "steam"}, []string{"1001", "1002", "1003", "1004", "1005"}},
+		{"substring", config.ForbiddenProgram{Name: "Steam", Match: "substring"}, []string{"1001", "1002", "1003", "1004", "1005"}},
+		{"exact", config.ForbiddenProgram{Name: "steam", Match: "exact"}, []string{"1001", "1004"}},
+		{"regex", config.ForbiddenProgram{Name: `^/usr/(bin|lib/steam)/steam`, Match: "regex"}, []string{"1001", "1002"}},
+		{"substring with exclude", config.ForbiddenProgram{Name: "steam", Exclude: []string{"vim", "LESS "}}, []string{"1001", "1002", "1004"}},
+		{"exact with exclude", config.ForbiddenProgram{Name: "steam", Match: "exact", Exclude: []string{"-silent"}}, []string{"1004"}},
+		{"glocker is never selected", config.ForbiddenProgram{Name: "glocker"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups, err := selectForbiddenProcesses(samplePsOutput, tt.program)
+			if err != nil {
+				t.Fatalf("selectForbiddenProcesses failed: %v", err)
+			}
+
+			var pids []string
+			for pid := range groups {
+				pids = append(pids, pid)
+			}
+			slices.Sort(pids)
+
+			if !slices.Equal(pids, tt.want) {
+				t.Errorf("selected PIDs = %v, want %v", pids, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectForbiddenProcesses_InvalidRegex(t *testing.T) {
+	program := config.ForbiddenProgram{Name: "steam(", Match: "regex"}
+
+	if _, err := selectForbiddenProcesses(samplePsOutput, program); err == nil {
+		t.Error("Expected an error for an invalid regex")
+	}
+}
+
+func TestParseCgroupPaths(t *testing.T) {
+	// cgroup v2: one unified line
+	v2 := "0::/user.slice/user-1000.slice/user@1000.service/app.slice/app-flatpak-com.valvesoftware.Steam-4242.scope\n"
+	if got := parseCgroupPaths(v2); !slices.Equal(got, []string{"/user.slice/user-1000.slice/user@1000.service/app.slice/app-flatpak-com.valvesoftware.Steam-4242.scope"}) {
+		t.Errorf("parseCgroupPaths(v2) = %v", got)
+	}
+
+	// cgroup v1 / hybrid: one line per controller, some with an empty path
+	v1 := "12:cpu,cpuacct:/user.slice\n1:name=systemd:/user.slice/user-1000.slice/session-2.scope\n0::\n"
+	if got := parseCgroupPaths(v1); !slices.Equal(got, []string{"/user.slice", "/user.slice/user-1000.slice/session-2.scope"}) {
+		t.Errorf("parseCgroupPaths(v1) = %v", got)
+	}
+}
+
+func TestStopScopes_RecordsViolationOnlyWhenStopped(t *testing.T) {
+	var calls []string
+	stubViolationActions(t, &calls)
+	state.ClearViolations()
+	defer state.ClearViolations()
+
+	cfg := &config.Config{ViolationTracking: config.ViolationTrackingConfig{Enabled: true, MaxViolations: 100, TimeWindowMinutes: 60}}
+	program := config.ForbiddenProgram{Name: "steam", CgroupMatch: "steam"}
+	scope := cgroupScope{Unit: "app-flatpak-com.valvesoftware.Steam-4242.scope", PIDs: []string{"1001"}}
+
+	// Every systemctl stop fails: nothing was stopped, so no violation
+	runCommand = func(name string, args ...string) error { return errors.New("exit status 5") }
+	stopScopes(cfg, program, []cgroupScope{scope})
+	if violations := state.GetViolations(); len(violations) != 0 {
+		t.Fatalf("Expected no violation when no scope was stopped, got %+v", violations)
+	}
+
+	runCommand = func(name string, args ...string) error { return nil }
+	stopScopes(cfg, program, []cgroupScope{scope})
+	violations := state.GetViolations()
+	if len(violations) != 1 || violations[0].Host != "steam" || violations[0].URL != "Stopped 1 scope(s)" {
+		t.Errorf("Expected one violation for the stopped scope, got %+v", violations)
+	}
+}
+
+func TestScopeFromCgroupPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		wantUnit string
+		wantUID  string
+		wantOK   bool
+	}{
+		{"/user.slice/user-1000.slice/user@1000.service/app.slice/app-flatpak-com.discordapp.Discord-99.scope", "app-flatpak-com.discordapp.Discord-99.scope", "1000", true},
+		{"/system.slice/docker-0123abcd.scope", "docker-0123abcd.scope", "", true},
+		{"/user.slice/user-1000.slice/session-2.scope", "", "", false},
+		{"/init.scope", "", "", false},
+		{"/user.slice/user-1000.slice/user@1000.service/app.slice/app-gnome-firefox.service", "", "", false},
+	}
+
+	for _, tt := range tests {
+		unit, uid, ok := scopeFromCgroupPath(tt.path)
+		if unit != tt.wantUnit || uid != tt.wantUID || ok != tt.wantOK {
+			t.Errorf("scopeFromCgroupPath(%q) = %q, %q, %v; want %q, %q, %v", tt.path, unit, uid, ok, tt.wantUnit, tt.wantUID, tt.wantOK)
+		}
+	}
+}
+
+func TestFindCgroupScopes(t *testing.T) {
+	procRoot := t.TempDir()
+	writeCgroup := func(pid, path string) {
+		dir := filepath.Join(procRoot, pid)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "cgroup"), []byte("0::"+path+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	userApps := "/user.slice/user-1000.slice/user@1000.service/app.slice/"
+	writeCgroup("1", "/init.scope")
+	writeCgroup("200", "/system.slice/glocker.service")
+	writeCgroup("1001", userApps+"app-flatpak-com.valvesoftware.Steam-4242.scope")
+	writeCgroup("1002", userApps+"app-flatpak-com.valvesoftware.Steam-4242.scope")
+	writeCgroup("1003", userApps+"app-flatpak-org.mozilla.firefox-77.scope")
+	writeCgroup("1004", "/user.slice/user-1000.slice/session-2.scope")
+
+	scopes, err := findCgroupScopes(procRoot, regexp.MustCompile(`(?i)steam`))
+	if err != nil {
+		t.Fatalf("findCgroupScopes failed: %v", err)
+	}
+	if len(scopes) != 1 {
+		t.Fatalf("Expected 1 scope, got %+v", scopes)
+	}
+	want := cgroupScope{Unit: "app-flatpak-com.valvesoftware.Steam-4242.scope", UID: "1000"}
+	if scopes[0].Unit != want.Unit || scopes[0].UID != want.UID {
+		t.Errorf("scope = %+v, want %+v", scopes[0], want)
+	}
+	pids := slices.Sorted(slices.Values(scopes[0].PIDs))
+	if !slices.Equal(pids, []string{"1001", "1002"}) {
+		t.Errorf("scope PIDs = %v, want [1001 1002]", pids)
+	}
+
+	// Protected cgroups and login sessions never match
+	scopes, err = findCgroupScopes(procRoot, regexp.MustCompile(`glocker|session|init`))
+	if err != nil {
+		t.Fatalf("findCgroupScopes failed: %v", err)
+	}
+	if len(scopes) != 0 {
+		t.Errorf("Expected no scopes for protected cgroups, got %+v", scopes)
+	}
+
+	// No match at all
+	scopes, _ = findCgroupScopes(procRoot, regexp.MustCompile(`discord`))
+	if len(scopes) != 0 {
+		t.Errorf("Expected no scopes, got %+v", scopes)
+	}
+}
+
+func TestSystemctlStopArgs(t *testing.T) {
+	system := systemctlStopArgs(cgroupScope{Unit: "docker-0123abcd.scope"})
+	if !slices.Equal(system, []string{"stop", "docker-0123abcd.scope"}) {
+		t.Errorf("systemctlStopArgs(system scope) = %v", system)
+	}
+
+	// A UID with no passwd entry is passed through as is
+	userScope := systemctlStopArgs(cgroupScope{Unit: "app-steam.scope", UID: "987654"})
+	if !slices.Equal(userScope, []string{"--user", "--machine=987654@", "stop", "app-steam.scope"}) {
+		t.Errorf("systemctlStopArgs(user scope) = %v", userScope)
+	}
+}
+
+// waitForChange returns the next path sent on changes, failing after a timeout.
+func waitForChange(t *testing.T, changes <-chan string) string {
+	t.Helper()
+	select {
+	case path := <-changes:
+		return path
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the file watcher")
+		return ""
+	}
+}
+
+func TestWatchFiles(t *testing.T) {
+	dir := t.TempDir()
+	hosts := filepath.Join(dir, "hosts")
+	other := filepath.Join(dir, "other")
+	if err := os.WriteFile(hosts, []byte("127.0.0.1 localhost\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	changes := make(chan string, 10)
+	watcher, err := WatchFiles([]string{hosts}, func(path string) { changes <- path })
+	if err != nil {
+		t.Fatalf("WatchFiles failed: %v", err)
+	}
+	defer watcher.Close()
+
+	// Files next to the watched one are ignored
+	if err := os.WriteFile(other, []byte("unrelated"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Modified in place
+	f, err := os.OpenFile(hosts, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("0.0.0.0 example.com\n")
+	f.Close()
+	if got := waitForChange(t, changes); got != hosts {
+		t.Errorf("Expected change for %s, got %s", hosts, got)
+	}
+
+	// Deleted
+	if err := os.Remove(hosts); err != nil {
+		t.Fatal(err)
+	}
+	if got := waitForChange(t, changes); got != hosts {
+		t.Errorf("Expected change for %s after delete, got %s", hosts, got)
+	}
+
+	// Replaced by rename, as editors save
+	replacement := filepath.Join(dir, "hosts.tmp")
+	if err := os.WriteFile(replacement, []byte("127.0.0.1 localhost\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(replacement, hosts); err != nil {
+		t.Fatal(err)
+	}
+	if got := waitForChange(t, changes); got != hosts {
+		t.Errorf("Expected change for %s after rename, got %s", hosts, got)
+	}
+}
+
+func TestWatchFiles_Close(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hosts")
+
+	changes := make(chan string, 10)
+	watcher, err := WatchFiles([]string{path}, func(path string) { changes <- path })
+	if err != nil {
+		t.Fatalf("WatchFiles failed: %v", err)
+	}
+	watcher.Close()
+
+	os.WriteFile(path, []byte("changed"), 0644)
+	select {
+	case got := <-changes:
+		t.Errorf("Expected no callbacks after Close, got %s", got)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWatchFiles_MissingDirectory(t *testing.T) {
+	if _, err := WatchFiles([]string{"/nonexistent/dir/hosts"}, func(string) {}); err == nil {
+		t.Error("Expected an error watching a file in a missing directory")
+	}
+}
+
+func TestWeeklyReportDue(t *testing.T) {
+	// Sunday 20:00 schedule; 2026-01-04 is a Sunday
+	scheduled := time.Date(2026, 1, 4, 20, 0, 0, 0, time.UTC)
+	previous := scheduled.AddDate(0, 0, -7)
+
+	tests := []struct {
+		name     string
+		now      time.Time
+		lastSent time.Time
+		want     time.Time
+		wantDue  bool
+	}{
+		{"just before the schedule", scheduled.Add(-time.Minute), previous, previous, false},
+		{"at the schedule", scheduled, previous, scheduled, true},
+		{"already sent", scheduled.Add(time.Minute), scheduled, scheduled, false},
+		{"missed, within grace", scheduled.Add(10 * time.Hour), previous, scheduled, true},
+		{"missed, past grace", scheduled.Add(weeklyReportGrace), previous, scheduled, false},
+		{"never sent", time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC), time.Time{}, scheduled, false},
+	}
+	for _, tt := range tests {
+		got, due := weeklyReportDue(tt.now, time.Sunday, "20:00", tt.lastSent)
+		if !got.Equal(tt.want) || due != tt.wantDue {
+			t.Errorf("%s: weeklyReportDue() = %v, %v; want %v, %v", tt.name, got, due, tt.want, tt.wantDue)
+		}
+	}
+}
+
+func TestWeeklyReporter_SendsOnceAfterDowntime(t *testing.T) {
+	clock := utils.NewFakeTimeProvider(time.Date(2026, 1, 4, 19, 59, 0, 0, time.UTC))
+	stateFile := t.TempDir() + "/weekly-report-sent"
+	var sent []time.Time
+	failing := false
+	reporter := newWeeklyReporter(clock, time.Sunday, "20:00", stateFile, func(weekStart, weekEnd time.Time) error {
+		if failing {
+			return os.ErrDeadlineExceeded
+		}
+		if weekEnd.Sub(weekStart) != 7*24*time.Hour {
+			t.Errorf("Expected a week-long report, got %v - %v", weekStart, weekEnd)
+		}
+		sent = append(sent, weekEnd)
+		return nil
+	})
+
+	reporter.check()
+	if len(sent) != 0 {
+		t.Fatalf("Report sent before its scheduled time: %v", sent)
+	}
+
+	// The daemon was down at 20:00 and comes back Monday morning
+	clock.Set(time.Date(2026, 1, 5, 9, 30, 0, 0, time.UTC))
+	failing = true
+	reporter.check()
+	failing = false
+	reporter.check()
+	if len(sent) != 0 {
+		t.Fatalf("Expected no retry within the retry delay, got %v", sent)
+	}
+	clock.Set(clock.Now().Add(weeklyReportRetry))
+	reporter.check()
+	if len(sent) != 1 || !sent[0].Equal(time.Date(2026, 1, 4, 20, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected the missed Sunday report to be sent once, got %v", sent)
+	}
+
+	// A restarted daemon reads the recorded report and doesn't send it again
+	restarted := *reporter
+	restarted.retryAfter = time.Time{}
+	restarted.check()
+	clock.Set(time.Date(2026, 1, 11, 20, 0, 0, 0, time.UTC))
+	restarted.check()
+	restarted.check()
+	if len(sent) != 2 {
+		t.Errorf("Expected one report per week, got %v", sent)
+	}
+}
+
+func TestDailyReportDue(t *testing.T) {
+	now := time.Date(2026, 1, 6, 9, 0, 0, 0, time.UTC)
+	yesterday := time.Date(2026, 1, 5, 8, 0, 0, 0, time.UTC)
+	today := time.Date(2026, 1, 6, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		now        time.Time
+		reportTime string
+		lastSent   time.Time
+		wantDue    bool
+	}{
+		{"before report time", time.Date(2026, 1, 6, 7, 59, 0, 0, time.UTC), "08:00", yesterday, false},
+		{"at report time", today, "08:00", yesterday, true},
+		{"missed, later tick", now, "08:00", yesterday, true},
+		{"never sent", now, "08:00", time.Time{}, true},
+		{"already sent today", now, "08:00", today, false},
+		{"invalid time uses default", now, "bad", yesterday, true},
+	}
+	for _, tt := range tests {
+		if _, due := dailyReportDue(tt.now, tt.reportTime, tt.lastSent); due != tt.wantDue {
+			t.Errorf("%s: dailyReportDue() due = %v, want %v", tt.name, due, tt.wantDue)
+		}
+	}
+}
+
+func TestDailyReportDue_DST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("Time zone data not available")
+	}
+
+	// Clocks go forward at 02:00 on 2026-03-08; a 02:30 report falls in the gap
+	// and is due from 03:30 local time, on the local date
+	scheduled, due := dailyReportDue(time.Date(2026, 3, 8, 3, 30, 0, 0, loc), "02:30", time.Date(2026, 3, 7, 2, 30, 0, 0, loc))
+	if !due {
+		t.Errorf("Expected the report to be due after the DST gap, scheduled %v", scheduled)
+	}
+
+	// Just after local midnight is a new day even though UTC is still on the old one
+	if _, due := dailyReportDue(time.Date(2026, 3, 9, 0, 30, 0, 0, loc), "00:00", time.Date(2026, 3, 8, 0, 0, 0, 0, loc)); !due {
+		t.Error("Expected the report to be due on the new local date")
+	}
+}
+
+func TestDailyReporter_SendsOnceAfterMissedWindow(t *testing.T) {
+	clock := utils.NewFakeTimeProvider(time.Date(2026, 1, 6, 7, 59, 0, 0, time.UTC))
+	stateFile := t.TempDir() + "/daily-report-sent"
+	var sent []time.Time
+	failing := false
+	reporter := newDailyReporter(clock, "08:00", stateFile, func(date time.Time) error {
+		if failing {
+			return os.ErrDeadlineExceeded
+		}
+		sent = append(sent, date)
+		return nil
+	})
+
+	reporter.check()
+	if len(sent) != 0 {
+		t.Fatalf("Report sent before its scheduled time: %v", sent)
+	}
+
+	// The laptop was asleep at 08:00 and wakes at 11:15
+	clock.Set(time.Date(2026, 1, 6, 11, 15, 0, 0, time.UTC))
+	failing = true
+	reporter.check()
+	failing = false
+	reporter.check()
+	if len(sent) != 0 {
+		t.Fatalf("Expected no retry within the retry delay, got %v", sent)
+	}
+	clock.Set(clock.Now().Add(dailyReportRetry))
+	reporter.check()
+	clock.Set(clock.Now().Add(time.Minute))
+	reporter.check()
+	if len(sent) != 1 || sent[0].Format("2006-01-02") != "2026-01-05" {
+		t.Fatalf("Expected yesterday's report to be sent once, got %v", sent)
+	}
+
+	// A restarted daemon reads the recorded date and doesn't send it again
+	restarted := *reporter
+	restarted.retryAfter = time.Time{}
+	restarted.check()
+	if len(sent) != 1 {
+		t.Fatalf("Expected no resend after restart, got %v", sent)
+	}
+
+	clock.Set(time.Date(2026, 1, 7, 8, 0, 0, 0, time.UTC))
+	restarted.check()
+	restarted.check()
+	if len(sent) != 2 || sent[1].Format("2006-01-02") != "2026-01-06" {
+		t.Errorf("Expected one report per day, got %v", sent)
+	}
+}
+
+func TestReadReportSent_LegacyDate(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "daily-report-sent")
+	if err := os.WriteFile(stateFile, []byte("2026-01-06\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A date written by an older daemon counts as today's report already sent
+	lastSent := readReportSent(stateFile)
+	if _, due := dailyReportDue(time.Date(2026, 1, 6, 9, 0, 0, 0, time.Local), "08:00", lastSent); due {
+		t.Errorf("Expected no resend on the recorded date, last sent %v", lastSent)
+	}
+	if _, due := dailyReportDue(time.Date(2026, 1, 7, 9, 0, 0, 0, time.Local), "08:00", lastSent); !due {
+		t.Errorf("Expected the next day's report to be due, last sent %v", lastSent)
+	}
+
+	scheduled := time.Date(2026, 1, 7, 8, 0, 0, 0, time.UTC)
+	if err := writeReportSent(stateFile, scheduled); err != nil {
+		t.Fatalf("writeReportSent() error: %v", err)
+	}
+	if got := readReportSent(stateFile); !got.Equal(scheduled) {
+		t.Errorf("readReportSent() = %v, want %v", got, scheduled)
+	}
+	if _, err := os.Stat(stateFile + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("Expected no temporary file left behind, got %v", err)
+	}
+}
+
+func TestBuildWeeklyReport(t *testing.T) {
+	weekStart := time.Date(2025, 12, 28, 20, 0, 0, 0, time.UTC) // Sunday
+	weekEnd := weekStart.AddDate(0, 0, 7)
+	violations := []reports.ReportEntry{
+		{Timestamp: weekStart.Add(time.Hour), Keyword: "youtube", Domain: "youtube.com"},
+		{Timestamp: weekStart.Add(25 * time.Hour), Keyword: "youtube", Domain: "youtube.com"},
+		{Timestamp: weekStart.Add(26 * time.Hour), Keyword: "reddit", Domain: "reddit.com"},
+	}
+	unblocks := []reports.UnblockEntry{
+		{UnblockTime: weekStart.Add(49 * time.Hour), Domain: "news.com", Reason: "work"},
+	}
+
+	subject, body := buildWeeklyReport(violations, unblocks, weekStart, weekEnd)
+	if subject != "Glocker Weekly Report: Dec 28 - Jan 4" {
+		t.Errorf("Unexpected subject %q", subject)
+	}
+	for _, want := range []string{
+		"Violations:     3", "Unblocks:       1",
+		"  youtube: 2", "  reddit.com: 1", "  news.com: 1",
+		"  Sun           1         0", "  Mon           2         0", "  Tue           0         1",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Report should contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestNextPanicWindow(t *testing.T) {
+	// 2026-01-06 is a Tuesday
+	schedule := []config.TimeWindow{
+		{Start: "00:00", End: "06:00", Days: []string{"Tue", "Wed"}},
+		{Start: "23:00", End: "01:00", Days: []string{"Fri"}},
+	}
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2026, 1, day, hour, minute, 0, 0, time.Local)
+	}
+
+	tests := []struct {
+		name      string
+		now       time.Time
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{"inside a window", at(6, 3, 0), at(6, 0, 0), at(6, 6, 0)},
+		{"at the start of a window", at(7, 0, 0), at(7, 0, 0), at(7, 6, 0)},
+		{"at the end of a window", at(6, 6, 0), at(7, 0, 0), at(7, 6, 0)},
+		{"between windows", at(7, 12, 0), at(9, 23, 0), at(10, 1, 0)},
+		{"after midnight in a window from the day before", at(10, 0, 30), at(9, 23, 0), at(10, 1, 0)},
+		{"wraps to next week", at(10, 2, 0), at(13, 0, 0), at(13, 6, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, ok := NextPanicWindow(schedule, tt.now)
+			if !ok || !start.Equal(tt.wantStart) || !end.Equal(tt.wantEnd) {
+				t.Errorf("NextPanicWindow() = %v, %v, %v; want %v, %v", start, end, ok, tt.wantStart, tt.wantEnd)
+			}
+		})
+	}
+
+	if _, _, ok := NextPanicWindow(nil, at(6, 3, 0)); ok {
+		t.Error("Expected no window for an empty schedule")
+	}
+}
+
+func TestPanicScheduler(t *testing.T) {
+	state.SetPanicUntil(time.Time{})
+	defer state.SetPanicUntil(time.Time{})
+
+	clock := utils.NewFakeTimeProvider(time.Date(2026, 1, 6, 23, 30, 0, 0, time.Local))
+	scheduler := &panicScheduler{
+		clock:    clock,
+		schedule: []config.TimeWindow{{Start: "00:00", End: "06:00", Days: []string{"Wed"}}},
+	}
+
+	if scheduler.check() {
+		t.Fatal("Expected no panic before the scheduled window")
+	}
+
+	// Entering the window starts panic mode until its end
+	clock.Set(time.Date(2026, 1, 7, 0, 0, 30, 0, time.Local))
+	windowEnd := time.Date(2026, 1, 7, 6, 0, 0, 0, time.Local)
+	if !scheduler.check() {
+		t.Fatal("Expected panic mode to start in the scheduled window")
+	}
+	if got := state.GetPanicUntil(); !got.Equal(windowEnd) {
+		t.Errorf("panic until = %v, want %v", got, windowEnd)
+	}
+
+	// Later checks in the same window don't activate again
+	clock.Set(time.Date(2026, 1, 7, 2, 0, 0, 0, time.Local))
+	if scheduler.check() {
+		t.Error("Expected no second activation within the same window")
+	}
+
+	// Nor does a fresh scheduler (e.g. after a restart) while panic already covers the window
+	restarted := &panicScheduler{clock: clock, schedule: scheduler.schedule}
+	if restarted.check() {
+		t.Error("Expected no activation while panic mode already covers the window")
+	}
+
+	// A manual panic ending earlier is extended to the end of the window
+	state.SetPanicUntil(clock.Now().Add(10 * time.Minute))
+	if !restarted.check() {
+		t.Error("Expected a shorter manual panic to be extended to the window end")
+	}
+	if got := state.GetPanicUntil(); !got.Equal(windowEnd) {
+		t.Errorf("panic until = %v, want %v", got, windowEnd)
+	}
+}
+
+func TestDetectTampering_SkipsFirewallWhilePaused(t *testing.T) {
+	cfg := &config.Config{}
+	firewallReduced := func(reasons []string) bool {
+		return slices.ContainsFunc(reasons, func(reason string) bool {
+			return strings.HasPrefix(reason, "Firewall rules reduced")
+		})
+	}
+
+	// No glocker rules are loaded here, so any baseline looks reduced
+	baseline := enforcement.CountFirewallRules() + 5
+	if !firewallReduced(detectTampering(cfg, nil, nil, baseline)) {
+		t.Fatal("Expected the reduced firewall to be reported")
+	}
+
+	// A pause clears the rules on purpose, also once it has run out but
+	// enforcement hasn't resumed yet
+	state.SetPausedUntil(time.Now().Add(10 * time.Minute))
+	defer state.SetPausedUntil(time.Time{})
+	if reasons := detectTampering(cfg, nil, nil, baseline); firewallReduced(reasons) {
+		t.Errorf("Expected no firewall reason while paused, got %v", reasons)
+	}
+	state.SetPausedUntil(time.Now().Add(-time.Minute))
+	if reasons := detectTampering(cfg, nil, nil, baseline); firewallReduced(reasons) {
+		t.Errorf("Expected no firewall reason before enforcement resumed, got %v", reasons)
+	}
+}
+
+func TestExecuteSuspendCommand(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "suspended")
+	if err := ExecuteSuspendCommand("touch " + marker); err != nil {
+		t.Fatalf("ExecuteSuspendCommand() error = %v", err)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("Expected the suspend command to run: %v", err)
+	}
+
+	if err := ExecuteSuspendCommand("false"); err == nil {
+		t.Error("Expected a failing suspend command to return an error")
+	}
+	if err := ExecuteSuspendCommand(""); err == nil {
+		t.Error("Expected an empty suspend command to return an error")
+	}
+
+	if got := panicCommand("rtcwake -m mem -s {duration_seconds}", 600); got != "rtcwake -m mem -s 600" {
+		t.Errorf("panicCommand() = %q", got)
+	}
+}
+
+func TestConfirmTampering(t *testing.T) {
+	detected := []string{"File modified: /etc/hosts"}
+
+	tests := []struct {
+		name      string
+		reasons   []string
+		debounce  time.Duration
+		recheck   []string
+		want      []string
+		wantSleep bool
+	}{
+		{"no change", nil, 5 * time.Second, nil, nil, false},
+		{"no debounce alarms at once", detected, 0, nil, detected, false},
+		{"change reverted during debounce", detected, 5 * time.Second, nil, nil, true},
+		{"change persists", detected, 5 * time.Second, detected, detected, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var slept time.Duration
+			rechecked := false
+			got := confirmTampering(tt.reasons, tt.debounce, func() []string {
+				rechecked = true
+				return tt.recheck
+			}, func(d time.Duration) { slept = d })
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("confirmTampering() = %v, want %v", got, tt.want)
+			}
+			if rechecked != tt.wantSleep || (tt.wantSleep && slept != tt.debounce) {
+				t.Errorf("rechecked = %v after sleeping %v, want recheck %v after %v", rechecked, slept, tt.wantSleep, tt.debounce)
+			}
+		})
+	}
+}
+
+func TestCheckDailyViolationReset(t *testing.T) {
+	fake := utils.NewFakeTimeProvider(time.Date(2026, 1, 6, 0, 0, 30, 0, time.Local))
+	SetClock(fake)
+	state.SetClock(fake)
+	defer func() {
+		SetClock(utils.DefaultTimeProvider{})
+		state.SetClock(utils.DefaultTimeProvider{})
+		state.ClearViolations()
+		state.SetActiveProfile("", time.Time{})
+	}()
+	cfg := &config.Config{ViolationTracking: config.ViolationTrackingConfig{Enabled: true, EscalationProfile: "strict"}}
+
+	// The first check ever resets
+	state.SetLastViolationReset(time.Time{})
+	if !checkDailyViolationReset(cfg) {
+		t.Fatal("Expected the first check to reset")
+	}
+	if got := state.GetLastViolationReset(); !got.Equal(fake.Now()) {
+		t.Errorf("Last reset = %v, want %v", got, fake.Now())
+	}
+
+	// Violations and escalation last through the day
+	state.AddViolation(state.Violation{Timestamp: fake.Now(), Host: "reddit.com"})
+	state.SetActiveProfile("strict", fake.Now())
+	fake.Set(time.Date(2026, 1, 6, 23, 59, 0, 0, time.Local))
+	if checkDailyViolationReset(cfg) {
+		t.Error("Expected no reset before midnight")
+	}
+	if got := len(state.GetViolations()); got != 1 {
+		t.Errorf("Expected the violation to be kept, got %d", got)
+	}
+
+	// The first check after midnight resets and reverts the escalation
+	fake.Advance(2 * time.Minute)
+	if !checkDailyViolationReset(cfg) {
+		t.Fatal("Expected a reset after midnight")
+	}
+	if got := len(state.GetViolations()); got != 0 {
+		t.Errorf("Expected violations to be cleared, got %d", got)
+	}
+	if active, _ := state.GetActiveProfile(); active != "" {
+		t.Errorf("Expected the escalation profile to be reverted, got %q", active)
+	}
+	if got := state.GetLastViolationReset(); !got.Equal(fake.Now()) {
+		t.Errorf("Last reset = %v, want %v", got, fake.Now())
+	}
+
+	// Later checks the same day leave it alone
+	fake.Advance(30 * time.Minute)
+	if checkDailyViolationReset(cfg) {
+		t.Error("Expected only one reset per day")
+	}
+}
+
+// stubViolationActions replaces the action hooks with ones recording each call
+// in calls. Commands named "false" fail.
+func stubViolationActions(t *testing.T, calls *[]string) {
+	origRun, origLock, origSuspend, origEmail := runCommand, lockScreen, suspend, sendEmail
+	t.Cleanup(func() { runCommand, lockScreen, suspend, sendEmail = origRun, origLock, origSuspend, origEmail })
+
+	runCommand = func(name string, args ...string) error {
+		*calls = append(*calls, strings.Join(append([]string{"command", name}, args...), " "))
+		if name == "false" {
+			return errors.New("exit status 1")
+		}
+		return nil
+	}
+	lockScreen = func(cfg *config.Config) error {
+		*calls = append(*calls, "lock")
+		return errors.New("no graphical session found")
+	}
+	suspend = func(command string) error {
+		*calls = append(*calls, "suspend "+command)
+		return nil
+	}
+	sendEmail = func(cfg *config.Config, count int) error {
+		*calls = append(*calls, fmt.Sprintf("notify %d", count))
+		return nil
+	}
+}
+
+func TestViolationActions(t *testing.T) {
+	disabled := false
+	cfg := &config.Config{
+		Accountability: config.AccountabilityConfig{Enabled: true},
+		ViolationTracking: config.ViolationTrackingConfig{
+			Command: "glocklock -duration 5m",
+			Actions: []config.ViolationAction{
+				{Type: "lock"},
+				{Type: "command", Command: "logger", Enabled: &disabled},
+				{Type: "suspend"},
+			},
+		},
+	}
+
+	// The legacy command runs first and the accountability email last
+	var types []string
+	for _, action := range violationActions(cfg) {
+		types = append(types, action.Type)
+	}
+	if want := []string{"command", "lock", "suspend", "notify"}; !slices.Equal(types, want) {
+		t.Errorf("violationActions() types = %v, want %v", types, want)
+	}
+
+	// A notify action takes the place of the implicit email
+	cfg.ViolationTracking.Command = ""
+	cfg.ViolationTracking.Actions = []config.ViolationAction{{Type: "notify"}, {Type: "lock"}}
+	types = nil
+	for _, action := range violationActions(cfg) {
+		types = append(types, action.Type)
+	}
+	if want := []string{"notify", "lock"}; !slices.Equal(types, want) {
+		t.Errorf("violationActions() with notify = %v, want %v", types, want)
+	}
+
+	// Nothing configured and no accountability: nothing to run
+	if actions := violationActions(&config.Config{}); len(actions) != 0 {
+		t.Errorf("Expected no actions, got %+v", actions)
+	}
+}
+
+func TestRunViolationActions_OrderAndFailures(t *testing.T) {
+	var calls []string
+	stubViolationActions(t, &calls)
+
+	cfg := &config.Config{
+		PanicCommand: "systemctl suspend",
+		ViolationTracking: config.ViolationTrackingConfig{
+			Actions: []config.ViolationAction{
+				{Type: "command", Command: "false"},
+				{Type: "lock"},
+				{Type: "notify"},
+				{Type: "command", Command: "logger", Args: []string{"-t", "glocker", "threshold exceeded"}},
+				{Type: "suspend"},
+				{Type: "suspend", Command: "loginctl suspend"},
+			},
+		},
+	}
+
+	// The failing command and lock don't stop the actions after them
+	runViolationActions(cfg, violationActions(cfg), 5)
+	want := []string{
+		"command false",
+		"lock",
+		"notify 5",
+		"command logger -t glocker threshold exceeded",
+		"suspend systemctl suspend",
+		"suspend loginctl suspend",
+	}
+	if !slices.Equal(calls, want) {
+		t.Errorf("Actions ran as\n%v\nwant\n%v", calls, want)
+	}
+}
+
+func TestCheckViolationThreshold_RunsActions(t *testing.T) {
+	var calls []string
+	stubViolationActions(t, &calls)
+
+	state.ClearViolations()
+	t.Cleanup(state.ClearViolations)
+	fake := utils.NewFakeTimeProvider(time.Date(2026, 1, 6, 10, 0, 0, 0, time.Local))
+	SetClock(fake)
+	t.Cleanup(func() { SetClock(utils.DefaultTimeProvider{}) })
+
+	cfg := &config.Config{
+		ViolationTracking: config.ViolationTrackingConfig{
+			Enabled:           true,
+			MaxViolations:     2,
+			TimeWindowMinutes: 60,
+			Command:           "echo threshold",
+			Actions:           []config.ViolationAction{{Type: "lock"}},
+		},
+	}
+
+	state.AddViolation(state.Violation{Timestamp: fake.Now(), Host: "a.com", Type: "web_access"})
+	checkViolationThreshold(cfg)
+	if len(calls) != 0 {
+		t.Fatalf("Expected no actions below the threshold, got %v", calls)
+	}
+
+	state.AddViolation(state.Violation{Timestamp: fake.Now(), Host: "b.com", Type: "web_access"})
+	checkViolationThreshold(cfg)
+	if want := []string{"command echo threshold", "lock"}; !slices.Equal(calls, want) {
+		t.Errorf("Expected %v at the threshold, got %v", want, calls)
+	}
+}
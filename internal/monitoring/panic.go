@@ -1,7 +1,11 @@
 package monitoring
 
 import (
+	"fmt"
 	"log"
+	"os/exec"
+	"strconv"
+	"strings"
 	"time"
 
 	"glocker/internal/config"
@@ -21,7 +25,7 @@ func MonitorPanicMode(cfg *config.Config) {
 	lastLogTime := time.Time{} // Track last time we logged to avoid spam
 
 	for range ticker.C {
-		now := time.Now()
+		now := clock.Now()
 		currentPanicUntil := state.GetPanicUntil()
 		currentLastSuspend := state.GetLastSuspendTime()
 
@@ -69,17 +73,34 @@ func MonitorPanicMode(cfg *config.Config) {
 				lastLogTime = now
 			}
 
-			// Suspend the system if needed
+			// Suspend the system if needed. A failed command still starts the
+			// grace period, so it isn't rerun every second.
 			if cfg.PanicCommand != "" {
-				ExecuteSuspendCommand(cfg.PanicCommand)
+				if err := ExecuteSuspendCommand(panicCommand(cfg.PanicCommand, remainingSeconds)); err != nil {
+					log.Printf("Failed to suspend for panic mode: %v", err)
+				}
 				state.SetLastSuspendTime(now)
 			}
 		}
 	}
 }
 
-// ExecuteSuspendCommand executes the system suspend command.
-func ExecuteSuspendCommand(command string) {
-	// Implementation placeholder - actual suspension would be dangerous in refactoring
-	log.Printf("Would execute suspend command: %s", command)
+// panicCommand fills in the {duration_seconds} template variable of the panic
+// command with the seconds left in panic mode.
+func panicCommand(command string, remainingSeconds int) string {
+	return strings.ReplaceAll(command, "{duration_seconds}", strconv.Itoa(remainingSeconds))
+}
+
+// ExecuteSuspendCommand runs the system suspend command, split on spaces (no
+// shell), and waits for it to exit.
+func ExecuteSuspendCommand(command string) error {
+	parts := strings.Fields(command)
+	if len(parts) == 0 {
+		return fmt.Errorf("no suspend command set")
+	}
+	log.Printf("Executing suspend command: %s", command)
+	if output, err := exec.Command(parts[0], parts[1:]...).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to execute %s: %w (output: %s)", parts[0], err, strings.TrimSpace(string(output)))
+	}
+	return nil
 }
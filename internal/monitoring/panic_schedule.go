@@ -0,0 +1,114 @@
+package monitoring
+
+import (
+	"log"
+	"slices"
+	"time"
+
+	"glocker/internal/audit"
+	"glocker/internal/config"
+	"glocker/internal/state"
+	"glocker/internal/utils"
+)
+
+// panicScheduler enters panic mode automatically during the panic_schedule windows.
+type panicScheduler struct {
+	clock    utils.TimeProvider
+	schedule []config.TimeWindow
+
+	activatedUntil time.Time // End of the window panic mode was last entered for
+}
+
+// MonitorPanicSchedule runs a background goroutine that enters panic mode for the
+// rest of each panic_schedule window. MonitorPanicMode then keeps the system
+// suspended, re-suspending it on early wake, as for a manual -panic.
+func MonitorPanicSchedule(cfg *config.Config) {
+	if len(cfg.PanicSchedule) == 0 {
+		return
+	}
+
+	scheduler := &panicScheduler{
+		clock:    clock,
+		schedule: cfg.PanicSchedule,
+	}
+
+	scheduler.check()
+
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		scheduler.check()
+	}
+}
+
+// check enters panic mode if a scheduled window is active and panic mode doesn't
+// already cover it. It reports whether panic mode was entered.
+func (s *panicScheduler) check() bool {
+	now := s.clock.Now()
+	if !config.AnyWindowActive(s.schedule, now) {
+		return false
+	}
+
+	// The active window's end, which panic mode lasts until
+	start, end, ok := NextPanicWindow(s.schedule, now)
+	if !ok || now.Before(start) {
+		return false
+	}
+
+	// Entered already for this window, or a manual panic lasts at least as long
+	if s.activatedUntil.Equal(end) || !state.GetPanicUntil().Before(end) {
+		return false
+	}
+
+	state.SetPanicUntil(end)
+	s.activatedUntil = end
+	audit.Log(audit.Event{Timestamp: now, Type: audit.EventPanic, Reason: "scheduled", Source: "panic_schedule", Until: end})
+	log.Printf("⚠️  SCHEDULED PANIC MODE ACTIVATED (until %s)", end.Format("15:04:05"))
+	return true
+}
+
+// NextPanicWindow returns the start and end of the panic_schedule window that is
+// active at now or, if none is, the next one to start. A window starts on one of
+// its days and, when it crosses midnight, ends the following day. ok is false if
+// the schedule has no window within the next week.
+func NextPanicWindow(schedule []config.TimeWindow, now time.Time) (start, end time.Time, ok bool) {
+	// Start from yesterday, whose midnight-crossing windows may still be running
+	for offset := -1; offset <= 7; offset++ {
+		day := now.AddDate(0, 0, offset)
+		for _, window := range schedule {
+			if !slices.Contains(window.Days, day.Weekday().String()[:3]) {
+				continue
+			}
+			windowStart, windowEnd, valid := windowBounds(window, day)
+			if !valid || !now.Before(windowEnd) {
+				continue
+			}
+			if !ok || windowStart.Before(start) {
+				start, end, ok = windowStart, windowEnd, true
+			}
+		}
+		if ok {
+			break // Windows of later days start later
+		}
+	}
+	return start, end, ok
+}
+
+// windowBounds returns the times a window starting on day begins and ends.
+func windowBounds(window config.TimeWindow, day time.Time) (start, end time.Time, ok bool) {
+	startTime, err := time.Parse("15:04", window.Start)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+	endTime, err := time.Parse("15:04", window.End)
+	if err != nil {
+		return time.Time{}, time.Time{}, false
+	}
+
+	start = time.Date(day.Year(), day.Month(), day.Day(), startTime.Hour(), startTime.Minute(), 0, 0, day.Location())
+	end = time.Date(day.Year(), day.Month(), day.Day(), endTime.Hour(), endTime.Minute(), 0, 0, day.Location())
+	if !end.After(start) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return start, end, true
+}
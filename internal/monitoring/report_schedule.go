@@ -0,0 +1,89 @@
+package monitoring
+
+import (
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"glocker/internal/utils"
+)
+
+// periodReporter decides when a periodic report (daily or weekly) is due, sends
+// it and records it as sent, so a restarted daemon doesn't send it again.
+type periodReporter struct {
+	name      string // "daily" or "weekly", for logs
+	clock     utils.TimeProvider
+	retry     time.Duration // Wait before retrying a report that failed to send
+	stateFile string        // Scheduled time of the last report sent, kept across restarts
+
+	// due returns the scheduled time of the latest report at now and whether it
+	// should be sent, given the scheduled time of the last one sent.
+	due  func(now, lastSent time.Time) (time.Time, bool)
+	send func(scheduled time.Time) error
+
+	retryAfter time.Time // Set after a failed send
+}
+
+// run checks for a due report now and then every minute, forever.
+func (r *periodReporter) run() {
+	r.check()
+
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+	for range ticker.C {
+		r.check()
+	}
+}
+
+// check sends the report if it is due and records it as sent.
+func (r *periodReporter) check() {
+	now := r.clock.Now()
+	if now.Before(r.retryAfter) {
+		return
+	}
+
+	scheduled, due := r.due(now, readReportSent(r.stateFile))
+	if !due {
+		return
+	}
+	if now.Sub(scheduled) > time.Minute {
+		log.Printf("Sending %s report missed at %s", r.name, scheduled.Format("2006-01-02 15:04"))
+	}
+
+	if err := r.send(scheduled); err != nil {
+		log.Printf("Failed to send %s report: %v", r.name, err)
+		r.retryAfter = now.Add(r.retry)
+		return
+	}
+	r.retryAfter = time.Time{}
+
+	if err := writeReportSent(r.stateFile, scheduled); err != nil {
+		log.Printf("Failed to record %s report: %v", r.name, err)
+	}
+	log.Printf("Sent the %s report scheduled for %s", r.name, scheduled.Format("2006-01-02 15:04"))
+}
+
+// readReportSent returns the scheduled time of the last report sent, or the zero
+// time if none was recorded. Older daily state files hold only the local date of
+// the last report, which counts as sent for that whole day.
+func readReportSent(path string) time.Time {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return time.Time{}
+	}
+	value := strings.TrimSpace(string(data))
+	if sent, err := time.Parse(time.RFC3339, value); err == nil {
+		return sent
+	}
+	if date, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return date.AddDate(0, 0, 1).Add(-time.Second)
+	}
+	return time.Time{}
+}
+
+// writeReportSent records the scheduled time of a report that was sent. The file
+// is replaced atomically, so a crash can't leave it empty and resend the report.
+func writeReportSent(path string, scheduled time.Time) error {
+	return utils.WriteFileAtomic(path, []byte(scheduled.Format(time.RFC3339)+"\n"), 0644)
+}
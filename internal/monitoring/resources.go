@@ -0,0 +1,139 @@
+package monitoring
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"glocker/internal/state"
+)
+
+// resourceSampleInterval is how often the daemon samples its own resource usage.
+const resourceSampleInterval = 5 * time.Minute
+
+// resourceGrowthWindow is how many consecutive samples must keep growing before
+// it is reported as a possible leak (30 minutes at the default interval).
+const resourceGrowthWindow = 6
+
+// MonitorResources periodically samples the daemon's memory, goroutine and GC usage,
+// logging each sample and warning when RSS or goroutine count grows monotonically.
+func MonitorResources() {
+	ticker := time.NewTicker(resourceSampleInterval)
+	defer ticker.Stop()
+
+	recordResourceSample()
+	for range ticker.C {
+		recordResourceSample()
+	}
+}
+
+// recordResourceSample takes a sample, stores it and logs any sustained growth.
+func recordResourceSample() {
+	sample := SampleResources()
+	state.AddResourceSample(sample)
+
+	slog.Debug("Resource sample",
+		"rss_bytes", sample.RSSBytes,
+		"heap_bytes", sample.HeapBytes,
+		"goroutines", sample.Goroutines,
+		"num_gc", sample.NumGC,
+		"gc_pause", sample.GCPause)
+
+	if warning := ResourceGrowthWarning(state.GetResourceSamples()); warning != "" {
+		log.Printf("WARNING: %s (possible leak)", warning)
+	}
+}
+
+// SampleResources measures the current process's resource usage.
+// RSS is read from /proc/self/status and is 0 if unavailable.
+func SampleResources() state.ResourceSample {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	sample := state.ResourceSample{
+		Timestamp:  clock.Now(),
+		HeapBytes:  mem.HeapAlloc,
+		Goroutines: runtime.NumGoroutine(),
+		NumGC:      mem.NumGC,
+		GCPause:    time.Duration(mem.PauseTotalNs),
+	}
+
+	if f, err := os.Open("/proc/self/status"); err == nil {
+		defer f.Close()
+		if rss, err := parseVmRSS(f); err == nil {
+			sample.RSSBytes = rss
+		} else {
+			slog.Debug("Could not read RSS", "error", err)
+		}
+	}
+
+	return sample
+}
+
+// parseVmRSS extracts the VmRSS line ("VmRSS:	  123456 kB") from /proc/<pid>/status, in bytes.
+func parseVmRSS(r io.Reader) (uint64, error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, "VmRSS:") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "VmRSS:"))
+		if len(fields) == 0 {
+			return 0, fmt.Errorf("malformed VmRSS line: %q", line)
+		}
+		kb, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("malformed VmRSS line: %q", line)
+		}
+		return kb * 1024, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("VmRSS not found")
+}
+
+// ResourceGrowthWarning describes RSS or goroutine counts that rose in every one of the
+// last resourceGrowthWindow samples. Returns "" when usage looks stable.
+func ResourceGrowthWarning(samples []state.ResourceSample) string {
+	if len(samples) < resourceGrowthWindow {
+		return ""
+	}
+	window := samples[len(samples)-resourceGrowthWindow:]
+
+	rssGrowing, goroutinesGrowing := true, true
+	for i := 1; i < len(window); i++ {
+		if window[i].RSSBytes <= window[i-1].RSSBytes {
+			rssGrowing = false
+		}
+		if window[i].Goroutines <= window[i-1].Goroutines {
+			goroutinesGrowing = false
+		}
+	}
+
+	first, last := window[0], window[len(window)-1]
+	var warnings []string
+	if rssGrowing && first.RSSBytes > 0 {
+		warnings = append(warnings, fmt.Sprintf("RSS grew from %s to %s", FormatBytes(first.RSSBytes), FormatBytes(last.RSSBytes)))
+	}
+	if goroutinesGrowing {
+		warnings = append(warnings, fmt.Sprintf("goroutines grew from %d to %d", first.Goroutines, last.Goroutines))
+	}
+	if len(warnings) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s over the last %d samples", strings.Join(warnings, ", "), len(window))
+}
+
+// FormatBytes formats a byte count in MB with one decimal place.
+func FormatBytes(b uint64) string {
+	return fmt.Sprintf("%.1f MB", float64(b)/(1024*1024))
+}
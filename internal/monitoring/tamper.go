@@ -4,75 +4,70 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"os/exec"
 	"strings"
 	"time"
 
+	"glocker/internal/audit"
 	"glocker/internal/config"
+	"glocker/internal/enforcement"
 	"glocker/internal/state"
 	"glocker/internal/notify"
+	"glocker/internal/utils"
 )
 
 // MonitorTampering continuously monitors file checksums and system state for tampering.
 // It checks files, firewall rules, and service status at regular intervals.
 func MonitorTampering(cfg *config.Config, checksums []state.FileChecksum, filesToMonitor []string) {
-	// Set default check interval if not specified
-	checkInterval := cfg.TamperDetection.CheckInterval
-	if checkInterval == 0 {
-		checkInterval = 30 // Default: check every 30 seconds
-	}
-
-	firewallRuleCount := CountFirewallRules()
+	firewallRuleCount := enforcement.CountFirewallRules()
 
-	ticker := time.NewTicker(time.Duration(checkInterval) * time.Second)
+	// A reload can change check_interval_seconds
+	interval := tamperCheckInterval(cfg)
+	ticker := utils.NewTicker(interval)
 	defer ticker.Stop()
-
-	for range ticker.C {
-		log.Println("Tamper check")
-		tampered := false
-		var tamperReasons []string
-
-		// Check file checksums
-		var currentChecksums []state.FileChecksum
-		for _, filePath := range filesToMonitor {
-			checksum := CaptureChecksum(cfg, filePath)
-			currentChecksums = append(currentChecksums, checksum)
+	reloads := make(chan struct{}, 1)
+	state.AddReloadListener(reloads)
+	pausedSinceBaseline := false
+
+	for {
+		select {
+		case <-reloads:
+			interval = utils.ResetTicker(ticker, interval, tamperCheckInterval(cfg))
+			continue
+		case <-ticker.Chan():
 		}
-		for i, current := range currentChecksums {
-			original := checksums[i]
 
-			// File was deleted
-			if original.Exists && !current.Exists {
-				tampered = true
-				tamperReasons = append(tamperReasons, fmt.Sprintf("File deleted: %s", current.Path))
-			}
+		log.Println("Tamper check")
 
-			// File was modified
-			if original.Exists && current.Exists && original.Checksum != current.Checksum {
-				tampered = true
-				tamperReasons = append(tamperReasons, fmt.Sprintf("File modified: %s", current.Path))
-			}
+		// A pause clears the firewall, and resuming rebuilds it with a rule count
+		// of its own, so the baseline is taken again once the pause is over
+		if enforcementPaused() {
+			pausedSinceBaseline = true
+		} else if pausedSinceBaseline {
+			firewallRuleCount = enforcement.CountFirewallRules()
+			pausedSinceBaseline = false
 		}
 
-		// Check firewall rules
-		currentRuleCount := CountFirewallRules()
-		if currentRuleCount < firewallRuleCount {
-			tampered = true
-			reason := fmt.Sprintf("Firewall rules reduced from %d to %d", firewallRuleCount, currentRuleCount)
-			tamperReasons = append(tamperReasons, reason)
+		check := func() []string {
+			return detectTampering(cfg, checksums, filesToMonitor, firewallRuleCount)
 		}
-
-		// Check if service is still running
-		if !IsServiceRunning() {
-			tampered = true
-			tamperReasons = append(tamperReasons, "Glocker service was stopped")
+		tamperReasons := confirmTampering(check(), cfg.TamperDetection.Debounce(), check, time.Sleep)
+		if len(tamperReasons) == 0 {
+			continue
 		}
 
-		// Trigger alarm if tampering detected
-		if tampered {
+		// Expected changes, like package updates, are only logged
+		if cfg.TamperDetection.InMaintenanceWindow(clock.Now()) {
+			log.Printf("Tamper check: %s during a maintenance window, not raising an alarm", strings.Join(tamperReasons, "; "))
+		} else {
 			log.Println("Tamper check failed")
 			log.Println(tamperReasons)
+			for _, reason := range tamperReasons {
+				audit.Log(audit.Event{Type: audit.EventTamper, Reason: reason, Source: "tamper_detection"})
+				state.RecordTamperEvent()
+			}
 
 			// Send desktop notification
 			notify.SendNotification(cfg, "Glocker Security Alert",
@@ -80,19 +75,87 @@ func MonitorTampering(cfg *config.Config, checksums []state.FileChecksum, filesT
 				"critical", "dialog-error")
 
 			RaiseAlarm(cfg, tamperReasons)
-			// Update baseline checksums after alarm
-			checksums = nil
-			for _, filePath := range filesToMonitor {
-				checksum := CaptureChecksum(cfg, filePath)
-				checksums = append(checksums, checksum)
-			}
-			// Also update global checksums
-			state.SetGlobalChecksums(checksums)
-			firewallRuleCount = CountFirewallRules()
 		}
+
+		// Update baseline checksums after the change
+		checksums = nil
+		for _, filePath := range filesToMonitor {
+			checksum := CaptureChecksum(cfg, filePath)
+			checksums = append(checksums, checksum)
+		}
+		// Also update global checksums
+		state.SetGlobalChecksums(checksums)
+		firewallRuleCount = enforcement.CountFirewallRules()
 	}
 }
 
+// detectTampering compares the monitored files, firewall rules and service with
+// the baseline and returns what changed.
+func detectTampering(cfg *config.Config, checksums []state.FileChecksum, filesToMonitor []string, firewallRuleCount int) []string {
+	var tamperReasons []string
+
+	// Check file checksums
+	for i, filePath := range filesToMonitor {
+		current := CaptureChecksum(cfg, filePath)
+		original := checksums[i]
+
+		// File was deleted
+		if original.Exists && !current.Exists {
+			tamperReasons = append(tamperReasons, fmt.Sprintf("File deleted: %s", current.Path))
+		}
+
+		// File was modified
+		if original.Exists && current.Exists && original.Checksum != current.Checksum {
+			tamperReasons = append(tamperReasons, fmt.Sprintf("File modified: %s", current.Path))
+		}
+	}
+
+	// Check firewall rules, which a pause clears on purpose
+	if !enforcementPaused() {
+		if currentRuleCount := enforcement.CountFirewallRules(); currentRuleCount < firewallRuleCount {
+			tamperReasons = append(tamperReasons, fmt.Sprintf("Firewall rules reduced from %d to %d", firewallRuleCount, currentRuleCount))
+		}
+	}
+
+	// Check if service is still running
+	if !IsServiceRunning() {
+		tamperReasons = append(tamperReasons, "Glocker service was stopped")
+	}
+	return tamperReasons
+}
+
+// enforcementPaused reports whether a pause has lifted the blocking: it is in
+// effect, or has run out but enforcement hasn't resumed yet.
+func enforcementPaused() bool {
+	return !state.GetPausedUntil().IsZero()
+}
+
+// confirmTampering waits out the debounce after a detected change and checks
+// again, so a file briefly rewritten by a system update doesn't raise an alarm.
+// It returns the reasons of the second check, or reasons as they are without a
+// debounce.
+func confirmTampering(reasons []string, debounce time.Duration, recheck func() []string, sleep func(time.Duration)) []string {
+	if len(reasons) == 0 || debounce <= 0 {
+		return reasons
+	}
+	slog.Debug("Possible tampering, checking again after the debounce", "reasons", reasons, "debounce", debounce)
+	sleep(debounce)
+	confirmed := recheck()
+	if len(confirmed) == 0 {
+		log.Printf("Tamper check: %s was back to normal after %v, not raising an alarm", strings.Join(reasons, "; "), debounce)
+	}
+	return confirmed
+}
+
+// tamperCheckInterval returns how often MonitorTampering checks, 30 seconds
+// unless tamper_detection.check_interval_seconds is set.
+func tamperCheckInterval(cfg *config.Config) time.Duration {
+	if cfg.TamperDetection.CheckInterval <= 0 {
+		return 30 * time.Second
+	}
+	return time.Duration(cfg.TamperDetection.CheckInterval) * time.Second
+}
+
 // CaptureChecksum calculates and returns the checksum for a file.
 // For hosts files, it only checksums the glocker section.
 func CaptureChecksum(cfg *config.Config, path string) state.FileChecksum {
@@ -151,27 +214,6 @@ func UpdateChecksum(filePath string) {
 	log.Printf("Updated checksum for %s: %s", filePath, newChecksum.Checksum)
 }
 
-// CountFirewallRules counts the number of glocker firewall rules currently active.
-func CountFirewallRules() int {
-	count := 0
-
-	// Count IPv4 rules
-	cmd := exec.Command("bash", "-c", "iptables -S OUTPUT | grep 'GLOCKER-BLOCK' | wc -l")
-	if output, err := cmd.Output(); err == nil {
-		fmt.Sscanf(strings.TrimSpace(string(output)), "%d", &count)
-	}
-
-	// Count IPv6 rules
-	cmd = exec.Command("bash", "-c", "ip6tables -S OUTPUT | grep 'GLOCKER-BLOCK' | wc -l")
-	if output, err := cmd.Output(); err == nil {
-		var count6 int
-		fmt.Sscanf(strings.TrimSpace(string(output)), "%d", &count6)
-		count += count6
-	}
-
-	return count
-}
-
 // IsServiceRunning checks if the glocker systemd service is active.
 func IsServiceRunning() bool {
 	cmd := exec.Command("systemctl", "is-active", "glocker.service")
@@ -192,15 +234,15 @@ func RaiseAlarm(cfg *config.Config, reasons []string) {
 	}
 
 	// Send accountability email
-	if cfg.Accountability.Enabled {
+	if cfg.NotifiesPartner() {
 		subject := "GLOCKER ALERT: Tampering Detected"
-		body := fmt.Sprintf("Tampering was detected at %s:\n\n", time.Now().Format("2006-01-02 15:04:05"))
+		body := fmt.Sprintf("Tampering was detected at %s:\n\n", clock.Now().Format("2006-01-02 15:04:05"))
 		for _, reason := range reasons {
 			body += "  - " + reason + "\n"
 		}
 		body += "\nThis is an automated alert from Glocker."
 
-		notify.SendEmail(cfg, subject, body)
+		notify.SendEmail(cfg, notify.EventTamperDetected, subject, body)
 	}
 
 	// Execute alarm command - split on spaces for proper argument handling
@@ -6,21 +6,40 @@ import (
 	"log/slog"
 	"os/exec"
 	"strings"
+	"sync"
 	"time"
 
+	"glocker/internal/audit"
 	"glocker/internal/config"
 	"glocker/internal/state"
 	"glocker/internal/notify"
+	"glocker/internal/utils"
 )
 
+// clock supplies the current time to monitoring; tests replace it with SetClock.
+var clock utils.TimeProvider = utils.DefaultTimeProvider{}
+
+// SetClock makes monitoring read the current time from c.
+func SetClock(c utils.TimeProvider) {
+	clock = c
+}
+
 // RecordViolation adds a violation to the tracking system and checks thresholds.
+// Violations are audited even when violation tracking is disabled.
 func RecordViolation(cfg *config.Config, violationType, host, url string) {
+	now := clock.Now()
+
+	// Keyword reports are audited with their keyword by web.LogContentReport
+	if violationType != "content_report" {
+		audit.Log(audit.Event{Timestamp: now, Type: audit.EventViolation, Domain: host, URL: url, Source: violationType})
+	}
+
 	if !cfg.ViolationTracking.Enabled {
 		return
 	}
 
 	violation := state.Violation{
-		Timestamp: time.Now(),
+		Timestamp: now,
 		Host:      host,
 		URL:       url,
 		Type:      violationType,
@@ -41,11 +60,13 @@ func checkViolationThreshold(cfg *config.Config) {
 		return
 	}
 
-	now := time.Now()
-	recentCount := countRecentViolations(cfg, now)
+	now := clock.Now()
+	recentCount := CountRecentViolations(cfg, now)
 
 	slog.Debug("Checking violation threshold", "recent_count", recentCount, "max_violations", cfg.ViolationTracking.MaxViolations)
 
+	checkViolationWarning(cfg, recentCount)
+
 	if recentCount >= cfg.ViolationTracking.MaxViolations {
 		log.Printf("VIOLATION THRESHOLD EXCEEDED: %d/%d violations in last %d minutes",
 			recentCount, cfg.ViolationTracking.MaxViolations, cfg.ViolationTracking.TimeWindowMinutes)
@@ -55,22 +76,59 @@ func checkViolationThreshold(cfg *config.Config) {
 			fmt.Sprintf("Violation threshold exceeded: %d/%d", recentCount, cfg.ViolationTracking.MaxViolations),
 			"critical", "dialog-warning")
 
-		// Execute the configured command
-		if cfg.ViolationTracking.Command != "" {
-			executeViolationCommand(cfg, recentCount)
-		}
+		// Run the command, the configured actions and the accountability email
+		runViolationActions(cfg, violationActions(cfg), recentCount)
 
-		// Send accountability email
-		if cfg.Accountability.Enabled {
-			sendViolationEmail(cfg, recentCount)
+		// Tighten protection for the rest of the day
+		if cfg.ViolationTracking.EscalationProfile != "" {
+			escalateProfile(cfg, recentCount, now)
 		}
 
 		log.Printf("Violation command executed - violations will continue to trigger until daily reset")
 	}
 }
 
-// countRecentViolations counts violations within the configured time window.
-func countRecentViolations(cfg *config.Config, now time.Time) int {
+// violationWarning remembers whether recent violations had reached warn_ratio at the
+// last check, so the warning is sent once each time they climb past it.
+var violationWarning struct {
+	mu      sync.Mutex
+	reached bool
+}
+
+// checkViolationWarning sends a warning notification when recentCount first reaches
+// warn_ratio of max_violations. It fires again only after the count has dropped
+// below that level, through the time window or the daily reset. Reports whether the
+// warning was sent.
+func checkViolationWarning(cfg *config.Config, recentCount int) bool {
+	maxViolations := cfg.ViolationTracking.MaxViolations
+	ratio := cfg.ViolationTracking.WarnRatio
+	if ratio <= 0 || maxViolations <= 0 {
+		return false
+	}
+
+	reached := float64(recentCount)/float64(maxViolations) >= ratio
+
+	violationWarning.mu.Lock()
+	rising := reached && !violationWarning.reached
+	violationWarning.reached = reached
+	violationWarning.mu.Unlock()
+
+	// At the threshold itself the threshold actions take over
+	if !rising || recentCount >= maxViolations {
+		return false
+	}
+
+	log.Printf("VIOLATION WARNING: %d/%d violations in last %d minutes",
+		recentCount, maxViolations, cfg.ViolationTracking.TimeWindowMinutes)
+	notify.SendNotification(cfg, "Glocker Warning",
+		fmt.Sprintf("%d of %d violations used - %d more triggers the threshold", recentCount, maxViolations, maxViolations-recentCount),
+		"normal", "dialog-warning")
+	return true
+}
+
+// CountRecentViolations counts violations within the configured time window,
+// each weighted by its type's violation_tracking.weights entry.
+func CountRecentViolations(cfg *config.Config, now time.Time) int {
 	if !cfg.ViolationTracking.Enabled {
 		return 0
 	}
@@ -81,37 +139,142 @@ func countRecentViolations(cfg *config.Config, now time.Time) int {
 	violations := state.GetViolations()
 	for _, v := range violations {
 		if v.Timestamp.After(cutoff) {
-			count++
+			count += cfg.ViolationTracking.Weight(v.Type)
 		}
 	}
 
 	return count
 }
 
-// executeViolationCommand executes the configured command when threshold is exceeded.
-func executeViolationCommand(cfg *config.Config, count int) {
-	parts := strings.Fields(cfg.ViolationTracking.Command)
-	if len(parts) == 0 {
-		return
+// Hooks for tests, so threshold actions can run without a display, suspending
+// or sending mail.
+var (
+	runCommand = func(name string, args ...string) error { return exec.Command(name, args...).Run() }
+	lockScreen = func(cfg *config.Config) error { return fmt.Errorf("no screen locker set") }
+	suspend    = ExecuteSuspendCommand
+	sendEmail  = sendViolationEmail
+)
+
+// SetScreenLocker sets how lock actions lock the screen. The daemon passes the
+// IPC server's screen locker, which monitoring can't import.
+func SetScreenLocker(lock func(cfg *config.Config) error) {
+	lockScreen = lock
+}
+
+// violationActions returns the actions run when the threshold is exceeded: the
+// legacy command, the enabled actions in order, and the accountability email
+// (or webhook) unless a notify action already sends it.
+func violationActions(cfg *config.Config) []config.ViolationAction {
+	var actions []config.ViolationAction
+	if parts := strings.Fields(cfg.ViolationTracking.Command); len(parts) > 0 {
+		actions = append(actions, config.ViolationAction{Type: config.ViolationActionCommand, Command: parts[0], Args: parts[1:]})
 	}
 
-	cmd := exec.Command(parts[0], parts[1:]...)
-	if err := cmd.Run(); err != nil {
-		log.Printf("Failed to execute violation command: %v", err)
-	} else {
-		log.Printf("Violation command executed successfully")
+	notifies := false
+	for _, action := range cfg.ViolationTracking.Actions {
+		if !action.IsEnabled() {
+			continue
+		}
+		actions = append(actions, action)
+		notifies = notifies || action.Type == config.ViolationActionNotify
+	}
+
+	if cfg.NotifiesPartner() && !notifies {
+		actions = append(actions, config.ViolationAction{Type: config.ViolationActionNotify})
+	}
+	return actions
+}
+
+// runViolationActions runs actions in order. A failing action is logged and
+// doesn't stop the ones after it.
+func runViolationActions(cfg *config.Config, actions []config.ViolationAction, count int) {
+	for i, action := range actions {
+		if err := runViolationAction(cfg, action, count); err != nil {
+			log.Printf("Violation action %d (%s) failed: %v", i+1, action.Type, err)
+			continue
+		}
+		slog.Debug("Violation action done", "index", i+1, "type", action.Type)
+	}
+}
+
+// runViolationAction runs a single threshold action.
+func runViolationAction(cfg *config.Config, action config.ViolationAction, count int) error {
+	switch action.Type {
+	case config.ViolationActionCommand:
+		if err := runCommand(action.Command, action.Args...); err != nil {
+			return fmt.Errorf("failed to execute %s: %w", action.Command, err)
+		}
+		log.Printf("Violation command %s executed successfully", action.Command)
+		return nil
+	case config.ViolationActionLock:
+		return lockScreen(cfg)
+	case config.ViolationActionSuspend:
+		command := action.Command
+		if command == "" {
+			command = cfg.PanicCommand
+		}
+		return suspend(command)
+	case config.ViolationActionNotify:
+		return sendEmail(cfg, count)
+	default:
+		return fmt.Errorf("unknown action type %q", action.Type)
 	}
 }
 
 // sendViolationEmail sends an email notification about violation threshold being exceeded.
-func sendViolationEmail(cfg *config.Config, count int) {
+func sendViolationEmail(cfg *config.Config, count int) error {
 	subject := "GLOCKER ALERT: Violation Threshold Exceeded"
-	body := fmt.Sprintf("Violation threshold was exceeded at %s.\n\n", time.Now().Format("2006-01-02 15:04:05"))
+	body := fmt.Sprintf("Violation threshold was exceeded at %s.\n\n", clock.Now().Format("2006-01-02 15:04:05"))
 	body += fmt.Sprintf("Recent violations: %d/%d in last %d minutes\n\n",
 		count, cfg.ViolationTracking.MaxViolations, cfg.ViolationTracking.TimeWindowMinutes)
 	body += "This is an automated alert from Glocker."
 
-	notify.SendEmail(cfg, subject, body)
+	return notify.SendEmail(cfg, notify.EventViolationThreshold, subject, body)
+}
+
+// escalateProfile switches to the configured escalation profile if it isn't active yet.
+// The enforcement loop picks up the profile switch and rebuilds the block list.
+func escalateProfile(cfg *config.Config, count int, now time.Time) {
+	profile := cfg.ViolationTracking.EscalationProfile
+	if active, _ := state.GetActiveProfile(); active == profile {
+		return
+	}
+	if _, ok := cfg.Profiles[profile]; !ok {
+		log.Printf("Cannot escalate: profile %q is not defined", profile)
+		return
+	}
+
+	state.SetActiveProfile(profile, now)
+	log.Printf("ESCALATION: switched to profile %q after %d violations (active until daily reset)", profile, count)
+
+	notify.SendNotification(cfg, "Glocker Alert",
+		fmt.Sprintf("Too many violations - %s profile active until tomorrow", profile),
+		"critical", "dialog-warning")
+
+	if cfg.NotifiesPartner() {
+		subject := "GLOCKER ALERT: Strict Profile Activated"
+		body := fmt.Sprintf("Glocker escalated to the %q profile at %s.\n\n", profile, now.Format("2006-01-02 15:04:05"))
+		body += fmt.Sprintf("Recent violations: %d/%d in last %d minutes\n",
+			count, cfg.ViolationTracking.MaxViolations, cfg.ViolationTracking.TimeWindowMinutes)
+		body += "The profile stays active until the daily violation reset.\n\n"
+		body += "This is an automated alert from Glocker."
+		if err := notify.SendEmail(cfg, notify.EventStrictProfile, subject, body); err != nil {
+			log.Printf("Failed to send escalation email: %v", err)
+		}
+	}
+}
+
+// revertEscalation switches back to the base config if the escalation profile is active.
+func revertEscalation(cfg *config.Config) {
+	profile := cfg.ViolationTracking.EscalationProfile
+	active, since := state.GetActiveProfile()
+	if profile == "" || active != profile {
+		return
+	}
+
+	state.SetActiveProfile("", time.Time{})
+	log.Printf("ESCALATION REVERTED: profile %q deactivated at daily reset (active since %s)",
+		profile, since.Format("2006-01-02 15:04"))
 }
 
 // MonitorViolations monitors and automatically resets violations daily.
@@ -124,13 +287,22 @@ func MonitorViolations(cfg *config.Config) {
 	defer ticker.Stop()
 
 	for range ticker.C {
-		now := time.Now()
-		lastReset := state.GetLastViolationReset()
+		checkDailyViolationReset(cfg)
+	}
+}
 
-		// Reset violations at midnight
-		if lastReset.IsZero() || (now.Day() != lastReset.Day() && now.Hour() == 0) {
-			state.ClearViolations()
-			log.Printf("Violations reset at daily boundary")
-		}
+// checkDailyViolationReset clears the violations and reverts escalation in the
+// first check after midnight, and on the first check ever. Reports whether it reset.
+func checkDailyViolationReset(cfg *config.Config) bool {
+	now := clock.Now()
+	lastReset := state.GetLastViolationReset()
+
+	// Reset violations at midnight
+	if lastReset.IsZero() || (now.Day() != lastReset.Day() && now.Hour() == 0) {
+		state.ClearViolations()
+		log.Printf("Violations reset at daily boundary")
+		revertEscalation(cfg)
+		return true
 	}
+	return false
 }
@@ -0,0 +1,130 @@
+package monitoring
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+// Events that mean a watched file's content may have changed. Directories are
+// watched rather than the files themselves, so files replaced by rename (as most
+// editors save) or deleted and recreated are still seen.
+const watchMask = syscall.IN_MODIFY | syscall.IN_DELETE | syscall.IN_CREATE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+// FileWatcher reports changes to a set of files as they happen, using inotify.
+type FileWatcher struct {
+	file  *os.File
+	dirs  map[int32]string // Watch descriptor to directory
+	files map[string]bool  // Cleaned paths being watched
+	done  chan struct{}
+}
+
+// WatchFiles starts watching paths and calls onChange with the path of a watched
+// file whenever it is modified, deleted, created or renamed. onChange runs on the
+// watcher's goroutine, so it should return quickly.
+func WatchFiles(paths []string, onChange func(path string)) (*FileWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_NONBLOCK | syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize inotify: %w", err)
+	}
+
+	// A non-blocking fd goes through the runtime poller, so Close unblocks Read
+	w := &FileWatcher{
+		file:  os.NewFile(uintptr(fd), "inotify"),
+		dirs:  make(map[int32]string),
+		files: make(map[string]bool),
+		done:  make(chan struct{}),
+	}
+
+	watched := make(map[string]bool)
+	for _, path := range paths {
+		path = filepath.Clean(path)
+		w.files[path] = true
+
+		dir := filepath.Dir(path)
+		if watched[dir] {
+			continue
+		}
+		wd, err := syscall.InotifyAddWatch(fd, dir, watchMask)
+		if err != nil {
+			w.file.Close()
+			return nil, fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+		w.dirs[int32(wd)] = dir
+		watched[dir] = true
+	}
+
+	go w.run(onChange)
+	return w, nil
+}
+
+// run reads inotify events until the watcher is closed.
+func (w *FileWatcher) run(onChange func(path string)) {
+	defer close(w.done)
+
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				slog.Debug("File watcher stopped", "error", err)
+			}
+			return
+		}
+
+		for _, path := range w.parseEvents(buf[:n]) {
+			onChange(path)
+		}
+	}
+}
+
+// parseEvents returns the watched paths named by the inotify events in buf.
+func (w *FileWatcher) parseEvents(buf []byte) []string {
+	var paths []string
+	for offset := 0; offset+syscall.SizeofInotifyEvent <= len(buf); {
+		event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+		nameStart := offset + syscall.SizeofInotifyEvent
+		nameEnd := nameStart + int(event.Len)
+		if nameEnd > len(buf) {
+			break
+		}
+		offset = nameEnd
+
+		if event.Mask&syscall.IN_Q_OVERFLOW != 0 {
+			// Events were dropped; report every file so nothing is missed
+			for path := range w.files {
+				paths = append(paths, path)
+			}
+			continue
+		}
+
+		dir, ok := w.dirs[event.Wd]
+		if !ok || event.Len == 0 {
+			continue
+		}
+		name := string(buf[nameStart:nameEnd])
+		for i := 0; i < len(name); i++ {
+			if name[i] == 0 {
+				name = name[:i] // Names are NUL-padded
+				break
+			}
+		}
+
+		if path := filepath.Join(dir, name); w.files[path] {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// Close stops the watcher and waits for its goroutine to exit.
+func (w *FileWatcher) Close() error {
+	err := w.file.Close()
+	<-w.done
+	return err
+}
@@ -0,0 +1,166 @@
+package monitoring
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"glocker/internal/config"
+	"glocker/internal/notify"
+	"glocker/internal/reports"
+	"glocker/internal/utils"
+)
+
+const (
+	// weeklyReportGrace is how long after its scheduled time a missed weekly report
+	// (daemon down or machine off) is still sent.
+	weeklyReportGrace = 24 * time.Hour
+
+	// weeklyReportRetry is how long to wait before retrying a report that failed to send.
+	weeklyReportRetry = time.Hour
+
+	// weeklyReportTopN is how many keywords and domains the weekly report lists.
+	weeklyReportTopN = 5
+)
+
+// MonitorWeeklyReport runs a background goroutine that emails a summary of the past
+// week at the configured day and time. A report missed while the daemon was down is
+// sent once on startup if the scheduled time passed less than weeklyReportGrace ago.
+func MonitorWeeklyReport(cfg *config.Config) {
+	if !cfg.Accountability.WeeklyReportEnabled {
+		return
+	}
+
+	day, reportTime, err := cfg.Accountability.WeeklyReportSchedule()
+	if err != nil {
+		log.Printf("Weekly report disabled: %v", err)
+		return
+	}
+
+	newWeeklyReporter(clock, day, reportTime, config.WeeklyReportStateFile, func(weekStart, weekEnd time.Time) error {
+		return sendWeeklyReport(cfg, weekStart, weekEnd)
+	}).run()
+}
+
+// newWeeklyReporter returns a reporter that sends the report of the week ending
+// at each scheduled day and reportTime (HH:MM) with send.
+func newWeeklyReporter(clock utils.TimeProvider, day time.Weekday, reportTime, stateFile string, send func(weekStart, weekEnd time.Time) error) *periodReporter {
+	return &periodReporter{
+		name:      "weekly",
+		clock:     clock,
+		retry:     weeklyReportRetry,
+		stateFile: stateFile,
+		due: func(now, lastSent time.Time) (time.Time, bool) {
+			return weeklyReportDue(now, day, reportTime, lastSent)
+		},
+		send: func(scheduled time.Time) error {
+			return send(scheduled.AddDate(0, 0, -7), scheduled)
+		},
+	}
+}
+
+// lastWeeklySchedule returns the most recent scheduled weekly report time at or before now.
+func lastWeeklySchedule(now time.Time, day time.Weekday, reportTime string) time.Time {
+	hour, minute := 0, 0
+	if t, err := time.Parse("15:04", reportTime); err == nil {
+		hour, minute = t.Hour(), t.Minute()
+	}
+
+	daysBack := (int(now.Weekday()) - int(day) + 7) % 7
+	scheduled := time.Date(now.Year(), now.Month(), now.Day()-daysBack, hour, minute, 0, 0, now.Location())
+	if scheduled.After(now) {
+		scheduled = time.Date(now.Year(), now.Month(), now.Day()-daysBack-7, hour, minute, 0, 0, now.Location())
+	}
+	return scheduled
+}
+
+// weeklyReportDue returns the most recent scheduled report time and whether the report
+// for it should be sent now: it hasn't been sent (lastSent is before it) and the
+// scheduled time passed less than weeklyReportGrace ago.
+func weeklyReportDue(now time.Time, day time.Weekday, reportTime string, lastSent time.Time) (time.Time, bool) {
+	scheduled := lastWeeklySchedule(now, day, reportTime)
+	if !lastSent.Before(scheduled) {
+		return scheduled, false
+	}
+	return scheduled, now.Sub(scheduled) < weeklyReportGrace
+}
+
+// sendWeeklyReport emails the summary of violations and unblocks between weekStart and weekEnd.
+func sendWeeklyReport(cfg *config.Config, weekStart, weekEnd time.Time) error {
+	windowEnd := weekEnd.Add(-time.Second)
+
+	violations, _, _ := reports.LoadReports()
+	violations = reports.FilterReports(violations, reports.ReportFilter{
+		StartTime: &weekStart,
+		EndTime:   &windowEnd,
+	})
+
+	unblocks, _ := reports.LoadUnblocks()
+	unblocks = reports.FilterUnblocks(unblocks, reports.UnblockFilter{
+		StartTime: &weekStart,
+		EndTime:   &windowEnd,
+	})
+
+	subject, body := buildWeeklyReport(violations, unblocks, weekStart, weekEnd)
+	return notify.SendEmail(cfg, notify.EventWeeklyReport, subject, body)
+}
+
+// buildWeeklyReport formats the weekly summary: totals, top keywords and domains,
+// and a day-of-week breakdown.
+func buildWeeklyReport(violations []reports.ReportEntry, unblocks []reports.UnblockEntry, weekStart, weekEnd time.Time) (string, string) {
+	violationSummary := reports.SummarizeReports(violations)
+	unblockSummary := reports.SummarizeUnblocks(unblocks)
+	lastDay := weekEnd.Add(-time.Second)
+
+	var body strings.Builder
+	body.WriteString(fmt.Sprintf("Weekly Glocker Report for %s - %s\n", weekStart.Format("Mon Jan 2"), lastDay.Format("Mon Jan 2, 2006")))
+	body.WriteString("=" + strings.Repeat("=", 50) + "\n\n")
+
+	body.WriteString("SUMMARY\n")
+	body.WriteString(strings.Repeat("-", 30) + "\n")
+	body.WriteString(fmt.Sprintf("Violations:     %d\n", violationSummary.TotalCount))
+	body.WriteString(fmt.Sprintf("Unblocks:       %d\n", unblockSummary.TotalCount))
+	body.WriteString("\n")
+
+	sections := []struct {
+		title  string
+		counts map[string]int
+	}{
+		{"TOP KEYWORDS", violationSummary.ByKeyword},
+		{"TOP BLOCKED DOMAINS", violationSummary.ByDomain},
+		{"TOP UNBLOCKED DOMAINS", unblockSummary.ByDomain},
+	}
+	for _, section := range sections {
+		if len(section.counts) == 0 {
+			continue
+		}
+		body.WriteString(section.title + "\n")
+		body.WriteString(strings.Repeat("-", 30) + "\n")
+		for _, item := range reports.TopN(section.counts, weeklyReportTopN) {
+			body.WriteString(fmt.Sprintf("  %s: %d\n", item.Name, item.Count))
+		}
+		body.WriteString("\n")
+	}
+
+	// Day-of-week breakdown, in the order the days occurred
+	var violationsByDay, unblocksByDay [7]int
+	for _, v := range violations {
+		violationsByDay[v.Timestamp.Weekday()]++
+	}
+	for _, u := range unblocks {
+		unblocksByDay[u.UnblockTime.Weekday()]++
+	}
+	body.WriteString("BY DAY\n")
+	body.WriteString(strings.Repeat("-", 30) + "\n")
+	body.WriteString("       Violations  Unblocks\n")
+	for i := 0; i < 7; i++ {
+		day := weekStart.AddDate(0, 0, i).Weekday()
+		body.WriteString(fmt.Sprintf("  %s  %10d  %8d\n", day.String()[:3], violationsByDay[day], unblocksByDay[day]))
+	}
+	body.WriteString("\n")
+	body.WriteString("This is an automated report from Glocker.")
+
+	subject := fmt.Sprintf("Glocker Weekly Report: %s - %s", weekStart.Format("Jan 2"), lastDay.Format("Jan 2"))
+	return subject, body.String()
+}
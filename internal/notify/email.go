@@ -4,19 +4,23 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"math/rand/v2"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/mailgun/mailgun-go/v4"
-
 	"glocker/internal/config"
 	"glocker/internal/state"
 )
 
-// SendEmail sends an email notification via Mailgun with rate limiting.
-// Returns nil if email is disabled, in dev mode, or rate limited.
-func SendEmail(cfg *config.Config, subject, body string) error {
-	if !cfg.Accountability.Enabled {
+// SendEmail queues an email notification for the background sender with rate limiting,
+// and POSTs it to the webhook as event when one is configured. Delivery, with its
+// retries, and the POST happen off the caller's goroutine; an email that can't be
+// delivered is spooled and resent after the next successful send. Webhook failures
+// are only logged. Returns an error only when the email can't be queued at all;
+// returns nil if both are disabled, in dev mode, or rate limited.
+func SendEmail(cfg *config.Config, event Event, subject, body string) error {
+	if !cfg.NotifiesPartner() {
 		return nil
 	}
 
@@ -35,36 +39,193 @@ func SendEmail(cfg *config.Config, subject, body string) error {
 	}
 	state.SetLastEmailTime(subject, now)
 
-	from := cfg.Accountability.FromEmail
-	to := cfg.Accountability.PartnerEmail
-	apiKey := cfg.Accountability.ApiKey
-	log.Printf("Sending email from %s to %s subject %s", from, to, subject)
+	if cfg.Notifications.WebhookURL != "" {
+		notificationsInFlight.Add(1)
+		go func() {
+			defer notificationsInFlight.Done()
+			if err := SendWebhook(cfg, event, subject, body, now); err != nil {
+				log.Printf("Warning: Failed to send webhook - Subject: %s: %v", subject, err)
+			}
+		}()
+	}
 
-	mg := mailgun.NewMailgun("noufalibrahim.name", apiKey)
+	if !cfg.Accountability.Enabled {
+		return nil
+	}
 
-	// Convert plain text body to HTML
-	htmlBody := GenerateHTMLEmail(subject, body)
+	recipients := cfg.Accountability.Recipients()
+	if len(recipients) == 0 {
+		return fmt.Errorf("no partner email configured (accountability.partner_email)")
+	}
 
-	mail := mailgun.NewMessage(
-		from,
-		subject,
-		body, // Keep plain text as fallback
-		to,
-	)
+	log.Printf("Queueing email from %s to %s subject %s", cfg.Accountability.FromEmail, strings.Join(recipients, ", "), subject)
+	return queueEmail(outgoingEmail{cfg: cfg, subject: subject, body: body, queuedAt: now})
+}
 
-	// Set HTML content
-	mail.SetHTML(htmlBody)
+// outgoingEmail is an email waiting for the background sender.
+type outgoingEmail struct {
+	cfg      *config.Config
+	subject  string
+	body     string
+	queuedAt time.Time
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
-	defer cancel()
-	_, _, err := mg.Send(ctx, mail)
+// emailQueueSize bounds the emails waiting for the background sender. When it
+// is full, emails go straight to the pending spool.
+const emailQueueSize = 64
 
-	if err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
+var (
+	emailQueue  = make(chan outgoingEmail, emailQueueSize)
+	startSender sync.Once
+
+	// notificationsInFlight counts queued emails not yet delivered or spooled
+	// and webhook POSTs not yet finished.
+	notificationsInFlight sync.WaitGroup
+)
+
+// queueEmail hands email to the background sender, starting it on first use.
+func queueEmail(email outgoingEmail) error {
+	startSender.Do(func() { go runEmailSender() })
+
+	notificationsInFlight.Add(1)
+	select {
+	case emailQueue <- email:
+		return nil
+	default:
+		notificationsInFlight.Done()
+	}
+
+	log.Printf("Email queue full, spooling - Subject: %s", email.subject)
+	if err := spoolEmail(email.subject, email.body, email.queuedAt); err != nil {
+		return fmt.Errorf("failed to queue email: %w", err)
 	}
 	return nil
 }
 
+// runEmailSender delivers queued emails one at a time, so retries and backoff
+// never hold up the code that sent them.
+func runEmailSender() {
+	for email := range emailQueue {
+		deliverQueuedEmail(email)
+		notificationsInFlight.Done()
+	}
+}
+
+// deliverQueuedEmail sends email with retries, spooling it if every attempt
+// fails and resending the spool once a send succeeds.
+func deliverQueuedEmail(email outgoingEmail) {
+	err := deliverEmailWithRetry(email.cfg, email.subject, email.body)
+	state.RecordEmailResult(err)
+	if err != nil {
+		log.Printf("Failed to send email - Subject: %s: %v", email.subject, err)
+		if spoolErr := spoolEmail(email.subject, email.body, email.queuedAt); spoolErr != nil {
+			log.Printf("Failed to queue undelivered email: %v", spoolErr)
+		} else {
+			log.Printf("Email queued for delivery after the next successful send - Subject: %s", email.subject)
+		}
+		return
+	}
+
+	// The connection works again, so deliver anything that failed earlier
+	flushPendingEmails(email.cfg)
+}
+
+// WaitForEmails waits up to timeout for queued emails to be delivered or
+// spooled and for webhook POSTs to finish, so alerts sent just before the
+// daemon exits aren't lost. Returns whether they were all done in time.
+func WaitForEmails(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		notificationsInFlight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// emailRetryBase is the wait before the first retry of a failed email. Each
+// further retry waits twice as long, plus up to 50% random jitter.
+const emailRetryBase = 2 * time.Second
+
+// sleep waits between email retries. Replaced in tests.
+var sleep = time.Sleep
+
+// deliverEmailWithRetry sends an email, retrying failed sends up to
+// accountability.max_retries times with exponential backoff.
+func deliverEmailWithRetry(cfg *config.Config, subject, body string) error {
+	retries := cfg.Accountability.EmailMaxRetries()
+	var err error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			delay := emailRetryDelay(attempt)
+			log.Printf("Email send failed (%v), retry %d of %d in %v", err, attempt, retries, delay.Round(time.Millisecond))
+			sleep(delay)
+		}
+		if err = deliverEmail(cfg, subject, body); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// emailRetryDelay returns the backoff before the given retry (starting at 1).
+func emailRetryDelay(retry int) time.Duration {
+	delay := emailRetryBase << (retry - 1)
+	return delay + rand.N(delay/2+1)
+}
+
+// SendTestEmail sends a fixed verification message, bypassing rate limiting, so the
+// accountability settings can be checked before relying on them.
+// Returns a confirmation, or in dev mode a description of what would be sent.
+func SendTestEmail(cfg *config.Config) (string, error) {
+	if !cfg.Accountability.Enabled {
+		return "", fmt.Errorf("accountability is disabled (set accountability.enabled: true in %s)", config.ConfigPath())
+	}
+
+	subject := "GLOCKER TEST: Email Verification"
+	body := fmt.Sprintf("This is a test message sent by 'glocker -test-email' at %s.\n\n", time.Now().Format("2006-01-02 15:04:05"))
+	body += fmt.Sprintf("From: %s\n", cfg.Accountability.FromEmail)
+	recipients := strings.Join(cfg.Accountability.Recipients(), ", ")
+	body += fmt.Sprintf("To: %s\n", recipients)
+	body += "\nIf you received this, accountability emails are configured correctly."
+
+	if cfg.Dev {
+		return fmt.Sprintf("DEV MODE: would send email via %s\n  From: %s\n  To: %s\n  Subject: %s\n\n%s",
+			providerName(cfg), cfg.Accountability.FromEmail, recipients, subject, body), nil
+	}
+
+	if err := deliverEmail(cfg, subject, body); err != nil {
+		return "", fmt.Errorf("failed to send test email: %w", err)
+	}
+	return fmt.Sprintf("test email accepted by %s for delivery to %s", providerName(cfg), recipients), nil
+}
+
+// deliverEmail composes a plain text and HTML message and hands it to the configured provider.
+func deliverEmail(cfg *config.Config, subject, body string) error {
+	recipients := cfg.Accountability.Recipients()
+	if len(recipients) == 0 {
+		return fmt.Errorf("no partner email configured (accountability.partner_email)")
+	}
+
+	provider, err := newEmailProvider(cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+	return provider.Send(ctx,
+		cfg.Accountability.FromEmail,
+		recipients,
+		subject,
+		body, // Keep plain text as fallback
+		GenerateHTMLEmail(subject, body))
+}
+
 // GenerateHTMLEmail creates a styled HTML email from plain text content.
 // Escapes HTML characters and applies styling based on the subject line.
 func GenerateHTMLEmail(subject, plainBody string) string {
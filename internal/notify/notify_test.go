@@ -1,11 +1,25 @@
 package notify
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"glocker/internal/config"
+	"glocker/internal/schema"
 	"glocker/internal/state"
 )
 
@@ -16,7 +30,7 @@ func TestSendEmail_Disabled(t *testing.T) {
 		},
 	}
 
-	err := SendEmail(cfg, "Test Subject", "Test Body")
+	err := SendEmail(cfg, EventTamperDetected, "Test Subject", "Test Body")
 	if err != nil {
 		t.Errorf("Expected nil error when accountability disabled, got %v", err)
 	}
@@ -30,7 +44,7 @@ func TestSendEmail_DevMode(t *testing.T) {
 		},
 	}
 
-	err := SendEmail(cfg, "Test Subject", "Test Body")
+	err := SendEmail(cfg, EventTamperDetected, "Test Subject", "Test Body")
 	if err != nil {
 		t.Errorf("Expected nil error in dev mode, got %v", err)
 	}
@@ -53,7 +67,7 @@ func TestSendEmail_RateLimiting(t *testing.T) {
 	}
 
 	// Should be rate limited since we just sent
-	err := SendEmail(cfg, subject, "Test Body")
+	err := SendEmail(cfg, EventTamperDetected, subject, "Test Body")
 	if err != nil {
 		t.Errorf("Expected nil error when rate limited, got %v", err)
 	}
@@ -68,6 +82,422 @@ func TestSendEmail_RateLimiting(t *testing.T) {
 	}
 }
 
+// sentEmail is a message captured by fakeProvider.
+type sentEmail struct {
+	From, To, Subject, TextBody, HTMLBody string
+}
+
+// fakeProvider records messages instead of delivering them.
+type fakeProvider struct {
+	sent     []sentEmail
+	err      error
+	failures int // Sends that fail with a network error before sends succeed
+	attempts int
+}
+
+func (f *fakeProvider) Send(ctx context.Context, from string, to []string, subject, textBody, htmlBody string) error {
+	f.attempts++
+	if f.failures > 0 {
+		f.failures--
+		return errors.New("dial tcp: connection refused")
+	}
+	f.sent = append(f.sent, sentEmail{from, strings.Join(to, ", "), subject, textBody, htmlBody})
+	return f.err
+}
+
+// useFakeProvider swaps in a fake provider for the duration of a test, with
+// retries that don't wait and a temporary pending emails file.
+func useFakeProvider(t *testing.T, provider *fakeProvider) {
+	originalProvider, originalSleep, originalSpool := newEmailProvider, sleep, pendingEmailsFile
+	newEmailProvider = func(cfg *config.Config) (EmailProvider, error) { return provider, nil }
+	sleep = func(time.Duration) {}
+	pendingEmailsFile = filepath.Join(t.TempDir(), "pending_emails.jsonl")
+	t.Cleanup(func() { newEmailProvider, sleep, pendingEmailsFile = originalProvider, originalSleep, originalSpool })
+}
+
+// waitForEmails waits for the background sender to deliver or spool the queued emails.
+func waitForEmails(t *testing.T) {
+	t.Helper()
+	if !WaitForEmails(5 * time.Second) {
+		t.Fatal("Timed out waiting for queued emails")
+	}
+}
+
+// retryTestConfig returns an enabled accountability config with the given retry limit.
+func retryTestConfig(maxRetries int) *config.Config {
+	return &config.Config{
+		Accountability: config.AccountabilityConfig{
+			Enabled:      true,
+			FromEmail:    "glocker@example.com",
+			PartnerEmail: "partner@example.com",
+			MaxRetries:   &maxRetries,
+		},
+	}
+}
+
+func TestSendEmail_RetriesThenSucceeds(t *testing.T) {
+	provider := &fakeProvider{failures: 2}
+	useFakeProvider(t, provider)
+
+	var delays []time.Duration
+	sleep = func(d time.Duration) { delays = append(delays, d) }
+
+	if err := SendEmail(retryTestConfig(3), EventTamperDetected, "Retry Success Test", "body"); err != nil {
+		t.Fatalf("Expected the send to succeed on the third attempt, got %v", err)
+	}
+	waitForEmails(t)
+	if provider.attempts != 3 || len(provider.sent) != 1 {
+		t.Errorf("Expected 3 attempts and 1 delivery, got %d attempts and %d deliveries", provider.attempts, len(provider.sent))
+	}
+
+	// Exponential backoff with up to 50% jitter: 2-3s, then 4-6s
+	if len(delays) != 2 {
+		t.Fatalf("Expected 2 backoff waits, got %v", delays)
+	}
+	for i, d := range delays {
+		base := emailRetryBase << i
+		if d < base || d > base+base/2 {
+			t.Errorf("Retry %d waited %v, want between %v and %v", i+1, d, base, base+base/2)
+		}
+	}
+	if pending, _ := readPendingEmails(); len(pending) != 0 {
+		t.Errorf("Nothing should be spooled after a successful send, got %d", len(pending))
+	}
+}
+
+func TestSendEmail_SpoolsAfterFinalFailure(t *testing.T) {
+	provider := &fakeProvider{failures: 10}
+	useFakeProvider(t, provider)
+
+	if err := SendEmail(retryTestConfig(2), EventTamperDetected, "Spool Test Tamper", "tamper body"); err != nil {
+		t.Fatalf("Expected the email to be queued, got %v", err)
+	}
+	waitForEmails(t)
+	if provider.attempts != 3 {
+		t.Errorf("Expected 1 attempt plus 2 retries, got %d attempts", provider.attempts)
+	}
+
+	pending, err := readPendingEmails()
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("Expected 1 spooled email, got %d (%v)", len(pending), err)
+	}
+	if pending[0].Subject != "Spool Test Tamper" || pending[0].Body != "tamper body" {
+		t.Errorf("Unexpected spooled email: %+v", pending[0])
+	}
+
+	// The next successful send delivers the spooled email too and empties the spool
+	provider.failures = 0
+	if err := SendEmail(retryTestConfig(2), EventTamperDetected, "Spool Test Recovery", "recovered"); err != nil {
+		t.Fatalf("Expected the next send to succeed, got %v", err)
+	}
+	waitForEmails(t)
+	if len(provider.sent) != 2 {
+		t.Fatalf("Expected the new email and the spooled one, got %d", len(provider.sent))
+	}
+	resent := provider.sent[1]
+	if resent.Subject != "Spool Test Tamper" || !strings.Contains(resent.TextBody, "could not be delivered") || !strings.Contains(resent.TextBody, "tamper body") {
+		t.Errorf("Unexpected resent email: %+v", resent)
+	}
+	if _, err := os.Stat(pendingEmailsFile); !os.IsNotExist(err) {
+		t.Error("Spool file should be removed once every pending email is sent")
+	}
+}
+
+func TestSendEmail_DoesNotWaitForRetries(t *testing.T) {
+	provider := &fakeProvider{failures: 1}
+	useFakeProvider(t, provider)
+
+	// The retry backoff blocks until released; the caller mustn't
+	release := make(chan struct{})
+	sleep = func(time.Duration) { <-release }
+
+	sent := make(chan error, 1)
+	go func() { sent <- SendEmail(retryTestConfig(1), EventTamperDetected, "Slow Send Test", "body") }()
+	select {
+	case err := <-sent:
+		if err != nil {
+			t.Errorf("SendEmail() error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("SendEmail waited for the retry backoff")
+	}
+
+	close(release)
+	waitForEmails(t)
+	if len(provider.sent) != 1 {
+		t.Errorf("Expected the retry to deliver the email, got %d", len(provider.sent))
+	}
+}
+
+func TestSpoolEmail_CapsPending(t *testing.T) {
+	useFakeProvider(t, &fakeProvider{})
+
+	queuedAt := time.Date(2026, 1, 6, 9, 0, 0, 0, time.Local)
+	for i := range maxPendingEmails + 5 {
+		if err := spoolEmail(fmt.Sprintf("email %d", i), "body", queuedAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pending, err := readPendingEmails()
+	if err != nil || len(pending) != maxPendingEmails {
+		t.Fatalf("Expected %d spooled emails, got %d (%v)", maxPendingEmails, len(pending), err)
+	}
+	if pending[0].Subject != "email 5" {
+		t.Errorf("Expected the oldest emails to be dropped, first is %q", pending[0].Subject)
+	}
+}
+
+func TestFlushPendingEmails_KeepsUnsent(t *testing.T) {
+	provider := &fakeProvider{}
+	useFakeProvider(t, provider)
+
+	queuedAt := time.Date(2026, 1, 6, 9, 0, 0, 0, time.Local)
+	for _, subject := range []string{"first", "second", "third"} {
+		if err := spoolEmail(subject, "body", queuedAt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The connection is still down: the first resend fails and nothing is removed
+	provider.err = errors.New("connection reset")
+	flushPendingEmails(retryTestConfig(0))
+
+	pending, _ := readPendingEmails()
+	if len(pending) != 3 || pending[0].Subject != "first" {
+		t.Errorf("A failed resend should keep every email from it on, got %+v", pending)
+	}
+
+	provider.err = nil
+	flushPendingEmails(retryTestConfig(0))
+	if pending, _ := readPendingEmails(); len(pending) != 0 {
+		t.Errorf("Expected an empty spool, got %d", len(pending))
+	}
+	// One failed attempt, then all three in order
+	if provider.attempts != 4 || provider.sent[1].Subject != "first" || provider.sent[3].Subject != "third" {
+		t.Errorf("Expected the spooled emails resent in order, got %d attempts", provider.attempts)
+	}
+}
+
+func TestSendTestEmail(t *testing.T) {
+	provider := &fakeProvider{}
+	useFakeProvider(t, provider)
+
+	cfg := &config.Config{
+		Accountability: config.AccountabilityConfig{
+			Enabled:      true,
+			FromEmail:    "glocker@example.com",
+			PartnerEmail: "partner@example.com",
+			ApiKey:       "test-api-key",
+		},
+	}
+
+	response, err := SendTestEmail(cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(response, "mailgun") {
+		t.Errorf("Expected response to name the provider, got %q", response)
+	}
+
+	if len(provider.sent) != 1 {
+		t.Fatalf("Expected 1 message sent, got %d", len(provider.sent))
+	}
+	msg := provider.sent[0]
+	if msg.From != "glocker@example.com" || msg.To != "partner@example.com" {
+		t.Errorf("Unexpected addresses: from %q to %q", msg.From, msg.To)
+	}
+	if msg.Subject != "GLOCKER TEST: Email Verification" {
+		t.Errorf("Unexpected subject %q", msg.Subject)
+	}
+	if !strings.Contains(msg.TextBody, "glocker -test-email") {
+		t.Errorf("Text body should describe the test, got %q", msg.TextBody)
+	}
+	if !strings.Contains(msg.HTMLBody, "<html") {
+		t.Error("HTML body should be generated")
+	}
+}
+
+func TestSendEmail_MultipleRecipients(t *testing.T) {
+	provider := &fakeProvider{}
+	useFakeProvider(t, provider)
+
+	cfg := retryTestConfig(0)
+	cfg.Accountability.PartnerEmail = "spouse@example.com, sponsor@example.com"
+	cfg.Accountability.PartnerEmails = []string{"coach@example.com", "Sponsor@example.com"}
+
+	if err := SendEmail(cfg, EventTamperDetected, "Multi Recipient Test", "body"); err != nil {
+		t.Fatalf("SendEmail failed: %v", err)
+	}
+	waitForEmails(t)
+	if len(provider.sent) != 1 {
+		t.Fatalf("Expected one message for all recipients, got %d", len(provider.sent))
+	}
+	if to := provider.sent[0].To; to != "spouse@example.com, sponsor@example.com, coach@example.com" {
+		t.Errorf("Message addressed to %q", to)
+	}
+
+	// The SMTP message carries every recipient in its To header
+	raw, err := buildMIMEMessage(cfg.Accountability.FromEmail, cfg.Accountability.Recipients(), "subject", "text", "<p>html</p>", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addresses, err := msg.Header.AddressList("To")
+	if err != nil || len(addresses) != 3 || addresses[2].Address != "coach@example.com" {
+		t.Errorf("Unexpected To header %q (err %v)", msg.Header.Get("To"), err)
+	}
+}
+
+func TestSendEmail_NoRecipients(t *testing.T) {
+	provider := &fakeProvider{}
+	useFakeProvider(t, provider)
+
+	cfg := retryTestConfig(0)
+	cfg.Accountability.PartnerEmail = ""
+	if err := SendEmail(cfg, EventTamperDetected, "No Recipient Test", "body"); err == nil {
+		t.Error("Expected an error without a partner email")
+	}
+	if provider.attempts != 0 {
+		t.Error("Nothing should be handed to the provider without recipients")
+	}
+}
+
+func TestSendTestEmail_Disabled(t *testing.T) {
+	provider := &fakeProvider{}
+	useFakeProvider(t, provider)
+
+	_, err := SendTestEmail(&config.Config{})
+	if err == nil {
+		t.Fatal("Expected error when accountability is disabled")
+	}
+	if len(provider.sent) != 0 {
+		t.Error("No message should be sent when accountability is disabled")
+	}
+}
+
+func TestSendTestEmail_DevModeAndErrors(t *testing.T) {
+	provider := &fakeProvider{err: errors.New("mailgun returned HTTP status 401: unauthorized")}
+	useFakeProvider(t, provider)
+
+	cfg := &config.Config{
+		Dev: true,
+		Accountability: config.AccountabilityConfig{
+			Enabled:      true,
+			PartnerEmail: "partner@example.com",
+		},
+	}
+
+	response, err := SendTestEmail(cfg)
+	if err != nil {
+		t.Fatalf("Dev mode should not fail: %v", err)
+	}
+	if !strings.Contains(response, "DEV MODE") || !strings.Contains(response, "partner@example.com") {
+		t.Errorf("Dev mode should describe the message, got %q", response)
+	}
+	if len(provider.sent) != 0 {
+		t.Error("Dev mode should not send")
+	}
+
+	cfg.Dev = false
+	_, err = SendTestEmail(cfg)
+	if err == nil || !strings.Contains(err.Error(), "HTTP status 401") {
+		t.Errorf("Expected provider error with HTTP status, got %v", err)
+	}
+}
+
+func TestNewEmailProvider(t *testing.T) {
+	tests := []struct {
+		provider string
+		wantType string
+		wantErr  bool
+	}{
+		{"", "*notify.mailgunProvider", false},
+		{"mailgun", "*notify.mailgunProvider", false},
+		{"SMTP", "*notify.smtpProvider", false},
+		{"carrier-pigeon", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			cfg := &config.Config{Accountability: config.AccountabilityConfig{Provider: tt.provider, SMTPHost: "mail.example.com"}}
+			provider, err := NewEmailProvider(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewEmailProvider(%q) error = %v, wantErr %v", tt.provider, err, tt.wantErr)
+			}
+			if got := fmt.Sprintf("%T", provider); !tt.wantErr && got != tt.wantType {
+				t.Errorf("NewEmailProvider(%q) = %s, want %s", tt.provider, got, tt.wantType)
+			}
+		})
+	}
+
+	cfg := &config.Config{Accountability: config.AccountabilityConfig{Provider: "smtp"}}
+	provider, _ := NewEmailProvider(cfg)
+	if smtp := provider.(*smtpProvider); smtp.port != 587 {
+		t.Errorf("Expected default SMTP port 587, got %d", smtp.port)
+	}
+}
+
+func TestBuildMIMEMessage(t *testing.T) {
+	date := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+	textBody := "Blocked access to example.com\nSecond line"
+	htmlBody := "<html><body><p>Blocked access to example.com</p></body></html>"
+
+	raw, err := buildMIMEMessage("glocker@example.com", []string{"partner@example.com"}, "GLOCKER ALERT: Über test", textBody, htmlBody, date)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Message is not valid RFC 5322: %v", err)
+	}
+
+	if msg.Header.Get("From") != "glocker@example.com" || msg.Header.Get("To") != "partner@example.com" {
+		t.Errorf("Unexpected addresses: %v", msg.Header)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "GLOCKER ALERT: Über test" {
+		t.Errorf("Unexpected subject %q (err %v)", subject, err)
+	}
+	if parsed, err := msg.Header.Date(); err != nil || !parsed.Equal(date) {
+		t.Errorf("Unexpected Date header: %v (err %v)", parsed, err)
+	}
+	if msg.Header.Get("Message-ID") == "" || msg.Header.Get("MIME-Version") != "1.0" {
+		t.Error("Expected Message-ID and MIME-Version headers")
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Expected multipart/alternative, got %q (err %v)", mediaType, err)
+	}
+
+	reader := multipart.NewReader(msg.Body, params["boundary"])
+	wantParts := []struct{ contentType, body string }{
+		{"text/plain; charset=UTF-8", textBody},
+		{"text/html; charset=UTF-8", htmlBody},
+	}
+	for _, want := range wantParts {
+		part, err := reader.NextPart()
+		if err != nil {
+			t.Fatalf("Reading part %s: %v", want.contentType, err)
+		}
+		if got := part.Header.Get("Content-Type"); got != want.contentType {
+			t.Errorf("Expected part %s, got %s", want.contentType, got)
+		}
+		// multipart.Reader decodes quoted-printable transparently; line breaks are CRLF on the wire
+		content, _ := io.ReadAll(part)
+		if strings.ReplaceAll(string(content), "\r\n", "\n") != want.body {
+			t.Errorf("Part %s body = %q, want %q", want.contentType, content, want.body)
+		}
+	}
+	if _, err := reader.NextPart(); err != io.EOF {
+		t.Errorf("Expected exactly two parts, got extra (err %v)", err)
+	}
+}
+
 func TestGenerateHTMLEmail_Escaping(t *testing.T) {
 	subject := "Test Subject"
 	body := "<script>alert('XSS')</script>\n&\"test\""
@@ -224,3 +654,115 @@ func TestSendNotification_PlaceholderReplacement(t *testing.T) {
 	SendNotification(cfg, "Test Title", "Test Message", "critical", "warning")
 	// No assertion needed - just verify it doesn't panic
 }
+
+// webhookServer records the requests POSTed to it and answers with status.
+func webhookServer(t *testing.T, status int) (*httptest.Server, chan *http.Request, chan []byte) {
+	requests, bodies := make(chan *http.Request, 10), make(chan []byte, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- r
+		bodies <- body
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, requests, bodies
+}
+
+func TestSendWebhook_ViolationPayload(t *testing.T) {
+	server, requests, bodies := webhookServer(t, http.StatusOK)
+	cfg := &config.Config{Notifications: config.NotificationsConfig{WebhookURL: server.URL + "/hook"}}
+
+	at := time.Date(2024, 6, 11, 15, 4, 5, 0, time.UTC)
+	if err := SendWebhook(cfg, EventViolationThreshold, "GLOCKER ALERT: Violation Threshold Exceeded", "12 violations in 60 minutes", at); err != nil {
+		t.Fatalf("SendWebhook() error: %v", err)
+	}
+
+	r := <-requests
+	if r.Method != http.MethodPost || r.URL.Path != "/hook" || r.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Expected a JSON POST to /hook, got %s %s (%s)", r.Method, r.URL.Path, r.Header.Get("Content-Type"))
+	}
+	want := fmt.Sprintf(`{"schema_version":%d,"event":"violation_threshold_exceeded","subject":"GLOCKER ALERT: Violation Threshold Exceeded","body":"12 violations in 60 minutes","timestamp":"2024-06-11T15:04:05Z"}`, schema.Version)
+	if body := string(<-bodies); body != want {
+		t.Errorf("Posted JSON:\n got %s\nwant %s", body, want)
+	}
+}
+
+func TestSendWebhook_Errors(t *testing.T) {
+	if err := SendWebhook(&config.Config{}, EventTamperDetected, "subject", "body", time.Now()); err != nil {
+		t.Errorf("Expected nil without a webhook, got %v", err)
+	}
+
+	server, _, _ := webhookServer(t, http.StatusInternalServerError)
+	cfg := &config.Config{Notifications: config.NotificationsConfig{WebhookURL: server.URL}}
+	if err := SendWebhook(cfg, EventTamperDetected, "subject", "body", time.Now()); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("Expected an error for a 500 response, got %v", err)
+	}
+}
+
+func TestSendEmail_AlsoPostsWebhook(t *testing.T) {
+	provider := &fakeProvider{}
+	useFakeProvider(t, provider)
+	server, _, bodies := webhookServer(t, http.StatusOK)
+
+	// Both configured: the email and the webhook are sent
+	cfg := retryTestConfig(0)
+	cfg.Notifications.WebhookURL = server.URL
+	if err := SendEmail(cfg, EventBlockingPaused, "GLOCKER ALERT: Webhook And Email", "body"); err != nil {
+		t.Fatalf("SendEmail() error: %v", err)
+	}
+	waitForEmails(t)
+	if len(provider.sent) != 1 {
+		t.Errorf("Expected the email to be sent, got %d", len(provider.sent))
+	}
+	var payload WebhookPayload
+	if err := json.Unmarshal(<-bodies, &payload); err != nil || payload.Event != EventBlockingPaused {
+		t.Errorf("Expected a blocking_paused event, got %+v (%v)", payload, err)
+	}
+
+	// Webhook only: nothing is emailed
+	cfg = &config.Config{Notifications: config.NotificationsConfig{WebhookURL: server.URL}}
+	if err := SendEmail(cfg, EventUnblockRevoked, "GLOCKER ALERT: Webhook Only", "body"); err != nil {
+		t.Fatalf("SendEmail() error: %v", err)
+	}
+	waitForEmails(t)
+	if len(provider.sent) != 1 {
+		t.Errorf("Expected no email without accountability, got %d", len(provider.sent))
+	}
+	if err := json.Unmarshal(<-bodies, &payload); err != nil || payload.Event != EventUnblockRevoked {
+		t.Errorf("Expected an unblock_revoked event, got %+v (%v)", payload, err)
+	}
+
+	// A failing webhook doesn't fail the email
+	failing, _, _ := webhookServer(t, http.StatusBadGateway)
+	cfg = retryTestConfig(0)
+	cfg.Notifications.WebhookURL = failing.URL
+	if err := SendEmail(cfg, EventTamperDetected, "GLOCKER ALERT: Webhook Down", "body"); err != nil {
+		t.Errorf("Expected a failing webhook to be only logged, got %v", err)
+	}
+	waitForEmails(t)
+}
+
+func TestSendEmail_DoesNotWaitForWebhook(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	cfg := &config.Config{Notifications: config.NotificationsConfig{WebhookURL: server.URL, WebhookTimeoutSeconds: 30}}
+	sent := make(chan error, 1)
+	go func() { sent <- SendEmail(cfg, EventTamperDetected, "GLOCKER ALERT: Slow Webhook", "body") }()
+	select {
+	case err := <-sent:
+		if err != nil {
+			t.Errorf("SendEmail() error: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("SendEmail blocked on the webhook POST")
+	}
+
+	if WaitForEmails(50 * time.Millisecond) {
+		t.Error("Expected WaitForEmails to wait for the webhook POST in flight")
+	}
+}
@@ -0,0 +1,138 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"glocker/internal/config"
+)
+
+// pendingEmail is an email that couldn't be delivered, kept until a later send succeeds.
+type pendingEmail struct {
+	QueuedAt time.Time `json:"queued_at"`
+	Subject  string    `json:"subject"`
+	Body     string    `json:"body"`
+}
+
+// maxPendingEmails caps the spool. While mail is down for long, the oldest
+// emails are dropped to make room for new ones.
+const maxPendingEmails = 100
+
+var (
+	pendingEmailsFile  = config.PendingEmailsFile // Replaced in tests
+	pendingEmailsMutex sync.Mutex
+)
+
+// spoolEmail adds an undelivered email to the pending emails file, dropping
+// the oldest ones beyond maxPendingEmails.
+func spoolEmail(subject, body string, queuedAt time.Time) error {
+	pendingEmailsMutex.Lock()
+	defer pendingEmailsMutex.Unlock()
+
+	pending, err := readPendingEmails()
+	if err != nil {
+		return fmt.Errorf("failed to read pending emails: %w", err)
+	}
+	pending = append(pending, pendingEmail{QueuedAt: queuedAt, Subject: subject, Body: body})
+	if dropped := len(pending) - maxPendingEmails; dropped > 0 {
+		log.Printf("Pending email spool is full, dropping the %d oldest", dropped)
+		pending = pending[dropped:]
+	}
+
+	if err := os.MkdirAll(filepath.Dir(pendingEmailsFile), 0755); err != nil {
+		return fmt.Errorf("failed to create spool directory: %w", err)
+	}
+	if err := writePendingEmails(pending); err != nil {
+		return fmt.Errorf("failed to write pending email: %w", err)
+	}
+	return nil
+}
+
+// readPendingEmails returns the spooled emails, oldest first. Malformed lines are skipped.
+func readPendingEmails() ([]pendingEmail, error) {
+	file, err := os.Open(pendingEmailsFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var pending []pendingEmail
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var email pendingEmail
+		if err := json.Unmarshal([]byte(line), &email); err != nil {
+			continue
+		}
+		pending = append(pending, email)
+	}
+	return pending, scanner.Err()
+}
+
+// writePendingEmails replaces the spool with the given emails, removing it when empty.
+func writePendingEmails(pending []pendingEmail) error {
+	if len(pending) == 0 {
+		if err := os.Remove(pendingEmailsFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	var data []byte
+	for _, email := range pending {
+		line, err := json.Marshal(email)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+	return os.WriteFile(pendingEmailsFile, data, 0600)
+}
+
+// flushPendingEmails resends spooled emails once each, after a send has just
+// succeeded. It stops at the first failure and keeps that email and the rest.
+func flushPendingEmails(cfg *config.Config) {
+	pendingEmailsMutex.Lock()
+	defer pendingEmailsMutex.Unlock()
+
+	pending, err := readPendingEmails()
+	if err != nil {
+		log.Printf("Failed to read pending emails: %v", err)
+		return
+	}
+	if len(pending) == 0 {
+		return
+	}
+
+	sent := 0
+	for _, email := range pending {
+		body := fmt.Sprintf("This message could not be delivered at %s and is being resent.\n\n%s",
+			email.QueuedAt.Format("2006-01-02 15:04:05"), email.Body)
+		if err := deliverEmail(cfg, email.Subject, body); err != nil {
+			log.Printf("Failed to resend pending email %q: %v", email.Subject, err)
+			break
+		}
+		sent++
+	}
+
+	if sent > 0 {
+		log.Printf("Resent %d pending email(s), %d still pending", sent, len(pending)-sent)
+	}
+	if err := writePendingEmails(pending[sent:]); err != nil {
+		log.Printf("Failed to update pending emails: %v", err)
+	}
+}
@@ -0,0 +1,255 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mailgun/mailgun-go/v4"
+
+	"glocker/internal/config"
+)
+
+// Email provider names accepted in accountability.provider.
+const (
+	ProviderMailgun = "mailgun"
+	ProviderSMTP    = "smtp"
+)
+
+// defaultMailgunDomain is the Mailgun sending domain used when none is configured.
+const defaultMailgunDomain = "noufalibrahim.name"
+
+// EmailProvider delivers a single email with plain text and HTML alternatives.
+type EmailProvider interface {
+	Send(ctx context.Context, from string, to []string, subject, textBody, htmlBody string) error
+}
+
+// newEmailProvider creates the provider used for outgoing email. Replaced in tests.
+var newEmailProvider = NewEmailProvider
+
+// NewEmailProvider returns the provider selected by accountability.provider.
+// Mailgun is used when no provider is configured.
+func NewEmailProvider(cfg *config.Config) (EmailProvider, error) {
+	acct := cfg.Accountability
+	switch strings.ToLower(acct.Provider) {
+	case "", ProviderMailgun:
+		domain := acct.MailgunDomain
+		if domain == "" {
+			domain = defaultMailgunDomain
+		}
+		return &mailgunProvider{domain: domain, apiKey: acct.ApiKey}, nil
+	case ProviderSMTP:
+		port := acct.SMTPPort
+		if port == 0 {
+			port = 587
+		}
+		return &smtpProvider{host: acct.SMTPHost, port: port, username: acct.SMTPUsername, password: acct.SMTPPassword}, nil
+	default:
+		return nil, fmt.Errorf("unknown email provider %q (use %s or %s)", acct.Provider, ProviderMailgun, ProviderSMTP)
+	}
+}
+
+// providerName returns the display name of the configured provider.
+func providerName(cfg *config.Config) string {
+	if cfg.Accountability.Provider == "" {
+		return ProviderMailgun
+	}
+	return strings.ToLower(cfg.Accountability.Provider)
+}
+
+// mailgunProvider delivers email through the Mailgun API.
+type mailgunProvider struct {
+	domain string
+	apiKey string
+}
+
+// Send sends the message via Mailgun. Errors include the HTTP status when Mailgun returned one.
+func (m *mailgunProvider) Send(ctx context.Context, from string, to []string, subject, textBody, htmlBody string) error {
+	mg := mailgun.NewMailgun(m.domain, m.apiKey)
+
+	mail := mailgun.NewMessage(from, subject, textBody, to...)
+	mail.SetHTML(htmlBody)
+
+	response, id, err := mg.Send(ctx, mail)
+	if err != nil {
+		if status := mailgun.GetStatusFromErr(err); status > 0 {
+			return fmt.Errorf("mailgun returned HTTP status %d: %w", status, err)
+		}
+		return err
+	}
+	log.Printf("Mailgun accepted message: %s (id: %s)", response, id)
+	return nil
+}
+
+// smtpProvider delivers email through an SMTP server, upgrading the connection with STARTTLS.
+type smtpProvider struct {
+	host     string
+	port     int
+	username string
+	password string
+}
+
+// Send sends the message via SMTP. STARTTLS is used whenever the server offers it and is
+// required when credentials are configured, so passwords are never sent in the clear.
+func (s *smtpProvider) Send(ctx context.Context, from string, to []string, subject, textBody, htmlBody string) error {
+	if s.host == "" {
+		return fmt.Errorf("smtp host is not configured")
+	}
+	if len(to) == 0 {
+		return fmt.Errorf("no recipients")
+	}
+
+	message, err := buildMIMEMessage(from, to, subject, textBody, htmlBody, time.Now())
+	if err != nil {
+		return fmt.Errorf("composing message: %w", err)
+	}
+
+	addr := net.JoinHostPort(s.host, strconv.Itoa(s.port))
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("connecting to smtp server %s: %w", addr, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("starting smtp session: %w", err)
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	} else if s.username != "" {
+		return fmt.Errorf("smtp server %s does not support STARTTLS; refusing to send credentials in plain text", addr)
+	}
+
+	if s.username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.username, s.password, s.host)); err != nil {
+			return fmt.Errorf("smtp authentication: %w", err)
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("smtp MAIL FROM: %w", err)
+	}
+	for _, recipient := range to {
+		if err := client.Rcpt(recipient); err != nil {
+			return fmt.Errorf("smtp RCPT TO %s: %w", recipient, err)
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA: %w", err)
+	}
+	if _, err := writer.Write(message); err != nil {
+		writer.Close()
+		return fmt.Errorf("writing message: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("smtp server rejected message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// buildMIMEMessage formats an RFC 5322 message with multipart/alternative plain text and HTML parts.
+func buildMIMEMessage(from string, to []string, subject, textBody, htmlBody string, date time.Time) ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=UTF-8", textBody},
+		{"text/html; charset=UTF-8", htmlBody},
+	} {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", part.contentType)
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		w, err := parts.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	msg.WriteString("From: " + from + "\r\n")
+	msg.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	msg.WriteString("Subject: " + mime.QEncoding.Encode("UTF-8", subject) + "\r\n")
+	msg.WriteString("Date: " + date.Format(time.RFC1123Z) + "\r\n")
+	msg.WriteString("Message-ID: " + newMessageID(from) + "\r\n")
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: multipart/alternative; boundary=\"" + parts.Boundary() + "\"\r\n")
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+
+	return msg.Bytes(), nil
+}
+
+// newMessageID generates a unique Message-ID using the sender's domain.
+func newMessageID(from string) string {
+	domain := "glocker.local"
+	if at := strings.LastIndex(from, "@"); at != -1 {
+		domain = strings.TrimSuffix(from[at+1:], ">")
+	}
+	random := make([]byte, 12)
+	rand.Read(random)
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(random), domain)
+}
+
+// DeliveryHosts returns the hosts alerts are delivered to: the email provider's
+// server when accountability is enabled and the webhook's host when one is set.
+// Firewall rules that block everything else must let these through.
+func DeliveryHosts(cfg *config.Config) []string {
+	var hosts []string
+	if cfg.Accountability.Enabled {
+		switch providerName(cfg) {
+		case ProviderMailgun:
+			if u, err := url.Parse(mailgun.APIBase); err == nil {
+				hosts = append(hosts, u.Hostname())
+			}
+		case ProviderSMTP:
+			if cfg.Accountability.SMTPHost != "" {
+				hosts = append(hosts, cfg.Accountability.SMTPHost)
+			}
+		}
+	}
+	if cfg.Notifications.WebhookURL != "" {
+		if u, err := url.Parse(cfg.Notifications.WebhookURL); err == nil && u.Hostname() != "" {
+			hosts = append(hosts, u.Hostname())
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,81 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"glocker/internal/config"
+	"glocker/internal/schema"
+)
+
+// Event identifies the kind of alert a notification is, independent of its
+// subject's wording. It is sent as the webhook payload's event.
+type Event string
+
+const (
+	EventViolationThreshold  Event = "violation_threshold_exceeded"
+	EventStrictProfile       Event = "strict_profile_activated"
+	EventBlockedSiteAccess   Event = "blocked_site_access_attempt"
+	EventTamperDetected      Event = "tampering_detected"
+	EventForbiddenPrograms   Event = "forbidden_programs_terminated"
+	EventProtectionsDegraded Event = "protections_degraded"
+	EventTemporaryUnblock    Event = "temporary_unblock"
+	EventUnblockRefused      Event = "unblock_refused"
+	EventUnblockRevoked      Event = "unblock_revoked"
+	EventBlockingPaused      Event = "blocking_paused"
+	EventUninstallRequested  Event = "uninstall_requested"
+	EventCategoryDisabled    Event = "keyword_category_disabled"
+	EventDailyReport         Event = "daily_report"
+	EventWeeklyReport        Event = "weekly_report"
+)
+
+// WebhookPayload is the JSON body POSTed to notifications.webhook_url.
+type WebhookPayload struct {
+	SchemaVersion int       `json:"schema_version"`
+	Event         Event     `json:"event"`
+	Subject       string    `json:"subject"`
+	Body          string    `json:"body"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// SendWebhook POSTs an alert to notifications.webhook_url, giving up after the
+// configured timeout. Returns nil if no webhook is configured.
+func SendWebhook(cfg *config.Config, event Event, subject, body string, at time.Time) error {
+	webhookURL := cfg.Notifications.WebhookURL
+	if webhookURL == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(WebhookPayload{
+		SchemaVersion: schema.Version,
+		Event:         event,
+		Subject:       subject,
+		Body:          body,
+		Timestamp:     at,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Notifications.WebhookTimeout())
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package reports
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"strings"
+	"time"
+
+	"glocker/internal/audit"
+	"glocker/internal/schema"
+	"glocker/internal/utils"
+)
+
+// ParseAuditLog reads and parses the JSONL audit log written by the daemon,
+// including its rotated files (oldest first). It fails if none of the files
+// exist or one can't be read.
+func ParseAuditLog(path string) ([]audit.Event, error) {
+	if path == "" {
+		path = audit.DefaultLogPath
+	}
+
+	files := utils.RotatedFiles(path)
+	if len(files) == 0 {
+		return nil, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	}
+	var events []audit.Event
+	for _, filePath := range files {
+		fileEvents, err := parseAuditFile(filePath)
+		events = append(events, fileEvents...)
+		if err != nil {
+			return events, err
+		}
+	}
+	return events, nil
+}
+
+// parseAuditFile parses the events in one audit log file.
+func parseAuditFile(path string) ([]audit.Event, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var events []audit.Event
+	scanner := bufio.NewScanner(file)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		var event audit.Event
+		if err := json.Unmarshal([]byte(line), &event); err != nil || event.Type == "" {
+			// Skip malformed lines
+			continue
+		}
+		if !schema.Compatible(event.SchemaVersion) {
+			// Skip events written by a newer, incompatible glocker
+			continue
+		}
+		events = append(events, event)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return events, err
+	}
+
+	return events, nil
+}
+
+// ReportsFromAudit returns the keyword reports among the audit events, in the form
+// ParseReportsLog returns them.
+func ReportsFromAudit(events []audit.Event) []ReportEntry {
+	var entries []ReportEntry
+	for _, e := range events {
+		reportType := ReportType(e.Source)
+		if e.Type != audit.EventViolation || (reportType != ReportTypeURL && reportType != ReportTypeContent) {
+			continue
+		}
+		entries = append(entries, ReportEntry{
+			Timestamp: e.Timestamp,
+			Type:      reportType,
+			Keyword:   e.Keyword,
+			URL:       e.URL,
+			Domain:    e.Domain,
+		})
+	}
+	return entries
+}
+
+// UnblocksFromAudit returns the unblocks among the audit events, in the form
+// ParseUnblocksLog returns them.
+func UnblocksFromAudit(events []audit.Event) []UnblockEntry {
+	var entries []UnblockEntry
+	for _, e := range events {
+		if e.Type != audit.EventUnblock {
+			continue
+		}
+		entries = append(entries, UnblockEntry{
+			UnblockTime: e.Timestamp,
+			RestoreTime: e.Until,
+			Reason:      e.Reason,
+			Domain:      e.Domain,
+		})
+	}
+	return entries
+}
+
+// LoadReports returns keyword reports from the audit log when it exists, and from
+// the legacy reports log otherwise. Legacy entries from before the first audit
+// event are kept, so history from before the audit log started isn't lost.
+// skipped counts the legacy log lines that couldn't be parsed.
+func LoadReports() (entries []ReportEntry, skipped int, err error) {
+	return loadReports(audit.DefaultLogPath, DefaultReportsLogPath)
+}
+
+func loadReports(auditPath, legacyPath string) ([]ReportEntry, int, error) {
+	events, err := ParseAuditLog(auditPath)
+	if err != nil {
+		return ParseReportsLog(legacyPath)
+	}
+
+	var entries []ReportEntry
+	legacy, skipped, _ := ParseReportsLog(legacyPath)
+	for _, e := range legacy {
+		if auditStarted(events, e.Timestamp) {
+			break
+		}
+		entries = append(entries, e)
+	}
+	return append(entries, ReportsFromAudit(events)...), skipped, nil
+}
+
+// LoadUnblocks returns unblocks from the audit log when it exists, and from the
+// legacy unblocks log otherwise, like LoadReports.
+func LoadUnblocks() ([]UnblockEntry, error) {
+	return loadUnblocks(audit.DefaultLogPath, DefaultUnblocksLogPath)
+}
+
+func loadUnblocks(auditPath, legacyPath string) ([]UnblockEntry, error) {
+	events, err := ParseAuditLog(auditPath)
+	if err != nil {
+		return ParseUnblocksLog(legacyPath)
+	}
+
+	var entries []UnblockEntry
+	legacy, _ := ParseUnblocksLog(legacyPath)
+	for _, e := range legacy {
+		if auditStarted(events, e.UnblockTime) {
+			break
+		}
+		entries = append(entries, e)
+	}
+	return append(entries, UnblocksFromAudit(events)...), nil
+}
+
+// auditStarted reports whether t is at or after the first audit event, from when
+// the audit log records everything the legacy logs do.
+func auditStarted(events []audit.Event, t time.Time) bool {
+	return len(events) > 0 && !t.Before(events[0].Timestamp)
+}
@@ -0,0 +1,130 @@
+package reports
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimePeriods lists the time-of-day periods used by cohort profiles, in order.
+var TimePeriods = []string{"night", "morning", "afternoon", "evening"}
+
+// TimePeriod returns the time-of-day period an hour falls in.
+func TimePeriod(hour int) string {
+	switch {
+	case hour < 6:
+		return "night"
+	case hour < 12:
+		return "morning"
+	case hour < 18:
+		return "afternoon"
+	default:
+		return "evening"
+	}
+}
+
+// IsWeekend reports whether a weekday is Saturday or Sunday.
+func IsWeekend(day time.Weekday) bool {
+	return day == time.Saturday || day == time.Sunday
+}
+
+// Cohort summarizes the report entries that fall on a group of days.
+type Cohort struct {
+	Name     string
+	Days     int            // Calendar days of this cohort in the compared range
+	Summary  ReportSummary  // Summary of the cohort's entries
+	ByPeriod map[string]int // Time period -> count (see TimePeriods)
+}
+
+// DailyAverage returns the average number of entries per day of the cohort.
+func (c Cohort) DailyAverage() float64 {
+	if c.Days == 0 {
+		return 0
+	}
+	return float64(c.Summary.TotalCount) / float64(c.Days)
+}
+
+// PeakPeriod returns the time period with the most entries ("" if there are none).
+func (c Cohort) PeakPeriod() string {
+	peak, peakCount := "", 0
+	for _, period := range TimePeriods {
+		if c.ByPeriod[period] > peakCount {
+			peak, peakCount = period, c.ByPeriod[period]
+		}
+	}
+	return peak
+}
+
+// CohortComparison compares weekday and weekend report entries over the same range.
+type CohortComparison struct {
+	Weekday Cohort
+	Weekend Cohort
+}
+
+// CompareWeekdayWeekend splits entries into weekday and weekend cohorts.
+// start and end bound the compared range (inclusive, by calendar day) and are used to
+// count how many days each cohort covers, so days without entries lower the average.
+func CompareWeekdayWeekend(entries []ReportEntry, start, end time.Time) CohortComparison {
+	var weekdayEntries, weekendEntries []ReportEntry
+	for _, e := range entries {
+		if IsWeekend(e.Timestamp.Weekday()) {
+			weekendEntries = append(weekendEntries, e)
+		} else {
+			weekdayEntries = append(weekdayEntries, e)
+		}
+	}
+
+	comparison := CohortComparison{
+		Weekday: newCohort("Weekdays", weekdayEntries),
+		Weekend: newCohort("Weekends", weekendEntries),
+	}
+
+	startDay := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	endDay := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, start.Location())
+	for day := startDay; !day.After(endDay); day = day.AddDate(0, 0, 1) {
+		if IsWeekend(day.Weekday()) {
+			comparison.Weekend.Days++
+		} else {
+			comparison.Weekday.Days++
+		}
+	}
+
+	return comparison
+}
+
+// newCohort builds a cohort from its entries.
+func newCohort(name string, entries []ReportEntry) Cohort {
+	cohort := Cohort{
+		Name:     name,
+		Summary:  SummarizeReports(entries),
+		ByPeriod: make(map[string]int),
+	}
+	for _, e := range entries {
+		cohort.ByPeriod[TimePeriod(e.Timestamp.Hour())]++
+	}
+	return cohort
+}
+
+// Verdict summarizes the comparison in one sentence, e.g.
+// "Weekends are 3.0× worse, concentrated in the evening".
+func (c CohortComparison) Verdict() string {
+	weekdayAvg, weekendAvg := c.Weekday.DailyAverage(), c.Weekend.DailyAverage()
+
+	switch {
+	case weekdayAvg == 0 && weekendAvg == 0:
+		return "No violations in either cohort"
+	case weekdayAvg == 0:
+		return fmt.Sprintf("Violations only happen on weekends, concentrated in the %s", c.Weekend.PeakPeriod())
+	case weekendAvg == 0:
+		return fmt.Sprintf("Violations only happen on weekdays, concentrated in the %s", c.Weekday.PeakPeriod())
+	}
+
+	ratio := weekendAvg / weekdayAvg
+	switch {
+	case ratio >= 1.2:
+		return fmt.Sprintf("Weekends are %.1f× worse, concentrated in the %s", ratio, c.Weekend.PeakPeriod())
+	case ratio <= 1/1.2:
+		return fmt.Sprintf("Weekdays are %.1f× worse, concentrated in the %s", 1/ratio, c.Weekday.PeakPeriod())
+	default:
+		return fmt.Sprintf("Weekdays and weekends are about the same (%.1f vs %.1f per day)", weekdayAvg, weekendAvg)
+	}
+}
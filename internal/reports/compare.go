@@ -0,0 +1,77 @@
+package reports
+
+import "sort"
+
+// CountDelta is how a count changed from one period to the next.
+type CountDelta struct {
+	Name   string
+	Before int
+	After  int
+}
+
+// Change returns the net change, positive when the count rose.
+func (d CountDelta) Change() int {
+	return d.After - d.Before
+}
+
+// Percent returns the change relative to Before. ok is false when Before is 0,
+// where a percentage means nothing.
+func (d CountDelta) Percent() (percent float64, ok bool) {
+	if d.Before == 0 {
+		return 0, false
+	}
+	return float64(d.Change()) / float64(d.Before) * 100, true
+}
+
+// PeriodComparison compares the violations of two periods.
+type PeriodComparison struct {
+	Before, After ReportSummary
+	Total         CountDelta
+	Keywords      []CountDelta // Largest changes first
+	Domains       []CountDelta // Largest changes first
+}
+
+// ComparePeriods computes the changes from the before summary to the after one,
+// for the totals and for every keyword and domain in either.
+func ComparePeriods(before, after ReportSummary) PeriodComparison {
+	return PeriodComparison{
+		Before:   before,
+		After:    after,
+		Total:    CountDelta{Name: "total", Before: before.TotalCount, After: after.TotalCount},
+		Keywords: countDeltas(before.ByKeyword, after.ByKeyword),
+		Domains:  countDeltas(before.ByDomain, after.ByDomain),
+	}
+}
+
+// countDeltas returns the change of every name in either map, sorted by the
+// size of the change (rises before equal falls), then by name.
+func countDeltas(before, after map[string]int) []CountDelta {
+	deltas := make([]CountDelta, 0, len(before)+len(after))
+	for name, count := range before {
+		deltas = append(deltas, CountDelta{Name: name, Before: count, After: after[name]})
+	}
+	for name, count := range after {
+		if _, seen := before[name]; !seen {
+			deltas = append(deltas, CountDelta{Name: name, After: count})
+		}
+	}
+
+	sort.Slice(deltas, func(i, j int) bool {
+		ci, cj := deltas[i].Change(), deltas[j].Change()
+		if abs(ci) != abs(cj) {
+			return abs(ci) > abs(cj)
+		}
+		if ci != cj {
+			return ci > cj
+		}
+		return deltas[i].Name < deltas[j].Name
+	})
+	return deltas
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,47 @@
+package reports
+
+import (
+	"encoding/csv"
+	"io"
+	"time"
+)
+
+// ReportsCSVHeader is the header row written by WriteReportsCSV.
+var ReportsCSVHeader = []string{"timestamp", "type", "keyword", "domain", "url"}
+
+// UnblocksCSVHeader is the header row written by WriteUnblocksCSV.
+var UnblocksCSVHeader = []string{"timestamp", "domain", "reason"}
+
+// WriteReportsCSV writes one CSV row per report entry, preceded by a header row.
+// Timestamps are formatted as RFC3339.
+func WriteReportsCSV(w io.Writer, entries []ReportEntry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(ReportsCSVHeader); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		row := []string{e.Timestamp.Format(time.RFC3339), string(e.Type), e.Keyword, e.Domain, e.URL}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteUnblocksCSV writes one CSV row per unblock entry, preceded by a header row.
+// Timestamps are formatted as RFC3339.
+func WriteUnblocksCSV(w io.Writer, entries []UnblockEntry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(UnblocksCSVHeader); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		row := []string{e.UnblockTime.Format(time.RFC3339), e.Domain, e.Reason}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -4,10 +4,14 @@ package reports
 import (
 	"bufio"
 	"encoding/json"
+	"fmt"
 	"os"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
+
+	"glocker/internal/utils"
 )
 
 // Default log file paths
@@ -78,22 +82,49 @@ func ParseUnblocksLog(path string) ([]UnblockEntry, error) {
 	return entries, nil
 }
 
-// reportLineRegex matches: [2025-11-17 15:35:46] | type:keyword | url | domain
-var reportLineRegex = regexp.MustCompile(`^\[(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})\] \| (url-keyword|content-keyword):([^ |]+) \| ([^ |]+)(?: \| (.+))?$`)
-
-// ParseReportsLog reads and parses the reports log file.
-func ParseReportsLog(path string) ([]ReportEntry, error) {
+// reportFieldSep separates the fields of a reports log line:
+//
+//	[2025-11-17 15:35:46] | type:keyword | url | domain
+//
+// The domain is optional. URLs may contain "|" themselves, so the URL is taken to
+// run up to the last separator that is followed by a host name.
+const reportFieldSep = " | "
+
+// reportDomainRegex matches the host name in the optional last field.
+var reportDomainRegex = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9.-]*[A-Za-z0-9])?$`)
+
+// ParseReportsLog reads and parses the reports log file, together with its
+// rotated files (oldest first), so reports from before a rotation aren't lost.
+// Blank lines are ignored; lines that can't be parsed are skipped and counted in
+// skipped. It fails if none of the files exist or one can't be read.
+func ParseReportsLog(path string) (entries []ReportEntry, skipped int, err error) {
 	if path == "" {
 		path = DefaultReportsLogPath
 	}
 
+	files := utils.RotatedFiles(path)
+	if len(files) == 0 {
+		return nil, 0, &os.PathError{Op: "open", Path: path, Err: os.ErrNotExist}
+	}
+	for _, filePath := range files {
+		fileEntries, fileSkipped, err := parseReportsFile(filePath)
+		entries = append(entries, fileEntries...)
+		skipped += fileSkipped
+		if err != nil {
+			return entries, skipped, err
+		}
+	}
+	return entries, skipped, nil
+}
+
+// parseReportsFile parses the lines of one reports log file.
+func parseReportsFile(path string) (entries []ReportEntry, skipped int, err error) {
 	file, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	defer file.Close()
 
-	var entries []ReportEntry
 	scanner := bufio.NewScanner(file)
 
 	for scanner.Scan() {
@@ -104,49 +135,80 @@ func ParseReportsLog(path string) ([]ReportEntry, error) {
 
 		entry, ok := parseReportLine(line)
 		if !ok {
+			skipped++
 			continue
 		}
 		entries = append(entries, entry)
 	}
 
 	if err := scanner.Err(); err != nil {
-		return entries, err
+		return entries, skipped, err
 	}
 
-	return entries, nil
+	return entries, skipped, nil
 }
 
+// parseReportLine parses one reports log line, reporting false if it is malformed.
+// The timestamp, trigger and URL are required; the domain may be missing.
 func parseReportLine(line string) (ReportEntry, bool) {
-	matches := reportLineRegex.FindStringSubmatch(line)
-	if matches == nil {
+	rest, ok := strings.CutPrefix(line, "[")
+	if !ok {
 		return ReportEntry{}, false
 	}
-
-	timestamp, err := time.ParseInLocation("2006-01-02 15:04:05", matches[1], time.Local)
+	stamp, rest, ok := strings.Cut(rest, "]")
+	if !ok {
+		return ReportEntry{}, false
+	}
+	timestamp, err := time.ParseInLocation("2006-01-02 15:04:05", strings.TrimSpace(stamp), time.Local)
 	if err != nil {
 		return ReportEntry{}, false
 	}
 
-	entry := ReportEntry{
-		Timestamp: timestamp,
-		Type:      ReportType(matches[2]),
-		Keyword:   matches[3],
-		URL:       matches[4],
+	// The trigger can't contain "|", so it ends at the first one after the timestamp
+	rest, ok = strings.CutPrefix(strings.TrimSpace(rest), "|")
+	if !ok {
+		return ReportEntry{}, false
+	}
+	trigger, rest, ok := strings.Cut(rest, "|")
+	if !ok {
+		return ReportEntry{}, false
+	}
+	kind, keyword, ok := strings.Cut(strings.TrimSpace(trigger), ":")
+	reportType := ReportType(strings.TrimSpace(kind))
+	keyword = strings.TrimSpace(keyword)
+	if !ok || keyword == "" || (reportType != ReportTypeURL && reportType != ReportTypeContent) {
+		return ReportEntry{}, false
 	}
 
-	if len(matches) > 5 && matches[5] != "" {
-		entry.Domain = matches[5]
+	// The space added to url lets a trailing " |" count as an empty domain field
+	url, domain := strings.TrimSpace(rest), ""
+	if i := strings.LastIndex(url+" ", reportFieldSep); i >= 0 {
+		candidate := strings.TrimSpace(url[min(i+len(reportFieldSep), len(url)):])
+		if candidate == "" || reportDomainRegex.MatchString(candidate) {
+			url, domain = strings.TrimSpace(url[:i]), candidate
+		}
+	}
+	if url == "" {
+		return ReportEntry{}, false
 	}
 
-	return entry, true
+	return ReportEntry{
+		Timestamp: timestamp,
+		Type:      reportType,
+		Keyword:   keyword,
+		URL:       url,
+		Domain:    domain,
+	}, true
 }
 
 // FilterUnblocks filters unblock entries based on criteria.
 type UnblockFilter struct {
-	Domain    string     // Filter by domain (substring match)
-	Reason    string     // Filter by reason (exact match)
-	StartTime *time.Time // Filter entries after this time
-	EndTime   *time.Time // Filter entries before this time
+	Domain         string         // Filter by domain (substring match)
+	Reason         string         // Filter by reason (exact match)
+	StartTime      *time.Time     // Filter entries after this time
+	EndTime        *time.Time     // Filter entries before this time
+	Weekdays       []time.Weekday // Only keep entries falling on these weekdays (empty = all)
+	ExcludeDomains []string       // Drop entries for these domains or their subdomains
 }
 
 // FilterUnblocks returns entries matching the filter criteria.
@@ -166,6 +228,12 @@ func FilterUnblocks(entries []UnblockEntry, filter UnblockFilter) []UnblockEntry
 		if filter.EndTime != nil && e.UnblockTime.After(*filter.EndTime) {
 			continue
 		}
+		if len(filter.Weekdays) > 0 && !slices.Contains(filter.Weekdays, e.UnblockTime.Weekday()) {
+			continue
+		}
+		if matchesAnyDomain(e.Domain, filter.ExcludeDomains) {
+			continue
+		}
 		result = append(result, e)
 	}
 
@@ -174,12 +242,15 @@ func FilterUnblocks(entries []UnblockEntry, filter UnblockFilter) []UnblockEntry
 
 // ReportFilter filters report entries based on criteria.
 type ReportFilter struct {
-	Type      ReportType // Filter by report type
-	Keyword   string     // Filter by keyword (substring match)
-	Domain    string     // Filter by domain (substring match)
-	URL       string     // Filter by URL (substring match)
-	StartTime *time.Time // Filter entries after this time
-	EndTime   *time.Time // Filter entries before this time
+	Type            ReportType     // Filter by report type
+	Keyword         string         // Filter by keyword (substring match)
+	Domain          string         // Filter by domain (substring match)
+	URL             string         // Filter by URL (substring match)
+	StartTime       *time.Time     // Filter entries after this time
+	EndTime         *time.Time     // Filter entries before this time
+	Weekdays        []time.Weekday // Only keep entries falling on these weekdays (empty = all)
+	ExcludeDomains  []string       // Drop entries for these domains or their subdomains
+	ExcludeKeywords []string       // Drop entries matching these keywords (case-insensitive)
 }
 
 // FilterReports returns entries matching the filter criteria.
@@ -205,12 +276,50 @@ func FilterReports(entries []ReportEntry, filter ReportFilter) []ReportEntry {
 		if filter.EndTime != nil && e.Timestamp.After(*filter.EndTime) {
 			continue
 		}
+		if len(filter.Weekdays) > 0 && !slices.Contains(filter.Weekdays, e.Timestamp.Weekday()) {
+			continue
+		}
+		if matchesAnyDomain(e.Domain, filter.ExcludeDomains) {
+			continue
+		}
+		if slices.ContainsFunc(filter.ExcludeKeywords, func(keyword string) bool {
+			return strings.EqualFold(e.Keyword, keyword)
+		}) {
+			continue
+		}
 		result = append(result, e)
 	}
 
 	return result
 }
 
+// matchesAnyDomain reports whether domain equals or is a subdomain of one of domains
+// (case-insensitive).
+func matchesAnyDomain(domain string, domains []string) bool {
+	domain = strings.ToLower(domain)
+	for _, d := range domains {
+		d = strings.ToLower(d)
+		if domain == d || strings.HasSuffix(domain, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseWeekday parses a weekday name such as "Sat" or "saturday" (case-insensitive).
+func ParseWeekday(s string) (time.Weekday, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	if len(name) >= 3 {
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			full := strings.ToLower(day.String())
+			if name == full || name == full[:3] {
+				return day, nil
+			}
+		}
+	}
+	return time.Sunday, fmt.Errorf("invalid weekday %q", s)
+}
+
 // LifecycleEntry represents a single install/uninstall log entry.
 type LifecycleEntry struct {
 	Timestamp time.Time `json:"timestamp"`
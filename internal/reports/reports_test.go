@@ -1,10 +1,17 @@
 package reports
 
 import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
+
+	"glocker/internal/utils"
 )
 
 func TestParseUnblocksLog(t *testing.T) {
@@ -52,7 +59,7 @@ func TestParseReportsLog(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	entries, err := ParseReportsLog(tmpFile)
+	entries, _, err := ParseReportsLog(tmpFile)
 	if err != nil {
 		t.Fatalf("ParseReportsLog failed: %v", err)
 	}
@@ -78,6 +85,34 @@ func TestParseReportsLog(t *testing.T) {
 	}
 }
 
+func TestParseReportsLog_RotatedFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reports.log")
+	for day := 1; day <= 4; day++ {
+		line := fmt.Sprintf("[2025-11-%02d 10:00:00] | url-keyword:casino | https://example.com/%d\n", day, day)
+		// Every entry fills a file, so the log is rotated before each one after the first
+		if err := utils.AppendRotating(path, []byte(line), 10, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, _, err := ParseReportsLog(path)
+	if err != nil {
+		t.Fatalf("ParseReportsLog failed: %v", err)
+	}
+	var urls []string
+	for _, entry := range entries {
+		urls = append(urls, entry.URL)
+	}
+	// The oldest entry was dropped with the rotated file past log_max_files
+	if want := []string{"https://example.com/2", "https://example.com/3", "https://example.com/4"}; !slices.Equal(urls, want) {
+		t.Errorf("Expected entries oldest first from the rotated files, got %v", urls)
+	}
+
+	if _, _, err := ParseReportsLog(filepath.Join(t.TempDir(), "missing.log")); !os.IsNotExist(err) {
+		t.Errorf("Expected a not-exist error for a missing log, got %v", err)
+	}
+}
+
 func TestFilterUnblocks(t *testing.T) {
 	now := time.Now()
 	entries := []UnblockEntry{
@@ -133,6 +168,259 @@ func TestFilterReports(t *testing.T) {
 	}
 }
 
+func TestFilterByWeekday(t *testing.T) {
+	saturday := time.Date(2026, 1, 10, 22, 0, 0, 0, time.Local)
+	monday := time.Date(2026, 1, 12, 9, 0, 0, 0, time.Local)
+	nextSaturday := saturday.AddDate(0, 0, 7)
+
+	unblocks := []UnblockEntry{
+		{UnblockTime: saturday, Domain: "youtube.com"},
+		{UnblockTime: monday, Domain: "youtube.com"},
+		{UnblockTime: nextSaturday, Domain: "reddit.com"},
+	}
+	filtered := FilterUnblocks(unblocks, UnblockFilter{Weekdays: []time.Weekday{time.Saturday}})
+	if len(filtered) != 2 {
+		t.Errorf("Expected 2 Saturday unblocks, got %d", len(filtered))
+	}
+
+	// Weekday filter combines with the date range
+	end := saturday.Add(24 * time.Hour)
+	filtered = FilterUnblocks(unblocks, UnblockFilter{EndTime: &end, Weekdays: []time.Weekday{time.Saturday}})
+	if len(filtered) != 1 {
+		t.Errorf("Expected 1 Saturday unblock before %v, got %d", end, len(filtered))
+	}
+
+	reports := []ReportEntry{
+		{Timestamp: saturday, Keyword: "porn"},
+		{Timestamp: monday, Keyword: "porn"},
+	}
+	filteredReports := FilterReports(reports, ReportFilter{Weekdays: []time.Weekday{time.Sunday, time.Monday}})
+	if len(filteredReports) != 1 || !filteredReports[0].Timestamp.Equal(monday) {
+		t.Errorf("Expected only the Monday report, got %v", filteredReports)
+	}
+}
+
+func TestFilterExclusions(t *testing.T) {
+	now := time.Now()
+	entries := []ReportEntry{
+		{Timestamp: now, Keyword: "porn", Domain: "ads.example.com"},
+		{Timestamp: now, Keyword: "porn", Domain: "tracker.ads.example.com"},
+		{Timestamp: now, Keyword: "Foo", Domain: "news.com"},
+		{Timestamp: now, Keyword: "porn", Domain: "badads.example.com"},
+		{Timestamp: now, Keyword: "xxx", Domain: "news.com"},
+	}
+
+	filtered := FilterReports(entries, ReportFilter{
+		ExcludeDomains:  []string{"ADS.example.com"},
+		ExcludeKeywords: []string{"foo"},
+	})
+	if len(filtered) != 2 {
+		t.Fatalf("Expected 2 entries after exclusions, got %d: %v", len(filtered), filtered)
+	}
+	if filtered[0].Domain != "badads.example.com" || filtered[1].Keyword != "xxx" {
+		t.Errorf("Unexpected entries after exclusions: %v", filtered)
+	}
+
+	unblocks := []UnblockEntry{
+		{UnblockTime: now, Domain: "www.ads.example.com"},
+		{UnblockTime: now, Domain: "youtube.com"},
+	}
+	filteredUnblocks := FilterUnblocks(unblocks, UnblockFilter{ExcludeDomains: []string{"ads.example.com"}})
+	if len(filteredUnblocks) != 1 || filteredUnblocks[0].Domain != "youtube.com" {
+		t.Errorf("Expected only the youtube.com unblock, got %v", filteredUnblocks)
+	}
+}
+
+func TestParseWeekday(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Weekday
+		wantErr bool
+	}{
+		{"Sat", time.Saturday, false},
+		{"saturday", time.Saturday, false},
+		{"MON", time.Monday, false},
+		{"Su", 0, true},
+		{"Funday", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseWeekday(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseWeekday(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("ParseWeekday(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseReportsLog_Hardened(t *testing.T) {
+	content := `[2025-11-17 15:35:46] | url-keyword:casino | https://example.com/search?q=a|b|c | example.com
+
+[2025-11-17 15:36:00] | url-keyword:casino | https://example.com/a|b
+   [2025-11-17 15:37:00]  |  content-keyword:poker  |  https://poker.example/x | y  |  poker.example  
+[2025-11-17 15:38:00] | content-keyword:poker | https://poker.example/ |
+
+not a report line
+[2025-11-17 25:00:00] | url-keyword:casino | https://example.com/
+[2025-11-17 15:39:00] | domain-keyword:casino | https://example.com/
+[2025-11-17 15:40:00] | url-keyword: | https://example.com/
+[2025-11-17 15:41:00] | url-keyword:casino |
+`
+	tmpFile := filepath.Join(t.TempDir(), "reports.log")
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, skipped, err := ParseReportsLog(tmpFile)
+	if err != nil {
+		t.Fatalf("ParseReportsLog failed: %v", err)
+	}
+	if skipped != 5 {
+		t.Errorf("Expected 5 skipped lines, got %d", skipped)
+	}
+
+	want := []ReportEntry{
+		{Type: ReportTypeURL, Keyword: "casino", URL: "https://example.com/search?q=a|b|c", Domain: "example.com"},
+		{Type: ReportTypeURL, Keyword: "casino", URL: "https://example.com/a|b"},
+		{Type: ReportTypeContent, Keyword: "poker", URL: "https://poker.example/x | y", Domain: "poker.example"},
+		{Type: ReportTypeContent, Keyword: "poker", URL: "https://poker.example/"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("Expected %d entries, got %d: %+v", len(want), len(entries), entries)
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.Type != w.Type || e.Keyword != w.Keyword || e.URL != w.URL || e.Domain != w.Domain {
+			t.Errorf("Entry %d = %+v, want %+v", i, e, w)
+		}
+	}
+	if entries[2].Timestamp.Format("15:04:05") != "15:37:00" {
+		t.Errorf("Expected the padded line's timestamp to parse, got %v", entries[2].Timestamp)
+	}
+}
+
+func TestWriteReportsCSV(t *testing.T) {
+	content := `[2025-11-17 15:35:46] | url-keyword:porn | https://www.google.com/search?q=test,more
+[2025-11-17 22:51:59] | content-keyword:boobs | https://example.com/page | example.com
+`
+	tmpFile := filepath.Join(t.TempDir(), "reports.log")
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	entries, _, err := ParseReportsLog(tmpFile)
+	if err != nil {
+		t.Fatalf("ParseReportsLog failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteReportsCSV(&buf, entries); err != nil {
+		t.Fatalf("WriteReportsCSV failed: %v", err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read back CSV: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("Expected header + 2 rows, got %d rows", len(rows))
+	}
+	if strings.Join(rows[0], ",") != "timestamp,type,keyword,domain,url" {
+		t.Errorf("Unexpected header: %v", rows[0])
+	}
+
+	expected := []string{entries[1].Timestamp.Format(time.RFC3339), "content-keyword", "boobs", "example.com", "https://example.com/page"}
+	if strings.Join(rows[2], "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected row %v, got %v", expected, rows[2])
+	}
+	if rows[1][4] != "https://www.google.com/search?q=test,more" {
+		t.Errorf("Expected URL with comma to round-trip, got %q", rows[1][4])
+	}
+}
+
+func TestWriteUnblocksCSV(t *testing.T) {
+	content := `{"unblock_time":"2025-12-05T13:48:24+05:30","restore_time":"2025-12-05T14:18:24+05:30","reason":"work, urgent","domain":"youtube.com"}
+{"unblock_time":"2025-12-05T22:49:56+05:30","restore_time":"2025-12-05T23:19:56+05:30","reason":"said \"just once\"","domain":"primevideo.com"}
+`
+	tmpFile := filepath.Join(t.TempDir(), "unblocks.log")
+	if err := os.WriteFile(tmpFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := ParseUnblocksLog(tmpFile)
+	if err != nil {
+		t.Fatalf("ParseUnblocksLog failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteUnblocksCSV(&buf, entries); err != nil {
+		t.Fatalf("WriteUnblocksCSV failed: %v", err)
+	}
+
+	lines := strings.Split(buf.String(), "\n")
+	if lines[0] != "timestamp,domain,reason" {
+		t.Errorf("Expected header on first line, got %q", lines[0])
+	}
+	if lines[1] != `2025-12-05T13:48:24+05:30,youtube.com,"work, urgent"` {
+		t.Errorf("Expected reason with comma to be quoted, got %q", lines[1])
+	}
+
+	rows, err := csv.NewReader(strings.NewReader(buf.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read back CSV: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("Expected header + 2 rows, got %d rows", len(rows))
+	}
+	if rows[1][2] != "work, urgent" || rows[2][2] != `said "just once"` {
+		t.Errorf("Reasons did not round-trip: %q, %q", rows[1][2], rows[2][2])
+	}
+}
+
+func TestCompareWeekdayWeekend(t *testing.T) {
+	// 2026-01-05 is a Monday; the range covers one full week (5 weekdays, 2 weekend days)
+	at := func(day, hour int, keyword string) ReportEntry {
+		return ReportEntry{Timestamp: time.Date(2026, 1, day, hour, 0, 0, 0, time.UTC), Keyword: keyword}
+	}
+	entries := []ReportEntry{
+		at(5, 10, "news"),
+		at(7, 14, "news"),
+		at(10, 20, "video"), // Saturday
+		at(10, 21, "video"),
+		at(11, 19, "video"), // Sunday
+		at(11, 9, "games"),
+	}
+	start := time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 1, 11, 23, 59, 59, 0, time.UTC)
+
+	comparison := CompareWeekdayWeekend(entries, start, end)
+
+	if comparison.Weekday.Days != 5 || comparison.Weekend.Days != 2 {
+		t.Errorf("Expected 5 weekday and 2 weekend days, got %d and %d", comparison.Weekday.Days, comparison.Weekend.Days)
+	}
+	if comparison.Weekday.Summary.TotalCount != 2 || comparison.Weekend.Summary.TotalCount != 4 {
+		t.Errorf("Expected 2 weekday and 4 weekend entries, got %d and %d",
+			comparison.Weekday.Summary.TotalCount, comparison.Weekend.Summary.TotalCount)
+	}
+	if comparison.Weekend.ByPeriod["evening"] != 3 || comparison.Weekend.PeakPeriod() != "evening" {
+		t.Errorf("Expected weekend peak in the evening, got %v", comparison.Weekend.ByPeriod)
+	}
+	if comparison.Weekend.Summary.ByKeyword["video"] != 3 {
+		t.Errorf("Expected 3 weekend video entries, got %d", comparison.Weekend.Summary.ByKeyword["video"])
+	}
+
+	// 4/2 per day on weekends vs 2/5 per day on weekdays = 5x
+	if verdict := comparison.Verdict(); verdict != "Weekends are 5.0× worse, concentrated in the evening" {
+		t.Errorf("Unexpected verdict: %q", verdict)
+	}
+
+	if verdict := CompareWeekdayWeekend(entries[:2], start, end).Verdict(); verdict != "Violations only happen on weekdays, concentrated in the morning" {
+		t.Errorf("Unexpected weekday-only verdict: %q", verdict)
+	}
+}
+
 func TestSummarizeUnblocks(t *testing.T) {
 	now := time.Now()
 	entries := []UnblockEntry{
@@ -177,3 +465,310 @@ func TestTopN(t *testing.T) {
 		t.Errorf("Expected a:10 second, got %s:%d", top[1].Name, top[1].Count)
 	}
 }
+
+func TestCategoryOf(t *testing.T) {
+	categories := map[string]string{
+		"reddit.com":      "social",
+		"news.google.com": "news",
+	}
+
+	tests := []struct {
+		domain string
+		want   string
+	}{
+		{"reddit.com", "social"},
+		{"www.reddit.com", "social"},
+		{"old.reddit.com", "social"},
+		{"news.google.com", "news"},
+		{"google.com", Uncategorized},
+		{"notreddit.com", Uncategorized},
+		{"localhost", Uncategorized},
+	}
+	for _, tt := range tests {
+		if got := CategoryOf(tt.domain, categories); got != tt.want {
+			t.Errorf("CategoryOf(%q) = %q, want %q", tt.domain, got, tt.want)
+		}
+	}
+}
+
+func TestCountByCategory(t *testing.T) {
+	categories := map[string]string{"reddit.com": "social", "x.com": "social", "cnn.com": "news"}
+	entries := []ReportEntry{
+		{Keyword: "a", Domain: "reddit.com"},
+		{Keyword: "a", Domain: "old.reddit.com"},
+		{Keyword: "b", Domain: "x.com"},
+		{Keyword: "b", Domain: "cnn.com"},
+		{Keyword: "c", Domain: "example.com"},
+		{Keyword: "c"}, // No domain, not counted
+	}
+
+	got := CountByCategory(SummarizeReports(entries).ByDomain, categories)
+	want := map[string]int{"social": 3, "news": 1, Uncategorized: 1}
+	if len(got) != len(want) {
+		t.Fatalf("CountByCategory() = %v, want %v", got, want)
+	}
+	for category, count := range want {
+		if got[category] != count {
+			t.Errorf("%s: count = %d, want %d", category, got[category], count)
+		}
+	}
+}
+
+func TestGroupByCategory(t *testing.T) {
+	categories := map[string]string{"reddit.com": "social", "x.com": "social", "cnn.com": "news"}
+	items := []CountItem{
+		{Name: "example.com", Count: 20},
+		{Name: "cnn.com", Count: 8},
+		{Name: "reddit.com", Count: 6},
+		{Name: "x.com", Count: 4},
+	}
+
+	groups := GroupByCategory(items, categories)
+	want := []struct {
+		category string
+		count    int
+		items    []string
+	}{
+		{"social", 10, []string{"reddit.com", "x.com"}},
+		{"news", 8, []string{"cnn.com"}},
+		{Uncategorized, 20, []string{"example.com"}},
+	}
+	if len(groups) != len(want) {
+		t.Fatalf("Expected %d groups, got %+v", len(want), groups)
+	}
+	for i, w := range want {
+		group := groups[i]
+		if group.Category != w.category || group.Count != w.count || len(group.Items) != len(w.items) {
+			t.Errorf("Group %d = %+v, want %s with %d (%v)", i, group, w.category, w.count, w.items)
+			continue
+		}
+		for j, name := range w.items {
+			if group.Items[j].Name != name {
+				t.Errorf("Group %s item %d = %s, want %s", w.category, j, group.Items[j].Name, name)
+			}
+		}
+	}
+}
+
+func TestBucketByDay(t *testing.T) {
+	day := func(d, hour int) time.Time { return time.Date(2026, 1, d, hour, 0, 0, 0, time.Local) }
+	entries := []ReportEntry{
+		{Timestamp: day(5, 9)},
+		{Timestamp: day(5, 23)},
+		{Timestamp: day(7, 0)},
+		{Timestamp: day(9, 12)}, // After the range
+	}
+
+	buckets := BucketByDay(entries, day(4, 15), day(8, 10))
+	want := []int{0, 2, 0, 1, 0}
+	if len(buckets) != len(want) {
+		t.Fatalf("Expected %d buckets, got %+v", len(want), buckets)
+	}
+	for i, bucket := range buckets {
+		if bucket.Count != want[i] {
+			t.Errorf("Bucket %d: count = %d, want %d", i, bucket.Count, want[i])
+		}
+		if wantStart := day(4+i, 0); !bucket.Start.Equal(wantStart) {
+			t.Errorf("Bucket %d: start = %v, want %v", i, bucket.Start, wantStart)
+		}
+	}
+}
+
+func TestBucketTrend_MonthsForLongRanges(t *testing.T) {
+	entries := []ReportEntry{
+		{Timestamp: time.Date(2026, 1, 31, 22, 0, 0, 0, time.Local)},
+		{Timestamp: time.Date(2026, 3, 1, 1, 0, 0, 0, time.Local)},
+		{Timestamp: time.Date(2026, 3, 15, 1, 0, 0, 0, time.Local)},
+	}
+
+	buckets, monthly := BucketTrend(entries, time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local), time.Date(2026, 6, 30, 0, 0, 0, 0, time.Local))
+	if !monthly {
+		t.Fatal("Expected monthly buckets for a six month range")
+	}
+	want := []int{1, 0, 2, 0, 0, 0}
+	if len(buckets) != len(want) {
+		t.Fatalf("Expected %d buckets, got %+v", len(want), buckets)
+	}
+	for i, bucket := range buckets {
+		if bucket.Count != want[i] {
+			t.Errorf("Month %d: count = %d, want %d", i+1, bucket.Count, want[i])
+		}
+	}
+
+	if _, monthly := BucketTrend(entries, time.Date(2026, 1, 1, 0, 0, 0, 0, time.Local), time.Date(2026, 1, 31, 0, 0, 0, 0, time.Local)); monthly {
+		t.Error("Expected daily buckets for a one month range")
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	tests := []struct {
+		counts []int
+		want   string
+	}{
+		{[]int{0, 1, 2, 3, 4, 5, 6, 7}, "▁▂▃▄▅▆▇█"},
+		{[]int{0, 1, 0}, "▁█▁"},
+		{[]int{0, 0}, "▁▁"},
+		{[]int{10, 1, 5}, "█▂▄"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := Sparkline(tt.counts); got != tt.want {
+			t.Errorf("Sparkline(%v) = %q, want %q", tt.counts, got, tt.want)
+		}
+	}
+}
+
+func TestParseAuditLog(t *testing.T) {
+	content := `{"schema_version":1,"ts":"2026-01-06T09:00:00+05:30","type":"reload","source":"socket"}
+{"schema_version":99,"ts":"2026-01-06T09:05:00+05:30","type":"reload","source":"socket"}
+{"ts":"2026-01-06T09:15:00+05:30","type":"violation","domain":"example.com","keyword":"casino","url":"https://example.com/","source":"content-keyword"}
+not json
+{"ts":"2026-01-06T09:20:00+05:30","type":"violation","domain":"reddit.com","url":"http://reddit.com/","source":"web_access"}
+{"ts":"2026-01-06T09:30:00+05:30","type":"unblock","domain":"youtube.com","reason":"work","source":"socket","until":"2026-01-06T10:00:00+05:30"}
+{"ts":"2026-01-06T09:45:00+05:30","type":"violation","keyword":"poker","url":"https://www.google.com/search?q=poker","source":"url-keyword"}
+`
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := ParseAuditLog(path)
+	if err != nil {
+		t.Fatalf("ParseAuditLog failed: %v", err)
+	}
+	if len(events) != 5 {
+		t.Fatalf("Expected 5 events (malformed and newer schema lines skipped), got %d", len(events))
+	}
+
+	reports := ReportsFromAudit(events)
+	if len(reports) != 2 {
+		t.Fatalf("Expected 2 keyword reports, got %d: %+v", len(reports), reports)
+	}
+	if reports[0].Type != ReportTypeContent || reports[0].Keyword != "casino" || reports[0].Domain != "example.com" {
+		t.Errorf("Unexpected first report: %+v", reports[0])
+	}
+	if reports[1].Type != ReportTypeURL || reports[1].Keyword != "poker" {
+		t.Errorf("Unexpected second report: %+v", reports[1])
+	}
+
+	unblocks := UnblocksFromAudit(events)
+	if len(unblocks) != 1 {
+		t.Fatalf("Expected 1 unblock, got %d", len(unblocks))
+	}
+	if unblocks[0].Domain != "youtube.com" || unblocks[0].Reason != "work" || unblocks[0].RestoreTime.Sub(unblocks[0].UnblockTime) != 30*time.Minute {
+		t.Errorf("Unexpected unblock: %+v", unblocks[0])
+	}
+}
+
+func TestParseAuditLog_RotatedFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	for hour := 9; hour <= 11; hour++ {
+		line := fmt.Sprintf(`{"ts":"2026-01-06T%02d:00:00Z","type":"reload","source":"socket"}`+"\n", hour)
+		if err := utils.AppendRotating(path, []byte(line), 10, 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events, err := ParseAuditLog(path)
+	if err != nil {
+		t.Fatalf("ParseAuditLog failed: %v", err)
+	}
+	var hours []int
+	for _, event := range events {
+		hours = append(hours, event.Timestamp.Hour())
+	}
+	if want := []int{9, 10, 11}; !slices.Equal(hours, want) {
+		t.Errorf("Expected events oldest first from the rotated files, got hours %v", hours)
+	}
+
+	if _, err := ParseAuditLog(filepath.Join(t.TempDir(), "missing.jsonl")); !os.IsNotExist(err) {
+		t.Errorf("Expected a not-exist error for a missing log, got %v", err)
+	}
+}
+
+func TestLoadReports_FallsBackToLegacyLog(t *testing.T) {
+	dir := t.TempDir()
+	legacyPath := filepath.Join(dir, "reports.log")
+	auditPath := filepath.Join(dir, "audit.jsonl")
+	legacy := `[2026-01-05 22:00:00] | url-keyword:casino | https://casino.example/
+[2026-01-06 09:15:00] | content-keyword:poker | https://example.com/ | example.com
+`
+	if err := os.WriteFile(legacyPath, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// No audit log: the legacy log is used as before
+	entries, _, err := loadReports(auditPath, legacyPath)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("Expected 2 legacy entries, got %d (%v)", len(entries), err)
+	}
+
+	// With an audit log, legacy entries from before it started are kept and the
+	// rest come from the audit log, so nothing is counted twice
+	start := time.Date(2026, 1, 6, 9, 0, 0, 0, time.Local)
+	auditLog := fmt.Sprintf(`{"ts":%q,"type":"reload","source":"socket"}
+{"ts":%q,"type":"violation","domain":"example.com","keyword":"poker","url":"https://example.com/","source":"content-keyword"}
+`, start.Format(time.RFC3339), start.Add(15*time.Minute).Format(time.RFC3339))
+	if err := os.WriteFile(auditPath, []byte(auditLog), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, _, err = loadReports(auditPath, legacyPath)
+	if err != nil {
+		t.Fatalf("loadReports failed: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Keyword != "casino" || entries[1].Keyword != "poker" {
+		t.Errorf("Expected the pre-audit legacy entry then the audit entry, got %+v", entries)
+	}
+
+	unblocks, err := loadUnblocks(auditPath, filepath.Join(dir, "missing.log"))
+	if err != nil || len(unblocks) != 0 {
+		t.Errorf("Expected no unblocks and no error with only an audit log, got %d (%v)", len(unblocks), err)
+	}
+}
+
+func TestComparePeriods(t *testing.T) {
+	june := ReportSummary{
+		TotalCount: 20,
+		ByKeyword:  map[string]int{"casino": 10, "poker": 6, "reddit": 4},
+		ByDomain:   map[string]int{"reddit.com": 8, "news.com": 2},
+	}
+	july := ReportSummary{
+		TotalCount: 15,
+		ByKeyword:  map[string]int{"casino": 2, "poker": 6, "slots": 7},
+		ByDomain:   map[string]int{"reddit.com": 12},
+	}
+
+	comparison := ComparePeriods(june, july)
+	if comparison.Total.Change() != -5 {
+		t.Errorf("Total change = %d, want -5", comparison.Total.Change())
+	}
+	if percent, ok := comparison.Total.Percent(); !ok || percent != -25 {
+		t.Errorf("Total percent = %v (%v), want -25", percent, ok)
+	}
+
+	// Largest changes first; a rise before an equal fall, unchanged last
+	wantKeywords := []CountDelta{
+		{Name: "casino", Before: 10, After: 2},
+		{Name: "slots", Before: 0, After: 7},
+		{Name: "reddit", Before: 4, After: 0},
+		{Name: "poker", Before: 6, After: 6},
+	}
+	if !slices.Equal(comparison.Keywords, wantKeywords) {
+		t.Errorf("Keyword deltas = %+v, want %+v", comparison.Keywords, wantKeywords)
+	}
+	wantDomains := []CountDelta{
+		{Name: "reddit.com", Before: 8, After: 12},
+		{Name: "news.com", Before: 2, After: 0},
+	}
+	if !slices.Equal(comparison.Domains, wantDomains) {
+		t.Errorf("Domain deltas = %+v, want %+v", comparison.Domains, wantDomains)
+	}
+
+	if percent, ok := wantKeywords[0].Percent(); !ok || percent != -80 {
+		t.Errorf("casino percent = %v (%v), want -80", percent, ok)
+	}
+	if _, ok := wantKeywords[1].Percent(); ok {
+		t.Error("A keyword new in the second period has no percentage")
+	}
+}
@@ -2,6 +2,7 @@ package reports
 
 import (
 	"sort"
+	"strings"
 	"time"
 )
 
@@ -110,6 +111,69 @@ func TopN(counts map[string]int, n int) []CountItem {
 	return items
 }
 
+// Uncategorized is the category of domains the config gives none.
+const Uncategorized = "uncategorized"
+
+// CategoryOf returns the category of domain in categories (domain name ->
+// category). Parent domains are looked up too, so "old.reddit.com" falls under a
+// "reddit.com" entry. Domains without one return Uncategorized.
+func CategoryOf(domain string, categories map[string]string) string {
+	name := strings.TrimPrefix(domain, "www.")
+	for {
+		if category, ok := categories[name]; ok {
+			return category
+		}
+		_, parent, found := strings.Cut(name, ".")
+		if !found || !strings.Contains(parent, ".") {
+			return Uncategorized
+		}
+		name = parent
+	}
+}
+
+// CountByCategory adds up per-domain counts by category.
+func CountByCategory(byDomain map[string]int, categories map[string]string) map[string]int {
+	result := make(map[string]int)
+	for domain, count := range byDomain {
+		result[CategoryOf(domain, categories)] += count
+	}
+	return result
+}
+
+// CategoryGroup is the items of one category, with their total count.
+type CategoryGroup struct {
+	Category string
+	Count    int
+	Items    []CountItem
+}
+
+// GroupByCategory groups domain count items by category, keeping the order of
+// the items within each group. Groups are sorted by total count descending,
+// with Uncategorized last.
+func GroupByCategory(items []CountItem, categories map[string]string) []CategoryGroup {
+	var groups []CategoryGroup
+	index := make(map[string]int)
+	for _, item := range items {
+		category := CategoryOf(item.Name, categories)
+		i, ok := index[category]
+		if !ok {
+			i = len(groups)
+			index[category] = i
+			groups = append(groups, CategoryGroup{Category: category})
+		}
+		groups[i].Count += item.Count
+		groups[i].Items = append(groups[i].Items, item)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if (groups[i].Category == Uncategorized) != (groups[j].Category == Uncategorized) {
+			return groups[j].Category == Uncategorized
+		}
+		return groups[i].Count > groups[j].Count
+	})
+	return groups
+}
+
 // GroupUnblocksByDay groups unblock entries by day.
 func GroupUnblocksByDay(entries []UnblockEntry) map[string][]UnblockEntry {
 	result := make(map[string][]UnblockEntry)
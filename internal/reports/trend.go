@@ -0,0 +1,88 @@
+package reports
+
+import (
+	"strings"
+	"time"
+)
+
+// trendMaxDays is the longest range the trend is shown per day; longer ranges
+// are shown per month.
+const trendMaxDays = 90
+
+// sparkBlocks are the sparkline levels, lowest first.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// TrendBucket is the number of entries in one day or month.
+type TrendBucket struct {
+	Start time.Time
+	Count int
+}
+
+// BucketByDay counts entries per day from start to end, including days without
+// entries. Entries outside the range are ignored.
+func BucketByDay(entries []ReportEntry, start, end time.Time) []TrendBucket {
+	first := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	return bucketEntries(entries, first, end, "2006-01-02", func(t time.Time) time.Time {
+		return t.AddDate(0, 0, 1)
+	})
+}
+
+// BucketByMonth counts entries per month from start to end, including months
+// without entries. Entries outside the range are ignored.
+func BucketByMonth(entries []ReportEntry, start, end time.Time) []TrendBucket {
+	first := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, start.Location())
+	return bucketEntries(entries, first, end, "2006-01", func(t time.Time) time.Time {
+		return t.AddDate(0, 1, 0)
+	})
+}
+
+// BucketTrend buckets entries by day, or by month when start and end are more
+// than trendMaxDays apart. It reports whether the buckets are months.
+func BucketTrend(entries []ReportEntry, start, end time.Time) ([]TrendBucket, bool) {
+	if end.Sub(start) > trendMaxDays*24*time.Hour {
+		return BucketByMonth(entries, start, end), true
+	}
+	return BucketByDay(entries, start, end), false
+}
+
+// bucketEntries makes a bucket for each period from first until end and counts the
+// entries in each, matching entries to periods by their time formatted with layout.
+func bucketEntries(entries []ReportEntry, first, end time.Time, layout string, next func(time.Time) time.Time) []TrendBucket {
+	var buckets []TrendBucket
+	index := make(map[string]int)
+	for t := first; !t.After(end); t = next(t) {
+		index[t.Format(layout)] = len(buckets)
+		buckets = append(buckets, TrendBucket{Start: t})
+	}
+	for _, e := range entries {
+		if i, ok := index[e.Timestamp.In(first.Location()).Format(layout)]; ok {
+			buckets[i].Count++
+		}
+	}
+	return buckets
+}
+
+// Sparkline renders counts as block characters scaled to the largest count.
+// Zero is always the lowest block and the largest count the highest, so a clean
+// day stands out from a day with a single violation.
+func Sparkline(counts []int) string {
+	maxCount := 0
+	for _, count := range counts {
+		maxCount = max(maxCount, count)
+	}
+
+	var b strings.Builder
+	top := len(sparkBlocks) - 1
+	for _, count := range counts {
+		level := 0
+		switch {
+		case count <= 0:
+		case maxCount == 1:
+			level = top
+		default:
+			level = 1 + (count-1)*(top-1)/(maxCount-1)
+		}
+		b.WriteRune(sparkBlocks[level])
+	}
+	return b.String()
+}
@@ -0,0 +1,20 @@
+// Package schema versions glocker's machine-readable outputs: -status -json,
+// -info -json, -json errors, audit log events and webhook payloads. Each
+// carries a top-level schema_version field set to Version.
+//
+// Compatibility: adding a field doesn't change the version, so consumers should
+// ignore fields they don't know. Bump Version when a field is removed or
+// renamed, or its type or meaning changes, and note the change below.
+//
+//	1: first versioned schema
+package schema
+
+// Version is the current schema_version of every JSON output.
+const Version = 1
+
+// Compatible reports whether a document with the given schema_version can be
+// read as the current schema. Documents written before outputs were versioned
+// have no schema_version (0) and share version 1's layout.
+func Compatible(version int) bool {
+	return version >= 0 && version <= Version
+}
@@ -0,0 +1,11 @@
+package schema
+
+import "testing"
+
+func TestCompatible(t *testing.T) {
+	for version, want := range map[int]bool{-1: false, 0: true, Version: true, Version + 1: false} {
+		if got := Compatible(version); got != want {
+			t.Errorf("Compatible(%d) = %v, want %v", version, got, want)
+		}
+	}
+}
@@ -1,13 +1,25 @@
 package state
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"slices"
 	"sync"
 	"time"
 
 	"glocker/internal/config"
+	"glocker/internal/utils"
 )
 
+// clock supplies the current time to state bookkeeping; tests replace it with SetClock.
+var clock utils.TimeProvider = utils.DefaultTimeProvider{}
+
+// SetClock makes state bookkeeping read the current time from c.
+func SetClock(c utils.TimeProvider) {
+	clock = c
+}
+
 // FileChecksum represents a file's checksum for tamper detection.
 type FileChecksum struct {
 	Path     string
@@ -23,6 +35,14 @@ func (f FileChecksum) String() string {
 type TempUnblock struct {
 	Domain    string
 	ExpiresAt time.Time
+	Duration  time.Duration // Length of the unblock as granted (0 if unknown)
+}
+
+// TempBlock is a domain blocked until ExpiresAt on top of the configured blocks,
+// by a focus session.
+type TempBlock struct {
+	Domain    string
+	ExpiresAt time.Time
 }
 
 // ContentReport represents a content monitoring violation from the browser extension.
@@ -50,6 +70,14 @@ type UnblockStats struct {
 	DomainCounts map[string]int    `json:"domain_counts"`
 }
 
+// PendingUninstall is an uninstall request that can only be confirmed once
+// UnlockAt has passed.
+type PendingUninstall struct {
+	Reason      string
+	RequestedAt time.Time
+	UnlockAt    time.Time
+}
+
 // LifecycleLogEntry represents a logged install/uninstall event.
 type LifecycleLogEntry struct {
 	Timestamp time.Time `json:"timestamp"`
@@ -73,6 +101,38 @@ type Violation struct {
 	Type      string // "web_access", "content_report", "forbidden_program"
 }
 
+// ResourceSample is a point-in-time measurement of the daemon's own resource usage.
+type ResourceSample struct {
+	Timestamp  time.Time
+	RSSBytes   uint64
+	HeapBytes  uint64
+	Goroutines int
+	NumGC      uint32
+	GCPause    time.Duration // cumulative GC stop-the-world pause time
+}
+
+// SelfTestResult is the outcome of the last block self-test.
+type SelfTestResult struct {
+	Time      time.Time
+	Sampled   int      // Blocked domains checked
+	Verified  int      // Checked domains confirmed unreachable
+	Reachable []string // Checked domains that were still reachable
+}
+
+// EnforcementEvent is an enforcement decision, kept for glocker -history.
+type EnforcementEvent struct {
+	Time         time.Time
+	Action       string // What was done, e.g. "full enforcement" or "hosts update"
+	Reason       string // Why, e.g. "time window state changed for example.com"
+	BlockedCount int    // Domains blocked afterwards
+}
+
+// enforcementHistorySize is how many enforcement events are kept in memory.
+const enforcementHistorySize = 50
+
+// maxResourceSamples is how many resource samples are kept in memory.
+const maxResourceSamples = 12
+
 // Global state variables (private, accessed via functions)
 var (
 	// Panic mode state
@@ -80,6 +140,14 @@ var (
 	lastSuspendTime time.Time
 	panicMutex      sync.RWMutex
 
+	// Enforcement pause state
+	pausedUntil time.Time
+	pauseMutex  sync.RWMutex
+
+	// Uninstall waiting out uninstall.cooldown_minutes
+	pendingUninstall      *PendingUninstall
+	pendingUninstallMutex sync.RWMutex
+
 	// Email rate limiting
 	lastEmailTimes = make(map[string]time.Time)
 	emailMutex     sync.RWMutex
@@ -94,16 +162,95 @@ var (
 	tempUnblocks      []TempUnblock
 	tempUnblocksMutex sync.RWMutex
 
+	// Temporary blocks (focus sessions)
+	tempBlocks      []TempBlock
+	tempBlocksMutex sync.RWMutex
+
 	// SSE clients (for browser extension updates)
 	sseClients      []chan string
 	sseClientsMutex sync.RWMutex
 
+	// Loops told when the config is reloaded
+	reloadListeners      []chan struct{}
+	reloadListenersMutex sync.RWMutex
+
 	// Violation tracking
 	violations         []Violation
 	violationsMutex    sync.RWMutex
 	lastViolationReset time.Time
+	totalViolations    int // Since the daemon started, across resets
+
+	// Event counters since the daemon started (for /metrics)
+	tamperEvents  int
+	emailsSent    int
+	emailsFailed  int
+	countersMutex sync.RWMutex
+
+	// Active profile (runtime profile switch)
+	activeProfile      string
+	activeProfileSince time.Time
+	activeProfileMutex sync.RWMutex
+
+	// Hosts file write progress
+	hostsWriteActive bool
+	hostsWriteDone   int
+	hostsWriteTotal  int
+	hostsWriteMutex  sync.RWMutex
+
+	// When domains were added to the block list at runtime (-block or reload)
+	blockAddedTimes      = make(map[string]time.Time)
+	blockAddedTimesMutex sync.RWMutex
+
+	// Keywords added at runtime with -add-keyword (kept across reloads)
+	runtimeKeywords      []string
+	runtimeKeywordsMutex sync.RWMutex
+
+	// When temporary unblocks were granted (for the daily unblock limit)
+	unblockGrants      []time.Time
+	unblockGrantsMutex sync.RWMutex
+
+	// Daemon resource samples (oldest first)
+	resourceSamples      []ResourceSample
+	resourceSamplesMutex sync.RWMutex
+
+	// Last block self-test
+	selfTestResult      *SelfTestResult
+	selfTestResultMutex sync.RWMutex
+
+	// Ring buffer of the most recent enforcement events; enforcementHistoryNext
+	// is where the next event goes
+	enforcementHistory      [enforcementHistorySize]EnforcementEvent
+	enforcementHistoryNext  int
+	enforcementHistoryCount int
+	enforcementHistoryMutex sync.RWMutex
 )
 
+// Uninstall functions
+
+// GetPendingUninstall returns the uninstall waiting for confirmation, if any.
+func GetPendingUninstall() (PendingUninstall, bool) {
+	pendingUninstallMutex.RLock()
+	defer pendingUninstallMutex.RUnlock()
+	if pendingUninstall == nil {
+		return PendingUninstall{}, false
+	}
+	return *pendingUninstall, true
+}
+
+// SetPendingUninstall records an uninstall waiting for confirmation.
+func SetPendingUninstall(p PendingUninstall) {
+	pendingUninstallMutex.Lock()
+	defer pendingUninstallMutex.Unlock()
+	pendingUninstall = &p
+}
+
+// ClearPendingUninstall forgets the pending uninstall.
+func ClearPendingUninstall() {
+	pendingUninstallMutex.Lock()
+	defer pendingUninstallMutex.Unlock()
+	pendingUninstall = nil
+}
+
 // Panic mode functions
 
 // GetPanicUntil returns the time until which panic mode is active.
@@ -134,6 +281,41 @@ func SetLastSuspendTime(t time.Time) {
 	lastSuspendTime = t
 }
 
+// Pause functions
+
+// GetPausedUntil returns the time until which enforcement is paused (zero if never paused).
+func GetPausedUntil() time.Time {
+	pauseMutex.RLock()
+	defer pauseMutex.RUnlock()
+	return pausedUntil
+}
+
+// SetPausedUntil pauses enforcement until t. A zero time clears the pause.
+func SetPausedUntil(t time.Time) {
+	pauseMutex.Lock()
+	defer pauseMutex.Unlock()
+	pausedUntil = t
+}
+
+// IsPaused reports whether enforcement is paused at the given time.
+func IsPaused(now time.Time) bool {
+	pauseMutex.RLock()
+	defer pauseMutex.RUnlock()
+	return now.Before(pausedUntil)
+}
+
+// EndExpiredPause clears a pause that has run out by the given time and reports
+// whether it did, so enforcement is resumed exactly once.
+func EndExpiredPause(now time.Time) bool {
+	pauseMutex.Lock()
+	defer pauseMutex.Unlock()
+	if pausedUntil.IsZero() || now.Before(pausedUntil) {
+		return false
+	}
+	pausedUntil = time.Time{}
+	return true
+}
+
 // Email rate limiting functions
 
 // GetLastEmailTime returns the last time an email was sent for a specific event type.
@@ -240,6 +422,17 @@ func AddTempUnblock(domain string, expiresAt time.Time) {
 	})
 }
 
+// AddTempUnblockFor adds a temporary unblock entry, recording the duration it was granted for.
+func AddTempUnblockFor(domain string, expiresAt time.Time, duration time.Duration) {
+	tempUnblocksMutex.Lock()
+	defer tempUnblocksMutex.Unlock()
+	tempUnblocks = append(tempUnblocks, TempUnblock{
+		Domain:    domain,
+		ExpiresAt: expiresAt,
+		Duration:  duration,
+	})
+}
+
 // SetTempUnblocks replaces the temporary unblocks list.
 func SetTempUnblocks(unblocks []TempUnblock) {
 	tempUnblocksMutex.Lock()
@@ -247,6 +440,98 @@ func SetTempUnblocks(unblocks []TempUnblock) {
 	tempUnblocks = unblocks
 }
 
+// RemoveTempUnblock drops every temporary unblock of domain and returns them.
+func RemoveTempUnblock(domain string) []TempUnblock {
+	tempUnblocksMutex.Lock()
+	defer tempUnblocksMutex.Unlock()
+
+	var kept, removed []TempUnblock
+	for _, unblock := range tempUnblocks {
+		if unblock.Domain == domain {
+			removed = append(removed, unblock)
+		} else {
+			kept = append(kept, unblock)
+		}
+	}
+	if len(removed) > 0 {
+		tempUnblocks = kept
+	}
+	return removed
+}
+
+// RemoveExpiredTempUnblocks drops unblocks that have expired at now and returns
+// them. Filtering under the lock keeps unblocks added concurrently from being lost.
+func RemoveExpiredTempUnblocks(now time.Time) []TempUnblock {
+	tempUnblocksMutex.Lock()
+	defer tempUnblocksMutex.Unlock()
+
+	var active, expired []TempUnblock
+	for _, unblock := range tempUnblocks {
+		if now.Before(unblock.ExpiresAt) {
+			active = append(active, unblock)
+		} else {
+			expired = append(expired, unblock)
+		}
+	}
+	if len(expired) > 0 {
+		tempUnblocks = active
+	}
+	return expired
+}
+
+// Temporary block functions
+
+// GetTempBlocks returns a copy of the temporary blocks list.
+func GetTempBlocks() []TempBlock {
+	tempBlocksMutex.RLock()
+	defer tempBlocksMutex.RUnlock()
+	result := make([]TempBlock, len(tempBlocks))
+	copy(result, tempBlocks)
+	return result
+}
+
+// AddTempBlock blocks domain until expiresAt. A domain already temporarily
+// blocked keeps the later of the two expiry times.
+func AddTempBlock(domain string, expiresAt time.Time) {
+	tempBlocksMutex.Lock()
+	defer tempBlocksMutex.Unlock()
+	for i := range tempBlocks {
+		if tempBlocks[i].Domain == domain {
+			if expiresAt.After(tempBlocks[i].ExpiresAt) {
+				tempBlocks[i].ExpiresAt = expiresAt
+			}
+			return
+		}
+	}
+	tempBlocks = append(tempBlocks, TempBlock{Domain: domain, ExpiresAt: expiresAt})
+}
+
+// SetTempBlocks replaces the temporary blocks list.
+func SetTempBlocks(blocks []TempBlock) {
+	tempBlocksMutex.Lock()
+	defer tempBlocksMutex.Unlock()
+	tempBlocks = blocks
+}
+
+// RemoveExpiredTempBlocks drops blocks that have expired at now and returns them.
+func RemoveExpiredTempBlocks(now time.Time) []TempBlock {
+	tempBlocksMutex.Lock()
+	defer tempBlocksMutex.Unlock()
+
+	var active, expired []TempBlock
+	for _, block := range tempBlocks {
+		if now.Before(block.ExpiresAt) {
+			active = append(active, block)
+		} else {
+			expired = append(expired, block)
+		}
+	}
+	if len(expired) > 0 {
+		tempBlocks = active
+	}
+	return expired
+}
+
 // SSE client functions
 
 // AddSSEClient adds a new SSE client channel.
@@ -288,6 +573,29 @@ func GetSSEClientCount() int {
 	return len(sseClients)
 }
 
+// Reload notification functions
+
+// AddReloadListener registers a channel that NotifyReload signals. Give it a
+// buffer of one so a reload isn't missed while the listener is busy.
+func AddReloadListener(ch chan struct{}) {
+	reloadListenersMutex.Lock()
+	defer reloadListenersMutex.Unlock()
+	reloadListeners = append(reloadListeners, ch)
+}
+
+// NotifyReload tells every reload listener that the config was reloaded.
+// Listeners that already have a notice pending aren't signalled twice.
+func NotifyReload() {
+	reloadListenersMutex.RLock()
+	defer reloadListenersMutex.RUnlock()
+	for _, listener := range reloadListeners {
+		select {
+		case listener <- struct{}{}:
+		default:
+		}
+	}
+}
+
 // Violation tracking functions
 
 // GetViolations returns a copy of the violations list.
@@ -304,6 +612,15 @@ func AddViolation(v Violation) {
 	violationsMutex.Lock()
 	defer violationsMutex.Unlock()
 	violations = append(violations, v)
+	totalViolations++
+}
+
+// GetTotalViolations returns the number of violations recorded since the daemon
+// started, including those cleared by resets.
+func GetTotalViolations() int {
+	violationsMutex.RLock()
+	defer violationsMutex.RUnlock()
+	return totalViolations
 }
 
 // ClearViolations clears all violations.
@@ -311,7 +628,7 @@ func ClearViolations() {
 	violationsMutex.Lock()
 	defer violationsMutex.Unlock()
 	violations = nil
-	lastViolationReset = time.Now()
+	lastViolationReset = clock.Now()
 }
 
 // GetLastViolationReset returns the last time violations were reset.
@@ -327,3 +644,348 @@ func SetLastViolationReset(t time.Time) {
 	defer violationsMutex.Unlock()
 	lastViolationReset = t
 }
+
+// Hosts write progress functions
+
+// SetHostsWriteProgress records that a hosts file write is in progress with
+// written of total domains completed.
+func SetHostsWriteProgress(written, total int) {
+	hostsWriteMutex.Lock()
+	defer hostsWriteMutex.Unlock()
+	hostsWriteActive = true
+	hostsWriteDone = written
+	hostsWriteTotal = total
+}
+
+// ClearHostsWriteProgress marks the current hosts file write as finished.
+func ClearHostsWriteProgress() {
+	hostsWriteMutex.Lock()
+	defer hostsWriteMutex.Unlock()
+	hostsWriteActive = false
+	hostsWriteDone = 0
+	hostsWriteTotal = 0
+}
+
+// GetHostsWriteProgress returns the progress of an ongoing hosts file write.
+// active is false when no write is in progress.
+func GetHostsWriteProgress() (written, total int, active bool) {
+	hostsWriteMutex.RLock()
+	defer hostsWriteMutex.RUnlock()
+	return hostsWriteDone, hostsWriteTotal, hostsWriteActive
+}
+
+// Profile functions
+
+// GetActiveProfile returns the name of the active profile ("" if none) and when it was activated.
+func GetActiveProfile() (string, time.Time) {
+	activeProfileMutex.RLock()
+	defer activeProfileMutex.RUnlock()
+	return activeProfile, activeProfileSince
+}
+
+// SetActiveProfile switches the active profile. Pass an empty name to revert to the base config.
+func SetActiveProfile(name string, since time.Time) {
+	activeProfileMutex.Lock()
+	defer activeProfileMutex.Unlock()
+	activeProfile = name
+	activeProfileSince = since
+}
+
+// Block add-time functions
+
+// RecordBlockAdded records when a domain was added to the block list.
+func RecordBlockAdded(domain string, t time.Time) {
+	blockAddedTimesMutex.Lock()
+	defer blockAddedTimesMutex.Unlock()
+	blockAddedTimes[domain] = t
+}
+
+// GetBlockAddedTime returns when a domain was added to the block list at runtime.
+// ok is false for domains that were already blocked when they were first seen.
+func GetBlockAddedTime(domain string) (t time.Time, ok bool) {
+	blockAddedTimesMutex.RLock()
+	defer blockAddedTimesMutex.RUnlock()
+	t, ok = blockAddedTimes[domain]
+	return t, ok
+}
+
+// PruneBlockAddedTimes drops domains added before the given time.
+func PruneBlockAddedTimes(before time.Time) {
+	blockAddedTimesMutex.Lock()
+	defer blockAddedTimesMutex.Unlock()
+	for domain, t := range blockAddedTimes {
+		if t.Before(before) {
+			delete(blockAddedTimes, domain)
+		}
+	}
+}
+
+// SetBlockAddedTimes replaces the recorded add times.
+func SetBlockAddedTimes(times map[string]time.Time) {
+	blockAddedTimesMutex.Lock()
+	defer blockAddedTimesMutex.Unlock()
+	if times == nil {
+		times = make(map[string]time.Time)
+	}
+	blockAddedTimes = times
+}
+
+// blockAddedFile is the on-disk format of the block add-time state file.
+type blockAddedFile struct {
+	Added map[string]time.Time `json:"added"` // domain -> when it was added
+}
+
+// SaveBlockAddedTimes writes the recorded add times to path as JSON.
+func SaveBlockAddedTimes(path string) error {
+	blockAddedTimesMutex.RLock()
+	data, err := json.Marshal(blockAddedFile{Added: blockAddedTimes})
+	blockAddedTimesMutex.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal block add times: %w", err)
+	}
+	return writeStateFile(path, data)
+}
+
+// LoadBlockAddedTimes replaces the recorded add times with those saved at path.
+// A missing file leaves none recorded and is not an error.
+func LoadBlockAddedTimes(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		SetBlockAddedTimes(nil)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read block add times: %w", err)
+	}
+
+	var file blockAddedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse block add times: %w", err)
+	}
+	SetBlockAddedTimes(file.Added)
+	return nil
+}
+
+// Runtime keyword functions
+
+// AddRuntimeKeyword records a keyword added with -add-keyword, so a config
+// reload can keep it. Recording the same keyword twice has no effect.
+func AddRuntimeKeyword(keyword string) {
+	runtimeKeywordsMutex.Lock()
+	defer runtimeKeywordsMutex.Unlock()
+	if !slices.Contains(runtimeKeywords, keyword) {
+		runtimeKeywords = append(runtimeKeywords, keyword)
+	}
+}
+
+// GetRuntimeKeywords returns a copy of the keywords added with -add-keyword
+// since the daemon started, oldest first.
+func GetRuntimeKeywords() []string {
+	runtimeKeywordsMutex.RLock()
+	defer runtimeKeywordsMutex.RUnlock()
+	return slices.Clone(runtimeKeywords)
+}
+
+// SetRuntimeKeywords replaces the recorded runtime keywords.
+func SetRuntimeKeywords(keywords []string) {
+	runtimeKeywordsMutex.Lock()
+	defer runtimeKeywordsMutex.Unlock()
+	runtimeKeywords = keywords
+}
+
+// Unblock grant functions
+
+// unblockGrantsFile is the on-disk format of the unblock grant state file.
+type unblockGrantsFile struct {
+	Grants []time.Time `json:"grants"`
+}
+
+// ClaimUnblockGrant records a grant at t if fewer than limit were granted at or
+// after since, and reports whether it did. Counting and recording happen under
+// one lock, so concurrent requests can't both take the last grant.
+func ClaimUnblockGrant(t, since time.Time, limit int) bool {
+	unblockGrantsMutex.Lock()
+	defer unblockGrantsMutex.Unlock()
+	count := 0
+	for _, granted := range unblockGrants {
+		if !granted.Before(since) {
+			count++
+		}
+	}
+	if count >= limit {
+		return false
+	}
+	unblockGrants = append(unblockGrants, t)
+	return true
+}
+
+// CountUnblockGrantsSince returns how many unblocks were granted at or after since.
+func CountUnblockGrantsSince(since time.Time) int {
+	unblockGrantsMutex.RLock()
+	defer unblockGrantsMutex.RUnlock()
+	count := 0
+	for _, t := range unblockGrants {
+		if !t.Before(since) {
+			count++
+		}
+	}
+	return count
+}
+
+// PruneUnblockGrants drops grants made before the given time.
+func PruneUnblockGrants(before time.Time) {
+	unblockGrantsMutex.Lock()
+	defer unblockGrantsMutex.Unlock()
+	var kept []time.Time
+	for _, t := range unblockGrants {
+		if !t.Before(before) {
+			kept = append(kept, t)
+		}
+	}
+	unblockGrants = kept
+}
+
+// SetUnblockGrants replaces the recorded unblock grants.
+func SetUnblockGrants(grants []time.Time) {
+	unblockGrantsMutex.Lock()
+	defer unblockGrantsMutex.Unlock()
+	unblockGrants = grants
+}
+
+// SaveUnblockGrants writes the recorded unblock grants to path as JSON.
+func SaveUnblockGrants(path string) error {
+	unblockGrantsMutex.RLock()
+	data, err := json.Marshal(unblockGrantsFile{Grants: unblockGrants})
+	unblockGrantsMutex.RUnlock()
+	if err != nil {
+		return fmt.Errorf("failed to marshal unblock grants: %w", err)
+	}
+	return writeStateFile(path, data)
+}
+
+// writeStateFile replaces the state file at path with data, creating its
+// directory. It writes to a temp file and renames it, so a crash never leaves
+// a truncated file.
+func writeStateFile(path string, data []byte) error {
+	return utils.WriteFileAtomic(path, data, 0644)
+}
+
+// LoadUnblockGrants replaces the recorded unblock grants with those saved at path.
+// A missing file leaves no grants recorded and is not an error.
+func LoadUnblockGrants(path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		SetUnblockGrants(nil)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read unblock grants: %w", err)
+	}
+
+	var file unblockGrantsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse unblock grants: %w", err)
+	}
+	SetUnblockGrants(file.Grants)
+	return nil
+}
+
+// Event counter functions
+
+// RecordTamperEvent counts a detected tampering attempt.
+func RecordTamperEvent() {
+	countersMutex.Lock()
+	defer countersMutex.Unlock()
+	tamperEvents++
+}
+
+// GetTamperEventCount returns the number of tampering attempts detected since the daemon started.
+func GetTamperEventCount() int {
+	countersMutex.RLock()
+	defer countersMutex.RUnlock()
+	return tamperEvents
+}
+
+// RecordEmailResult counts an accountability email as sent or, if err is set, failed.
+func RecordEmailResult(err error) {
+	countersMutex.Lock()
+	defer countersMutex.Unlock()
+	if err != nil {
+		emailsFailed++
+	} else {
+		emailsSent++
+	}
+}
+
+// GetEmailCounts returns the number of emails sent and failed since the daemon started.
+func GetEmailCounts() (sent, failed int) {
+	countersMutex.RLock()
+	defer countersMutex.RUnlock()
+	return emailsSent, emailsFailed
+}
+
+// Resource sample functions
+
+// AddResourceSample records a resource sample, keeping only the most recent ones.
+func AddResourceSample(sample ResourceSample) {
+	resourceSamplesMutex.Lock()
+	defer resourceSamplesMutex.Unlock()
+	resourceSamples = append(resourceSamples, sample)
+	if len(resourceSamples) > maxResourceSamples {
+		resourceSamples = append([]ResourceSample(nil), resourceSamples[len(resourceSamples)-maxResourceSamples:]...)
+	}
+}
+
+// GetResourceSamples returns a copy of the recorded resource samples, oldest first.
+func GetResourceSamples() []ResourceSample {
+	resourceSamplesMutex.RLock()
+	defer resourceSamplesMutex.RUnlock()
+	result := make([]ResourceSample, len(resourceSamples))
+	copy(result, resourceSamples)
+	return result
+}
+
+// Self-test functions
+
+// SetSelfTestResult records the outcome of a block self-test.
+func SetSelfTestResult(result SelfTestResult) {
+	selfTestResultMutex.Lock()
+	defer selfTestResultMutex.Unlock()
+	selfTestResult = &result
+}
+
+// GetSelfTestResult returns the last self-test result, if a self-test has run.
+func GetSelfTestResult() (SelfTestResult, bool) {
+	selfTestResultMutex.RLock()
+	defer selfTestResultMutex.RUnlock()
+	if selfTestResult == nil {
+		return SelfTestResult{}, false
+	}
+	result := *selfTestResult
+	result.Reachable = append([]string(nil), selfTestResult.Reachable...)
+	return result, true
+}
+
+// Enforcement history functions
+
+// RecordEnforcement adds an event to the enforcement history, replacing the
+// oldest one once enforcementHistorySize events are kept.
+func RecordEnforcement(event EnforcementEvent) {
+	enforcementHistoryMutex.Lock()
+	defer enforcementHistoryMutex.Unlock()
+	enforcementHistory[enforcementHistoryNext] = event
+	enforcementHistoryNext = (enforcementHistoryNext + 1) % enforcementHistorySize
+	enforcementHistoryCount = min(enforcementHistoryCount+1, enforcementHistorySize)
+}
+
+// GetEnforcementHistory returns the recorded enforcement events, oldest first.
+func GetEnforcementHistory() []EnforcementEvent {
+	enforcementHistoryMutex.RLock()
+	defer enforcementHistoryMutex.RUnlock()
+	result := make([]EnforcementEvent, 0, enforcementHistoryCount)
+	start := enforcementHistoryNext - enforcementHistoryCount + enforcementHistorySize
+	for i := 0; i < enforcementHistoryCount; i++ {
+		result = append(result, enforcementHistory[(start+i)%enforcementHistorySize])
+	}
+	return result
+}
@@ -1,6 +1,11 @@
 package state
 
 import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -25,6 +30,44 @@ func TestPanicMode(t *testing.T) {
 	}
 }
 
+func TestPauseTransitions(t *testing.T) {
+	start := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+	SetPausedUntil(time.Time{})
+	defer SetPausedUntil(time.Time{})
+
+	// Never paused: nothing to end
+	if IsPaused(start) || EndExpiredPause(start) {
+		t.Error("Expected no pause before one is set")
+	}
+
+	// Active pause
+	until := start.Add(15 * time.Minute)
+	SetPausedUntil(until)
+	if !IsPaused(start) || !IsPaused(until.Add(-time.Second)) {
+		t.Error("Expected enforcement to be paused until the end time")
+	}
+	if EndExpiredPause(start) {
+		t.Error("An active pause should not be ended")
+	}
+	if !GetPausedUntil().Equal(until) {
+		t.Errorf("GetPausedUntil() = %v, want %v", GetPausedUntil(), until)
+	}
+
+	// Expired pause is ended exactly once
+	if IsPaused(until) {
+		t.Error("Expected the pause to be over at its end time")
+	}
+	if !EndExpiredPause(until) {
+		t.Error("Expected the expired pause to be ended")
+	}
+	if EndExpiredPause(until.Add(time.Minute)) {
+		t.Error("Expected an ended pause not to be ended again")
+	}
+	if !GetPausedUntil().IsZero() {
+		t.Errorf("Expected the pause to be cleared, got %v", GetPausedUntil())
+	}
+}
+
 func TestEmailRateLimiting(t *testing.T) {
 	// Test setting and getting email time
 	eventType := "test_event"
@@ -89,6 +132,92 @@ func TestTempUnblocks(t *testing.T) {
 	}
 }
 
+func TestTempBlocks(t *testing.T) {
+	SetTempBlocks(nil)
+	t.Cleanup(func() { SetTempBlocks(nil) })
+
+	now := time.Date(2026, 1, 6, 10, 0, 0, 0, time.Local)
+	AddTempBlock("reddit.com", now.Add(50*time.Minute))
+	AddTempBlock("news.com", now.Add(25*time.Minute))
+
+	// A second session keeps the later expiry
+	AddTempBlock("reddit.com", now.Add(30*time.Minute))
+	AddTempBlock("news.com", now.Add(50*time.Minute))
+
+	blocks := GetTempBlocks()
+	if len(blocks) != 2 {
+		t.Fatalf("Expected 2 temp blocks, got %+v", blocks)
+	}
+	for _, block := range blocks {
+		if !block.ExpiresAt.Equal(now.Add(50 * time.Minute)) {
+			t.Errorf("Expected %s to expire at 10:50, got %v", block.Domain, block.ExpiresAt)
+		}
+	}
+
+	if expired := RemoveExpiredTempBlocks(now.Add(49 * time.Minute)); len(expired) != 0 {
+		t.Errorf("Expected nothing expired at 10:49, got %+v", expired)
+	}
+	if expired := RemoveExpiredTempBlocks(now.Add(50 * time.Minute)); len(expired) != 2 {
+		t.Errorf("Expected both blocks expired at 10:50, got %+v", expired)
+	}
+	if blocks := GetTempBlocks(); len(blocks) != 0 {
+		t.Errorf("Expected no temp blocks left, got %+v", blocks)
+	}
+}
+
+// TestTempUnblocks_Concurrent adds, cleans up and reads unblocks from many
+// goroutines at once, as the socket handlers, enforcement loop and web server do.
+// Run with -race to check the accessors for data races.
+func TestTempUnblocks_Concurrent(t *testing.T) {
+	SetTempUnblocks(nil)
+	defer SetTempUnblocks(nil)
+
+	now := time.Date(2026, 1, 6, 12, 0, 0, 0, time.UTC)
+	const workers, perWorker = 8, 50
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(3)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				// Alternate active and already expired unblocks
+				expiresAt := now.Add(time.Hour)
+				if i%2 == 1 {
+					expiresAt = now.Add(-time.Minute)
+				}
+				AddTempUnblockFor(fmt.Sprintf("w%d-%d.com", w, i), expiresAt, time.Hour)
+			}
+		}(w)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				RemoveExpiredTempUnblocks(now)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				for _, unblock := range GetTempUnblocks() {
+					_ = unblock.Domain
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	RemoveExpiredTempUnblocks(now)
+
+	// Every active unblock survives the concurrent cleanups
+	if got, want := len(GetTempUnblocks()), workers*perWorker/2; got != want {
+		t.Errorf("Expected %d active unblocks after cleanup, got %d", want, got)
+	}
+	for _, unblock := range GetTempUnblocks() {
+		if !now.Before(unblock.ExpiresAt) {
+			t.Errorf("Expired unblock %s was not removed", unblock.Domain)
+		}
+	}
+}
+
 func TestSSEClients(t *testing.T) {
 	// Create a test channel
 	ch1 := make(chan string, 1)
@@ -147,6 +276,64 @@ func TestSSEClients(t *testing.T) {
 	RemoveSSEClient(ch2)
 }
 
+func TestNotifyReload(t *testing.T) {
+	ch := make(chan struct{}, 1)
+	AddReloadListener(ch)
+
+	// A second notice while one is pending doesn't block
+	NotifyReload()
+	NotifyReload()
+
+	select {
+	case <-ch:
+	default:
+		t.Fatal("Listener wasn't notified of the reload")
+	}
+	select {
+	case <-ch:
+		t.Error("Listener got a second notice for the same pending reload")
+	default:
+	}
+}
+
+func TestEnforcementHistory_Wraparound(t *testing.T) {
+	enforcementHistoryMutex.Lock()
+	enforcementHistoryNext, enforcementHistoryCount = 0, 0
+	enforcementHistoryMutex.Unlock()
+
+	if history := GetEnforcementHistory(); len(history) != 0 {
+		t.Fatalf("Expected an empty history, got %d events", len(history))
+	}
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	record := func(n int) {
+		RecordEnforcement(EnforcementEvent{Time: base.Add(time.Duration(n) * time.Minute), Action: "hosts update", BlockedCount: n})
+	}
+
+	for n := 0; n < 3; n++ {
+		record(n)
+	}
+	history := GetEnforcementHistory()
+	if len(history) != 3 || history[0].BlockedCount != 0 || history[2].BlockedCount != 2 {
+		t.Errorf("Expected events 0-2 oldest first, got %+v", history)
+	}
+
+	// Past the buffer size the oldest events are dropped
+	total := enforcementHistorySize + 7
+	for n := 3; n < total; n++ {
+		record(n)
+	}
+	history = GetEnforcementHistory()
+	if len(history) != enforcementHistorySize {
+		t.Fatalf("Expected %d events, got %d", enforcementHistorySize, len(history))
+	}
+	for i, event := range history {
+		if want := total - enforcementHistorySize + i; event.BlockedCount != want {
+			t.Fatalf("history[%d] = event %d, want event %d", i, event.BlockedCount, want)
+		}
+	}
+}
+
 func TestViolations(t *testing.T) {
 	// Clear violations
 	ClearViolations()
@@ -259,6 +446,135 @@ func TestFilesToMonitor(t *testing.T) {
 	}
 }
 
+func TestHostsWriteProgress(t *testing.T) {
+	ClearHostsWriteProgress()
+	if _, _, active := GetHostsWriteProgress(); active {
+		t.Fatal("Expected no hosts write in progress initially")
+	}
+
+	SetHostsWriteProgress(620, 1000)
+	written, total, active := GetHostsWriteProgress()
+	if !active || written != 620 || total != 1000 {
+		t.Errorf("Expected 620/1000 active, got %d/%d active=%v", written, total, active)
+	}
+
+	ClearHostsWriteProgress()
+	if _, _, active := GetHostsWriteProgress(); active {
+		t.Error("Expected hosts write progress to be cleared")
+	}
+}
+
+func TestResourceSamples(t *testing.T) {
+	for i := 0; i < maxResourceSamples+3; i++ {
+		AddResourceSample(ResourceSample{Goroutines: i})
+	}
+
+	samples := GetResourceSamples()
+	if len(samples) != maxResourceSamples {
+		t.Fatalf("Expected %d samples to be kept, got %d", maxResourceSamples, len(samples))
+	}
+	if samples[0].Goroutines != 3 || samples[len(samples)-1].Goroutines != maxResourceSamples+2 {
+		t.Errorf("Expected oldest samples to be dropped, got first=%d last=%d",
+			samples[0].Goroutines, samples[len(samples)-1].Goroutines)
+	}
+}
+
+func TestUnblockGrants(t *testing.T) {
+	defer SetUnblockGrants(nil)
+	base := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+
+	SetUnblockGrants([]time.Time{base.Add(-25 * time.Hour), base.Add(-time.Hour)})
+	if !ClaimUnblockGrant(base, base.Add(-24*time.Hour), 2) {
+		t.Fatal("Expected the second grant of the day to be claimed")
+	}
+	if ClaimUnblockGrant(base, base.Add(-24*time.Hour), 2) {
+		t.Error("Expected a third grant to be refused with a limit of 2")
+	}
+
+	if got := CountUnblockGrantsSince(base.Add(-24 * time.Hour)); got != 2 {
+		t.Errorf("Expected 2 grants in the last day, got %d", got)
+	}
+
+	PruneUnblockGrants(base.Add(-24 * time.Hour))
+	path := filepath.Join(t.TempDir(), "state", "unblock-grants.json")
+	if err := SaveUnblockGrants(path); err != nil {
+		t.Fatalf("SaveUnblockGrants failed: %v", err)
+	}
+
+	SetUnblockGrants(nil)
+	if err := LoadUnblockGrants(path); err != nil {
+		t.Fatalf("LoadUnblockGrants failed: %v", err)
+	}
+	if got := CountUnblockGrantsSince(time.Time{}); got != 2 {
+		t.Errorf("Expected 2 grants after reload (older one pruned), got %d", got)
+	}
+
+	// A missing state file means no grants yet
+	if err := LoadUnblockGrants(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Errorf("Missing state file should not be an error, got: %v", err)
+	}
+	if got := CountUnblockGrantsSince(time.Time{}); got != 0 {
+		t.Errorf("Expected no grants after loading a missing file, got %d", got)
+	}
+}
+
+func TestClaimUnblockGrant_Concurrent(t *testing.T) {
+	defer SetUnblockGrants(nil)
+	SetUnblockGrants(nil)
+	now := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+
+	var wg sync.WaitGroup
+	var claimed atomic.Int32
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ClaimUnblockGrant(now, now.Add(-time.Hour), 3) {
+				claimed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := claimed.Load(); got != 3 {
+		t.Errorf("Expected exactly 3 of 50 concurrent claims to succeed, got %d", got)
+	}
+}
+
+func TestBlockAddedTimes(t *testing.T) {
+	defer SetBlockAddedTimes(nil)
+	base := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+
+	SetBlockAddedTimes(nil)
+	RecordBlockAdded("old.com", base.Add(-49*time.Hour))
+	RecordBlockAdded("new.com", base.Add(-time.Hour))
+
+	PruneBlockAddedTimes(base.Add(-48 * time.Hour))
+	path := filepath.Join(t.TempDir(), "state", "block-added.json")
+	if err := SaveBlockAddedTimes(path); err != nil {
+		t.Fatalf("SaveBlockAddedTimes failed: %v", err)
+	}
+
+	SetBlockAddedTimes(nil)
+	if err := LoadBlockAddedTimes(path); err != nil {
+		t.Fatalf("LoadBlockAddedTimes failed: %v", err)
+	}
+	if added, ok := GetBlockAddedTime("new.com"); !ok || !added.Equal(base.Add(-time.Hour)) {
+		t.Errorf("Expected new.com's add time after reload, got %v (ok=%v)", added, ok)
+	}
+	if _, ok := GetBlockAddedTime("old.com"); ok {
+		t.Error("Expected old.com to have been pruned")
+	}
+
+	// A missing state file means nothing was added yet
+	if err := LoadBlockAddedTimes(filepath.Join(t.TempDir(), "missing.json")); err != nil {
+		t.Errorf("Missing state file should not be an error, got: %v", err)
+	}
+	if _, ok := GetBlockAddedTime("new.com"); ok {
+		t.Error("Expected no add times after loading a missing file")
+	}
+}
+
 func TestFileChecksumString(t *testing.T) {
 	fc := FileChecksum{
 		Path:     "/test/file",
@@ -272,3 +588,47 @@ func TestFileChecksumString(t *testing.T) {
 		t.Errorf("String() = %q, want %q", s, expected)
 	}
 }
+
+func TestEventCounters(t *testing.T) {
+	sentBefore, failedBefore := GetEmailCounts()
+	RecordEmailResult(nil)
+	RecordEmailResult(errors.New("connection refused"))
+	RecordEmailResult(nil)
+	if sent, failed := GetEmailCounts(); sent != sentBefore+2 || failed != failedBefore+1 {
+		t.Errorf("Expected 2 more sent and 1 more failed, got %d sent and %d failed", sent-sentBefore, failed-failedBefore)
+	}
+
+	tamperBefore := GetTamperEventCount()
+	RecordTamperEvent()
+	if got := GetTamperEventCount(); got != tamperBefore+1 {
+		t.Errorf("Expected %d tamper events, got %d", tamperBefore+1, got)
+	}
+
+	// The total survives violation resets
+	totalBefore := GetTotalViolations()
+	AddViolation(Violation{Timestamp: time.Now(), Host: "example.com"})
+	ClearViolations()
+	if got := GetTotalViolations(); got != totalBefore+1 {
+		t.Errorf("Expected %d total violations after reset, got %d", totalBefore+1, got)
+	}
+}
+
+func TestRuntimeKeywords(t *testing.T) {
+	defer SetRuntimeKeywords(nil)
+	SetRuntimeKeywords(nil)
+
+	AddRuntimeKeyword("poker")
+	AddRuntimeKeyword("casino")
+	AddRuntimeKeyword("poker")
+
+	keywords := GetRuntimeKeywords()
+	if len(keywords) != 2 || keywords[0] != "poker" || keywords[1] != "casino" {
+		t.Errorf("Expected [poker casino], got %v", keywords)
+	}
+
+	// The returned slice is a copy
+	keywords[0] = "changed"
+	if GetRuntimeKeywords()[0] != "poker" {
+		t.Error("Modifying the returned keywords changed the recorded ones")
+	}
+}
@@ -0,0 +1,268 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// Resolver configuration files. systemd-resolved's stub (127.0.0.53) answers
+// from /etc/hosts, so the upstream servers it lists are preferred.
+const (
+	resolvConf         = "/etc/resolv.conf"
+	resolvedResolvConf = "/run/systemd/resolve/resolv.conf"
+)
+
+// DNS record types and header bits used by QueryDNS.
+const (
+	dnsTypeA     = 1
+	dnsTypeCNAME = 5
+	dnsTypeAAAA  = 28
+	dnsClassIN   = 1
+
+	dnsFlagResponse  = 1 << 15
+	dnsFlagTruncated = 1 << 9
+	dnsFlagRecursion = 1 << 8
+	dnsRcodeMask     = 0xF
+	dnsRcodeNXDomain = 3
+)
+
+// errDNSTruncated reports a UDP answer that didn't fit and must be asked over TCP.
+var errDNSTruncated = errors.New("truncated DNS response")
+
+// SystemNameservers returns the nameservers the system resolves through: the
+// upstream servers of systemd-resolved when it is in use, else those of
+// /etc/resolv.conf.
+func SystemNameservers() []string {
+	for _, path := range []string{resolvedResolvConf, resolvConf} {
+		if servers := readNameservers(path); len(servers) > 0 {
+			return servers
+		}
+	}
+	return nil
+}
+
+// readNameservers returns the nameserver addresses listed in a resolv.conf.
+func readNameservers(path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var servers []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" && IsIPAddress(fields[1]) {
+			servers = append(servers, fields[1])
+		}
+	}
+	return servers
+}
+
+// ResolveIPsVia looks up the recordType ("A" or "AAAA") addresses of domain
+// by asking servers directly, in order until one answers, so /etc/hosts isn't
+// consulted. It also returns the lowest TTL of the records on the way to them
+// (CNAMEs included). A failed lookup returns no addresses and a zero TTL.
+func ResolveIPsVia(servers []string, domain, recordType string) ([]string, time.Duration) {
+	for _, server := range servers {
+		ips, ttl, err := QueryDNS(server, domain, recordType)
+		if err == nil {
+			return ips, ttl
+		}
+	}
+	return []string{}, 0
+}
+
+// QueryDNS sends one recursive query for domain's recordType records to server
+// ("address" or "address:port"), over UDP and again over TCP if the answer was
+// truncated. A name that doesn't exist is an empty answer, not an error.
+func QueryDNS(server, domain, recordType string) ([]string, time.Duration, error) {
+	var qtype uint16
+	switch recordType {
+	case "A":
+		qtype = dnsTypeA
+	case "AAAA":
+		qtype = dnsTypeAAAA
+	default:
+		return []string{}, 0, fmt.Errorf("unsupported record type %q", recordType)
+	}
+	if IsIPAddress(server) {
+		server = net.JoinHostPort(server, "53")
+	}
+
+	id := uint16(rand.Uint32())
+	query, err := buildDNSQuery(id, domain, qtype)
+	if err != nil {
+		return []string{}, 0, err
+	}
+	response, err := exchangeDNS("udp", server, query)
+	if err == nil {
+		var ips []string
+		var ttl time.Duration
+		ips, ttl, err = parseDNSResponse(response, id, qtype)
+		if err == nil {
+			return ips, ttl, nil
+		}
+	}
+	if !errors.Is(err, errDNSTruncated) {
+		return []string{}, 0, err
+	}
+
+	response, err = exchangeDNS("tcp", server, query)
+	if err != nil {
+		return []string{}, 0, err
+	}
+	ips, ttl, err := parseDNSResponse(response, id, qtype)
+	if err != nil {
+		return []string{}, 0, err
+	}
+	return ips, ttl, nil
+}
+
+// exchangeDNS sends query to server over network ("udp" or "tcp") and returns
+// the response, giving up after resolveTimeout.
+func exchangeDNS(network, server string, query []byte) ([]byte, error) {
+	conn, err := net.DialTimeout(network, server, resolveTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(resolveTimeout))
+
+	if network == "udp" {
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+		buf := make([]byte, 4096)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+
+	// TCP messages are prefixed with their length
+	framed := binary.BigEndian.AppendUint16(nil, uint16(len(query)))
+	if _, err := conn.Write(append(framed, query...)); err != nil {
+		return nil, err
+	}
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// buildDNSQuery encodes a recursive query with one question.
+func buildDNSQuery(id uint16, domain string, qtype uint16) ([]byte, error) {
+	msg := binary.BigEndian.AppendUint16(nil, id)
+	msg = binary.BigEndian.AppendUint16(msg, dnsFlagRecursion)
+	msg = binary.BigEndian.AppendUint16(msg, 1) // QDCOUNT
+	msg = append(msg, 0, 0, 0, 0, 0, 0)         // ANCOUNT, NSCOUNT, ARCOUNT
+
+	for _, label := range strings.Split(strings.TrimSuffix(domain, "."), ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, fmt.Errorf("invalid domain name %q", domain)
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	return binary.BigEndian.AppendUint16(msg, dnsClassIN), nil
+}
+
+// parseDNSResponse returns the qtype addresses in the answer section of msg and
+// the lowest TTL of its address and CNAME records.
+func parseDNSResponse(msg []byte, id, qtype uint16) ([]string, time.Duration, error) {
+	if len(msg) < 12 {
+		return nil, 0, errors.New("short DNS response")
+	}
+	flags := binary.BigEndian.Uint16(msg[2:])
+	switch {
+	case binary.BigEndian.Uint16(msg) != id || flags&dnsFlagResponse == 0:
+		return nil, 0, errors.New("unexpected DNS response")
+	case flags&dnsFlagTruncated != 0:
+		return nil, 0, errDNSTruncated
+	case flags&dnsRcodeMask == dnsRcodeNXDomain:
+		return []string{}, 0, nil
+	case flags&dnsRcodeMask != 0:
+		return nil, 0, fmt.Errorf("DNS error code %d", flags&dnsRcodeMask)
+	}
+
+	questions := int(binary.BigEndian.Uint16(msg[4:]))
+	answers := int(binary.BigEndian.Uint16(msg[6:]))
+	offset := 12
+	for range questions {
+		end, err := skipDNSName(msg, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		offset = end + 4 // QTYPE, QCLASS
+	}
+
+	ips := []string{}
+	minTTL := uint32(0)
+	seenTTL := false
+	for range answers {
+		end, err := skipDNSName(msg, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		if end+10 > len(msg) {
+			return nil, 0, errors.New("short DNS record")
+		}
+		rtype := binary.BigEndian.Uint16(msg[end:])
+		ttl := binary.BigEndian.Uint32(msg[end+4:])
+		length := int(binary.BigEndian.Uint16(msg[end+8:]))
+		data := end + 10
+		if data+length > len(msg) {
+			return nil, 0, errors.New("short DNS record data")
+		}
+		offset = data + length
+
+		if rtype != qtype && rtype != dnsTypeCNAME {
+			continue
+		}
+		if !seenTTL || ttl < minTTL {
+			minTTL, seenTTL = ttl, true
+		}
+		if rtype == qtype && (length == net.IPv4len || length == net.IPv6len) {
+			ips = append(ips, net.IP(msg[data:data+length]).String())
+		}
+	}
+	if len(ips) == 0 {
+		return ips, 0, nil
+	}
+	return ips, time.Duration(minTTL) * time.Second, nil
+}
+
+// skipDNSName returns the offset just past the (possibly compressed) name at
+// offset.
+func skipDNSName(msg []byte, offset int) (int, error) {
+	for offset < len(msg) {
+		length := int(msg[offset])
+		switch {
+		case length == 0:
+			return offset + 1, nil
+		case length&0xC0 == 0xC0:
+			return offset + 2, nil
+		default:
+			offset += 1 + length
+		}
+	}
+	return 0, errors.New("malformed DNS name")
+}
@@ -0,0 +1,114 @@
+package utils
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// dnsRecord is an answer record served by fakeNameserver.
+type dnsRecord struct {
+	rtype uint16
+	ttl   uint32
+	data  []byte
+}
+
+// fakeNameserver answers every UDP query with records, or with the given
+// response code, and returns its address.
+func fakeNameserver(t *testing.T, rcode uint16, records []dnsRecord) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("Can't listen on UDP: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			query := buf[:n]
+			question, _ := skipDNSName(query, 12)
+
+			response := slices.Clone(query[:2])
+			response = binary.BigEndian.AppendUint16(response, dnsFlagResponse|dnsFlagRecursion|rcode)
+			response = binary.BigEndian.AppendUint16(response, 1)
+			response = binary.BigEndian.AppendUint16(response, uint16(len(records)))
+			response = append(response, 0, 0, 0, 0)
+			response = append(response, query[12:question+4]...)
+			for _, record := range records {
+				response = append(response, 0xC0, 12) // Pointer to the question's name
+				response = binary.BigEndian.AppendUint16(response, record.rtype)
+				response = binary.BigEndian.AppendUint16(response, dnsClassIN)
+				response = binary.BigEndian.AppendUint32(response, record.ttl)
+				response = binary.BigEndian.AppendUint16(response, uint16(len(record.data)))
+				response = append(response, record.data...)
+			}
+			conn.WriteTo(response, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestQueryDNS(t *testing.T) {
+	server := fakeNameserver(t, 0, []dnsRecord{
+		{dnsTypeCNAME, 300, []byte{3, 'c', 'd', 'n', 0xC0, 12}},
+		{dnsTypeA, 60, net.ParseIP("93.184.215.14").To4()},
+		{dnsTypeA, 120, net.ParseIP("93.184.215.15").To4()},
+		{dnsTypeAAAA, 30, net.ParseIP("2001:db8::1")}, // Not asked for
+	})
+
+	ips, ttl, err := QueryDNS(server, "www.example.com", "A")
+	if err != nil {
+		t.Fatalf("QueryDNS() error = %v", err)
+	}
+	if want := []string{"93.184.215.14", "93.184.215.15"}; !slices.Equal(ips, want) {
+		t.Errorf("QueryDNS() = %v, want %v", ips, want)
+	}
+	if ttl != 60*time.Second {
+		t.Errorf("QueryDNS() TTL = %v, want the lowest A or CNAME TTL, 1m0s", ttl)
+	}
+
+	if _, _, err := QueryDNS(server, "example.com", "MX"); err == nil {
+		t.Error("QueryDNS() with MX should fail")
+	}
+}
+
+func TestQueryDNS_NXDomain(t *testing.T) {
+	server := fakeNameserver(t, dnsRcodeNXDomain, nil)
+	ips, ttl, err := QueryDNS(server, "missing.example", "AAAA")
+	if err != nil || len(ips) != 0 || ttl != 0 {
+		t.Errorf("QueryDNS() for a missing name = %v, %v, %v, want an empty answer", ips, ttl, err)
+	}
+}
+
+func TestResolveIPsVia_FallsBackToNextServer(t *testing.T) {
+	failing := fakeNameserver(t, 2, nil) // SERVFAIL
+	working := fakeNameserver(t, 0, []dnsRecord{{dnsTypeAAAA, 3600, net.ParseIP("2001:db8::1")}})
+
+	ips, ttl := ResolveIPsVia([]string{failing, working}, "example.com", "AAAA")
+	if !slices.Equal(ips, []string{"2001:db8::1"}) || ttl != time.Hour {
+		t.Errorf("ResolveIPsVia() = %v, %v, want the second server's answer", ips, ttl)
+	}
+	if ips, ttl := ResolveIPsVia(nil, "example.com", "A"); ips == nil || len(ips) != 0 || ttl != 0 {
+		t.Errorf("ResolveIPsVia() without servers = %v, %v, want an empty answer", ips, ttl)
+	}
+}
+
+func TestReadNameservers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resolv.conf")
+	conf := "# Generated\nnameserver 192.0.2.53\nsearch lan\nnameserver 2001:db8::53\nnameserver not-an-ip\noptions edns0\n"
+	if err := os.WriteFile(path, []byte(conf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if servers := readNameservers(path); !slices.Equal(servers, []string{"192.0.2.53", "2001:db8::53"}) {
+		t.Errorf("readNameservers() = %v", servers)
+	}
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"os"
+	"os/exec"
 	"path/filepath"
 	"testing"
 )
@@ -45,6 +46,37 @@ func TestCopyFile(t *testing.T) {
 	}
 }
 
+func TestReplaceFile_RunningBinary(t *testing.T) {
+	sleep, err := exec.LookPath("sleep")
+	if err != nil {
+		t.Skip("sleep not available")
+	}
+	tmpDir := t.TempDir()
+	dstPath := filepath.Join(tmpDir, "running")
+	if err := CopyFile(sleep, dstPath); err != nil {
+		t.Fatalf("CopyFile failed: %v", err)
+	}
+	cmd := exec.Command(dstPath, "10")
+	if err := cmd.Start(); err != nil {
+		t.Skipf("Can't run the copied binary: %v", err)
+	}
+	t.Cleanup(func() { cmd.Process.Kill(); cmd.Wait() })
+
+	srcPath := filepath.Join(tmpDir, "new")
+	if err := os.WriteFile(srcPath, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ReplaceFile(srcPath, dstPath); err != nil {
+		t.Fatalf("ReplaceFile over a running binary failed: %v", err)
+	}
+	if content, _ := os.ReadFile(dstPath); string(content) != "#!/bin/sh\n" {
+		t.Errorf("Expected the new content, got %q", content)
+	}
+	if _, err := os.Stat(dstPath + ".new"); !os.IsNotExist(err) {
+		t.Error("Expected no temporary file to be left behind")
+	}
+}
+
 func TestCopyFile_NonExistentSource(t *testing.T) {
 	tmpDir := t.TempDir()
 	srcPath := filepath.Join(tmpDir, "nonexistent.txt")
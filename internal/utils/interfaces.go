@@ -3,6 +3,7 @@ package utils
 import (
 	"net"
 	"os"
+	"sync"
 	"time"
 )
 
@@ -41,6 +42,14 @@ type TimeProvider interface {
 	Now() time.Time
 }
 
+// Ticker abstracts time.Ticker for testing.
+// This allows us to check how periodic loops change their interval.
+type Ticker interface {
+	Chan() <-chan time.Time
+	Reset(d time.Duration)
+	Stop()
+}
+
 // DefaultFileSystem implements FileSystem using actual os package calls.
 type DefaultFileSystem struct{}
 
@@ -82,3 +91,49 @@ type DefaultTimeProvider struct{}
 func (DefaultTimeProvider) Now() time.Time {
 	return time.Now()
 }
+
+// FakeTimeProvider implements TimeProvider with a time that only changes when
+// set or advanced, so tests can step time-dependent logic deterministically.
+type FakeTimeProvider struct {
+	mu  sync.RWMutex
+	now time.Time
+}
+
+// NewFakeTimeProvider returns a FakeTimeProvider starting at now.
+func NewFakeTimeProvider(now time.Time) *FakeTimeProvider {
+	return &FakeTimeProvider{now: now}
+}
+
+func (f *FakeTimeProvider) Now() time.Time {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.now
+}
+
+// Set moves the fake time to now.
+func (f *FakeTimeProvider) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = now
+}
+
+// Advance moves the fake time forward by d.
+func (f *FakeTimeProvider) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+// DefaultTicker implements Ticker using an actual time.Ticker.
+type DefaultTicker struct {
+	*time.Ticker
+}
+
+// NewTicker returns a DefaultTicker ticking every d.
+func NewTicker(d time.Duration) DefaultTicker {
+	return DefaultTicker{time.NewTicker(d)}
+}
+
+func (t DefaultTicker) Chan() <-chan time.Time {
+	return t.C
+}
@@ -1,9 +1,11 @@
 package utils
 
 import (
+	"context"
 	"net"
 	"os/exec"
 	"strings"
+	"time"
 )
 
 // IsIPAddress checks if a string is a valid IPv4 or IPv6 address.
@@ -12,44 +14,64 @@ func IsIPAddress(s string) bool {
 	return net.ParseIP(s) != nil
 }
 
+// resolveTimeout bounds a single DNS lookup, so an unreachable resolver can't
+// stall a firewall rebuild.
+const resolveTimeout = 5 * time.Second
+
+// IPLookup is the part of *net.Resolver used to resolve domains, so tests can
+// substitute canned answers.
+type IPLookup interface {
+	LookupIP(ctx context.Context, network, host string) ([]net.IP, error)
+}
+
+// DefaultIPLookup is the resolver ResolveIPs uses.
+var DefaultIPLookup IPLookup = net.DefaultResolver
+
 // ResolveIPs resolves a domain name to IP addresses using DNS.
 // recordType should be "A" for IPv4 or "AAAA" for IPv6.
 // Returns a list of IP addresses, or an empty list if resolution fails.
 func ResolveIPs(domain string, recordType string) []string {
-	ips := make([]string, 0)
+	return ResolveIPsWith(DefaultIPLookup, domain, recordType)
+}
 
-	// Try to resolve the domain using dig
-	cmd := exec.Command("dig", "+short", domain, recordType)
-	output, err := cmd.Output()
-	if err != nil {
-		return ips
+// ResolveIPsWith resolves domain like ResolveIPs using resolver.
+func ResolveIPsWith(resolver IPLookup, domain string, recordType string) []string {
+	var network string
+	switch recordType {
+	case "A":
+		network = "ip4"
+	case "AAAA":
+		network = "ip6"
+	default:
+		return make([]string, 0)
 	}
 
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	for _, line := range lines {
-		ip := strings.TrimSpace(line)
-
-		// Skip empty lines
-		if ip == "" {
-			continue
-		}
+	ctx, cancel := context.WithTimeout(context.Background(), resolveTimeout)
+	defer cancel()
+	addrs, err := resolver.LookupIP(ctx, network, domain)
+	if err != nil {
+		return make([]string, 0)
+	}
 
-		// Validate IPv4
-		if recordType == "A" {
-			if strings.Count(ip, ".") == 3 && !strings.Contains(ip, " ") && len(ip) >= 7 {
-				ips = append(ips, ip)
-			}
-		}
+	ipv4, ipv6 := SplitIPFamilies(addrs)
+	if recordType == "A" {
+		return ipv4
+	}
+	return ipv6
+}
 
-		// Validate IPv6 (basic check for colon)
-		if recordType == "AAAA" {
-			if strings.Contains(ip, ":") && !strings.Contains(ip, " ") {
-				ips = append(ips, ip)
-			}
+// SplitIPFamilies partitions ips into IPv4 and IPv6 addresses. IPv4-mapped
+// IPv6 addresses count as IPv4.
+func SplitIPFamilies(ips []net.IP) (ipv4, ipv6 []string) {
+	ipv4, ipv6 = make([]string, 0), make([]string, 0)
+	for _, ip := range ips {
+		if ip4 := ip.To4(); ip4 != nil {
+			ipv4 = append(ipv4, ip4.String())
+		} else if ip.To16() != nil {
+			ipv6 = append(ipv6, ip.String())
 		}
 	}
-
-	return ips
+	return ipv4, ipv6
 }
 
 // IsServiceRunning checks if a systemd service is running.
@@ -1,6 +1,10 @@
 package utils
 
 import (
+	"context"
+	"errors"
+	"net"
+	"slices"
 	"testing"
 )
 
@@ -74,3 +78,52 @@ func TestIsServiceRunning(t *testing.T) {
 	// Note: We can't reliably test with real services as they may or may not be running
 	// In a real test environment, you'd use mocks or test fixtures
 }
+
+// cannedLookup answers every lookup with the same addresses, whatever the
+// network asked for, and records the networks requested.
+type cannedLookup struct {
+	ips      []net.IP
+	err      error
+	networks []string
+}
+
+func (c *cannedLookup) LookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	c.networks = append(c.networks, network)
+	if _, ok := ctx.Deadline(); !ok {
+		return nil, errors.New("lookup without a timeout")
+	}
+	return c.ips, c.err
+}
+
+func TestResolveIPsWith_SplitsFamilies(t *testing.T) {
+	resolver := &cannedLookup{ips: []net.IP{
+		net.ParseIP("93.184.216.34"),
+		net.ParseIP("2606:2800:220:1:248:1893:25c8:1946"),
+		net.ParseIP("::ffff:192.0.2.1"), // IPv4-mapped
+		net.ParseIP("2001:db8::1"),
+	}}
+
+	ipv4 := ResolveIPsWith(resolver, "example.com", "A")
+	if want := []string{"93.184.216.34", "192.0.2.1"}; !slices.Equal(ipv4, want) {
+		t.Errorf("A = %v, want %v", ipv4, want)
+	}
+	ipv6 := ResolveIPsWith(resolver, "example.com", "AAAA")
+	if want := []string{"2606:2800:220:1:248:1893:25c8:1946", "2001:db8::1"}; !slices.Equal(ipv6, want) {
+		t.Errorf("AAAA = %v, want %v", ipv6, want)
+	}
+	if want := []string{"ip4", "ip6"}; !slices.Equal(resolver.networks, want) {
+		t.Errorf("Looked up networks %v, want %v", resolver.networks, want)
+	}
+
+	// Unknown record types aren't looked up
+	if ips := ResolveIPsWith(resolver, "example.com", "MX"); ips == nil || len(ips) != 0 || len(resolver.networks) != 2 {
+		t.Errorf("MX = %v after lookups %v, want an empty list and no lookup", ips, resolver.networks)
+	}
+}
+
+func TestResolveIPsWith_Error(t *testing.T) {
+	resolver := &cannedLookup{ips: []net.IP{net.ParseIP("192.0.2.1")}, err: &net.DNSError{Err: "no such host", IsNotFound: true}}
+	if ips := ResolveIPsWith(resolver, "missing.example", "A"); ips == nil || len(ips) != 0 {
+		t.Errorf("Failed lookup = %v, want an empty list", ips)
+	}
+}
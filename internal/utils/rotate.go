@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+)
+
+// RotatingWriter appends to a log file and rotates it by size: once a write
+// would take the file past maxBytes, path.N-1 is renamed to path.N, ..., path
+// to path.1, and a new path is started. At most maxFiles rotated files are
+// kept; the oldest is dropped.
+type RotatingWriter struct {
+	path     string
+	maxBytes int64
+	maxFiles int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// NewRotatingWriter returns a writer for path. The file is opened on the first write.
+func NewRotatingWriter(path string, maxBytes int64, maxFiles int) *RotatingWriter {
+	return &RotatingWriter{path: path, maxBytes: maxBytes, maxFiles: maxFiles}
+}
+
+// Write appends p to the file, rotating it first if p would take it past maxBytes.
+// A single write larger than maxBytes still goes into one file.
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+	if w.size > 0 && w.size+int64(len(p)) > w.maxBytes {
+		w.file.Close()
+		w.file = nil
+		if err := rotateFiles(w.path, w.maxFiles); err != nil {
+			return 0, err
+		}
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close closes the current file. A later write reopens it.
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// open opens the file for appending and picks up its current size.
+func (w *RotatingWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	w.file = file
+	w.size = info.Size()
+	return nil
+}
+
+// AppendRotating appends data to the log file at path, rotating it the way
+// RotatingWriter does. For logs written now and then, where keeping the file
+// open isn't worth it.
+func AppendRotating(path string, data []byte, maxBytes int64, maxFiles int) error {
+	w := NewRotatingWriter(path, maxBytes, maxFiles)
+	defer w.Close()
+	_, err := w.Write(data)
+	return err
+}
+
+// RotatedFiles returns path and its rotated files that exist, oldest first:
+// path.N, ..., path.1, path. Readers of a rotated log go through them in this
+// order to see all of it.
+func RotatedFiles(path string) []string {
+	var files []string
+	for i := 1; ; i++ {
+		rotated := fmt.Sprintf("%s.%d", path, i)
+		if _, err := os.Stat(rotated); err != nil {
+			break
+		}
+		files = append(files, rotated)
+	}
+	slices.Reverse(files)
+	if _, err := os.Stat(path); err == nil {
+		files = append(files, path)
+	}
+	return files
+}
+
+// rotateFiles shifts path.N-1 to path.N, ..., path to path.1, dropping the
+// rotated file past maxFiles. With maxFiles 0 the file is just removed.
+func rotateFiles(path string, maxFiles int) error {
+	if maxFiles <= 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to truncate log file: %w", err)
+		}
+		return nil
+	}
+
+	os.Remove(fmt.Sprintf("%s.%d", path, maxFiles))
+	for i := maxFiles - 1; i >= 1; i-- {
+		old := fmt.Sprintf("%s.%d", path, i)
+		if err := os.Rename(old, fmt.Sprintf("%s.%d", path, i+1)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to rotate log file: %w", err)
+		}
+	}
+	if err := os.Rename(path, path+".1"); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingWriter_RotatesPastMaxSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "glocker.log")
+	w := NewRotatingWriter(path, 20, 3)
+	defer w.Close()
+
+	// 10 bytes each: two fit in a file, the third starts a new one
+	for i := 0; i < 3; i++ {
+		if _, err := fmt.Fprintf(w, "line %03d\n", i); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	rotated, err := os.ReadFile(path + ".1")
+	if err != nil {
+		t.Fatalf("Expected a rotated file: %v", err)
+	}
+	if string(rotated) != "line 000\nline 001\n" {
+		t.Errorf("Rotated file = %q, want the first two lines", rotated)
+	}
+	current, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(current) != "line 002\n" {
+		t.Errorf("Current file = %q, want the third line", current)
+	}
+}
+
+func TestRotatingWriter_KeepsMaxFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "glocker.log")
+	w := NewRotatingWriter(path, 10, 2)
+
+	// Every write fills a file, so each one after the first rotates
+	for i := 0; i < 6; i++ {
+		if _, err := fmt.Fprintf(w, "line %03d\n", i); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+	w.Close()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if strings.Join(names, ",") != "glocker.log,glocker.log.1,glocker.log.2" {
+		t.Errorf("Expected the log and 2 rotated files, got %v", names)
+	}
+	for name, want := range map[string]string{"glocker.log": "line 005\n", "glocker.log.1": "line 004\n", "glocker.log.2": "line 003\n"} {
+		if data, _ := os.ReadFile(filepath.Join(dir, name)); string(data) != want {
+			t.Errorf("%s = %q, want %q", name, data, want)
+		}
+	}
+}
+
+func TestAppendRotating(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reports.log")
+	if err := os.WriteFile(path, []byte("existing entry\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The existing file's size counts toward the limit
+	if err := AppendRotating(path, []byte("new entry\n"), 20, 1); err != nil {
+		t.Fatalf("AppendRotating failed: %v", err)
+	}
+	if data, _ := os.ReadFile(path + ".1"); string(data) != "existing entry\n" {
+		t.Errorf("Rotated file = %q, want the existing entry", data)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "new entry\n" {
+		t.Errorf("Current file = %q, want the new entry", data)
+	}
+
+	// With no rotated files kept, the file is just started over
+	if err := AppendRotating(path, []byte("another entry\n"), 20, 0); err != nil {
+		t.Fatalf("AppendRotating failed: %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "another entry\n" {
+		t.Errorf("Current file = %q, want only the latest entry", data)
+	}
+}
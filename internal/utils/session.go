@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GraphicalSession describes the X11 session of a logged-in user, enough to start
+// a program on their display.
+type GraphicalSession struct {
+	UID        uint32
+	GID        uint32
+	Display    string // e.g. ":0"
+	XAuthority string // May be empty when the display needs no cookie file
+	Home       string
+}
+
+// FindGraphicalSession finds a logged-in user's X11 session by looking for a
+// non-root process with DISPLAY in its environment. The oldest such process is
+// used, which is normally part of the session itself rather than a short-lived tool.
+func FindGraphicalSession() (GraphicalSession, error) {
+	return findGraphicalSession("/proc")
+}
+
+func findGraphicalSession(procRoot string) (GraphicalSession, error) {
+	entries, err := os.ReadDir(procRoot)
+	if err != nil {
+		return GraphicalSession{}, fmt.Errorf("reading %s: %w", procRoot, err)
+	}
+
+	var pids []int
+	for _, entry := range entries {
+		if pid, err := strconv.Atoi(entry.Name()); err == nil && entry.IsDir() {
+			pids = append(pids, pid)
+		}
+	}
+	sort.Ints(pids)
+
+	for _, pid := range pids {
+		dir := filepath.Join(procRoot, strconv.Itoa(pid))
+		uid, gid, ok := readProcessIDs(filepath.Join(dir, "status"))
+		if !ok || uid == 0 {
+			continue
+		}
+		env, err := os.ReadFile(filepath.Join(dir, "environ"))
+		if err != nil {
+			continue // Processes of other users can vanish or be unreadable
+		}
+		vars := parseEnviron(env)
+		if vars["DISPLAY"] == "" {
+			continue
+		}
+		return GraphicalSession{
+			UID:        uid,
+			GID:        gid,
+			Display:    vars["DISPLAY"],
+			XAuthority: vars["XAUTHORITY"],
+			Home:       vars["HOME"],
+		}, nil
+	}
+	return GraphicalSession{}, fmt.Errorf("no graphical session found (no user process has DISPLAY set)")
+}
+
+// readProcessIDs returns the real UID and GID from a /proc/<pid>/status file.
+func readProcessIDs(statusPath string) (uid, gid uint32, ok bool) {
+	f, err := os.Open(statusPath)
+	if err != nil {
+		return 0, 0, false
+	}
+	defer f.Close()
+
+	foundUID, foundGID := false, false
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		fields := strings.Fields(value)
+		if !found || len(fields) == 0 {
+			continue
+		}
+		id, err := strconv.ParseUint(fields[0], 10, 32)
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "Uid":
+			uid, foundUID = uint32(id), true
+		case "Gid":
+			gid, foundGID = uint32(id), true
+		}
+	}
+	return uid, gid, foundUID && foundGID
+}
+
+// parseEnviron splits a NUL-separated /proc/<pid>/environ file into variables.
+func parseEnviron(data []byte) map[string]string {
+	vars := make(map[string]string)
+	for _, entry := range bytes.Split(data, []byte{0}) {
+		if key, value, found := strings.Cut(string(entry), "="); found {
+			vars[key] = value
+		}
+	}
+	return vars
+}
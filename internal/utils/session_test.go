@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFakeProcess creates /proc/<pid>/status and environ files under procRoot.
+func writeFakeProcess(t *testing.T, procRoot, pid string, uid, gid string, env ...string) {
+	t.Helper()
+	dir := filepath.Join(procRoot, pid)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	status := "Name:\ttest\nUid:\t" + uid + "\t" + uid + "\t" + uid + "\t" + uid + "\nGid:\t" + gid + "\t" + gid + "\t" + gid + "\t" + gid + "\n"
+	if err := os.WriteFile(filepath.Join(dir, "status"), []byte(status), 0644); err != nil {
+		t.Fatal(err)
+	}
+	environ := strings.Join(env, "\x00") + "\x00"
+	if err := os.WriteFile(filepath.Join(dir, "environ"), []byte(environ), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindGraphicalSession(t *testing.T) {
+	procRoot := t.TempDir()
+	writeFakeProcess(t, procRoot, "1", "0", "0", "DISPLAY=:0", "HOME=/root")              // root is skipped
+	writeFakeProcess(t, procRoot, "20", "1000", "1000", "HOME=/home/alice", "TERM=xterm") // no display
+	writeFakeProcess(t, procRoot, "300", "1000", "100", "DISPLAY=:1", "XAUTHORITY=/home/alice/.Xauthority", "HOME=/home/alice")
+	writeFakeProcess(t, procRoot, "4000", "1001", "1001", "DISPLAY=:2", "HOME=/home/bob")
+	os.MkdirAll(filepath.Join(procRoot, "self"), 0755)
+
+	session, err := findGraphicalSession(procRoot)
+	if err != nil {
+		t.Fatalf("findGraphicalSession failed: %v", err)
+	}
+	want := GraphicalSession{UID: 1000, GID: 100, Display: ":1", XAuthority: "/home/alice/.Xauthority", Home: "/home/alice"}
+	if session != want {
+		t.Errorf("findGraphicalSession() = %+v, want %+v", session, want)
+	}
+}
+
+func TestFindGraphicalSession_NoSession(t *testing.T) {
+	procRoot := t.TempDir()
+	writeFakeProcess(t, procRoot, "1", "0", "0", "DISPLAY=:0")
+	writeFakeProcess(t, procRoot, "2", "1000", "1000", "HOME=/home/alice")
+
+	if _, err := findGraphicalSession(procRoot); err == nil {
+		t.Error("Expected an error when no user process has DISPLAY set")
+	}
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// SdNotify sends a state such as "READY=1" or "WATCHDOG=1" to systemd over NOTIFY_SOCKET.
+// Returns false with no error when NOTIFY_SOCKET isn't set (not running under systemd).
+func SdNotify(state string) (bool, error) {
+	socketPath := os.Getenv("NOTIFY_SOCKET")
+	if socketPath == "" {
+		return false, nil
+	}
+
+	// A leading @ names a socket in the abstract namespace
+	if socketPath[0] == '@' {
+		socketPath = "\x00" + socketPath[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("connecting to systemd notify socket: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("sending %q to systemd: %w", state, err)
+	}
+	return true, nil
+}
+
+// WatchdogTimeout returns the systemd watchdog timeout (WatchdogSec=) from WATCHDOG_USEC,
+// or 0 if the watchdog isn't enabled for this process.
+func WatchdogTimeout() time.Duration {
+	usecStr := os.Getenv("WATCHDOG_USEC")
+	if usecStr == "" || os.Getenv("NOTIFY_SOCKET") == "" {
+		return 0
+	}
+
+	// WATCHDOG_PID, if set, names the process the watchdog is meant for
+	if pidStr := os.Getenv("WATCHDOG_PID"); pidStr != "" {
+		if pid, err := strconv.Atoi(pidStr); err != nil || pid != os.Getpid() {
+			return 0
+		}
+	}
+
+	usec, err := strconv.ParseInt(usecStr, 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSdNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if sent, err := SdNotify("WATCHDOG=1"); sent || err != nil {
+		t.Errorf("Expected no-op without NOTIFY_SOCKET, got sent=%v err=%v", sent, err)
+	}
+
+	socketPath := filepath.Join(t.TempDir(), "notify.sock")
+	listener, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socketPath, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("Failed to listen on %s: %v", socketPath, err)
+	}
+	defer listener.Close()
+
+	t.Setenv("NOTIFY_SOCKET", socketPath)
+	sent, err := SdNotify("WATCHDOG=1")
+	if !sent || err != nil {
+		t.Fatalf("Expected notification to be sent, got sent=%v err=%v", sent, err)
+	}
+
+	buf := make([]byte, 64)
+	listener.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := listener.Read(buf)
+	if err != nil {
+		t.Fatalf("Failed to read notification: %v", err)
+	}
+	if got := string(buf[:n]); got != "WATCHDOG=1" {
+		t.Errorf("Expected WATCHDOG=1, got %q", got)
+	}
+}
+
+func TestWatchdogTimeout(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+	tests := []struct {
+		name         string
+		notifySocket string
+		usec         string
+		pid          string
+		want         time.Duration
+	}{
+		{"not under systemd", "", "", "", 0},
+		{"watchdog disabled", "/run/notify", "", "", 0},
+		{"watchdog enabled", "/run/notify", "30000000", "", 30 * time.Second},
+		{"watchdog for this process", "/run/notify", "30000000", pid, 30 * time.Second},
+		{"watchdog for another process", "/run/notify", "30000000", "1", 0},
+		{"invalid value", "/run/notify", "soon", "", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("NOTIFY_SOCKET", tt.notifySocket)
+			t.Setenv("WATCHDOG_USEC", tt.usec)
+			t.Setenv("WATCHDOG_PID", tt.pid)
+			if got := WatchdogTimeout(); got != tt.want {
+				t.Errorf("WatchdogTimeout() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -21,3 +21,14 @@ func IsInTimeWindow(current, start, end string) bool {
 	// Wraparound case: 22:00 - 02:00
 	return current >= start || current <= end
 }
+
+// ResetTicker changes ticker's period from current to next and returns the
+// period in effect. The ticker is left alone if next is the same or not
+// positive.
+func ResetTicker(ticker Ticker, current, next time.Duration) time.Duration {
+	if next <= 0 || next == current {
+		return current
+	}
+	ticker.Reset(next)
+	return next
+}
@@ -1,6 +1,9 @@
 package utils
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestIsValidTime(t *testing.T) {
 	tests := []struct {
@@ -65,3 +68,65 @@ func TestIsInTimeWindow(t *testing.T) {
 		})
 	}
 }
+
+// fakeTicker records the periods it is reset to.
+type fakeTicker struct {
+	resets []time.Duration
+}
+
+func (f *fakeTicker) Chan() <-chan time.Time { return nil }
+func (f *fakeTicker) Reset(d time.Duration)  { f.resets = append(f.resets, d) }
+func (f *fakeTicker) Stop()                  {}
+
+func TestResetTicker(t *testing.T) {
+	ticker := &fakeTicker{}
+
+	// An unchanged or invalid interval leaves the ticker alone
+	if got := ResetTicker(ticker, time.Minute, time.Minute); got != time.Minute {
+		t.Errorf("ResetTicker(same) = %v, want %v", got, time.Minute)
+	}
+	if got := ResetTicker(ticker, time.Minute, 0); got != time.Minute {
+		t.Errorf("ResetTicker(0) = %v, want %v", got, time.Minute)
+	}
+	if len(ticker.resets) != 0 {
+		t.Errorf("Expected no resets, got %v", ticker.resets)
+	}
+
+	if got := ResetTicker(ticker, time.Minute, 10*time.Second); got != 10*time.Second {
+		t.Errorf("ResetTicker(10s) = %v, want 10s", got)
+	}
+	if len(ticker.resets) != 1 || ticker.resets[0] != 10*time.Second {
+		t.Errorf("Expected one reset to 10s, got %v", ticker.resets)
+	}
+}
+
+func TestResetTicker_NewPeriodTakesEffect(t *testing.T) {
+	ticker := NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	ResetTicker(ticker, time.Hour, 10*time.Millisecond)
+	select {
+	case <-ticker.Chan():
+	case <-time.After(time.Second):
+		t.Fatal("Ticker didn't tick at the new period")
+	}
+}
+
+func TestFakeTimeProvider(t *testing.T) {
+	start := time.Date(2026, 1, 6, 9, 0, 0, 0, time.UTC)
+	fake := NewFakeTimeProvider(start)
+	if !fake.Now().Equal(start) {
+		t.Errorf("Now() = %v, want %v", fake.Now(), start)
+	}
+
+	fake.Advance(90 * time.Minute)
+	if want := start.Add(90 * time.Minute); !fake.Now().Equal(want) {
+		t.Errorf("Now() after Advance = %v, want %v", fake.Now(), want)
+	}
+
+	later := time.Date(2026, 1, 7, 0, 0, 0, 0, time.UTC)
+	fake.Set(later)
+	if !fake.Now().Equal(later) {
+		t.Errorf("Now() after Set = %v, want %v", fake.Now(), later)
+	}
+}
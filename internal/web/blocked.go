@@ -1,48 +1,25 @@
 package web
 
 import (
-	"fmt"
+	"bytes"
+	"html/template"
+	"log"
 	"net/http"
 	"time"
 )
 
-// HandleBlockedPageRequest displays a blocked page to the user when they try to access a blocked domain.
-func HandleBlockedPageRequest(w http.ResponseWriter, r *http.Request) {
-	// Only accept GET requests
-	if r.Method != http.MethodGet {
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Get parameters from the query string
-	domain := r.URL.Query().Get("domain")
-	matchedDomain := r.URL.Query().Get("matched")
-	originalURL := r.URL.Query().Get("url")
-	reason := r.URL.Query().Get("reason")
-
-	// Set defaults if parameters are missing
-	if domain == "" {
-		domain = "this site"
-	}
-	if matchedDomain == "" {
-		matchedDomain = domain
-	}
-	if reason == "" {
-		reason = "This content has been blocked by Glocker."
-	}
-
-	// Set content type
-	w.Header().Set("Content-Type", "text/html")
-	w.WriteHeader(http.StatusOK)
-
-	// Add original URL info if provided
-	originalURLInfo := ""
-	if originalURL != "" {
-		originalURLInfo = fmt.Sprintf(`<p class="matched">Original URL: %s</p>`, originalURL)
-	}
+// BlockedPageData holds the fields available to the blocked page template.
+type BlockedPageData struct {
+	Domain        string    // Domain that was requested
+	MatchedDomain string    // Blocking rule that matched it
+	OriginalURL   string    // Full URL that was requested, if known
+	Reason        string    // Why the domain is blocked
+	Time          time.Time // When the request was blocked
+}
 
-	// Generate the blocked page HTML
-	blockedPage := fmt.Sprintf(`
+// defaultBlockedPageTemplate is the built-in blocked page, used when no
+// web_tracking.blocked_page_template is configured or it can't be loaded.
+var defaultBlockedPageTemplate = template.Must(template.New("blocked").Parse(`
 <!DOCTYPE html>
 <html>
 <head>
@@ -116,14 +93,78 @@ func HandleBlockedPageRequest(w http.ResponseWriter, r *http.Request) {
 <body>
     <div class="container">
         <h1>🚫 Site Blocked</h1>
-        <p>Access to <span class="domain">%s</span> has been blocked by Glocker.</p>
-        <p class="matched">Matched blocking rule: %s</p>
-        %s
-        <div class="reason">%s</div>
-        <p class="time">Blocked at: %s</p>
+        <p>Access to <span class="domain">{{.Domain}}</span> has been blocked by Glocker.</p>
+        <p class="matched">Matched blocking rule: {{.MatchedDomain}}</p>
+        {{- if .OriginalURL}}
+        <p class="matched">Original URL: {{.OriginalURL}}</p>
+        {{- end}}
+        <div class="reason">{{.Reason}}</div>
+        <p class="time">Blocked at: {{.Time.Format "2006-01-02 15:04:05"}}</p>
     </div>
 </body>
-</html>`, domain, matchedDomain, originalURLInfo, reason, time.Now().Format("2006-01-02 15:04:05"))
+</html>`))
+
+// blockedPageTemplate is the template HandleBlockedPageRequest renders.
+var blockedPageTemplate = defaultBlockedPageTemplate
+
+// LoadBlockedPageTemplate parses the HTML template at path and uses it for the
+// blocked page. An empty path, or a file that is missing or fails to parse,
+// selects the built-in page.
+func LoadBlockedPageTemplate(path string) {
+	blockedPageTemplate = defaultBlockedPageTemplate
+	if path == "" {
+		return
+	}
 
-	w.Write([]byte(blockedPage))
+	tmpl, err := template.ParseFiles(path)
+	if err != nil {
+		log.Printf("Warning: using the built-in blocked page: %v", err)
+		return
+	}
+	blockedPageTemplate = tmpl
+	log.Printf("Loaded blocked page template from %s", path)
+}
+
+// HandleBlockedPageRequest displays a blocked page to the user when they try to access a blocked domain.
+func HandleBlockedPageRequest(w http.ResponseWriter, r *http.Request) {
+	// Only accept GET requests
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get parameters from the query string
+	data := BlockedPageData{
+		Domain:        r.URL.Query().Get("domain"),
+		MatchedDomain: r.URL.Query().Get("matched"),
+		OriginalURL:   r.URL.Query().Get("url"),
+		Reason:        r.URL.Query().Get("reason"),
+		Time:          time.Now(),
+	}
+
+	// Set defaults if parameters are missing
+	if data.Domain == "" {
+		data.Domain = "this site"
+	}
+	if data.MatchedDomain == "" {
+		data.MatchedDomain = data.Domain
+	}
+	if data.Reason == "" {
+		data.Reason = "This content has been blocked by Glocker."
+	}
+
+	// Render before writing the header so a broken custom template can fall back
+	// to the built-in page. html/template escapes the query parameters.
+	var page bytes.Buffer
+	tmpl := blockedPageTemplate
+	if err := tmpl.Execute(&page, data); err != nil {
+		log.Printf("Warning: blocked page template failed, using the built-in page: %v", err)
+		page.Reset()
+		defaultBlockedPageTemplate.Execute(&page, data)
+	}
+
+	// Set content type
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(page.Bytes())
 }
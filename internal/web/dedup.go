@@ -0,0 +1,57 @@
+package web
+
+import (
+	"sync"
+	"time"
+
+	"glocker/internal/state"
+)
+
+// reportDeduper collapses identical content reports, so a page that POSTs the
+// same match over and over within seconds counts as one violation.
+type reportDeduper struct {
+	mu   sync.Mutex
+	seen map[string]*seenReport // "<domain>|<trigger>|<url>" -> first occurrence in the window
+}
+
+// seenReport is a report's first occurrence in the current window and how many
+// times it has been seen since.
+type seenReport struct {
+	first time.Time
+	count int
+}
+
+// reportDedup is the deduper the /report handler uses.
+var reportDedup = newReportDeduper()
+
+func newReportDeduper() *reportDeduper {
+	return &reportDeduper{seen: make(map[string]*seenReport)}
+}
+
+// Observe records report at now and returns how many times it has been seen
+// within window of its first occurrence, 1 for a new report. A window of zero
+// treats every report as new.
+func (d *reportDeduper) Observe(report state.ContentReport, now time.Time, window time.Duration) int {
+	if window <= 0 {
+		return 1
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Forget reports whose window has passed, keeping the map small
+	for key, seen := range d.seen {
+		if now.Sub(seen.first) >= window {
+			delete(d.seen, key)
+		}
+	}
+
+	key := report.Domain + "|" + report.Trigger + "|" + report.URL
+	seen, ok := d.seen[key]
+	if !ok {
+		seen = &seenReport{first: now}
+		d.seen[key] = seen
+	}
+	seen.count++
+	return seen.count
+}
@@ -1,32 +1,75 @@
 package web
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/exec"
-	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"glocker/internal/config"
+	"glocker/internal/enforcement"
 	"glocker/internal/state"
-	"glocker/internal/utils"
 	"glocker/internal/monitoring"
 	"glocker/internal/notify"
 )
 
 // blockedDomainCache stores domains that have been checked, populated on first access.
-// This keeps memory usage low while making repeat violations fast.
+// The enforced rules are loaded once, on the first miss after each full
+// enforcement, so misses don't read the config from disk.
 type blockedDomainCache struct {
-	mu      sync.RWMutex
-	domains map[string]*config.Domain // domain name -> full domain config (nil if not blocked)
+	mu         sync.RWMutex
+	domains    map[string]*config.Domain // domain name -> full domain config (nil if not blocked)
+	rules      *ruleIndex                // every enforced rule, loaded with the first miss
+	profile    string                    // active profile the cached results were computed for
+	generation uint64                    // enforcement generation the cached results were computed for
 }
 
+// ruleIndex holds the enforced rules, indexed for host lookups.
+type ruleIndex struct {
+	byName    map[string][]config.Domain // rule name -> rules with that name
+	patterns  []config.Domain            // pattern rules, which can't be looked up by name
+	pathRules []config.Domain            // rules with path patterns
+}
+
+// newRuleIndex indexes domains for host lookups.
+func newRuleIndex(domains []config.Domain) *ruleIndex {
+	index := &ruleIndex{
+		byName:    make(map[string][]config.Domain, len(domains)),
+		pathRules: pathPatternRules(domains),
+	}
+	for _, domain := range domains {
+		index.byName[domain.Name] = append(index.byName[domain.Name], domain)
+		if domain.Pattern {
+			index.patterns = append(index.patterns, domain)
+		}
+	}
+	return index
+}
+
+// rulesFor returns the rules that can match host: those named after it, its
+// www.-less form or a parent domain, and the pattern rules.
+func (index *ruleIndex) rulesFor(host string) []config.Domain {
+	var rules []config.Domain
+	for _, name := range hostCandidates(host) {
+		rules = append(rules, index.byName[name]...)
+	}
+	return append(rules, index.patterns...)
+}
+
+// allowlistMatch is reported as the matched domain for hosts blocked because
+// allowlist mode is active and they aren't on the allowlist.
+const allowlistMatch = "allowlist mode"
+
 var domainCache = &blockedDomainCache{
 	domains: make(map[string]*config.Domain),
 }
@@ -53,19 +96,19 @@ func HandleWebTrackingRequest(cfg *config.Config, w http.ResponseWriter, r *http
 	slog.Debug("Web tracking request received", "host", host, "url", r.URL.String(), "method", r.Method)
 
 	// Check if this host is blocked (uses lazy-loaded cache)
-	isBlocked, matchedDomain := isHostBlocked(host)
+	isBlocked, matchedDomain := isHostBlocked(cfg, host, time.Now())
 
 	slog.Debug("Host blocking check", "host", host, "is_blocked", isBlocked, "matched_domain", matchedDomain)
 
 	if isBlocked {
 		// Determine the blocking reason
-		blockingReason := GetBlockingReason(cfg, matchedDomain, time.Now())
+		now := time.Now()
+		rule, found := findDomainRule(cfg, matchedDomain, now)
+		blockingReason := describeBlockingReason(rule, found, now)
 		log.Printf("BLOCKED SITE ACCESS: %s -> matched domain: %s -> reason: %s", host, matchedDomain, blockingReason)
 
-		// Record violation
-		if cfg.ViolationTracking.Enabled {
-			monitoring.RecordViolation(cfg, "web_access", host, r.URL.String())
-		}
+		// Record violation (audited even when violation tracking is off)
+		monitoring.RecordViolation(cfg, "web_access", host, r.URL.String())
 
 		// Execute the configured command
 		if cfg.WebTracking.Command != "" {
@@ -78,7 +121,7 @@ func HandleWebTrackingRequest(cfg *config.Config, w http.ResponseWriter, r *http
 			"normal", "dialog-information")
 
 		// Send accountability email
-		if cfg.Accountability.Enabled {
+		if cfg.NotifiesPartner() {
 			subject := "GLOCKER ALERT: Blocked Site Access Attempt"
 			body := fmt.Sprintf("An attempt to access a blocked site was detected at %s:\n\n", time.Now().Format("2006-01-02 15:04:05"))
 			body += fmt.Sprintf("Host: %s\n", host)
@@ -90,14 +133,26 @@ func HandleWebTrackingRequest(cfg *config.Config, w http.ResponseWriter, r *http
 			body += fmt.Sprintf("Remote Address: %s\n", r.RemoteAddr)
 			body += "\nThis is an automated alert from Glocker."
 
-			if err := notify.SendEmail(cfg, subject, body); err != nil {
+			if err := notify.SendEmail(cfg, notify.EventBlockedSiteAccess, subject, body); err != nil {
 				log.Printf("Failed to send web tracking accountability email: %v", err)
 			}
 		}
 
+		// For the stickiest sites, fail the connection instead of offering a page to look at.
+		// http.ErrAbortHandler makes the server close the connection without a response or log.
+		if rule.BlockStyle == config.BlockStyleRefused {
+			slog.Debug("Refusing blocked request", "host", host, "matched_domain", matchedDomain)
+			panic(http.ErrAbortHandler)
+		}
+
 		// Redirect to localhost blocked page to avoid double violation
-		blockedURL := fmt.Sprintf("http://127.0.0.1/blocked?domain=%s&matched=%s&url=%s", host, matchedDomain, r.URL.String())
-		http.Redirect(w, r, blockedURL, http.StatusFound)
+		query := url.Values{
+			"domain":  {host},
+			"matched": {matchedDomain},
+			"reason":  {blockingReason},
+			"url":     {r.URL.String()},
+		}
+		http.Redirect(w, r, cfg.WebTracking.BaseURL()+"/blocked?"+query.Encode(), http.StatusFound)
 	} else {
 		// Not a blocked domain, return a simple response
 		w.WriteHeader(http.StatusOK)
@@ -107,103 +162,263 @@ func HandleWebTrackingRequest(cfg *config.Config, w http.ResponseWriter, r *http
 
 // isHostBlocked checks if a host is blocked using a lazy-loaded cache.
 // First access loads from config and caches result. Subsequent accesses are instant.
+// While allowlist mode is active, any host off the allowlist is blocked, and
+// during a focus session so are its distraction domains.
 // Returns (isBlocked, matchedDomain).
-func isHostBlocked(host string) (bool, string) {
-	// Build list of domains to check (host and all parent domains)
-	domainsToCheck := []string{host}
+func isHostBlocked(cfg *config.Config, host string, now time.Time) (bool, string) {
+	// Allowlist mode is checked on every request, since it is switched by its time windows
+	if enforcement.IsAllowlistActive(cfg, now) && !cfg.AllowlistMode.Allows(host) {
+		slog.Debug("Host not on the allowlist", "host", host)
+		return true, allowlistMatch
+	}
 
-	// Strip www. prefix if present
-	hostWithoutWWW := host
-	if strings.HasPrefix(host, "www.") {
-		hostWithoutWWW = host[4:]
-		domainsToCheck = append(domainsToCheck, hostWithoutWWW)
+	domainsToCheck := hostCandidates(host)
+
+	// Focus session blocks are checked on every request too, since they expire on their own
+	for _, checkDomain := range domainsToCheck {
+		if enforcement.IsTempBlocked(checkDomain, now) {
+			slog.Debug("Host blocked by a focus session", "host", host, "matched", checkDomain)
+			return true, checkDomain
+		}
 	}
 
-	// Add parent domains (e.g., for "api.elevenlabs.io", check "elevenlabs.io")
-	parts := strings.Split(hostWithoutWWW, ".")
-	for i := 1; i < len(parts)-1; i++ {
-		parentDomain := strings.Join(parts[i:], ".")
-		domainsToCheck = append(domainsToCheck, parentDomain)
+	// Cached results are only valid for the profile and enforced rules they were computed under
+	if activeProfile, _ := state.GetActiveProfile(); activeProfile != domainCache.currentProfile() {
+		ClearDomainCache()
+		domainCache.mu.Lock()
+		domainCache.profile = activeProfile
+		domainCache.mu.Unlock()
+	}
+	if enforcement.Generation() != domainCache.currentGeneration() {
+		ClearDomainCache()
 	}
 
-	// Check cache first (fast path)
+	// Check cache first (fast path). A blocked entry for any candidate wins;
+	// a negative entry for the host itself means it was fully evaluated before.
 	domainCache.mu.RLock()
 	for _, checkDomain := range domainsToCheck {
-		if domainConfig, exists := domainCache.domains[checkDomain]; exists {
+		if domainConfig := domainCache.domains[checkDomain]; domainConfig != nil && cachedRuleApplies(domainConfig, host) {
 			domainCache.mu.RUnlock()
-			if domainConfig != nil {
-				// Domain is blocked and cached
-				slog.Debug("Cache hit: domain is blocked", "host", host, "matched", checkDomain)
-				return true, checkDomain
-			}
-			// Domain was checked before and is not blocked
-			slog.Debug("Cache hit: domain not blocked", "host", host)
-			return false, ""
+			// Domain is blocked and cached
+			slog.Debug("Cache hit: domain is blocked", "host", host, "matched", checkDomain)
+			return true, cachedMatchName(checkDomain, domainConfig)
 		}
 	}
+	if _, exists := domainCache.domains[host]; exists {
+		domainCache.mu.RUnlock()
+		// Host was checked before and is not blocked
+		slog.Debug("Cache hit: domain not blocked", "host", host)
+		return false, ""
+	}
 	domainCache.mu.RUnlock()
 
-	// Cache miss - need to load from config (slow path, only happens once per domain)
-	slog.Debug("Cache miss: loading domain from config", "host", host)
+	// Cache miss - look the host up in the enforced rules (only happens once per domain)
+	slog.Debug("Cache miss: looking domain up in the enforced rules", "host", host)
 
-	freshCfg, err := config.LoadConfig()
-	if err != nil {
-		log.Printf("Failed to load config for domain check: %v", err)
+	rules := cachedRules()
+	if rules == nil {
 		return false, ""
 	}
+	matched, cacheKey := findBlockingDomain(rules.rulesFor(host), host, time.Now())
 
-	now := time.Now()
-	currentDay := now.Weekday().String()[:3]
-	currentTime := now.Format("15:04")
-
-	// Check each potential domain match
 	domainCache.mu.Lock()
 	defer domainCache.mu.Unlock()
 
-	for _, checkDomain := range domainsToCheck {
-		// Find in config
-		for _, configDomain := range freshCfg.Domains {
-			if configDomain.Name == checkDomain {
-				// Check if domain is currently blocked
-				// Domains without time windows are permanently blocked by default
-				isBlocked := false
-
-				if len(configDomain.TimeWindows) == 0 {
-					// No time windows = always blocked
-					isBlocked = true
-				} else {
-					// Check time windows
-					for _, window := range configDomain.TimeWindows {
-						if slices.Contains(window.Days, currentDay) && utils.IsInTimeWindow(currentTime, window.Start, window.End) {
-							isBlocked = true
-							break
-						}
-					}
-				}
-
-				if isBlocked {
-					// Cache the domain config
-					cachedDomain := configDomain // Copy
-					domainCache.domains[checkDomain] = &cachedDomain
-					slog.Debug("Cached blocked domain", "domain", checkDomain)
-					return true, checkDomain
-				}
-			}
+	if matched != nil {
+		domainCache.domains[cacheKey] = matched
+		if matched.Pattern {
+			// Also cache under the pattern so GetBlockingReason avoids a disk load
+			domainCache.domains[matched.Name] = matched
 		}
-
-		// Cache negative result (domain not blocked)
-		domainCache.domains[checkDomain] = nil
+		slog.Debug("Cached blocked domain", "domain", cacheKey, "pattern", matched.Pattern)
+		return true, matched.Name
 	}
 
+	// Cache negative result for this host only. Parents can't be cached as
+	// unblocked: a rule may skip this host (exact_only, except_subdomains) but
+	// still block the parent itself.
+	domainCache.domains[host] = nil
+
 	slog.Debug("Domain not blocked", "host", host)
 	return false, ""
 }
 
+// isPathBlocked checks whether a path-pattern rule covering the host blocks urlPath right now.
+// Path rules never reach the hosts file, so the browser extension asks about them
+// through /is-blocked. Uses cfg.Domains if populated (tests), otherwise the cached rules.
+// Returns whether the path is blocked and the rule blocking it.
+func isPathBlocked(cfg *config.Config, host, urlPath string, now time.Time) (bool, config.Domain) {
+	var rules []config.Domain
+	if len(cfg.Domains) > 0 {
+		rules = pathPatternRules(cfg.Domains)
+	} else {
+		rules = cachedPathRules()
+	}
+
+	for _, rule := range rules {
+		if !(rule.CoversHost(host) || rule.MatchesHost(host)) || !rule.MatchesPath(urlPath) || !isDomainActive(rule, now) {
+			continue
+		}
+		// Like whole hosts, an unblockable rule's paths are open during a temporary unblock
+		if rule.Unblockable && enforcement.IsTempUnblocked(rule.Name, now) {
+			continue
+		}
+		return true, rule
+	}
+	return false, config.Domain{}
+}
+
+// cachedPathRules returns the rules with path patterns from the cached rule index.
+// Time windows are checked per request, so cached rules stay valid.
+func cachedPathRules() []config.Domain {
+	rules := cachedRules()
+	if rules == nil {
+		return nil
+	}
+	return rules.pathRules
+}
+
+// cachedRules returns the index of the enforced rules, loading the config once
+// after each full enforcement. Returns nil if the config can't be loaded.
+func cachedRules() *ruleIndex {
+	generation := enforcement.Generation()
+	domainCache.mu.RLock()
+	rules, current := domainCache.rules, domainCache.generation == generation
+	domainCache.mu.RUnlock()
+	if rules != nil && current {
+		return rules
+	}
+
+	freshCfg, err := enforcement.LoadEnforcedConfig()
+	if err != nil {
+		log.Printf("Failed to load config for domain check: %v", err)
+		return nil
+	}
+	rules = newRuleIndex(freshCfg.Domains)
+
+	domainCache.mu.Lock()
+	if domainCache.generation != generation {
+		// Results cached under the previous rules are stale too
+		domainCache.domains = make(map[string]*config.Domain)
+		domainCache.generation = generation
+	}
+	domainCache.rules = rules
+	domainCache.mu.Unlock()
+
+	slog.Debug("Cached enforced rules", "rules", len(freshCfg.Domains), "path_rules", len(rules.pathRules))
+	return rules
+}
+
+// pathPatternRules returns copies of the rules with path patterns.
+func pathPatternRules(domains []config.Domain) []config.Domain {
+	rules := []config.Domain{}
+	for _, domain := range domains {
+		if len(domain.PathPatterns) > 0 {
+			rules = append(rules, domain)
+		}
+	}
+	return rules
+}
+
+// hostCandidates returns the names to look up for a host, most specific first:
+// the host, the host without www., then each parent domain
+// (e.g. for "api.elevenlabs.io", also "elevenlabs.io").
+func hostCandidates(host string) []string {
+	candidates := []string{host}
+
+	// Strip www. prefix if present
+	hostWithoutWWW := host
+	if strings.HasPrefix(host, "www.") {
+		hostWithoutWWW = host[4:]
+		candidates = append(candidates, hostWithoutWWW)
+	}
+
+	// Add parent domains
+	parts := strings.Split(hostWithoutWWW, ".")
+	for i := 1; i < len(parts)-1; i++ {
+		candidates = append(candidates, strings.Join(parts[i:], "."))
+	}
+	return candidates
+}
+
+// cachedRuleApplies checks a cached rule against a host, since rules are cached under the
+// name they matched and may not cover every subdomain of it.
+func cachedRuleApplies(domain *config.Domain, host string) bool {
+	if domain.Pattern {
+		return domain.MatchesHost(host)
+	}
+	return domain.CoversHost(host)
+}
+
+// cachedMatchName returns the rule name to report for a cache hit.
+// Pattern rules are cached under the host they matched, so report the pattern itself.
+func cachedMatchName(cacheKey string, domain *config.Domain) string {
+	if domain.Pattern {
+		return domain.Name
+	}
+	return cacheKey
+}
+
+// findBlockingDomain finds the domain rule currently blocking a host.
+// Exact names (host, host without www. and parent domains, in that order) take
+// precedence, honoring each rule's exact_only and except_subdomains settings;
+// pattern domains are only consulted when no exact rule blocks the host.
+// Rules with path patterns never block a whole host (see isPathBlocked).
+// Returns a copy of the matching rule and the key it should be cached under, or nil.
+func findBlockingDomain(domains []config.Domain, host string, now time.Time) (*config.Domain, string) {
+	for _, checkDomain := range hostCandidates(host) {
+		for _, configDomain := range domains {
+			if configDomain.Pattern || len(configDomain.PathPatterns) > 0 || configDomain.Name != checkDomain || !configDomain.CoversHost(host) {
+				continue
+			}
+			if isDomainActive(configDomain, now) {
+				cachedDomain := configDomain // Copy
+				return &cachedDomain, checkDomain
+			}
+		}
+	}
+
+	for _, configDomain := range domains {
+		if len(configDomain.PathPatterns) > 0 || !configDomain.MatchesHost(host) {
+			continue
+		}
+		if isDomainActive(configDomain, now) {
+			cachedDomain := configDomain // Copy
+			return &cachedDomain, host
+		}
+	}
+
+	return nil, ""
+}
+
+// isDomainActive reports whether a domain rule is blocking at the given time,
+// with the same window rules as enforcement (midnight-crossing windows included).
+// Domains without time windows are permanently blocked by default.
+func isDomainActive(domain config.Domain, now time.Time) bool {
+	return enforcement.IsScheduledBlock(domain, now)
+}
+
+// currentProfile returns the profile the cached results were computed for.
+func (c *blockedDomainCache) currentProfile() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.profile
+}
+
+// currentGeneration returns the enforcement generation the cached results were computed for.
+func (c *blockedDomainCache) currentGeneration() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.generation
+}
+
 // ClearDomainCache clears the domain cache. Called after config reload.
 func ClearDomainCache() {
 	domainCache.mu.Lock()
 	defer domainCache.mu.Unlock()
 	domainCache.domains = make(map[string]*config.Domain)
+	domainCache.rules = nil
+	domainCache.generation = enforcement.Generation()
 	slog.Debug("Domain cache cleared")
 }
 
@@ -254,17 +469,8 @@ func HandleKeywordsRequest(cfg *config.Config, w http.ResponseWriter, r *http.Re
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 	w.Header().Set("Content-Type", "application/json")
 
-	// Combine content keywords with URL keywords
-	combinedContentKeywords := make([]string, 0, len(cfg.ExtensionKeywords.ContentKeywords)+len(cfg.ExtensionKeywords.URLKeywords))
-	combinedContentKeywords = append(combinedContentKeywords, cfg.ExtensionKeywords.ContentKeywords...)
-	combinedContentKeywords = append(combinedContentKeywords, cfg.ExtensionKeywords.URLKeywords...)
-
-	// Create response with keywords
-	response := map[string]interface{}{
-		"url_keywords":     cfg.ExtensionKeywords.URLKeywords,
-		"content_keywords": combinedContentKeywords,
-		"whitelist":        cfg.ExtensionKeywords.Whitelist,
-	}
+	// Keywords of the base lists and the currently active categories
+	response := keywordsPayload(cfg, time.Now())
 
 	// Encode and send response
 	if err := json.NewEncoder(w).Encode(response); err != nil {
@@ -273,7 +479,92 @@ func HandleKeywordsRequest(cfg *config.Config, w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	slog.Debug("Keywords request served", "url_keywords_count", len(cfg.ExtensionKeywords.URLKeywords), "content_keywords_count", len(combinedContentKeywords))
+	slog.Debug("Keywords request served", "url_keywords_count", len(response.URLKeywords), "content_keywords_count", len(response.ContentKeywords))
+}
+
+// isBlockedResponse is the JSON answer of the /is-blocked endpoint.
+type isBlockedResponse struct {
+	Blocked bool   `json:"blocked"`
+	Matched string `json:"matched"` // Rule covering the host, empty if none
+	Reason  string `json:"reason"`
+}
+
+// HandleIsBlockedRequest tells browser extensions whether a host is blocked right now,
+// so they don't have to re-implement the matching rules. The host comes from the
+// "host" query parameter. With a "path" parameter, path_patterns rules are checked
+// too; the hosts file can't block a path, so this is the only way they are enforced.
+// Answering has no side effects: the extension reports the blocked paths it
+// redirects with a POST to /report (see handlePathReport). Like /metrics, it only
+// answers loopback clients, and only extension origins may read it cross-origin.
+func HandleIsBlockedRequest(cfg *config.Config, w http.ResponseWriter, r *http.Request) {
+	// Only the browser extension may read the answer; a web page could otherwise
+	// probe which sites are blocked
+	w.Header().Set("Vary", "Origin")
+	if origin := r.Header.Get("Origin"); isExtensionOrigin(origin) {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Allow-Methods", "GET")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	}
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !isLoopbackRemote(r) {
+		http.Error(w, "block checks are only served on loopback", http.StatusForbidden)
+		return
+	}
+	if !isLoopbackHost(r.Host) {
+		http.Error(w, "block checks are only served to localhost or a loopback address", http.StatusForbidden)
+		return
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(r.URL.Query().Get("host"))), ".")
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "" {
+		http.Error(w, "host parameter required", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	response := checkHostBlocked(cfg, host, now)
+
+	// A host that is allowed as a whole may still have blocked paths
+	if urlPath := r.URL.Query().Get("path"); !response.Blocked && urlPath != "" {
+		if blocked, rule := isPathBlocked(cfg, host, urlPath, now); blocked {
+			response = isBlockedResponse{Blocked: true, Matched: rule.Name, Reason: describeBlockingReason(rule, true, now)}
+		}
+	}
+	slog.Debug("Is-blocked request served", "host", host, "blocked", response.Blocked, "matched", response.Matched)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.Debug("Failed to encode is-blocked response", "error", err)
+	}
+}
+
+// checkHostBlocked decides whether host is blocked at now: a rule must cover it
+// (looked up through the domain cache), be inside one of its time windows, and not
+// be temporarily unblocked.
+func checkHostBlocked(cfg *config.Config, host string, now time.Time) isBlockedResponse {
+	blocked, matched := isHostBlocked(cfg, host, now)
+	if !blocked {
+		return isBlockedResponse{Reason: "not blocked"}
+	}
+
+	// Cached rules were blocking when they were cached; their windows may have closed since
+	rule, found := findDomainRule(cfg, matched, now)
+	if found && !isDomainActive(rule, now) {
+		return isBlockedResponse{Matched: matched, Reason: "outside blocking time windows"}
+	}
+	if found && rule.Unblockable && enforcement.IsTempUnblocked(rule.Name, now) {
+		return isBlockedResponse{Matched: matched, Reason: "temporarily unblocked"}
+	}
+
+	return isBlockedResponse{Blocked: true, Matched: matched, Reason: describeBlockingReason(rule, found, now)}
 }
 
 // HandleReportRequest processes content monitoring reports from browser extensions.
@@ -284,20 +575,48 @@ func HandleReportRequest(cfg *config.Config, w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxReportBodyBytes))
+	if err != nil {
+		slog.Debug("Failed to read report body", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// Blocked paths are reported whether or not content monitoring is enabled
+	var pathReport state.ContentReport
+	if json.Unmarshal(body, &pathReport) == nil && pathReport.Trigger == pathPatternTrigger {
+		handlePathReport(cfg, w, r, pathReport)
+		return
+	}
+
 	// Check if content monitoring is enabled
 	if !cfg.ContentMonitoring.Enabled {
 		w.WriteHeader(http.StatusServiceUnavailable)
 		return
 	}
 
+	// A JSON array is a batch of reports from one scan
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+		handleReportBatch(cfg, w, trimmed)
+		return
+	}
+
 	// Parse JSON body
 	var report state.ContentReport
-	if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+	if err := json.Unmarshal(body, &report); err != nil {
 		slog.Debug("Failed to parse report JSON", "error", err)
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
+	// Identical reports in quick succession are only counted and logged once
+	if count := reportDedup.Observe(report, time.Now(), cfg.ContentMonitoring.DedupWindow()); count > 1 {
+		logDuplicateReport(cfg, &report, count)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+		return
+	}
+
 	// Record violation
 	if cfg.ViolationTracking.Enabled {
 		monitoring.RecordViolation(cfg, "content_report", report.Domain, report.URL)
@@ -317,6 +636,145 @@ func HandleReportRequest(cfg *config.Config, w http.ResponseWriter, r *http.Requ
 	w.Write([]byte("OK"))
 }
 
+// pathPatternTrigger is the trigger of a report the extension sends after
+// redirecting a page blocked by a path_patterns rule.
+const pathPatternTrigger = "path-pattern"
+
+// handlePathReport records a web access violation for a page the extension
+// redirected because /is-blocked matched a path rule. Only the extension can
+// report one, and the path must still be blocked, so other pages and hosts on
+// the network can't add violations.
+func handlePathReport(cfg *config.Config, w http.ResponseWriter, r *http.Request, report state.ContentReport) {
+	if !isExtensionRequest(r) {
+		http.Error(w, "path reports are only accepted from the browser extension", http.StatusForbidden)
+		return
+	}
+	target, err := url.Parse(report.URL)
+	if err != nil || target.Hostname() == "" {
+		http.Error(w, "url must be an absolute URL", http.StatusBadRequest)
+		return
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(target.Hostname()), ".")
+	now := time.Now()
+	blocked, rule := isPathBlocked(cfg, host, target.Path, now)
+	if !blocked {
+		http.Error(w, "path is not blocked", http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("BLOCKED PATH ACCESS: %s%s -> matched domain: %s -> reason: %s", host, target.Path, rule.Name, describeBlockingReason(rule, true, now))
+	monitoring.RecordViolation(cfg, "web_access", host, host+target.Path)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("OK"))
+}
+
+// isExtensionRequest reports whether r comes from the browser extension: it was
+// made on this machine, by an extension page, with a JSON body. Web pages can't
+// send a JSON POST to another origin without a CORS preflight, which glocker
+// doesn't grant.
+func isExtensionRequest(r *http.Request) bool {
+	return isLoopbackRemote(r) && isExtensionOrigin(r.Header.Get("Origin")) && strings.HasPrefix(r.Header.Get("Content-Type"), "application/json")
+}
+
+// isExtensionOrigin reports whether origin is a Firefox extension's.
+func isExtensionOrigin(origin string) bool {
+	return strings.HasPrefix(origin, "moz-extension://")
+}
+
+// logDuplicateReport notes a report already counted in the current dedup window.
+func logDuplicateReport(cfg *config.Config, report *state.ContentReport, count int) {
+	log.Printf("CONTENT REPORT: %s - %s (seen %d times in %v, counted once)",
+		report.Trigger, report.URL, count, cfg.ContentMonitoring.DedupWindow())
+}
+
+// maxReportBodyBytes caps the size of a /report request body.
+const maxReportBodyBytes = 1 << 20
+
+// maxReportBatchSize is the most reports accepted in one batch POST.
+const maxReportBatchSize = 100
+
+// reportItemResult is the outcome of one report in a batch POST.
+type reportItemResult struct {
+	Index  int    `json:"index"`
+	Status string `json:"status"` // "accepted" or "rejected"
+	Error  string `json:"error,omitempty"`
+}
+
+// reportBatchResponse is the JSON response to a batch POST.
+type reportBatchResponse struct {
+	Accepted int                `json:"accepted"`
+	Rejected int                `json:"rejected"`
+	Results  []reportItemResult `json:"results"`
+}
+
+// handleReportBatch processes a JSON array of content reports. Every valid report is
+// logged, but each page (URL) records at most one violation, however many triggers it matched.
+// Reports repeating one already seen in the dedup window are accepted but not logged again.
+func handleReportBatch(cfg *config.Config, w http.ResponseWriter, body []byte) {
+	var reports []state.ContentReport
+	if err := json.Unmarshal(body, &reports); err != nil {
+		slog.Debug("Failed to parse report batch JSON", "error", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if len(reports) == 0 || len(reports) > maxReportBatchSize {
+		slog.Debug("Rejected report batch", "size", len(reports), "max", maxReportBatchSize)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	response := reportBatchResponse{Results: make([]reportItemResult, 0, len(reports))}
+	var violationURLs []string
+	violationDomains := make(map[string]string) // URL -> domain of its first report
+
+	for i, report := range reports {
+		result := reportItemResult{Index: i, Status: "accepted"}
+		switch {
+		case report.URL == "":
+			result.Status, result.Error = "rejected", "missing url"
+		case report.Trigger == "":
+			result.Status, result.Error = "rejected", "missing trigger"
+		default:
+			if count := reportDedup.Observe(report, time.Now(), cfg.ContentMonitoring.DedupWindow()); count > 1 {
+				logDuplicateReport(cfg, &report, count)
+				break
+			}
+			if err := LogContentReport(cfg, &report); err != nil {
+				slog.Debug("Failed to log content report", "error", err)
+				result.Status, result.Error = "rejected", "failed to log report"
+				break
+			}
+			log.Printf("CONTENT REPORT: %s - %s", report.Trigger, report.URL)
+			if _, seen := violationDomains[report.URL]; !seen {
+				violationURLs = append(violationURLs, report.URL)
+				violationDomains[report.URL] = report.Domain
+			}
+		}
+
+		if result.Status == "accepted" {
+			response.Accepted++
+		} else {
+			response.Rejected++
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	// Record one violation per page, not one per matched trigger
+	if cfg.ViolationTracking.Enabled {
+		for _, url := range violationURLs {
+			monitoring.RecordViolation(cfg, "content_report", violationDomains[url], url)
+		}
+	}
+
+	slog.Debug("Content report batch processed", "accepted", response.Accepted, "rejected", response.Rejected, "pages", len(violationURLs))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		slog.Debug("Failed to encode report batch response", "error", err)
+	}
+}
+
 // HandleSSERequest manages server-sent events connections for real-time keyword updates.
 func HandleSSERequest(cfg *config.Config, w http.ResponseWriter, r *http.Request) {
 	// Only accept GET requests
@@ -339,17 +797,8 @@ func HandleSSERequest(cfg *config.Config, w http.ResponseWriter, r *http.Request
 
 	slog.Debug("SSE client connected", "total_clients", state.GetSSEClientCount())
 
-	// Combine content keywords with URL keywords for initial send
-	combinedContentKeywords := make([]string, 0, len(cfg.ExtensionKeywords.ContentKeywords)+len(cfg.ExtensionKeywords.URLKeywords))
-	combinedContentKeywords = append(combinedContentKeywords, cfg.ExtensionKeywords.ContentKeywords...)
-	combinedContentKeywords = append(combinedContentKeywords, cfg.ExtensionKeywords.URLKeywords...)
-
 	// Send initial keywords
-	initialKeywords := map[string]interface{}{
-		"url_keywords":     cfg.ExtensionKeywords.URLKeywords,
-		"content_keywords": combinedContentKeywords,
-		"whitelist":        cfg.ExtensionKeywords.Whitelist,
-	}
+	initialKeywords := keywordsPayload(cfg, time.Now())
 	if keywordsJSON, err := json.Marshal(initialKeywords); err == nil {
 		fmt.Fprintf(w, "data: %s\n\n", keywordsJSON)
 		w.(http.Flusher).Flush()
@@ -382,57 +831,73 @@ func HandleSSERequest(cfg *config.Config, w http.ResponseWriter, r *http.Request
 	}
 }
 
+// lookupBlockingRule finds the rule for a matched domain name, or failing that the rule
+// that would block it as a host (so subdomain rules are honored).
+func lookupBlockingRule(domains []config.Domain, domain string, now time.Time) (config.Domain, bool) {
+	for _, d := range domains {
+		if d.Name == domain {
+			return d, true
+		}
+	}
+	if matched, _ := findBlockingDomain(domains, domain, now); matched != nil {
+		return *matched, true
+	}
+	return config.Domain{}, false
+}
+
 // GetBlockingReason returns a human-readable reason for why a domain is blocked.
-// Checks cfg.Domains if populated (tests), otherwise uses cache or loads from disk.
+// Checks cfg.Domains if populated (tests), otherwise the cache or the enforced rule index.
 func GetBlockingReason(cfg *config.Config, domain string, now time.Time) string {
-	currentDay := now.Weekday().String()[:3]
-	currentTime := now.Format("15:04")
+	configDomain, found := findDomainRule(cfg, domain, now)
+	return describeBlockingReason(configDomain, found, now)
+}
 
-	var configDomain config.Domain
-	found := false
+// findDomainRule finds the config rule for a matched domain name.
+// Checks cfg.Domains if populated (tests), otherwise the cache or the enforced rule index.
+func findDomainRule(cfg *config.Config, domain string, now time.Time) (config.Domain, bool) {
+	if domain == allowlistMatch {
+		return config.Domain{Name: allowlistMatch}, true
+	}
+	// A focus session blocks the domain outright, whatever its config rule says
+	if enforcement.IsTempBlocked(domain, now) {
+		return config.Domain{Name: domain}, true
+	}
 
 	// If cfg.Domains is populated (e.g., in tests), use it directly
 	if len(cfg.Domains) > 0 {
-		for _, d := range cfg.Domains {
-			if d.Name == domain {
-				configDomain = d
-				found = true
-				break
-			}
-		}
-	} else {
-		// In normal runtime, cfg.Domains is cleared for memory optimization
-		// Check if we have this domain cached
-		domainCache.mu.RLock()
-		cachedDomain, exists := domainCache.domains[domain]
-		domainCache.mu.RUnlock()
+		return lookupBlockingRule(cfg.Domains, domain, now)
+	}
 
-		if exists && cachedDomain != nil {
-			// Use cached domain config
-			configDomain = *cachedDomain
-			found = true
-		} else {
-			// Not cached, load from disk
-			freshCfg, err := config.LoadConfig()
-			if err != nil {
-				log.Printf("Failed to reload config for blocking reason: %v", err)
-				return "blocked by glocker"
-			}
+	// In normal runtime, cfg.Domains is cleared for memory optimization
+	// Check if we have this domain cached
+	domainCache.mu.RLock()
+	cachedDomain, exists := domainCache.domains[domain]
+	domainCache.mu.RUnlock()
 
-			// Find the domain in the config
-			for _, d := range freshCfg.Domains {
-				if d.Name == domain {
-					configDomain = d
-					found = true
-					break
-				}
-			}
-		}
+	if exists && cachedDomain != nil {
+		// Use cached domain config
+		return *cachedDomain, true
 	}
 
+	// Not cached, look it up in the enforced rules
+	rules := cachedRules()
+	if rules == nil {
+		return config.Domain{}, false
+	}
+	return lookupBlockingRule(rules.rulesFor(domain), domain, now)
+}
+
+// describeBlockingReason returns a human-readable reason for a rule blocking at the given time.
+func describeBlockingReason(configDomain config.Domain, found bool, now time.Time) string {
 	if !found {
 		return "blocked by glocker"
 	}
+	if configDomain.Name == allowlistMatch {
+		return "not on the allowlist (allowlist mode)"
+	}
+	if enforcement.IsTempBlocked(configDomain.Name, now) {
+		return "blocked for the focus session"
+	}
 
 	// NEW BEHAVIOR: Domains without time windows are always blocked (permanent by default)
 	if len(configDomain.TimeWindows) == 0 {
@@ -444,7 +909,7 @@ func GetBlockingReason(cfg *config.Config, domain string, now time.Time) string
 
 	// Check which time window is active
 	for _, window := range configDomain.TimeWindows {
-		if slices.Contains(window.Days, currentDay) && utils.IsInTimeWindow(currentTime, window.Start, window.End) {
+		if window.Active(now) {
 			return fmt.Sprintf("time-based block (active %s-%s on %s)", window.Start, window.End, strings.Join(window.Days, ","))
 		}
 	}
@@ -0,0 +1,130 @@
+package web
+
+import (
+	"encoding/json"
+	"log"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"glocker/internal/config"
+	"glocker/internal/state"
+)
+
+// keywordCategoryState remembers which keyword categories were active at the last
+// check, so clients are only sent an update when one turns on or off.
+var keywordCategoryState = struct {
+	mu     sync.Mutex
+	active map[string]bool
+}{}
+
+// EffectiveKeywords returns the URL and content keywords in force at now: the
+// extension_keywords lists plus those of every active keyword category, without
+// duplicates. Content keywords include the URL keywords, as the extension expects.
+func EffectiveKeywords(cfg *config.Config, now time.Time) (urlKeywords, contentKeywords []string) {
+	urlKeywords = appendUnique(urlKeywords, cfg.ExtensionKeywords.URLKeywords...)
+	contentKeywords = appendUnique(contentKeywords, cfg.ExtensionKeywords.ContentKeywords...)
+	for _, category := range cfg.KeywordCategories {
+		if !keywordCategoryActive(category, now) {
+			continue
+		}
+		urlKeywords = appendUnique(urlKeywords, category.URLKeywords...)
+		contentKeywords = appendUnique(contentKeywords, category.ContentKeywords...)
+	}
+	contentKeywords = appendUnique(contentKeywords, urlKeywords...)
+	return urlKeywords, contentKeywords
+}
+
+// keywordCategoryActive reports whether a category's keywords apply at now: it is
+// enabled and, if it has time windows, inside one of them.
+func keywordCategoryActive(category config.KeywordCategory, now time.Time) bool {
+	if category.Enabled != nil && !*category.Enabled {
+		return false
+	}
+	return len(category.TimeWindows) == 0 || config.AnyWindowActive(category.TimeWindows, now)
+}
+
+// appendUnique appends the keywords not already in list.
+func appendUnique(list []string, keywords ...string) []string {
+	for _, keyword := range keywords {
+		if !slices.Contains(list, keyword) {
+			list = append(list, keyword)
+		}
+	}
+	return list
+}
+
+// keywordsMessage is the keyword set served to browser extensions, by /keywords
+// and over SSE.
+type keywordsMessage struct {
+	URLKeywords     []string `json:"url_keywords"`
+	ContentKeywords []string `json:"content_keywords"`
+	Whitelist       []string `json:"whitelist"`
+}
+
+// keywordsPayload returns the keyword message in force at now.
+func keywordsPayload(cfg *config.Config, now time.Time) keywordsMessage {
+	urlKeywords, contentKeywords := EffectiveKeywords(cfg, now)
+	return keywordsMessage{
+		URLKeywords:     nonNilKeywords(urlKeywords),
+		ContentKeywords: nonNilKeywords(contentKeywords),
+		Whitelist:       cfg.ExtensionKeywords.Whitelist,
+	}
+}
+
+// nonNilKeywords returns an empty list for nil, so it encodes as [] rather than null.
+func nonNilKeywords(keywords []string) []string {
+	if keywords == nil {
+		return []string{}
+	}
+	return keywords
+}
+
+// broadcastKeywordUpdate sends the keywords in force at now to every connected
+// SSE client.
+func broadcastKeywordUpdate(cfg *config.Config, now time.Time) {
+	keywordsJSON, err := json.Marshal(keywordsPayload(cfg, now))
+	if err != nil {
+		slog.Debug("Failed to encode keyword update", "error", err)
+		return
+	}
+	state.BroadcastSSE(string(keywordsJSON))
+	slog.Debug("Keyword update broadcast", "clients", state.GetSSEClientCount())
+}
+
+// CheckKeywordCategories broadcasts the keyword set to SSE clients when a keyword
+// category has turned on or off since the last check. It is called on every
+// enforcement tick; the first call only records the current state.
+func CheckKeywordCategories(cfg *config.Config, now time.Time) {
+	if len(cfg.KeywordCategories) == 0 {
+		return
+	}
+
+	active := make(map[string]bool, len(cfg.KeywordCategories))
+	for _, category := range cfg.KeywordCategories {
+		active[category.Name] = keywordCategoryActive(category, now)
+	}
+
+	keywordCategoryState.mu.Lock()
+	previous := keywordCategoryState.active
+	keywordCategoryState.active = active
+	keywordCategoryState.mu.Unlock()
+
+	if previous == nil {
+		return
+	}
+	var changed []string
+	for name, isActive := range active {
+		if previous[name] != isActive {
+			changed = append(changed, name)
+		}
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	slices.Sort(changed)
+	log.Printf("Keyword categories changed state: %v - updating extensions", changed)
+	broadcastKeywordUpdate(cfg, now)
+}
@@ -9,8 +9,10 @@ import (
 	"strings"
 	"time"
 
+	"glocker/internal/audit"
 	"glocker/internal/config"
 	"glocker/internal/state"
+	"glocker/internal/utils"
 )
 
 // LogContentReport logs a content monitoring report from the browser extension.
@@ -28,21 +30,32 @@ func LogContentReport(cfg *config.Config, report *state.ContentReport) error {
 	}
 	logEntry += "\n"
 
-	// Append to log file
-	file, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to open log file: %w", err)
-	}
-	defer file.Close()
-
-	_, err = file.WriteString(logEntry)
-	if err != nil {
+	// Append to log file, rotating it past log_max_size_mb
+	if err := utils.AppendRotating(logFile, []byte(logEntry), cfg.LogMaxBytes(), cfg.LogRotatedFiles()); err != nil {
 		return fmt.Errorf("failed to write to log file: %w", err)
 	}
 
+	auditContentReport(report)
 	return nil
 }
 
+// auditContentReport records a keyword report in the audit log. The trigger has
+// the form "url-keyword:gambling"; its kind becomes the event source.
+func auditContentReport(report *state.ContentReport) {
+	source, keyword, found := strings.Cut(report.Trigger, ":")
+	if !found {
+		source, keyword = "", report.Trigger
+	}
+	audit.Log(audit.Event{
+		Timestamp: time.Unix(report.Timestamp/1000, 0),
+		Type:      audit.EventViolation,
+		Domain:    report.Domain,
+		Keyword:   keyword,
+		URL:       report.URL,
+		Source:    source,
+	})
+}
+
 // LogUnblockEntry logs a temporary unblock request with details.
 func LogUnblockEntry(cfg *config.Config, domain, reason string, unblockTime, restoreTime time.Time) error {
 	if cfg.Unblocking.LogFile == "" {
@@ -0,0 +1,113 @@
+package web
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"glocker/internal/config"
+	"glocker/internal/enforcement"
+	"glocker/internal/monitoring"
+	"glocker/internal/state"
+)
+
+// metric is a single sample in the Prometheus text exposition format.
+type metric struct {
+	name  string
+	help  string
+	kind  string // "gauge" or "counter"
+	value int
+}
+
+// HandleMetricsRequest serves daemon metrics in the Prometheus text format for
+// scraping. Only requests from the local machine that are addressed to it by
+// loopback name are answered: blocked domains resolve to this server too, so a
+// page on one of them must not be able to read the metrics.
+func HandleMetricsRequest(cfg *config.Config, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !isLoopbackRemote(r) {
+		http.Error(w, "metrics are only served on loopback", http.StatusForbidden)
+		return
+	}
+	if !isLoopbackHost(r.Host) {
+		http.Error(w, "metrics are only served to localhost or a loopback address", http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := writeMetrics(w, collectMetrics(cfg, time.Now())); err != nil {
+		slog.Debug("Failed to write metrics", "error", err)
+	}
+}
+
+// isLoopbackRemote reports whether r was made from this machine.
+func isLoopbackRemote(r *http.Request) bool {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	ip := net.ParseIP(host)
+	return err == nil && ip != nil && ip.IsLoopback()
+}
+
+// isLoopbackHost reports whether a Host header names this machine: localhost or
+// a loopback address, with or without a port.
+func isLoopbackHost(host string) bool {
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+	host = strings.Trim(host, "[]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// collectMetrics reads the current metric values from the daemon state.
+func collectMetrics(cfg *config.Config, now time.Time) []metric {
+	_, blockedCount, _ := enforcement.GetEnforcementState()
+
+	activeUnblocks := 0
+	for _, unblock := range state.GetTempUnblocks() {
+		if now.Before(unblock.ExpiresAt) {
+			activeUnblocks++
+		}
+	}
+
+	recentViolations := monitoring.CountRecentViolations(cfg, now)
+	emailsSent, emailsFailed := state.GetEmailCounts()
+
+	panicActive := 0
+	if now.Before(state.GetPanicUntil()) {
+		panicActive = 1
+	}
+
+	return []metric{
+		{"glocker_blocked_domains", "Domains blocked by the last enforcement.", "gauge", blockedCount},
+		{"glocker_temp_unblocks_active", "Temporary unblocks that haven't expired.", "gauge", activeUnblocks},
+		{"glocker_violations_recent", "Weighted violations within violation_tracking.time_window_minutes, as counted against max_violations.", "gauge", recentViolations},
+		{"glocker_violations_total", "Violations recorded since the daemon started.", "counter", state.GetTotalViolations()},
+		{"glocker_tamper_events_total", "Tampering attempts detected since the daemon started.", "counter", state.GetTamperEventCount()},
+		{"glocker_emails_sent_total", "Accountability emails sent since the daemon started.", "counter", emailsSent},
+		{"glocker_emails_failed_total", "Accountability emails that failed to send since the daemon started.", "counter", emailsFailed},
+		{"glocker_panic_mode_active", "1 while panic mode is active, 0 otherwise.", "gauge", panicActive},
+	}
+}
+
+// writeMetrics writes metrics in the Prometheus text exposition format.
+func writeMetrics(w io.Writer, metrics []metric) error {
+	var b strings.Builder
+	for _, m := range metrics {
+		fmt.Fprintf(&b, "# HELP %s %s\n", m.name, m.help)
+		fmt.Fprintf(&b, "# TYPE %s %s\n", m.name, m.kind)
+		fmt.Fprintf(&b, "%s %d\n", m.name, m.value)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -1,20 +1,31 @@
 package web
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"fmt"
 	"log"
 	"log/slog"
+	"math/big"
+	"net"
 	"net/http"
-	"os"
-	"os/exec"
+	"time"
 
 	"glocker/internal/config"
 )
 
 // StartWebTrackingServer starts HTTP and HTTPS servers for web tracking and browser extension communication.
-// The HTTP server runs on port 80 and HTTPS on port 443 with a self-signed certificate.
+// The HTTP server runs on web_tracking.http_port (default 80) and HTTPS on https_port
+// (default 443) with a self-signed certificate, on all interfaces unless bind_addr is set.
 func StartWebTrackingServer(cfg *config.Config) {
-	slog.Debug("Starting web tracking servers on ports 80 and 443")
+	httpAddr, httpsAddr := cfg.WebTracking.HTTPAddr(), cfg.WebTracking.HTTPSAddr()
+	slog.Debug("Starting web tracking servers", "http_addr", httpAddr, "https_addr", httpsAddr)
+
+	LoadBlockedPageTemplate(cfg.WebTracking.BlockedPageTemplate)
 
 	// Setup routes
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -33,6 +44,14 @@ func StartWebTrackingServer(cfg *config.Config) {
 		HandleSSERequest(cfg, w, r)
 	})
 
+	http.HandleFunc("/is-blocked", func(w http.ResponseWriter, r *http.Request) {
+		HandleIsBlockedRequest(cfg, w, r)
+	})
+
+	http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		HandleMetricsRequest(cfg, w, r)
+	})
+
 	http.HandleFunc("/blocked", func(w http.ResponseWriter, r *http.Request) {
 		HandleBlockedPageRequest(w, r)
 	})
@@ -40,11 +59,11 @@ func StartWebTrackingServer(cfg *config.Config) {
 	// Start HTTP server
 	go func() {
 		server := &http.Server{
-			Addr:    ":80",
+			Addr:    httpAddr,
 			Handler: nil,
 		}
 
-		log.Printf("Web tracking HTTP server started on port 80")
+		log.Printf("Web tracking HTTP server started on %s", httpAddr)
 		if err := server.ListenAndServe(); err != nil {
 			log.Printf("Web tracking HTTP server error: %v", err)
 		}
@@ -52,43 +71,59 @@ func StartWebTrackingServer(cfg *config.Config) {
 
 	// Start HTTPS server
 	go func() {
-		server := &http.Server{
-			Addr:    ":443",
-			Handler: nil,
-		}
-
 		// Generate self-signed certificate
-		certFile, keyFile, err := generateSelfSignedCert()
+		cert, err := generateSelfSignedCert(time.Now())
 		if err != nil {
 			log.Printf("Failed to generate SSL certificate: %v", err)
 			return
 		}
-		defer os.Remove(certFile)
-		defer os.Remove(keyFile)
 
-		log.Printf("Web tracking HTTPS server started on port 443")
-		if err := server.ListenAndServeTLS(certFile, keyFile); err != nil {
+		server := &http.Server{
+			Addr:      httpsAddr,
+			Handler:   nil,
+			TLSConfig: &tls.Config{Certificates: []tls.Certificate{cert}},
+		}
+
+		log.Printf("Web tracking HTTPS server started on %s", httpsAddr)
+		if err := server.ListenAndServeTLS("", ""); err != nil {
 			log.Printf("Web tracking HTTPS server error: %v", err)
 		}
 	}()
 }
 
-// generateSelfSignedCert creates a temporary self-signed SSL certificate for HTTPS.
-// Returns paths to the certificate and key files.
-func generateSelfSignedCert() (string, string, error) {
-	// Generate a private key
-	priv, err := exec.Command("openssl", "genrsa", "-out", "/tmp/glocker-key.pem", "2048").CombinedOutput()
+// generateSelfSignedCert creates a self-signed certificate for localhost, valid for
+// 365 days from now. The key only ever lives in memory.
+func generateSelfSignedCert(now time.Time) (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to generate private key: %w", err)
+	}
+
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
 	if err != nil {
-		return "", "", fmt.Errorf("failed to generate private key: %v, output: %s", err, priv)
+		return tls.Certificate{}, fmt.Errorf("failed to generate serial number: %w", err)
 	}
 
-	// Generate a self-signed certificate
-	cert, err := exec.Command("openssl", "req", "-new", "-x509", "-key", "/tmp/glocker-key.pem",
-		"-out", "/tmp/glocker-cert.pem", "-days", "365", "-subj", "/CN=localhost").CombinedOutput()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "localhost"},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		NotBefore:             now.Add(-time.Hour), // Tolerate small clock differences
+		NotAfter:              now.AddDate(0, 0, 365),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to generate certificate: %w", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
 	if err != nil {
-		os.Remove("/tmp/glocker-key.pem")
-		return "", "", fmt.Errorf("failed to generate certificate: %v, output: %s", err, cert)
+		return tls.Certificate{}, fmt.Errorf("failed to parse generated certificate: %w", err)
 	}
 
-	return "/tmp/glocker-cert.pem", "/tmp/glocker-key.pem", nil
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, nil
 }
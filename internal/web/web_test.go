@@ -2,15 +2,20 @@ package web
 
 import (
 	"bytes"
+	"crypto/x509"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
 
 	"glocker/internal/config"
+	"glocker/internal/enforcement"
 	"glocker/internal/state"
 )
 
@@ -61,6 +66,8 @@ func TestHandleKeywordsRequest_MethodNotAllowed(t *testing.T) {
 }
 
 func TestHandleReportRequest(t *testing.T) {
+	useReportDeduper(t)
+
 	// Create temporary log file
 	tmpFile, err := os.CreateTemp("", "glocker-reports-*.log")
 	if err != nil {
@@ -108,6 +115,168 @@ func TestHandleReportRequest(t *testing.T) {
 	}
 }
 
+func TestHandleReportRequest_Batch(t *testing.T) {
+	useReportDeduper(t)
+
+	tmpFile, err := os.CreateTemp("", "glocker-reports-*.log")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpFile.Name())
+	tmpFile.Close()
+
+	cfg := &config.Config{
+		ContentMonitoring: config.ContentMonitoringConfig{
+			Enabled: true,
+			LogFile: tmpFile.Name(),
+		},
+		ViolationTracking: config.ViolationTrackingConfig{
+			Enabled:           true,
+			MaxViolations:     100,
+			TimeWindowMinutes: 60,
+		},
+	}
+	state.ClearViolations()
+	defer state.ClearViolations()
+
+	now := time.Now().UnixMilli()
+	reports := []state.ContentReport{
+		{URL: "https://example.com/page", Domain: "example.com", Trigger: "content-keyword:foo", Timestamp: now},
+		{URL: "https://example.com/page", Domain: "example.com", Trigger: "content-keyword:bar", Timestamp: now},
+		{URL: "https://example.com/page", Domain: "example.com", Timestamp: now}, // no trigger
+		{URL: "https://other.com/", Domain: "other.com", Trigger: "url-keyword:baz", Timestamp: now},
+	}
+
+	body, _ := json.Marshal(reports)
+	req := httptest.NewRequest("POST", "/report", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	HandleReportRequest(cfg, w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+
+	var response reportBatchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response %q: %v", w.Body.String(), err)
+	}
+	if response.Accepted != 3 || response.Rejected != 1 {
+		t.Errorf("Expected 3 accepted and 1 rejected, got %+v", response)
+	}
+	if len(response.Results) != 4 || response.Results[2].Status != "rejected" || response.Results[2].Error == "" {
+		t.Errorf("Expected item 2 to be rejected with an error, got %+v", response.Results)
+	}
+
+	// Every accepted report is logged
+	content, err := os.ReadFile(tmpFile.Name())
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	for _, trigger := range []string{"content-keyword:foo", "content-keyword:bar", "url-keyword:baz"} {
+		if !strings.Contains(string(content), trigger) {
+			t.Errorf("Log file should contain %q, got:\n%s", trigger, content)
+		}
+	}
+
+	// ...but each page records a single violation
+	if violations := state.GetViolations(); len(violations) != 2 {
+		t.Errorf("Expected 2 violations (one per page), got %d: %+v", len(violations), violations)
+	}
+}
+
+// useReportDeduper gives the test a fresh report deduper, so reports from other
+// tests (or earlier runs) don't count as duplicates.
+func useReportDeduper(t *testing.T) {
+	orig := reportDedup
+	reportDedup = newReportDeduper()
+	t.Cleanup(func() { reportDedup = orig })
+}
+
+func TestHandleReportRequest_DedupBurst(t *testing.T) {
+	useReportDeduper(t)
+	logFile := filepath.Join(t.TempDir(), "reports.log")
+	cfg := &config.Config{
+		ContentMonitoring: config.ContentMonitoringConfig{Enabled: true, LogFile: logFile},
+		ViolationTracking: config.ViolationTrackingConfig{Enabled: true, MaxViolations: 100, TimeWindowMinutes: 60},
+	}
+	state.ClearViolations()
+	defer state.ClearViolations()
+
+	post := func(report state.ContentReport) {
+		body, _ := json.Marshal(report)
+		w := httptest.NewRecorder()
+		HandleReportRequest(cfg, w, httptest.NewRequest("POST", "/report", bytes.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d", w.Code)
+		}
+	}
+
+	report := state.ContentReport{URL: "https://example.com/page", Domain: "example.com", Trigger: "content-keyword:foo", Timestamp: time.Now().UnixMilli()}
+	for range 5 {
+		post(report)
+	}
+	if violations := state.GetViolations(); len(violations) != 1 {
+		t.Errorf("Expected a burst of identical reports to record 1 violation, got %d", len(violations))
+	}
+	content, _ := os.ReadFile(logFile)
+	if lines := strings.Count(string(content), "\n"); lines != 1 {
+		t.Errorf("Expected the report to be logged once, got %d lines:\n%s", lines, content)
+	}
+
+	// A different trigger on the same page is a new report
+	report.Trigger = "content-keyword:bar"
+	post(report)
+	if violations := state.GetViolations(); len(violations) != 2 {
+		t.Errorf("Expected a new trigger to record a violation, got %d", len(violations))
+	}
+
+	// With deduplication off every report counts
+	off := 0
+	cfg.ContentMonitoring.DedupSeconds = &off
+	post(report)
+	if violations := state.GetViolations(); len(violations) != 3 {
+		t.Errorf("Expected every report to count with dedup_seconds 0, got %d", len(violations))
+	}
+}
+
+func TestReportDeduper_Window(t *testing.T) {
+	d := newReportDeduper()
+	report := state.ContentReport{URL: "https://example.com/", Domain: "example.com", Trigger: "url-keyword:foo"}
+	start := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+
+	for i, offset := range []time.Duration{0, 5 * time.Second, 29 * time.Second} {
+		if count := d.Observe(report, start.Add(offset), 30*time.Second); count != i+1 {
+			t.Errorf("Observe at +%v = %d, want %d", offset, count, i+1)
+		}
+	}
+	// The window runs from the first occurrence
+	if count := d.Observe(report, start.Add(30*time.Second), 30*time.Second); count != 1 {
+		t.Errorf("Expected a new window after 30s, got count %d", count)
+	}
+	if len(d.seen) != 1 {
+		t.Errorf("Expected expired reports to be forgotten, have %d", len(d.seen))
+	}
+}
+
+func TestHandleReportRequest_InvalidBatch(t *testing.T) {
+	cfg := &config.Config{
+		ContentMonitoring: config.ContentMonitoringConfig{Enabled: true},
+	}
+
+	for _, body := range []string{"[]", "[{\"url\": 1}]", "[{"} {
+		req := httptest.NewRequest("POST", "/report", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		HandleReportRequest(cfg, w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Body %s: expected status 400, got %d", body, w.Code)
+		}
+	}
+}
+
 func TestHandleReportRequest_Disabled(t *testing.T) {
 	cfg := &config.Config{
 		ContentMonitoring: config.ContentMonitoringConfig{
@@ -165,6 +334,95 @@ func TestHandleBlockedPageRequest_DefaultValues(t *testing.T) {
 	}
 }
 
+func TestHandleBlockedPageRequest_EscapesScriptTags(t *testing.T) {
+	payload := url.QueryEscape("<script>alert(1)</script>")
+	for _, param := range []string{"domain", "matched", "url", "reason"} {
+		req := httptest.NewRequest("GET", "/blocked?"+param+"="+payload, nil)
+		w := httptest.NewRecorder()
+
+		HandleBlockedPageRequest(w, req)
+
+		body := w.Body.String()
+		if strings.Contains(body, "<script>") {
+			t.Errorf("Raw <script> tag rendered from the %s parameter", param)
+		}
+		if !strings.Contains(body, "&lt;script&gt;alert(1)&lt;/script&gt;") {
+			t.Errorf("Expected the escaped %s parameter in the page", param)
+		}
+	}
+}
+
+func TestHandleBlockedPageRequest_CustomTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocked.html")
+	tmpl := `<h1>{{.Domain}} is blocked</h1><p>Rule {{.MatchedDomain}}: {{.Reason}}</p>` +
+		`<a href="{{.OriginalURL}}">url</a><p>Call Sam. {{.Time.Format "15:04"}}</p>`
+	if err := os.WriteFile(path, []byte(tmpl), 0644); err != nil {
+		t.Fatal(err)
+	}
+	LoadBlockedPageTemplate(path)
+	t.Cleanup(func() { LoadBlockedPageTemplate("") })
+
+	req := httptest.NewRequest("GET", "/blocked?domain=example.com&matched=*.example.com&reason=Work+hours&url=https://example.com/x", nil)
+	w := httptest.NewRecorder()
+	HandleBlockedPageRequest(w, req)
+
+	body := w.Body.String()
+	for _, want := range []string{"<h1>example.com is blocked</h1>", "Rule *.example.com: Work hours", `href="https://example.com/x"`, "Call Sam."} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Custom blocked page should contain %q, got:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "Site Blocked") {
+		t.Error("Custom template should replace the built-in page")
+	}
+}
+
+func TestHandleBlockedPageRequest_EscapesQueryParams(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocked.html")
+	if err := os.WriteFile(path, []byte(`<p>{{.Domain}}</p><a href="{{.OriginalURL}}">back</a>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	query := "/blocked?domain=%3Cscript%3Ealert(1)%3C%2Fscript%3E&url=javascript:alert(1)"
+	for _, templatePath := range []string{"", path} {
+		LoadBlockedPageTemplate(templatePath)
+
+		w := httptest.NewRecorder()
+		HandleBlockedPageRequest(w, httptest.NewRequest("GET", query, nil))
+
+		body := w.Body.String()
+		if strings.Contains(body, "<script>alert(1)</script>") {
+			t.Errorf("Domain was not escaped (template %q):\n%s", templatePath, body)
+		}
+		if !strings.Contains(body, "&lt;script&gt;alert(1)&lt;/script&gt;") {
+			t.Errorf("Expected the escaped domain in the page (template %q)", templatePath)
+		}
+		if strings.Contains(body, `href="javascript:`) {
+			t.Errorf("javascript: URL was not sanitized (template %q)", templatePath)
+		}
+	}
+	LoadBlockedPageTemplate("")
+}
+
+func TestLoadBlockedPageTemplate_FallsBack(t *testing.T) {
+	dir := t.TempDir()
+	broken := filepath.Join(dir, "broken.html")
+	if err := os.WriteFile(broken, []byte("<p>{{.Domain</p>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { LoadBlockedPageTemplate("") })
+
+	for _, path := range []string{filepath.Join(dir, "missing.html"), broken} {
+		LoadBlockedPageTemplate(path)
+
+		w := httptest.NewRecorder()
+		HandleBlockedPageRequest(w, httptest.NewRequest("GET", "/blocked?domain=example.com", nil))
+		if !strings.Contains(w.Body.String(), "Site Blocked") {
+			t.Errorf("Expected the built-in page when the template %s can't be loaded", filepath.Base(path))
+		}
+	}
+}
+
 func TestGetBlockingReason_PermanentByDefault(t *testing.T) {
 	cfg := &config.Config{
 		Domains: []config.Domain{
@@ -234,6 +492,578 @@ func TestGetBlockingReason_TimeWindow(t *testing.T) {
 	}
 }
 
+func TestFindBlockingDomain_ExactAndPattern(t *testing.T) {
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{Name: `.*\.example\.com`, Pattern: true},
+			{Name: "example.com", Unblockable: true},
+		},
+	}
+	config.CompilePatterns(cfg)
+
+	now := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+
+	// api.example.com matches the pattern and (via its parent) the exact rule;
+	// the exact rule must win.
+	matched, key := findBlockingDomain(cfg.Domains, "api.example.com", now)
+	if matched == nil {
+		t.Fatal("Expected api.example.com to be blocked")
+	}
+	if matched.Pattern || matched.Name != "example.com" || key != "example.com" {
+		t.Errorf("Expected exact rule example.com to win, got %q (key %q)", matched.Name, key)
+	}
+
+	// With no exact rule in play, the pattern applies and is cached under the host.
+	matched, key = findBlockingDomain(cfg.Domains[:1], "api.example.com", now)
+	if matched == nil || !matched.Pattern {
+		t.Fatal("Expected pattern rule to block api.example.com")
+	}
+	if key != "api.example.com" {
+		t.Errorf("Expected pattern match cached under host, got %q", key)
+	}
+
+	matched, _ = findBlockingDomain(cfg.Domains[:1], "example.org", now)
+	if matched != nil {
+		t.Errorf("Expected example.org not to be blocked, got %q", matched.Name)
+	}
+}
+
+func TestFindBlockingDomain_PatternOutsideTimeWindow(t *testing.T) {
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{
+				Name:    `proxy\d+\.example\.net`,
+				Pattern: true,
+				TimeWindows: []config.TimeWindow{
+					{Start: "18:00", End: "22:00", Days: []string{"Tue"}},
+				},
+			},
+		},
+	}
+	config.CompilePatterns(cfg)
+
+	now := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC) // Tuesday morning
+	if matched, _ := findBlockingDomain(cfg.Domains, "proxy3.example.net", now); matched != nil {
+		t.Error("Pattern should not block outside its time window")
+	}
+}
+
+func TestFindBlockingDomain_SubdomainControls(t *testing.T) {
+	domains := []config.Domain{
+		{Name: "example.com", ExceptSubdomains: []string{"docs"}},
+		{Name: "example.org", ExactOnly: true},
+	}
+	now := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+
+	for host, wantBlocked := range map[string]bool{
+		"example.com":      true,
+		"api.example.com":  true,
+		"docs.example.com": false,
+		"www.example.org":  true,
+		"api.example.org":  false,
+	} {
+		matched, _ := findBlockingDomain(domains, host, now)
+		if (matched != nil) != wantBlocked {
+			t.Errorf("findBlockingDomain(%q) blocked = %v, want %v", host, matched != nil, wantBlocked)
+		}
+	}
+}
+
+func TestFindBlockingDomain_IncludeSubdomains(t *testing.T) {
+	now := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+
+	// Hosts past the hosts_subdomains list are still caught by the interceptor.
+	domains := []config.Domain{{Name: "example.com", IncludeSubdomains: true}}
+	if matched, _ := findBlockingDomain(domains, "sub.api.example.com", now); matched == nil {
+		t.Error("Expected sub.api.example.com to be blocked with include_subdomains")
+	}
+
+	domains = []config.Domain{{Name: "example.com", ExactOnly: true}}
+	if matched, _ := findBlockingDomain(domains, "sub.api.example.com", now); matched != nil {
+		t.Error("Expected sub.api.example.com to stay reachable with exact_only")
+	}
+}
+
+func TestCheckHostBlocked_AllowlistMode(t *testing.T) {
+	cfg := &config.Config{
+		AllowlistMode: config.AllowlistModeConfig{
+			Enabled:        true,
+			AllowedDomains: []config.Domain{{Name: "go.dev"}},
+			TimeWindows:    []config.TimeWindow{{Start: "09:00", End: "12:00", Days: []string{"Tue"}}},
+		},
+	}
+
+	// Seed the host cache as earlier requests would have
+	ClearDomainCache()
+	t.Cleanup(ClearDomainCache)
+	domainCache.mu.Lock()
+	domainCache.domains["pkg.go.dev"] = nil
+	domainCache.domains["news.example.com"] = nil
+	domainCache.mu.Unlock()
+
+	focus := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC) // Tuesday, inside the window
+	response := checkHostBlocked(cfg, "news.example.com", focus)
+	if !response.Blocked || response.Matched != allowlistMatch || response.Reason != "not on the allowlist (allowlist mode)" {
+		t.Errorf("Expected news.example.com to be blocked by allowlist mode, got %+v", response)
+	}
+	if response := checkHostBlocked(cfg, "pkg.go.dev", focus); response.Blocked {
+		t.Errorf("Expected allowlisted pkg.go.dev to stay reachable, got %+v", response)
+	}
+
+	evening := time.Date(2026, 1, 6, 18, 0, 0, 0, time.UTC)
+	if response := checkHostBlocked(cfg, "news.example.com", evening); response.Blocked {
+		t.Errorf("Expected news.example.com to be reachable outside the allowlist window, got %+v", response)
+	}
+}
+
+func TestCheckHostBlocked_FocusSession(t *testing.T) {
+	now := time.Date(2026, 1, 6, 18, 0, 0, 0, time.UTC)
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			// Only blocked in the morning, but the focus session blocks it now
+			{Name: "youtube.com", TimeWindows: []config.TimeWindow{{Start: "09:00", End: "12:00", Days: []string{"Tue"}}}},
+		},
+	}
+	state.SetTempBlocks([]state.TempBlock{
+		{Domain: "youtube.com", ExpiresAt: now.Add(time.Hour)},
+		{Domain: "reddit.com", ExpiresAt: now.Add(time.Hour)},
+	})
+	t.Cleanup(func() { state.SetTempBlocks(nil) })
+
+	// Seed the host cache as requests before the session would have
+	ClearDomainCache()
+	t.Cleanup(ClearDomainCache)
+	domainCache.mu.Lock()
+	domainCache.domains["www.reddit.com"] = nil
+	domainCache.mu.Unlock()
+
+	for _, host := range []string{"www.reddit.com", "m.youtube.com"} {
+		response := checkHostBlocked(cfg, host, now)
+		if !response.Blocked || response.Reason != "blocked for the focus session" {
+			t.Errorf("Expected %s to be blocked by the focus session, got %+v", host, response)
+		}
+	}
+
+	if response := checkHostBlocked(cfg, "www.reddit.com", now.Add(2*time.Hour)); response.Blocked {
+		t.Errorf("Expected www.reddit.com to be reachable after the focus session, got %+v", response)
+	}
+}
+
+func TestIsHostBlocked_RuleIndexPerEnforcement(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig := func(domains string) {
+		t.Helper()
+		if err := os.WriteFile(configPath, []byte("domains:\n"+domains), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig("  - {name: news.com}\n")
+	config.SetConfigPath(configPath)
+	t.Cleanup(func() { config.SetConfigPath(config.GlockerConfigFile) })
+	ClearDomainCache()
+	t.Cleanup(ClearDomainCache)
+
+	cfg := &config.Config{}
+	now := time.Now()
+	if blocked, matched := isHostBlocked(cfg, "www.news.com", now); !blocked || matched != "news.com" {
+		t.Errorf("isHostBlocked(www.news.com) = %v, %q, want blocked by news.com", blocked, matched)
+	}
+
+	// Later misses use the loaded rules, not the file
+	writeConfig("  - {name: games.com}\n")
+	if blocked, _ := isHostBlocked(cfg, "games.com", now); blocked {
+		t.Error("Expected games.com to be looked up in the rules loaded for this enforcement")
+	}
+
+	// The next enforcement makes the rules reload
+	enforcement.InitializeTestCache([]config.Domain{{Name: "games.com"}})
+	t.Cleanup(func() { enforcement.InitializeTestCache(nil) })
+	if blocked, _ := isHostBlocked(cfg, "games.com", now); !blocked {
+		t.Error("Expected games.com to be blocked after the rules were reloaded")
+	}
+	if blocked, _ := isHostBlocked(cfg, "www.news.com", now); blocked {
+		t.Error("Expected the cached news.com result to be dropped with the old rules")
+	}
+}
+
+func TestHandleIsBlockedRequest_PathPatterns(t *testing.T) {
+	now := time.Now()
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{
+				Name:         "reddit.com",
+				PathPatterns: []string{"/r/specificsub"},
+				TimeWindows: []config.TimeWindow{
+					{Start: "00:00", End: "23:59", Days: []string{now.Weekday().String()[:3]}},
+				},
+			},
+		},
+		ViolationTracking: config.ViolationTrackingConfig{
+			Enabled:           true,
+			MaxViolations:     100,
+			TimeWindowMinutes: 60,
+		},
+	}
+	state.ClearViolations()
+	t.Cleanup(state.ClearViolations)
+	ClearDomainCache()
+	t.Cleanup(ClearDomainCache)
+	domainCache.mu.Lock()
+	domainCache.domains["www.reddit.com"] = nil
+	domainCache.domains["reddit.com"] = nil
+	domainCache.mu.Unlock()
+
+	isBlocked := func(host, path string) isBlockedResponse {
+		t.Helper()
+		query := url.Values{"host": {host}, "path": {path}}
+		w := httptest.NewRecorder()
+		HandleIsBlockedRequest(cfg, w, isBlockedRequest("/is-blocked?"+query.Encode()))
+		var response isBlockedResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to parse response %q: %v", w.Body.String(), err)
+		}
+		return response
+	}
+
+	// Allowed host, blocked path
+	if response := isBlocked("www.reddit.com", "/r/SpecificSub/comments/abc"); !response.Blocked || response.Matched != "reddit.com" {
+		t.Errorf("Expected the path to be blocked by reddit.com, got %+v", response)
+	}
+
+	// Allowed host, allowed path
+	for _, path := range []string{"/r/other", "/r/specificsubreddit", "/"} {
+		if response := isBlocked("reddit.com", path); response.Blocked {
+			t.Errorf("Expected %s to be allowed, got %+v", path, response)
+		}
+	}
+	if len(state.GetViolations()) != 0 {
+		t.Errorf("Expected /is-blocked not to record violations, got %+v", state.GetViolations())
+	}
+
+	report := func(origin, pageURL string) int {
+		t.Helper()
+		body := fmt.Sprintf(`{"url":%q,"trigger":"path-pattern","timestamp":1}`, pageURL)
+		req := httptest.NewRequest("POST", "/report", strings.NewReader(body))
+		req.RemoteAddr = "127.0.0.1:12345"
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		HandleReportRequest(cfg, w, req)
+		return w.Code
+	}
+
+	// Only the extension can report a blocked path, and only one that is blocked
+	if code := report("https://evil.example", "https://www.reddit.com/r/specificsub"); code != http.StatusForbidden {
+		t.Errorf("Expected a report from a web page to be forbidden, got %d", code)
+	}
+	if code := report("moz-extension://abc", "https://www.reddit.com/r/other"); code != http.StatusBadRequest {
+		t.Errorf("Expected a report for an allowed path to be rejected, got %d", code)
+	}
+	if len(state.GetViolations()) != 0 {
+		t.Errorf("Expected rejected reports not to record violations, got %+v", state.GetViolations())
+	}
+	if code := report("moz-extension://abc", "https://www.reddit.com/r/SpecificSub/comments/abc"); code != http.StatusOK {
+		t.Errorf("Expected the extension's report to be accepted, got %d", code)
+	}
+	violations := state.GetViolations()
+	if len(violations) != 1 || violations[0].Host != "www.reddit.com" || violations[0].URL != "www.reddit.com/r/SpecificSub/comments/abc" {
+		t.Errorf("Expected one violation for www.reddit.com, got %+v", violations)
+	}
+}
+
+func TestIsPathBlocked_TempUnblock(t *testing.T) {
+	now := time.Now()
+	rule := config.Domain{Name: "reddit.com", PathPatterns: []string{"/r/specificsub"}}
+	cfg := &config.Config{Domains: []config.Domain{rule}}
+
+	state.SetTempUnblocks([]state.TempUnblock{{Domain: "reddit.com", ExpiresAt: now.Add(time.Hour)}})
+	t.Cleanup(func() { state.SetTempUnblocks(nil) })
+
+	if blocked, _ := isPathBlocked(cfg, "reddit.com", "/r/specificsub", now); !blocked {
+		t.Error("Expected a permanent path rule to ignore the temporary unblock")
+	}
+
+	cfg.Domains[0].Unblockable = true
+	if blocked, _ := isPathBlocked(cfg, "reddit.com", "/r/specificsub", now); blocked {
+		t.Error("Expected an unblockable path rule to be open during its temporary unblock")
+	}
+}
+
+func TestHandleWebTrackingRequest_BlockStyle(t *testing.T) {
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{Name: "friendly.com", BlockStyle: config.BlockStylePage},
+			{Name: "tempting.com", BlockStyle: config.BlockStyleRefused},
+		},
+	}
+
+	// Seed the host cache so the hosts count as blocked without a config file on disk
+	ClearDomainCache()
+	t.Cleanup(ClearDomainCache)
+	domainCache.mu.Lock()
+	for i := range cfg.Domains {
+		domainCache.domains[cfg.Domains[i].Name] = &cfg.Domains[i]
+	}
+	domainCache.mu.Unlock()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		HandleWebTrackingRequest(cfg, w, r)
+	}))
+	defer server.Close()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	get := func(host string) (*http.Response, error) {
+		req, err := http.NewRequest("GET", server.URL+"/feed", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Host = host
+		return client.Do(req)
+	}
+
+	resp, err := get("friendly.com")
+	if err != nil {
+		t.Fatalf("Expected a response for the page block style, got: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound || !strings.Contains(resp.Header.Get("Location"), "/blocked?") {
+		t.Errorf("Expected redirect to the blocked page, got %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+
+	if resp, err := get("www.tempting.com"); err == nil {
+		resp.Body.Close()
+		t.Errorf("Expected the connection to be refused, got status %d", resp.StatusCode)
+	}
+}
+
+func TestHandleWebTrackingRequest_RedirectUsesHTTPPort(t *testing.T) {
+	cfg := &config.Config{
+		Domains:     []config.Domain{{Name: "blocked.com"}},
+		WebTracking: config.WebTrackingConfig{HTTPPort: 8080, BindAddr: "127.0.0.1"},
+	}
+	ClearDomainCache()
+	t.Cleanup(ClearDomainCache)
+	domainCache.mu.Lock()
+	domainCache.domains["blocked.com"] = &cfg.Domains[0]
+	domainCache.mu.Unlock()
+
+	req := httptest.NewRequest("GET", "http://blocked.com/", nil)
+	w := httptest.NewRecorder()
+	HandleWebTrackingRequest(cfg, w, req)
+
+	if location := w.Header().Get("Location"); !strings.HasPrefix(location, "http://127.0.0.1:8080/blocked?domain=blocked.com") {
+		t.Errorf("Expected redirect to the blocked page on port 8080, got %q", location)
+	}
+}
+
+func TestHandleWebTrackingRequest_RedirectQuery(t *testing.T) {
+	cfg := &config.Config{Domains: []config.Domain{{Name: "blocked.com"}}}
+	ClearDomainCache()
+	t.Cleanup(ClearDomainCache)
+	domainCache.mu.Lock()
+	domainCache.domains["blocked.com"] = &cfg.Domains[0]
+	domainCache.mu.Unlock()
+
+	req := httptest.NewRequest("GET", "http://blocked.com/search?q=a&b=c", nil)
+	w := httptest.NewRecorder()
+	HandleWebTrackingRequest(cfg, w, req)
+
+	location, err := url.Parse(w.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("Invalid redirect location: %v", err)
+	}
+	query := location.Query()
+	if query.Get("domain") != "blocked.com" || query.Get("matched") != "blocked.com" {
+		t.Errorf("Unexpected domain parameters in %q", location)
+	}
+	if query.Get("url") != req.URL.String() {
+		t.Errorf("Expected the full blocked URL %q, got %q", req.URL.String(), query.Get("url"))
+	}
+	if query.Get("reason") == "" {
+		t.Errorf("Expected the blocking reason in %q", location)
+	}
+}
+
+func TestHandleIsBlockedRequest(t *testing.T) {
+	now := time.Now()
+	otherDay := now.AddDate(0, 0, 1).Weekday().String()[:3]
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{Name: "blocked.com"},
+			{Name: "news.com", Unblockable: true},
+			{Name: "evening.com", TimeWindows: []config.TimeWindow{{Start: "00:00", End: "23:59", Days: []string{otherDay}}}},
+		},
+	}
+
+	// Seed the host cache as earlier requests would have
+	ClearDomainCache()
+	t.Cleanup(ClearDomainCache)
+	domainCache.mu.Lock()
+	for i := range cfg.Domains {
+		domainCache.domains[cfg.Domains[i].Name] = &cfg.Domains[i]
+	}
+	domainCache.domains["allowed.com"] = nil
+	domainCache.mu.Unlock()
+
+	state.SetTempUnblocks([]state.TempUnblock{{Domain: "news.com", ExpiresAt: now.Add(time.Hour)}})
+	t.Cleanup(func() { state.SetTempUnblocks(nil) })
+
+	tests := []struct {
+		host        string
+		wantBlocked bool
+		wantMatched string
+		wantReason  string
+	}{
+		{"blocked.com", true, "blocked.com", "always blocked (permanent)"},
+		{"WWW.Blocked.com:443", true, "blocked.com", "always blocked (permanent)"},
+		{"allowed.com", false, "", "not blocked"},
+		{"news.com", false, "news.com", "temporarily unblocked"},
+		{"evening.com", false, "evening.com", "outside blocking time windows"},
+	}
+	for _, tt := range tests {
+		req := isBlockedRequest("/is-blocked?host=" + url.QueryEscape(tt.host))
+		req.Header.Set("Origin", "moz-extension://abc")
+		w := httptest.NewRecorder()
+		HandleIsBlockedRequest(cfg, w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", tt.host, w.Code)
+		}
+		if origin := w.Header().Get("Access-Control-Allow-Origin"); origin != "moz-extension://abc" {
+			t.Errorf("%s: expected CORS header, got %q", tt.host, origin)
+		}
+		var response isBlockedResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s: failed to parse response %q: %v", tt.host, w.Body.String(), err)
+		}
+		if response.Blocked != tt.wantBlocked || response.Matched != tt.wantMatched || response.Reason != tt.wantReason {
+			t.Errorf("%s: got %+v, want blocked=%v matched=%q reason=%q", tt.host, response, tt.wantBlocked, tt.wantMatched, tt.wantReason)
+		}
+	}
+
+	// Once the unblock ends the domain is blocked again
+	state.SetTempUnblocks(nil)
+	req := isBlockedRequest("/is-blocked?host=news.com")
+	w := httptest.NewRecorder()
+	HandleIsBlockedRequest(cfg, w, req)
+	if !strings.Contains(w.Body.String(), `"blocked":true`) {
+		t.Errorf("Expected news.com to be blocked after its unblock, got %s", w.Body.String())
+	}
+
+	// The host parameter is required
+	w = httptest.NewRecorder()
+	HandleIsBlockedRequest(cfg, w, isBlockedRequest("/is-blocked"))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without a host, got %d", w.Code)
+	}
+
+	// Web pages can't read the answer
+	req = isBlockedRequest("/is-blocked?host=news.com")
+	req.Header.Set("Origin", "https://evil.example")
+	w = httptest.NewRecorder()
+	HandleIsBlockedRequest(cfg, w, req)
+	if origin := w.Header().Get("Access-Control-Allow-Origin"); origin != "" {
+		t.Errorf("Expected no CORS header for a web page, got %q", origin)
+	}
+
+	// Only loopback clients asking for a loopback host are answered
+	req = httptest.NewRequest("GET", "/is-blocked?host=news.com", nil)
+	w = httptest.NewRecorder()
+	HandleIsBlockedRequest(cfg, w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a remote client, got %d", w.Code)
+	}
+	req = isBlockedRequest("/is-blocked?host=news.com")
+	req.Host = "attacker.example"
+	w = httptest.NewRecorder()
+	HandleIsBlockedRequest(cfg, w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a non-loopback Host, got %d", w.Code)
+	}
+}
+
+// isBlockedRequest builds a /is-blocked request as the extension sends it, from
+// loopback to 127.0.0.1.
+func isBlockedRequest(target string) *http.Request {
+	req := httptest.NewRequest("GET", "http://127.0.0.1"+target, nil)
+	req.RemoteAddr = "127.0.0.1:12345"
+	return req
+}
+
+func TestCheckHostBlocked_MidnightCrossingWindow(t *testing.T) {
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{Name: "late.com", TimeWindows: []config.TimeWindow{{Start: "22:00", End: "06:00", Days: []string{"Mon"}}}},
+		},
+	}
+	ClearDomainCache()
+	t.Cleanup(ClearDomainCache)
+	domainCache.mu.Lock()
+	domainCache.domains["late.com"] = &cfg.Domains[0]
+	domainCache.mu.Unlock()
+
+	tests := []struct {
+		now  time.Time
+		want bool
+	}{
+		{time.Date(2026, 1, 5, 23, 0, 0, 0, time.UTC), true},  // Monday night
+		{time.Date(2026, 1, 6, 2, 0, 0, 0, time.UTC), true},   // Early Tuesday, still Monday's window
+		{time.Date(2026, 1, 6, 23, 0, 0, 0, time.UTC), false}, // Tuesday night
+		{time.Date(2026, 1, 5, 2, 0, 0, 0, time.UTC), false},  // Early Monday belongs to Sunday
+	}
+	for _, tt := range tests {
+		if response := checkHostBlocked(cfg, "late.com", tt.now); response.Blocked != tt.want {
+			t.Errorf("checkHostBlocked(late.com, %s) = %+v, want blocked=%v", tt.now.Format("Mon 15:04"), response, tt.want)
+		}
+	}
+	if reason := GetBlockingReason(cfg, "late.com", time.Date(2026, 1, 6, 2, 0, 0, 0, time.UTC)); !strings.Contains(reason, "22:00-06:00") {
+		t.Errorf("Expected the window in the reason, got %q", reason)
+	}
+}
+
+func TestIsPathBlocked_TimeWindowAndWildcards(t *testing.T) {
+	cfg := &config.Config{
+		Domains: []config.Domain{
+			{
+				Name:         "reddit.com",
+				PathPatterns: []string{"/r/*/comments"},
+				TimeWindows: []config.TimeWindow{
+					{Start: "18:00", End: "22:00", Days: []string{"Tue"}},
+				},
+			},
+			{Name: "youtube.com", PathPatterns: []string{"shorts"}, ExactOnly: true},
+		},
+	}
+
+	morning := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC) // Tuesday
+	evening := time.Date(2026, 1, 6, 19, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		host, path string
+		now        time.Time
+		want       bool
+	}{
+		{"reddit.com", "/r/golang/comments/123", evening, true},
+		{"reddit.com", "/r/golang/comments/123", morning, false},
+		{"old.reddit.com", "/r/golang/comments/123", evening, true},
+		{"reddit.com", "/r/golang", evening, false},
+		{"youtube.com", "/shorts/xyz", morning, true},
+		{"m.youtube.com", "/shorts/xyz", morning, false},
+	}
+	for _, tt := range tests {
+		if got, _ := isPathBlocked(cfg, tt.host, tt.path, tt.now); got != tt.want {
+			t.Errorf("isPathBlocked(%q, %q, %s) = %v, want %v", tt.host, tt.path, tt.now.Format("15:04"), got, tt.want)
+		}
+	}
+
+	// Path rules never block the whole host
+	if matched, _ := findBlockingDomain(cfg.Domains, "reddit.com", evening); matched != nil {
+		t.Errorf("Expected reddit.com itself to stay reachable, got %q", matched.Name)
+	}
+}
+
 func TestLogContentReport(t *testing.T) {
 	tmpFile, err := os.CreateTemp("", "glocker-test-*.log")
 	if err != nil {
@@ -422,3 +1252,212 @@ func TestIsValidUnblockReason_WithConfig(t *testing.T) {
 		t.Error("Should reject invalid reason 'gaming'")
 	}
 }
+
+func TestGenerateSelfSignedCert(t *testing.T) {
+	now := time.Date(2026, 1, 6, 10, 0, 0, 0, time.UTC)
+	cert, err := generateSelfSignedCert(now)
+	if err != nil {
+		t.Fatalf("generateSelfSignedCert failed: %v", err)
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("Generated certificate doesn't parse: %v", err)
+	}
+	if leaf.Subject.CommonName != "localhost" {
+		t.Errorf("CN = %q, want localhost", leaf.Subject.CommonName)
+	}
+	if days := leaf.NotAfter.Sub(now).Hours() / 24; days != 365 {
+		t.Errorf("Certificate valid for %v days, want 365", days)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(leaf)
+	for _, name := range []string{"localhost", "127.0.0.1"} {
+		if _, err := leaf.Verify(x509.VerifyOptions{DNSName: name, Roots: roots, CurrentTime: now}); err != nil {
+			t.Errorf("Certificate not valid for %s: %v", name, err)
+		}
+	}
+}
+
+func TestEffectiveKeywords(t *testing.T) {
+	disabled := false
+	cfg := &config.Config{
+		ExtensionKeywords: config.ExtensionKeywordsConfig{
+			URLKeywords:     []string{"casino"},
+			ContentKeywords: []string{"jackpot"},
+		},
+		KeywordCategories: []config.KeywordCategory{
+			{
+				Name:        "gaming",
+				URLKeywords: []string{"steam", "casino"},
+				TimeWindows: []config.TimeWindow{{Start: "09:00", End: "17:00", Days: []string{"Tue"}}},
+			},
+			{
+				Name:            "late night",
+				ContentKeywords: []string{"stream"},
+				TimeWindows:     []config.TimeWindow{{Start: "22:00", End: "02:00", Days: []string{"Mon"}}},
+			},
+			{Name: "news", Enabled: &disabled, URLKeywords: []string{"headlines"}},
+			{Name: "always", ContentKeywords: []string{"bet"}},
+		},
+	}
+
+	tests := []struct {
+		name            string
+		now             time.Time
+		urlKeywords     []string
+		contentKeywords []string
+	}{
+		{"work hours", time.Date(2026, 1, 6, 10, 0, 0, 0, time.Local),
+			[]string{"casino", "steam"}, []string{"jackpot", "bet", "casino", "steam"}},
+		{"evening", time.Date(2026, 1, 6, 20, 0, 0, 0, time.Local),
+			[]string{"casino"}, []string{"jackpot", "bet", "casino"}},
+		// Monday's late night window still runs early on Tuesday
+		{"after midnight", time.Date(2026, 1, 6, 1, 0, 0, 0, time.Local),
+			[]string{"casino"}, []string{"jackpot", "stream", "bet", "casino"}},
+		{"late on Tuesday", time.Date(2026, 1, 6, 23, 0, 0, 0, time.Local),
+			[]string{"casino"}, []string{"jackpot", "bet", "casino"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urlKeywords, contentKeywords := EffectiveKeywords(cfg, tt.now)
+			if strings.Join(urlKeywords, ",") != strings.Join(tt.urlKeywords, ",") {
+				t.Errorf("URL keywords = %v, want %v", urlKeywords, tt.urlKeywords)
+			}
+			if strings.Join(contentKeywords, ",") != strings.Join(tt.contentKeywords, ",") {
+				t.Errorf("Content keywords = %v, want %v", contentKeywords, tt.contentKeywords)
+			}
+		})
+	}
+}
+
+func TestCheckKeywordCategories_BroadcastsOnChange(t *testing.T) {
+	keywordCategoryState.mu.Lock()
+	keywordCategoryState.active = nil
+	keywordCategoryState.mu.Unlock()
+
+	cfg := &config.Config{
+		KeywordCategories: []config.KeywordCategory{{
+			Name:        "gaming",
+			URLKeywords: []string{"steam"},
+			TimeWindows: []config.TimeWindow{{Start: "09:00", End: "17:00", Days: []string{"Tue"}}},
+		}},
+	}
+
+	client := make(chan string, 10)
+	state.AddSSEClient(client)
+	defer state.RemoveSSEClient(client)
+
+	morning := time.Date(2026, 1, 6, 8, 59, 0, 0, time.Local)
+	CheckKeywordCategories(cfg, morning)
+	CheckKeywordCategories(cfg, morning.Add(30*time.Second))
+	if len(client) != 0 {
+		t.Fatalf("Expected no update before the category turns on, got %d", len(client))
+	}
+
+	CheckKeywordCategories(cfg, morning.Add(time.Minute))
+	if len(client) != 1 {
+		t.Fatalf("Expected one update when the category turns on, got %d", len(client))
+	}
+	var message keywordsMessage
+	if err := json.Unmarshal([]byte(<-client), &message); err != nil {
+		t.Fatalf("Failed to parse update: %v", err)
+	}
+	if len(message.URLKeywords) != 1 || message.URLKeywords[0] != "steam" {
+		t.Errorf("Expected the category's keywords in the update, got %v", message.URLKeywords)
+	}
+
+	CheckKeywordCategories(cfg, time.Date(2026, 1, 6, 17, 1, 0, 0, time.Local))
+	if len(client) != 1 {
+		t.Errorf("Expected one update when the category turns off, got %d", len(client))
+	}
+}
+
+func TestHandleMetricsRequest(t *testing.T) {
+	now := time.Now()
+	state.SetTempUnblocks([]state.TempUnblock{
+		{Domain: "youtube.com", ExpiresAt: now.Add(10 * time.Minute)},
+		{Domain: "reddit.com", ExpiresAt: now.Add(-time.Minute)},
+	})
+	defer state.SetTempUnblocks(nil)
+	state.ClearViolations()
+	defer state.ClearViolations()
+	state.AddViolation(state.Violation{Timestamp: now, Host: "example.com"})
+	state.AddViolation(state.Violation{Timestamp: now.Add(-2 * time.Hour), Host: "example.com"})
+	state.SetPanicUntil(now.Add(time.Minute))
+	defer state.SetPanicUntil(time.Time{})
+
+	cfg := &config.Config{ViolationTracking: config.ViolationTrackingConfig{Enabled: true, TimeWindowMinutes: 60}}
+
+	req := httptest.NewRequest("GET", "http://127.0.0.1/metrics", nil)
+	req.RemoteAddr = "127.0.0.1:41234"
+	w := httptest.NewRecorder()
+	HandleMetricsRequest(cfg, w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if contentType := w.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf("Expected the Prometheus text content type, got %q", contentType)
+	}
+
+	body := w.Body.String()
+	for _, line := range []string{
+		"# TYPE glocker_blocked_domains gauge",
+		"# TYPE glocker_temp_unblocks_active gauge",
+		"glocker_temp_unblocks_active 1\n",
+		"# TYPE glocker_violations_recent gauge",
+		"glocker_violations_recent 1\n",
+		"# TYPE glocker_violations_total counter",
+		"# TYPE glocker_tamper_events_total counter",
+		"# TYPE glocker_emails_sent_total counter",
+		"# TYPE glocker_emails_failed_total counter",
+		"# TYPE glocker_panic_mode_active gauge",
+		"glocker_panic_mode_active 1\n",
+	} {
+		if !strings.Contains(body, line) {
+			t.Errorf("Metrics should contain %q, got:\n%s", line, body)
+		}
+	}
+
+	// Every sample is preceded by its HELP and TYPE lines
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	if len(lines)%3 != 0 {
+		t.Fatalf("Expected HELP, TYPE and sample lines for each metric, got:\n%s", body)
+	}
+	for i := 0; i < len(lines); i += 3 {
+		name := strings.Fields(lines[i+2])[0]
+		if !strings.HasPrefix(lines[i], "# HELP "+name+" ") || !strings.HasPrefix(lines[i+1], "# TYPE "+name+" ") {
+			t.Errorf("Metric %s is missing its HELP or TYPE line", name)
+		}
+	}
+
+	req = httptest.NewRequest("GET", "/metrics", nil)
+	req.RemoteAddr = "192.168.1.20:41234"
+	w = httptest.NewRecorder()
+	HandleMetricsRequest(cfg, w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for a remote scrape, got %d", w.Code)
+	}
+
+	// Blocked domains resolve to this server, so a page on one must not read the metrics
+	req = httptest.NewRequest("GET", "http://youtube.com/metrics", nil)
+	req.RemoteAddr = "127.0.0.1:41234"
+	w = httptest.NewRecorder()
+	HandleMetricsRequest(cfg, w, req)
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for a request to a blocked domain, got %d", w.Code)
+	}
+
+	for _, host := range []string{"localhost:80", "[::1]", "127.0.0.1:8080"} {
+		req = httptest.NewRequest("GET", "/metrics", nil)
+		req.Host = host
+		req.RemoteAddr = "127.0.0.1:41234"
+		w = httptest.NewRecorder()
+		HandleMetricsRequest(cfg, w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected status 200 for Host %q, got %d", host, w.Code)
+		}
+	}
+}